@@ -0,0 +1,311 @@
+package auroraclient
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/diamnet/go/keypair"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/txnbuild"
+)
+
+const (
+	// DefaultChannelStartingBalance is the balance of the channel accounts
+	// created by a ChannelPool by default.
+	DefaultChannelStartingBalance = "5"
+	// DefaultChannelTransactionTimeout is the validity of the transactions
+	// built with the channels of a ChannelPool by default.
+	DefaultChannelTransactionTimeout = time.Minute
+	// maxChannelOperations is the number of channel accounts created or merged
+	// per transaction.
+	maxChannelOperations = 100
+)
+
+// ChannelPoolConfig configures a ChannelPool.
+type ChannelPoolConfig struct {
+	NetworkPassphrase string
+	// Account funds the channel accounts and is the source account of the
+	// operations of the transactions built with the channels. It signs every
+	// transaction.
+	Account *keypair.Full
+	// Channels are the keys of the channel accounts. The channel accounts
+	// which don't exist are created and funded by Account. The sequence
+	// numbers of the others are loaded from Aurora, so passing the same keys
+	// after a restart recovers the pool.
+	Channels []*keypair.Full
+	// StartingBalance is the balance of the created channel accounts,
+	// DefaultChannelStartingBalance if empty.
+	StartingBalance string
+	// BaseFee is the base fee of the transactions, txnbuild.MinBaseFee if 0.
+	BaseFee int64
+	// TransactionTimeout is the validity of the transactions,
+	// DefaultChannelTransactionTimeout if 0.
+	TransactionTimeout time.Duration
+}
+
+// ChannelPool manages channel accounts which are the source accounts of
+// transactions sending operations from a single account. Every channel has
+// its own sequence number so the transactions built with different channels
+// can be submitted concurrently.
+//
+// A channel is leased with Lease to build and submit a transaction and is
+// given back with Release. The pool tracks the sequence numbers of the
+// channels; call Channel.Reset when a transaction built with a channel was
+// not included in a ledger and won't be resubmitted.
+type ChannelPool struct {
+	client    ClientInterface
+	config    ChannelPoolConfig
+	available chan *Channel
+
+	// lock protects size, which doesn't count the channels being retired
+	lock sync.Mutex
+	size int
+}
+
+// Channel is a channel account leased from a ChannelPool.
+type Channel struct {
+	pool    *ChannelPool
+	keypair *keypair.Full
+	// account is nil when the sequence number of the channel is unknown
+	account *txnbuild.SimpleAccount
+}
+
+// NewChannelPool returns a pool of the channel accounts of config. The channel
+// accounts which don't exist are created.
+func NewChannelPool(client ClientInterface, config ChannelPoolConfig) (*ChannelPool, error) {
+	if config.Account == nil {
+		return nil, errors.New("account is required")
+	}
+	if len(config.Channels) == 0 {
+		return nil, errors.New("at least one channel account is required")
+	}
+	if config.StartingBalance == "" {
+		config.StartingBalance = DefaultChannelStartingBalance
+	}
+	if config.BaseFee == 0 {
+		config.BaseFee = txnbuild.MinBaseFee
+	}
+	if config.TransactionTimeout == 0 {
+		config.TransactionTimeout = DefaultChannelTransactionTimeout
+	}
+
+	pool := &ChannelPool{
+		client:    client,
+		config:    config,
+		available: make(chan *Channel, len(config.Channels)),
+		size:      len(config.Channels),
+	}
+
+	var missing []txnbuild.Operation
+	for _, kp := range config.Channels {
+		ch := &Channel{pool: pool, keypair: kp}
+		err := ch.load()
+		if IsNotFoundError(err) {
+			missing = append(missing, &txnbuild.CreateAccount{
+				Destination: kp.Address(),
+				Amount:      config.StartingBalance,
+			})
+		} else if err != nil {
+			return nil, err
+		}
+		pool.available <- ch
+	}
+
+	if _, err := pool.submitFromAccount(missing, nil); err != nil {
+		return nil, errors.Wrap(err, "could not create channel accounts")
+	}
+	return pool, nil
+}
+
+// Size returns the number of channels in the pool, not counting the channels
+// being retired.
+func (p *ChannelPool) Size() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.size
+}
+
+// Lease waits until a channel is available and leases it. The sequence number
+// of the channel is loaded from Aurora if it's unknown.
+func (p *ChannelPool) Lease(ctx context.Context) (*Channel, error) {
+	select {
+	case ch := <-p.available:
+		if ch.account == nil {
+			if err := ch.load(); err != nil {
+				p.available <- ch
+				return nil, err
+			}
+		}
+		return ch, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Release gives a leased channel back to the pool.
+func (p *ChannelPool) Release(ch *Channel) {
+	p.available <- ch
+}
+
+// Retire removes count channels from the pool and merges their accounts back
+// into the pool account. It waits until the channels are available.
+func (p *ChannelPool) Retire(ctx context.Context, count int) error {
+	// the channels are reserved so that concurrent calls can't retire more
+	// channels than the pool has
+	p.lock.Lock()
+	if count > p.size {
+		size := p.size
+		p.lock.Unlock()
+		return errors.Errorf("cannot retire %d channels from a pool of %d", count, size)
+	}
+	p.size -= count
+	p.lock.Unlock()
+
+	channels := make([]*Channel, 0, count)
+	ops := make([]txnbuild.Operation, 0, count)
+	signers := make([]*keypair.Full, 0, count)
+	for len(channels) < count {
+		select {
+		case ch := <-p.available:
+			channels = append(channels, ch)
+			ops = append(ops, &txnbuild.AccountMerge{
+				Destination:   p.config.Account.Address(),
+				SourceAccount: ch.Address(),
+			})
+			signers = append(signers, ch.keypair)
+		case <-ctx.Done():
+			for _, ch := range channels {
+				p.available <- ch
+			}
+			p.lock.Lock()
+			p.size += count
+			p.lock.Unlock()
+			return ctx.Err()
+		}
+	}
+
+	merged, err := p.submitFromAccount(ops, signers)
+	for _, ch := range channels[merged:] {
+		ch.Reset()
+		p.available <- ch
+	}
+	p.lock.Lock()
+	p.size += count - merged
+	p.lock.Unlock()
+	return errors.Wrap(err, "could not merge channel accounts")
+}
+
+// Close merges all the channel accounts back into the pool account.
+func (p *ChannelPool) Close(ctx context.Context) error {
+	return p.Retire(ctx, p.Size())
+}
+
+// submitFromAccount submits operations in transactions with the pool account
+// as source account, signed by the pool account and signers. It returns the
+// number of operations which were submitted successfully.
+func (p *ChannelPool) submitFromAccount(ops []txnbuild.Operation, signers []*keypair.Full) (int, error) {
+	if len(ops) == 0 {
+		return 0, nil
+	}
+
+	accountID := p.config.Account.Address()
+	account, err := p.client.AccountDetail(AccountRequest{AccountID: accountID})
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not load account %s", accountID)
+	}
+
+	for start := 0; start < len(ops); start += maxChannelOperations {
+		end := start + maxChannelOperations
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+			SourceAccount:        &account,
+			IncrementSequenceNum: true,
+			Operations:           ops[start:end],
+			BaseFee:              p.config.BaseFee,
+			Timebounds:           txnbuild.NewTimeout(int64(p.config.TransactionTimeout / time.Second)),
+		})
+		if err != nil {
+			return start, errors.Wrap(err, "could not build transaction")
+		}
+
+		kps := []*keypair.Full{p.config.Account}
+		if signers != nil {
+			kps = append(kps, signers[start:end]...)
+		}
+		if tx, err = tx.Sign(p.config.NetworkPassphrase, kps...); err != nil {
+			return start, errors.Wrap(err, "could not sign transaction")
+		}
+		if _, err = p.client.SubmitTransaction(tx); err != nil {
+			return start, err
+		}
+	}
+	return len(ops), nil
+}
+
+// Address returns the address of the channel account.
+func (ch *Channel) Address() string {
+	return ch.keypair.Address()
+}
+
+// Reset forgets the sequence number of the channel, which is loaded from
+// Aurora when the channel is leased again.
+func (ch *Channel) Reset() {
+	ch.account = nil
+}
+
+// BuildTransaction builds a transaction with the channel account as source
+// account and the pool account as the source account of the operations which
+// don't have one. The transaction is signed by the channel and the pool
+// account and consumes the next sequence number of the channel.
+func (ch *Channel) BuildTransaction(memo txnbuild.Memo, operations ...txnbuild.Operation) (*txnbuild.Transaction, error) {
+	if ch.account == nil {
+		return nil, errors.New("the sequence number of the channel is unknown")
+	}
+
+	config := ch.pool.config
+	ops := make([]txnbuild.Operation, 0, len(operations))
+	for _, op := range operations {
+		if op.GetSourceAccount() == "" {
+			var err error
+			if op, err = txnbuild.WithOperationSourceAccount(op, config.Account.Address(), false); err != nil {
+				return nil, errors.Wrap(err, "could not set the source account of an operation")
+			}
+		}
+		ops = append(ops, op)
+	}
+
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        ch.account,
+		IncrementSequenceNum: true,
+		Operations:           ops,
+		BaseFee:              config.BaseFee,
+		Memo:                 memo,
+		Timebounds:           txnbuild.NewTimeout(int64(config.TransactionTimeout / time.Second)),
+	})
+	if err != nil {
+		// the sequence number may have been incremented
+		ch.Reset()
+		return nil, errors.Wrap(err, "could not build transaction")
+	}
+	tx, err = tx.Sign(config.NetworkPassphrase, ch.keypair, config.Account)
+	return tx, errors.Wrap(err, "could not sign transaction")
+}
+
+// load loads the sequence number of the channel account from Aurora.
+func (ch *Channel) load() error {
+	accountID := ch.Address()
+	account, err := ch.pool.client.AccountDetail(AccountRequest{AccountID: accountID})
+	if err != nil {
+		return errors.Wrapf(err, "could not load channel account %s", accountID)
+	}
+	sequence, err := account.GetSequenceNumber()
+	if err != nil {
+		return errors.Wrapf(err, "invalid sequence number of channel account %s", accountID)
+	}
+	ch.account = &txnbuild.SimpleAccount{AccountID: accountID, Sequence: sequence}
+	return nil
+}
@@ -0,0 +1,181 @@
+package auroraclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/keypair"
+	"github.com/diamnet/go/network"
+	hProtocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/txnbuild"
+)
+
+func expectAccountDetail(client *MockClient, kp *keypair.Full, sequence string) {
+	client.On("AccountDetail", AccountRequest{AccountID: kp.Address()}).
+		Return(hProtocol.Account{AccountID: kp.Address(), Sequence: sequence}, nil).Once()
+}
+
+func TestChannelPool(t *testing.T) {
+	client := &MockClient{}
+	account, existing, missing := keypair.MustRandom(), keypair.MustRandom(), keypair.MustRandom()
+
+	expectAccountDetail(client, existing, "10")
+	client.On("AccountDetail", AccountRequest{AccountID: missing.Address()}).
+		Return(hProtocol.Account{}, &Error{Problem: problem.P{Type: "https://diamnet.org/aurora-errors/not_found"}}).Once()
+	expectAccountDetail(client, account, "100")
+	var created *txnbuild.Transaction
+	client.On("SubmitTransaction", mock.Anything).
+		Run(func(args mock.Arguments) {
+			created = args.Get(0).(*txnbuild.Transaction)
+		}).
+		Return(hProtocol.Transaction{Successful: true}, nil).Once()
+
+	pool, err := NewChannelPool(client, ChannelPoolConfig{
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		Account:           account,
+		Channels:          []*keypair.Full{existing, missing},
+	})
+	require.NoError(t, err)
+	client.AssertExpectations(t)
+	assert.Equal(t, 2, pool.Size())
+
+	require.NotNil(t, created)
+	assert.Equal(t, account.Address(), created.SourceAccount().AccountID)
+	assert.Equal(t, int64(101), created.SequenceNumber())
+	assert.Equal(t, []txnbuild.Operation{&txnbuild.CreateAccount{
+		Destination: missing.Address(),
+		Amount:      "5",
+	}}, created.Operations())
+
+	ctx := context.Background()
+	ch, err := pool.Lease(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, existing.Address(), ch.Address())
+
+	payment := &txnbuild.Payment{
+		Destination: keypair.MustRandom().Address(),
+		Amount:      "10",
+		Asset:       txnbuild.NativeAsset{},
+	}
+	tx, err := ch.BuildTransaction(txnbuild.MemoText("payout"), payment)
+	require.NoError(t, err)
+	assert.Equal(t, existing.Address(), tx.SourceAccount().AccountID)
+	assert.Equal(t, int64(11), tx.SequenceNumber())
+	assert.Equal(t, txnbuild.MemoText("payout"), tx.Memo())
+	assert.Equal(t, account.Address(), tx.Operations()[0].GetSourceAccount())
+	assert.Len(t, tx.Signatures(), 2)
+
+	tx, err = ch.BuildTransaction(nil, payment)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), tx.SequenceNumber())
+
+	// the created channel is loaded when it's leased
+	expectAccountDetail(client, missing, "4294967296")
+	other, err := pool.Lease(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, missing.Address(), other.Address())
+	tx, err = other.BuildTransaction(nil, payment)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4294967297), tx.SequenceNumber())
+	pool.Release(other)
+
+	// a reset channel is reloaded
+	ch.Reset()
+	_, err = ch.BuildTransaction(nil, payment)
+	assert.EqualError(t, err, "the sequence number of the channel is unknown")
+	pool.Release(ch)
+	expectAccountDetail(client, existing, "12")
+	for i := 0; i < 2; i++ {
+		leased, err := pool.Lease(ctx)
+		require.NoError(t, err)
+		pool.Release(leased)
+	}
+	client.AssertExpectations(t)
+}
+
+func TestChannelPoolRetire(t *testing.T) {
+	client := &MockClient{}
+	account, first, second := keypair.MustRandom(), keypair.MustRandom(), keypair.MustRandom()
+
+	expectAccountDetail(client, first, "10")
+	expectAccountDetail(client, second, "20")
+	pool, err := NewChannelPool(client, ChannelPoolConfig{
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		Account:           account,
+		Channels:          []*keypair.Full{first, second},
+	})
+	require.NoError(t, err)
+
+	assert.EqualError(t, pool.Retire(context.Background(), 3), "cannot retire 3 channels from a pool of 2")
+
+	expectAccountDetail(client, account, "100")
+	var merged *txnbuild.Transaction
+	client.On("SubmitTransaction", mock.Anything).
+		Run(func(args mock.Arguments) {
+			merged = args.Get(0).(*txnbuild.Transaction)
+		}).
+		Return(hProtocol.Transaction{Successful: true}, nil).Once()
+
+	require.NoError(t, pool.Retire(context.Background(), 1))
+	assert.Equal(t, 1, pool.Size())
+	require.NotNil(t, merged)
+	assert.Equal(t, account.Address(), merged.SourceAccount().AccountID)
+	assert.Equal(t, []txnbuild.Operation{&txnbuild.AccountMerge{
+		Destination:   account.Address(),
+		SourceAccount: first.Address(),
+	}}, merged.Operations())
+	assert.Len(t, merged.Signatures(), 2)
+
+	ch, err := pool.Lease(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, second.Address(), ch.Address())
+
+	// the leased channel can't be retired until it's released
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, pool.Close(ctx))
+	assert.Equal(t, 1, pool.Size())
+	client.AssertExpectations(t)
+}
+
+func TestChannelPoolConcurrentRetire(t *testing.T) {
+	client := &MockClient{}
+	first, second := keypair.MustRandom(), keypair.MustRandom()
+
+	expectAccountDetail(client, first, "10")
+	expectAccountDetail(client, second, "20")
+	pool, err := NewChannelPool(client, ChannelPoolConfig{
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		Account:           keypair.MustRandom(),
+		Channels:          []*keypair.Full{first, second},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = pool.Lease(context.Background())
+		require.NoError(t, err)
+	}
+
+	// the channel being retired can't be retired by another call while the
+	// first one waits for it to be released
+	ctx, cancel := context.WithCancel(context.Background())
+	retired := make(chan error)
+	go func() {
+		retired <- pool.Retire(ctx, 1)
+	}()
+	assert.Eventually(t, func() bool {
+		return pool.Size() == 1
+	}, time.Second, time.Millisecond)
+	assert.EqualError(t, pool.Retire(context.Background(), 2), "cannot retire 2 channels from a pool of 1")
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-retired)
+	assert.Equal(t, 2, pool.Size())
+	client.AssertExpectations(t)
+}
@@ -131,6 +131,20 @@ func (c *Client) stream(
 	ctx context.Context,
 	streamURL string,
 	handler func(data []byte) error,
+) error {
+	return c.streamWithOptions(ctx, streamURL, nil, handler)
+}
+
+// streamWithOptions streams an endpoint like stream. Without options the stream
+// reconnects as soon as Aurora closes the connection and returns on any error.
+// With options the cursor is loaded from and saved to the checkpoint store,
+// transient errors are retried with backoff and the events which are not after
+// the last event received are dropped.
+func (c *Client) streamWithOptions(
+	ctx context.Context,
+	streamURL string,
+	opts *StreamOptions,
+	handler func(data []byte) error,
 ) error {
 	su, err := url.Parse(streamURL)
 	if err != nil {
@@ -138,116 +152,217 @@ func (c *Client) stream(
 	}
 
 	query := su.Query()
+	// lastToken is the paging token of the last event handled
+	var lastToken string
+	if opts != nil {
+		opts = opts.withDefaults()
+		if opts.Checkpoint != nil {
+			cursor, err := opts.Checkpoint.LoadCursor(ctx)
+			if err != nil {
+				return errors.Wrap(err, "error loading stream cursor")
+			}
+			if cursor != "" {
+				query.Set("cursor", cursor)
+				lastToken = cursor
+			}
+		}
+	}
 	if query.Get("cursor") == "" {
 		query.Set("cursor", "now")
 	}
+	ascending := query.Get("order") != string(OrderDesc)
+	// handled is the number of events handled since the last connection
+	handled := 0
+
+	onEvent := func(event sse.Event) error {
+		var data []byte
+		switch d := event.Data.(type) {
+		case string:
+			data = []byte(d)
+		case []byte:
+			data = d
+		default:
+			return errors.New("invalid event.Data type")
+		}
 
+		token := event.Id
+		if opts != nil {
+			if token == "" {
+				token = pagingTokenOf(data)
+			}
+			if ascending && token != "" && lastToken != "" {
+				if after, ok := pagingTokenAfter(token, lastToken); ok && !after {
+					if opts.OnDuplicate != nil {
+						opts.OnDuplicate(token)
+					}
+					return nil
+				}
+				if opts.OnGap != nil && opts.isGap != nil && opts.isGap(lastToken, token) {
+					opts.OnGap(lastToken, token)
+				}
+			}
+		}
+
+		if err := handler(data); err != nil {
+			return errors.Wrap(err, "handler error")
+		}
+
+		handled++
+
+		// Update cursor with event ID
+		if token != "" {
+			query.Set("cursor", token)
+			lastToken = token
+			if opts != nil && opts.Checkpoint != nil {
+				if err := opts.Checkpoint.SaveCursor(ctx, token); err != nil {
+					return errors.Wrap(err, "error saving stream cursor")
+				}
+			}
+		}
+		return nil
+	}
+
+	failures := 0
 	for {
 		// updates the url with new cursor
 		su.RawQuery = query.Encode()
-		req, err := http.NewRequest("GET", su.String(), nil)
-		if err != nil {
-			return errors.Wrap(err, "error creating HTTP request")
+		handled = 0
+		err := c.readStream(ctx, su.String(), onEvent)
+		if ctx.Err() != nil {
+			return nil
 		}
-		req.Header.Set("Accept", "text/event-stream")
-		c.setDefaultClient()
-		c.setClientAppHeaders(req)
 
-		// We can use c.HTTP here because we set Timeout per request not on the client. See sendRequest()
-		resp, err := c.HTTP.Do(req)
-		if err != nil {
-			return errors.Wrap(err, "error sending HTTP request")
+		transient, isTransient := err.(transientStreamError)
+		if isTransient {
+			err = transient.err
 		}
 
-		// Expected statusCode are 200-299
-		if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
-			return fmt.Errorf("got bad HTTP status code %d", resp.StatusCode)
+		if opts == nil {
+			if err != nil {
+				return err
+			}
+			// the connection was closed, reconnect right away
+			continue
 		}
-		defer resp.Body.Close()
 
-		reader := bufio.NewReader(resp.Body)
+		if err == nil {
+			// the connection was closed after streaming successfully
+			failures = 0
+			continue
+		}
+		if !isTransient {
+			return err
+		}
+		if handled > 0 {
+			failures = 0
+		}
+		failures++
+		if opts.MaxRetries > 0 && failures > opts.MaxRetries {
+			return errors.Wrapf(err, "giving up after %d attempts", failures)
+		}
 
-		// Read events one by one. Break this loop when there is no more data to be
-		// read from resp.Body (io.EOF).
-	Events:
-		for {
-			// Read until empty line = event delimiter. The perfect solution would be to read
-			// as many bytes as possible and forward them to sse.Decode. However this
-			// requires much more complicated code.
-			// We could also write our own `sse` package that works fine with streams directly
-			// (github.com/manucorporat/sse is just using io/ioutils.ReadAll).
-			var buffer bytes.Buffer
-			nonEmptylinesRead := 0
-			for {
-				// Check if ctx is not cancelled
-				select {
-				case <-ctx.Done():
-					return nil
-				default:
-					// Continue
-				}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(opts.backoff(failures)):
+		}
+	}
+}
 
-				line, err := reader.ReadString('\n')
-				if err != nil {
-					if err == io.EOF || err == io.ErrUnexpectedEOF {
-						// We catch EOF errors to handle two possible situations:
-						// - The last line before closing the stream was not empty. This should never
-						//   happen in Aurora as it always sends an empty line after each event.
-						// - The stream was closed by the server/proxy because the connection was idle.
-						//
-						// In the former case, that (again) should never happen in Aurora, we need to
-						// check if there are any events we need to decode. We do this in the `if`
-						// statement below just in case if Aurora behaviour changes in a future.
-						//
-						// From spec:
-						// > Once the end of the file is reached, the user agent must dispatch the
-						// > event one final time, as defined below.
-						if nonEmptylinesRead == 0 {
-							break Events
-						}
-					} else {
-						return errors.Wrap(err, "error reading line")
-					}
-				}
-				buffer.WriteString(line)
+// readStream reads the events of a single connection to a streaming endpoint
+// and calls onEvent with every message event. It returns nil when the
+// connection is closed by Aurora or when ctx is done. Connection and server
+// errors are returned as transientStreamError.
+func (c *Client) readStream(ctx context.Context, streamURL string, onEvent func(event sse.Event) error) error {
+	req, err := http.NewRequest("GET", streamURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "error creating HTTP request")
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.setDefaultClient()
+	c.setClientAppHeaders(req)
 
-				if strings.TrimRight(line, "\n\r") == "" {
-					break
-				}
+	// We can use c.HTTP here because we set Timeout per request not on the client. See sendRequest()
+	resp, err := c.HTTP.Do(req.WithContext(ctx))
+	if err != nil {
+		return transientStreamError{err: errors.Wrap(err, "error sending HTTP request")}
+	}
+	defer resp.Body.Close()
+
+	// Expected statusCode are 200-299
+	if !(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		err = fmt.Errorf("got bad HTTP status code %d", resp.StatusCode)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return transientStreamError{err: err}
+		}
+		return err
+	}
 
-				nonEmptylinesRead++
+	reader := bufio.NewReader(resp.Body)
+
+	// Read events one by one. Return when there is no more data to be
+	// read from resp.Body (io.EOF).
+	for {
+		// Read until empty line = event delimiter. The perfect solution would be to read
+		// as many bytes as possible and forward them to sse.Decode. However this
+		// requires much more complicated code.
+		// We could also write our own `sse` package that works fine with streams directly
+		// (github.com/manucorporat/sse is just using io/ioutils.ReadAll).
+		var buffer bytes.Buffer
+		nonEmptylinesRead := 0
+		for {
+			// Check if ctx is not cancelled
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+				// Continue
 			}
 
-			events, err := sse.Decode(strings.NewReader(buffer.String()))
+			line, err := reader.ReadString('\n')
 			if err != nil {
-				return errors.Wrap(err, "error decoding event")
+				if err == io.EOF || err == io.ErrUnexpectedEOF {
+					// We catch EOF errors to handle two possible situations:
+					// - The last line before closing the stream was not empty. This should never
+					//   happen in Aurora as it always sends an empty line after each event.
+					// - The stream was closed by the server/proxy because the connection was idle.
+					//
+					// In the former case, that (again) should never happen in Aurora, we need to
+					// check if there are any events we need to decode. We do this in the `if`
+					// statement below just in case if Aurora behaviour changes in a future.
+					//
+					// From spec:
+					// > Once the end of the file is reached, the user agent must dispatch the
+					// > event one final time, as defined below.
+					if nonEmptylinesRead == 0 {
+						return nil
+					}
+				} else {
+					return transientStreamError{err: errors.Wrap(err, "error reading line")}
+				}
 			}
+			buffer.WriteString(line)
 
-			// Right now len(events) should always be 1. This loop will be helpful after writing
-			// new SSE decoder that can handle io.Reader without using ioutils.ReadAll().
-			for _, event := range events {
-				if event.Event != "message" {
-					continue
-				}
+			if strings.TrimRight(line, "\n\r") == "" {
+				break
+			}
 
-				// Update cursor with event ID
-				if event.Id != "" {
-					query.Set("cursor", event.Id)
-				}
+			nonEmptylinesRead++
+		}
 
-				switch data := event.Data.(type) {
-				case string:
-					err = handler([]byte(data))
-					err = errors.Wrap(err, "handler error")
-				case []byte:
-					err = handler(data)
-					err = errors.Wrap(err, "handler error")
-				default:
-					err = errors.New("invalid event.Data type")
-				}
-				if err != nil {
-					return err
-				}
+		events, err := sse.Decode(strings.NewReader(buffer.String()))
+		if err != nil {
+			return errors.Wrap(err, "error decoding event")
+		}
+
+		// Right now len(events) should always be 1. This loop will be helpful after writing
+		// new SSE decoder that can handle io.Reader without using ioutils.ReadAll().
+		for _, event := range events {
+			if event.Event != "message" {
+				continue
+			}
+			if err := onEvent(event); err != nil {
+				return err
 			}
 		}
 	}
@@ -469,6 +584,10 @@ func (c *Client) SubmitFeeBumpTransactionWithOptions(transaction *txnbuild.FeeBu
 		}
 	}
 
+	if opts.FeePolicy != nil {
+		return c.submitWithFeePolicy(transaction, transaction.InnerTransaction(), opts.FeePolicy)
+	}
+
 	txeBase64, err := transaction.Base64()
 	if err != nil {
 		err = errors.Wrap(err, "Unable to convert transaction object to base64 string")
@@ -494,6 +613,9 @@ func (c *Client) SubmitTransaction(transaction *txnbuild.Transaction) (tx hProto
 // SubmitTransactionWithOptions submits a transaction to the network, allowing
 // you to pass SubmitTxOpts. err can be either an error object or a aurora.Error object.
 //
+// If opts.FeePolicy is set and the transaction fails with tx_insufficient_fee, it is
+// resubmitted in fee bump transactions with increasing fees, see FeePolicy.
+//
 // See https://developers.diamnet.org/api/resources/transactions/post/
 func (c *Client) SubmitTransactionWithOptions(transaction *txnbuild.Transaction, opts SubmitTxOpts) (tx hProtocol.Transaction, err error) {
 	// only check if memo is required if skip is false and the transaction
@@ -505,6 +627,10 @@ func (c *Client) SubmitTransactionWithOptions(transaction *txnbuild.Transaction,
 		}
 	}
 
+	if opts.FeePolicy != nil {
+		return c.submitWithFeePolicy(transaction, transaction, opts.FeePolicy)
+	}
+
 	txeBase64, err := transaction.Base64()
 	if err != nil {
 		err = errors.Wrap(err, "Unable to convert transaction object to base64 string")
@@ -649,6 +775,64 @@ func (c *Client) StreamOrderBooks(ctx context.Context, request OrderBookRequest,
 	return request.StreamOrderBooks(ctx, c, handler)
 }
 
+// StreamTransactionsWithOptions streams transactions like StreamTransactions, persisting the
+// cursor in opts.Checkpoint and resuming the stream with backoff after transient errors.
+// Missing transactions are reported to opts.OnGap when the request includes failed
+// transactions and is not filtered by account, claimable balance or liquidity pool. See
+// StreamOptions.
+func (c *Client) StreamTransactionsWithOptions(ctx context.Context, request TransactionRequest, opts StreamOptions, handler TransactionHandler) error {
+	if request.IncludeFailed && request.ForAccount == "" && request.ForMuxedAccount == "" &&
+		request.ForClaimableBalance == "" && request.ForLiquidityPool == "" {
+		opts.isGap = isTransactionGap
+	}
+	return request.streamTransactions(ctx, c, &opts, handler)
+}
+
+// StreamEffectsWithOptions streams effects like StreamEffects, persisting the cursor in
+// opts.Checkpoint and resuming the stream with backoff after transient errors. Missing
+// effects are reported to opts.OnGap when the request is not filtered by account or
+// liquidity pool. See StreamOptions.
+func (c *Client) StreamEffectsWithOptions(ctx context.Context, request EffectRequest, opts StreamOptions, handler EffectHandler) error {
+	if request.ForAccount == "" && request.ForLiquidityPool == "" {
+		opts.isGap = isEffectGap
+	}
+	return request.streamEffects(ctx, c, &opts, handler)
+}
+
+// StreamOperationsWithOptions streams operations like StreamOperations, persisting the cursor in
+// opts.Checkpoint and resuming the stream with backoff after transient errors. Missing
+// operations are reported to opts.OnGap when the request includes failed transactions and
+// is not filtered by account, claimable balance or liquidity pool. See StreamOptions.
+func (c *Client) StreamOperationsWithOptions(ctx context.Context, request OperationRequest, opts StreamOptions, handler OperationHandler) error {
+	if request.IncludeFailed && request.ForAccount == "" && request.ForMuxedAccount == "" &&
+		request.ForClaimableBalance == "" && request.ForLiquidityPool == "" {
+		opts.isGap = isOperationGap
+	}
+	return request.SetOperationsEndpoint().streamOperations(ctx, c, &opts, handler)
+}
+
+// StreamPaymentsWithOptions streams payments like StreamPayments, persisting the cursor in
+// opts.Checkpoint and resuming the stream with backoff after transient errors.
+// See StreamOptions.
+func (c *Client) StreamPaymentsWithOptions(ctx context.Context, request OperationRequest, opts StreamOptions, handler OperationHandler) error {
+	return request.SetPaymentsEndpoint().streamOperations(ctx, c, &opts, handler)
+}
+
+// StreamLedgersWithOptions streams ledgers like StreamLedgers, persisting the cursor in
+// opts.Checkpoint and resuming the stream with backoff after transient errors. Missing
+// ledgers are reported to opts.OnGap. See StreamOptions.
+func (c *Client) StreamLedgersWithOptions(ctx context.Context, request LedgerRequest, opts StreamOptions, handler LedgerHandler) error {
+	opts.isGap = isLedgerGap
+	return request.streamLedgers(ctx, c, &opts, handler)
+}
+
+// StreamTradesWithOptions streams trades like StreamTrades, persisting the cursor in
+// opts.Checkpoint and resuming the stream with backoff after transient errors.
+// See StreamOptions.
+func (c *Client) StreamTradesWithOptions(ctx context.Context, request TradeRequest, opts StreamOptions, handler TradeHandler) error {
+	return request.streamTrades(ctx, c, &opts, handler)
+}
+
 // FetchTimebounds provides timebounds for N seconds from now using the server time of the aurora instance.
 // It defaults to localtime when the server time is not available.
 // Note that this will generate your timebounds when you init the transaction, not when you build or submit
@@ -62,13 +62,18 @@ func (er EffectRequest) BuildURL() (endpoint string, err error) {
 // Use context.WithCancel to stop streaming or context.Background() if you want to stream indefinitely.
 // EffectHandler is a user-supplied function that is executed for each streamed effect received.
 func (er EffectRequest) StreamEffects(ctx context.Context, client *Client, handler EffectHandler) error {
+	return er.streamEffects(ctx, client, nil, handler)
+}
+
+func (er EffectRequest) streamEffects(ctx context.Context, client *Client, opts *StreamOptions,
+	handler EffectHandler) error {
 	endpoint, err := er.BuildURL()
 	if err != nil {
 		return errors.Wrap(err, "unable to build endpoint for effects request")
 	}
 
 	url := fmt.Sprintf("%s%s", client.fixAuroraURL(), endpoint)
-	return client.stream(ctx, url, func(data []byte) error {
+	return client.streamWithOptions(ctx, url, opts, func(data []byte) error {
 		var baseEffect effects.Base
 		// unmarshal into the base effect type
 		if err = json.Unmarshal(data, &baseEffect); err != nil {
@@ -3,6 +3,8 @@ package auroraclient_test
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"time"
 
 	"github.com/diamnet/go/clients/auroraclient"
@@ -112,6 +114,20 @@ func ExampleClient_Fund() {
 	fmt.Print(resp)
 }
 
+func ExampleClient_IterateOperations() {
+	client := auroraclient.DefaultTestNetClient
+	// all operations of an account, 200 per page
+	opRequest := auroraclient.OperationRequest{ForAccount: "GCLWGQPMKXQSPF776IU33AH4PZNOOWNAWGGKVTBQMIC5IMKUNP3E6NVU", Limit: 200}
+
+	it := client.IterateOperations(context.Background(), opRequest)
+	for it.Next() {
+		fmt.Println(it.Operation())
+	}
+	if err := it.Err(); err != nil {
+		fmt.Println(err)
+	}
+}
+
 func ExampleClient_LedgerDetail() {
 	client := auroraclient.DefaultPublicNetClient
 	// details for a ledger
@@ -948,6 +964,48 @@ func ExampleClient_StreamLedgers() {
 	}
 }
 
+// cursorFile is a CheckpointStore saving the cursor of a stream in a file.
+type cursorFile string
+
+func (f cursorFile) LoadCursor(ctx context.Context) (string, error) {
+	cursor, err := ioutil.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(cursor), err
+}
+
+func (f cursorFile) SaveCursor(ctx context.Context, cursor string) error {
+	return ioutil.WriteFile(string(f), []byte(cursor), 0600)
+}
+
+func ExampleClient_StreamLedgersWithOptions() {
+	client := auroraclient.DefaultTestNetClient
+	// all ledgers from the last ledger handled, or from now on the first run
+	ledgerRequest := auroraclient.LedgerRequest{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// Stop streaming after 60 seconds.
+		time.Sleep(60 * time.Second)
+		cancel()
+	}()
+
+	opts := auroraclient.StreamOptions{
+		Checkpoint: cursorFile("ledgers.cursor"),
+		OnGap: func(previous, next string) {
+			fmt.Println("missing ledgers between", previous, "and", next)
+		},
+	}
+	printHandler := func(ledger hProtocol.Ledger) {
+		fmt.Println(ledger)
+	}
+	err := client.StreamLedgersWithOptions(ctx, ledgerRequest, opts, printHandler)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
 func ExampleClient_StreamOffers() {
 	client := auroraclient.DefaultTestNetClient
 	// offers for account
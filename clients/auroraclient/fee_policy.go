@@ -0,0 +1,241 @@
+package auroraclient
+
+import (
+	"fmt"
+
+	"github.com/diamnet/go/keypair"
+	hProtocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/txnbuild"
+)
+
+// DefaultFeePercentiles is the escalation schedule of a FeePolicy by default.
+var DefaultFeePercentiles = []int{50, 70, 90, 95, 99}
+
+// FeePolicy resubmits transactions rejected with tx_insufficient_fee during
+// surge pricing. The transaction is wrapped in fee bump transactions paid by
+// FeeAccount, with the base fees given by the percentiles of the max fees
+// returned by FeeStats, until it is accepted or the next fee would exceed
+// MaxBaseFee.
+type FeePolicy struct {
+	NetworkPassphrase string
+	// FeeAccount pays for and signs the fee bump transactions.
+	FeeAccount *keypair.Full
+	// MaxBaseFee is the highest base fee, in stroops per operation, of the
+	// fee bump transactions. The last attempt is made with MaxBaseFee if the
+	// percentiles of the schedule are lower.
+	MaxBaseFee int64
+	// Percentiles of the max fee distribution of FeeStats used as base fees,
+	// in increasing order. 100 is the highest max fee. DefaultFeePercentiles
+	// if empty.
+	Percentiles []int
+	// OnAttempt, if set, is called with the outcome of every submission,
+	// including the first one, as it's made. The attempts are also returned
+	// in the *FeePolicyError of the transactions which are not accepted.
+	OnAttempt func(SubmissionAttempt)
+}
+
+// SubmissionAttempt is the outcome of a submission made by a FeePolicy.
+type SubmissionAttempt struct {
+	// BaseFee is the base fee of the submitted transaction.
+	BaseFee int64
+	// FeeBump is true when the submitted transaction is a fee bump
+	// transaction.
+	FeeBump bool
+	// Hash is the hash of the submitted transaction.
+	Hash        string
+	Transaction hProtocol.Transaction
+	Err         error
+	// FeeStatsErr is the error returned by FeeStats when the fee bump
+	// transaction was built without fee stats.
+	FeeStatsErr error
+}
+
+// submittable is implemented by txnbuild.Transaction and
+// txnbuild.FeeBumpTransaction.
+type submittable interface {
+	Base64() (string, error)
+	HashHex(network string) (string, error)
+	BaseFee() int64
+}
+
+func (p *FeePolicy) validate() error {
+	if p.FeeAccount == nil {
+		return errors.New("fee policy requires a fee account")
+	}
+	if p.MaxBaseFee < txnbuild.MinBaseFee {
+		return errors.Errorf("fee policy max base fee cannot be lower than network minimum of %d", txnbuild.MinBaseFee)
+	}
+	for _, percentile := range p.percentiles() {
+		if _, err := feePercentile(hProtocol.FeeDistribution{}, percentile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *FeePolicy) percentiles() []int {
+	if len(p.Percentiles) == 0 {
+		return DefaultFeePercentiles
+	}
+	return p.Percentiles
+}
+
+// schedule returns the base fees of the fee bump transactions.
+func (p *FeePolicy) schedule(stats hProtocol.FeeStats) []int64 {
+	fees := make([]int64, 0, len(p.percentiles())+1)
+	for _, percentile := range p.percentiles() {
+		fee, _ := feePercentile(stats.MaxFee, percentile)
+		if fee > p.MaxBaseFee {
+			break
+		}
+		fees = append(fees, fee)
+	}
+	return append(fees, p.MaxBaseFee)
+}
+
+func feePercentile(distribution hProtocol.FeeDistribution, percentile int) (int64, error) {
+	switch percentile {
+	case 10:
+		return distribution.P10, nil
+	case 20:
+		return distribution.P20, nil
+	case 30:
+		return distribution.P30, nil
+	case 40:
+		return distribution.P40, nil
+	case 50:
+		return distribution.P50, nil
+	case 60:
+		return distribution.P60, nil
+	case 70:
+		return distribution.P70, nil
+	case 80:
+		return distribution.P80, nil
+	case 90:
+		return distribution.P90, nil
+	case 95:
+		return distribution.P95, nil
+	case 99:
+		return distribution.P99, nil
+	case 100:
+		return distribution.Max, nil
+	}
+	return 0, errors.Errorf("unsupported fee percentile %d", percentile)
+}
+
+func isInsufficientFee(err error) bool {
+	hErr := GetError(err)
+	if hErr == nil {
+		return false
+	}
+	codes, err := hErr.ResultCodes()
+	return err == nil && codes.TransactionCode == "tx_insufficient_fee"
+}
+
+// FeePolicyError is returned when a transaction submitted with a FeePolicy is
+// not accepted. Its cause is the error of the last attempt, so GetError
+// returns the error sent by Aurora, if any.
+type FeePolicyError struct {
+	// Attempts are the submissions made, in order.
+	Attempts []SubmissionAttempt
+	// FeeStatsErr is the error returned by FeeStats, in which case the
+	// transaction was only bumped to MaxBaseFee.
+	FeeStatsErr error
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e *FeePolicyError) Error() string {
+	msg := fmt.Sprintf("transaction not accepted after %d attempts: %s", len(e.Attempts), e.Err)
+	if e.FeeStatsErr != nil {
+		msg += fmt.Sprintf(" (fee stats unavailable: %s)", e.FeeStatsErr)
+	}
+	return msg
+}
+
+// Cause returns the error of the last attempt.
+func (e *FeePolicyError) Cause() error {
+	return e.Err
+}
+
+// submitWithFeePolicy submits transaction and wraps inner in fee bump
+// transactions with increasing fees while the submissions fail with
+// tx_insufficient_fee. It returns the outcome of the last submission, or a
+// *FeePolicyError with all the attempts if the transaction was not accepted.
+func (c *Client) submitWithFeePolicy(transaction submittable, inner *txnbuild.Transaction, policy *FeePolicy) (hProtocol.Transaction, error) {
+	if err := policy.validate(); err != nil {
+		return hProtocol.Transaction{}, err
+	}
+
+	result := &FeePolicyError{}
+	_, feeBump := transaction.(*txnbuild.FeeBumpTransaction)
+	attempt := c.submitAttempt(transaction, feeBump, policy, nil)
+	result.Attempts = append(result.Attempts, attempt)
+	if attempt.Err == nil {
+		return attempt.Transaction, nil
+	}
+	if !isInsufficientFee(attempt.Err) {
+		result.Err = attempt.Err
+		return attempt.Transaction, result
+	}
+
+	// without fee stats the transaction is only bumped to MaxBaseFee
+	stats, err := c.FeeStats()
+	if err != nil {
+		result.FeeStatsErr = errors.Wrap(err, "could not load fee stats")
+	}
+
+	lastFee := transaction.BaseFee()
+	for _, fee := range policy.schedule(stats) {
+		if fee <= lastFee {
+			continue
+		}
+		lastFee = fee
+
+		bumped, err := txnbuild.NewFeeBumpTransaction(txnbuild.FeeBumpTransactionParams{
+			Inner:      inner,
+			FeeAccount: policy.FeeAccount.Address(),
+			BaseFee:    fee,
+		})
+		if err != nil {
+			result.Err = errors.Wrap(err, "could not build fee bump transaction")
+			return hProtocol.Transaction{}, result
+		}
+		if bumped, err = bumped.Sign(policy.NetworkPassphrase, policy.FeeAccount); err != nil {
+			result.Err = errors.Wrap(err, "could not sign fee bump transaction")
+			return hProtocol.Transaction{}, result
+		}
+
+		attempt = c.submitAttempt(bumped, true, policy, result.FeeStatsErr)
+		result.Attempts = append(result.Attempts, attempt)
+		if attempt.Err == nil {
+			return attempt.Transaction, nil
+		}
+		if !isInsufficientFee(attempt.Err) {
+			break
+		}
+	}
+	result.Err = attempt.Err
+	return attempt.Transaction, result
+}
+
+func (c *Client) submitAttempt(transaction submittable, feeBump bool, policy *FeePolicy, feeStatsErr error) SubmissionAttempt {
+	attempt := SubmissionAttempt{
+		BaseFee:     transaction.BaseFee(),
+		FeeBump:     feeBump,
+		FeeStatsErr: feeStatsErr,
+	}
+	attempt.Hash, _ = transaction.HashHex(policy.NetworkPassphrase)
+
+	txeBase64, err := transaction.Base64()
+	if err != nil {
+		attempt.Err = errors.Wrap(err, "Unable to convert transaction object to base64 string")
+	} else {
+		attempt.Transaction, attempt.Err = c.SubmitTransactionXDR(txeBase64)
+	}
+	if policy.OnAttempt != nil {
+		policy.OnAttempt(attempt)
+	}
+	return attempt
+}
@@ -0,0 +1,250 @@
+package auroraclient
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/keypair"
+	"github.com/diamnet/go/network"
+	"github.com/diamnet/go/support/http/httptest"
+	"github.com/diamnet/go/txnbuild"
+)
+
+var insufficientFeeFailure = `{
+  "type": "https://diamnet.org/aurora-errors/transaction_failed",
+  "title": "Transaction Failed",
+  "status": 400,
+  "extras": {
+    "result_codes": {
+      "transaction": "tx_insufficient_fee"
+    }
+  }
+}`
+
+func newFeePolicyTransaction(t *testing.T, source *keypair.Full) *txnbuild.Transaction {
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &txnbuild.SimpleAccount{AccountID: source.Address(), Sequence: 100},
+		IncrementSequenceNum: true,
+		Operations: []txnbuild.Operation{&txnbuild.BumpSequence{
+			BumpTo: 200,
+		}},
+		BaseFee:    txnbuild.MinBaseFee,
+		Timebounds: txnbuild.NewInfiniteTimeout(),
+	})
+	require.NoError(t, err)
+	tx, err = tx.Sign(network.TestNetworkPassphrase, source)
+	require.NoError(t, err)
+	return tx
+}
+
+// respondToSubmissions responds to the submitted transactions with
+// tx_insufficient_fee until the base fee is at least includedFee, and records
+// the submitted transactions.
+func respondToSubmissions(t *testing.T, includedFee int64, submitted *[]*txnbuild.GenericTransaction) httpmock.Responder {
+	return func(request *http.Request) (*http.Response, error) {
+		tx, err := txnbuild.TransactionFromXDR(request.FormValue("tx"))
+		require.NoError(t, err)
+		*submitted = append(*submitted, tx)
+
+		baseFee := int64(0)
+		if feeBump, ok := tx.FeeBump(); ok {
+			baseFee = feeBump.BaseFee()
+		} else if simple, ok := tx.Transaction(); ok {
+			baseFee = simple.BaseFee()
+		}
+		if baseFee < includedFee {
+			return httpmock.NewStringResponse(400, insufficientFeeFailure), nil
+		}
+		return httpmock.NewStringResponse(200, txSuccess), nil
+	}
+}
+
+func TestSubmitTransactionWithFeePolicy(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		AuroraURL: "https://localhost/",
+		HTTP:      hmock,
+	}
+	source, feeAccount := keypair.MustRandom(), keypair.MustRandom()
+	tx := newFeePolicyTransaction(t, source)
+
+	var submitted []*txnbuild.GenericTransaction
+	hmock.On("POST", "https://localhost/transactions").Return(respondToSubmissions(t, 2000, &submitted))
+	hmock.On("GET", "https://localhost/fee_stats").ReturnString(200, feesResponse)
+
+	var attempts []SubmissionAttempt
+	resp, err := client.SubmitTransactionWithOptions(tx, SubmitTxOpts{
+		SkipMemoRequiredCheck: true,
+		FeePolicy: &FeePolicy{
+			NetworkPassphrase: network.TestNetworkPassphrase,
+			FeeAccount:        feeAccount,
+			MaxBaseFee:        3000,
+			OnAttempt: func(attempt SubmissionAttempt) {
+				attempts = append(attempts, attempt)
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Successful)
+
+	// the transaction is bumped to the 50th and 70th percentiles of the max fees
+	require.Len(t, attempts, 3)
+	require.Len(t, submitted, 3)
+	assert.Equal(t, int64(100), attempts[0].BaseFee)
+	assert.False(t, attempts[0].FeeBump)
+	assert.True(t, isInsufficientFee(attempts[0].Err))
+	hash, err := tx.HashHex(network.TestNetworkPassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, hash, attempts[0].Hash)
+
+	for i, fee := range []int64{500, 2000} {
+		attempt := attempts[i+1]
+		assert.Equal(t, fee, attempt.BaseFee)
+		assert.True(t, attempt.FeeBump)
+
+		feeBump, ok := submitted[i+1].FeeBump()
+		require.True(t, ok)
+		assert.Equal(t, feeAccount.Address(), feeBump.FeeAccount())
+		assert.Equal(t, tx.Signatures(), feeBump.InnerTransaction().Signatures())
+		assert.Len(t, feeBump.Signatures(), 1)
+		hash, err := feeBump.HashHex(network.TestNetworkPassphrase)
+		require.NoError(t, err)
+		assert.Equal(t, hash, attempt.Hash)
+	}
+	assert.True(t, isInsufficientFee(attempts[1].Err))
+	assert.NoError(t, attempts[2].Err)
+	assert.True(t, attempts[2].Transaction.Successful)
+}
+
+func TestSubmitTransactionWithFeePolicyBudgetExhausted(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		AuroraURL: "https://localhost/",
+		HTTP:      hmock,
+	}
+	source, feeAccount := keypair.MustRandom(), keypair.MustRandom()
+	tx := newFeePolicyTransaction(t, source)
+
+	var submitted []*txnbuild.GenericTransaction
+	hmock.On("POST", "https://localhost/transactions").Return(respondToSubmissions(t, 10000, &submitted))
+	hmock.On("GET", "https://localhost/fee_stats").ReturnString(200, feesResponse)
+
+	var fees []int64
+	_, err := client.SubmitTransactionWithOptions(tx, SubmitTxOpts{
+		SkipMemoRequiredCheck: true,
+		FeePolicy: &FeePolicy{
+			NetworkPassphrase: network.TestNetworkPassphrase,
+			FeeAccount:        feeAccount,
+			MaxBaseFee:        3000,
+			Percentiles:       []int{10, 50, 90},
+			OnAttempt: func(attempt SubmissionAttempt) {
+				fees = append(fees, attempt.BaseFee)
+			},
+		},
+	})
+	codes, codesErr := GetError(err).ResultCodes()
+	require.NoError(t, codesErr)
+	assert.Equal(t, "tx_insufficient_fee", codes.TransactionCode)
+
+	// the 90th percentile exceeds the budget, the last attempt is made with
+	// the max base fee
+	assert.Equal(t, []int64{100, 150, 500, 3000}, fees)
+	assert.Len(t, submitted, 4)
+
+	policyErr, ok := err.(*FeePolicyError)
+	require.True(t, ok)
+	require.Len(t, policyErr.Attempts, 4)
+	for i, attempt := range policyErr.Attempts {
+		assert.Equal(t, fees[i], attempt.BaseFee)
+		assert.True(t, isInsufficientFee(attempt.Err))
+	}
+	assert.NoError(t, policyErr.FeeStatsErr)
+	assert.EqualError(t, err, "transaction not accepted after 4 attempts: "+policyErr.Err.Error())
+}
+
+func TestSubmitTransactionWithFeePolicyFeeStatsFailure(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		AuroraURL: "https://localhost/",
+		HTTP:      hmock,
+	}
+	tx := newFeePolicyTransaction(t, keypair.MustRandom())
+
+	var submitted []*txnbuild.GenericTransaction
+	hmock.On("POST", "https://localhost/transactions").Return(respondToSubmissions(t, 10000, &submitted))
+	hmock.On("GET", "https://localhost/fee_stats").ReturnString(500, "")
+
+	_, err := client.SubmitTransactionWithOptions(tx, SubmitTxOpts{
+		SkipMemoRequiredCheck: true,
+		FeePolicy: &FeePolicy{
+			NetworkPassphrase: network.TestNetworkPassphrase,
+			FeeAccount:        keypair.MustRandom(),
+			MaxBaseFee:        3000,
+		},
+	})
+
+	// without fee stats the transaction is bumped to the max base fee
+	policyErr, ok := err.(*FeePolicyError)
+	require.True(t, ok)
+	require.Len(t, policyErr.Attempts, 2)
+	assert.Equal(t, int64(3000), policyErr.Attempts[1].BaseFee)
+	assert.Error(t, policyErr.FeeStatsErr)
+	assert.Equal(t, policyErr.FeeStatsErr, policyErr.Attempts[1].FeeStatsErr)
+	assert.Contains(t, err.Error(), "fee stats unavailable: could not load fee stats")
+	assert.Len(t, submitted, 2)
+}
+
+func TestSubmitTransactionWithFeePolicyOtherFailure(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		AuroraURL: "https://localhost/",
+		HTTP:      hmock,
+	}
+	tx := newFeePolicyTransaction(t, keypair.MustRandom())
+
+	hmock.On("POST", "https://localhost/transactions").ReturnString(400, transactionFailure)
+
+	attempts := 0
+	_, err := client.SubmitTransactionWithOptions(tx, SubmitTxOpts{
+		SkipMemoRequiredCheck: true,
+		FeePolicy: &FeePolicy{
+			NetworkPassphrase: network.TestNetworkPassphrase,
+			FeeAccount:        keypair.MustRandom(),
+			MaxBaseFee:        3000,
+			OnAttempt: func(SubmissionAttempt) {
+				attempts++
+			},
+		},
+	})
+	codes, codesErr := GetError(err).ResultCodes()
+	require.NoError(t, codesErr)
+	assert.Equal(t, "tx_no_source_account", codes.TransactionCode)
+	assert.Equal(t, 1, attempts)
+	require.IsType(t, &FeePolicyError{}, err)
+	assert.Len(t, err.(*FeePolicyError).Attempts, 1)
+}
+
+func TestFeePolicyValidation(t *testing.T) {
+	client := &Client{AuroraURL: "https://localhost/", HTTP: httptest.NewClient()}
+	tx := newFeePolicyTransaction(t, keypair.MustRandom())
+	opts := func(policy FeePolicy) SubmitTxOpts {
+		return SubmitTxOpts{SkipMemoRequiredCheck: true, FeePolicy: &policy}
+	}
+
+	_, err := client.SubmitTransactionWithOptions(tx, opts(FeePolicy{MaxBaseFee: 1000}))
+	assert.EqualError(t, err, "fee policy requires a fee account")
+
+	_, err = client.SubmitTransactionWithOptions(tx, opts(FeePolicy{FeeAccount: keypair.MustRandom()}))
+	assert.EqualError(t, err, "fee policy max base fee cannot be lower than network minimum of 100")
+
+	_, err = client.SubmitTransactionWithOptions(tx, opts(FeePolicy{
+		FeeAccount:  keypair.MustRandom(),
+		MaxBaseFee:  1000,
+		Percentiles: []int{50, 75},
+	}))
+	assert.EqualError(t, err, "unsupported fee percentile 75")
+}
@@ -0,0 +1,221 @@
+package auroraclient
+
+import (
+	"context"
+
+	hProtocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/protocols/aurora/effects"
+	"github.com/diamnet/go/protocols/aurora/operations"
+)
+
+// pageIterator walks through the records of the pages of a collection. load
+// loads the first page when first is true and the next page otherwise, and
+// returns the number of records of the loaded page.
+type pageIterator struct {
+	ctx     context.Context
+	load    func(first bool) (int, error)
+	index   int
+	count   int
+	started bool
+	done    bool
+	err     error
+}
+
+func newPageIterator(ctx context.Context, load func(first bool) (int, error)) pageIterator {
+	return pageIterator{ctx: ctx, load: load, index: -1}
+}
+
+// Next advances the iterator to the next record, loading the next page when
+// the records of the current page are exhausted. It returns false when there
+// are no more records or when an error occurred, see Err.
+func (it *pageIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if it.index+1 < it.count {
+		it.index++
+		return true
+	}
+
+	if err := it.ctx.Err(); err != nil {
+		it.done, it.err = true, err
+		return false
+	}
+	count, err := it.load(!it.started)
+	it.started = true
+	if err != nil {
+		it.done, it.err = true, err
+		return false
+	}
+	if count == 0 {
+		it.done = true
+		return false
+	}
+	it.index, it.count = 0, count
+	return true
+}
+
+// Err returns the error which stopped the iterator, if any.
+func (it *pageIterator) Err() error {
+	return it.err
+}
+
+// OperationsIterator iterates through operations or payments. Call Next to
+// advance to the next operation:
+//
+//	it := client.IterateOperations(ctx, request)
+//	for it.Next() {
+//		op := it.Operation()
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+type OperationsIterator struct {
+	pageIterator
+	page operations.OperationsPage
+}
+
+// Operation returns the current operation.
+func (it *OperationsIterator) Operation() operations.Operation {
+	return it.page.Embedded.Records[it.index]
+}
+
+// IterateOperations returns an iterator through the operations of request,
+// which loads the following pages until there are no more operations.
+func (c *Client) IterateOperations(ctx context.Context, request OperationRequest) *OperationsIterator {
+	it := &OperationsIterator{}
+	it.pageIterator = newPageIterator(ctx, func(first bool) (count int, err error) {
+		if first {
+			it.page, err = c.Operations(request)
+		} else {
+			it.page, err = c.NextOperationsPage(it.page)
+		}
+		return len(it.page.Embedded.Records), err
+	})
+	return it
+}
+
+// IteratePayments returns an iterator through the payments of request, which
+// loads the following pages until there are no more payments.
+func (c *Client) IteratePayments(ctx context.Context, request OperationRequest) *OperationsIterator {
+	it := &OperationsIterator{}
+	it.pageIterator = newPageIterator(ctx, func(first bool) (count int, err error) {
+		if first {
+			it.page, err = c.Payments(request)
+		} else {
+			it.page, err = c.NextPaymentsPage(it.page)
+		}
+		return len(it.page.Embedded.Records), err
+	})
+	return it
+}
+
+// TransactionsIterator iterates through transactions. See OperationsIterator
+// for an example.
+type TransactionsIterator struct {
+	pageIterator
+	page hProtocol.TransactionsPage
+}
+
+// Transaction returns the current transaction.
+func (it *TransactionsIterator) Transaction() hProtocol.Transaction {
+	return it.page.Embedded.Records[it.index]
+}
+
+// IterateTransactions returns an iterator through the transactions of
+// request, which loads the following pages until there are no more
+// transactions.
+func (c *Client) IterateTransactions(ctx context.Context, request TransactionRequest) *TransactionsIterator {
+	it := &TransactionsIterator{}
+	it.pageIterator = newPageIterator(ctx, func(first bool) (count int, err error) {
+		if first {
+			it.page, err = c.Transactions(request)
+		} else {
+			it.page, err = c.NextTransactionsPage(it.page)
+		}
+		return len(it.page.Embedded.Records), err
+	})
+	return it
+}
+
+// EffectsIterator iterates through effects. See OperationsIterator for an
+// example.
+type EffectsIterator struct {
+	pageIterator
+	page effects.EffectsPage
+}
+
+// Effect returns the current effect.
+func (it *EffectsIterator) Effect() effects.Effect {
+	return it.page.Embedded.Records[it.index]
+}
+
+// IterateEffects returns an iterator through the effects of request, which
+// loads the following pages until there are no more effects.
+func (c *Client) IterateEffects(ctx context.Context, request EffectRequest) *EffectsIterator {
+	it := &EffectsIterator{}
+	it.pageIterator = newPageIterator(ctx, func(first bool) (count int, err error) {
+		if first {
+			it.page, err = c.Effects(request)
+		} else {
+			it.page, err = c.NextEffectsPage(it.page)
+		}
+		return len(it.page.Embedded.Records), err
+	})
+	return it
+}
+
+// LedgersIterator iterates through ledgers. See OperationsIterator for an
+// example.
+type LedgersIterator struct {
+	pageIterator
+	page hProtocol.LedgersPage
+}
+
+// Ledger returns the current ledger.
+func (it *LedgersIterator) Ledger() hProtocol.Ledger {
+	return it.page.Embedded.Records[it.index]
+}
+
+// IterateLedgers returns an iterator through the ledgers of request, which
+// loads the following pages until there are no more ledgers.
+func (c *Client) IterateLedgers(ctx context.Context, request LedgerRequest) *LedgersIterator {
+	it := &LedgersIterator{}
+	it.pageIterator = newPageIterator(ctx, func(first bool) (count int, err error) {
+		if first {
+			it.page, err = c.Ledgers(request)
+		} else {
+			it.page, err = c.NextLedgersPage(it.page)
+		}
+		return len(it.page.Embedded.Records), err
+	})
+	return it
+}
+
+// TradesIterator iterates through trades. See OperationsIterator for an
+// example.
+type TradesIterator struct {
+	pageIterator
+	page hProtocol.TradesPage
+}
+
+// Trade returns the current trade.
+func (it *TradesIterator) Trade() hProtocol.Trade {
+	return it.page.Embedded.Records[it.index]
+}
+
+// IterateTrades returns an iterator through the trades of request, which
+// loads the following pages until there are no more trades.
+func (c *Client) IterateTrades(ctx context.Context, request TradeRequest) *TradesIterator {
+	it := &TradesIterator{}
+	it.pageIterator = newPageIterator(ctx, func(first bool) (count int, err error) {
+		if first {
+			it.page, err = c.Trades(request)
+		} else {
+			it.page, err = c.NextTradesPage(it.page)
+		}
+		return len(it.page.Embedded.Records), err
+	})
+	return it
+}
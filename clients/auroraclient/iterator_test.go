@@ -0,0 +1,99 @@
+package auroraclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/support/http/httptest"
+)
+
+func TestIterateLedgers(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		AuroraURL: "https://localhost/",
+		HTTP:      hmock,
+	}
+
+	hmock.On(
+		"GET",
+		"https://localhost/ledgers?limit=2",
+	).ReturnString(200, ledgerIteratorPage("8589934592", 1, 2))
+	hmock.On(
+		"GET",
+		"https://localhost/ledgers?cursor=8589934592&limit=2&order=asc",
+	).ReturnString(200, ledgerIteratorPage("12884901888", 3))
+	hmock.On(
+		"GET",
+		"https://localhost/ledgers?cursor=12884901888&limit=2&order=asc",
+	).ReturnString(200, ledgerIteratorPage("12884901888"))
+
+	it := client.IterateLedgers(context.Background(), LedgerRequest{Limit: 2})
+	var sequences []int32
+	for it.Next() {
+		sequences = append(sequences, it.Ledger().Sequence)
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []int32{1, 2, 3}, sequences)
+	assert.False(t, it.Next())
+}
+
+func TestIterateLedgersError(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		AuroraURL: "https://localhost/",
+		HTTP:      hmock,
+	}
+
+	hmock.On(
+		"GET",
+		"https://localhost/ledgers?limit=2",
+	).ReturnString(200, ledgerIteratorPage("8589934592", 1, 2))
+	hmock.On(
+		"GET",
+		"https://localhost/ledgers?cursor=8589934592&limit=2&order=asc",
+	).ReturnString(503, "")
+
+	it := client.IterateLedgers(context.Background(), LedgerRequest{Limit: 2})
+	count := 0
+	for it.Next() {
+		count++
+	}
+	assert.Equal(t, 2, count)
+	assert.Error(t, it.Err())
+
+	// the iterator stops when the context is done
+	hmock.On(
+		"GET",
+		"https://localhost/ledgers?limit=2",
+	).ReturnString(200, ledgerIteratorPage("8589934592", 1, 2))
+	ctx, cancel := context.WithCancel(context.Background())
+	it = client.IterateLedgers(ctx, LedgerRequest{Limit: 2})
+	require.True(t, it.Next())
+	require.True(t, it.Next())
+	cancel()
+	assert.False(t, it.Next())
+	assert.Equal(t, context.Canceled, it.Err())
+}
+
+func ledgerIteratorPage(nextCursor string, sequences ...int) string {
+	records := ""
+	for i, sequence := range sequences {
+		if i > 0 {
+			records += ","
+		}
+		records += ledgerRecord(sequence)
+	}
+	return `{
+  "_links": {
+    "next": {
+      "href": "https://localhost/ledgers?cursor=` + nextCursor + `&limit=2&order=asc"
+    }
+  },
+  "_embedded": {
+    "records": [` + records + `]
+  }
+}`
+}
@@ -58,6 +58,11 @@ type LedgerHandler func(hProtocol.Ledger)
 // to stop streaming or context.Background() if you want to stream indefinitely.
 // LedgerHandler is a user-supplied function that is executed for each streamed ledger received.
 func (lr LedgerRequest) StreamLedgers(ctx context.Context, client *Client,
+	handler LedgerHandler) (err error) {
+	return lr.streamLedgers(ctx, client, nil, handler)
+}
+
+func (lr LedgerRequest) streamLedgers(ctx context.Context, client *Client, opts *StreamOptions,
 	handler LedgerHandler) (err error) {
 	endpoint, err := lr.BuildURL()
 	if err != nil {
@@ -65,7 +70,7 @@ func (lr LedgerRequest) StreamLedgers(ctx context.Context, client *Client,
 	}
 
 	url := fmt.Sprintf("%s%s", client.fixAuroraURL(), endpoint)
-	return client.stream(ctx, url, func(data []byte) error {
+	return client.streamWithOptions(ctx, url, opts, func(data []byte) error {
 		var ledger hProtocol.Ledger
 		err = json.Unmarshal(data, &ledger)
 		if err != nil {
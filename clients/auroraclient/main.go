@@ -152,6 +152,9 @@ type Client struct {
 // SubmitTxOpts represents the submit transaction options
 type SubmitTxOpts struct {
 	SkipMemoRequiredCheck bool
+	// FeePolicy, if set, resubmits the transaction as a fee bump transaction
+	// with increasing fees when it fails with tx_insufficient_fee.
+	FeePolicy *FeePolicy
 }
 
 // ClientInterface contains methods implemented by the aurora client
@@ -189,6 +192,12 @@ type ClientInterface interface {
 	StreamOffers(ctx context.Context, request OfferRequest, handler OfferHandler) error
 	StreamLedgers(ctx context.Context, request LedgerRequest, handler LedgerHandler) error
 	StreamOrderBooks(ctx context.Context, request OrderBookRequest, handler OrderBookHandler) error
+	StreamTransactionsWithOptions(ctx context.Context, request TransactionRequest, opts StreamOptions, handler TransactionHandler) error
+	StreamEffectsWithOptions(ctx context.Context, request EffectRequest, opts StreamOptions, handler EffectHandler) error
+	StreamOperationsWithOptions(ctx context.Context, request OperationRequest, opts StreamOptions, handler OperationHandler) error
+	StreamPaymentsWithOptions(ctx context.Context, request OperationRequest, opts StreamOptions, handler OperationHandler) error
+	StreamLedgersWithOptions(ctx context.Context, request LedgerRequest, opts StreamOptions, handler LedgerHandler) error
+	StreamTradesWithOptions(ctx context.Context, request TradeRequest, opts StreamOptions, handler TradeHandler) error
 	Root() (hProtocol.Root, error)
 	NextAccountsPage(hProtocol.AccountsPage) (hProtocol.AccountsPage, error)
 	NextAssetsPage(hProtocol.AssetsPage) (hProtocol.AssetsPage, error)
@@ -311,11 +320,13 @@ type OfferRequest struct {
 }
 
 // OperationRequest struct contains data for getting operation details from a aurora server.
-// "ForAccount", "ForLedger", "ForTransaction": Only one of these can be set at a time. If none
+// "ForAccount", "ForMuxedAccount", "ForLedger", "ForTransaction": Only one of these can be set at a time. If none
 // are provided, the default is to return all operations.
+// "ForMuxedAccount" is the M-address of a muxed account, only its operations are returned.
 // The query parameters (Order, Cursor, Limit and IncludeFailed) are optional. All or none can be set.
 type OperationRequest struct {
 	ForAccount          string
+	ForMuxedAccount     string
 	ForClaimableBalance string
 	ForLedger           uint
 	ForLiquidityPool    string
@@ -335,11 +346,13 @@ type submitRequest struct {
 }
 
 // TransactionRequest struct contains data for getting transaction details from a aurora server.
-// "ForAccount", "ForClaimableBalance", "ForLedger": Only one of these can be set at a time.
+// "ForAccount", "ForMuxedAccount", "ForClaimableBalance", "ForLedger": Only one of these can be set at a time.
 // If none are provided, the default is to return all transactions.
+// "ForMuxedAccount" is the M-address of a muxed account, only its transactions are returned.
 // The query parameters (Order, Cursor, Limit and IncludeFailed) are optional. All or none can be set.
 type TransactionRequest struct {
 	ForAccount          string
+	ForMuxedAccount     string
 	ForClaimableBalance string
 	ForLedger           uint
 	ForLiquidityPool    string
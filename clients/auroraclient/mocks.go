@@ -205,6 +205,36 @@ func (m *MockClient) StreamOrderBooks(ctx context.Context, request OrderBookRequ
 	return m.Called(ctx, request, handler).Error(0)
 }
 
+// StreamTransactionsWithOptions is a mocking method
+func (m *MockClient) StreamTransactionsWithOptions(ctx context.Context, request TransactionRequest, opts StreamOptions, handler TransactionHandler) error {
+	return m.Called(ctx, request, opts, handler).Error(0)
+}
+
+// StreamEffectsWithOptions is a mocking method
+func (m *MockClient) StreamEffectsWithOptions(ctx context.Context, request EffectRequest, opts StreamOptions, handler EffectHandler) error {
+	return m.Called(ctx, request, opts, handler).Error(0)
+}
+
+// StreamOperationsWithOptions is a mocking method
+func (m *MockClient) StreamOperationsWithOptions(ctx context.Context, request OperationRequest, opts StreamOptions, handler OperationHandler) error {
+	return m.Called(ctx, request, opts, handler).Error(0)
+}
+
+// StreamPaymentsWithOptions is a mocking method
+func (m *MockClient) StreamPaymentsWithOptions(ctx context.Context, request OperationRequest, opts StreamOptions, handler OperationHandler) error {
+	return m.Called(ctx, request, opts, handler).Error(0)
+}
+
+// StreamLedgersWithOptions is a mocking method
+func (m *MockClient) StreamLedgersWithOptions(ctx context.Context, request LedgerRequest, opts StreamOptions, handler LedgerHandler) error {
+	return m.Called(ctx, request, opts, handler).Error(0)
+}
+
+// StreamTradesWithOptions is a mocking method
+func (m *MockClient) StreamTradesWithOptions(ctx context.Context, request TradeRequest, opts StreamOptions, handler TradeHandler) error {
+	return m.Called(ctx, request, opts, handler).Error(0)
+}
+
 // Root is a mocking method
 func (m *MockClient) Root() (hProtocol.Root, error) {
 	a := m.Called()
@@ -14,7 +14,7 @@ import (
 // BuildURL creates the endpoint to be queried based on the data in the OperationRequest struct.
 // If no data is set, it defaults to the build the URL for all operations or all payments; depending on thevalue of `op.endpoint`
 func (op OperationRequest) BuildURL() (endpoint string, err error) {
-	nParams := countParams(op.ForAccount, op.ForLedger, op.ForLiquidityPool, op.forOperationID, op.ForTransaction)
+	nParams := countParams(op.ForAccount, op.ForMuxedAccount, op.ForLedger, op.ForLiquidityPool, op.forOperationID, op.ForTransaction)
 
 	if nParams > 1 {
 		return endpoint, errors.New("invalid request: too many parameters")
@@ -28,6 +28,9 @@ func (op OperationRequest) BuildURL() (endpoint string, err error) {
 	if op.ForAccount != "" {
 		endpoint = fmt.Sprintf("accounts/%s/%s", op.ForAccount, op.endpoint)
 	}
+	if op.ForMuxedAccount != "" {
+		endpoint = fmt.Sprintf("accounts/%s/%s", op.ForMuxedAccount, op.endpoint)
+	}
 	if op.ForClaimableBalance != "" {
 		endpoint = fmt.Sprintf("claimable_balances/%s/%s", op.ForClaimableBalance, op.endpoint)
 	}
@@ -97,13 +100,18 @@ type OperationHandler func(operations.Operation)
 // stream indefinitely. OperationHandler is a user-supplied function that is executed for each streamed
 // operation received.
 func (op OperationRequest) StreamOperations(ctx context.Context, client *Client, handler OperationHandler) error {
+	return op.streamOperations(ctx, client, nil, handler)
+}
+
+func (op OperationRequest) streamOperations(ctx context.Context, client *Client, opts *StreamOptions,
+	handler OperationHandler) error {
 	endpoint, err := op.BuildURL()
 	if err != nil {
 		return errors.Wrap(err, "unable to build endpoint for operation request")
 	}
 
 	url := fmt.Sprintf("%s%s", client.fixAuroraURL(), endpoint)
-	return client.stream(ctx, url, func(data []byte) error {
+	return client.streamWithOptions(ctx, url, opts, func(data []byte) error {
 		var baseRecord operations.Base
 
 		if err = json.Unmarshal(data, &baseRecord); err != nil {
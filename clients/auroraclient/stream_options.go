@@ -0,0 +1,249 @@
+package auroraclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultStreamMinBackoff is the delay before reconnecting a stream after
+	// the first failed connection attempt by default.
+	DefaultStreamMinBackoff = time.Second
+	// DefaultStreamMaxBackoff is the longest delay between two connection
+	// attempts of a stream by default.
+	DefaultStreamMaxBackoff = time.Minute
+)
+
+// CheckpointStore persists the cursor of a stream so that the stream resumes
+// from the last event handled after a restart. Use a different store for
+// every stream.
+type CheckpointStore interface {
+	// LoadCursor returns the saved cursor, or an empty string if there is
+	// none.
+	LoadCursor(ctx context.Context) (string, error)
+	// SaveCursor saves the paging token of the last event handled.
+	SaveCursor(ctx context.Context, cursor string) error
+}
+
+// StreamOptions configures the Stream*WithOptions methods.
+//
+// The paging token of an event is saved to Checkpoint once the handler of the
+// event returns, so an event can be handled again after a crash but is never
+// skipped. The stream starts at the saved cursor if there is one, or at the
+// cursor of the request otherwise.
+//
+// The stream is resumed with jittered exponential backoff when the connection
+// fails or Aurora responds with a server error. Aurora closing the connection
+// is not a failure, the stream is resumed right away.
+type StreamOptions struct {
+	Checkpoint CheckpointStore
+	// MinBackoff is the delay before reconnecting after the first failed
+	// attempt, DefaultStreamMinBackoff if 0. It doubles after every
+	// consecutive failure, up to MaxBackoff.
+	MinBackoff time.Duration
+	// MaxBackoff is the longest delay between two attempts,
+	// DefaultStreamMaxBackoff if 0.
+	MaxBackoff time.Duration
+	// MaxRetries is the number of consecutive failed attempts after which the
+	// stream returns the error of the last attempt. Streams are retried
+	// forever if 0.
+	MaxRetries int
+	// OnDuplicate is called with the paging token of the events which are not
+	// after the last event handled. These events are not passed to the
+	// handler.
+	OnDuplicate func(pagingToken string)
+	// OnGap is called with the paging tokens of two consecutive events when
+	// events are missing between them. The second event is still passed to the
+	// handler; the missing records can be fetched with the Iterate* methods.
+	// Gaps are detected from the order of the records in the paging tokens of
+	// ledger streams, of transaction and operation streams including the
+	// failed transactions, and of effect streams. They can't be detected in
+	// the streams filtered by account, claimable balance or liquidity pool,
+	// nor in payment and trade streams, which only contain some of the records.
+	OnGap func(previous, next string)
+
+	// isGap reports whether events are missing between two paging tokens, it's
+	// set by the streams which can detect gaps.
+	isGap func(previous, next string) bool
+}
+
+func (o *StreamOptions) withDefaults() *StreamOptions {
+	opts := *o
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = DefaultStreamMinBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultStreamMaxBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = opts.MinBackoff
+	}
+	return &opts
+}
+
+// backoff returns the delay before the next attempt after failures
+// consecutive failed attempts, picked at random between half and all of the
+// exponential backoff so that clients don't reconnect in lockstep.
+func (o *StreamOptions) backoff(failures int) time.Duration {
+	delay := o.MaxBackoff
+	if failures < 32 {
+		if d := o.MinBackoff << uint(failures-1); d > 0 && d < delay {
+			delay = d
+		}
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// transientStreamError is an error after which a stream is resumed when it's
+// streamed with options.
+type transientStreamError struct {
+	err error
+}
+
+func (e transientStreamError) Error() string {
+	return e.err.Error()
+}
+
+// pagingTokenOf returns the paging token of a streamed resource, for the
+// events which don't have an ID.
+func pagingTokenOf(data []byte) string {
+	var record struct {
+		PagingToken string `json:"paging_token"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return ""
+	}
+	return record.PagingToken
+}
+
+// parsePagingToken parses paging tokens made of numbers separated by dashes,
+// like "12345" or "12345-1".
+func parsePagingToken(token string) ([]int64, bool) {
+	parts := strings.Split(token, "-")
+	values := make([]int64, len(parts))
+	for i, part := range parts {
+		value, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		values[i] = value
+	}
+	return values, true
+}
+
+// pagingTokenAfter reports whether token comes after previous in ascending
+// order. ok is false if the tokens can't be compared.
+func pagingTokenAfter(token, previous string) (after bool, ok bool) {
+	a, ok := parsePagingToken(token)
+	if !ok {
+		return false, false
+	}
+	b, ok := parsePagingToken(previous)
+	if !ok {
+		return false, false
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] > b[i], true
+		}
+	}
+	return len(a) > len(b), true
+}
+
+// recordPosition is the position of a record parsed from its paging token.
+// The paging tokens of ledgers, transactions and operations are TOIDs: the
+// ledger sequence in the 32 high bits, then the application order of the
+// transaction in 20 bits and the index of the operation in the 12 low bits.
+// The paging tokens of effects are the TOID of their operation followed by
+// their order in the operation, "TOID-order".
+type recordPosition struct {
+	ledger, transaction, operation, effect int64
+}
+
+func parseRecordPosition(token string) (recordPosition, bool) {
+	parts := strings.Split(token, "-")
+	if len(parts) > 2 {
+		return recordPosition{}, false
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return recordPosition{}, false
+	}
+	position := recordPosition{
+		ledger:      id >> 32,
+		transaction: (id >> 12) & (1<<20 - 1),
+		operation:   id & (1<<12 - 1),
+	}
+	if len(parts) == 2 {
+		if position.effect, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return recordPosition{}, false
+		}
+	}
+	return position, true
+}
+
+// gapDetector returns a function reporting whether records are missing
+// between two paging tokens according to isGap. Paging tokens which can't be
+// parsed are never reported.
+func gapDetector(isGap func(previous, next recordPosition) bool) func(previous, next string) bool {
+	return func(previous, next string) bool {
+		a, ok := parseRecordPosition(previous)
+		if !ok {
+			return false
+		}
+		b, ok := parseRecordPosition(next)
+		if !ok {
+			return false
+		}
+		return isGap(a, b)
+	}
+}
+
+// isLedgerGap reports whether ledgers are missing between two ledger paging
+// tokens.
+var isLedgerGap = gapDetector(func(previous, next recordPosition) bool {
+	return next.ledger-previous.ledger > 1
+})
+
+// isTransactionGap reports whether transactions are missing between two
+// transaction paging tokens, in the streams of all the transactions of the
+// ledgers including the failed ones: the transactions of a ledger are
+// numbered from 1 in application order. Ledgers without transactions can't
+// be told apart from missing ones, so they are not reported.
+var isTransactionGap = gapDetector(func(previous, next recordPosition) bool {
+	if next.ledger == previous.ledger {
+		return next.transaction-previous.transaction > 1
+	}
+	return next.transaction > 1
+})
+
+// isOperationGap reports whether operations are missing between two operation
+// paging tokens, in the streams of all the operations of the transactions
+// including the failed ones: every transaction has at least one operation and
+// the operations of a transaction are numbered from 1.
+var isOperationGap = gapDetector(func(previous, next recordPosition) bool {
+	switch {
+	case next.ledger == previous.ledger && next.transaction == previous.transaction:
+		return next.operation-previous.operation > 1
+	case next.ledger == previous.ledger:
+		return next.transaction-previous.transaction > 1 || next.operation > 1
+	default:
+		return next.transaction > 1 || next.operation > 1
+	}
+})
+
+// isEffectGap reports whether effects are missing between two effect paging
+// tokens, in the streams of all the effects of the operations: the effects of
+// an operation are numbered from 1. Operations without effects can't be told
+// apart from missing ones, so they are not reported.
+var isEffectGap = gapDetector(func(previous, next recordPosition) bool {
+	if next.ledger == previous.ledger && next.transaction == previous.transaction && next.operation == previous.operation {
+		return next.effect-previous.effect > 1
+	}
+	return next.effect > 1
+})
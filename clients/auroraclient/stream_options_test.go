@@ -0,0 +1,286 @@
+package auroraclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hProtocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/protocols/aurora/effects"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/http/httptest"
+)
+
+type memoryCheckpointStore struct {
+	cursor string
+	saved  []string
+}
+
+func (s *memoryCheckpointStore) LoadCursor(ctx context.Context) (string, error) {
+	return s.cursor, nil
+}
+
+func (s *memoryCheckpointStore) SaveCursor(ctx context.Context, cursor string) error {
+	s.cursor = cursor
+	s.saved = append(s.saved, cursor)
+	return nil
+}
+
+func ledgerPagingToken(sequence int) string {
+	return strconv.FormatInt(int64(sequence)<<32, 10)
+}
+
+func ledgerRecord(sequence int) string {
+	return fmt.Sprintf(`{"id":"%d","paging_token":"%s","sequence":%d}`, sequence, ledgerPagingToken(sequence), sequence)
+}
+
+// ledgerEvents returns an event stream of ledgers. The events of the even
+// sequences have an ID, the others only have a paging token.
+func ledgerEvents(sequences ...int) string {
+	events := ""
+	for _, sequence := range sequences {
+		if sequence%2 == 0 {
+			events += "id: " + ledgerPagingToken(sequence) + "\n"
+		}
+		events += "data: " + ledgerRecord(sequence) + "\n\n"
+	}
+	return events
+}
+
+// sequentialResponder responds to the successive requests with responders.
+func sequentialResponder(t *testing.T, responders ...httpmock.Responder) httpmock.Responder {
+	calls := 0
+	return func(request *http.Request) (*http.Response, error) {
+		require.Less(t, calls, len(responders), "unexpected request %s", request.URL)
+		calls++
+		return responders[calls-1](request)
+	}
+}
+
+func TestStreamLedgersWithOptions(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		AuroraURL: "https://localhost/",
+		HTTP:      hmock,
+	}
+	store := &memoryCheckpointStore{cursor: ledgerPagingToken(1)}
+
+	hmock.On(
+		"GET",
+		"https://localhost/ledgers?cursor="+ledgerPagingToken(1),
+	).Return(sequentialResponder(t,
+		httpmock.NewStringResponder(503, ""),
+		httpmock.NewStringResponder(200, ledgerEvents(1, 2)),
+	))
+	hmock.On(
+		"GET",
+		"https://localhost/ledgers?cursor="+ledgerPagingToken(2),
+	).Return(sequentialResponder(t,
+		func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection reset")
+		},
+		httpmock.NewStringResponder(200, ledgerEvents(2, 4, 5)),
+	))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		sequences  []int32
+		duplicates []string
+		gaps       [][2]string
+	)
+	err := client.StreamLedgersWithOptions(ctx, LedgerRequest{}, StreamOptions{
+		Checkpoint: store,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+		OnDuplicate: func(pagingToken string) {
+			duplicates = append(duplicates, pagingToken)
+		},
+		OnGap: func(previous, next string) {
+			gaps = append(gaps, [2]string{previous, next})
+		},
+	}, func(ledger hProtocol.Ledger) {
+		sequences = append(sequences, ledger.Sequence)
+		if ledger.Sequence == 5 {
+			cancel()
+		}
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int32{2, 4, 5}, sequences)
+	assert.Equal(t, []string{ledgerPagingToken(1), ledgerPagingToken(2)}, duplicates)
+	assert.Equal(t, [][2]string{{ledgerPagingToken(2), ledgerPagingToken(4)}}, gaps)
+	assert.Equal(t, []string{ledgerPagingToken(2), ledgerPagingToken(4), ledgerPagingToken(5)}, store.saved)
+}
+
+func TestStreamWithOptionsGivesUp(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		AuroraURL: "https://localhost/",
+		HTTP:      hmock,
+	}
+
+	hmock.On(
+		"GET",
+		"https://localhost/transactions?cursor=now",
+	).ReturnString(503, "")
+
+	err := client.StreamTransactionsWithOptions(context.Background(), TransactionRequest{}, StreamOptions{
+		MinBackoff: time.Millisecond,
+		MaxRetries: 2,
+	}, func(hProtocol.Transaction) {
+		t.Fatal("unexpected transaction")
+	})
+	assert.EqualError(t, err, "giving up after 3 attempts: got bad HTTP status code 503")
+
+	// client errors are not retried
+	hmock.On(
+		"GET",
+		"https://localhost/transactions?cursor=now",
+	).ReturnString(400, "")
+	err = client.StreamTransactionsWithOptions(context.Background(), TransactionRequest{}, StreamOptions{
+		MinBackoff: time.Millisecond,
+	}, func(hProtocol.Transaction) {
+		t.Fatal("unexpected transaction")
+	})
+	assert.EqualError(t, err, "got bad HTTP status code 400")
+}
+
+func TestStreamBackoff(t *testing.T) {
+	opts := (&StreamOptions{MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}).withDefaults()
+	for failures, max := range map[int]time.Duration{
+		1:  10 * time.Millisecond,
+		2:  20 * time.Millisecond,
+		3:  40 * time.Millisecond,
+		4:  50 * time.Millisecond,
+		40: 50 * time.Millisecond,
+	} {
+		for i := 0; i < 10; i++ {
+			delay := opts.backoff(failures)
+			assert.True(t, delay >= max/2 && delay <= max, "backoff(%d) = %s", failures, delay)
+		}
+	}
+}
+
+func TestPagingTokenAfter(t *testing.T) {
+	for _, tc := range []struct {
+		token, previous string
+		after, ok       bool
+	}{
+		{"2", "1", true, true},
+		{"1", "1", false, true},
+		{"1", "2", false, true},
+		{"10-2", "10-1", true, true},
+		{"10-1", "10-2", false, true},
+		{"11-1", "10-2", true, true},
+		{"now", "10", false, false},
+	} {
+		after, ok := pagingTokenAfter(tc.token, tc.previous)
+		assert.Equal(t, tc.after, after, "%s after %s", tc.token, tc.previous)
+		assert.Equal(t, tc.ok, ok, "%s after %s", tc.token, tc.previous)
+	}
+}
+
+func effectRecord(pagingToken string) string {
+	return fmt.Sprintf(`{"id":"%s","paging_token":"%s","type":"account_credited","type_i":2}`, pagingToken, pagingToken)
+}
+
+func TestStreamEffectsWithOptions(t *testing.T) {
+	hmock := httptest.NewClient()
+	client := &Client{
+		AuroraURL: "https://localhost/",
+		HTTP:      hmock,
+	}
+	operation := int64(10)<<32 | 1<<12 | 1
+	token := func(operation int64, order int) string {
+		return fmt.Sprintf("%d-%d", operation, order)
+	}
+
+	events := ""
+	for _, pagingToken := range []string{
+		token(operation, 1),
+		token(operation, 2),
+		token(operation, 1),
+		token(operation, 4),
+		token(operation+1, 1),
+		token(operation+2, 2),
+	} {
+		events += "data: " + effectRecord(pagingToken) + "\n\n"
+	}
+	hmock.On("GET", "https://localhost/effects?cursor=now").ReturnString(200, events)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		handled    []string
+		duplicates []string
+		gaps       [][2]string
+	)
+	err := client.StreamEffectsWithOptions(ctx, EffectRequest{}, StreamOptions{
+		OnDuplicate: func(pagingToken string) {
+			duplicates = append(duplicates, pagingToken)
+		},
+		OnGap: func(previous, next string) {
+			gaps = append(gaps, [2]string{previous, next})
+		},
+	}, func(effect effects.Effect) {
+		handled = append(handled, effect.PagingToken())
+		if len(handled) == 5 {
+			cancel()
+		}
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		token(operation, 1),
+		token(operation, 2),
+		token(operation, 4),
+		token(operation+1, 1),
+		token(operation+2, 2),
+	}, handled)
+	assert.Equal(t, []string{token(operation, 1)}, duplicates)
+	assert.Equal(t, [][2]string{
+		{token(operation, 2), token(operation, 4)},
+		{token(operation+1, 1), token(operation+2, 2)},
+	}, gaps)
+}
+
+func TestStreamGapDetection(t *testing.T) {
+	toid := func(ledger, transaction, operation int64) string {
+		return strconv.FormatInt(ledger<<32|transaction<<12|operation, 10)
+	}
+	for _, tc := range []struct {
+		name           string
+		isGap          func(previous, next string) bool
+		previous, next string
+		gap            bool
+	}{
+		{"next ledger", isLedgerGap, toid(10, 0, 0), toid(11, 0, 0), false},
+		{"missing ledger", isLedgerGap, toid(10, 0, 0), toid(12, 0, 0), true},
+		{"next transaction", isTransactionGap, toid(10, 1, 0), toid(10, 2, 0), false},
+		{"missing transaction", isTransactionGap, toid(10, 1, 0), toid(10, 3, 0), true},
+		{"first transaction of next ledger", isTransactionGap, toid(10, 5, 0), toid(12, 1, 0), false},
+		{"missing first transaction", isTransactionGap, toid(10, 5, 0), toid(11, 2, 0), true},
+		{"next operation", isOperationGap, toid(10, 1, 1), toid(10, 1, 2), false},
+		{"missing operation", isOperationGap, toid(10, 1, 1), toid(10, 1, 3), true},
+		{"first operation of next transaction", isOperationGap, toid(10, 1, 3), toid(10, 2, 1), false},
+		{"missing transaction operations", isOperationGap, toid(10, 1, 3), toid(10, 3, 1), true},
+		{"missing first operation", isOperationGap, toid(10, 1, 3), toid(10, 2, 2), true},
+		{"first operation of next ledger", isOperationGap, toid(10, 4, 1), toid(11, 1, 1), false},
+		{"missing first operation of next ledger", isOperationGap, toid(10, 4, 1), toid(11, 1, 2), true},
+		{"next effect", isEffectGap, toid(10, 1, 1) + "-1", toid(10, 1, 1) + "-2", false},
+		{"missing effect", isEffectGap, toid(10, 1, 1) + "-1", toid(10, 1, 1) + "-3", true},
+		{"first effect of next operation", isEffectGap, toid(10, 1, 1) + "-3", toid(10, 1, 2) + "-1", false},
+		{"missing first effect", isEffectGap, toid(10, 1, 1) + "-3", toid(10, 2, 1) + "-2", true},
+		{"invalid paging token", isOperationGap, "now", toid(10, 1, 3), false},
+	} {
+		assert.Equal(t, tc.gap, tc.isGap(tc.previous, tc.next), tc.name)
+	}
+}
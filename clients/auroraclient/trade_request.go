@@ -85,6 +85,11 @@ type TradeHandler func(hProtocol.Trade)
 // trades for an offer. Use context.WithCancel to stop streaming or context.Background() if you want
 // to stream indefinitely. TradeHandler is a user-supplied function that is executed for each streamed trade received.
 func (tr TradeRequest) StreamTrades(ctx context.Context, client *Client,
+	handler TradeHandler) (err error) {
+	return tr.streamTrades(ctx, client, nil, handler)
+}
+
+func (tr TradeRequest) streamTrades(ctx context.Context, client *Client, opts *StreamOptions,
 	handler TradeHandler) (err error) {
 	endpoint, err := tr.BuildURL()
 	if err != nil {
@@ -93,7 +98,7 @@ func (tr TradeRequest) StreamTrades(ctx context.Context, client *Client,
 
 	url := fmt.Sprintf("%s%s", client.fixAuroraURL(), endpoint)
 
-	return client.stream(ctx, url, func(data []byte) error {
+	return client.streamWithOptions(ctx, url, opts, func(data []byte) error {
 		var trade hProtocol.Trade
 		err = json.Unmarshal(data, &trade)
 		if err != nil {
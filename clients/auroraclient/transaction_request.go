@@ -14,7 +14,7 @@ import (
 // BuildURL creates the endpoint to be queried based on the data in the TransactionRequest struct.
 // If no data is set, it defaults to the build the URL for all transactions
 func (tr TransactionRequest) BuildURL() (endpoint string, err error) {
-	nParams := countParams(tr.ForAccount, tr.ForLedger, tr.ForLiquidityPool, tr.forTransactionHash)
+	nParams := countParams(tr.ForAccount, tr.ForMuxedAccount, tr.ForLedger, tr.ForLiquidityPool, tr.forTransactionHash)
 
 	if nParams > 1 {
 		return endpoint, errors.New("invalid request: too many parameters")
@@ -24,6 +24,9 @@ func (tr TransactionRequest) BuildURL() (endpoint string, err error) {
 	if tr.ForAccount != "" {
 		endpoint = fmt.Sprintf("accounts/%s/transactions", tr.ForAccount)
 	}
+	if tr.ForMuxedAccount != "" {
+		endpoint = fmt.Sprintf("accounts/%s/transactions", tr.ForMuxedAccount)
+	}
 	if tr.ForClaimableBalance != "" {
 		endpoint = fmt.Sprintf("claimable_balances/%s/transactions", tr.ForClaimableBalance)
 	}
@@ -67,6 +70,11 @@ type TransactionHandler func(hProtocol.Transaction)
 // StreamTransactions streams executed transactions. It can be used to stream all transactions and  transactions for an account. Use context.WithCancel to stop streaming or context.Background() if you want
 // to stream indefinitely. TransactionHandler is a user-supplied function that is executed for each streamed transaction received.
 func (tr TransactionRequest) StreamTransactions(ctx context.Context, client *Client,
+	handler TransactionHandler) (err error) {
+	return tr.streamTransactions(ctx, client, nil, handler)
+}
+
+func (tr TransactionRequest) streamTransactions(ctx context.Context, client *Client, opts *StreamOptions,
 	handler TransactionHandler) (err error) {
 	endpoint, err := tr.BuildURL()
 	if err != nil {
@@ -75,7 +83,7 @@ func (tr TransactionRequest) StreamTransactions(ctx context.Context, client *Cli
 
 	url := fmt.Sprintf("%s%s", client.fixAuroraURL(), endpoint)
 
-	return client.stream(ctx, url, func(data []byte) error {
+	return client.streamWithOptions(ctx, url, opts, func(data []byte) error {
 		var transaction hProtocol.Transaction
 		err = json.Unmarshal(data, &transaction)
 		if err != nil {
@@ -0,0 +1,391 @@
+package auroraclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	hProtocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/protocols/aurora/effects"
+	"github.com/diamnet/go/protocols/aurora/operations"
+	"github.com/diamnet/go/support/errors"
+)
+
+// webSocketReconnectDelay is the time to wait before reconnecting after the
+// WebSocket connection was lost.
+const webSocketReconnectDelay = time.Second
+
+// AccountHandler is a function that is called when an account is updated
+type AccountHandler func(hProtocol.Account)
+
+// StreamErrorHandler is a function that is called when Aurora closes a
+// stream of a WebSocketStream because of an error.
+type StreamErrorHandler func(stream string, err error)
+
+// WebSocketStream streams several Aurora endpoints over a single WebSocket
+// connection. Every stream is identified by a name chosen when subscribing
+// to it. Subscribe to streams with the Subscribe* methods (before or after
+// calling Run) and call Run to receive the events.
+//
+// Like the Stream* methods, streams start at the cursor of the request, or
+// `now` when it's not set. If the connection is lost, Run reconnects and
+// resumes every stream from the last event received.
+type WebSocketStream struct {
+	// ErrorHandler is called when Aurora closes a stream because of an
+	// error. The stream is unsubscribed.
+	ErrorHandler StreamErrorHandler
+
+	client *Client
+
+	// lock protects conn and subscriptions. It's also held when writing to
+	// conn, which doesn't support concurrent writers.
+	lock          sync.Mutex
+	conn          *websocket.Conn
+	subscriptions map[string]*webSocketSubscription
+}
+
+type webSocketSubscription struct {
+	path    *url.URL
+	handler func(data []byte) error
+}
+
+// NewWebSocketStream returns a WebSocketStream connecting to the `/ws`
+// endpoint of the Aurora server.
+func (c *Client) NewWebSocketStream() *WebSocketStream {
+	return &WebSocketStream{
+		client:        c,
+		subscriptions: map[string]*webSocketSubscription{},
+	}
+}
+
+// Subscribe subscribes to the stream of the endpoint of request. The handler
+// is called with the JSON representation of every resource streamed.
+func (s *WebSocketStream) Subscribe(stream string, request AuroraRequest, handler func(data []byte) error) error {
+	endpoint, err := request.BuildURL()
+	if err != nil {
+		return errors.Wrap(err, "unable to build endpoint")
+	}
+
+	path, err := url.Parse("/" + endpoint)
+	if err != nil {
+		return errors.Wrap(err, "error parsing endpoint")
+	}
+	query := path.Query()
+	if query.Get("cursor") == "" {
+		query.Set("cursor", "now")
+	}
+	path.RawQuery = query.Encode()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, exists := s.subscriptions[stream]; exists {
+		return errors.Errorf("stream %s is already subscribed", stream)
+	}
+	subscription := &webSocketSubscription{path: path, handler: handler}
+	s.subscriptions[stream] = subscription
+
+	if s.conn != nil {
+		return s.send(hProtocol.WebSocketRequest{
+			Action: hProtocol.WebSocketActionSubscribe,
+			Stream: stream,
+			Path:   subscription.path.String(),
+		})
+	}
+	return nil
+}
+
+// Unsubscribe unsubscribes from a stream.
+func (s *WebSocketStream) Unsubscribe(stream string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, exists := s.subscriptions[stream]; !exists {
+		return errors.Errorf("stream %s is not subscribed", stream)
+	}
+	delete(s.subscriptions, stream)
+
+	if s.conn != nil {
+		return s.send(hProtocol.WebSocketRequest{
+			Action: hProtocol.WebSocketActionUnsubscribe,
+			Stream: stream,
+		})
+	}
+	return nil
+}
+
+// Cursor returns the paging token of the last event received on a stream. It
+// returns an empty string for streams of single resources (like accounts or
+// order books).
+func (s *WebSocketStream) Cursor(stream string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if subscription, exists := s.subscriptions[stream]; exists {
+		return subscription.path.Query().Get("cursor")
+	}
+	return ""
+}
+
+// SubscribeAccount streams the updates of an account.
+func (s *WebSocketStream) SubscribeAccount(stream string, request AccountRequest, handler AccountHandler) error {
+	return s.Subscribe(stream, request, func(data []byte) error {
+		var account hProtocol.Account
+		if err := json.Unmarshal(data, &account); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for account request")
+		}
+		handler(account)
+		return nil
+	})
+}
+
+// SubscribeEffects streams effects, see StreamEffects.
+func (s *WebSocketStream) SubscribeEffects(stream string, request EffectRequest, handler EffectHandler) error {
+	return s.Subscribe(stream, request, func(data []byte) error {
+		var baseEffect effects.Base
+		if err := json.Unmarshal(data, &baseEffect); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for effects request")
+		}
+
+		effect, err := effects.UnmarshalEffect(baseEffect.GetType(), data)
+		if err != nil {
+			return errors.Wrap(err, "unmarshaling to the correct effect type")
+		}
+
+		handler(effect)
+		return nil
+	})
+}
+
+// SubscribeLedgers streams ledgers, see StreamLedgers.
+func (s *WebSocketStream) SubscribeLedgers(stream string, request LedgerRequest, handler LedgerHandler) error {
+	return s.Subscribe(stream, request, func(data []byte) error {
+		var ledger hProtocol.Ledger
+		if err := json.Unmarshal(data, &ledger); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for ledger request")
+		}
+		handler(ledger)
+		return nil
+	})
+}
+
+// SubscribeOffers streams the offers of an account, see StreamOffers.
+func (s *WebSocketStream) SubscribeOffers(stream string, request OfferRequest, handler OfferHandler) error {
+	return s.Subscribe(stream, request, func(data []byte) error {
+		var offer hProtocol.Offer
+		if err := json.Unmarshal(data, &offer); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for offers request")
+		}
+		handler(offer)
+		return nil
+	})
+}
+
+// SubscribeOperations streams operations, see StreamOperations.
+func (s *WebSocketStream) SubscribeOperations(stream string, request OperationRequest, handler OperationHandler) error {
+	return s.subscribeOperations(stream, request.SetOperationsEndpoint(), handler)
+}
+
+// SubscribePayments streams payments, see StreamPayments.
+func (s *WebSocketStream) SubscribePayments(stream string, request OperationRequest, handler OperationHandler) error {
+	return s.subscribeOperations(stream, request.SetPaymentsEndpoint(), handler)
+}
+
+func (s *WebSocketStream) subscribeOperations(stream string, request *OperationRequest, handler OperationHandler) error {
+	return s.Subscribe(stream, request, func(data []byte) error {
+		var baseRecord operations.Base
+		if err := json.Unmarshal(data, &baseRecord); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for operation request")
+		}
+
+		op, err := operations.UnmarshalOperation(baseRecord.GetTypeI(), data)
+		if err != nil {
+			return errors.Wrap(err, "unmarshaling to the correct operation type")
+		}
+
+		handler(op)
+		return nil
+	})
+}
+
+// SubscribeOrderBooks streams the order book of an asset pair, see
+// StreamOrderBooks.
+func (s *WebSocketStream) SubscribeOrderBooks(stream string, request OrderBookRequest, handler OrderBookHandler) error {
+	return s.Subscribe(stream, request, func(data []byte) error {
+		var orderbook hProtocol.OrderBookSummary
+		if err := json.Unmarshal(data, &orderbook); err != nil {
+			return errors.Wrap(err, "error unmarshaling data for orderbook request")
+		}
+		handler(orderbook)
+		return nil
+	})
+}
+
+// SubscribeTrades streams trades, see StreamTrades.
+func (s *WebSocketStream) SubscribeTrades(stream string, request TradeRequest, handler TradeHandler) error {
+	return s.Subscribe(stream, request, func(data []byte) error {
+		var trade hProtocol.Trade
+		if err := json.Unmarshal(data, &trade); err != nil {
+			return errors.Wrap(err, "error unmarshaling data")
+		}
+		handler(trade)
+		return nil
+	})
+}
+
+// SubscribeTransactions streams transactions, see StreamTransactions.
+func (s *WebSocketStream) SubscribeTransactions(stream string, request TransactionRequest, handler TransactionHandler) error {
+	return s.Subscribe(stream, request, func(data []byte) error {
+		var transaction hProtocol.Transaction
+		if err := json.Unmarshal(data, &transaction); err != nil {
+			return errors.Wrap(err, "error unmarshaling data")
+		}
+		handler(transaction)
+		return nil
+	})
+}
+
+// Run connects to Aurora and calls the handlers of the subscribed streams
+// until ctx is cancelled or a handler returns an error. Use context.WithCancel
+// to stop streaming or context.Background() if you want to stream
+// indefinitely.
+func (s *WebSocketStream) Run(ctx context.Context) error {
+	for {
+		conn, err := s.connect(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		err = s.read(ctx, conn)
+		s.lock.Lock()
+		s.conn = nil
+		s.lock.Unlock()
+		conn.Close()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// The connection was lost, reconnect and resume the streams
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(webSocketReconnectDelay):
+		}
+	}
+}
+
+func (s *WebSocketStream) connect(ctx context.Context) (*websocket.Conn, error) {
+	wsURL, err := url.Parse(s.client.fixAuroraURL() + "ws")
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing aurora url")
+	}
+	switch wsURL.Scheme {
+	case "http":
+		wsURL.Scheme = "ws"
+	case "https":
+		wsURL.Scheme = "wss"
+	}
+
+	req, err := http.NewRequest("GET", wsURL.String(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating HTTP request")
+	}
+	s.client.setClientAppHeaders(req)
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, wsURL.String(), req.Header)
+	if err != nil {
+		if resp != nil {
+			return nil, errors.Wrapf(err, "got bad HTTP status code %d", resp.StatusCode)
+		}
+		return nil, errors.Wrap(err, "error connecting to aurora")
+	}
+
+	// Unblock reads when the context is cancelled
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.conn = conn
+	for stream, subscription := range s.subscriptions {
+		err = s.send(hProtocol.WebSocketRequest{
+			Action: hProtocol.WebSocketActionSubscribe,
+			Stream: stream,
+			Path:   subscription.path.String(),
+		})
+		if err != nil {
+			s.conn = nil
+			conn.Close()
+			return nil, errors.Wrap(err, "error subscribing to stream")
+		}
+	}
+
+	return conn, nil
+}
+
+// read reads messages from conn until the connection is closed. It returns
+// nil when the connection was lost.
+func (s *WebSocketStream) read(ctx context.Context, conn *websocket.Conn) error {
+	for {
+		var message hProtocol.WebSocketMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			if _, ok := err.(*json.UnmarshalTypeError); ok {
+				return errors.Wrap(err, "error decoding message")
+			}
+			return nil
+		}
+
+		switch message.Type {
+		case hProtocol.WebSocketMessageEvent:
+			s.lock.Lock()
+			subscription, exists := s.subscriptions[message.Stream]
+			if exists && message.ID != "" {
+				query := subscription.path.Query()
+				query.Set("cursor", message.ID)
+				subscription.path.RawQuery = query.Encode()
+			}
+			s.lock.Unlock()
+
+			// Events can be received after unsubscribing
+			if !exists {
+				continue
+			}
+			if err := subscription.handler(message.Data); err != nil {
+				return errors.Wrap(err, "handler error")
+			}
+		case hProtocol.WebSocketMessageError:
+			if message.Error == nil {
+				continue
+			}
+			s.lock.Lock()
+			delete(s.subscriptions, message.Stream)
+			s.lock.Unlock()
+
+			if s.ErrorHandler != nil {
+				s.ErrorHandler(message.Stream, &Error{Problem: *message.Error})
+			}
+		}
+	}
+}
+
+// send sends a request to Aurora, s.lock must be held.
+func (s *WebSocketStream) send(request hProtocol.WebSocketRequest) error {
+	if err := s.conn.WriteJSON(request); err != nil {
+		return errors.Wrap(err, "error sending message")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package auroraclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hProtocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/support/render/problem"
+)
+
+func TestWebSocketStream(t *testing.T) {
+	var lock sync.Mutex
+	var paths []string
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ws", r.URL.Path)
+		assert.Equal(t, "go-diamnet-sdk", r.Header.Get("X-Client-Name"))
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var request hProtocol.WebSocketRequest
+		require.NoError(t, conn.ReadJSON(&request))
+		assert.Equal(t, hProtocol.WebSocketActionSubscribe, request.Action)
+		assert.Equal(t, "ledgers", request.Stream)
+
+		lock.Lock()
+		paths = append(paths, request.Path)
+		connections := len(paths)
+		lock.Unlock()
+
+		if connections == 1 {
+			// Send an event and drop the connection, the client must resume
+			// the stream from the event
+			data, err := json.Marshal(hProtocol.Ledger{Sequence: 10})
+			require.NoError(t, err)
+			require.NoError(t, conn.WriteJSON(hProtocol.WebSocketMessage{
+				Type:   hProtocol.WebSocketMessageEvent,
+				Stream: "ledgers",
+				ID:     "42949672960",
+				Data:   data,
+			}))
+			return
+		}
+
+		require.NoError(t, conn.WriteJSON(hProtocol.WebSocketMessage{
+			Type:   hProtocol.WebSocketMessageError,
+			Stream: "ledgers",
+			Error:  &problem.NotFound,
+		}))
+		// Wait for the client to close the connection
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	client := &Client{AuroraURL: server.URL}
+	stream := client.NewWebSocketStream()
+
+	var ledgers []hProtocol.Ledger
+	require.NoError(t, stream.SubscribeLedgers("ledgers", LedgerRequest{}, func(ledger hProtocol.Ledger) {
+		ledgers = append(ledgers, ledger)
+	}))
+	assert.Error(t, stream.SubscribeLedgers("ledgers", LedgerRequest{}, func(hProtocol.Ledger) {}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var streamErr error
+	stream.ErrorHandler = func(name string, err error) {
+		assert.Equal(t, "ledgers", name)
+		streamErr = err
+		cancel()
+	}
+	require.NoError(t, stream.Run(ctx))
+
+	require.Len(t, ledgers, 1)
+	assert.Equal(t, int32(10), ledgers[0].Sequence)
+
+	require.Len(t, paths, 2)
+	first, err := url.Parse(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, "/ledgers", first.Path)
+	assert.Equal(t, "now", first.Query().Get("cursor"))
+	second, err := url.Parse(paths[1])
+	require.NoError(t, err)
+	assert.Equal(t, "42949672960", second.Query().Get("cursor"))
+
+	if assert.IsType(t, &Error{}, streamErr) {
+		assert.Equal(t, http.StatusNotFound, streamErr.(*Error).Problem.Status)
+	}
+	// The stream is unsubscribed after an error
+	assert.Error(t, stream.Unsubscribe("ledgers"))
+}
@@ -0,0 +1,76 @@
+// Package txqueue submits batches of operations sent from a single account
+// through the channel accounts of an auroraclient.ChannelPool. The queue
+// assigns the sequence numbers of the transactions, signs them and resubmits
+// them on transient failures so that many batches can be submitted
+// concurrently without running into tx_bad_seq errors.
+package txqueue
+
+import (
+	"time"
+
+	"github.com/diamnet/go/clients/auroraclient"
+	hProtocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/txnbuild"
+)
+
+const (
+	// DefaultMaxAttempts is the number of times a batch is submitted by default
+	// before giving up.
+	DefaultMaxAttempts = 5
+	// DefaultRetryDelay is the delay before the second submission of a batch
+	// by default. It doubles for every following attempt.
+	DefaultRetryDelay = time.Second
+)
+
+// ErrClosed is returned for the batches submitted to a queue which is not
+// running anymore.
+var ErrClosed = errors.New("queue closed")
+
+// Config configures a Queue.
+type Config struct {
+	Client            auroraclient.ClientInterface
+	NetworkPassphrase string
+	// Pool provides the channel accounts which are the source accounts of the
+	// transactions. The pool account is the source account of the operations
+	// which don't have one. The queue submits as many transactions
+	// concurrently as the pool has channels.
+	Pool *auroraclient.ChannelPool
+	// MaxAttempts is the number of times a batch is submitted before giving
+	// up, DefaultMaxAttempts if 0.
+	MaxAttempts int
+	// RetryDelay is the delay before the second submission of a batch,
+	// DefaultRetryDelay if 0.
+	RetryDelay time.Duration
+	// FeePolicy, if set, is used to resubmit the transactions failing with
+	// tx_insufficient_fee in fee bump transactions with higher fees, see
+	// auroraclient.FeePolicy. Without it, or once its budget is exhausted,
+	// tx_insufficient_fee is a permanent failure.
+	FeePolicy *auroraclient.FeePolicy
+}
+
+// Result is the final outcome of a batch of operations.
+type Result struct {
+	// Hash is the hash of the last transaction submitted for the batch.
+	Hash string
+	// Transaction is the transaction which included the batch, if the batch
+	// was successful.
+	Transaction hProtocol.Transaction
+	// Attempts is the number of times the batch was submitted.
+	Attempts int
+	// Err is the error of the last attempt if the batch was not successful.
+	// Errors returned by aurora are *auroraclient.Error.
+	Err error
+}
+
+// Queue submits batches of operations. It must be started with Run.
+type Queue struct {
+	config   Config
+	requests chan request
+	done     chan struct{}
+}
+
+type request struct {
+	operations []txnbuild.Operation
+	result     chan<- Result
+}
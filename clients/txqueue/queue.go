@@ -0,0 +1,203 @@
+package txqueue
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/diamnet/go/clients/auroraclient"
+	hProtocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/txnbuild"
+)
+
+// New returns a queue submitting batches of operations with the given
+// configuration.
+func New(config Config) (*Queue, error) {
+	if config.Client == nil {
+		return nil, errors.New("aurora client is required")
+	}
+	if config.Pool == nil {
+		return nil, errors.New("channel pool is required")
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.RetryDelay == 0 {
+		config.RetryDelay = DefaultRetryDelay
+	}
+
+	return &Queue{
+		config:   config,
+		requests: make(chan request),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Run submits the queued batches until ctx is done, with one goroutine per
+// channel of the pool. Run must only be called once; batches submitted after
+// it returned fail with ErrClosed.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.config.Pool.Size(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+	close(q.done)
+}
+
+// Submit queues a batch of operations which are submitted in a single
+// transaction. It blocks until the batch is picked up and returns a channel
+// which receives the outcome of the batch.
+func (q *Queue) Submit(ctx context.Context, operations ...txnbuild.Operation) <-chan Result {
+	result := make(chan Result, 1)
+	select {
+	case q.requests <- request{operations: operations, result: result}:
+	case <-ctx.Done():
+		result <- Result{Err: ctx.Err()}
+	case <-q.done:
+		result <- Result{Err: ErrClosed}
+	}
+	return result
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-q.requests:
+			req.result <- q.submit(ctx, req.operations)
+		}
+	}
+}
+
+// retryAction is what to do with a transaction after a failed submission.
+type retryAction int
+
+const (
+	giveUp retryAction = iota
+	// resubmit the same transaction, which may already have been received by
+	// diamnet-core
+	resubmit
+	// rebuild the transaction with the current sequence number of the channel
+	rebuild
+)
+
+func retryActionFor(err error) retryAction {
+	hErr := auroraclient.GetError(err)
+	if hErr == nil {
+		if errors.Cause(err) == auroraclient.ErrAccountRequiresMemo {
+			return giveUp
+		}
+		// aurora could not be reached or its response could not be read
+		return resubmit
+	}
+
+	switch hErr.Problem.Status {
+	case http.StatusGatewayTimeout,
+		http.StatusServiceUnavailable,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError:
+		return resubmit
+	}
+
+	codes, err := hErr.ResultCodes()
+	if err != nil {
+		return giveUp
+	}
+	switch codes.TransactionCode {
+	case "tx_bad_seq", "tx_too_late":
+		return rebuild
+	}
+	// tx_insufficient_fee is not retried, a transaction rebuilt with the
+	// same fee would fail again. Fees are raised by Config.FeePolicy.
+	return giveUp
+}
+
+// submit submits a batch with a channel of the pool until it is included in
+// a ledger or fails with a permanent error.
+func (q *Queue) submit(ctx context.Context, operations []txnbuild.Operation) Result {
+	var (
+		result Result
+		ch     *auroraclient.Channel
+		tx     *txnbuild.Transaction
+	)
+	defer func() {
+		if ch != nil {
+			q.config.Pool.Release(ch)
+		}
+	}()
+
+	for result.Attempts < q.config.MaxAttempts {
+		if result.Attempts > 0 {
+			delay := q.config.RetryDelay << uint(result.Attempts-1)
+			select {
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+				return result
+			case <-time.After(delay):
+			}
+		}
+		result.Attempts++
+
+		if ch == nil {
+			var err error
+			if ch, err = q.config.Pool.Lease(ctx); err != nil {
+				result.Err = err
+				continue
+			}
+		}
+		if tx == nil {
+			var err error
+			if tx, err = ch.BuildTransaction(nil, operations...); err != nil {
+				result.Err = err
+				return result
+			}
+			if result.Hash, err = tx.HashHex(q.config.NetworkPassphrase); err != nil {
+				result.Err = errors.Wrap(err, "could not hash transaction")
+				return result
+			}
+		}
+
+		resp, err := q.submitTransaction(tx)
+		if err == nil {
+			result.Transaction = resp
+			result.Err = nil
+			return result
+		}
+		result.Err = err
+
+		switch retryActionFor(err) {
+		case resubmit:
+		case rebuild:
+			// lease a channel again to reload its sequence number
+			tx = nil
+			ch.Reset()
+			q.config.Pool.Release(ch)
+			ch = nil
+		default:
+			// the transaction may have consumed the sequence number if it
+			// failed in a ledger
+			ch.Reset()
+			return result
+		}
+	}
+	return result
+}
+
+// submitTransaction submits the transaction with the fee policy of the queue,
+// if any.
+func (q *Queue) submitTransaction(tx *txnbuild.Transaction) (hProtocol.Transaction, error) {
+	if q.config.FeePolicy == nil {
+		return q.config.Client.SubmitTransaction(tx)
+	}
+	return q.config.Client.SubmitTransactionWithOptions(tx, auroraclient.SubmitTxOpts{
+		FeePolicy: q.config.FeePolicy,
+	})
+}
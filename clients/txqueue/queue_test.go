@@ -0,0 +1,240 @@
+package txqueue
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/clients/auroraclient"
+	"github.com/diamnet/go/keypair"
+	"github.com/diamnet/go/network"
+	hProtocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/txnbuild"
+)
+
+func transactionFailed(code string) error {
+	return &auroraclient.Error{
+		Problem: problem.P{
+			Type:   "https://diamnet.org/aurora-errors/transaction_failed",
+			Title:  "Transaction Failed",
+			Status: http.StatusBadRequest,
+			Extras: map[string]interface{}{
+				"result_codes": map[string]interface{}{
+					"transaction": code,
+				},
+			},
+		},
+	}
+}
+
+type queueTest struct {
+	client  *auroraclient.MockClient
+	account *keypair.Full
+	channel *keypair.Full
+	queue   *Queue
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+func newQueueTest(t *testing.T) *queueTest {
+	qt := &queueTest{
+		client:  &auroraclient.MockClient{},
+		account: keypair.MustRandom(),
+		channel: keypair.MustRandom(),
+		stopped: make(chan struct{}),
+	}
+	qt.expectLoad("100")
+	pool, err := auroraclient.NewChannelPool(qt.client, auroraclient.ChannelPoolConfig{
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		Account:           qt.account,
+		Channels:          []*keypair.Full{qt.channel},
+	})
+	require.NoError(t, err)
+
+	qt.queue, err = New(Config{
+		Client:            qt.client,
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		Pool:              pool,
+		RetryDelay:        time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	var ctx context.Context
+	ctx, qt.cancel = context.WithCancel(context.Background())
+	go func() {
+		qt.queue.Run(ctx)
+		close(qt.stopped)
+	}()
+	return qt
+}
+
+func (qt *queueTest) stop() {
+	qt.cancel()
+	<-qt.stopped
+}
+
+func (qt *queueTest) payment() txnbuild.Operation {
+	return &txnbuild.Payment{
+		Destination: keypair.MustRandom().Address(),
+		Amount:      "10",
+		Asset:       txnbuild.NativeAsset{},
+	}
+}
+
+func (qt *queueTest) expectLoad(sequence string) {
+	qt.client.On("AccountDetail", auroraclient.AccountRequest{AccountID: qt.channel.Address()}).
+		Return(hProtocol.Account{AccountID: qt.channel.Address(), Sequence: sequence}, nil).Once()
+}
+
+func TestQueueRebuildsOnBadSequence(t *testing.T) {
+	qt := newQueueTest(t)
+	defer qt.stop()
+
+	var submitted []*txnbuild.Transaction
+	qt.client.On("SubmitTransaction", mock.Anything).
+		Run(func(args mock.Arguments) {
+			submitted = append(submitted, args.Get(0).(*txnbuild.Transaction))
+		}).
+		Return(hProtocol.Transaction{}, transactionFailed("tx_bad_seq")).Once()
+	qt.expectLoad("104")
+	qt.client.On("SubmitTransaction", mock.Anything).
+		Run(func(args mock.Arguments) {
+			submitted = append(submitted, args.Get(0).(*txnbuild.Transaction))
+		}).
+		Return(hProtocol.Transaction{Successful: true}, nil).Once()
+
+	result := <-qt.queue.Submit(context.Background(), qt.payment())
+	assert.NoError(t, result.Err)
+	assert.Equal(t, 2, result.Attempts)
+	assert.True(t, result.Transaction.Successful)
+	qt.client.AssertExpectations(t)
+
+	require.Len(t, submitted, 2)
+	assert.Equal(t, int64(101), submitted[0].SequenceNumber())
+	assert.Equal(t, int64(105), submitted[1].SequenceNumber())
+	assert.Equal(t, qt.channel.Address(), submitted[1].SourceAccount().AccountID)
+	assert.Equal(t, qt.account.Address(), submitted[1].Operations()[0].GetSourceAccount())
+	assert.Len(t, submitted[1].Signatures(), 2)
+
+	hash, err := submitted[1].HashHex(network.TestNetworkPassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, hash, result.Hash)
+}
+
+func TestQueueResubmitsOnTimeout(t *testing.T) {
+	qt := newQueueTest(t)
+	defer qt.stop()
+
+	var submitted []*txnbuild.Transaction
+	qt.client.On("SubmitTransaction", mock.Anything).
+		Run(func(args mock.Arguments) {
+			submitted = append(submitted, args.Get(0).(*txnbuild.Transaction))
+		}).
+		Return(hProtocol.Transaction{}, &auroraclient.Error{Problem: problem.P{Status: http.StatusGatewayTimeout}}).Once()
+	qt.client.On("SubmitTransaction", mock.Anything).
+		Run(func(args mock.Arguments) {
+			submitted = append(submitted, args.Get(0).(*txnbuild.Transaction))
+		}).
+		Return(hProtocol.Transaction{Successful: true}, nil).Once()
+
+	result := <-qt.queue.Submit(context.Background(), qt.payment())
+	assert.NoError(t, result.Err)
+	assert.Equal(t, 2, result.Attempts)
+	qt.client.AssertExpectations(t)
+
+	require.Len(t, submitted, 2)
+	assert.Same(t, submitted[0], submitted[1])
+
+	// the sequence number of the channel is kept for the next batch
+	qt.client.On("SubmitTransaction", mock.Anything).
+		Run(func(args mock.Arguments) {
+			submitted = append(submitted, args.Get(0).(*txnbuild.Transaction))
+		}).
+		Return(hProtocol.Transaction{Successful: true}, nil).Once()
+	result = <-qt.queue.Submit(context.Background(), qt.payment())
+	assert.NoError(t, result.Err)
+	require.Len(t, submitted, 3)
+	assert.Equal(t, int64(102), submitted[2].SequenceNumber())
+}
+
+func TestQueueGivesUp(t *testing.T) {
+	qt := newQueueTest(t)
+	defer qt.stop()
+
+	qt.client.On("SubmitTransaction", mock.Anything).
+		Return(hProtocol.Transaction{}, transactionFailed("tx_failed")).Once()
+
+	result := <-qt.queue.Submit(context.Background(), qt.payment())
+	assert.Error(t, result.Err)
+	assert.Equal(t, 1, result.Attempts)
+	qt.client.AssertExpectations(t)
+
+	// transient errors are retried at most MaxAttempts times
+	qt.expectLoad("101")
+	qt.client.On("SubmitTransaction", mock.Anything).
+		Return(hProtocol.Transaction{}, &auroraclient.Error{Problem: problem.P{Status: http.StatusServiceUnavailable}}).
+		Times(DefaultMaxAttempts)
+
+	result = <-qt.queue.Submit(context.Background(), qt.payment())
+	assert.Error(t, result.Err)
+	assert.Equal(t, DefaultMaxAttempts, result.Attempts)
+	qt.client.AssertExpectations(t)
+}
+
+func TestQueueInsufficientFee(t *testing.T) {
+	qt := newQueueTest(t)
+	defer qt.stop()
+
+	// without a fee policy a transaction rebuilt with the same fee would
+	// fail again, so the batch fails right away
+	qt.client.On("SubmitTransaction", mock.Anything).
+		Return(hProtocol.Transaction{}, transactionFailed("tx_insufficient_fee")).Once()
+
+	result := <-qt.queue.Submit(context.Background(), qt.payment())
+	codes, err := auroraclient.GetError(result.Err).ResultCodes()
+	require.NoError(t, err)
+	assert.Equal(t, "tx_insufficient_fee", codes.TransactionCode)
+	assert.Equal(t, 1, result.Attempts)
+	qt.client.AssertExpectations(t)
+}
+
+func TestQueueFeePolicy(t *testing.T) {
+	qt := newQueueTest(t)
+	defer qt.stop()
+	policy := &auroraclient.FeePolicy{
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		FeeAccount:        qt.account,
+		MaxBaseFee:        1000,
+	}
+	qt.queue.config.FeePolicy = policy
+
+	qt.client.On("SubmitTransactionWithOptions", mock.Anything, auroraclient.SubmitTxOpts{FeePolicy: policy}).
+		Return(hProtocol.Transaction{Successful: true}, nil).Once()
+
+	result := <-qt.queue.Submit(context.Background(), qt.payment())
+	assert.NoError(t, result.Err)
+	assert.Equal(t, 1, result.Attempts)
+	qt.client.AssertExpectations(t)
+}
+
+func TestQueueClosed(t *testing.T) {
+	qt := newQueueTest(t)
+	qt.stop()
+
+	result := <-qt.queue.Submit(context.Background(), qt.payment())
+	assert.Equal(t, ErrClosed, result.Err)
+}
+
+func TestNewQueueValidatesConfig(t *testing.T) {
+	_, err := New(Config{})
+	assert.EqualError(t, err, "aurora client is required")
+
+	_, err = New(Config{Client: &auroraclient.MockClient{}})
+	assert.EqualError(t, err, "channel pool is required")
+}
@@ -87,24 +87,46 @@ func (tx *orderBookBatchedUpdates) apply(ledger uint32) error {
 		return errUnexpectedLedger
 	}
 
+	// updatedPairs contains the edges updated by the batch, whose cached
+	// paths must be invalidated
+	var updatedPairs map[assetPair]bool
+	if tx.orderbook.pathCache != nil {
+		updatedPairs = map[assetPair]bool{}
+	}
 	for _, operation := range tx.operations {
 		switch operation.operationType {
 		case addOfferOperationType:
+			if updatedPairs != nil {
+				updatedPairs[newAssetPair(operation.offer.Selling.String(), operation.offer.Buying.String())] = true
+			}
 			if err := tx.orderbook.addOffer(*operation.offer); err != nil {
 				panic(errors.Wrap(err, "could not apply update in batch"))
 			}
 		case removeOfferOperationType:
-			if _, ok := tx.orderbook.tradingPairForOffer[operation.offerID]; !ok {
+			pair, ok := tx.orderbook.tradingPairForOffer[operation.offerID]
+			if !ok {
 				continue
 			}
+			if updatedPairs != nil {
+				updatedPairs[newAssetPair(
+					tx.orderbook.idToAssetString[pair.sellingAsset],
+					tx.orderbook.idToAssetString[pair.buyingAsset],
+				)] = true
+			}
 			if err := tx.orderbook.removeOffer(operation.offerID); err != nil {
 				panic(errors.Wrap(err, "could not apply update in batch"))
 			}
 
 		case addLiquidityPoolOperationType:
+			if updatedPairs != nil {
+				updatedPairs[newPoolAssetPair(*operation.liquidityPool)] = true
+			}
 			tx.orderbook.addPool(*operation.liquidityPool)
 
 		case removeLiquidityPoolOperationType:
+			if updatedPairs != nil {
+				updatedPairs[newPoolAssetPair(*operation.liquidityPool)] = true
+			}
 			tx.orderbook.removePool(*operation.liquidityPool)
 
 		default:
@@ -113,6 +135,9 @@ func (tx *orderBookBatchedUpdates) apply(ledger uint32) error {
 	}
 
 	tx.orderbook.lastLedger = ledger
+	if updatedPairs != nil {
+		tx.orderbook.pathCache.invalidate(updatedPairs)
+	}
 
 	return nil
 }
@@ -0,0 +1,296 @@
+package orderbook
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/diamnet/go/xdr"
+)
+
+// defaultPathCacheMaxAge is the number of ledgers after which cached routes are
+// searched again, even if none of their edges were updated, so that routes
+// made possible by new offers are eventually found.
+const defaultPathCacheMaxAge = 60
+
+// assetPair is an unordered pair of asset strings identifying the edges
+// between two assets in both directions.
+type assetPair struct {
+	a, b string
+}
+
+func newAssetPair(a, b string) assetPair {
+	if b < a {
+		a, b = b, a
+	}
+	return assetPair{a: a, b: b}
+}
+
+func newPoolAssetPair(pool xdr.LiquidityPoolEntry) assetPair {
+	assetA, assetB := getPoolAssets(pool)
+	return newAssetPair(assetA.String(), assetB.String())
+}
+
+type pathCacheEntry struct {
+	key string
+	// routes are the asset strings of the paths found by the search, in the
+	// order in which they were traversed (starting with the asset the search
+	// started from).
+	routes [][]string
+	ledger uint32
+	// pairs are the edges along the routes and the edges directly connecting
+	// the start asset to the target assets.
+	pairs []assetPair
+	// assets are indexed when the search did not find any route, in which
+	// case any update of the start or target assets invalidates the entry.
+	assets []string
+}
+
+// PathCacheStats contains the counters of a PathCache.
+type PathCacheStats struct {
+	Hits          uint64
+	Misses        uint64
+	Invalidations uint64
+	Entries       int
+}
+
+// PathCache is an LRU cache of the routes found by path finding searches on an
+// OrderBookGraph. Searches are keyed by all their parameters: start asset,
+// target assets, amount, maximum path length, the source account whose offers
+// are ignored and the balances validated, since the routes found depend on
+// all of them. Cached routes are evaluated again against the current order
+// book for every request so the returned amounts are always exact.
+//
+// Entries are invalidated when the edges along their routes are updated by
+// OrderBookGraph.Apply.
+type PathCache struct {
+	mutex   sync.Mutex
+	size    int
+	maxAge  uint32
+	lru     *list.List
+	entries map[string]*list.Element
+	pairs   map[assetPair]map[string]struct{}
+	assets  map[string]map[string]struct{}
+	stats   PathCacheStats
+}
+
+// NewPathCache constructs a PathCache containing up to size searches.
+func NewPathCache(size int) *PathCache {
+	cache := &PathCache{
+		size:   size,
+		maxAge: defaultPathCacheMaxAge,
+	}
+	cache.clear()
+	return cache
+}
+
+// Stats returns the hit, miss and invalidation counters of the cache.
+func (c *PathCache) Stats() PathCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+func (c *PathCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.lru = list.New()
+	c.entries = map[string]*list.Element{}
+	c.pairs = map[assetPair]map[string]struct{}{}
+	c.assets = map[string]map[string]struct{}{}
+}
+
+// get returns the routes cached for key if they were found at most maxAge
+// ledgers before ledger.
+func (c *PathCache) get(key string, ledger uint32) ([][]string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if ok {
+		entry := element.Value.(*pathCacheEntry)
+		if ledger < entry.ledger+c.maxAge {
+			c.lru.MoveToFront(element)
+			c.stats.Hits++
+			return entry.routes, true
+		}
+		c.remove(element)
+	}
+	c.stats.Misses++
+	return nil, false
+}
+
+func (c *PathCache) add(entry *pathCacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[entry.key]; ok {
+		c.remove(element)
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for _, pair := range entry.pairs {
+		if c.pairs[pair] == nil {
+			c.pairs[pair] = map[string]struct{}{}
+		}
+		c.pairs[pair][entry.key] = struct{}{}
+	}
+	for _, asset := range entry.assets {
+		if c.assets[asset] == nil {
+			c.assets[asset] = map[string]struct{}{}
+		}
+		c.assets[asset][entry.key] = struct{}{}
+	}
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// invalidate removes the entries depending on the edges between the given
+// asset pairs.
+func (c *PathCache) invalidate(pairs map[assetPair]bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for pair := range pairs {
+		for _, keys := range []map[string]struct{}{
+			c.pairs[pair], c.assets[pair.a], c.assets[pair.b],
+		} {
+			for key := range keys {
+				if element, ok := c.entries[key]; ok {
+					c.remove(element)
+					c.stats.Invalidations++
+				}
+			}
+		}
+	}
+}
+
+func (c *PathCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*pathCacheEntry)
+	delete(c.entries, entry.key)
+	for _, pair := range entry.pairs {
+		delete(c.pairs[pair], entry.key)
+		if len(c.pairs[pair]) == 0 {
+			delete(c.pairs, pair)
+		}
+	}
+	for _, asset := range entry.assets {
+		delete(c.assets[asset], entry.key)
+		if len(c.assets[asset]) == 0 {
+			delete(c.assets, asset)
+		}
+	}
+}
+
+func pathCacheKey(
+	searchType sortByType,
+	startAsset string,
+	targetAssets []string,
+	restrictions string,
+	amount xdr.Int64,
+	maxPathLength int,
+	includePools bool,
+) string {
+	targets := append([]string{}, targetAssets...)
+	sort.Strings(targets)
+	return fmt.Sprintf(
+		"%s:%s:%s:%d:%d:%t:%s",
+		searchType,
+		startAsset,
+		strings.Join(targets, ","),
+		amount,
+		maxPathLength,
+		includePools,
+		restrictions,
+	)
+}
+
+func newPathCacheEntry(
+	key string,
+	ledger uint32,
+	startAsset string,
+	targetAssets []string,
+	routes [][]string,
+) *pathCacheEntry {
+	entry := &pathCacheEntry{key: key, ledger: ledger}
+	pairs := map[assetPair]bool{}
+	seen := map[string]bool{}
+	for _, route := range routes {
+		routeKey := strings.Join(route, ",")
+		if seen[routeKey] {
+			continue
+		}
+		seen[routeKey] = true
+		entry.routes = append(entry.routes, route)
+		for i := 1; i < len(route); i++ {
+			pairs[newAssetPair(route[i-1], route[i])] = true
+		}
+	}
+	for _, target := range targetAssets {
+		pairs[newAssetPair(startAsset, target)] = true
+	}
+	for pair := range pairs {
+		entry.pairs = append(entry.pairs, pair)
+	}
+	if len(entry.routes) == 0 {
+		entry.assets = append([]string{startAsset}, targetAssets...)
+	}
+	return entry
+}
+
+// routeForPath returns the assets of a path in the order in which they are
+// traversed by a search of the given type.
+func routeForPath(searchType sortByType, path Path) []string {
+	route := make([]string, 0, len(path.InteriorNodes)+2)
+	if searchType == sortBySourceAsset {
+		// Searches for a source asset start from the destination asset
+		route = append(route, path.DestinationAsset)
+		for i := len(path.InteriorNodes) - 1; i >= 0; i-- {
+			route = append(route, path.InteriorNodes[i])
+		}
+		return append(route, path.SourceAsset)
+	}
+	route = append(route, path.SourceAsset)
+	route = append(route, path.InteriorNodes...)
+	return append(route, path.DestinationAsset)
+}
+
+// evaluateRoute trades amount along route using the current venues of the
+// graph and appends the resulting path to state if it is still viable.
+func evaluateRoute(graph *OrderBookGraph, state searchState, route []string, amount xdr.Int64) error {
+	path := make([]int32, len(route))
+	for i, asset := range route {
+		id, ok := graph.assetStringToID[asset]
+		if !ok {
+			return nil
+		}
+		path[i] = id
+	}
+
+	currentAsset, currentAmount := path[0], amount
+	for _, nextAsset := range path[1:] {
+		edges := state.venues(currentAsset)
+		i := edges.find(nextAsset)
+		if i < 0 {
+			return nil
+		}
+		nextAmount, err := processVenues(state, currentAsset, currentAmount, edges[i].value)
+		if err != nil {
+			return err
+		}
+		if nextAmount <= 0 {
+			return nil
+		}
+		currentAsset, currentAmount = nextAsset, nextAmount
+	}
+
+	if state.includePath(currentAsset, currentAmount) {
+		state.appendToPaths(path, currentAsset, currentAmount)
+	}
+	return nil
+}
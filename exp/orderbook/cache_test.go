@@ -0,0 +1,213 @@
+package orderbook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diamnet/go/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPathCacheTestGraphs(t *testing.T, offers ...xdr.OfferEntry) (*OrderBookGraph, *OrderBookGraph, *PathCache) {
+	uncached := NewOrderBookGraph()
+	cached := NewOrderBookGraph()
+	cache := NewPathCache(10)
+	cached.SetPathCache(cache)
+
+	for _, graph := range []*OrderBookGraph{uncached, cached} {
+		graph.AddOffers(offers...)
+		if !assert.NoError(t, graph.Apply(1)) {
+			t.FailNow()
+		}
+	}
+	return uncached, cached, cache
+}
+
+func assertCachedPathsEqual(t *testing.T, uncached, cached *OrderBookGraph, amount xdr.Int64) {
+	sourceAssets := []xdr.Asset{yenAsset, usdAsset, eurAsset}
+	balances := []xdr.Int64{1000, 1000, 1000}
+	expected, expectedLedger, err := uncached.FindPaths(
+		context.TODO(), 3, nativeAsset, amount, nil, sourceAssets, balances, true, 5, true,
+	)
+	assert.NoError(t, err)
+	paths, lastLedger, err := cached.FindPaths(
+		context.TODO(), 3, nativeAsset, amount, nil, sourceAssets, balances, true, 5, true,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedLedger, lastLedger)
+	assertPathEquals(t, paths, expected)
+}
+
+func TestPathCache(t *testing.T) {
+	eurUsdOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  xdr.Int64(9),
+		Buying:   eurAsset,
+		Selling:  usdAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   xdr.Int64(500),
+	}
+	uncached, cached, cache := newPathCacheTestGraphs(
+		t, dollarOffer, quarterOffer, eurOffer, twoEurOffer, eurUsdOffer,
+	)
+
+	assertCachedPathsEqual(t, uncached, cached, 20)
+	assert.Equal(t, PathCacheStats{Misses: 1, Entries: 1}, cache.Stats())
+
+	// The same search reuses the cached routes, which are evaluated again
+	// against the current order book
+	assertCachedPathsEqual(t, uncached, cached, 20)
+	assert.Equal(t, PathCacheStats{Hits: 1, Misses: 1, Entries: 1}, cache.Stats())
+
+	assertCachedPathsEqual(t, uncached, cached, 200)
+	assert.Equal(t, PathCacheStats{Hits: 1, Misses: 2, Entries: 2}, cache.Stats())
+
+	// Updates of edges which are not on cached routes keep the entries
+	chfYenOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  xdr.Int64(20),
+		Buying:   chfAsset,
+		Selling:  yenAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   xdr.Int64(500),
+	}
+	for _, graph := range []*OrderBookGraph{uncached, cached} {
+		graph.AddOffers(chfYenOffer)
+		assert.NoError(t, graph.Apply(2))
+	}
+	assertCachedPathsEqual(t, uncached, cached, 20)
+	assert.Equal(t, PathCacheStats{Hits: 2, Misses: 2, Entries: 2}, cache.Stats())
+
+	// Updates of edges on cached routes invalidate the entries
+	for _, graph := range []*OrderBookGraph{uncached, cached} {
+		graph.RemoveOffer(quarterOffer.OfferId)
+		assert.NoError(t, graph.Apply(3))
+	}
+	assert.Equal(t, PathCacheStats{Hits: 2, Misses: 2, Invalidations: 2}, cache.Stats())
+	assertCachedPathsEqual(t, uncached, cached, 20)
+	assert.Equal(t, PathCacheStats{Hits: 2, Misses: 3, Invalidations: 2, Entries: 1}, cache.Stats())
+
+	// Entries are searched again once they are too old
+	cache.maxAge = 2
+	for _, graph := range []*OrderBookGraph{uncached, cached} {
+		graph.AddOffers(chfYenOffer)
+		assert.NoError(t, graph.Apply(5))
+	}
+	assertCachedPathsEqual(t, uncached, cached, 20)
+	assert.Equal(t, PathCacheStats{Hits: 2, Misses: 4, Invalidations: 2, Entries: 1}, cache.Stats())
+
+	cached.Clear()
+	assert.Equal(t, 0, cache.Stats().Entries)
+}
+
+func TestPathCacheRestrictions(t *testing.T) {
+	uncached, cached, cache := newPathCacheTestGraphs(t, dollarOffer, eurOffer)
+
+	// Routes are cached for every source account and balances, which change
+	// the routes found by the search
+	ignoreOffersFrom := issuer
+	for _, balances := range [][]xdr.Int64{{1000, 1000}, {1000, 1}, {1000, 1000}, {1000, 1}} {
+		for _, ignore := range []*xdr.AccountId{nil, &ignoreOffersFrom} {
+			var results [][]Path
+			for _, graph := range []*OrderBookGraph{uncached, cached} {
+				paths, _, err := graph.FindPaths(
+					context.TODO(),
+					3,
+					nativeAsset,
+					20,
+					ignore,
+					[]xdr.Asset{usdAsset, eurAsset},
+					balances,
+					true,
+					5,
+					true,
+				)
+				assert.NoError(t, err)
+				results = append(results, paths)
+			}
+			assertPathEquals(t, results[1], results[0])
+		}
+	}
+	assert.Equal(t, PathCacheStats{Hits: 4, Misses: 4, Entries: 4}, cache.Stats())
+}
+
+func TestFixedPathCache(t *testing.T) {
+	uncached, cached, cache := newPathCacheTestGraphs(
+		t, dollarOffer, quarterOffer, eurOffer, twoEurOffer,
+	)
+
+	for i, amount := range []xdr.Int64{20, 20, 30} {
+		expected, _, err := uncached.FindFixedPaths(
+			context.TODO(), 3, usdAsset, amount, []xdr.Asset{nativeAsset, eurAsset}, 5, true,
+		)
+		assert.NoError(t, err)
+		paths, _, err := cached.FindFixedPaths(
+			context.TODO(), 3, usdAsset, amount, []xdr.Asset{nativeAsset, eurAsset}, 5, true,
+		)
+		assert.NoError(t, err)
+		assertPathEquals(t, paths, expected)
+
+		if i == 1 {
+			for _, graph := range []*OrderBookGraph{uncached, cached} {
+				graph.RemoveOffer(dollarOffer.OfferId)
+				assert.NoError(t, graph.Apply(2))
+			}
+		}
+	}
+	assert.Equal(t, PathCacheStats{Hits: 1, Misses: 2, Invalidations: 1, Entries: 1}, cache.Stats())
+}
+
+func TestPathCacheAmounts(t *testing.T) {
+	usdEurOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  xdr.Int64(40),
+		Buying:   usdAsset,
+		Selling:  eurAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   xdr.Int64(20),
+	}
+	usdYenOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  xdr.Int64(41),
+		Buying:   usdAsset,
+		Selling:  yenAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   xdr.Int64(1000),
+	}
+	yenEurOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  xdr.Int64(42),
+		Buying:   yenAsset,
+		Selling:  eurAsset,
+		Price:    xdr.Price{N: 2, D: 1},
+		Amount:   xdr.Int64(1000),
+	}
+	eurNativeOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  xdr.Int64(43),
+		Buying:   eurAsset,
+		Selling:  nativeAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   xdr.Int64(1000),
+	}
+	uncached, cached, cache := newPathCacheTestGraphs(
+		t, usdEurOffer, usdYenOffer, yenEurOffer, eurNativeOffer,
+	)
+
+	// Searches only keep the best path to every asset: EUR is reached
+	// directly for 16 USD but through YEN for 31 USD, which the direct offer
+	// cannot absorb
+	for _, amount := range []xdr.Int64{16, 31, 16, 31} {
+		expected, _, err := uncached.FindFixedPaths(
+			context.TODO(), 3, usdAsset, amount, []xdr.Asset{nativeAsset}, 5, true,
+		)
+		assert.NoError(t, err)
+		assert.Len(t, expected, 1)
+		paths, _, err := cached.FindFixedPaths(
+			context.TODO(), 3, usdAsset, amount, []xdr.Asset{nativeAsset}, 5, true,
+		)
+		assert.NoError(t, err)
+		assertPathEquals(t, paths, expected)
+	}
+	assert.Equal(t, PathCacheStats{Hits: 2, Misses: 2, Entries: 2}, cache.Stats())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/diamnet/go/support/errors"
@@ -86,6 +87,9 @@ type OrderBookGraph struct {
 	lock           sync.RWMutex
 	// the orderbook graph is accurate up to lastLedger
 	lastLedger uint32
+	// pathCache caches the routes found by FindPaths and FindFixedPaths,
+	// it is nil if caching is disabled.
+	pathCache *PathCache
 }
 
 var _ OBGraph = (*OrderBookGraph)(nil)
@@ -97,6 +101,14 @@ func NewOrderBookGraph() *OrderBookGraph {
 	return graph
 }
 
+// SetPathCache enables caching of the payment paths found by FindPaths and
+// FindFixedPaths in the given cache. Caching is disabled if cache is nil.
+func (graph *OrderBookGraph) SetPathCache(cache *PathCache) {
+	graph.lock.Lock()
+	defer graph.lock.Unlock()
+	graph.pathCache = cache
+}
+
 // AddOffers will queue an operation to add the given offer(s) to the order book
 // in the internal batch.
 //
@@ -166,6 +178,29 @@ func (graph *OrderBookGraph) Offers() []xdr.OfferEntry {
 	return offers
 }
 
+// OffersForPair returns the offers selling `selling` in exchange for `buying`
+// sorted from cheapest to most expensive
+func (graph *OrderBookGraph) OffersForPair(selling, buying xdr.Asset) []xdr.OfferEntry {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	sellingID, ok := graph.assetStringToID[selling.String()]
+	if !ok {
+		return nil
+	}
+	buyingID, ok := graph.assetStringToID[buying.String()]
+	if !ok {
+		return nil
+	}
+
+	edges := graph.venuesForSellingAsset[sellingID]
+	i := edges.find(buyingID)
+	if i < 0 {
+		return nil
+	}
+	return append([]xdr.OfferEntry(nil), edges[i].value.offers...)
+}
+
 // Verify checks the internal consistency of the OrderBookGraph data structures
 // and returns all the offers and pools contained in the graph.
 func (graph *OrderBookGraph) Verify() ([]xdr.OfferEntry, []xdr.LiquidityPoolEntry, error) {
@@ -518,6 +553,9 @@ func (graph *OrderBookGraph) Clear() {
 	graph.liquidityPools = map[tradingPair]xdr.LiquidityPoolEntry{}
 	graph.batchedUpdates = graph.batch()
 	graph.lastLedger = 0
+	if graph.pathCache != nil {
+		graph.pathCache.clear()
+	}
 }
 
 // Batch creates a new batch of order book updates which can be applied
@@ -719,13 +757,47 @@ func (graph *OrderBookGraph) FindPaths(
 		paths:                  []Path{},
 		includePools:           includePools,
 	}
-	err := search(
-		ctx,
-		searchState,
-		maxPathLength,
-		destinationAssetID,
-		destinationAmount,
-	)
+	var err error
+	if graph.pathCache != nil {
+		// The offers ignored and the balances validated change the routes
+		// found by the search, so they are part of the cache key.
+		restrictions := ""
+		if sourceAccountID != nil {
+			restrictions = sourceAccountID.Address()
+		}
+		sourceAssetStrings := make([]string, len(sourceAssets))
+		for i, sourceAsset := range sourceAssets {
+			sourceAssetStrings[i] = sourceAsset.String()
+		}
+		if validateSourceBalance {
+			balances := make([]string, len(sourceAssets))
+			for i, sourceAsset := range sourceAssetStrings {
+				balances[i] = fmt.Sprintf("%s=%d", sourceAsset, sourceAssetBalances[i])
+			}
+			sort.Strings(balances)
+			restrictions += ":" + strings.Join(balances, ",")
+		}
+		err = graph.findCachedPaths(
+			ctx,
+			searchState,
+			sortBySourceAsset,
+			destinationAssetString,
+			sourceAssetStrings,
+			restrictions,
+			maxPathLength,
+			destinationAssetID,
+			destinationAmount,
+			includePools,
+		)
+	} else {
+		err = search(
+			ctx,
+			searchState,
+			maxPathLength,
+			destinationAssetID,
+			destinationAmount,
+		)
+	}
 	lastLedger := graph.lastLedger
 	graph.lock.RUnlock()
 	if err != nil {
@@ -740,6 +812,57 @@ func (graph *OrderBookGraph) FindPaths(
 	return paths, lastLedger, err
 }
 
+// findCachedPaths evaluates the routes cached for a search against the current
+// state of the graph and appends the viable ones to the paths of state. If the
+// routes are not cached they are found by searching the graph with state.
+// restrictions describes the parameters of state which are not arguments of
+// findCachedPaths but change the routes found, ex. the offers ignored.
+//
+// findCachedPaths must be called with the graph's read lock held, so that the
+// cached routes are consistent with lastLedger.
+func (graph *OrderBookGraph) findCachedPaths(
+	ctx context.Context,
+	state searchState,
+	searchType sortByType,
+	startAsset string,
+	targetAssets []string,
+	restrictions string,
+	maxPathLength int,
+	startAssetID int32,
+	amount xdr.Int64,
+	includePools bool,
+) error {
+	key := pathCacheKey(searchType, startAsset, targetAssets, restrictions, amount, maxPathLength, includePools)
+	if routes, ok := graph.pathCache.get(key, graph.lastLedger); ok {
+		for _, route := range routes {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := evaluateRoute(graph, state, route, amount); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := search(ctx, state, maxPathLength, startAssetID, amount); err != nil {
+		return err
+	}
+	var found []Path
+	switch state := state.(type) {
+	case *sellingGraphSearchState:
+		found = state.paths
+	case *buyingGraphSearchState:
+		found = state.paths
+	}
+	routes := make([][]string, len(found))
+	for i, path := range found {
+		routes[i] = routeForPath(searchType, path)
+	}
+	graph.pathCache.add(newPathCacheEntry(key, graph.lastLedger, startAsset, targetAssets, routes))
+	return nil
+}
+
 type sortablePaths struct {
 	paths []Path
 	less  func(paths []Path, i, j int) bool
@@ -799,13 +922,33 @@ func (graph *OrderBookGraph) FindFixedPaths(
 		paths:             []Path{},
 		includePools:      includePools,
 	}
-	err := search(
-		ctx,
-		searchState,
-		maxPathLength,
-		sourceAssetID,
-		amountToSpend,
-	)
+	var err error
+	if graph.pathCache != nil {
+		destinationAssetStrings := make([]string, len(destinationAssets))
+		for i, destinationAsset := range destinationAssets {
+			destinationAssetStrings[i] = destinationAsset.String()
+		}
+		err = graph.findCachedPaths(
+			ctx,
+			searchState,
+			sortByDestinationAsset,
+			sourceAssetString,
+			destinationAssetStrings,
+			"",
+			maxPathLength,
+			sourceAssetID,
+			amountToSpend,
+			includePools,
+		)
+	} else {
+		err = search(
+			ctx,
+			searchState,
+			maxPathLength,
+			sourceAssetID,
+			amountToSpend,
+		)
+	}
 	lastLedger := graph.lastLedger
 	graph.lock.RUnlock()
 	if err != nil {
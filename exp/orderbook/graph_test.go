@@ -803,6 +803,23 @@ func TestDiscard(t *testing.T) {
 	assertOfferListEquals(t, graph.Offers(), expectedOffers)
 }
 
+func TestOffersForPair(t *testing.T) {
+	graph := NewOrderBookGraph()
+	graph.AddOffers(dollarOffer, eurOffer, quarterOffer, fiftyCentsOffer)
+	if !assert.NoError(t, graph.Apply(1)) {
+		t.FailNow()
+	}
+
+	assert.Equal(
+		t,
+		[]xdr.OfferEntry{quarterOffer, fiftyCentsOffer, dollarOffer},
+		graph.OffersForPair(nativeAsset, usdAsset),
+	)
+	assert.Equal(t, []xdr.OfferEntry{eurOffer}, graph.OffersForPair(nativeAsset, eurAsset))
+	assert.Empty(t, graph.OffersForPair(usdAsset, nativeAsset))
+	assert.Empty(t, graph.OffersForPair(nativeAsset, chfAsset))
+}
+
 func TestRemoveOfferOrderBook(t *testing.T) {
 	graph := NewOrderBookGraph()
 
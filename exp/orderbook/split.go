@@ -0,0 +1,404 @@
+package orderbook
+
+import (
+	"context"
+	"strings"
+
+	"github.com/diamnet/go/price"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// splitPathIncrements is the number of parts in which the amount of a split
+// payment is divided and allocated to the payment paths.
+const splitPathIncrements = 20
+
+// offerFill is the amount sold by an offer in a simulated trade.
+type offerFill struct {
+	offerID xdr.Int64
+	amount  xdr.Int64
+}
+
+// hopTrade is a simulated trade between two adjacent assets of a payment
+// path, which is executed either with a liquidity pool or with offers.
+type hopTrade struct {
+	// pool is the state of the liquidity pool after the trade, or nil if the
+	// trade consumed offers.
+	pool  *liquidityPool
+	fills []offerFill
+}
+
+// splitCandidateSearchState is a buying search state ignoring some edges of
+// the graph, so that successive searches find different routes.
+type splitCandidateSearchState struct {
+	*buyingGraphSearchState
+	excluded map[int32]map[int32]bool
+}
+
+func (state *splitCandidateSearchState) exclude(from, to int32) {
+	if state.excluded[from] == nil {
+		state.excluded[from] = map[int32]bool{}
+	}
+	state.excluded[from][to] = true
+}
+
+func (state *splitCandidateSearchState) venues(currentAsset int32) edgeSet {
+	edges := state.buyingGraphSearchState.venues(currentAsset)
+	excluded := state.excluded[currentAsset]
+	if len(excluded) == 0 {
+		return edges
+	}
+	filtered := make(edgeSet, 0, len(edges))
+	for _, e := range edges {
+		if !excluded[e.key] {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// splitPathState simulates the consumption of the order book by the payment
+// paths of a split payment, so that the paths sharing edges are evaluated
+// with the prices left by the previous ones.
+type splitPathState struct {
+	graph        *OrderBookGraph
+	includePools bool
+	offersSold   map[xdr.Int64]xdr.Int64
+	pools        map[xdr.PoolId]liquidityPool
+}
+
+func newSplitPathState(graph *OrderBookGraph, includePools bool) *splitPathState {
+	return &splitPathState{
+		graph:        graph,
+		includePools: includePools,
+		offersSold:   map[xdr.Int64]xdr.Int64{},
+		pools:        map[xdr.PoolId]liquidityPool{},
+	}
+}
+
+// sendOffers sells amount of the buying asset of offers, consuming what is
+// left of them by previous trades. It returns -1 if the offers cannot absorb
+// amount, like consumeOffersForBuyingAsset.
+func (s *splitPathState) sendOffers(offers []xdr.OfferEntry, amount xdr.Int64) (xdr.Int64, []offerFill, error) {
+	received := xdr.Int64(0)
+	var fills []offerFill
+	for _, offer := range offers {
+		available := offer.Amount - s.offersSold[offer.OfferId]
+		if available <= 0 {
+			continue
+		}
+		n, d := int64(offer.Price.N), int64(offer.Price.D)
+
+		// check if we can spend all of amount on the current offer otherwise
+		// consume what is left of the offer and move on to the next one
+		amountSold, err := price.MulFractionRoundDown(int64(amount), d, n)
+		if err == nil {
+			if amountSold == 0 {
+				return -1, nil, nil
+			}
+			if amountSold < 0 {
+				return -1, nil, errSoldTooMuch
+			}
+			if xdr.Int64(amountSold) <= available {
+				fills = append(fills, offerFill{offerID: offer.OfferId, amount: xdr.Int64(amountSold)})
+				return received + xdr.Int64(amountSold), fills, nil
+			}
+		} else if err != price.ErrOverflow {
+			return -1, nil, err
+		}
+
+		buyingUnits, sellingUnits, err := price.ConvertToBuyingUnits(int64(available), int64(available), n, d)
+		if err == price.ErrOverflow {
+			return -1, nil, nil
+		} else if err != nil {
+			return -1, nil, err
+		}
+
+		fills = append(fills, offerFill{offerID: offer.OfferId, amount: xdr.Int64(sellingUnits)})
+		received += xdr.Int64(sellingUnits)
+		amount -= xdr.Int64(buyingUnits)
+		if amount == 0 {
+			return received, fills, nil
+		}
+		if amount < 0 {
+			return -1, nil, errSoldTooMuch
+		}
+	}
+	return -1, nil, nil
+}
+
+// sendPool deposits amount of asset into the current state of pool.
+func (s *splitPathState) sendPool(pool liquidityPool, asset int32, amount xdr.Int64) (xdr.Int64, *liquidityPool) {
+	if current, ok := s.pools[pool.LiquidityPoolId]; ok {
+		pool = current
+	}
+	received, err := makeTrade(pool, asset, tradeTypeDeposit, amount)
+	if err != nil || received <= 0 {
+		return 0, nil
+	}
+
+	// Copy the pool body so the graph is not modified
+	body := *pool.Body.ConstantProduct
+	if pool.assetA == asset {
+		body.ReserveA += amount
+		body.ReserveB -= received
+	} else {
+		body.ReserveB += amount
+		body.ReserveA -= received
+	}
+	updated := pool
+	updated.LiquidityPoolEntry.Body.ConstantProduct = &body
+	return received, &updated
+}
+
+// send simulates sending amount along route without modifying the state. It
+// returns the amount received at the end of the route, or 0 if the route
+// cannot absorb amount, along with the trades to commit.
+func (s *splitPathState) send(route []int32, amount xdr.Int64) (xdr.Int64, []hopTrade, error) {
+	trades := make([]hopTrade, 0, len(route)-1)
+	currentAsset, currentAmount := route[0], amount
+	for _, nextAsset := range route[1:] {
+		edges := s.graph.venuesForBuyingAsset[currentAsset]
+		i := edges.find(nextAsset)
+		if i < 0 {
+			return 0, nil, nil
+		}
+		venues := edges[i].value
+
+		// Like path payments, every hop is executed either with the liquidity
+		// pool or with the offers, whichever is better.
+		poolAmount, pool := xdr.Int64(0), (*liquidityPool)(nil)
+		if s.includePools && venues.pool.Body.ConstantProduct != nil {
+			poolAmount, pool = s.sendPool(venues.pool, currentAsset, currentAmount)
+		}
+		offersAmount, fills := xdr.Int64(-1), []offerFill(nil)
+		if len(venues.offers) > 0 {
+			var err error
+			offersAmount, fills, err = s.sendOffers(venues.offers, currentAmount)
+			if err != nil && poolAmount == 0 {
+				return 0, nil, err
+			}
+		}
+
+		if poolAmount <= 0 && offersAmount <= 0 {
+			return 0, nil, nil
+		}
+		if poolAmount > offersAmount {
+			trades = append(trades, hopTrade{pool: pool})
+			currentAmount = poolAmount
+		} else {
+			trades = append(trades, hopTrade{fills: fills})
+			currentAmount = offersAmount
+		}
+		currentAsset = nextAsset
+	}
+	return currentAmount, trades, nil
+}
+
+func (s *splitPathState) commit(trades []hopTrade) {
+	for _, trade := range trades {
+		if trade.pool != nil {
+			s.pools[trade.pool.LiquidityPoolId] = *trade.pool
+		}
+		for _, fill := range trade.fills {
+			s.offersSold[fill.offerID] += fill.amount
+		}
+	}
+}
+
+// FindSplitPaths splits a strict send payment of `amountToSpend` of
+// `sourceAsset` across up to `maxPaths` payment paths ending with
+// `destinationAsset`, so as to maximize the total amount received.
+//
+// The amount is allocated in increments to the path with the best marginal
+// rate, taking into account the offers and liquidity pools consumed by the
+// increments already allocated to other paths sharing the same edges. The
+// returned paths must be executed in order: the destination amount of every
+// path is computed after executing the previous ones.
+//
+// A single path is returned if splitting the payment does not improve the
+// total amount received, and no paths are returned if the amount cannot be
+// sent to `destinationAsset`.
+func (graph *OrderBookGraph) FindSplitPaths(
+	ctx context.Context,
+	maxPathLength int,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAsset xdr.Asset,
+	maxPaths int,
+	includePools bool,
+) ([]Path, uint32, error) {
+	if maxPaths <= 0 {
+		return nil, 0, errors.New("maxPaths must be positive")
+	}
+	if amountToSpend <= 0 {
+		return nil, 0, errBadAmount
+	}
+
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	sourceAssetString := sourceAsset.String()
+	sourceAssetID, ok := graph.assetStringToID[sourceAssetString]
+	if !ok {
+		return []Path{}, graph.lastLedger, nil
+	}
+	destinationAssetID, ok := graph.assetStringToID[destinationAsset.String()]
+	if !ok {
+		return []Path{}, graph.lastLedger, nil
+	}
+
+	increments := xdr.Int64(splitPathIncrements)
+	if amountToSpend < increments {
+		increments = amountToSpend
+	}
+	increment := amountToSpend / increments
+
+	// Searches only return the best path to the destination asset, so the
+	// candidate routes are found by searching again without the edges of the
+	// routes already found, both for a single increment and for the whole
+	// amount. Searches do not account for the liquidity consumed by the other
+	// routes, so a route sharing an edge with a previous one would be valued
+	// with liquidity the previous route already takes.
+	var routes [][]int32
+	seen := map[string]bool{}
+	for _, amount := range []xdr.Int64{increment, amountToSpend} {
+		searchState := &splitCandidateSearchState{
+			buyingGraphSearchState: &buyingGraphSearchState{
+				graph:             graph,
+				sourceAssetString: sourceAssetString,
+				sourceAssetAmount: amount,
+				targetAssets:      map[int32]bool{destinationAssetID: true},
+				includePools:      includePools,
+			},
+			excluded: map[int32]map[int32]bool{},
+		}
+		for i := 0; i < maxPaths; i++ {
+			searchState.paths = []Path{}
+			if err := search(ctx, searchState, maxPathLength, sourceAssetID, amount); err != nil {
+				return nil, graph.lastLedger, errors.Wrap(err, "could not determine paths")
+			}
+			found, err := sortAndFilterPaths(searchState.paths, 1, sortByDestinationAsset)
+			if err != nil {
+				return nil, graph.lastLedger, err
+			}
+			if len(found) == 0 {
+				break
+			}
+
+			route := routeForPath(sortByDestinationAsset, found[0])
+			ids := make([]int32, len(route))
+			for j, asset := range route {
+				ids[j] = graph.assetStringToID[asset]
+			}
+			for j := 1; j < len(ids); j++ {
+				searchState.exclude(ids[j-1], ids[j])
+			}
+			key := strings.Join(route, ",")
+			if !seen[key] {
+				seen[key] = true
+				routes = append(routes, ids)
+			}
+		}
+	}
+
+	paths, total, err := allocateSplitPaths(ctx, graph, routes, amountToSpend, increments, maxPaths, includePools)
+	if err != nil {
+		return nil, graph.lastLedger, err
+	}
+
+	// Fall back to the best single path if the increments cannot be
+	// allocated or if splitting does not pay off
+	if len(paths) != 1 {
+		state := newSplitPathState(graph, includePools)
+		for _, route := range routes {
+			received, _, err := state.send(route, amountToSpend)
+			if err != nil {
+				return nil, graph.lastLedger, err
+			}
+			if received > 0 && received >= total {
+				paths, total = []Path{newSplitPath(graph, route, amountToSpend, received)}, received
+			}
+		}
+	}
+	return paths, graph.lastLedger, nil
+}
+
+// allocateSplitPaths allocates amountToSpend in increments to the routes
+// receiving the most for every increment. It returns the paths in execution
+// order along with the total amount received, or no paths if some increment
+// cannot be sent along any route.
+func allocateSplitPaths(
+	ctx context.Context,
+	graph *OrderBookGraph,
+	routes [][]int32,
+	amountToSpend xdr.Int64,
+	increments xdr.Int64,
+	maxPaths int,
+	includePools bool,
+) ([]Path, xdr.Int64, error) {
+	increment := amountToSpend / increments
+	state := newSplitPathState(graph, includePools)
+	allocations := make([]xdr.Int64, len(routes))
+	var order []int
+	for i := xdr.Int64(0); i < increments; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		amount := increment
+		if i == increments-1 {
+			amount = amountToSpend - increment*(increments-1)
+		}
+
+		best, bestReceived := -1, xdr.Int64(0)
+		var bestTrades []hopTrade
+		for j, route := range routes {
+			if len(order) >= maxPaths && allocations[j] == 0 {
+				continue
+			}
+			received, trades, err := state.send(route, amount)
+			if err != nil {
+				return nil, 0, err
+			}
+			if received > bestReceived {
+				best, bestReceived, bestTrades = j, received, trades
+			}
+		}
+		if best < 0 {
+			return []Path{}, 0, nil
+		}
+		if allocations[best] == 0 {
+			order = append(order, best)
+		}
+		allocations[best] += amount
+		state.commit(bestTrades)
+	}
+
+	// Evaluate the paths with their whole allocation, in execution order
+	state = newSplitPathState(graph, includePools)
+	paths := make([]Path, 0, len(order))
+	total := xdr.Int64(0)
+	for _, j := range order {
+		received, trades, err := state.send(routes[j], allocations[j])
+		if err != nil {
+			return nil, 0, err
+		}
+		if received <= 0 {
+			return []Path{}, 0, nil
+		}
+		state.commit(trades)
+		paths = append(paths, newSplitPath(graph, routes[j], allocations[j], received))
+		total += received
+	}
+	return paths, total, nil
+}
+
+func newSplitPath(graph *OrderBookGraph, route []int32, sourceAmount, destinationAmount xdr.Int64) Path {
+	return Path{
+		SourceAsset:       graph.idToAssetString[route[0]],
+		SourceAmount:      sourceAmount,
+		DestinationAsset:  graph.idToAssetString[route[len(route)-1]],
+		DestinationAmount: destinationAmount,
+		InteriorNodes:     assetIDsToAssetStrings(graph, route[1:len(route)-1]),
+	}
+}
@@ -0,0 +1,172 @@
+package orderbook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diamnet/go/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSplitPaths(t *testing.T) {
+	graph := NewOrderBookGraph()
+	graph.AddLiquidityPools(
+		eurUsdLiquidityPool,
+		eurYenLiquidityPool,
+		makePool(usdAsset, yenAsset, 1000, 1000),
+	)
+	if !assert.NoError(t, graph.Apply(1)) {
+		t.FailNow()
+	}
+
+	fixedPaths, _, err := graph.FindFixedPaths(
+		context.TODO(), 3, usdAsset, 400, []xdr.Asset{eurAsset}, 5, true,
+	)
+	assert.NoError(t, err)
+	bestSinglePath := fixedPaths[0]
+	assert.Empty(t, bestSinglePath.InteriorNodes)
+
+	paths, lastLedger, err := graph.FindSplitPaths(
+		context.TODO(), 3, usdAsset, 400, eurAsset, 3, true,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), lastLedger)
+	if !assert.Len(t, paths, 2) {
+		t.FailNow()
+	}
+
+	// The direct path has the best rate and is allocated first
+	assert.Empty(t, paths[0].InteriorNodes)
+	assert.Equal(t, []string{yenAsset.String()}, paths[1].InteriorNodes)
+	total := xdr.Int64(0)
+	for _, path := range paths {
+		assert.Equal(t, usdAsset.String(), path.SourceAsset)
+		assert.Equal(t, eurAsset.String(), path.DestinationAsset)
+		assert.True(t, path.SourceAmount > 0)
+		total += path.DestinationAmount
+	}
+	assert.Equal(t, xdr.Int64(400), paths[0].SourceAmount+paths[1].SourceAmount)
+	assert.True(t, total > bestSinglePath.DestinationAmount)
+
+	// The amount received on every path accounts for the previous paths
+	direct, _, err := graph.FindSplitPaths(
+		context.TODO(), 3, usdAsset, paths[0].SourceAmount, eurAsset, 1, true,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, paths[0].DestinationAmount, direct[0].DestinationAmount)
+
+	paths, _, err = graph.FindSplitPaths(
+		context.TODO(), 3, usdAsset, 400, eurAsset, 1, true,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []Path{bestSinglePath}, paths)
+
+	// Splitting small amounts does not pay off
+	paths, _, err = graph.FindSplitPaths(
+		context.TODO(), 3, usdAsset, 10, eurAsset, 3, true,
+	)
+	assert.NoError(t, err)
+	assert.Len(t, paths, 1)
+	assert.Equal(t, xdr.Int64(10), paths[0].SourceAmount)
+
+	paths, _, err = graph.FindSplitPaths(
+		context.TODO(), 3, usdAsset, 400, chfAsset, 3, true,
+	)
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+
+	_, _, err = graph.FindSplitPaths(
+		context.TODO(), 3, usdAsset, 0, eurAsset, 3, true,
+	)
+	assert.Error(t, err)
+}
+
+func TestFindSplitPathsLimitedLiquidity(t *testing.T) {
+	graph := NewOrderBookGraph()
+	eurNativeOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  xdr.Int64(20),
+		Buying:   eurAsset,
+		Selling:  nativeAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   xdr.Int64(100),
+	}
+	usdEurOffer := xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  xdr.Int64(21),
+		Buying:   usdAsset,
+		Selling:  eurAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   xdr.Int64(1000),
+	}
+	graph.AddOffers(eurNativeOffer, usdEurOffer)
+	graph.AddLiquidityPools(nativeUsdPool)
+	if !assert.NoError(t, graph.Apply(1)) {
+		t.FailNow()
+	}
+
+	// The path through EUR cannot absorb the whole amount, so the increments
+	// go through the pool until its price drops and then through EUR until
+	// the offer is consumed.
+	paths, _, err := graph.FindSplitPaths(
+		context.TODO(), 3, usdAsset, 150, nativeAsset, 3, true,
+	)
+	assert.NoError(t, err)
+	if !assert.Len(t, paths, 2) {
+		t.FailNow()
+	}
+	total := xdr.Int64(0)
+	for _, path := range paths {
+		total += path.SourceAmount
+		if len(path.InteriorNodes) > 0 {
+			assert.Equal(t, []string{eurAsset.String()}, path.InteriorNodes)
+			assert.True(t, path.DestinationAmount <= eurNativeOffer.Amount)
+		}
+	}
+	assert.Equal(t, xdr.Int64(150), total)
+}
+
+func TestFindSplitPathsSharedEdges(t *testing.T) {
+	graph := NewOrderBookGraph()
+	offer := func(id int64, selling, buying xdr.Asset, n xdr.Int32, amount xdr.Int64) xdr.OfferEntry {
+		return xdr.OfferEntry{
+			SellerId: issuer,
+			OfferId:  xdr.Int64(id),
+			Buying:   buying,
+			Selling:  selling,
+			Price:    xdr.Price{N: n, D: 1},
+			Amount:   amount,
+		}
+	}
+	graph.AddOffers(
+		offer(30, eurAsset, usdAsset, 1, 1000),
+		offer(31, yenAsset, usdAsset, 1, 1000),
+		offer(32, eurAsset, yenAsset, 1, 1000),
+		// EUR -> CHF is shared by USD -> EUR -> CHF -> native and
+		// USD -> YEN -> EUR -> CHF -> native, its price rises after 100 CHF
+		offer(33, chfAsset, eurAsset, 1, 100),
+		offer(34, chfAsset, eurAsset, 4, 1000),
+		offer(35, nativeAsset, chfAsset, 1, 1000),
+		offer(36, nativeAsset, usdAsset, 2, 1000),
+	)
+	if !assert.NoError(t, graph.Apply(1)) {
+		t.FailNow()
+	}
+
+	// Once the cheap CHF is consumed the direct path receives more than the
+	// routes through EUR -> CHF, so it must be one of the candidates even
+	// though the route through YEN is better on an untouched order book.
+	paths, _, err := graph.FindSplitPaths(
+		context.TODO(), 4, usdAsset, 200, nativeAsset, 2, false,
+	)
+	assert.NoError(t, err)
+	if !assert.Len(t, paths, 2) {
+		t.FailNow()
+	}
+	assert.Equal(t, []string{eurAsset.String(), chfAsset.String()}, paths[0].InteriorNodes)
+	assert.Equal(t, xdr.Int64(100), paths[0].SourceAmount)
+	assert.Equal(t, xdr.Int64(100), paths[0].DestinationAmount)
+	assert.Empty(t, paths[1].InteriorNodes)
+	assert.Equal(t, xdr.Int64(100), paths[1].SourceAmount)
+	assert.Equal(t, xdr.Int64(50), paths[1].DestinationAmount)
+}
@@ -0,0 +1,293 @@
+package ledgerbackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/diamnet/go/historyarchive"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+const (
+	// FileLedgerManifestPath is the path of the manifest of the ledgers
+	// exported by FileLedgerExporter.
+	FileLedgerManifestPath = "manifest.json"
+	// DefaultLedgersPerFile is the number of ledgers in every file written by
+	// FileLedgerExporter when it is not set.
+	DefaultLedgersPerFile = 64
+
+	fileLedgerManifestVersion = 1
+)
+
+// FileLedgerManifest describes the files written by FileLedgerExporter. The
+// ledgers from FirstLedger to LastLedger (inclusive) are stored in gzipped
+// files of framed LedgerCloseMeta XDR, see FileLedgerPath.
+type FileLedgerManifest struct {
+	Version        uint32 `json:"version"`
+	LedgersPerFile uint32 `json:"ledgers_per_file"`
+	FirstLedger    uint32 `json:"first_ledger"`
+	LastLedger     uint32 `json:"last_ledger"`
+}
+
+// FileLedgerPath returns the path of the file containing the given ledger
+// when every file contains ledgersPerFile ledgers. Files start at multiples of
+// ledgersPerFile.
+func FileLedgerPath(ledgersPerFile, sequence uint32) string {
+	start := sequence / ledgersPerFile * ledgersPerFile
+	return fmt.Sprintf("ledgers/%08x-%08x.xdr.gz", start, start+ledgersPerFile-1)
+}
+
+// FileLedgerBackend is a LedgerBackend reading the ledgers exported by
+// FileLedgerExporter from a directory (file://), an S3 bucket (s3://) or an
+// HTTP server (http(s)://). It does not need diamnet-core so several
+// FileLedgerBackends can read the same files concurrently, for example to
+// reingest ledgers in parallel.
+//
+// The manifest is polled while unbounded ranges wait for new ledgers, so the
+// backend can follow an export in progress.
+type FileLedgerBackend struct {
+	storage      historyarchive.ArchiveBackend
+	pollInterval time.Duration
+
+	mutex     sync.Mutex
+	manifest  *FileLedgerManifest
+	prepared  *Range
+	filePath  string
+	fileCache map[uint32]xdr.LedgerCloseMeta
+	closed    bool
+	// done is closed by Close to stop waiting for ledgers
+	done chan struct{}
+}
+
+// Ensure FileLedgerBackend implements LedgerBackend
+var _ LedgerBackend = (*FileLedgerBackend)(nil)
+
+// NewFileLedgerBackend returns a FileLedgerBackend reading the files of the
+// given storage, see historyarchive.ConnectBackend.
+func NewFileLedgerBackend(storage historyarchive.ArchiveBackend) *FileLedgerBackend {
+	return &FileLedgerBackend{
+		storage:      storage,
+		pollInterval: time.Second,
+		done:         make(chan struct{}),
+	}
+}
+
+// GetLatestLedgerSequence returns the last ledger of the manifest.
+func (b *FileLedgerBackend) GetLatestLedgerSequence(ctx context.Context) (uint32, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return 0, errors.New("backend is closed")
+	}
+	if err := b.loadManifest(); err != nil {
+		return 0, err
+	}
+	return b.manifest.LastLedger, nil
+}
+
+// PrepareRange checks that the ledgers of the range are exported. Unbounded
+// ranges block until their first ledger is exported.
+func (b *FileLedgerBackend) PrepareRange(ctx context.Context, ledgerRange Range) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return errors.New("backend is closed")
+	}
+	if err := b.loadManifest(); err != nil {
+		return err
+	}
+	if ledgerRange.from < b.manifest.FirstLedger {
+		return errors.Errorf(
+			"ledger %d is not exported, the first exported ledger is %d",
+			ledgerRange.from,
+			b.manifest.FirstLedger,
+		)
+	}
+	if ledgerRange.bounded && ledgerRange.to > b.manifest.LastLedger {
+		return errors.Errorf(
+			"ledger %d is not exported, the last exported ledger is %d",
+			ledgerRange.to,
+			b.manifest.LastLedger,
+		)
+	}
+	if err := b.waitForLedger(ctx, ledgerRange.from); err != nil {
+		return err
+	}
+
+	b.prepared = &ledgerRange
+	return nil
+}
+
+// IsPrepared returns true if the given range is within the prepared range.
+func (b *FileLedgerBackend) IsPrepared(ctx context.Context, ledgerRange Range) (bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.prepared != nil && b.prepared.Contains(ledgerRange), nil
+}
+
+// GetLedger returns the given ledger, which must be in the prepared range. In
+// unbounded ranges it blocks until the ledger is exported.
+func (b *FileLedgerBackend) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return xdr.LedgerCloseMeta{}, errors.New("backend is closed")
+	}
+	if b.prepared == nil {
+		return xdr.LedgerCloseMeta{}, errors.New("session is not prepared, call PrepareRange first")
+	}
+	if !b.prepared.Contains(SingleLedgerRange(sequence)) {
+		return xdr.LedgerCloseMeta{}, errors.Errorf("ledger %d is outside the prepared range %s", sequence, b.prepared)
+	}
+	if err := b.waitForLedger(ctx, sequence); err != nil {
+		return xdr.LedgerCloseMeta{}, err
+	}
+
+	// The cached file is read again if it has been extended since it was read
+	filePath := FileLedgerPath(b.manifest.LedgersPerFile, sequence)
+	if _, ok := b.fileCache[sequence]; filePath != b.filePath || !ok {
+		ledgers, err := readLedgerFile(b.storage, filePath)
+		if err != nil {
+			return xdr.LedgerCloseMeta{}, err
+		}
+		b.fileCache = map[uint32]xdr.LedgerCloseMeta{}
+		for _, ledger := range ledgers {
+			b.fileCache[ledger.LedgerSequence()] = ledger
+		}
+		b.filePath = filePath
+	}
+
+	ledger, ok := b.fileCache[sequence]
+	if !ok {
+		return xdr.LedgerCloseMeta{}, errors.Errorf("ledger %d not found in %s", sequence, filePath)
+	}
+	return ledger, nil
+}
+
+// Close closes the backend.
+func (b *FileLedgerBackend) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if !b.closed {
+		close(b.done)
+	}
+	b.closed = true
+	b.fileCache = nil
+	b.filePath = ""
+	return nil
+}
+
+// waitForLedger polls the manifest until the given ledger is exported. It is
+// called with b.mutex held, which is released between the polls so that Close
+// isn't blocked by a waiting GetLedger or PrepareRange.
+func (b *FileLedgerBackend) waitForLedger(ctx context.Context, sequence uint32) error {
+	for sequence > b.manifest.LastLedger {
+		b.mutex.Unlock()
+		var err error
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-b.done:
+		case <-time.After(b.pollInterval):
+		}
+		b.mutex.Lock()
+
+		if err != nil {
+			return err
+		}
+		if b.closed {
+			return errors.New("backend is closed")
+		}
+		if err := b.loadManifest(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *FileLedgerBackend) loadManifest() error {
+	manifest, err := readFileLedgerManifest(b.storage)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		return errors.Errorf("%s not found, no ledgers are exported", FileLedgerManifestPath)
+	}
+	b.manifest = manifest
+	return nil
+}
+
+// readFileLedgerManifest returns the manifest of the storage or nil if there
+// is none.
+func readFileLedgerManifest(storage historyarchive.ArchiveBackend) (*FileLedgerManifest, error) {
+	exists, err := storage.Exists(FileLedgerManifestPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not check if %s exists", FileLedgerManifestPath)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	file, err := storage.GetFile(FileLedgerManifestPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open %s", FileLedgerManifestPath)
+	}
+	defer file.Close()
+	var manifest FileLedgerManifest
+	if err = json.NewDecoder(file).Decode(&manifest); err != nil {
+		return nil, errors.Wrapf(err, "could not decode %s", FileLedgerManifestPath)
+	}
+	if manifest.Version != fileLedgerManifestVersion {
+		return nil, errors.Errorf("unsupported %s version %d", FileLedgerManifestPath, manifest.Version)
+	}
+	if manifest.LedgersPerFile == 0 {
+		return nil, errors.Errorf("invalid %s: ledgers_per_file is 0", FileLedgerManifestPath)
+	}
+	return &manifest, nil
+}
+
+func writeFileLedgerManifest(storage historyarchive.ArchiveBackend, manifest FileLedgerManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return errors.Wrapf(err, "could not encode %s", FileLedgerManifestPath)
+	}
+	if err = storage.PutFile(FileLedgerManifestPath, ioutil.NopCloser(bytes.NewReader(data))); err != nil {
+		return errors.Wrapf(err, "could not write %s", FileLedgerManifestPath)
+	}
+	return nil
+}
+
+func readLedgerFile(storage historyarchive.ArchiveBackend, filePath string) ([]xdr.LedgerCloseMeta, error) {
+	file, err := storage.GetFile(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open %s", filePath)
+	}
+	stream, err := historyarchive.NewXdrGzStream(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", filePath)
+	}
+	defer stream.Close()
+
+	var ledgers []xdr.LedgerCloseMeta
+	for {
+		var ledger xdr.LedgerCloseMeta
+		err = stream.ReadOne(&ledger)
+		if err == io.EOF {
+			return ledgers, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read %s", filePath)
+		}
+		ledgers = append(ledgers, ledger)
+	}
+}
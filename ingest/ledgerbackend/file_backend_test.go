@@ -0,0 +1,147 @@
+package ledgerbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/historyarchive"
+	"github.com/diamnet/go/xdr"
+)
+
+func testLedgerCloseMeta(sequence uint32) xdr.LedgerCloseMeta {
+	return xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq: xdr.Uint32(sequence),
+				},
+			},
+		},
+	}
+}
+
+func exportTestLedgers(t *testing.T, exporter *FileLedgerExporter, from, to uint32) {
+	source := &MockDatabaseBackend{}
+	source.On("PrepareRange", mock.Anything, BoundedRange(from, to)).Return(nil).Once()
+	for sequence := from; sequence <= to; sequence++ {
+		source.On("GetLedger", mock.Anything, sequence).Return(testLedgerCloseMeta(sequence), nil).Once()
+	}
+	require.NoError(t, exporter.Export(context.Background(), source, from, to))
+	source.AssertExpectations(t)
+}
+
+func TestFileLedgerExporter(t *testing.T) {
+	storage, err := historyarchive.ConnectBackend("mock://test", historyarchive.ConnectOptions{})
+	require.NoError(t, err)
+	exporter := NewFileLedgerExporter(storage, 4)
+
+	exportTestLedgers(t, exporter, 6, 9)
+	manifest, err := readFileLedgerManifest(storage)
+	require.NoError(t, err)
+	assert.Equal(t, &FileLedgerManifest{Version: 1, LedgersPerFile: 4, FirstLedger: 6, LastLedger: 9}, manifest)
+
+	// Files are completed when the range is extended
+	exportTestLedgers(t, exporter, 2, 6)
+	exportTestLedgers(t, exporter, 10, 13)
+	manifest, err = readFileLedgerManifest(storage)
+	require.NoError(t, err)
+	assert.Equal(t, &FileLedgerManifest{Version: 1, LedgersPerFile: 4, FirstLedger: 2, LastLedger: 13}, manifest)
+
+	ledgers, err := readLedgerFile(storage, "ledgers/00000004-00000007.xdr.gz")
+	require.NoError(t, err)
+	var sequences []uint32
+	for _, ledger := range ledgers {
+		sequences = append(sequences, ledger.LedgerSequence())
+	}
+	assert.Equal(t, []uint32{4, 5, 6, 7}, sequences)
+
+	assert.EqualError(
+		t,
+		exporter.Export(context.Background(), &MockDatabaseBackend{}, 15, 20),
+		"range [15, 20] would leave a gap with the exported ledgers [2, 13]",
+	)
+	assert.EqualError(
+		t,
+		NewFileLedgerExporter(storage, 64).Export(context.Background(), &MockDatabaseBackend{}, 14, 20),
+		"the ledgers are exported in files of 4 ledgers, not 64",
+	)
+}
+
+func TestFileLedgerBackend(t *testing.T) {
+	storage, err := historyarchive.ConnectBackend("mock://test", historyarchive.ConnectOptions{})
+	require.NoError(t, err)
+	backend := NewFileLedgerBackend(storage)
+	backend.pollInterval = time.Millisecond
+	ctx := context.Background()
+
+	_, err = backend.GetLatestLedgerSequence(ctx)
+	assert.EqualError(t, err, "manifest.json not found, no ledgers are exported")
+
+	exporter := NewFileLedgerExporter(storage, 4)
+	exportTestLedgers(t, exporter, 2, 9)
+
+	latest, err := backend.GetLatestLedgerSequence(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(9), latest)
+
+	_, err = backend.GetLedger(ctx, 3)
+	assert.EqualError(t, err, "session is not prepared, call PrepareRange first")
+	assert.EqualError(
+		t,
+		backend.PrepareRange(ctx, BoundedRange(1, 5)),
+		"ledger 1 is not exported, the first exported ledger is 2",
+	)
+	assert.EqualError(
+		t,
+		backend.PrepareRange(ctx, BoundedRange(3, 10)),
+		"ledger 10 is not exported, the last exported ledger is 9",
+	)
+
+	require.NoError(t, backend.PrepareRange(ctx, BoundedRange(3, 9)))
+	prepared, err := backend.IsPrepared(ctx, BoundedRange(4, 9))
+	require.NoError(t, err)
+	assert.True(t, prepared)
+	for sequence := uint32(3); sequence <= 9; sequence++ {
+		ledger, err := backend.GetLedger(ctx, sequence)
+		require.NoError(t, err)
+		assert.Equal(t, testLedgerCloseMeta(sequence), ledger)
+	}
+	_, err = backend.GetLedger(ctx, 10)
+	assert.EqualError(t, err, "ledger 10 is outside the prepared range [3,9]")
+
+	// Unbounded ranges wait for the ledgers to be exported
+	require.NoError(t, backend.PrepareRange(ctx, UnboundedRange(8)))
+	go exportTestLedgers(t, exporter, 10, 10)
+	ledger, err := backend.GetLedger(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, testLedgerCloseMeta(10), ledger)
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = backend.GetLedger(timeout, 11)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// Close doesn't wait for the ledgers being waited for
+	errs := make(chan error)
+	go func() {
+		_, err := backend.GetLedger(ctx, 11)
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, backend.Close())
+	select {
+	case err = <-errs:
+		assert.EqualError(t, err, "backend is closed")
+	case <-time.After(time.Second):
+		t.Fatal("GetLedger is still waiting after Close")
+	}
+
+	_, err = backend.GetLedger(ctx, 9)
+	assert.EqualError(t, err, "backend is closed")
+	require.NoError(t, backend.Close())
+}
@@ -0,0 +1,160 @@
+package ledgerbackend
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+
+	"github.com/diamnet/go/historyarchive"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// FileLedgerExporter writes the ledgers of a LedgerBackend to files read by
+// FileLedgerBackend: gzipped files of framed LedgerCloseMeta XDR containing
+// LedgersPerFile ledgers each, and a manifest with the range of exported
+// ledgers.
+type FileLedgerExporter struct {
+	storage        historyarchive.ArchiveBackend
+	ledgersPerFile uint32
+}
+
+// NewFileLedgerExporter returns a FileLedgerExporter writing files of
+// ledgersPerFile ledgers (DefaultLedgersPerFile if 0) to the given storage.
+func NewFileLedgerExporter(storage historyarchive.ArchiveBackend, ledgersPerFile uint32) *FileLedgerExporter {
+	if ledgersPerFile == 0 {
+		ledgersPerFile = DefaultLedgersPerFile
+	}
+	return &FileLedgerExporter{
+		storage:        storage,
+		ledgersPerFile: ledgersPerFile,
+	}
+}
+
+// Export writes the ledgers from `from` to `to` (inclusive) read from source.
+// The range must overlap or be adjacent to the ledgers already exported so
+// that the exported ledgers have no gaps. The manifest is updated after every
+// file so an interrupted export can be continued.
+func (e *FileLedgerExporter) Export(ctx context.Context, source LedgerBackend, from, to uint32) error {
+	if from == 0 || from > to {
+		return errors.Errorf("invalid range [%d, %d]", from, to)
+	}
+	manifest, err := readFileLedgerManifest(e.storage)
+	if err != nil {
+		return err
+	}
+	if manifest != nil {
+		if manifest.LedgersPerFile != e.ledgersPerFile {
+			return errors.Errorf(
+				"the ledgers are exported in files of %d ledgers, not %d",
+				manifest.LedgersPerFile,
+				e.ledgersPerFile,
+			)
+		}
+		if from > manifest.LastLedger+1 || to+1 < manifest.FirstLedger {
+			return errors.Errorf(
+				"range [%d, %d] would leave a gap with the exported ledgers [%d, %d]",
+				from, to, manifest.FirstLedger, manifest.LastLedger,
+			)
+		}
+	}
+
+	if err = source.PrepareRange(ctx, BoundedRange(from, to)); err != nil {
+		return errors.Wrap(err, "could not prepare range")
+	}
+
+	for start := from; start <= to; {
+		end := start/e.ledgersPerFile*e.ledgersPerFile + e.ledgersPerFile - 1
+		if end > to {
+			end = to
+		}
+
+		var ledgers []xdr.LedgerCloseMeta
+		for sequence := start; sequence <= end; sequence++ {
+			ledger, err := source.GetLedger(ctx, sequence)
+			if err != nil {
+				return errors.Wrapf(err, "could not get ledger %d", sequence)
+			}
+			ledgers = append(ledgers, ledger)
+		}
+		if err = e.writeFile(manifest, ledgers); err != nil {
+			return err
+		}
+
+		// The manifest is only extended once the exported ledgers are
+		// contiguous with the ledgers exported before
+		switch {
+		case manifest == nil:
+			manifest = &FileLedgerManifest{
+				Version:        fileLedgerManifestVersion,
+				LedgersPerFile: e.ledgersPerFile,
+				FirstLedger:    from,
+				LastLedger:     end,
+			}
+		case end+1 >= manifest.FirstLedger:
+			if from < manifest.FirstLedger {
+				manifest.FirstLedger = from
+			}
+			if end > manifest.LastLedger {
+				manifest.LastLedger = end
+			}
+		}
+		if end+1 >= manifest.FirstLedger {
+			if err = writeFileLedgerManifest(e.storage, *manifest); err != nil {
+				return err
+			}
+		}
+
+		start = end + 1
+	}
+	return nil
+}
+
+// writeFile writes the file of the given ledgers, keeping the ledgers of the
+// file which were exported before.
+func (e *FileLedgerExporter) writeFile(manifest *FileLedgerManifest, ledgers []xdr.LedgerCloseMeta) error {
+	first, last := ledgers[0].LedgerSequence(), ledgers[len(ledgers)-1].LedgerSequence()
+	filePath := FileLedgerPath(e.ledgersPerFile, first)
+
+	if manifest != nil {
+		exists, err := e.storage.Exists(filePath)
+		if err != nil {
+			return errors.Wrapf(err, "could not check if %s exists", filePath)
+		}
+		if exists {
+			existing, err := readLedgerFile(e.storage, filePath)
+			if err != nil {
+				return err
+			}
+			var merged []xdr.LedgerCloseMeta
+			for _, ledger := range existing {
+				if ledger.LedgerSequence() < first {
+					merged = append(merged, ledger)
+				}
+			}
+			merged = append(merged, ledgers...)
+			for _, ledger := range existing {
+				if ledger.LedgerSequence() > last {
+					merged = append(merged, ledger)
+				}
+			}
+			ledgers = merged
+		}
+	}
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	for _, ledger := range ledgers {
+		if err := xdr.MarshalFramed(writer, ledger); err != nil {
+			return errors.Wrapf(err, "could not encode ledger %d", ledger.LedgerSequence())
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return errors.Wrapf(err, "could not compress %s", filePath)
+	}
+	if err := e.storage.PutFile(filePath, ioutil.NopCloser(&buffer)); err != nil {
+		return errors.Wrapf(err, "could not write %s", filePath)
+	}
+	return nil
+}
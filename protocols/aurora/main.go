@@ -15,6 +15,7 @@ import (
 	"github.com/diamnet/go/strkey"
 	"github.com/diamnet/go/support/errors"
 	"github.com/diamnet/go/support/render/hal"
+	"github.com/diamnet/go/support/render/problem"
 	"github.com/diamnet/go/xdr"
 )
 
@@ -302,6 +303,25 @@ func (p Path) PagingToken() string {
 	return ""
 }
 
+// SplitPathPayment is a strict send payment split across several payment
+// paths. Every path is sent with a path_payment_strict_send operation, using
+// its source amount as the send amount and its destination amount (minus any
+// slippage tolerance) as the minimum destination amount. The operations must
+// be submitted in order, in a single transaction, because the destination
+// amount of every path accounts for the offers and liquidity pools consumed by
+// the previous ones.
+type SplitPathPayment struct {
+	SourceAssetType        string `json:"source_asset_type"`
+	SourceAssetCode        string `json:"source_asset_code,omitempty"`
+	SourceAssetIssuer      string `json:"source_asset_issuer,omitempty"`
+	SourceAmount           string `json:"source_amount"`
+	DestinationAssetType   string `json:"destination_asset_type"`
+	DestinationAssetCode   string `json:"destination_asset_code,omitempty"`
+	DestinationAssetIssuer string `json:"destination_asset_issuer,omitempty"`
+	DestinationAmount      string `json:"destination_amount"`
+	Paths                  []Path `json:"paths"`
+}
+
 // Price represents a price for an offer
 type Price base.Price
 
@@ -484,6 +504,23 @@ func (res TradeAggregation) PagingToken() string {
 	return strconv.FormatInt(res.Timestamp, 10)
 }
 
+// AssetStatsBucket represents the statistics of an asset over a period of
+// time. Holders, Trustlines and Supply are the values at the end of the period,
+// they are null if Aurora did not ingest the state of the ledgers.
+type AssetStatsBucket struct {
+	Timestamp     int64   `json:"timestamp,string"`
+	Holders       *int32  `json:"holders"`
+	Trustlines    *int32  `json:"trustlines"`
+	Supply        *string `json:"supply"`
+	PaymentVolume string  `json:"payment_volume"`
+	TransferCount int64   `json:"transfer_count,string"`
+}
+
+// PagingToken implementation for hal.Pageable. Not actually used
+func (res AssetStatsBucket) PagingToken() string {
+	return strconv.FormatInt(res.Timestamp, 10)
+}
+
 // Transaction represents a single, successful transaction
 type Transaction struct {
 	Links struct {
@@ -610,6 +647,80 @@ type TransactionResultCodes struct {
 	OperationCodes       []string `json:"operations,omitempty"`
 }
 
+// AsyncTransactionSubmissionResponse is the response of diamnet-core to a
+// transaction submitted to the asynchronous submission endpoint. TxStatus is
+// one of PENDING, DUPLICATE, ERROR or TRY_AGAIN_LATER and ErrorResultXDR is
+// only set for ERROR.
+type AsyncTransactionSubmissionResponse struct {
+	Hash           string `json:"hash"`
+	TxStatus       string `json:"tx_status"`
+	ErrorResultXDR string `json:"error_result_xdr,omitempty"`
+}
+
+// Statuses of a transaction returned by the transaction status endpoint.
+const (
+	TransactionStatusPending  = "pending"
+	TransactionStatusIncluded = "included"
+	TransactionStatusFailed   = "failed"
+	TransactionStatusExpired  = "expired"
+)
+
+// TransactionStatus is the status of a transaction submitted to aurora.
+// Ledger is set once the transaction is included in a ledger, successfully or
+// not, and ResultXDR once the transaction failed.
+type TransactionStatus struct {
+	Hash      string `json:"hash"`
+	Status    string `json:"status"`
+	Ledger    int32  `json:"ledger,omitempty"`
+	ResultXDR string `json:"result_xdr,omitempty"`
+}
+
+// TransactionSimulation is the predicted outcome of a transaction which was
+// simulated against the state ingested by aurora without being submitted.
+type TransactionSimulation struct {
+	Hash string `json:"hash"`
+	// Ledger is the last ingested ledger the transaction was simulated against
+	Ledger      int32                  `json:"ledger"`
+	Successful  bool                   `json:"successful"`
+	FeeCharged  int64                  `json:"fee_charged,string"`
+	ResultCodes TransactionResultCodes `json:"result_codes"`
+	Operations  []SimulatedOperation   `json:"operations"`
+}
+
+// SimulatedOperation is the predicted outcome of a single operation of a
+// simulated transaction. Operations which aurora cannot simulate are not
+// Simulated and have no result code.
+type SimulatedOperation struct {
+	Type       string            `json:"type"`
+	Simulated  bool              `json:"simulated"`
+	ResultCode string            `json:"result_code,omitempty"`
+	Effects    []SimulatedEffect `json:"effects"`
+}
+
+// SimulatedEffect is an effect predicted for a simulated operation. Details
+// contain the same fields as the corresponding effect resource.
+type SimulatedEffect struct {
+	Type    string                 `json:"type"`
+	Account string                 `json:"account"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// AccountBalanceHistory is the balance of an asset held by an account at a
+// series of ledgers.
+type AccountBalanceHistory struct {
+	Account string `json:"account"`
+	base.Asset
+	Records []AccountBalanceRecord `json:"records"`
+}
+
+// AccountBalanceRecord is the balance of an asset held by an account once a
+// ledger closed.
+type AccountBalanceRecord struct {
+	Ledger   int32     `json:"ledger"`
+	ClosedAt time.Time `json:"closed_at"`
+	Balance  string    `json:"balance"`
+}
+
 // KeyTypeFromAddress converts the version byte of the provided strkey encoded
 // value (for example an account id or a signer key) and returns the appropriate
 // aurora-specific type name.
@@ -817,3 +928,169 @@ type LiquidityPoolReserve struct {
 	Asset  string `json:"asset"`
 	Amount string `json:"amount"`
 }
+
+// LiquidityPoolStatsBucket represents the activity of a liquidity pool over a
+// period of time. Reserves, shares, trust lines and the share price are the
+// values at the end of the period.
+type LiquidityPoolStatsBucket struct {
+	Timestamp       int64                  `json:"timestamp,string"`
+	TradeCount      int64                  `json:"trade_count,string"`
+	TotalTrustlines uint64                 `json:"total_trustlines,string"`
+	TotalShares     string                 `json:"total_shares"`
+	Reserves        []LiquidityPoolReserve `json:"reserves"`
+	SharePrice      []LiquidityPoolReserve `json:"share_price"`
+	Volume          []LiquidityPoolReserve `json:"volume"`
+	FeeRevenue      []LiquidityPoolReserve `json:"fee_revenue"`
+}
+
+// PagingToken implementation for hal.Pageable. Not actually used
+func (res LiquidityPoolStatsBucket) PagingToken() string {
+	return strconv.FormatInt(res.Timestamp, 10)
+}
+
+// LiquidityPoolAPR represents the returns of a liquidity pool over windows of
+// time ending at the last ingested hour.
+type LiquidityPoolAPR struct {
+	ID      string                   `json:"id"`
+	FeeBP   uint32                   `json:"fee_bp"`
+	Windows []LiquidityPoolAPRWindow `json:"windows"`
+}
+
+// LiquidityPoolAPRWindow is the activity of a liquidity pool over a window of
+// time. FeeAPR is the annualized fee revenue relative to the reserves at the
+// start of the window and ImpermanentLoss the loss of a deposit made at the
+// start of the window compared to holding the assets. They are null if the
+// pool had no reserves.
+type LiquidityPoolAPRWindow struct {
+	Days            uint32                 `json:"days"`
+	StartTime       time.Time              `json:"start_time"`
+	EndTime         time.Time              `json:"end_time"`
+	TradeCount      int64                  `json:"trade_count,string"`
+	Volume          []LiquidityPoolReserve `json:"volume"`
+	FeeRevenue      []LiquidityPoolReserve `json:"fee_revenue"`
+	FeeAPR          *string                `json:"fee_apr"`
+	ImpermanentLoss *string                `json:"impermanent_loss"`
+}
+
+// WebhookSubscription represents a webhook subscription registered through
+// the admin port. Secret is only populated when the subscription is created.
+type WebhookSubscription struct {
+	ID             string    `json:"id"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"secret,omitempty"`
+	AccountID      string    `json:"account_id,omitempty"`
+	Asset          string    `json:"asset,omitempty"`
+	OperationTypes []string  `json:"operation_types,omitempty"`
+	Cursor         string    `json:"cursor"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (res WebhookSubscription) PagingToken() string {
+	return res.ID
+}
+
+// WebhookDeadLetter represents a webhook event which could not be delivered
+// to the subscription URL.
+type WebhookDeadLetter struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	OperationID    string          `json:"operation_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (res WebhookDeadLetter) PagingToken() string {
+	return res.ID
+}
+
+// WebhookEvent is the body of a request sent to a webhook subscription URL.
+// Operation contains the same representation of the operation as returned by
+// the `/operations/{id}` endpoint.
+type WebhookEvent struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	LedgerSequence int32           `json:"ledger"`
+	Operation      json.RawMessage `json:"operation"`
+}
+
+// RateLimitTier represents the hourly quotas of the API keys assigned to a
+// tier. A quota of 0 is unlimited.
+type RateLimitTier struct {
+	Name            string `json:"name"`
+	RequestsPerHour int64  `json:"requests_per_hour"`
+	StreamsPerHour  int64  `json:"streams_per_hour"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (res RateLimitTier) PagingToken() string {
+	return res.Name
+}
+
+// APIKey represents an API key registered through the admin port. Key is only
+// populated when the API key is created.
+type APIKey struct {
+	ID          string    `json:"id"`
+	Key         string    `json:"key,omitempty"`
+	Tier        string    `json:"tier"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (res APIKey) PagingToken() string {
+	return res.ID
+}
+
+// IngestFilter represents the values allowed by one of the transaction
+// filters of ingestion, set through the admin port.
+type IngestFilter struct {
+	Name      string    `json:"name"`
+	Allowed   []string  `json:"allowed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (res IngestFilter) PagingToken() string {
+	return res.Name
+}
+
+// WebSocket stream actions sent by clients.
+const (
+	WebSocketActionSubscribe   = "subscribe"
+	WebSocketActionUnsubscribe = "unsubscribe"
+)
+
+// WebSocket stream message types sent by Aurora.
+const (
+	WebSocketMessageSubscribed   = "subscribed"
+	WebSocketMessageUnsubscribed = "unsubscribed"
+	WebSocketMessageEvent        = "event"
+	WebSocketMessageError        = "error"
+)
+
+// WebSocketRequest is a message sent by clients to the `/ws` endpoint to
+// subscribe to or unsubscribe from a stream. Stream is an identifier chosen by
+// the client which is included in all the messages of the stream. Path is the
+// path and query of any streamable endpoint, for example
+// `/accounts/{account_id}/payments?cursor=now`.
+type WebSocketRequest struct {
+	Action string `json:"action"`
+	Stream string `json:"stream"`
+	Path   string `json:"path,omitempty"`
+}
+
+// WebSocketMessage is a message sent by Aurora over the `/ws` endpoint. For
+// `event` messages Data contains the resource streamed by the endpoint and ID
+// its paging token, which can be used as a cursor to resume the stream. An
+// `error` message ends the stream.
+type WebSocketMessage struct {
+	Type   string          `json:"type"`
+	Stream string          `json:"stream,omitempty"`
+	ID     string          `json:"id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Error  *problem.P      `json:"error,omitempty"`
+}
@@ -8,12 +8,16 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
 
+	"github.com/diamnet/go/historyarchive"
 	aurora "github.com/diamnet/go/services/aurora/internal"
+	"github.com/diamnet/go/services/aurora/internal/coldstorage"
 	"github.com/diamnet/go/services/aurora/internal/db2/schema"
 	"github.com/diamnet/go/services/aurora/internal/ingest"
 	support "github.com/diamnet/go/support/config"
@@ -227,8 +231,24 @@ var (
 	parallelJobSize     uint32
 	retries             uint
 	retryBackoffSeconds uint
+	ledgerFilesURL      string
 )
 
+// ledgerFilesURLOption returns the option of the commands which can read the
+// ledgers from files exported with `aurora ingest export-ledger-meta` instead
+// of diamnet-core.
+func ledgerFilesURLOption() *support.ConfigOption {
+	return &support.ConfigOption{
+		Name:        "ledger-files-url",
+		ConfigKey:   &ledgerFilesURL,
+		OptType:     types.String,
+		Required:    false,
+		FlagDefault: "",
+		Usage: "[optional] location (file://, s3:// or http(s):// URL) of the ledgers exported with " +
+			"`aurora ingest export-ledger-meta`, when set the ledgers are read from these files instead of diamnet-core",
+	}
+}
+
 func ingestRangeCmdOpts() support.ConfigOptions {
 	return support.ConfigOptions{
 		{
@@ -272,6 +292,7 @@ func ingestRangeCmdOpts() support.ConfigOptions {
 			FlagDefault: uint(5),
 			Usage:       "[optional] backoff seconds between reingest retries",
 		},
+		ledgerFilesURLOption(),
 	}
 }
 
@@ -369,17 +390,18 @@ var dbFillGapsCmd = &cobra.Command{
 			hlog.Infof("found gaps %v", gaps)
 		}
 
+		if len(gaps) == 0 {
+			hlog.Info("No gaps found")
+			return nil
+		}
 		return runDBReingestRange(gaps, reingestForce, parallelWorkers, *config)
 	},
 }
 
-func runDBReingestRange(ledgerRanges []history.LedgerRange, reingestForce bool, parallelWorkers uint, config aurora.Config) error {
-	if reingestForce && parallelWorkers > 1 {
-		return errors.New("--force is incompatible with --parallel-workers > 1")
-	}
+func reingestIngestConfig(config aurora.Config) (ingest.Config, error) {
 	auroraSession, err := db.Open("postgres", config.DatabaseURL)
 	if err != nil {
-		return fmt.Errorf("cannot open Aurora DB: %v", err)
+		return ingest.Config{}, fmt.Errorf("cannot open Aurora DB: %v", err)
 	}
 
 	ingestConfig := ingest.Config{
@@ -396,29 +418,57 @@ func runDBReingestRange(ledgerRanges []history.LedgerRange, reingestForce bool,
 		CaptiveCoreStoragePath:      config.CaptiveCoreStoragePath,
 		DiamnetCoreCursor:           config.CursorName,
 		DiamnetCoreURL:              config.DiamnetCoreURL,
+		LedgerFilesURL:              ledgerFilesURL,
 	}
 
-	if !ingestConfig.EnableCaptiveCore {
+	if !ingestConfig.EnableCaptiveCore && ingestConfig.LedgerFilesURL == "" {
 		if config.DiamnetCoreDatabaseURL == "" {
-			return fmt.Errorf("flag --%s cannot be empty", aurora.DiamnetCoreDBURLFlagName)
+			return ingest.Config{}, fmt.Errorf("flag --%s cannot be empty", aurora.DiamnetCoreDBURLFlagName)
 		}
 		coreSession, dbErr := db.Open("postgres", config.DiamnetCoreDatabaseURL)
 		if dbErr != nil {
-			return fmt.Errorf("cannot open Core DB: %v", dbErr)
+			return ingest.Config{}, fmt.Errorf("cannot open Core DB: %v", dbErr)
 		}
 		ingestConfig.CoreSession = coreSession
 	}
 
-	if parallelWorkers > 1 {
+	ingestConfig.HistoryPartitionSize, err = (&history.Q{auroraSession}).GetHistoryPartitionSize(context.Background())
+	if err != nil {
+		return ingest.Config{}, fmt.Errorf("cannot get history partition size: %v", err)
+	}
+	return ingestConfig, nil
+}
+
+const reingestRangeConflictMsg = `The range you have provided overlaps with Aurora's most recently ingested ledger.
+It is not possible to run the reingest command on this range in parallel with
+Aurora's ingestion system.
+Either reduce the range so that it doesn't overlap with Aurora's ingestion system,
+or, use the force flag to ensure that Aurora's ingestion system is blocked until
+the reingest command completes.`
+
+// runDBReingestRange reingests the ledger ranges. Unless --force is set, the
+// ranges are reingested by a reingest job which can be resumed with
+// `db reingest resume` if interrupted.
+func runDBReingestRange(ledgerRanges []history.LedgerRange, reingestForce bool, parallelWorkers uint, config aurora.Config) error {
+	if reingestForce && parallelWorkers > 1 {
+		return errors.New("--force is incompatible with --parallel-workers > 1")
+	}
+	ingestConfig, err := reingestIngestConfig(config)
+	if err != nil {
+		return err
+	}
+
+	if !reingestForce {
 		system, systemErr := ingest.NewParallelSystems(ingestConfig, parallelWorkers)
 		if systemErr != nil {
 			return systemErr
 		}
-
-		return system.ReingestRange(
-			ledgerRanges,
-			parallelJobSize,
-		)
+		job, jobErr := system.CreateReingestJob(ledgerRanges, parallelJobSize)
+		if jobErr != nil {
+			return jobErr
+		}
+		hlog.Infof("Created reingest job %d", job.ID)
+		return runReingestJob(system, job.ID)
 	}
 
 	system, systemErr := ingest.NewSystem(ingestConfig)
@@ -429,12 +479,7 @@ func runDBReingestRange(ledgerRanges []history.LedgerRange, reingestForce bool,
 	err = system.ReingestRange(ledgerRanges, reingestForce)
 	if err != nil {
 		if _, ok := errors.Cause(err).(ingest.ErrReingestRangeConflict); ok {
-			return fmt.Errorf(`The range you have provided overlaps with Aurora's most recently ingested ledger.
-It is not possible to run the reingest command on this range in parallel with
-Aurora's ingestion system.
-Either reduce the range so that it doesn't overlap with Aurora's ingestion system,
-or, use the force flag to ensure that Aurora's ingestion system is blocked until
-the reingest command completes.`)
+			return fmt.Errorf(reingestRangeConflictMsg)
 		}
 
 		return err
@@ -443,14 +488,439 @@ the reingest command completes.`)
 	return nil
 }
 
+func runReingestJob(system *ingest.ParallelSystems, jobID int64) error {
+	if err := system.ResumeReingestJob(jobID); err != nil {
+		if _, ok := errors.Cause(err).(ingest.ErrReingestRangeConflict); ok {
+			return fmt.Errorf(reingestRangeConflictMsg)
+		}
+		return errors.Wrapf(err, "run `%s db reingest resume %d` to resume the job", os.Args[0], jobID)
+	}
+	hlog.Infof("Reingest job %d run successfully!", jobID)
+	return nil
+}
+
+func reingestJobCmdOpts() support.ConfigOptions {
+	var opts support.ConfigOptions
+	for _, opt := range ingestRangeCmdOpts() {
+		// The batches of the job and the lock of the ingestion system are
+		// decided when the job is created
+		if opt.Name != "force" && opt.Name != "parallel-job-size" {
+			opts = append(opts, opt)
+		}
+	}
+	return opts
+}
+
+var dbReingestResumeCmdOpts = reingestJobCmdOpts()
+var dbReingestResumeCmd = &cobra.Command{
+	Use:   "resume [Job ID]",
+	Short: "resumes a reingest job",
+	Long:  "resumes a reingest job which failed or was interrupted, only the batches of ledgers which are not done are reingested",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := dbReingestResumeCmdOpts.RequireE(); err != nil {
+			return err
+		}
+		if err := dbReingestResumeCmdOpts.SetValues(); err != nil {
+			return err
+		}
+
+		if len(args) != 1 {
+			return ErrUsage{cmd}
+		}
+		jobID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			cmd.Usage()
+			return fmt.Errorf(`invalid job ID "%s"`, args[0])
+		}
+
+		err = aurora.ApplyFlags(config, flags, aurora.ApplyOptions{RequireCaptiveCoreConfig: false, AlwaysIngest: true})
+		if err != nil {
+			return err
+		}
+		ingestConfig, err := reingestIngestConfig(*config)
+		if err != nil {
+			return err
+		}
+		system, err := ingest.NewParallelSystems(ingestConfig, parallelWorkers)
+		if err != nil {
+			return err
+		}
+		return runReingestJob(system, jobID)
+	},
+}
+
+var dbReingestStatusCmd = &cobra.Command{
+	Use:   "status [Job ID]",
+	Short: "prints the status of reingest jobs",
+	Long:  "prints the status of the most recent reingest jobs or, when a job ID is given, the status of the batches of the job",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlag(aurora.DatabaseURLFlagName); err != nil {
+			return err
+		}
+
+		if len(args) > 1 {
+			return ErrUsage{cmd}
+		}
+
+		auroraSession, err := db.Open("postgres", config.DatabaseURL)
+		if err != nil {
+			return fmt.Errorf("cannot open Aurora DB: %v", err)
+		}
+		q := &history.Q{auroraSession}
+		ctx := context.Background()
+
+		var jobs []history.ReingestJob
+		if len(args) == 1 {
+			jobID, parseErr := strconv.ParseInt(args[0], 10, 64)
+			if parseErr != nil {
+				cmd.Usage()
+				return fmt.Errorf(`invalid job ID "%s"`, args[0])
+			}
+			job, jobErr := q.GetReingestJob(ctx, jobID)
+			if jobErr != nil {
+				return errors.Wrapf(jobErr, "could not load reingest job %d", jobID)
+			}
+			jobs = append(jobs, job)
+		} else {
+			jobs, err = q.GetReingestJobs(ctx, 20)
+			if err != nil {
+				return errors.Wrap(err, "could not load reingest jobs")
+			}
+			if len(jobs) == 0 {
+				fmt.Println("No reingest jobs found")
+				return nil
+			}
+		}
+
+		for _, job := range jobs {
+			batches, batchesErr := q.GetReingestBatches(ctx, job.ID)
+			if batchesErr != nil {
+				return errors.Wrapf(batchesErr, "could not load batches of reingest job %d", job.ID)
+			}
+			fmt.Println(newReingestJobStatus(job, batches, time.Now()).String(len(args) == 1))
+		}
+		return nil
+	},
+}
+
+// reingestJobStatus summarizes the progress of a reingest job.
+type reingestJobStatus struct {
+	job             history.ReingestJob
+	batchCounts     map[string]int
+	failedBatches   []history.ReingestBatch
+	ledgersTotal    uint64
+	ledgersDone     uint64
+	ledgersPerSec   float64
+	eta             time.Duration
+	firstSequence   uint32
+	lastSequence    uint32
+	numberOfBatches int
+}
+
+func newReingestJobStatus(job history.ReingestJob, batches []history.ReingestBatch, now time.Time) reingestJobStatus {
+	status := reingestJobStatus{
+		job:             job,
+		batchCounts:     map[string]int{},
+		numberOfBatches: len(batches),
+	}
+
+	var firstStarted, lastFinished time.Time
+	for i, batch := range batches {
+		if i == 0 || batch.StartSequence < status.firstSequence {
+			status.firstSequence = batch.StartSequence
+		}
+		if batch.EndSequence > status.lastSequence {
+			status.lastSequence = batch.EndSequence
+		}
+
+		size := uint64(batch.EndSequence - batch.StartSequence + 1)
+		status.ledgersTotal += size
+		status.batchCounts[batch.Status]++
+		if batch.Status == history.ReingestBatchFailed {
+			status.failedBatches = append(status.failedBatches, batch)
+		}
+		if batch.StartedAt.Valid && (firstStarted.IsZero() || batch.StartedAt.Time.Before(firstStarted)) {
+			firstStarted = batch.StartedAt.Time
+		}
+		if batch.Status != history.ReingestBatchDone {
+			continue
+		}
+		status.ledgersDone += size
+		if batch.FinishedAt.Time.After(lastFinished) {
+			lastFinished = batch.FinishedAt.Time
+		}
+	}
+
+	// The throughput is measured from the first batch started to the last
+	// batch done, it doesn't account for the time the job was interrupted.
+	if status.ledgersDone > 0 && lastFinished.After(firstStarted) {
+		status.ledgersPerSec = float64(status.ledgersDone) / lastFinished.Sub(firstStarted).Seconds()
+		left := status.ledgersTotal - status.ledgersDone
+		status.eta = time.Duration(float64(left) / status.ledgersPerSec * float64(time.Second)).Round(time.Second)
+	}
+	return status
+}
+
+// String returns a line describing the job and, if verbose is set, the number
+// of batches in every status and the errors of the failed batches.
+func (s reingestJobStatus) String(verbose bool) string {
+	state := "in progress"
+	if s.job.FinishedAt.Valid {
+		state = "finished at " + s.job.FinishedAt.Time.Format(time.RFC3339)
+	}
+	var b strings.Builder
+	fmt.Fprintf(
+		&b,
+		"Job %d [%d, %d] created at %s, %s: %d/%d batches done, %d/%d ledgers done",
+		s.job.ID,
+		s.firstSequence,
+		s.lastSequence,
+		s.job.CreatedAt.Format(time.RFC3339),
+		state,
+		s.batchCounts[history.ReingestBatchDone],
+		s.numberOfBatches,
+		s.ledgersDone,
+		s.ledgersTotal,
+	)
+	if failed := len(s.failedBatches); failed > 0 {
+		fmt.Fprintf(&b, ", %d batches failed", failed)
+	}
+	if !verbose {
+		return b.String()
+	}
+
+	for _, status := range []string{
+		history.ReingestBatchPending,
+		history.ReingestBatchRunning,
+		history.ReingestBatchDone,
+		history.ReingestBatchFailed,
+	} {
+		fmt.Fprintf(&b, "\n  %s batches: %d", status, s.batchCounts[status])
+	}
+	if s.ledgersPerSec > 0 {
+		fmt.Fprintf(&b, "\n  throughput: %.2f ledgers/s", s.ledgersPerSec)
+		if !s.job.FinishedAt.Valid {
+			fmt.Fprintf(&b, ", ETA: %s", s.eta)
+		}
+	}
+	for _, batch := range s.failedBatches {
+		fmt.Fprintf(&b, "\n  batch [%d, %d] failed: %s", batch.StartSequence, batch.EndSequence, batch.Error.String)
+	}
+	return b.String()
+}
+
+var historyPartitionSize uint32
+
+var dbPartitionHistoryCmdOpts = support.ConfigOptions{
+	{
+		Name:        "partition-size",
+		ConfigKey:   &historyPartitionSize,
+		OptType:     types.Uint32,
+		Required:    false,
+		FlagDefault: uint32(100000),
+		Usage:       "[optional] number of ledgers in every partition of the history tables",
+	},
+}
+
+var dbPartitionHistoryCmd = &cobra.Command{
+	Use:   "partition-history",
+	Short: "partitions the history tables by ledger range",
+	Long: "partition-history converts the largest history tables into tables partitioned by ledger range. " +
+		"The existing rows are kept in a single partition, later partitions are created during ingestion " +
+		"and the reaper drops the partitions older than the history retention. Aurora must be stopped while " +
+		"the tables are converted, which requires PostgreSQL 11 or later and scans the existing tables.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlag(aurora.DatabaseURLFlagName); err != nil {
+			return err
+		}
+		if err := dbPartitionHistoryCmdOpts.RequireE(); err != nil {
+			return err
+		}
+		if err := dbPartitionHistoryCmdOpts.SetValues(); err != nil {
+			return err
+		}
+
+		if len(args) != 0 {
+			return ErrUsage{cmd}
+		}
+		if historyPartitionSize == 0 {
+			return errors.New("--partition-size must be positive")
+		}
+
+		auroraSession, err := db.Open("postgres", config.DatabaseURL)
+		if err != nil {
+			return fmt.Errorf("cannot open Aurora DB: %v", err)
+		}
+		q := &history.Q{auroraSession}
+		ctx := context.Background()
+
+		if err = q.Begin(); err != nil {
+			return err
+		}
+		defer q.Rollback()
+
+		latest, err := q.GetLatestHistoryLedger(ctx)
+		if err != nil {
+			return errors.Wrap(err, "could not get the latest ingested ledger")
+		}
+		// The existing partition contains the ledgers up to the end of the
+		// partition of the latest ledger
+		boundary := (latest/historyPartitionSize + 1) * historyPartitionSize
+		if err = q.PartitionHistoryTables(ctx, historyPartitionSize, boundary); err != nil {
+			return err
+		}
+		if err = q.Commit(); err != nil {
+			return err
+		}
+
+		hlog.Infof(
+			"History tables partitioned in partitions of %d ledgers, ledgers before %d are kept in the existing partition",
+			historyPartitionSize,
+			boundary,
+		)
+		return nil
+	},
+}
+
+var dbExportLedgersCmd = &cobra.Command{
+	Use:   "export-ledgers [Start sequence number] [End sequence number]",
+	Short: "exports ledgers to the cold storage",
+	Long: "export-ledgers writes the ledgers of the checkpoints containing the given range, which must be " +
+		"ingested, to --cold-storage-url so that they can still be served after being removed by the reaper.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlag(aurora.DatabaseURLFlagName); err != nil {
+			return err
+		}
+		for _, name := range []string{"cold-storage-url", "checkpoint-frequency"} {
+			if err := requireAndSetFlag(name); err != nil {
+				return err
+			}
+		}
+		if config.ColdStorageURL == "" {
+			return errors.New("--cold-storage-url must be set")
+		}
+
+		if len(args) != 2 {
+			return ErrUsage{cmd}
+		}
+		start, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf(`invalid sequence number "%s"`, args[0])
+		}
+		end, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf(`invalid sequence number "%s"`, args[1])
+		}
+		if start == 0 || start > end {
+			return fmt.Errorf("invalid range [%d, %d]", start, end)
+		}
+
+		auroraSession, err := db.Open("postgres", config.DatabaseURL)
+		if err != nil {
+			return fmt.Errorf("cannot open Aurora DB: %v", err)
+		}
+		backend, err := coldstorage.NewFileBackend(config.ColdStorageURL, historyarchive.ConnectOptions{
+			Context:             context.Background(),
+			CheckpointFrequency: config.CheckpointFrequency,
+		})
+		if err != nil {
+			return err
+		}
+		return runDBExportLedgers(
+			context.Background(),
+			&history.Q{auroraSession},
+			backend,
+			historyarchive.NewCheckpointManager(config.CheckpointFrequency),
+			uint32(start),
+			uint32(end),
+		)
+	},
+}
+
+// runDBExportLedgers exports the checkpoints containing the ledgers from start
+// to end, every ledger of the checkpoints must be in the history tables.
+func runDBExportLedgers(
+	ctx context.Context,
+	q *history.Q,
+	backend *coldstorage.FileBackend,
+	checkpoints historyarchive.CheckpointManager,
+	start, end uint32,
+) error {
+	for checkpoint := checkpoints.GetCheckpoint(start); ; checkpoint += checkpoints.GetCheckpointFrequency() {
+		checkpointRange := checkpoints.GetCheckpointRange(checkpoint)
+		var ledgers []coldstorage.Ledger
+		for sequence := checkpointRange.Low; sequence <= checkpointRange.High; sequence++ {
+			var ledger history.Ledger
+			if err := q.LedgerBySequence(ctx, &ledger, int32(sequence)); err != nil {
+				return errors.Wrapf(err, "could not load ledger %d", sequence)
+			}
+			var transactions []history.Transaction
+			err := q.Transactions().ForLedger(ctx, int32(sequence)).IncludeFailed().Select(ctx, &transactions)
+			if err != nil {
+				return errors.Wrapf(err, "could not load the transactions of ledger %d", sequence)
+			}
+			exported, err := coldstorage.NewLedger(ledger, transactions)
+			if err != nil {
+				return errors.Wrapf(err, "could not convert ledger %d", sequence)
+			}
+			ledgers = append(ledgers, exported)
+		}
+		if err := backend.PutLedgers(ledgers); err != nil {
+			return err
+		}
+		hlog.Infof("Exported ledgers [%d, %d]", checkpointRange.Low, checkpointRange.High)
+
+		if checkpointRange.High >= end {
+			return nil
+		}
+	}
+}
+
+var createReingestJob bool
+
+var dbDetectGapsCmdOpts = support.ConfigOptions{
+	{
+		Name:        "create-reingest-job",
+		ConfigKey:   &createReingestJob,
+		OptType:     types.Bool,
+		Required:    false,
+		FlagDefault: false,
+		Usage: "[optional] if this flag is set, aurora creates a reingest job filling in the gaps, " +
+			"which is run with `db reingest resume`, instead of printing the reingest commands",
+	},
+	{
+		Name:        "parallel-workers",
+		ConfigKey:   &parallelWorkers,
+		OptType:     types.Uint,
+		Required:    false,
+		FlagDefault: uint(1),
+		Usage:       "[optional] number of workers the batches of the reingest job are sized for",
+	},
+	{
+		Name:        "parallel-job-size",
+		ConfigKey:   &parallelJobSize,
+		OptType:     types.Uint32,
+		Required:    false,
+		FlagDefault: uint32(100000),
+		Usage:       "[optional] size of the batches of ledgers of the reingest job",
+	},
+}
+
 var dbDetectGapsCmd = &cobra.Command{
 	Use:   "detect-gaps",
 	Short: "detects ingestion gaps in Aurora's database",
-	Long:  "detects ingestion gaps in Aurora's database and prints a list of reingest commands needed to fill the gaps",
+	Long: "detects ingestion gaps in Aurora's database and prints a list of reingest commands needed to fill the gaps, " +
+		"or creates a reingest job filling them in with --create-reingest-job",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := requireAndSetFlag(aurora.DatabaseURLFlagName); err != nil {
 			return err
 		}
+		if err := dbDetectGapsCmdOpts.RequireE(); err != nil {
+			return err
+		}
+		if err := dbDetectGapsCmdOpts.SetValues(); err != nil {
+			return err
+		}
 
 		if len(args) != 0 {
 			return ErrUsage{cmd}
@@ -463,11 +933,16 @@ var dbDetectGapsCmd = &cobra.Command{
 			hlog.Info("No gaps found")
 			return nil
 		}
+		if createReingestJob {
+			return runDBCreateReingestJob(gaps, parallelWorkers, *config)
+		}
 		fmt.Println("Aurora commands to run in order to fill in the gaps:")
 		cmdname := os.Args[0]
 		for _, g := range gaps {
 			fmt.Printf("%s db reingest range %d %d\n", cmdname, g.StartSequence, g.EndSequence)
 		}
+		fmt.Println("Or, to reingest all the gaps in a single resumable job:")
+		fmt.Printf("%s db fill-gaps\n", cmdname)
 		return nil
 	},
 }
@@ -481,6 +956,26 @@ func runDBDetectGaps(config aurora.Config) ([]history.LedgerRange, error) {
 	return q.GetLedgerGaps(context.Background())
 }
 
+// runDBCreateReingestJob creates a reingest job for the ledger ranges without
+// running it, so that it can be run later with `db reingest resume`.
+func runDBCreateReingestJob(ledgerRanges []history.LedgerRange, parallelWorkers uint, config aurora.Config) error {
+	auroraSession, err := db.Open("postgres", config.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("cannot open Aurora DB: %v", err)
+	}
+	system, err := ingest.NewParallelSystems(ingest.Config{HistorySession: auroraSession}, parallelWorkers)
+	if err != nil {
+		return err
+	}
+	job, err := system.CreateReingestJob(ledgerRanges, parallelJobSize)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created reingest job %d to fill in the gaps, run it with:\n", job.ID)
+	fmt.Printf("%s db reingest resume %d\n", os.Args[0], job.ID)
+	return nil
+}
+
 func runDBDetectGapsInRange(config aurora.Config, start, end uint32) ([]history.LedgerRange, error) {
 	auroraSession, err := db.Open("postgres", config.DatabaseURL)
 	if err != nil {
@@ -497,9 +992,21 @@ func init() {
 	if err := dbFillGapsCmdOpts.Init(dbFillGapsCmd); err != nil {
 		log.Fatal(err.Error())
 	}
+	if err := dbReingestResumeCmdOpts.Init(dbReingestResumeCmd); err != nil {
+		log.Fatal(err.Error())
+	}
+	if err := dbPartitionHistoryCmdOpts.Init(dbPartitionHistoryCmd); err != nil {
+		log.Fatal(err.Error())
+	}
+	if err := dbDetectGapsCmdOpts.Init(dbDetectGapsCmd); err != nil {
+		log.Fatal(err.Error())
+	}
 
 	viper.BindPFlags(dbReingestRangeCmd.PersistentFlags())
 	viper.BindPFlags(dbFillGapsCmd.PersistentFlags())
+	viper.BindPFlags(dbReingestResumeCmd.PersistentFlags())
+	viper.BindPFlags(dbPartitionHistoryCmd.PersistentFlags())
+	viper.BindPFlags(dbDetectGapsCmd.PersistentFlags())
 
 	RootCmd.AddCommand(dbCmd)
 	dbCmd.AddCommand(
@@ -509,6 +1016,8 @@ func init() {
 		dbReingestCmd,
 		dbDetectGapsCmd,
 		dbFillGapsCmd,
+		dbPartitionHistoryCmd,
+		dbExportLedgersCmd,
 	)
 	dbMigrateCmd.AddCommand(
 		dbMigrateDownCmd,
@@ -516,5 +1025,9 @@ func init() {
 		dbMigrateStatusCmd,
 		dbMigrateUpCmd,
 	)
-	dbReingestCmd.AddCommand(dbReingestRangeCmd)
+	dbReingestCmd.AddCommand(
+		dbReingestRangeCmd,
+		dbReingestResumeCmd,
+		dbReingestStatusCmd,
+	)
 }
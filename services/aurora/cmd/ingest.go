@@ -10,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/diamnet/go/historyarchive"
+	"github.com/diamnet/go/ingest/ledgerbackend"
 	aurora "github.com/diamnet/go/services/aurora/internal"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/services/aurora/internal/ingest"
@@ -59,6 +60,7 @@ var ingestVerifyRangeCmdOpts = []*support.ConfigOption{
 		FlagDefault: uint32(0),
 		Usage:       "[optional] opens a net/http/pprof server at given port",
 	},
+	ledgerFilesURLOption(),
 }
 
 var ingestVerifyRangeCmd = &cobra.Command{
@@ -113,9 +115,10 @@ var ingestVerifyRangeCmd = &cobra.Command{
 			CheckpointFrequency:    config.CheckpointFrequency,
 			CaptiveCoreToml:        config.CaptiveCoreToml,
 			CaptiveCoreStoragePath: config.CaptiveCoreStoragePath,
+			LedgerFilesURL:         ledgerFilesURL,
 		}
 
-		if !ingestConfig.EnableCaptiveCore {
+		if !ingestConfig.EnableCaptiveCore && ingestConfig.LedgerFilesURL == "" {
 			if config.DiamnetCoreDatabaseURL == "" {
 				return fmt.Errorf("flag --%s cannot be empty", aurora.DiamnetCoreDBURLFlagName)
 			}
@@ -146,6 +149,103 @@ var ingestVerifyRangeCmd = &cobra.Command{
 	},
 }
 
+var exportLedgerMetaFrom, exportLedgerMetaTo, exportLedgerMetaLedgersPerFile uint32
+var exportLedgerMetaURL string
+
+var ingestExportLedgerMetaCmdOpts = []*support.ConfigOption{
+	{
+		Name:        "from",
+		ConfigKey:   &exportLedgerMetaFrom,
+		OptType:     types.Uint32,
+		Required:    true,
+		FlagDefault: uint32(0),
+		Usage:       "first ledger of the range to export",
+	},
+	{
+		Name:        "to",
+		ConfigKey:   &exportLedgerMetaTo,
+		OptType:     types.Uint32,
+		Required:    true,
+		FlagDefault: uint32(0),
+		Usage:       "last ledger of the range to export",
+	},
+	{
+		Name:        "ledger-files-url",
+		ConfigKey:   &exportLedgerMetaURL,
+		OptType:     types.String,
+		Required:    true,
+		FlagDefault: "",
+		Usage:       "location (file:// or s3:// URL) the ledger files and their manifest are written to",
+	},
+	{
+		Name:        "ledgers-per-file",
+		ConfigKey:   &exportLedgerMetaLedgersPerFile,
+		OptType:     types.Uint32,
+		Required:    false,
+		FlagDefault: uint32(ledgerbackend.DefaultLedgersPerFile),
+		Usage:       "[optional] number of ledgers in every file, it must be the same for every export to the same location",
+	},
+}
+
+var ingestExportLedgerMetaCmd = &cobra.Command{
+	Use:   "export-ledger-meta",
+	Short: "exports the meta of a range of ledgers to files",
+	Long: "export-ledger-meta writes the LedgerCloseMeta of the ledgers between --from and --to (inclusive), " +
+		"read from Captive Core or the diamnet-core DB, to gzipped files with a manifest. The files can be used " +
+		"with --ledger-files-url to reingest or verify ledgers without diamnet-core. An export can be extended " +
+		"by exporting an adjacent range to the same location.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, co := range ingestExportLedgerMetaCmdOpts {
+			if err := co.RequireE(); err != nil {
+				return err
+			}
+			co.SetValue()
+		}
+
+		if err := aurora.ApplyFlags(config, flags, aurora.ApplyOptions{RequireCaptiveCoreConfig: false, AlwaysIngest: true}); err != nil {
+			return err
+		}
+
+		auroraSession, err := db.Open("postgres", config.DatabaseURL)
+		if err != nil {
+			return fmt.Errorf("cannot open Aurora DB: %v", err)
+		}
+		ingestConfig := ingest.Config{
+			NetworkPassphrase:      config.NetworkPassphrase,
+			HistorySession:         auroraSession,
+			HistoryArchiveURL:      config.HistoryArchiveURLs[0],
+			EnableCaptiveCore:      config.EnableCaptiveCoreIngestion,
+			CaptiveCoreBinaryPath:  config.CaptiveCoreBinaryPath,
+			RemoteCaptiveCoreURL:   config.RemoteCaptiveCoreURL,
+			CheckpointFrequency:    config.CheckpointFrequency,
+			CaptiveCoreToml:        config.CaptiveCoreToml,
+			CaptiveCoreStoragePath: config.CaptiveCoreStoragePath,
+		}
+		if !ingestConfig.EnableCaptiveCore {
+			if config.DiamnetCoreDatabaseURL == "" {
+				return fmt.Errorf("flag --%s cannot be empty", aurora.DiamnetCoreDBURLFlagName)
+			}
+			coreSession, dbErr := db.Open("postgres", config.DiamnetCoreDatabaseURL)
+			if dbErr != nil {
+				return fmt.Errorf("cannot open Core DB: %v", dbErr)
+			}
+			ingestConfig.CoreSession = coreSession
+		}
+
+		storage, err := historyarchive.ConnectBackend(exportLedgerMetaURL, historyarchive.ConnectOptions{})
+		if err != nil {
+			return err
+		}
+		exporter := ledgerbackend.NewFileLedgerExporter(storage, exportLedgerMetaLedgersPerFile)
+		if err = ingest.ExportLedgers(ingestConfig, exporter, exportLedgerMetaFrom, exportLedgerMetaTo); err != nil {
+			return err
+		}
+
+		log.Infof("Ledgers [%d, %d] exported to %s", exportLedgerMetaFrom, exportLedgerMetaTo, exportLedgerMetaURL)
+		return nil
+	},
+}
+
 var stressTestNumTransactions, stressTestChangesPerTransaction int
 
 var stressTestCmdOpts = []*support.ConfigOption{
@@ -337,7 +437,15 @@ func init() {
 		}
 	}
 
+	for _, co := range ingestExportLedgerMetaCmdOpts {
+		err := co.Init(ingestExportLedgerMetaCmd)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
 	viper.BindPFlags(ingestVerifyRangeCmd.PersistentFlags())
+	viper.BindPFlags(ingestExportLedgerMetaCmd.PersistentFlags())
 
 	RootCmd.AddCommand(ingestCmd)
 	ingestCmd.AddCommand(
@@ -345,5 +453,6 @@ func init() {
 		ingestStressTestCmd,
 		ingestTriggerStateRebuildCmd,
 		ingestInitGenesisStateCmd,
+		ingestExportLedgerMetaCmd,
 	)
 }
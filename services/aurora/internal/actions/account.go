@@ -69,10 +69,11 @@ func AccountInfo(ctx context.Context, hq *history.Q, addr string) (*protocol.Acc
 
 // AccountsQuery query struct for accounts end-point
 type AccountsQuery struct {
-	Signer        string `schema:"signer" valid:"accountID,optional"`
-	Sponsor       string `schema:"sponsor" valid:"accountID,optional"`
-	AssetFilter   string `schema:"asset" valid:"asset,optional"`
-	LiquidityPool string `schema:"liquidity_pool" valid:"sha256,optional"`
+	Signer                string `schema:"signer" valid:"accountID,optional"`
+	Sponsor               string `schema:"sponsor" valid:"accountID,optional"`
+	AssetFilter           string `schema:"asset" valid:"asset,optional"`
+	LiquidityPool         string `schema:"liquidity_pool" valid:"sha256,optional"`
+	AsOfLedgerQueryParams `valid:"-"`
 }
 
 // URITemplate returns a rfc6570 URI template the query struct
@@ -148,6 +149,11 @@ func (handler GetAccountsHandler) GetResourcePage(
 		return nil, err
 	}
 
+	ctx, err = qp.Context(ctx, historyQ)
+	if err != nil {
+		return nil, err
+	}
+
 	var records []history.AccountEntry
 
 	if len(qp.Sponsor) > 0 {
@@ -285,7 +291,8 @@ func getLedgerBySequence(ctx context.Context, hq *history.Q, sequence int32) (*h
 
 // AccountByIDQuery query struct for accounts/{account_id} end-point
 type AccountByIDQuery struct {
-	AccountID string `schema:"account_id" valid:"accountID,optional"`
+	AccountID             string `schema:"account_id" valid:"accountID,optional"`
+	AsOfLedgerQueryParams `valid:"-"`
 }
 
 // GetAccountByIDHandler is the action handler for the /accounts/{account_id} endpoint
@@ -316,7 +323,11 @@ func (handler GetAccountByIDHandler) GetResource(
 	if err != nil {
 		return nil, err
 	}
-	account, err := AccountInfo(r.Context(), historyQ, qp.AccountID)
+	ctx, err := qp.Context(r.Context(), historyQ)
+	if err != nil {
+		return nil, err
+	}
+	account, err := AccountInfo(ctx, historyQ, qp.AccountID)
 	if err != nil {
 		return Account{}, err
 	}
@@ -0,0 +1,260 @@
+package actions
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/diamnet/go/amount"
+	"github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/protocols/aurora/base"
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
+	"github.com/diamnet/go/services/aurora/internal/ledger"
+	hProblem "github.com/diamnet/go/services/aurora/internal/render/problem"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/xdr"
+)
+
+// maxBalanceHistoryRecords is the maximum number of balances returned by a
+// single request to the balance history endpoint.
+const maxBalanceHistoryRecords = 1000
+
+// AccountBalanceHistoryQuery query struct for the account balance history
+// end-point
+type AccountBalanceHistoryQuery struct {
+	AccountID  string `schema:"account_id" valid:"accountID"`
+	Asset      string `schema:"asset" valid:"asset"`
+	FromLedger uint32 `schema:"from_ledger" valid:"-"`
+	ToLedger   uint32 `schema:"to_ledger" valid:"-"`
+	Resolution int64  `schema:"resolution" valid:"-"`
+}
+
+// Validate runs custom validations.
+func (q AccountBalanceHistoryQuery) Validate() error {
+	if q.Asset == "" {
+		return problem.MakeInvalidFieldProblem(
+			"asset",
+			errors.New("this parameter is required"),
+		)
+	}
+	if q.FromLedger == 0 {
+		return problem.MakeInvalidFieldProblem(
+			"from_ledger",
+			errors.New("this parameter must be a ledger sequence greater than zero"),
+		)
+	}
+	if q.ToLedger != 0 && q.ToLedger < q.FromLedger {
+		return problem.MakeInvalidFieldProblem(
+			"to_ledger",
+			errors.New("this parameter must not be lower than from_ledger"),
+		)
+	}
+	if q.Resolution != 0 {
+		if _, ok := history.AllowedResolutions[time.Duration(q.Resolution)*time.Millisecond]; !ok {
+			return problem.MakeInvalidFieldProblem(
+				"resolution",
+				errors.New("resolution is not allowed"),
+			)
+		}
+	}
+	if parts := strings.Split(q.Asset, ":"); len(parts) == 2 && parts[1] == q.AccountID {
+		return problem.MakeInvalidFieldProblem(
+			"asset",
+			errors.New("the account is the issuer of this asset"),
+		)
+	}
+	return nil
+}
+
+// ingestionFiltered returns true if the transactions ingested into the
+// history tables are restricted by the filters of the config or the ones
+// stored in the DB, which replace them.
+func (handler GetAccountBalanceHistoryHandler) ingestionFiltered(r *http.Request, historyQ *history.Q) (bool, error) {
+	rules := handler.IngestFilters
+	stored, err := historyQ.GetIngestFilters(r.Context())
+	if err != nil {
+		return false, errors.Wrap(err, "could not load ingest filters")
+	}
+	for _, filter := range stored {
+		if err = rules.Set(filter.Name, filter.Allowed); err != nil {
+			return false, err
+		}
+	}
+	return !rules.Empty(), nil
+}
+
+func (q AccountBalanceHistoryQuery) asset() xdr.Asset {
+	if q.Asset == "native" {
+		return xdr.MustNewNativeAsset()
+	}
+	parts := strings.Split(q.Asset, ":")
+	return xdr.MustNewCreditAsset(parts[0], parts[1])
+}
+
+// GetAccountBalanceHistoryHandler is the action handler for the account
+// balance history endpoint. Balances are derived from the effects of the
+// account, starting from its balance at the last ingested ledger, so the
+// endpoint is not available when ingestion is filtered: the state tables are
+// complete but the effects are not.
+type GetAccountBalanceHistoryHandler struct {
+	LedgerState *ledger.State
+	// IngestFilters are the transaction filters of ingestion set in the
+	// config, the ones set with the admin endpoints are loaded from the DB.
+	IngestFilters processors.TransactionFilterRules
+}
+
+// GetResource returns the balance of an asset held by an account at every
+// ledger in which it changed or, if a resolution is given, at the last ledger
+// of every time bucket.
+func (handler GetAccountBalanceHistoryHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := AccountBalanceHistoryQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered, err := handler.ingestionFiltered(r, historyQ)
+	if err != nil {
+		return nil, err
+	}
+	if filtered {
+		return nil, hProblem.FilteredHistory
+	}
+
+	lastIngested, err := historyQ.GetLastLedgerIngestNonBlocking(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load last ingested ledger")
+	}
+	if lastIngested == 0 {
+		return nil, hProblem.StillIngesting
+	}
+
+	from, to := int32(qp.FromLedger), int32(lastIngested)
+	if qp.ToLedger != 0 && int32(qp.ToLedger) < to {
+		to = int32(qp.ToLedger)
+	}
+	if from < handler.LedgerState.CurrentStatus().HistoryElder {
+		return nil, hProblem.BeforeHistory
+	}
+	if from > to {
+		return nil, problem.MakeInvalidFieldProblem(
+			"from_ledger",
+			errors.New("this parameter must not be greater than the last ingested ledger"),
+		)
+	}
+
+	asset := qp.asset()
+	current, err := currentBalance(r, historyQ, qp.AccountID, asset)
+	if err != nil {
+		return nil, err
+	}
+	changedSince, err := historyQ.BalanceChangeAfterLedger(ctx, qp.AccountID, asset, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load balance changes")
+	}
+	changes, err := historyQ.BalanceChangesInRange(ctx, qp.AccountID, asset, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load balance changes")
+	}
+
+	var sequences []int32
+	if qp.Resolution != 0 {
+		sequences, err = historyQ.LastLedgersByResolution(ctx, from, to, qp.Resolution)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not load ledgers")
+		}
+	} else {
+		sequences = append(sequences, from)
+		for _, change := range changes {
+			sequences = append(sequences, change.LedgerSequence)
+		}
+	}
+	if len(sequences) > maxBalanceHistoryRecords {
+		return nil, problem.MakeInvalidFieldProblem(
+			"to_ledger",
+			errors.Errorf(
+				"the range contains more than %d balances, use a shorter range or a larger resolution",
+				maxBalanceHistoryRecords,
+			),
+		)
+	}
+
+	closedAt := map[int32]time.Time{}
+	if len(sequences) > 0 {
+		var ledgers []history.Ledger
+		if err = historyQ.LedgersBySequence(ctx, &ledgers, sequences...); err != nil {
+			return nil, errors.Wrap(err, "could not load ledgers")
+		}
+		for _, l := range ledgers {
+			closedAt[l.Sequence] = l.ClosedAt
+		}
+	}
+
+	// balance is the balance at `from`, it is moved forward as the
+	// changes up to every record are applied
+	balance := current - changedSince
+	for _, change := range changes {
+		balance -= change.Amount
+	}
+
+	var assetType, code, issuer string
+	if err = asset.Extract(&assetType, &code, &issuer); err != nil {
+		return nil, errors.Wrap(err, "could not extract asset")
+	}
+	result := aurora.AccountBalanceHistory{
+		Account: qp.AccountID,
+		Asset:   base.Asset{Type: assetType, Code: code, Issuer: issuer},
+		Records: make([]aurora.AccountBalanceRecord, 0, len(sequences)),
+	}
+	for _, seq := range sequences {
+		for len(changes) > 0 && changes[0].LedgerSequence <= seq {
+			balance += changes[0].Amount
+			changes = changes[1:]
+		}
+		result.Records = append(result.Records, aurora.AccountBalanceRecord{
+			Ledger:   seq,
+			ClosedAt: closedAt[seq],
+			Balance:  amount.StringFromInt64(balance),
+		})
+	}
+
+	return result, nil
+}
+
+// currentBalance returns the balance of `asset` held by `accountID` at the
+// last ingested ledger. Accounts and trust lines which do not exist hold a
+// zero balance.
+func currentBalance(r *http.Request, historyQ *history.Q, accountID string, asset xdr.Asset) (int64, error) {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		account, err := historyQ.GetAccountByID(r.Context(), accountID)
+		if historyQ.NoRows(err) {
+			return 0, nil
+		} else if err != nil {
+			return 0, errors.Wrap(err, "could not load account")
+		}
+		return account.Balance, nil
+	}
+
+	var assetType, code, issuer string
+	if err := asset.Extract(&assetType, &code, &issuer); err != nil {
+		return 0, errors.Wrap(err, "could not extract asset")
+	}
+	trustLines, err := historyQ.GetSortedTrustLinesByAccountID(r.Context(), accountID)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not load trust lines")
+	}
+	for _, trustLine := range trustLines {
+		if trustLine.AssetType == asset.Type && trustLine.AssetCode == code && trustLine.AssetIssuer == issuer {
+			return trustLine.Balance, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,158 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/guregu/null"
+
+	protocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
+	"github.com/diamnet/go/services/aurora/internal/ledger"
+	hProblem "github.com/diamnet/go/services/aurora/internal/render/problem"
+	"github.com/diamnet/go/services/aurora/internal/test"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/xdr"
+)
+
+func TestGetAccountBalanceHistoryPathPayment(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{tt.AuroraSession()}
+
+	for seq := uint32(2); seq <= 4; seq++ {
+		_, err := q.InsertLedger(tt.Ctx, xdr.LedgerHeaderHistoryEntry{
+			Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(seq)},
+		}, 0, 0, 0, 0, 0)
+		tt.Assert.NoError(err)
+	}
+	tt.Assert.NoError(q.UpdateLastLedgerIngest(tt.Ctx, 4))
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []history.AccountEntry{{
+		AccountID:          accountOne,
+		Balance:            850000000,
+		SequenceNumber:     1,
+		LastModifiedLedger: 4,
+	}}))
+
+	accountIDs, err := q.CreateAccounts(tt.Ctx, []string{accountOne, accountTwo}, 2)
+	tt.Assert.NoError(err)
+
+	// ledger 3: accountOne pays 20 USD to accountTwo with a path payment
+	// sending 10 XLM through an offer of accountTwo
+	pathPaymentID := toid.New(3, 1, 1).ToInt64()
+	operations := q.NewOperationBatchInsertBuilder(1)
+	tt.Assert.NoError(operations.Add(
+		tt.Ctx,
+		pathPaymentID,
+		toid.New(3, 1, 0).ToInt64(),
+		1,
+		xdr.OperationTypePathPaymentStrictReceive,
+		[]byte("{}"),
+		accountOne,
+		null.String{},
+	))
+	tt.Assert.NoError(operations.Exec(tt.Ctx))
+
+	effects := q.NewEffectBatchInsertBuilder(10)
+	order := uint32(1)
+	addEffect := func(account string, operationID int64, effectType history.EffectType, details interface{}) {
+		encoded, err := json.Marshal(details)
+		tt.Assert.NoError(err)
+		tt.Assert.NoError(effects.Add(tt.Ctx, accountIDs[account], null.String{}, operationID, order, effectType, encoded))
+		order++
+	}
+	addEffect(accountOne, toid.New(2, 1, 1).ToInt64(), history.EffectAccountCreated, map[string]string{
+		"starting_balance": "100.0000000",
+	})
+	addEffect(accountOne, pathPaymentID, history.EffectAccountDebited, map[string]string{
+		"amount":     "10.0000000",
+		"asset_type": "native",
+	})
+	addEffect(accountOne, pathPaymentID, history.EffectTrade, map[string]string{
+		"seller":              accountTwo,
+		"sold_amount":         "10.0000000",
+		"sold_asset_type":     "native",
+		"bought_amount":       "20.0000000",
+		"bought_asset_type":   "credit_alphanum4",
+		"bought_asset_code":   "USD",
+		"bought_asset_issuer": trustLineIssuer,
+	})
+	addEffect(accountTwo, pathPaymentID, history.EffectTrade, map[string]string{
+		"seller":            accountOne,
+		"sold_amount":       "20.0000000",
+		"sold_asset_type":   "credit_alphanum4",
+		"sold_asset_code":   "USD",
+		"sold_asset_issuer": trustLineIssuer,
+		"bought_amount":     "10.0000000",
+		"bought_asset_type": "native",
+	})
+	addEffect(accountTwo, pathPaymentID, history.EffectAccountCredited, map[string]string{
+		"amount":       "20.0000000",
+		"asset_type":   "credit_alphanum4",
+		"asset_code":   "USD",
+		"asset_issuer": trustLineIssuer,
+	})
+	// ledger 4: accountOne deposits 5 XLM and 2 USD in a liquidity pool
+	addEffect(accountOne, toid.New(4, 1, 1).ToInt64(), history.EffectLiquidityPoolDeposited, map[string]interface{}{
+		"reserves_deposited": []map[string]string{
+			{"asset": "native", "amount": "5.0000000"},
+			{"asset": usd.StringCanonical(), "amount": "2.0000000"},
+		},
+		"shares_received": "3.0000000",
+	})
+	tt.Assert.NoError(effects.Exec(tt.Ctx))
+
+	handler := GetAccountBalanceHistoryHandler{LedgerState: &ledger.State{}}
+	response, err := handler.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"asset": "native", "from_ledger": "2"},
+		map[string]string{"account_id": accountOne},
+		q,
+	))
+	tt.Assert.NoError(err)
+
+	var balances []string
+	for _, record := range response.(protocol.AccountBalanceHistory).Records {
+		balances = append(balances, record.Balance)
+	}
+	// the trade effect of the path payment source does not debit it again
+	tt.Assert.Equal([]string{"100.0000000", "90.0000000", "85.0000000"}, balances)
+}
+
+func TestGetAccountBalanceHistoryFilteredIngestion(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{tt.AuroraSession()}
+
+	request := func() *http.Request {
+		return makeRequest(
+			t,
+			map[string]string{"asset": "native", "from_ledger": "2"},
+			map[string]string{"account_id": accountOne},
+			q,
+		)
+	}
+
+	// filters of the config
+	handler := GetAccountBalanceHistoryHandler{
+		LedgerState:   &ledger.State{},
+		IngestFilters: processors.TransactionFilterRules{Accounts: []string{accountTwo}},
+	}
+	_, err := handler.GetResource(httptest.NewRecorder(), request())
+	tt.Assert.Equal(hProblem.FilteredHistory, err)
+
+	// filters set with the admin endpoints replace the ones of the config
+	tt.Assert.NoError(q.UpsertIngestFilter(tt.Ctx, processors.AccountsFilter, []string{}))
+	_, err = handler.GetResource(httptest.NewRecorder(), request())
+	tt.Assert.Equal(hProblem.StillIngesting, err)
+
+	handler.IngestFilters = processors.TransactionFilterRules{}
+	tt.Assert.NoError(q.UpsertIngestFilter(tt.Ctx, processors.OperationTypesFilter, []string{"payment"}))
+	_, err = handler.GetResource(httptest.NewRecorder(), request())
+	tt.Assert.Equal(hProblem.FilteredHistory, err)
+}
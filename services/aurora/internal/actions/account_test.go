@@ -716,7 +716,7 @@ func TestGetAccountsHandlerInvalidParams(t *testing.T) {
 
 func TestAccountQueryURLTemplate(t *testing.T) {
 	tt := assert.New(t)
-	expected := "/accounts{?signer,sponsor,asset,liquidity_pool,cursor,limit,order}"
+	expected := "/accounts{?signer,sponsor,asset,liquidity_pool,as_of_ledger,cursor,limit,order}"
 	accountsQuery := AccountsQuery{}
 	tt.Equal(expected, accountsQuery.URITemplate())
 }
@@ -0,0 +1,310 @@
+package actions
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/diamnet/go/protocols/aurora"
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ratelimit"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/render/hal"
+	"github.com/diamnet/go/support/render/problem"
+)
+
+var tierNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,64}$`)
+
+// GetRateLimitTiersHandler is the admin action handler listing all rate
+// limit tiers.
+type GetRateLimitTiersHandler struct{}
+
+// GetResource returns all rate limit tiers.
+func (handler GetRateLimitTiersHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tiers, err := historyQ.GetRateLimitTiers(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	var page hal.BasePage
+	page.Init()
+	for _, tier := range tiers {
+		page.Add(newRateLimitTierResource(tier))
+	}
+	return page, nil
+}
+
+// PutRateLimitTierHandler is the admin action handler creating or updating a
+// rate limit tier.
+type PutRateLimitTierHandler struct {
+	Limiter *ratelimit.Limiter
+}
+
+// GetResource creates the rate limit tier named in the URL or updates its
+// quotas (`requests_per_hour` and `streams_per_hour`, 0 is unlimited).
+func (handler PutRateLimitTierHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	name, err := getRateLimitTierName(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tier := history.RateLimitTier{Name: name}
+	if tier.RequestsPerHour, err = getQuota(r, "requests_per_hour"); err != nil {
+		return nil, err
+	}
+	if tier.StreamsPerHour, err = getQuota(r, "streams_per_hour"); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = historyQ.UpsertRateLimitTier(ctx, tier); err != nil {
+		return nil, errors.Wrap(err, "could not upsert rate limit tier")
+	}
+	if handler.Limiter != nil {
+		// The quotas of all the keys of the tier changed
+		handler.Limiter.Forget("")
+	}
+
+	return newRateLimitTierResource(tier), nil
+}
+
+// DeleteRateLimitTierHandler is the admin action handler removing a rate
+// limit tier.
+type DeleteRateLimitTierHandler struct{}
+
+// GetResource removes a rate limit tier which is not assigned to any API key
+// and returns its last state.
+func (handler DeleteRateLimitTierHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	name, err := getRateLimitTierName(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tier, err := historyQ.GetRateLimitTierByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := historyQ.RemoveRateLimitTier(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if removed == 0 {
+		return nil, problem.MakeInvalidFieldProblem(
+			"name",
+			errors.New("The tier is assigned to API keys"),
+		)
+	}
+
+	return newRateLimitTierResource(tier), nil
+}
+
+// CreateAPIKeyHandler is the admin action handler registering a new API key.
+type CreateAPIKeyHandler struct{}
+
+// GetResource creates an API key assigned to the `tier` of the request form.
+// The key itself is only returned in this response.
+func (handler CreateAPIKeyHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	tier, err := getString(r, "tier")
+	if err != nil {
+		return nil, err
+	}
+	description, err := getString(r, "description")
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = historyQ.GetRateLimitTierByName(ctx, tier); historyQ.NoRows(err) {
+		return nil, problem.MakeInvalidFieldProblem(
+			"tier",
+			errors.New("Rate limit tier does not exist"),
+		)
+	} else if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := ratelimit.NewKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := historyQ.InsertAPIKey(ctx, history.APIKey{
+		KeyHash:     ratelimit.HashKey(apiKey),
+		Tier:        tier,
+		Description: description,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resource := newAPIKeyResource(key)
+	resource.Key = apiKey
+	return resource, nil
+}
+
+// GetAPIKeysHandler is the admin action handler listing all API keys.
+type GetAPIKeysHandler struct{}
+
+// GetResource returns all API keys.
+func (handler GetAPIKeysHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := historyQ.GetAPIKeys(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	var page hal.BasePage
+	page.Init()
+	for _, key := range keys {
+		page.Add(newAPIKeyResource(key))
+	}
+	return page, nil
+}
+
+// GetAPIKeyByIDHandler is the admin action handler returning a single API
+// key.
+type GetAPIKeyByIDHandler struct{}
+
+// GetResource returns an API key.
+func (handler GetAPIKeyByIDHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	id, err := getAPIKeyID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := historyQ.GetAPIKeyByID(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAPIKeyResource(key), nil
+}
+
+// DeleteAPIKeyHandler is the admin action handler revoking an API key.
+type DeleteAPIKeyHandler struct {
+	Limiter *ratelimit.Limiter
+}
+
+// GetResource removes an API key and returns its last state.
+func (handler DeleteAPIKeyHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	id, err := getAPIKeyID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := historyQ.GetAPIKeyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = historyQ.RemoveAPIKey(ctx, id); err != nil {
+		return nil, err
+	}
+	if handler.Limiter != nil {
+		handler.Limiter.Forget(key.KeyHash)
+	}
+
+	return newAPIKeyResource(key), nil
+}
+
+func getRateLimitTierName(r *http.Request) (string, error) {
+	name, err := getString(r, "name")
+	if err != nil {
+		return "", err
+	}
+	if !tierNameRegexp.MatchString(name) {
+		return "", problem.MakeInvalidFieldProblem(
+			"name",
+			errors.New("Tier name must contain 1 to 64 letters, digits, '-' or '_'"),
+		)
+	}
+	return name, nil
+}
+
+func getQuota(r *http.Request, name string) (int64, error) {
+	value, err := getString(r, name)
+	if err != nil {
+		return 0, err
+	}
+
+	quota, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || quota < 0 {
+		return 0, problem.MakeInvalidFieldProblem(
+			name,
+			errors.New("Quota must be an integer higher than or equal to 0"),
+		)
+	}
+	return quota, nil
+}
+
+func getAPIKeyID(r *http.Request) (int64, error) {
+	value, err := getString(r, "id")
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, problem.MakeInvalidFieldProblem(
+			"id",
+			errors.New("API key ID must be an integer higher than 0"),
+		)
+	}
+
+	return id, nil
+}
+
+func newRateLimitTierResource(tier history.RateLimitTier) aurora.RateLimitTier {
+	return aurora.RateLimitTier{
+		Name:            tier.Name,
+		RequestsPerHour: tier.RequestsPerHour,
+		StreamsPerHour:  tier.StreamsPerHour,
+	}
+}
+
+func newAPIKeyResource(key history.APIKey) aurora.APIKey {
+	return aurora.APIKey{
+		ID:          strconv.FormatInt(key.ID, 10),
+		Tier:        key.Tier,
+		Description: key.Description,
+		CreatedAt:   key.CreatedAt,
+	}
+}
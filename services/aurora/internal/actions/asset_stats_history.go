@@ -0,0 +1,146 @@
+package actions
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	gTime "time"
+
+	"github.com/diamnet/go/protocols/aurora"
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ledger"
+	"github.com/diamnet/go/services/aurora/internal/resourceadapter"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/render/hal"
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/support/time"
+	"github.com/diamnet/go/xdr"
+)
+
+// AssetStatsHistoryQuery query struct for the asset stats history end-point
+type AssetStatsHistoryQuery struct {
+	Asset            string      `schema:"asset" valid:"asset"`
+	StartTimeFilter  time.Millis `schema:"start_time" valid:"-"`
+	EndTimeFilter    time.Millis `schema:"end_time" valid:"-"`
+	ResolutionFilter uint64      `schema:"resolution" valid:"-"`
+}
+
+// Validate runs custom validations.
+func (q AssetStatsHistoryQuery) Validate() error {
+	if q.Asset == "native" {
+		return problem.MakeInvalidFieldProblem(
+			"asset",
+			errors.New("statistics are only available for credit assets"),
+		)
+	}
+	resolutionDuration := gTime.Duration(q.ResolutionFilter) * gTime.Millisecond
+	if _, ok := history.AllowedAssetStatsResolutions[resolutionDuration]; !ok {
+		return problem.MakeInvalidFieldProblem(
+			"resolution",
+			errors.New("illegal or missing resolution. "+
+				"allowed resolutions are: 1 day (86400000) and 1 week (604800000)"),
+		)
+	}
+	if !q.StartTimeFilter.IsNil() && !q.EndTimeFilter.IsNil() && q.EndTimeFilter < q.StartTimeFilter {
+		return problem.MakeInvalidFieldProblem(
+			"end_time",
+			errors.New("illegal end time. end time must be greater than the start time"),
+		)
+	}
+	return nil
+}
+
+func (q AssetStatsHistoryQuery) asset() xdr.Asset {
+	parts := strings.Split(q.Asset, ":")
+	return xdr.MustNewCreditAsset(parts[0], parts[1])
+}
+
+// GetAssetStatsHistoryHandler is the action handler for the statistics of an
+// asset over time. The statistics are aggregated from daily buckets like the
+// trade aggregations.
+type GetAssetStatsHistoryHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResource returns a page of the statistics of an asset
+func (handler GetAssetStatsHistoryHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+	qp := AssetStatsHistoryQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	assetID, err := historyQ.GetAssetID(ctx, qp.asset())
+	if err != nil {
+		p := problem.BadRequest
+		if historyQ.NoRows(err) {
+			p = problem.NotFound
+			err = errors.New("not found")
+		}
+		return nil, problem.NewProblemWithInvalidField(p, "asset", err)
+	}
+
+	records, err := historyQ.GetAssetStatsBuckets(
+		ctx,
+		assetID,
+		int64(qp.ResolutionFilter),
+		qp.StartTimeFilter,
+		qp.EndTimeFilter,
+		pq,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	page := hal.Page{
+		Cursor: pq.Cursor,
+		Order:  pq.Order,
+		Limit:  pq.Limit,
+	}
+	page.Init()
+	for _, record := range records {
+		var res aurora.AssetStatsBucket
+		if err = resourceadapter.PopulateAssetStatsBucket(ctx, &res, record); err != nil {
+			return nil, err
+		}
+		page.Add(res)
+	}
+
+	newURL := FullURL(ctx)
+	q := newURL.Query()
+	page.Links.Self = hal.NewLink(newURL.String())
+
+	// adjust time range for next page
+	if len(records) == 0 {
+		page.Links.Next = page.Links.Self
+	} else {
+		timestamp := records[len(records)-1].Timestamp
+		if page.Order == "asc" {
+			newStartTime := timestamp + int64(qp.ResolutionFilter)
+			if !qp.EndTimeFilter.IsNil() && newStartTime >= qp.EndTimeFilter.ToInt64() {
+				newStartTime = qp.EndTimeFilter.ToInt64()
+			}
+			q.Set("start_time", strconv.FormatInt(newStartTime, 10))
+		} else {
+			newEndTime := timestamp
+			if newEndTime <= qp.StartTimeFilter.ToInt64() {
+				newEndTime = qp.StartTimeFilter.ToInt64()
+			}
+			q.Set("end_time", strconv.FormatInt(newEndTime, 10))
+		}
+		newURL.RawQuery = q.Encode()
+		page.Links.Next = hal.NewLink(newURL.String())
+	}
+
+	return page, nil
+}
@@ -0,0 +1,75 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/xdr"
+)
+
+func TestAssetStatsHistoryQueryParams(t *testing.T) {
+	issuer := "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+	for _, testCase := range []struct {
+		name         string
+		asset        string
+		query        map[string]string
+		invalidField string
+	}{
+		{
+			name:  "daily",
+			asset: "USD:" + issuer,
+			query: map[string]string{"resolution": "86400000"},
+		},
+		{
+			name:  "weekly with time range",
+			asset: "USD:" + issuer,
+			query: map[string]string{"resolution": "604800000", "start_time": "0", "end_time": "604800000"},
+		},
+		{
+			name:         "native",
+			asset:        "native",
+			query:        map[string]string{"resolution": "86400000"},
+			invalidField: "asset",
+		},
+		{
+			name:         "invalid asset",
+			asset:        "USD",
+			query:        map[string]string{"resolution": "86400000"},
+			invalidField: "asset",
+		},
+		{
+			name:         "missing resolution",
+			asset:        "USD:" + issuer,
+			query:        map[string]string{},
+			invalidField: "resolution",
+		},
+		{
+			name:         "hourly",
+			asset:        "USD:" + issuer,
+			query:        map[string]string{"resolution": "3600000"},
+			invalidField: "resolution",
+		},
+		{
+			name:         "end before start",
+			asset:        "USD:" + issuer,
+			query:        map[string]string{"resolution": "86400000", "start_time": "1000", "end_time": "500"},
+			invalidField: "end_time",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := makeRequest(t, testCase.query, map[string]string{"asset": testCase.asset}, nil)
+			qp := AssetStatsHistoryQuery{}
+			err := getParams(&qp, r)
+			if testCase.invalidField == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, xdr.MustNewCreditAsset("USD", issuer), qp.asset())
+				return
+			}
+			if assert.IsType(t, &problem.P{}, err) {
+				assert.Equal(t, testCase.invalidField, err.(*problem.P).Extras["invalid_field"])
+			}
+		})
+	}
+}
@@ -22,7 +22,8 @@ type GetClaimableBalanceByIDHandler struct{}
 
 // ClaimableBalanceQuery query struct for claimables_balances/id end-point
 type ClaimableBalanceQuery struct {
-	ID string `schema:"id" valid:"claimableBalanceID,required"`
+	ID                    string `schema:"id" valid:"claimableBalanceID,required"`
+	AsOfLedgerQueryParams `valid:"-"`
 }
 
 // GetResource returns an claimable balance page.
@@ -38,6 +39,10 @@ func (handler GetClaimableBalanceByIDHandler) GetResource(w HeaderWriter, r *htt
 	if err != nil {
 		return nil, err
 	}
+	ctx, err = qp.Context(ctx, historyQ)
+	if err != nil {
+		return nil, err
+	}
 	cb, err := historyQ.FindClaimableBalanceByID(ctx, qp.ID)
 	if err != nil {
 		return nil, err
@@ -64,9 +69,10 @@ func (handler GetClaimableBalanceByIDHandler) GetResource(w HeaderWriter, r *htt
 
 // ClaimableBalancesQuery query struct for claimable_balances end-point
 type ClaimableBalancesQuery struct {
-	AssetFilter    string `schema:"asset" valid:"asset,optional"`
-	SponsorFilter  string `schema:"sponsor" valid:"accountID,optional"`
-	ClaimantFilter string `schema:"claimant" valid:"accountID,optional"`
+	AssetFilter           string `schema:"asset" valid:"asset,optional"`
+	SponsorFilter         string `schema:"sponsor" valid:"accountID,optional"`
+	ClaimantFilter        string `schema:"claimant" valid:"accountID,optional"`
+	AsOfLedgerQueryParams `valid:"-"`
 }
 
 func (q ClaimableBalancesQuery) asset() *xdr.Asset {
@@ -100,7 +106,7 @@ func (q ClaimableBalancesQuery) claimant() *xdr.AccountId {
 
 // URITemplate returns a rfc6570 URI template the query struct
 func (q ClaimableBalancesQuery) URITemplate() string {
-	return "/claimable_balances?{asset,claimant,sponsor}"
+	return "/claimable_balances?{asset,claimant,sponsor,as_of_ledger}"
 }
 
 type GetClaimableBalancesHandler struct {
@@ -144,6 +150,11 @@ func (handler GetClaimableBalancesHandler) GetResourcePage(
 		return nil, err
 	}
 
+	ctx, err = qp.Context(ctx, historyQ)
+	if err != nil {
+		return nil, err
+	}
+
 	claimableBalances, err := getClaimableBalancesPage(ctx, historyQ, query)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,163 @@
+package actions
+
+import (
+	"context"
+	"sort"
+
+	"github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/coldstorage"
+	"github.com/diamnet/go/services/aurora/internal/db2"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/render/problem"
+	"github.com/diamnet/go/services/aurora/internal/resourceadapter"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/render/hal"
+	supportProblem "github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/xdr"
+)
+
+// loadColdRecords returns the records of a ledger reaped from the history
+// tables. It returns problem.BeforeHistory when there is no cold storage or the
+// ledger is not in it.
+func loadColdRecords(ctx context.Context, store *coldstorage.Store, sequence uint32) (*coldstorage.Records, error) {
+	if store == nil {
+		return nil, problem.BeforeHistory
+	}
+	records, err := store.Records(ctx, sequence)
+	if err == coldstorage.ErrNotFound {
+		return nil, problem.BeforeHistory
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "loading records from cold storage")
+	}
+	return records, nil
+}
+
+// coldTransactionByHash returns the transaction with the given hash from the
+// cold storage, it is used when the transaction is not in the history tables.
+func coldTransactionByHash(ctx context.Context, hq *history.Q, store *coldstorage.Store, hash string) (history.Transaction, error) {
+	sequence, err := hq.ColdTransactionLedger(ctx, hash)
+	if err != nil {
+		return history.Transaction{}, err
+	}
+	records, err := loadColdRecords(ctx, store, uint32(sequence))
+	if err != nil {
+		return history.Transaction{}, err
+	}
+	transaction, ok := records.Transaction(hash)
+	if !ok {
+		return history.Transaction{}, supportProblem.NotFound
+	}
+	return transaction, nil
+}
+
+// coldPageIDs returns the indexes of the page of the rows with the given IDs,
+// which are in ascending order, selected by pq.
+func coldPageIDs(ids []int64, pq db2.PageQuery) ([]int, error) {
+	cursor, err := pq.CursorInt64()
+	if err != nil {
+		return nil, supportProblem.MakeInvalidFieldProblem("cursor", errors.New("invalid value"))
+	}
+
+	var page []int
+	if pq.Order == db2.OrderDescending {
+		for i := sort.Search(len(ids), func(i int) bool { return ids[i] >= cursor }) - 1; i >= 0; i-- {
+			if uint64(len(page)) == pq.Limit {
+				break
+			}
+			page = append(page, i)
+		}
+		return page, nil
+	}
+	for i := sort.Search(len(ids), func(i int) bool { return ids[i] > cursor }); i < len(ids); i++ {
+		if uint64(len(page)) == pq.Limit {
+			break
+		}
+		page = append(page, i)
+	}
+	return page, nil
+}
+
+// coldTransactionsPage returns a page of the transactions of a ledger from the
+// cold storage.
+func coldTransactionsPage(ctx context.Context, records *coldstorage.Records, qp TransactionsQuery, pq db2.PageQuery) ([]hal.Pageable, error) {
+	var transactions []history.Transaction
+	var ids []int64
+	for _, transaction := range records.Transactions {
+		if !transaction.Successful && !qp.IncludeFailedTransactions {
+			continue
+		}
+		transactions = append(transactions, transaction)
+		ids = append(ids, transaction.ID)
+	}
+
+	page, err := coldPageIDs(ids, pq)
+	if err != nil {
+		return nil, err
+	}
+	var response []hal.Pageable
+	for _, i := range page {
+		var res aurora.Transaction
+		err = resourceadapter.PopulateTransaction(ctx, transactions[i].TransactionHash, &res, transactions[i])
+		if err != nil {
+			return nil, errors.Wrap(err, "could not populate transaction")
+		}
+		response = append(response, res)
+	}
+	return response, nil
+}
+
+// coldOperationsPage returns a page of the operations of a ledger from the
+// cold storage.
+func coldOperationsPage(ctx context.Context, records *coldstorage.Records, qp OperationsQuery, pq db2.PageQuery, onlyPayments bool) ([]hal.Pageable, error) {
+	transactions := map[int64]*history.Transaction{}
+	for i := range records.Transactions {
+		transactions[records.Transactions[i].ID] = &records.Transactions[i]
+	}
+
+	var operations []history.Operation
+	var ids []int64
+	for _, operation := range records.Operations {
+		if !operation.TransactionSuccessful && !qp.IncludeFailedTransactions {
+			continue
+		}
+		if onlyPayments && !isPaymentOperation(operation.Type) {
+			continue
+		}
+		operations = append(operations, operation)
+		ids = append(ids, operation.ID)
+	}
+
+	page, err := coldPageIDs(ids, pq)
+	if err != nil {
+		return nil, err
+	}
+	var response []hal.Pageable
+	for _, i := range page {
+		var transaction *history.Transaction
+		if qp.IncludeTransactions() {
+			transaction = transactions[operations[i].TransactionID]
+		}
+		res, err := resourceadapter.NewOperation(
+			ctx,
+			operations[i],
+			operations[i].TransactionHash,
+			transaction,
+			records.Ledger,
+		)
+		if err != nil {
+			return nil, err
+		}
+		response = append(response, res)
+	}
+	return response, nil
+}
+
+func isPaymentOperation(operationType xdr.OperationType) bool {
+	for _, paymentType := range history.PaymentOperationTypes {
+		if operationType == paymentType {
+			return true
+		}
+	}
+	return false
+}
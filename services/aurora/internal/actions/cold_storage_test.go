@@ -0,0 +1,35 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/diamnet/go/services/aurora/internal/db2"
+)
+
+func TestColdPageIDs(t *testing.T) {
+	ids := []int64{10, 20, 30, 40}
+	for _, testCase := range []struct {
+		name     string
+		pq       db2.PageQuery
+		expected []int
+	}{
+		{"asc", db2.PageQuery{Order: db2.OrderAscending, Limit: 10}, []int{0, 1, 2, 3}},
+		{"asc limit", db2.PageQuery{Order: db2.OrderAscending, Limit: 2}, []int{0, 1}},
+		{"asc cursor", db2.PageQuery{Cursor: "20", Order: db2.OrderAscending, Limit: 10}, []int{2, 3}},
+		{"desc", db2.PageQuery{Order: db2.OrderDescending, Limit: 3}, []int{3, 2, 1}},
+		{"desc cursor", db2.PageQuery{Cursor: "30", Order: db2.OrderDescending, Limit: 10}, []int{1, 0}},
+		{"desc cursor between ids", db2.PageQuery{Cursor: "35", Order: db2.OrderDescending, Limit: 10}, []int{2, 1, 0}},
+		{"after last", db2.PageQuery{Cursor: "40", Order: db2.OrderAscending, Limit: 10}, nil},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			page, err := coldPageIDs(ids, testCase.pq)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected, page)
+		})
+	}
+
+	_, err := coldPageIDs(ids, db2.PageQuery{Cursor: "invalid", Order: db2.OrderAscending, Limit: 10})
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package actions
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/diamnet/go/protocols/aurora"
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/render/hal"
+	"github.com/diamnet/go/support/render/problem"
+)
+
+// GetIngestFiltersHandler is the admin action handler listing the ingestion
+// filters set through the admin port.
+type GetIngestFiltersHandler struct{}
+
+// GetResource returns all ingestion filters stored in the DB.
+func (handler GetIngestFiltersHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := historyQ.GetIngestFilters(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	var page hal.BasePage
+	page.Init()
+	for _, filter := range filters {
+		page.Add(newIngestFilterResource(filter))
+	}
+	return page, nil
+}
+
+// PutIngestFilterHandler is the admin action handler replacing the values
+// allowed by one of the ingestion filters.
+type PutIngestFilterHandler struct{}
+
+// GetResource sets the comma-separated `allowed` values of the filter named
+// in the URL (`accounts`, `assets` or `operation_types`). It replaces the
+// filter of the ingestion config, an empty list lifts the restriction. The
+// filter applies from the next ingested ledger.
+func (handler PutIngestFilterHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	name, err := getIngestFilterName(r)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := getString(r, "allowed")
+	if err != nil {
+		return nil, err
+	}
+	allowed := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			allowed = append(allowed, v)
+		}
+	}
+
+	var rules processors.TransactionFilterRules
+	if err = rules.Set(name, allowed); err != nil {
+		return nil, err
+	}
+	if _, err = processors.NewTransactionFilter(rules); err != nil {
+		return nil, problem.MakeInvalidFieldProblem("allowed", err)
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = historyQ.UpsertIngestFilter(ctx, name, allowed); err != nil {
+		return nil, errors.Wrap(err, "could not upsert ingest filter")
+	}
+
+	filter, err := historyQ.GetIngestFilterByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return newIngestFilterResource(filter), nil
+}
+
+// DeleteIngestFilterHandler is the admin action handler removing one of the
+// ingestion filters set through the admin port.
+type DeleteIngestFilterHandler struct{}
+
+// GetResource removes an ingestion filter, which restores the filter of the
+// ingestion config, and returns its last state.
+func (handler DeleteIngestFilterHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	name, err := getIngestFilterName(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := historyQ.GetIngestFilterByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = historyQ.RemoveIngestFilter(ctx, name); err != nil {
+		return nil, err
+	}
+
+	return newIngestFilterResource(filter), nil
+}
+
+func getIngestFilterName(r *http.Request) (string, error) {
+	name, err := getString(r, "name")
+	if err != nil {
+		return "", err
+	}
+
+	var rules processors.TransactionFilterRules
+	if err = rules.Set(name, nil); err != nil {
+		return "", problem.MakeInvalidFieldProblem(
+			"name",
+			errors.New("Filter name must be accounts, assets or operation_types"),
+		)
+	}
+	return name, nil
+}
+
+func newIngestFilterResource(filter history.IngestFilter) aurora.IngestFilter {
+	allowed := []string(filter.Allowed)
+	if allowed == nil {
+		allowed = []string{}
+	}
+	return aurora.IngestFilter{
+		Name:      filter.Name,
+		Allowed:   allowed,
+		UpdatedAt: filter.UpdatedAt,
+	}
+}
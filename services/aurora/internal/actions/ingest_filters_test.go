@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	protocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/test"
+	"github.com/diamnet/go/support/render/hal"
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPutIngestFilterValidation(t *testing.T) {
+	tt := assert.New(t)
+
+	for _, testCase := range []struct {
+		name   string
+		params map[string]string
+		route  map[string]string
+		field  string
+	}{
+		{"unknown filter", map[string]string{"allowed": "native"}, map[string]string{"name": "ledgers"}, "name"},
+		{"invalid account", map[string]string{"allowed": "GABC"}, map[string]string{"name": "accounts"}, "allowed"},
+		{"invalid asset", map[string]string{"allowed": "native,USD"}, map[string]string{"name": "assets"}, "allowed"},
+		{"invalid operation type", map[string]string{"allowed": "payment,foo"}, map[string]string{"name": "operation_types"}, "allowed"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := PutIngestFilterHandler{}.GetResource(
+				httptest.NewRecorder(),
+				makeRequest(t, testCase.params, testCase.route, nil),
+			)
+			tt.Error(err)
+			p, ok := err.(*problem.P)
+			if tt.True(ok) {
+				tt.Equal(testCase.field, p.Extras["invalid_field"])
+			}
+		})
+	}
+}
+
+func TestIngestFilterHandlers(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{tt.AuroraSession()}
+
+	response, err := PutIngestFilterHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"allowed": "payment, path_payment_strict_send"},
+		map[string]string{"name": "operation_types"},
+		q,
+	))
+	tt.Assert.NoError(err)
+	filter := response.(protocol.IngestFilter)
+	tt.Assert.Equal("operation_types", filter.Name)
+	tt.Assert.Equal([]string{"payment", "path_payment_strict_send"}, filter.Allowed)
+
+	response, err = GetIngestFiltersHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.NoError(err)
+	page := response.(hal.BasePage)
+	tt.Assert.Len(page.Embedded.Records, 1)
+
+	response, err = DeleteIngestFilterHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{"name": "operation_types"},
+		q,
+	))
+	tt.Assert.NoError(err)
+	tt.Assert.Equal("operation_types", response.(protocol.IngestFilter).Name)
+
+	_, err = DeleteIngestFilterHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{"name": "operation_types"},
+		q,
+	))
+	tt.Assert.True(q.NoRows(err))
+}
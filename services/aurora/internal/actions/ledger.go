@@ -4,10 +4,10 @@ import (
 	"net/http"
 
 	"github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/coldstorage"
 	"github.com/diamnet/go/services/aurora/internal/context"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/services/aurora/internal/ledger"
-	"github.com/diamnet/go/services/aurora/internal/render/problem"
 	"github.com/diamnet/go/services/aurora/internal/resourceadapter"
 	"github.com/diamnet/go/support/render/hal"
 )
@@ -57,6 +57,8 @@ type LedgerByIDQuery struct {
 
 type GetLedgerByIDHandler struct {
 	LedgerState *ledger.State
+	// ColdStorage serves the ledgers removed by the reaper, if set.
+	ColdStorage *coldstorage.Store
 }
 
 func (handler GetLedgerByIDHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
@@ -66,7 +68,13 @@ func (handler GetLedgerByIDHandler) GetResource(w HeaderWriter, r *http.Request)
 		return nil, err
 	}
 	if int32(qp.LedgerID) < handler.LedgerState.CurrentStatus().HistoryElder {
-		return nil, problem.BeforeHistory
+		records, err := loadColdRecords(r.Context(), handler.ColdStorage, qp.LedgerID)
+		if err != nil {
+			return nil, err
+		}
+		var result aurora.Ledger
+		resourceadapter.PopulateLedger(r.Context(), &result, records.Ledger)
+		return result, nil
 	}
 	historyQ, err := context.HistoryQFromRequest(r)
 	if err != nil {
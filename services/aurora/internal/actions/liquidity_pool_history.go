@@ -0,0 +1,245 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	gTime "time"
+
+	"github.com/diamnet/go/protocols/aurora"
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ledger"
+	"github.com/diamnet/go/services/aurora/internal/resourceadapter"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/render/hal"
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/support/time"
+)
+
+const (
+	defaultLiquidityPoolAPRWindows = "1,7,30"
+	maxLiquidityPoolAPRWindows     = 10
+	maxLiquidityPoolAPRWindowDays  = 365
+)
+
+// LiquidityPoolHistoryQuery query struct for the liquidity pool history end-point
+type LiquidityPoolHistoryQuery struct {
+	ID               string      `schema:"liquidity_pool_id" valid:"sha256"`
+	StartTimeFilter  time.Millis `schema:"start_time" valid:"-"`
+	EndTimeFilter    time.Millis `schema:"end_time" valid:"-"`
+	ResolutionFilter uint64      `schema:"resolution" valid:"-"`
+}
+
+// Validate runs custom validations.
+func (q LiquidityPoolHistoryQuery) Validate() error {
+	resolutionDuration := gTime.Duration(q.ResolutionFilter) * gTime.Millisecond
+	if _, ok := history.AllowedLiquidityPoolStatsResolutions[resolutionDuration]; !ok {
+		return problem.MakeInvalidFieldProblem(
+			"resolution",
+			errors.New("illegal or missing resolution. "+
+				"allowed resolutions are: 1 hour (3600000), 1 day (86400000) and 1 week (604800000)"),
+		)
+	}
+	if !q.StartTimeFilter.IsNil() && !q.EndTimeFilter.IsNil() && q.EndTimeFilter < q.StartTimeFilter {
+		return problem.MakeInvalidFieldProblem(
+			"end_time",
+			errors.New("illegal end time. end time must be greater than the start time"),
+		)
+	}
+	return nil
+}
+
+// findHistoryLiquidityPool returns the liquidity pool with the given id and its
+// id in the history tables.
+func findHistoryLiquidityPool(ctx context.Context, historyQ *history.Q, poolID string) (history.LiquidityPool, int64, error) {
+	pool, err := historyQ.FindLiquidityPoolByID(ctx, poolID)
+	if err != nil {
+		return pool, 0, err
+	}
+	historyPool, err := historyQ.LiquidityPoolByID(ctx, poolID)
+	if err != nil {
+		return pool, 0, err
+	}
+	return pool, historyPool.InternalID, nil
+}
+
+// GetLiquidityPoolHistoryHandler is the action handler for the activity of a
+// liquidity pool over time. The activity is aggregated from hourly buckets like
+// the trade aggregations.
+type GetLiquidityPoolHistoryHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResource returns a page of the activity of a liquidity pool
+func (handler GetLiquidityPoolHistoryHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+	qp := LiquidityPoolHistoryQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	pool, historyID, err := findHistoryLiquidityPool(ctx, historyQ, qp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := historyQ.GetLiquidityPoolStatsBuckets(
+		ctx,
+		historyID,
+		int64(qp.ResolutionFilter),
+		qp.StartTimeFilter,
+		qp.EndTimeFilter,
+		pq,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	page := hal.Page{
+		Cursor: pq.Cursor,
+		Order:  pq.Order,
+		Limit:  pq.Limit,
+	}
+	page.Init()
+	for _, record := range records {
+		var res aurora.LiquidityPoolStatsBucket
+		if err = resourceadapter.PopulateLiquidityPoolStatsBucket(ctx, &res, pool, record); err != nil {
+			return nil, err
+		}
+		page.Add(res)
+	}
+
+	newURL := FullURL(ctx)
+	q := newURL.Query()
+	page.Links.Self = hal.NewLink(newURL.String())
+
+	// adjust time range for next page
+	if len(records) == 0 {
+		page.Links.Next = page.Links.Self
+	} else {
+		timestamp := records[len(records)-1].Timestamp
+		if page.Order == "asc" {
+			newStartTime := timestamp + int64(qp.ResolutionFilter)
+			if !qp.EndTimeFilter.IsNil() && newStartTime >= qp.EndTimeFilter.ToInt64() {
+				newStartTime = qp.EndTimeFilter.ToInt64()
+			}
+			q.Set("start_time", strconv.FormatInt(newStartTime, 10))
+		} else {
+			newEndTime := timestamp
+			if newEndTime <= qp.StartTimeFilter.ToInt64() {
+				newEndTime = qp.StartTimeFilter.ToInt64()
+			}
+			q.Set("end_time", strconv.FormatInt(newEndTime, 10))
+		}
+		newURL.RawQuery = q.Encode()
+		page.Links.Next = hal.NewLink(newURL.String())
+	}
+
+	return page, nil
+}
+
+// LiquidityPoolAPRQuery query struct for the liquidity pool APR end-point
+type LiquidityPoolAPRQuery struct {
+	ID      string `schema:"liquidity_pool_id" valid:"sha256"`
+	Windows string `schema:"windows" valid:"optional"`
+}
+
+// Validate runs custom validations.
+func (q LiquidityPoolAPRQuery) Validate() error {
+	_, err := q.windows()
+	return err
+}
+
+// windows returns the length in days of the requested windows.
+func (q LiquidityPoolAPRQuery) windows() ([]uint32, error) {
+	windows := q.Windows
+	if windows == "" {
+		windows = defaultLiquidityPoolAPRWindows
+	}
+	parts := strings.Split(windows, ",")
+	if len(parts) > maxLiquidityPoolAPRWindows {
+		return nil, problem.MakeInvalidFieldProblem(
+			"windows",
+			errors.Errorf("at most %d windows can be requested", maxLiquidityPoolAPRWindows),
+		)
+	}
+	days := make([]uint32, 0, len(parts))
+	for _, part := range parts {
+		d, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil || d == 0 || d > maxLiquidityPoolAPRWindowDays {
+			return nil, problem.MakeInvalidFieldProblem(
+				"windows",
+				errors.Errorf(
+					"windows must be a comma separated list of days between 1 and %d",
+					maxLiquidityPoolAPRWindowDays,
+				),
+			)
+		}
+		days = append(days, uint32(d))
+	}
+	return days, nil
+}
+
+// GetLiquidityPoolAPRHandler is the action handler for the fee APR and the
+// impermanent loss of a liquidity pool over windows of time ending at the last
+// complete hour ingested.
+type GetLiquidityPoolAPRHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResource returns the returns of a liquidity pool
+func (handler GetLiquidityPoolAPRHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := LiquidityPoolAPRQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+	windows, err := qp.windows()
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	pool, historyID, err := findHistoryLiquidityPool(ctx, historyQ, qp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	endTime := handler.LedgerState.CurrentStatus().HistoryLatestClosedAt.UTC().Truncate(gTime.Hour)
+	resource := aurora.LiquidityPoolAPR{
+		ID:    pool.PoolID,
+		FeeBP: pool.Fee,
+	}
+	for _, days := range windows {
+		startTime := endTime.Add(-gTime.Duration(days) * 24 * gTime.Hour)
+		window, err := historyQ.GetLiquidityPoolStatsWindow(
+			ctx,
+			historyID,
+			time.MillisFromTime(startTime),
+			time.MillisFromTime(endTime),
+		)
+		if err != nil {
+			return nil, err
+		}
+		var res aurora.LiquidityPoolAPRWindow
+		err = resourceadapter.PopulateLiquidityPoolAPRWindow(ctx, &res, pool, days, startTime, endTime, window)
+		if err != nil {
+			return nil, err
+		}
+		resource.Windows = append(resource.Windows, res)
+	}
+	return resource, nil
+}
@@ -0,0 +1,91 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/diamnet/go/support/render/problem"
+)
+
+const liquidityPoolHistoryID = "cafebabedeadbeef000000000000000000000000000000000000000000000000"
+
+func TestLiquidityPoolHistoryQueryParams(t *testing.T) {
+	for _, testCase := range []struct {
+		name         string
+		query        map[string]string
+		invalidField string
+	}{
+		{
+			name:  "hourly",
+			query: map[string]string{"resolution": "3600000"},
+		},
+		{
+			name:  "weekly with time range",
+			query: map[string]string{"resolution": "604800000", "start_time": "0", "end_time": "604800000"},
+		},
+		{
+			name:         "missing resolution",
+			query:        map[string]string{},
+			invalidField: "resolution",
+		},
+		{
+			name:         "minute",
+			query:        map[string]string{"resolution": "60000"},
+			invalidField: "resolution",
+		},
+		{
+			name:         "end before start",
+			query:        map[string]string{"resolution": "3600000", "start_time": "1000", "end_time": "500"},
+			invalidField: "end_time",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := makeRequest(t, testCase.query, map[string]string{"liquidity_pool_id": liquidityPoolHistoryID}, nil)
+			err := getParams(&LiquidityPoolHistoryQuery{}, r)
+			if testCase.invalidField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.IsType(t, &problem.P{}, err) {
+				assert.Equal(t, testCase.invalidField, err.(*problem.P).Extras["invalid_field"])
+			}
+		})
+	}
+}
+
+func TestLiquidityPoolAPRQueryWindows(t *testing.T) {
+	for _, testCase := range []struct {
+		windows  string
+		expected []uint32
+	}{
+		{windows: "", expected: []uint32{1, 7, 30}},
+		{windows: "90", expected: []uint32{90}},
+		{windows: "1, 365", expected: []uint32{1, 365}},
+		{windows: "0"},
+		{windows: "366"},
+		{windows: "7,month"},
+		{windows: "1,2,3,4,5,6,7,8,9,10,11"},
+	} {
+		t.Run(testCase.windows, func(t *testing.T) {
+			r := makeRequest(
+				t,
+				map[string]string{"windows": testCase.windows},
+				map[string]string{"liquidity_pool_id": liquidityPoolHistoryID},
+				nil,
+			)
+			qp := LiquidityPoolAPRQuery{}
+			err := getParams(&qp, r)
+			if testCase.expected == nil {
+				if assert.IsType(t, &problem.P{}, err) {
+					assert.Equal(t, "windows", err.(*problem.P).Extras["invalid_field"])
+				}
+				return
+			}
+			assert.NoError(t, err)
+			windows, err := qp.windows()
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected, windows)
+		})
+	}
+}
@@ -15,7 +15,8 @@ import (
 
 // AccountOffersQuery query struct for offers end-point
 type OfferByIDQuery struct {
-	OfferID uint64 `schema:"offer_id" valid:"-"`
+	OfferID               uint64 `schema:"offer_id" valid:"-"`
+	AsOfLedgerQueryParams `valid:"-"`
 }
 
 // GetOfferByID is the action handler for the /offers/{id} endpoint
@@ -35,14 +36,19 @@ func (handler GetOfferByID) GetResource(w HeaderWriter, r *http.Request) (interf
 		return nil, err
 	}
 
-	record, err := historyQ.GetOfferByID(r.Context(), int64(qp.OfferID))
+	ctx, err = qp.Context(ctx, historyQ)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := historyQ.GetOfferByID(ctx, int64(qp.OfferID))
 	if err != nil {
 		return nil, err
 	}
 
 	ledger := &history.Ledger{}
 	err = historyQ.LedgerBySequence(
-		r.Context(),
+		ctx,
 		ledger,
 		int32(record.LastModifiedLedger),
 	)
@@ -62,12 +68,13 @@ type OffersQuery struct {
 	SellingBuyingAssetQueryParams `valid:"-"`
 	Seller                        string `schema:"seller" valid:"accountID,optional"`
 	Sponsor                       string `schema:"sponsor" valid:"accountID,optional"`
+	AsOfLedgerQueryParams         `valid:"-"`
 }
 
 // URITemplate returns a rfc6570 URI template the query struct
 func (q OffersQuery) URITemplate() string {
 	// building this manually since we don't want to include all the params in SellingBuyingAssetQueryParams
-	return "/offers{?selling,buying,seller,sponsor,cursor,limit,order}"
+	return "/offers{?selling,buying,seller,sponsor,as_of_ledger,cursor,limit,order}"
 }
 
 // Validate runs custom validations.
@@ -119,6 +126,11 @@ func (handler GetOffersHandler) GetResourcePage(
 		return nil, err
 	}
 
+	ctx, err = qp.Context(ctx, historyQ)
+	if err != nil {
+		return nil, err
+	}
+
 	offers, err := getOffersPage(ctx, historyQ, query)
 	if err != nil {
 		return nil, err
@@ -129,7 +141,8 @@ func (handler GetOffersHandler) GetResourcePage(
 
 // AccountOffersQuery query struct for offers end-point
 type AccountOffersQuery struct {
-	AccountID string `schema:"account_id" valid:"accountID,required"`
+	AccountID             string `schema:"account_id" valid:"accountID,required"`
+	AsOfLedgerQueryParams `valid:"-"`
 }
 
 // GetAccountOffersHandler is the action handler for the
@@ -138,15 +151,15 @@ type GetAccountOffersHandler struct {
 	LedgerState *ledger.State
 }
 
-func (handler GetAccountOffersHandler) parseOffersQuery(r *http.Request) (history.OffersQuery, error) {
+func (handler GetAccountOffersHandler) parseOffersQuery(r *http.Request) (history.OffersQuery, AccountOffersQuery, error) {
 	pq, err := GetPageQuery(handler.LedgerState, r)
 	if err != nil {
-		return history.OffersQuery{}, err
+		return history.OffersQuery{}, AccountOffersQuery{}, err
 	}
 
 	qp := AccountOffersQuery{}
 	if err = getParams(&qp, r); err != nil {
-		return history.OffersQuery{}, err
+		return history.OffersQuery{}, AccountOffersQuery{}, err
 	}
 
 	query := history.OffersQuery{
@@ -154,7 +167,7 @@ func (handler GetAccountOffersHandler) parseOffersQuery(r *http.Request) (histor
 		SellerID:  qp.AccountID,
 	}
 
-	return query, nil
+	return query, qp, nil
 }
 
 // GetResourcePage returns a page of offers for a given account.
@@ -163,7 +176,7 @@ func (handler GetAccountOffersHandler) GetResourcePage(
 	r *http.Request,
 ) ([]hal.Pageable, error) {
 	ctx := r.Context()
-	query, err := handler.parseOffersQuery(r)
+	query, qp, err := handler.parseOffersQuery(r)
 	if err != nil {
 		return nil, err
 	}
@@ -173,6 +186,11 @@ func (handler GetAccountOffersHandler) GetResourcePage(
 		return nil, err
 	}
 
+	ctx, err = qp.Context(ctx, historyQ)
+	if err != nil {
+		return nil, err
+	}
+
 	offers, err := getOffersPage(ctx, historyQ, query)
 	if err != nil {
 		return nil, err
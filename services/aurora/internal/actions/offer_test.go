@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -510,7 +511,60 @@ func pageableToOffers(t *testing.T, page []hal.Pageable) []aurora.Offer {
 
 func TestOffersQueryURLTemplate(t *testing.T) {
 	tt := assert.New(t)
-	expected := "/offers{?selling,buying,seller,sponsor,cursor,limit,order}"
+	expected := "/offers{?selling,buying,seller,sponsor,as_of_ledger,cursor,limit,order}"
 	offersQuery := OffersQuery{}
 	tt.Equal(expected, offersQuery.URITemplate())
 }
+
+func TestGetOfferByIDAsOfLedger(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+
+	q := &history.Q{tt.AuroraSession()}
+	handler := GetOfferByID{}
+
+	_, err := q.InsertLedger(tt.Ctx, xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{LedgerSeq: 3},
+	}, 0, 0, 0, 0, 0)
+	tt.Assert.NoError(err)
+
+	err = q.UpsertOffers(tt.Ctx, []history.Offer{eurOffer})
+	tt.Assert.NoError(err)
+
+	request := func(asOfLedger string) *http.Request {
+		return makeRequest(
+			t,
+			map[string]string{"as_of_ledger": asOfLedger},
+			map[string]string{"offer_id": strconv.FormatInt(eurOffer.OfferID, 10)},
+			q,
+		)
+	}
+
+	// Versioned state was not ingested
+	_, err = handler.GetResource(httptest.NewRecorder(), request("3"))
+	tt.Assert.Error(err)
+	p := err.(*problem.P)
+	tt.Assert.Equal("as_of_ledger", p.Extras["invalid_field"])
+
+	tt.Assert.NoError(q.UpdateStateVersionsLedgerRange(tt.Ctx, 3, 5))
+	tt.Assert.NoError(q.ArchiveOffers(tt.Ctx, []int64{eurOffer.OfferID}, 5))
+	updated := eurOffer
+	updated.Amount = 1
+	updated.LastModifiedLedger = 5
+	tt.Assert.NoError(q.UpsertOffers(tt.Ctx, []history.Offer{updated}))
+
+	response, err := handler.GetResource(httptest.NewRecorder(), request("4"))
+	tt.Assert.NoError(err)
+	tt.Assert.Equal("0.0000500", response.(aurora.Offer).Amount)
+
+	response, err = handler.GetResource(httptest.NewRecorder(), request("5"))
+	tt.Assert.NoError(err)
+	tt.Assert.Equal("0.0000001", response.(aurora.Offer).Amount)
+
+	_, err = handler.GetResource(httptest.NewRecorder(), request("6"))
+	tt.Assert.Error(err)
+	p = err.(*problem.P)
+	tt.Assert.Equal("as_of_ledger", p.Extras["invalid_field"])
+	tt.Assert.Equal("the state is only available as of ledgers 3 to 5", p.Extras["reason"])
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/diamnet/go/services/aurora/internal/coldstorage"
 	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/services/aurora/internal/ledger"
@@ -29,7 +30,7 @@ func (qp Joinable) IncludeTransactions() bool {
 // OperationsQuery query struct for operations end-points
 type OperationsQuery struct {
 	Joinable                  `valid:"optional"`
-	AccountID                 string `schema:"account_id" valid:"accountID,optional"`
+	AccountID                 string `schema:"account_id" valid:"accountOrMuxedAccountID,optional"`
 	ClaimableBalanceID        string `schema:"claimable_balance_id" valid:"claimableBalanceID,optional"`
 	LiquidityPoolID           string `schema:"liquidity_pool_id" valid:"sha256,optional"`
 	TransactionHash           string `schema:"tx_id" valid:"transactionHash,optional"`
@@ -65,6 +66,9 @@ func (qp OperationsQuery) Validate() error {
 type GetOperationsHandler struct {
 	LedgerState  *ledger.State
 	OnlyPayments bool
+	// ColdStorage serves the operations of the ledgers removed by the reaper,
+	// if set.
+	ColdStorage *coldstorage.Store
 }
 
 // GetResourcePage returns a page of operations.
@@ -76,13 +80,22 @@ func (handler GetOperationsHandler) GetResourcePage(w HeaderWriter, r *http.Requ
 		return nil, err
 	}
 
-	err = validateCursorWithinHistory(handler.LedgerState, pq)
+	qp := OperationsQuery{}
+	err = getParams(&qp, r)
 	if err != nil {
 		return nil, err
 	}
 
-	qp := OperationsQuery{}
-	err = getParams(&qp, r)
+	if handler.ColdStorage != nil && qp.LedgerID > 0 &&
+		int32(qp.LedgerID) < handler.LedgerState.CurrentStatus().HistoryElder {
+		records, err := loadColdRecords(ctx, handler.ColdStorage, qp.LedgerID)
+		if err != nil {
+			return nil, err
+		}
+		return coldOperationsPage(ctx, records, qp, pq, handler.OnlyPayments)
+	}
+
+	err = validateCursorWithinHistory(handler.LedgerState, pq)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +108,8 @@ func (handler GetOperationsHandler) GetResourcePage(w HeaderWriter, r *http.Requ
 	query := historyQ.Operations()
 
 	switch {
+	case isMuxedAccountID(qp.AccountID):
+		query.ForMuxedAccount(ctx, qp.AccountID)
 	case qp.AccountID != "":
 		query.ForAccount(ctx, qp.AccountID)
 	case qp.ClaimableBalanceID != "":
@@ -381,3 +381,159 @@ func assetsForAddress(r *http.Request, addy string) ([]xdr.Asset, []xdr.Int64, e
 
 	return assets, balances, nil
 }
+
+const (
+	// defaultSplitPaths is the default maximum number of paths a payment is
+	// split across in `/paths/strict-send/split`
+	defaultSplitPaths = 3
+	// maxSplitPaths is the largest value of the `max_paths` parameter
+	maxSplitPaths = 5
+)
+
+// FindSplitPathsHandler is the http handler for the split strict send payment
+// paths endpoint. The source amount is split across several payment paths
+// which maximize the total amount delivered.
+type FindSplitPathsHandler struct {
+	MaxPathLength       uint
+	SetLastLedgerHeader bool
+	PathFinder          paths.Finder
+}
+
+// FindSplitPathsQuery query struct for paths/strict-send/split end-point
+type FindSplitPathsQuery struct {
+	SourceAssetType        string `schema:"source_asset_type" valid:"assetType"`
+	SourceAssetIssuer      string `schema:"source_asset_issuer" valid:"accountID,optional"`
+	SourceAssetCode        string `schema:"source_asset_code" valid:"-"`
+	SourceAmount           string `schema:"source_amount" valid:"amount"`
+	DestinationAssetType   string `schema:"destination_asset_type" valid:"assetType"`
+	DestinationAssetIssuer string `schema:"destination_asset_issuer" valid:"accountID,optional"`
+	DestinationAssetCode   string `schema:"destination_asset_code" valid:"-"`
+	MaxPaths               uint   `schema:"max_paths" valid:"-"`
+}
+
+// URITemplate returns a rfc6570 URI template for the query struct
+func (q FindSplitPathsQuery) URITemplate() string {
+	return "/paths/strict-send/split{?" + strings.Join(getURIParams(&q, false), ",") + "}"
+}
+
+// Validate runs custom validations.
+func (q FindSplitPathsQuery) Validate() error {
+	err := validateAssetParams(
+		q.SourceAssetType,
+		q.SourceAssetCode,
+		q.SourceAssetIssuer,
+		"source_",
+	)
+	if err != nil {
+		return err
+	}
+
+	err = validateAssetParams(
+		q.DestinationAssetType,
+		q.DestinationAssetCode,
+		q.DestinationAssetIssuer,
+		"destination_",
+	)
+	if err != nil {
+		return err
+	}
+
+	if q.MaxPaths > maxSplitPaths {
+		return problem.MakeInvalidFieldProblem(
+			"max_paths",
+			fmt.Errorf("max_paths cannot exceed %d", maxSplitPaths),
+		)
+	}
+	return nil
+}
+
+// Amount returns source amount
+func (q FindSplitPathsQuery) Amount() xdr.Int64 {
+	parsed, err := amount.Parse(q.SourceAmount)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// SourceAsset returns an xdr.Asset
+func (q FindSplitPathsQuery) SourceAsset() xdr.Asset {
+	asset, err := xdr.BuildAsset(
+		q.SourceAssetType,
+		q.SourceAssetIssuer,
+		q.SourceAssetCode,
+	)
+	if err != nil {
+		panic(err)
+	}
+	return asset
+}
+
+// DestinationAsset returns an xdr.Asset
+func (q FindSplitPathsQuery) DestinationAsset() xdr.Asset {
+	asset, err := xdr.BuildAsset(
+		q.DestinationAssetType,
+		q.DestinationAssetIssuer,
+		q.DestinationAssetCode,
+	)
+	if err != nil {
+		panic(err)
+	}
+	return asset
+}
+
+// GetResource returns a strict send payment split across several paths
+func (handler FindSplitPathsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := FindSplitPathsQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	maxPaths := int(qp.MaxPaths)
+	if maxPaths == 0 {
+		maxPaths = defaultSplitPaths
+	}
+
+	records, lastIngestedLedger, err := handler.PathFinder.FindSplitPaths(
+		ctx,
+		qp.SourceAsset(),
+		qp.Amount(),
+		qp.DestinationAsset(),
+		maxPaths,
+		handler.MaxPathLength,
+	)
+	if err == simplepath.ErrEmptyInMemoryOrderBook {
+		err = auroraProblem.StillIngesting
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if handler.SetLastLedgerHeader {
+		// To make the Last-Ledger header consistent with the response content,
+		// we need to extract it from the ledger and not the DB.
+		// Thus, we overwrite the header if it was previously set.
+		SetLastLedgerHeader(w, lastIngestedLedger)
+	}
+
+	payment := aurora.SplitPathPayment{
+		SourceAssetType:        qp.SourceAssetType,
+		SourceAssetCode:        qp.SourceAssetCode,
+		SourceAssetIssuer:      qp.SourceAssetIssuer,
+		SourceAmount:           amount.String(qp.Amount()),
+		DestinationAssetType:   qp.DestinationAssetType,
+		DestinationAssetCode:   qp.DestinationAssetCode,
+		DestinationAssetIssuer: qp.DestinationAssetIssuer,
+		Paths:                  make([]aurora.Path, len(records)),
+	}
+	destinationAmount := xdr.Int64(0)
+	for i, record := range records {
+		if err := resourceadapter.PopulatePath(ctx, &payment.Paths[i], record); err != nil {
+			return nil, err
+		}
+		destinationAmount += record.DestinationAmount
+	}
+	payment.DestinationAmount = amount.String(destinationAmount)
+	return payment, nil
+}
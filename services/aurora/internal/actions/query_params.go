@@ -1,9 +1,11 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/support/errors"
 	"github.com/diamnet/go/support/render/problem"
 	"github.com/diamnet/go/xdr"
@@ -142,3 +144,46 @@ func (q SellingBuyingAssetQueryParams) Buying() (*xdr.Asset, error) {
 
 	return &buying, nil
 }
+
+// AsOfLedgerQueryParams query struct for state end-points which can return
+// the state as of an earlier ledger
+type AsOfLedgerQueryParams struct {
+	AsOfLedger uint32 `schema:"as_of_ledger" valid:"-"`
+}
+
+// Context returns a context in which the state queries return the state as of
+// the requested ledger, if any. The ledger must have been ingested with
+// versioned state and must not have been reaped.
+func (q AsOfLedgerQueryParams) Context(ctx context.Context, historyQ *history.Q) (context.Context, error) {
+	if q.AsOfLedger == 0 {
+		return ctx, nil
+	}
+
+	start, last, err := historyQ.GetStateVersionsLedgerRange(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get state versions ledger range")
+	}
+	if start == 0 {
+		return nil, problem.MakeInvalidFieldProblem(
+			"as_of_ledger",
+			errors.New("the state as of earlier ledgers is not available on this server"),
+		)
+	}
+
+	var elder int32
+	if err = historyQ.ElderLedger(ctx, &elder); err != nil {
+		return nil, errors.Wrap(err, "could not get elder ledger")
+	}
+	if uint32(elder) > start {
+		start = uint32(elder)
+	}
+
+	if q.AsOfLedger < start || q.AsOfLedger > last {
+		return nil, problem.MakeInvalidFieldProblem(
+			"as_of_ledger",
+			errors.Errorf("the state is only available as of ledgers %d to %d", start, last),
+		)
+	}
+
+	return history.WithAsOfLedger(ctx, q.AsOfLedger), nil
+}
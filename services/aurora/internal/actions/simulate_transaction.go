@@ -0,0 +1,81 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/diamnet/go/protocols/aurora"
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	hProblem "github.com/diamnet/go/services/aurora/internal/render/problem"
+	"github.com/diamnet/go/services/aurora/internal/txsim"
+)
+
+// SimulateTransactionHandler is the action handler for the transaction
+// simulation endpoint. The transaction is not submitted to Diamnet Core.
+type SimulateTransactionHandler struct {
+	NetworkPassphrase string
+	OrderBook         txsim.OrderBook
+}
+
+// GetResource returns the predicted outcome of the transaction in the request
+// body.
+func (handler SimulateTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	if err := validateBodyType(r); err != nil {
+		return nil, err
+	}
+
+	raw, err := getString(r, "tx")
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := extractEnvelopeInfo(raw, handler.NetworkPassphrase)
+	if err != nil {
+		return nil, malformedEnvelopeProblem(raw)
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	simulator := txsim.Simulator{
+		NetworkPassphrase: handler.NetworkPassphrase,
+		OrderBook:         handler.OrderBook,
+	}
+	result, err := simulator.Simulate(r.Context(), historyQ, info.parsed)
+	if err == txsim.ErrNoIngestedLedger {
+		return nil, hProblem.StillIngesting
+	} else if err != nil {
+		return nil, err
+	}
+
+	resource := aurora.TransactionSimulation{
+		Hash:       result.Hash,
+		Ledger:     result.Ledger,
+		Successful: result.Successful(),
+		FeeCharged: result.FeeCharged,
+		ResultCodes: aurora.TransactionResultCodes{
+			TransactionCode:      result.TransactionCode,
+			InnerTransactionCode: result.InnerTransactionCode,
+		},
+		Operations: make([]aurora.SimulatedOperation, len(result.Operations)),
+	}
+	for i, op := range result.Operations {
+		resource.ResultCodes.OperationCodes = append(resource.ResultCodes.OperationCodes, op.Code)
+		resource.Operations[i] = aurora.SimulatedOperation{
+			Type:       op.Type,
+			Simulated:  op.Simulated,
+			ResultCode: op.Code,
+			Effects:    []aurora.SimulatedEffect{},
+		}
+		for _, effect := range op.Effects {
+			resource.Operations[i].Effects = append(resource.Operations[i].Effects, aurora.SimulatedEffect{
+				Type:    effect.Type,
+				Account: effect.Account,
+				Details: effect.Details,
+			})
+		}
+	}
+
+	return resource, nil
+}
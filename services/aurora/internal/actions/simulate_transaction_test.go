@@ -0,0 +1,112 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/diamnet/go/keypair"
+	"github.com/diamnet/go/network"
+	"github.com/diamnet/go/protocols/aurora"
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	hProblem "github.com/diamnet/go/services/aurora/internal/render/problem"
+	"github.com/diamnet/go/services/aurora/internal/test"
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/txnbuild"
+)
+
+func TestSimulateTransactionMalformedTx(t *testing.T) {
+	handler := SimulateTransactionHandler{}
+
+	form := url.Values{}
+	form.Set("tx", "not a transaction")
+	r := httptest.NewRequest("POST", "/transactions/simulate", strings.NewReader(form.Encode()))
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	_, err := handler.GetResource(httptest.NewRecorder(), r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*problem.P).Status)
+	assert.Equal(t, "transaction_malformed", err.(*problem.P).Type)
+}
+
+func TestSimulateTransaction(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	tt.Scenario("base")
+
+	q := &history.Q{tt.AuroraSession()}
+	source := keypair.MustRandom()
+	destination := keypair.MustRandom()
+	for _, kp := range []*keypair.Full{source, destination} {
+		tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []history.AccountEntry{{
+			AccountID:          kp.Address(),
+			Balance:            1000 * 10000000,
+			SequenceNumber:     8589934592,
+			MasterWeight:       1,
+			LastModifiedLedger: 3,
+		}}))
+		_, err := q.CreateAccountSigner(tt.Ctx, kp.Address(), kp.Address(), 1, nil)
+		tt.Assert.NoError(err)
+	}
+
+	simulate := func(amount string) (aurora.TransactionSimulation, error) {
+		tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+			SourceAccount:        &txnbuild.SimpleAccount{AccountID: source.Address(), Sequence: 8589934592},
+			IncrementSequenceNum: true,
+			Operations: []txnbuild.Operation{&txnbuild.Payment{
+				Destination: destination.Address(),
+				Amount:      amount,
+				Asset:       txnbuild.NativeAsset{},
+			}},
+			BaseFee:    txnbuild.MinBaseFee,
+			Timebounds: txnbuild.NewInfiniteTimeout(),
+		})
+		tt.Assert.NoError(err)
+		tx, err = tx.Sign(network.TestNetworkPassphrase, source)
+		tt.Assert.NoError(err)
+		raw, err := tx.Base64()
+		tt.Assert.NoError(err)
+
+		form := url.Values{}
+		form.Set("tx", raw)
+		r := httptest.NewRequest("POST", "/transactions/simulate", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), &auroraContext.SessionContextKey, q.SessionInterface))
+
+		handler := SimulateTransactionHandler{NetworkPassphrase: network.TestNetworkPassphrase}
+		resource, err := handler.GetResource(httptest.NewRecorder(), r)
+		if err != nil {
+			return aurora.TransactionSimulation{}, err
+		}
+		return resource.(aurora.TransactionSimulation), nil
+	}
+
+	_, err := simulate("10")
+	tt.Assert.Equal(hProblem.StillIngesting, err)
+
+	tt.Assert.NoError(q.UpdateLastLedgerIngest(tt.Ctx, 3))
+	simulation, err := simulate("10")
+	tt.Assert.NoError(err)
+	tt.Assert.True(simulation.Successful)
+	tt.Assert.Equal(int32(3), simulation.Ledger)
+	tt.Assert.Equal("tx_success", simulation.ResultCodes.TransactionCode)
+	tt.Assert.Equal([]string{"op_success"}, simulation.ResultCodes.OperationCodes)
+	if tt.Assert.Len(simulation.Operations, 1) {
+		tt.Assert.True(simulation.Operations[0].Simulated)
+		tt.Assert.Len(simulation.Operations[0].Effects, 2)
+	}
+
+	simulation, err = simulate("5000")
+	tt.Assert.NoError(err)
+	tt.Assert.False(simulation.Successful)
+	tt.Assert.Equal("tx_failed", simulation.ResultCodes.TransactionCode)
+	tt.Assert.Equal([]string{"op_underfunded"}, simulation.ResultCodes.OperationCodes)
+	tt.Assert.Empty(simulation.Operations[0].Effects)
+}
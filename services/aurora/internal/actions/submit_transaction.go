@@ -44,7 +44,23 @@ func extractEnvelopeInfo(raw string, passphrase string) (envelopeInfo, error) {
 	return result, nil
 }
 
-func (handler SubmitTransactionHandler) validateBodyType(r *http.Request) error {
+func malformedEnvelopeProblem(raw string) *problem.P {
+	return &problem.P{
+		Type:   "transaction_malformed",
+		Title:  "Transaction Malformed",
+		Status: http.StatusBadRequest,
+		Detail: "Aurora could not decode the transaction envelope in this " +
+			"request. A transaction should be an XDR TransactionEnvelope struct " +
+			"encoded using base64.  The envelope read from this request is " +
+			"echoed in the `extras.envelope_xdr` field of this response for your " +
+			"convenience.",
+		Extras: map[string]interface{}{
+			"envelope_xdr": raw,
+		},
+	}
+}
+
+func validateBodyType(r *http.Request) error {
 	c := r.Header.Get("Content-Type")
 	if c == "" {
 		return nil
@@ -111,7 +127,7 @@ func (handler SubmitTransactionHandler) response(r *http.Request, info envelopeI
 }
 
 func (handler SubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
-	if err := handler.validateBodyType(r); err != nil {
+	if err := validateBodyType(r); err != nil {
 		return nil, err
 	}
 
@@ -122,19 +138,7 @@ func (handler SubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Requ
 
 	info, err := extractEnvelopeInfo(raw, handler.NetworkPassphrase)
 	if err != nil {
-		return nil, &problem.P{
-			Type:   "transaction_malformed",
-			Title:  "Transaction Malformed",
-			Status: http.StatusBadRequest,
-			Detail: "Aurora could not decode the transaction envelope in this " +
-				"request. A transaction should be an XDR TransactionEnvelope struct " +
-				"encoded using base64.  The envelope read from this request is " +
-				"echoed in the `extras.envelope_xdr` field of this response for your " +
-				"convenience.",
-			Extras: map[string]interface{}{
-				"envelope_xdr": raw,
-			},
-		}
+		return nil, malformedEnvelopeProblem(raw)
 	}
 
 	coreState := handler.GetCoreState()
@@ -0,0 +1,128 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/diamnet/go/protocols/aurora"
+	proto "github.com/diamnet/go/protocols/diamnetcore"
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	hProblem "github.com/diamnet/go/services/aurora/internal/render/problem"
+	"github.com/diamnet/go/services/aurora/internal/txsub"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/render/problem"
+)
+
+// AsyncSubmitTransactionHandler is the action handler submitting transactions
+// to diamnet-core without waiting for them to be included in a ledger.
+type AsyncSubmitTransactionHandler struct {
+	Submitter         *txsub.System
+	NetworkPassphrase string
+	CoreStateGetter
+}
+
+// GetResource submits a transaction and returns the status diamnet-core
+// responded with.
+func (handler AsyncSubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	if err := validateBodyType(r); err != nil {
+		return nil, err
+	}
+
+	raw, err := getString(r, "tx")
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := extractEnvelopeInfo(raw, handler.NetworkPassphrase)
+	if err != nil {
+		return nil, malformedEnvelopeProblem(raw)
+	}
+
+	coreState := handler.GetCoreState()
+	if !coreState.Synced {
+		return nil, hProblem.StaleHistory
+	}
+
+	sr := handler.Submitter.SubmitAsync(r.Context(), info.raw, info.parsed, info.hash)
+	resource := aurora.AsyncTransactionSubmissionResponse{
+		Hash:     info.hash,
+		TxStatus: sr.Status,
+	}
+	if sr.Status == proto.TXStatusError {
+		if fte, ok := sr.Err.(*txsub.FailedTransactionError); ok {
+			resource.ErrorResultXDR = fte.ResultXDR
+			return resource, nil
+		}
+	}
+	if sr.Err != nil {
+		return nil, sr.Err
+	}
+	return resource, nil
+}
+
+// GetTransactionStatusHandler is the action handler for the end-point
+// returning the status of a submitted transaction.
+type GetTransactionStatusHandler struct {
+	Submitter *txsub.System
+}
+
+// GetResource returns the status of a transaction. Transactions are looked up
+// in the history database first and then in the submissions of this instance,
+// which are the only ones that can be pending or expired.
+func (handler GetTransactionStatusHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := TransactionQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var record history.Transaction
+	err = historyQ.TransactionByHash(ctx, &record, qp.TransactionHash)
+	if err == nil {
+		return transactionStatusFromHistory(qp.TransactionHash, record), nil
+	}
+	if !historyQ.NoRows(err) {
+		return nil, errors.Wrap(err, "loading transaction record")
+	}
+
+	result, pending, found := handler.Submitter.SubmissionStatus(ctx, qp.TransactionHash)
+	if !found {
+		return nil, problem.NotFound
+	}
+	if pending {
+		return aurora.TransactionStatus{
+			Hash:   qp.TransactionHash,
+			Status: aurora.TransactionStatusPending,
+		}, nil
+	}
+	if result.Err == txsub.ErrTimeout {
+		return aurora.TransactionStatus{
+			Hash:   qp.TransactionHash,
+			Status: aurora.TransactionStatusExpired,
+		}, nil
+	}
+	if _, failed := result.Err.(*txsub.FailedTransactionError); result.Err != nil && !failed {
+		return nil, result.Err
+	}
+	// the transaction was found in a ledger after the history database was
+	// queried
+	return transactionStatusFromHistory(qp.TransactionHash, result.Transaction), nil
+}
+
+func transactionStatusFromHistory(hash string, record history.Transaction) aurora.TransactionStatus {
+	status := aurora.TransactionStatus{
+		Hash:   hash,
+		Status: aurora.TransactionStatusIncluded,
+		Ledger: record.LedgerSequence,
+	}
+	if !record.Successful {
+		status.Status = aurora.TransactionStatusFailed
+		status.ResultXDR = record.TxResult
+	}
+	return status
+}
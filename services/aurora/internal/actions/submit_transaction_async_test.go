@@ -0,0 +1,83 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/network"
+	"github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/corestate"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/support/render/problem"
+)
+
+func TestAsyncSubmitTransactionMalformedTx(t *testing.T) {
+	handler := AsyncSubmitTransactionHandler{}
+
+	r := httptest.NewRequest("POST", "https://aurora.diamnet.org/transactions_async", nil)
+	w := httptest.NewRecorder()
+	_, err := handler.GetResource(w, r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*problem.P).Status)
+	assert.Equal(t, "Transaction Malformed", err.(*problem.P).Title)
+}
+
+func TestAsyncSubmitTransactionCoreNotSynced(t *testing.T) {
+	mock := &coreStateGetterMock{}
+	mock.On("GetCoreState").Return(corestate.State{
+		Synced: false,
+	})
+
+	handler := AsyncSubmitTransactionHandler{
+		NetworkPassphrase: network.PublicNetworkPassphrase,
+		CoreStateGetter:   mock,
+	}
+
+	form := url.Values{}
+	form.Set("tx", "AAAAAAGUcmKO5465JxTSLQOQljwk2SfqAJmZSG6JH6wtqpwhAAABLAAAAAAAAAABAAAAAAAAAAEAAAALaGVsbG8gd29ybGQAAAAAAwAAAAAAAAAAAAAAABbxCy3mLg3hiTqX4VUEEp60pFOrJNxYM1JtxXTwXhY2AAAAAAvrwgAAAAAAAAAAAQAAAAAW8Qst5i4N4Yk6l+FVBBKetKRTqyTcWDNSbcV08F4WNgAAAAAN4Lazj4x61AAAAAAAAAAFAAAAAAAAAAAAAAAAAAAAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABLaqcIQAAAEBKwqWy3TaOxoGnfm9eUjfTRBvPf34dvDA0Nf+B8z4zBob90UXtuCqmQqwMCyH+okOI3c05br3khkH0yP4kCwcE")
+
+	request, err := http.NewRequest(
+		"POST",
+		"https://aurora.diamnet.org/transactions_async",
+		strings.NewReader(form.Encode()),
+	)
+	require.NoError(t, err)
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	_, err = handler.GetResource(w, request)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, err.(problem.P).Status)
+	assert.Equal(t, "stale_history", err.(problem.P).Type)
+}
+
+func TestTransactionStatusFromHistory(t *testing.T) {
+	hash := "2374e99349b9ef7dba9a5db3339b78fda8f34777b1af33ba468ad5c0df946d4d"
+	record := history.Transaction{
+		TransactionWithoutLedger: history.TransactionWithoutLedger{
+			LedgerSequence: 12,
+			TxResult:       "AAAAAAAAAAD////7AAAAAA==",
+			Successful:     true,
+		},
+	}
+
+	assert.Equal(t, aurora.TransactionStatus{
+		Hash:   hash,
+		Status: aurora.TransactionStatusIncluded,
+		Ledger: 12,
+	}, transactionStatusFromHistory(hash, record))
+
+	record.Successful = false
+	assert.Equal(t, aurora.TransactionStatus{
+		Hash:      hash,
+		Status:    aurora.TransactionStatusFailed,
+		Ledger:    12,
+		ResultXDR: "AAAAAAAAAAD////7AAAAAA==",
+	}, transactionStatusFromHistory(hash, record))
+}
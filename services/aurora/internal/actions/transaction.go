@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/coldstorage"
 	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
 	"github.com/diamnet/go/services/aurora/internal/db2"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
@@ -23,6 +24,8 @@ type TransactionQuery struct {
 
 // GetTransactionByHashHandler is the action handler for the end-point returning a transaction.
 type GetTransactionByHashHandler struct {
+	// ColdStorage serves the transactions removed by the reaper, if set.
+	ColdStorage *coldstorage.Store
 }
 
 // GetResource returns a transaction page.
@@ -45,6 +48,9 @@ func (handler GetTransactionByHashHandler) GetResource(w HeaderWriter, r *http.R
 	)
 
 	err = historyQ.TransactionByHash(ctx, &record, qp.TransactionHash)
+	if historyQ.NoRows(err) && handler.ColdStorage != nil {
+		record, err = coldTransactionByHash(ctx, historyQ, handler.ColdStorage, qp.TransactionHash)
+	}
 	if err != nil {
 		return resource, errors.Wrap(err, "loading transaction record")
 	}
@@ -57,7 +63,7 @@ func (handler GetTransactionByHashHandler) GetResource(w HeaderWriter, r *http.R
 
 // TransactionsQuery query struct for transactions end-points
 type TransactionsQuery struct {
-	AccountID                 string `schema:"account_id" valid:"accountID,optional"`
+	AccountID                 string `schema:"account_id" valid:"accountOrMuxedAccountID,optional"`
 	ClaimableBalanceID        string `schema:"claimable_balance_id" valid:"claimableBalanceID,optional"`
 	LiquidityPoolID           string `schema:"liquidity_pool_id" valid:"sha256,optional"`
 	IncludeFailedTransactions bool   `schema:"include_failed" valid:"-"`
@@ -90,6 +96,9 @@ func (qp TransactionsQuery) Validate() error {
 // GetTransactionsHandler is the action handler for all end-points returning a list of transactions.
 type GetTransactionsHandler struct {
 	LedgerState *ledger.State
+	// ColdStorage serves the transactions of the ledgers removed by the
+	// reaper, if set.
+	ColdStorage *coldstorage.Store
 }
 
 // GetResourcePage returns a page of transactions.
@@ -101,13 +110,22 @@ func (handler GetTransactionsHandler) GetResourcePage(w HeaderWriter, r *http.Re
 		return nil, err
 	}
 
-	err = validateCursorWithinHistory(handler.LedgerState, pq)
+	qp := TransactionsQuery{}
+	err = getParams(&qp, r)
 	if err != nil {
 		return nil, err
 	}
 
-	qp := TransactionsQuery{}
-	err = getParams(&qp, r)
+	if handler.ColdStorage != nil && qp.LedgerID > 0 &&
+		int32(qp.LedgerID) < handler.LedgerState.CurrentStatus().HistoryElder {
+		records, err := loadColdRecords(ctx, handler.ColdStorage, qp.LedgerID)
+		if err != nil {
+			return nil, err
+		}
+		return coldTransactionsPage(ctx, records, qp, pq)
+	}
+
+	err = validateCursorWithinHistory(handler.LedgerState, pq)
 	if err != nil {
 		return nil, err
 	}
@@ -144,6 +162,8 @@ func loadTransactionRecords(ctx context.Context, hq *history.Q, qp TransactionsQ
 
 	txs := hq.Transactions()
 	switch {
+	case isMuxedAccountID(qp.AccountID):
+		txs.ForMuxedAccount(ctx, qp.AccountID)
 	case qp.AccountID != "":
 		txs.ForAccount(ctx, qp.AccountID)
 	case qp.ClaimableBalanceID != "":
@@ -10,6 +10,7 @@ import (
 
 	"github.com/diamnet/go/amount"
 	"github.com/diamnet/go/services/aurora/internal/assets"
+	"github.com/diamnet/go/strkey"
 	"github.com/diamnet/go/support/errors"
 	"github.com/diamnet/go/xdr"
 )
@@ -21,6 +22,7 @@ type Validateable interface {
 
 func init() {
 	govalidator.TagMap["accountID"] = isAccountID
+	govalidator.TagMap["accountOrMuxedAccountID"] = isAccountOrMuxedAccountID
 	govalidator.TagMap["amount"] = isAmount
 	govalidator.TagMap["assetType"] = isAssetType
 	govalidator.TagMap["asset"] = isAsset
@@ -42,6 +44,8 @@ var customTagsErrorMessages = map[string]string{
 	"op_id":                "Operation ID must be an integer higher than 0",
 	"transactionHash":      "Transaction hash must be a hex-encoded, lowercase SHA-256 hash",
 	"tradeType":            "Trade type must be all, orderbook, or liquidity_pool",
+	"accountOrMuxedAccountID": "Account ID must start with `G` and contain 56 alphanum characters " +
+		"or be a muxed account starting with `M` and containing 69 alphanum characters",
 }
 
 func isTradeType(tradeType string) bool {
@@ -165,3 +169,11 @@ func isClaimableBalanceID(str string) bool {
 	err := xdr.SafeUnmarshalHex(str, &cbID)
 	return err == nil
 }
+
+func isMuxedAccountID(str string) bool {
+	return strkey.IsValidMuxedAccountEd25519PublicKey(str)
+}
+
+func isAccountOrMuxedAccountID(str string) bool {
+	return isAccountID(str) || isMuxedAccountID(str)
+}
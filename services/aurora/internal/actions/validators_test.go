@@ -100,6 +100,51 @@ func TestAccountIDValidator(t *testing.T) {
 	}
 }
 
+func TestAccountOrMuxedAccountIDValidator(t *testing.T) {
+	type Query struct {
+		Account string `valid:"accountOrMuxedAccountID,optional"`
+	}
+
+	for _, testCase := range []struct {
+		name          string
+		value         string
+		expectedError string
+	}{
+		{
+			"invalid diamnet address",
+			"FON4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQPZW",
+			"Account: FON4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQPZW does not validate as accountOrMuxedAccountID",
+		},
+		{
+			"valid diamnet address",
+			"GAN4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQPZW",
+			"",
+		},
+		{
+			"valid muxed address",
+			"MAN4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQAAAAAAAAAAAAGK4O",
+			"",
+		},
+		{
+			"invalid muxed address",
+			"MAN4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQAAAAAAAAAAAAGK4P",
+			"Account: MAN4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQAAAAAAAAAAAAGK4P does not validate as accountOrMuxedAccountID",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			tt := assert.New(t)
+
+			result, err := govalidator.ValidateStruct(Query{Account: testCase.value})
+			if testCase.expectedError == "" {
+				tt.NoError(err)
+				tt.True(result)
+			} else {
+				tt.Equal(testCase.expectedError, err.Error())
+			}
+		})
+	}
+}
+
 func TestAssetValidator(t *testing.T) {
 	type Query struct {
 		Asset string `valid:"asset"`
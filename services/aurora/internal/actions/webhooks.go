@@ -0,0 +1,323 @@
+package actions
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+
+	"github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/protocols/aurora/operations"
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/services/aurora/internal/webhooks"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/render/hal"
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/xdr"
+)
+
+// CreateWebhookSubscriptionHandler is the admin action handler registering a
+// new webhook subscription.
+type CreateWebhookSubscriptionHandler struct{}
+
+// GetResource creates a webhook subscription. The subscription will receive
+// events for operations ingested after it was created.
+func (handler CreateWebhookSubscriptionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+
+	subscription, err := webhookSubscriptionFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := historyQ.GetLatestHistoryLedger(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load latest history ledger")
+	}
+	if latest > 0 {
+		subscription.Cursor = toid.AfterLedger(int32(latest)).ToInt64()
+	}
+
+	subscription.Secret, err = webhooks.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err = historyQ.InsertWebhookSubscription(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := newWebhookSubscriptionResource(subscription)
+	// The secret is only returned once, when the subscription is created.
+	resource.Secret = subscription.Secret
+	return resource, nil
+}
+
+// GetWebhookSubscriptionsHandler is the admin action handler listing all
+// webhook subscriptions.
+type GetWebhookSubscriptionsHandler struct{}
+
+// GetResource returns all webhook subscriptions.
+func (handler GetWebhookSubscriptionsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := historyQ.GetWebhookSubscriptions(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	var page hal.BasePage
+	page.Init()
+	for _, subscription := range subscriptions {
+		page.Add(newWebhookSubscriptionResource(subscription))
+	}
+	return page, nil
+}
+
+// GetWebhookSubscriptionByIDHandler is the admin action handler returning a
+// single webhook subscription.
+type GetWebhookSubscriptionByIDHandler struct{}
+
+// GetResource returns a webhook subscription.
+func (handler GetWebhookSubscriptionByIDHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	id, err := getWebhookSubscriptionID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := historyQ.GetWebhookSubscriptionByID(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWebhookSubscriptionResource(subscription), nil
+}
+
+// DeleteWebhookSubscriptionHandler is the admin action handler removing a
+// webhook subscription.
+type DeleteWebhookSubscriptionHandler struct{}
+
+// GetResource removes a webhook subscription and returns its last state.
+func (handler DeleteWebhookSubscriptionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	id, err := getWebhookSubscriptionID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := historyQ.GetWebhookSubscriptionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = historyQ.RemoveWebhookSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return newWebhookSubscriptionResource(subscription), nil
+}
+
+// GetWebhookDeadLettersHandler is the admin action handler returning the
+// events which could not be delivered to a webhook subscription.
+type GetWebhookDeadLettersHandler struct{}
+
+// GetResource returns the dead letters of a webhook subscription.
+func (handler GetWebhookDeadLettersHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	id, err := getWebhookSubscriptionID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return 404 for unknown subscriptions
+	if _, err = historyQ.GetWebhookSubscriptionByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	deadLetters, err := historyQ.GetWebhookDeadLetters(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var page hal.BasePage
+	page.Init()
+	for _, deadLetter := range deadLetters {
+		page.Add(aurora.WebhookDeadLetter{
+			ID:             strconv.FormatInt(deadLetter.ID, 10),
+			SubscriptionID: strconv.FormatInt(deadLetter.SubscriptionID, 10),
+			OperationID:    strconv.FormatInt(deadLetter.OperationID, 10),
+			Payload:        deadLetter.Payload,
+			Attempts:       deadLetter.Attempts,
+			LastError:      deadLetter.LastError,
+			CreatedAt:      deadLetter.CreatedAt,
+		})
+	}
+	return page, nil
+}
+
+func getWebhookSubscriptionID(r *http.Request) (int64, error) {
+	value, err := getString(r, "id")
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, problem.MakeInvalidFieldProblem(
+			"id",
+			errors.New("Webhook subscription ID must be an integer higher than 0"),
+		)
+	}
+
+	return id, nil
+}
+
+// webhookSubscriptionFromRequest reads the subscription filters from the
+// request form: `url` (required), `account_id`, `asset` (`native` or
+// `CODE:ISSUER`) and `operation_types` (comma separated operation type names).
+func webhookSubscriptionFromRequest(r *http.Request) (history.WebhookSubscription, error) {
+	var subscription history.WebhookSubscription
+
+	target, err := getString(r, "url")
+	if err != nil {
+		return subscription, err
+	}
+	if !isURL(target) {
+		return subscription, problem.MakeInvalidFieldProblem(
+			"url",
+			errors.New("URL must be an absolute http or https URL"),
+		)
+	}
+	subscription.URL = target
+
+	accountID, err := getString(r, "account_id")
+	if err != nil {
+		return subscription, err
+	}
+	if accountID != "" {
+		if !isAccountID(accountID) {
+			return subscription, problem.MakeInvalidFieldProblem(
+				"account_id",
+				errors.New(customTagsErrorMessages["accountID"]),
+			)
+		}
+		subscription.AccountID = null.StringFrom(accountID)
+	}
+
+	assetString, err := getString(r, "asset")
+	if err != nil {
+		return subscription, err
+	}
+	if assetString != "" {
+		if !isAsset(assetString) {
+			return subscription, problem.MakeInvalidFieldProblem(
+				"asset",
+				errors.New(customTagsErrorMessages["asset"]),
+			)
+		}
+		parsed, err := xdr.BuildAssets(assetString)
+		if err != nil {
+			return subscription, problem.MakeInvalidFieldProblem("asset", err)
+		}
+
+		var assetType, code, issuer string
+		if err := parsed[0].Extract(&assetType, &code, &issuer); err != nil {
+			return subscription, problem.MakeInvalidFieldProblem("asset", err)
+		}
+		subscription.AssetType = null.StringFrom(assetType)
+		if code != "" {
+			subscription.AssetCode = null.StringFrom(code)
+			subscription.AssetIssuer = null.StringFrom(issuer)
+		}
+	}
+
+	typesString, err := getString(r, "operation_types")
+	if err != nil {
+		return subscription, err
+	}
+	if typesString != "" {
+		subscription.OperationTypes = pq.Int64Array{}
+		for _, name := range strings.Split(typesString, ",") {
+			typ, ok := operationTypeByName(strings.TrimSpace(name))
+			if !ok {
+				return subscription, problem.MakeInvalidFieldProblem(
+					"operation_types",
+					errors.Errorf("unknown operation type: %s", name),
+				)
+			}
+			subscription.OperationTypes = append(subscription.OperationTypes, int64(typ))
+		}
+	}
+
+	return subscription, nil
+}
+
+func operationTypeByName(name string) (xdr.OperationType, bool) {
+	for typ, typeName := range operations.TypeNames {
+		if typeName == name {
+			return typ, true
+		}
+	}
+	return 0, false
+}
+
+func isURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func newWebhookSubscriptionResource(subscription history.WebhookSubscription) aurora.WebhookSubscription {
+	resource := aurora.WebhookSubscription{
+		ID:        strconv.FormatInt(subscription.ID, 10),
+		URL:       subscription.URL,
+		AccountID: subscription.AccountID.String,
+		Cursor:    strconv.FormatInt(subscription.Cursor, 10),
+		CreatedAt: subscription.CreatedAt,
+	}
+
+	if subscription.AssetType.Valid {
+		if subscription.AssetType.String == "native" {
+			resource.Asset = "native"
+		} else {
+			resource.Asset = subscription.AssetCode.String + ":" + subscription.AssetIssuer.String
+		}
+	}
+
+	for _, typ := range subscription.OperationTypes {
+		resource.OperationTypes = append(resource.OperationTypes, operations.TypeNames[xdr.OperationType(typ)])
+	}
+
+	return resource
+}
@@ -0,0 +1,151 @@
+package actions
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	protocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/test"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/render/hal"
+	"github.com/diamnet/go/support/render/problem"
+	"github.com/diamnet/go/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSubscriptionFromRequest(t *testing.T) {
+	tt := assert.New(t)
+
+	subscription, err := webhookSubscriptionFromRequest(makeRequest(
+		t,
+		map[string]string{
+			"url":             "https://example.com/hook",
+			"account_id":      "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB",
+			"asset":           "USD:GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB",
+			"operation_types": "payment, path_payment_strict_receive",
+		},
+		map[string]string{},
+		nil,
+	))
+	tt.NoError(err)
+	tt.Equal("https://example.com/hook", subscription.URL)
+	tt.Equal("GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB", subscription.AccountID.String)
+	tt.Equal("credit_alphanum4", subscription.AssetType.String)
+	tt.Equal("USD", subscription.AssetCode.String)
+	tt.Equal("GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB", subscription.AssetIssuer.String)
+	tt.Equal(
+		[]int64{int64(xdr.OperationTypePayment), int64(xdr.OperationTypePathPaymentStrictReceive)},
+		[]int64(subscription.OperationTypes),
+	)
+
+	subscription, err = webhookSubscriptionFromRequest(makeRequest(
+		t,
+		map[string]string{"url": "http://localhost:8080", "asset": "native"},
+		map[string]string{},
+		nil,
+	))
+	tt.NoError(err)
+	tt.Equal("native", subscription.AssetType.String)
+	tt.False(subscription.AssetCode.Valid)
+	tt.False(subscription.AccountID.Valid)
+	tt.Nil(subscription.OperationTypes)
+
+	for _, testCase := range []struct {
+		name   string
+		params map[string]string
+		field  string
+	}{
+		{"missing url", map[string]string{}, "url"},
+		{"relative url", map[string]string{"url": "/hook"}, "url"},
+		{"invalid scheme", map[string]string{"url": "ftp://example.com"}, "url"},
+		{"invalid account", map[string]string{"url": "https://example.com", "account_id": "GABC"}, "account_id"},
+		{"invalid asset", map[string]string{"url": "https://example.com", "asset": "USD"}, "asset"},
+		{"invalid operation type", map[string]string{"url": "https://example.com", "operation_types": "payment,foo"}, "operation_types"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := webhookSubscriptionFromRequest(makeRequest(t, testCase.params, map[string]string{}, nil))
+			tt.Error(err)
+			p, ok := err.(*problem.P)
+			if tt.True(ok) {
+				tt.Equal(testCase.field, p.Extras["invalid_field"])
+			}
+		})
+	}
+}
+
+func TestWebhookSubscriptionHandlers(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{tt.AuroraSession()}
+
+	created, err := CreateWebhookSubscriptionHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"url": "https://example.com/hook", "operation_types": "payment"},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.NoError(err)
+	subscription := created.(protocol.WebhookSubscription)
+	tt.Assert.NotEmpty(subscription.ID)
+	tt.Assert.Len(subscription.Secret, 64)
+	tt.Assert.Equal([]string{"payment"}, subscription.OperationTypes)
+
+	response, err := GetWebhookSubscriptionByIDHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{"id": subscription.ID},
+		q,
+	))
+	tt.Assert.NoError(err)
+	fetched := response.(protocol.WebhookSubscription)
+	tt.Assert.Equal(subscription.ID, fetched.ID)
+	tt.Assert.Equal("https://example.com/hook", fetched.URL)
+	// The secret is only returned on creation
+	tt.Assert.Empty(fetched.Secret)
+
+	response, err = GetWebhookSubscriptionsHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.NoError(err)
+	page := response.(hal.BasePage)
+	tt.Assert.Len(page.Embedded.Records, 1)
+
+	response, err = GetWebhookDeadLettersHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{"id": subscription.ID},
+		q,
+	))
+	tt.Assert.NoError(err)
+	page = response.(hal.BasePage)
+	tt.Assert.Len(page.Embedded.Records, 0)
+
+	_, err = DeleteWebhookSubscriptionHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{"id": subscription.ID},
+		q,
+	))
+	tt.Assert.NoError(err)
+
+	_, err = GetWebhookSubscriptionByIDHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{"id": subscription.ID},
+		q,
+	))
+	tt.Assert.True(q.NoRows(errors.Cause(err)))
+
+	_, err = GetWebhookSubscriptionByIDHandler{}.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{},
+		map[string]string{"id": "abc"},
+		q,
+	))
+	tt.Assert.Error(err)
+}
@@ -3,6 +3,7 @@ package aurora
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -48,6 +49,11 @@ func mockPathFindingClient(
 		MaxPathLength:        3,
 		SetLastLedgerHeader:  true,
 	}}
+	findSplitPaths := httpx.ObjectActionHandler{actions.FindSplitPathsHandler{
+		PathFinder:          finder,
+		MaxPathLength:       3,
+		SetLastLedgerHeader: true,
+	}}
 
 	router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -71,6 +77,7 @@ func mockPathFindingClient(
 		router.Method("GET", "/paths", findPaths)
 		router.Method("GET", "/paths/strict-receive", findPaths)
 		router.Method("GET", "/paths/strict-send", findFixedPaths)
+		router.Method("GET", "/paths/strict-send/split", findSplitPaths)
 	})
 
 	return test.NewRequestHelper(router)
@@ -628,6 +635,75 @@ func TestPathActionsStrictSend(t *testing.T) {
 	finder.AssertExpectations(t)
 }
 
+func TestPathActionsStrictSendSplit(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	assertions := &test.Assertions{tt.Assert}
+
+	issuer := "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN"
+	sourceAsset := xdr.MustNewCreditAsset("USD", issuer)
+	finder := paths.MockFinder{}
+	finder.On(
+		"FindSplitPaths", mock.Anything, sourceAsset, xdr.Int64(100000000), xdr.MustNewNativeAsset(), 3, uint(3),
+	).Return([]paths.Path{
+		{
+			Path:              []string{},
+			Source:            "credit_alphanum4/USD/" + issuer,
+			SourceAmount:      60000000,
+			Destination:       "native",
+			DestinationAmount: 120000000,
+		},
+		{
+			Path:              []string{"credit_alphanum4/EUR/" + issuer},
+			Source:            "credit_alphanum4/USD/" + issuer,
+			SourceAmount:      40000000,
+			Destination:       "native",
+			DestinationAmount: 70000000,
+		},
+	}, uint32(1234), nil).Once()
+	finder.On(
+		"FindSplitPaths", mock.Anything, sourceAsset, xdr.Int64(100000000), xdr.MustNewNativeAsset(), 5, uint(3),
+	).Return([]paths.Path{}, uint32(1234), nil).Once()
+
+	rh := mockPathFindingClient(tt, &finder, 3, tt.AuroraSession())
+
+	q := make(url.Values)
+	q.Add("source_asset_type", "credit_alphanum4")
+	q.Add("source_asset_code", "USD")
+	q.Add("source_asset_issuer", issuer)
+	q.Add("source_amount", "10")
+	q.Add("destination_asset_type", "native")
+
+	w := rh.Get("/paths/strict-send/split?" + q.Encode())
+	assertions.Equal(http.StatusOK, w.Code)
+	assertions.Equal("1234", w.Header().Get(actions.LastLedgerHeaderName))
+	var payment aurora.SplitPathPayment
+	tt.Assert.NoError(json.Unmarshal(w.Body.Bytes(), &payment))
+	tt.Assert.Equal("10.0000000", payment.SourceAmount)
+	tt.Assert.Equal("19.0000000", payment.DestinationAmount)
+	tt.Assert.Equal("native", payment.DestinationAssetType)
+	tt.Assert.Len(payment.Paths, 2)
+	tt.Assert.Equal("6.0000000", payment.Paths[0].SourceAmount)
+	tt.Assert.Empty(payment.Paths[0].Path)
+	tt.Assert.Equal("7.0000000", payment.Paths[1].DestinationAmount)
+	tt.Assert.Equal("EUR", payment.Paths[1].Path[0].Code)
+
+	q.Set("max_paths", "5")
+	w = rh.Get("/paths/strict-send/split?" + q.Encode())
+	assertions.Equal(http.StatusOK, w.Code)
+	payment = aurora.SplitPathPayment{}
+	tt.Assert.NoError(json.Unmarshal(w.Body.Bytes(), &payment))
+	tt.Assert.Empty(payment.Paths)
+	tt.Assert.Equal("0.0000000", payment.DestinationAmount)
+
+	q.Set("max_paths", "6")
+	w = rh.Get("/paths/strict-send/split?" + q.Encode())
+	assertions.Equal(http.StatusBadRequest, w.Code)
+
+	finder.AssertExpectations(t)
+}
+
 func assetsToURLParam(xdrAssets []xdr.Asset) string {
 	var assets []string
 	for _, xdrAsset := range xdrAssets {
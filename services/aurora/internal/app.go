@@ -14,15 +14,20 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/diamnet/go/clients/diamnetcore"
+	"github.com/diamnet/go/exp/orderbook"
+	"github.com/diamnet/go/services/aurora/internal/coldstorage"
 	"github.com/diamnet/go/services/aurora/internal/corestate"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/services/aurora/internal/httpx"
 	"github.com/diamnet/go/services/aurora/internal/ingest"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
 	"github.com/diamnet/go/services/aurora/internal/ledger"
 	"github.com/diamnet/go/services/aurora/internal/logmetrics"
 	"github.com/diamnet/go/services/aurora/internal/operationfeestats"
 	"github.com/diamnet/go/services/aurora/internal/paths"
+	"github.com/diamnet/go/services/aurora/internal/ratelimit"
 	"github.com/diamnet/go/services/aurora/internal/reap"
+	"github.com/diamnet/go/services/aurora/internal/txsim"
 	"github.com/diamnet/go/services/aurora/internal/txsub"
 	"github.com/diamnet/go/support/app"
 	"github.com/diamnet/go/support/db"
@@ -38,6 +43,7 @@ type App struct {
 	webServer       *httpx.Server
 	historyQ        *history.Q
 	primaryHistoryQ *history.Q
+	replicaPool     *db.ReplicaPool
 	ctx             context.Context
 	cancel          func()
 	auroraVersion  string
@@ -45,8 +51,11 @@ type App struct {
 	orderBookStream *ingest.OrderBookStream
 	submitter       *txsub.System
 	paths           paths.Finder
+	pathCache       *orderbook.PathCache
+	orderBook       txsim.OrderBook
 	ingester        ingest.System
 	reaper          *reap.System
+	coldStorage     *coldstorage.Store
 	ticks           *time.Ticker
 	ledgerState     *ledger.State
 
@@ -178,6 +187,9 @@ func (a *App) waitForDone() {
 	if a.reaper != nil {
 		a.reaper.Shutdown()
 	}
+	if a.replicaPool != nil {
+		a.replicaPool.Close()
+	}
 	a.ticks.Stop()
 }
 
@@ -490,8 +502,12 @@ func (a *App) init() error {
 	// txsub
 	initSubmissionSystem(a)
 
+	// cold storage
+	initColdStorage(a)
+
 	// reaper
 	a.reaper = reap.New(a.config.HistoryRetentionCount, a.AuroraSession(), a.ledgerState)
+	a.reaper.IndexColdTransactions = a.coldStorage != nil
 
 	// go metrics
 	initGoMetrics(a)
@@ -502,6 +518,9 @@ func (a *App) init() error {
 	// db-metrics
 	initDbMetrics(a)
 
+	// path finding metrics
+	initPathFinderMetrics(a)
+
 	// ingest.metrics
 	initIngestMetrics(a)
 
@@ -521,10 +540,20 @@ func (a *App) init() error {
 		MaxPathLength:           a.config.MaxPathLength,
 		MaxAssetsPerPathRequest: a.config.MaxAssetsPerPathRequest,
 		PathFinder:              a.paths,
+		OrderBook:               a.orderBook,
 		PrometheusRegistry:      a.prometheusRegistry,
 		CoreGetter:              a,
 		AuroraVersion:          a.auroraVersion,
 		FriendbotURL:            a.config.FriendbotURL,
+		EnableGraphQL:           a.config.EnableGraphQL,
+		GraphQLMaxDepth:         a.config.GraphQLMaxDepth,
+		GraphQLMaxCost:          a.config.GraphQLMaxCost,
+		ColdStorage:             a.coldStorage,
+		IngestFilters: processors.TransactionFilterRules{
+			Accounts:       a.config.IngestFilterAccounts,
+			Assets:         a.config.IngestFilterAssets,
+			OperationTypes: a.config.IngestFilterOperationTypes,
+		},
 		HealthCheck: healthCheck{
 			session: a.historyQ.SessionInterface,
 			ctx:     a.ctx,
@@ -539,8 +568,23 @@ func (a *App) init() error {
 	if a.primaryHistoryQ != nil {
 		routerConfig.PrimaryDBSession = a.primaryHistoryQ.SessionInterface
 	}
+	if a.replicaPool != nil {
+		routerConfig.ReplicaPool = a.replicaPool
+	}
 
 	var err error
+	if a.config.EnableAPIKeys {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if a.config.RateLimitRedisURL != "" {
+			store, err = ratelimit.NewRedisStore(a.config.RateLimitRedisURL)
+			if err != nil {
+				return err
+			}
+		}
+		routerConfig.APIKeyLimiter = httpx.NewAPIKeyLimiter(store, a.historyQ.SessionInterface)
+		routerConfig.RateLimitRouteCosts = a.config.RateLimitRouteCosts
+	}
+
 	config := httpx.ServerConfig{
 		Port:      uint16(a.config.Port),
 		AdminPort: uint16(a.config.AdminPort),
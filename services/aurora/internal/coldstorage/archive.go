@@ -0,0 +1,82 @@
+package coldstorage
+
+import (
+	"context"
+
+	"github.com/diamnet/go/historyarchive"
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/network"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// ArchiveBackend reads the ledgers of the cold storage from a history
+// archive, a checkpoint at a time.
+type ArchiveBackend struct {
+	Archive           historyarchive.ArchiveInterface
+	NetworkPassphrase string
+}
+
+// GetLedgers returns the ledgers of the checkpoint containing the given
+// ledger.
+func (b ArchiveBackend) GetLedgers(ctx context.Context, sequence uint32) ([]Ledger, error) {
+	checkpoints := b.Archive.GetCheckpointManager()
+	checkpointRange := checkpoints.GetCheckpointRange(sequence)
+
+	archived, err := b.Archive.GetLedgers(checkpointRange.Low, checkpointRange.High)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get checkpoint %d from history archive", checkpointRange.High)
+	}
+	if _, ok := archived[sequence]; !ok {
+		return nil, ErrNotFound
+	}
+
+	ledgers := make([]Ledger, 0, len(archived))
+	for seq := checkpointRange.Low; seq <= checkpointRange.High; seq++ {
+		entry, ok := archived[seq]
+		if !ok {
+			continue
+		}
+		ledger, err := b.ledger(entry)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read ledger %d", seq)
+		}
+		ledgers = append(ledgers, ledger)
+	}
+	return ledgers, nil
+}
+
+// ledger matches the results of the ledger, which are in application order,
+// with the transactions of the transaction set.
+func (b ArchiveBackend) ledger(entry *historyarchive.Ledger) (Ledger, error) {
+	envelopes := map[xdr.Hash]xdr.TransactionEnvelope{}
+	for _, envelope := range entry.Transaction.TxSet.Txs {
+		hash, err := network.HashTransactionInEnvelope(envelope, b.NetworkPassphrase)
+		if err != nil {
+			return Ledger{}, errors.Wrap(err, "could not hash transaction")
+		}
+		envelopes[hash] = envelope
+	}
+
+	ledger := Ledger{Header: entry.Header}
+	for i, result := range entry.TransactionResult.TxResultSet.Results {
+		envelope, ok := envelopes[result.TransactionHash]
+		if !ok {
+			return Ledger{}, errors.Errorf("transaction %x not found in transaction set", result.TransactionHash)
+		}
+		ledger.Transactions = append(ledger.Transactions, ingest.LedgerTransaction{
+			Index:    uint32(i + 1),
+			Envelope: envelope,
+			Result:   result,
+			// The changes of the operations are not archived
+			UnsafeMeta: xdr.TransactionMeta{
+				V: 2,
+				V2: &xdr.TransactionMetaV2{
+					Operations: make([]xdr.OperationMeta, len(envelope.Operations())),
+				},
+			},
+			FeeChanges: xdr.LedgerEntryChanges{},
+		})
+	}
+	return ledger, nil
+}
@@ -0,0 +1,187 @@
+package coldstorage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	"github.com/diamnet/go/historyarchive"
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// fileLedger is a line of the files of the FileBackend, the XDR values are
+// base64 encoded.
+type fileLedger struct {
+	Header       string            `json:"header"`
+	Transactions []fileTransaction `json:"transactions"`
+}
+
+type fileTransaction struct {
+	Envelope   string `json:"envelope"`
+	Result     string `json:"result"`
+	Meta       string `json:"meta"`
+	FeeChanges string `json:"fee_changes"`
+}
+
+// FileBackend stores the ledgers of every checkpoint in a gzipped newline
+// delimited JSON file (ledgers/xx/yy/zz/ledgers-xxyyzzww.ndjson.gz, where
+// xxyyzzww is the checkpoint ledger in hexadecimal), in a directory, an S3
+// bucket or, read-only, on an HTTP server.
+type FileBackend struct {
+	storage     historyarchive.ArchiveBackend
+	checkpoints historyarchive.CheckpointManager
+}
+
+// NewFileBackend returns a FileBackend storing its files at the given URL.
+func NewFileBackend(url string, opts historyarchive.ConnectOptions) (*FileBackend, error) {
+	storage, err := historyarchive.ConnectBackend(url, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to cold storage")
+	}
+	return &FileBackend{
+		storage:     storage,
+		checkpoints: historyarchive.NewCheckpointManager(opts.CheckpointFrequency),
+	}, nil
+}
+
+func (b *FileBackend) checkpointPath(checkpoint uint32) string {
+	return path.Join(
+		"ledgers",
+		historyarchive.CheckpointPrefix(checkpoint).Path(),
+		fmt.Sprintf("ledgers-%08x.ndjson.gz", checkpoint),
+	)
+}
+
+// GetLedgers returns the ledgers of the checkpoint containing the given
+// ledger.
+func (b *FileBackend) GetLedgers(ctx context.Context, sequence uint32) ([]Ledger, error) {
+	filePath := b.checkpointPath(b.checkpoints.GetCheckpoint(sequence))
+	exists, err := b.storage.Exists(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not check if %s exists", filePath)
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	file, err := b.storage.GetFile(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open %s", filePath)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", filePath)
+	}
+	defer reader.Close()
+
+	var ledgers []Ledger
+	scanner := bufio.NewScanner(reader)
+	// Lines contain whole ledgers
+	scanner.Buffer(nil, 256*1024*1024)
+	for scanner.Scan() {
+		var line fileLedger
+		if err = json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, errors.Wrapf(err, "could not decode line %d of %s", len(ledgers)+1, filePath)
+		}
+		ledger, err := line.ledger()
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not decode line %d of %s", len(ledgers)+1, filePath)
+		}
+		ledgers = append(ledgers, ledger)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", filePath)
+	}
+	return ledgers, nil
+}
+
+// PutLedgers writes the file of a checkpoint, replacing the existing file if
+// any. The ledgers must be ordered and belong to the same checkpoint.
+func (b *FileBackend) PutLedgers(ledgers []Ledger) error {
+	if len(ledgers) == 0 {
+		return nil
+	}
+	checkpoint := b.checkpoints.GetCheckpoint(uint32(ledgers[0].Header.Header.LedgerSeq))
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	encoder := json.NewEncoder(writer)
+	for _, ledger := range ledgers {
+		sequence := uint32(ledger.Header.Header.LedgerSeq)
+		if b.checkpoints.GetCheckpoint(sequence) != checkpoint {
+			return errors.Errorf("ledger %d is not in checkpoint %d", sequence, checkpoint)
+		}
+		line, err := newFileLedger(ledger)
+		if err != nil {
+			return errors.Wrapf(err, "could not encode ledger %d", sequence)
+		}
+		if err = encoder.Encode(line); err != nil {
+			return errors.Wrapf(err, "could not encode ledger %d", sequence)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return errors.Wrap(err, "could not compress ledgers")
+	}
+
+	filePath := b.checkpointPath(checkpoint)
+	if err := b.storage.PutFile(filePath, ioutil.NopCloser(&buffer)); err != nil {
+		return errors.Wrapf(err, "could not write %s", filePath)
+	}
+	return nil
+}
+
+func newFileLedger(ledger Ledger) (fileLedger, error) {
+	var err error
+	line := fileLedger{Transactions: make([]fileTransaction, len(ledger.Transactions))}
+	if line.Header, err = xdr.MarshalBase64(ledger.Header); err != nil {
+		return line, err
+	}
+	for i, transaction := range ledger.Transactions {
+		encoded := &line.Transactions[i]
+		if encoded.Envelope, err = xdr.MarshalBase64(transaction.Envelope); err != nil {
+			return line, err
+		}
+		if encoded.Result, err = xdr.MarshalBase64(transaction.Result); err != nil {
+			return line, err
+		}
+		if encoded.Meta, err = xdr.MarshalBase64(transaction.UnsafeMeta); err != nil {
+			return line, err
+		}
+		if encoded.FeeChanges, err = xdr.MarshalBase64(transaction.FeeChanges); err != nil {
+			return line, err
+		}
+	}
+	return line, nil
+}
+
+func (l fileLedger) ledger() (Ledger, error) {
+	var ledger Ledger
+	if err := xdr.SafeUnmarshalBase64(l.Header, &ledger.Header); err != nil {
+		return ledger, errors.Wrap(err, "invalid ledger header")
+	}
+	for i, encoded := range l.Transactions {
+		transaction := ingest.LedgerTransaction{Index: uint32(i + 1)}
+		if err := xdr.SafeUnmarshalBase64(encoded.Envelope, &transaction.Envelope); err != nil {
+			return ledger, errors.Wrap(err, "invalid transaction envelope")
+		}
+		if err := xdr.SafeUnmarshalBase64(encoded.Result, &transaction.Result); err != nil {
+			return ledger, errors.Wrap(err, "invalid transaction result")
+		}
+		if err := xdr.SafeUnmarshalBase64(encoded.Meta, &transaction.UnsafeMeta); err != nil {
+			return ledger, errors.Wrap(err, "invalid transaction meta")
+		}
+		if err := xdr.SafeUnmarshalBase64(encoded.FeeChanges, &transaction.FeeChanges); err != nil {
+			return ledger, errors.Wrap(err, "invalid transaction fee changes")
+		}
+		ledger.Transactions = append(ledger.Transactions, transaction)
+	}
+	return ledger, nil
+}
@@ -0,0 +1,257 @@
+// Package coldstorage serves the history of ledgers removed by the reaper from
+// a cold storage tier: the history archives or ledgers exported with
+// `aurora db export-ledgers` to a directory, an S3 bucket or an HTTP server.
+// Ledgers are fetched on demand and converted into the rows of the history
+// tables, so they are rendered by the same code as the ledgers in the DB. The
+// converted ledgers are kept in an LRU cache.
+//
+// History archives don't contain the meta of the transactions: the effects of
+// the transactions are not available and the details of liquidity pool
+// deposits and withdrawals are empty.
+package coldstorage
+
+import (
+	"container/list"
+	"context"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/guregu/null"
+
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// ErrNotFound is returned when a ledger is not in the cold storage.
+var ErrNotFound = errors.New("ledger not found in cold storage")
+
+// Ledger is a ledger stored in the cold storage.
+type Ledger struct {
+	Header xdr.LedgerHeaderHistoryEntry
+	// Transactions are ordered by application order. Their meta is empty if
+	// it is not stored.
+	Transactions []ingest.LedgerTransaction
+}
+
+// Backend is the storage of the ledgers of the cold storage.
+type Backend interface {
+	// GetLedgers returns the ledger with the given sequence along with the
+	// ledgers stored in the same file, or ErrNotFound.
+	GetLedgers(ctx context.Context, sequence uint32) ([]Ledger, error)
+}
+
+// Records are the rows of the history tables of a ledger of the cold storage.
+type Records struct {
+	Ledger history.Ledger
+	// Transactions and Operations are ordered by ID.
+	Transactions []history.Transaction
+	Operations   []history.Operation
+}
+
+// Transaction returns the transaction of the ledger with the given hash or
+// inner transaction hash.
+func (r *Records) Transaction(hash string) (history.Transaction, bool) {
+	for _, transaction := range r.Transactions {
+		if transaction.TransactionHash == hash || transaction.InnerTransactionHash.String == hash {
+			return transaction, true
+		}
+	}
+	return history.Transaction{}, false
+}
+
+// Store fetches the ledgers of the cold storage and caches their records.
+type Store struct {
+	backend Backend
+	size    int
+
+	mutex   sync.Mutex
+	lru     *list.List
+	entries map[uint32]*list.Element
+}
+
+type cacheEntry struct {
+	sequence uint32
+	records  *Records
+}
+
+// NewStore returns a store caching the records of up to cacheSize ledgers.
+func NewStore(backend Backend, cacheSize int) *Store {
+	if cacheSize < 1 {
+		cacheSize = 1
+	}
+	return &Store{
+		backend: backend,
+		size:    cacheSize,
+		lru:     list.New(),
+		entries: map[uint32]*list.Element{},
+	}
+}
+
+// Records returns the records of the ledger with the given sequence, or
+// ErrNotFound.
+func (s *Store) Records(ctx context.Context, sequence uint32) (*Records, error) {
+	if records := s.cached(sequence); records != nil {
+		return records, nil
+	}
+
+	// The ledgers stored with the requested ledger are cached too because
+	// requests for a ledger are usually followed by requests for the next ones.
+	ledgers, err := s.backend.GetLedgers(ctx, sequence)
+	if err != nil {
+		return nil, err
+	}
+	var found *Records
+	for _, ledger := range ledgers {
+		records, err := NewRecords(ledger)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not convert ledger %d", ledger.Header.Header.LedgerSeq)
+		}
+		s.add(records)
+		if uint32(records.Ledger.Sequence) == sequence {
+			found = records
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (s *Store) cached(sequence uint32) *Records {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	element, ok := s.entries[sequence]
+	if !ok {
+		return nil
+	}
+	s.lru.MoveToFront(element)
+	return element.Value.(cacheEntry).records
+}
+
+func (s *Store) add(records *Records) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sequence := uint32(records.Ledger.Sequence)
+	if element, ok := s.entries[sequence]; ok {
+		s.lru.MoveToFront(element)
+		return
+	}
+	s.entries[sequence] = s.lru.PushFront(cacheEntry{sequence: sequence, records: records})
+	for s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(cacheEntry).sequence)
+	}
+}
+
+// NewRecords converts a ledger of the cold storage into the rows of the
+// history tables, like the ingestion processors.
+func NewRecords(ledger Ledger) (*Records, error) {
+	header := ledger.Header.Header
+	sequence := uint32(header.LedgerSeq)
+	closedAt := time.Unix(int64(header.ScpValue.CloseTime), 0).UTC()
+
+	records := &Records{}
+	var successful, failed, operations, txSetOperations int32
+	for _, transaction := range ledger.Transactions {
+		row, err := history.NewTransactionRow(transaction, sequence)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not convert transaction")
+		}
+		records.Transactions = append(records.Transactions, history.Transaction{
+			LedgerCloseTime:          closedAt,
+			TransactionWithoutLedger: row,
+		})
+
+		opRows, err := processors.OperationRows(transaction, sequence)
+		if err != nil {
+			return nil, err
+		}
+		records.Operations = append(records.Operations, opRows...)
+
+		txSetOperations += row.OperationCount
+		if row.Successful {
+			successful++
+			operations += row.OperationCount
+		} else {
+			failed++
+		}
+	}
+
+	headerXDR, err := xdr.MarshalBase64(header)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode ledger header")
+	}
+	records.Ledger = history.Ledger{
+		Sequence:                   int32(sequence),
+		LedgerHash:                 hex.EncodeToString(ledger.Header.Hash[:]),
+		PreviousLedgerHash:         null.NewString(hex.EncodeToString(header.PreviousLedgerHash[:]), sequence > 1),
+		TransactionCount:           successful,
+		SuccessfulTransactionCount: &successful,
+		FailedTransactionCount:     &failed,
+		OperationCount:             operations,
+		TxSetOperationCount:        &txSetOperations,
+		ClosedAt:                   closedAt,
+		CreatedAt:                  closedAt,
+		UpdatedAt:                  closedAt,
+		TotalCoins:                 int64(header.TotalCoins),
+		FeePool:                    int64(header.FeePool),
+		BaseFee:                    int32(header.BaseFee),
+		BaseReserve:                int32(header.BaseReserve),
+		MaxTxSetSize:               int32(header.MaxTxSetSize),
+		ProtocolVersion:            int32(header.LedgerVersion),
+		LedgerHeaderXDR:            null.StringFrom(headerXDR),
+	}
+	records.Ledger.ID = toid.New(int32(sequence), 0, 0).ToInt64()
+	return records, nil
+}
+
+// NewLedger converts the rows of the history tables of a ledger into a ledger
+// of the cold storage. transactions must contain the failed transactions.
+func NewLedger(ledger history.Ledger, transactions []history.Transaction) (Ledger, error) {
+	var result Ledger
+	if !ledger.LedgerHeaderXDR.Valid {
+		return result, errors.Errorf("ledger %d has no header", ledger.Sequence)
+	}
+	if err := xdr.SafeUnmarshalBase64(ledger.LedgerHeaderXDR.String, &result.Header.Header); err != nil {
+		return result, errors.Wrap(err, "invalid ledger header")
+	}
+	hash, err := hex.DecodeString(ledger.LedgerHash)
+	if err != nil || len(hash) != len(result.Header.Hash) {
+		return result, errors.Errorf("invalid ledger hash %q", ledger.LedgerHash)
+	}
+	copy(result.Header.Hash[:], hash)
+
+	sorted := append([]history.Transaction(nil), transactions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ApplicationOrder < sorted[j].ApplicationOrder
+	})
+	for _, row := range sorted {
+		transaction := ingest.LedgerTransaction{Index: uint32(row.ApplicationOrder)}
+		if err := xdr.SafeUnmarshalBase64(row.TxEnvelope, &transaction.Envelope); err != nil {
+			return result, errors.Wrapf(err, "invalid envelope of transaction %s", row.TransactionHash)
+		}
+		if err := xdr.SafeUnmarshalBase64(row.TxResult, &transaction.Result.Result); err != nil {
+			return result, errors.Wrapf(err, "invalid result of transaction %s", row.TransactionHash)
+		}
+		if err := xdr.SafeUnmarshalBase64(row.TxMeta, &transaction.UnsafeMeta); err != nil {
+			return result, errors.Wrapf(err, "invalid meta of transaction %s", row.TransactionHash)
+		}
+		if err := xdr.SafeUnmarshalBase64(row.TxFeeMeta, &transaction.FeeChanges); err != nil {
+			return result, errors.Wrapf(err, "invalid fee meta of transaction %s", row.TransactionHash)
+		}
+		hash, err := hex.DecodeString(row.TransactionHash)
+		if err != nil || len(hash) != len(transaction.Result.TransactionHash) {
+			return result, errors.Errorf("invalid transaction hash %q", row.TransactionHash)
+		}
+		copy(transaction.Result.TransactionHash[:], hash)
+		result.Transactions = append(result.Transactions, transaction)
+	}
+	return result, nil
+}
@@ -0,0 +1,219 @@
+package coldstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/historyarchive"
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/network"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/xdr"
+)
+
+func testTransaction(index uint32, successful bool) ingest.LedgerTransaction {
+	code := xdr.TransactionResultCodeTxSuccess
+	if !successful {
+		code = xdr.TransactionResultCodeTxFailed
+	}
+	source := xdr.MustAddress("GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY")
+	bumpSequence := xdr.BumpSequenceOp{BumpTo: 30000}
+	return ingest.LedgerTransaction{
+		Index: index,
+		Result: xdr.TransactionResultPair{
+			TransactionHash: xdr.Hash{byte(index)},
+			Result: xdr.TransactionResult{
+				FeeCharged: 100,
+				Result: xdr.TransactionResultResult{
+					Code:    code,
+					Results: &[]xdr.OperationResult{},
+				},
+			},
+		},
+		Envelope: xdr.TransactionEnvelope{
+			Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+			V1: &xdr.TransactionV1Envelope{
+				Tx: xdr.Transaction{
+					SourceAccount: source.ToMuxedAccount(),
+					Fee:           100,
+					SeqNum:        xdr.SequenceNumber(index),
+					Operations: []xdr.Operation{{
+						Body: xdr.OperationBody{
+							Type:           xdr.OperationTypeBumpSequence,
+							BumpSequenceOp: &bumpSequence,
+						},
+					}},
+				},
+			},
+		},
+		UnsafeMeta: xdr.TransactionMeta{
+			V:  2,
+			V2: &xdr.TransactionMetaV2{Operations: make([]xdr.OperationMeta, 1)},
+		},
+		FeeChanges: xdr.LedgerEntryChanges{},
+	}
+}
+
+func testLedger(sequence uint32) Ledger {
+	return Ledger{
+		Header: xdr.LedgerHeaderHistoryEntry{
+			Hash: xdr.Hash{byte(sequence)},
+			Header: xdr.LedgerHeader{
+				LedgerSeq:     xdr.Uint32(sequence),
+				LedgerVersion: 18,
+				ScpValue:      xdr.DiamnetValue{CloseTime: 1000},
+			},
+		},
+		Transactions: []ingest.LedgerTransaction{
+			testTransaction(1, true),
+			testTransaction(2, false),
+		},
+	}
+}
+
+func TestNewRecords(t *testing.T) {
+	records, err := NewRecords(testLedger(100))
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(100), records.Ledger.Sequence)
+	assert.Equal(t, toid.New(100, 0, 0).ToInt64(), records.Ledger.ID)
+	assert.Equal(t, int32(1), records.Ledger.TransactionCount)
+	assert.Equal(t, int32(1), *records.Ledger.FailedTransactionCount)
+	assert.Equal(t, int32(1), records.Ledger.OperationCount)
+	assert.Equal(t, int32(2), *records.Ledger.TxSetOperationCount)
+
+	require.Len(t, records.Transactions, 2)
+	assert.Equal(t, toid.New(100, 1, 0).ToInt64(), records.Transactions[0].ID)
+	assert.True(t, records.Transactions[0].Successful)
+	assert.False(t, records.Transactions[1].Successful)
+
+	require.Len(t, records.Operations, 2)
+	assert.Equal(t, toid.New(100, 2, 1).ToInt64(), records.Operations[1].ID)
+	assert.Equal(t, records.Transactions[1].ID, records.Operations[1].TransactionID)
+	assert.False(t, records.Operations[1].TransactionSuccessful)
+
+	transaction, ok := records.Transaction(records.Transactions[1].TransactionHash)
+	assert.True(t, ok)
+	assert.Equal(t, records.Transactions[1].ID, transaction.ID)
+	_, ok = records.Transaction("0000000000000000000000000000000000000000000000000000000000000000")
+	assert.False(t, ok)
+}
+
+type testBackend struct {
+	calls []uint32
+}
+
+func (b *testBackend) GetLedgers(ctx context.Context, sequence uint32) ([]Ledger, error) {
+	b.calls = append(b.calls, sequence)
+	if sequence > 10 {
+		return nil, ErrNotFound
+	}
+	// Ledgers are stored by pairs
+	first := sequence - (sequence+1)%2
+	return []Ledger{testLedger(first), testLedger(first + 1)}, nil
+}
+
+func TestStore(t *testing.T) {
+	backend := &testBackend{}
+	store := NewStore(backend, 3)
+	ctx := context.Background()
+
+	records, err := store.Records(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), records.Ledger.Sequence)
+	records, err = store.Records(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), records.Ledger.Sequence)
+	assert.Equal(t, []uint32{3}, backend.calls)
+
+	// 3 is evicted
+	_, err = store.Records(ctx, 5)
+	require.NoError(t, err)
+	_, err = store.Records(ctx, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{3, 5}, backend.calls)
+	_, err = store.Records(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{3, 5, 3}, backend.calls)
+
+	_, err = store.Records(ctx, 11)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestFileBackend(t *testing.T) {
+	backend, err := NewFileBackend("mock://test", historyarchive.ConnectOptions{CheckpointFrequency: 64})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = backend.GetLedgers(ctx, 100)
+	assert.Equal(t, ErrNotFound, err)
+
+	ledgers := []Ledger{testLedger(64), testLedger(65)}
+	require.NoError(t, backend.PutLedgers(ledgers))
+	read, err := backend.GetLedgers(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, read, 2)
+	for i, ledger := range read {
+		// Empty arrays are decoded as nil slices
+		expected, err := newFileLedger(ledgers[i])
+		require.NoError(t, err)
+		actual, err := newFileLedger(ledger)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, ledgers[i].Transactions[1].Index, ledger.Transactions[1].Index)
+	}
+
+	_, err = backend.GetLedgers(ctx, 128)
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.EqualError(
+		t,
+		backend.PutLedgers([]Ledger{testLedger(127), testLedger(128)}),
+		"ledger 128 is not in checkpoint 127",
+	)
+}
+
+func TestArchiveBackend(t *testing.T) {
+	passphrase := "test network"
+	first, second := testTransaction(1, true), testTransaction(2, false)
+	var results []xdr.TransactionResultPair
+	for _, transaction := range []ingest.LedgerTransaction{second, first} {
+		hash, err := network.HashTransactionInEnvelope(transaction.Envelope, passphrase)
+		require.NoError(t, err)
+		transaction.Result.TransactionHash = hash
+		results = append(results, transaction.Result)
+	}
+
+	archive := &historyarchive.MockArchive{}
+	archive.On("GetCheckpointManager").Return(historyarchive.NewCheckpointManager(64))
+	archive.On("GetLedgers", uint32(64), uint32(127)).Return(map[uint32]*historyarchive.Ledger{
+		100: {
+			Header: testLedger(100).Header,
+			Transaction: xdr.TransactionHistoryEntry{
+				TxSet: xdr.TransactionSet{Txs: []xdr.TransactionEnvelope{first.Envelope, second.Envelope}},
+			},
+			TransactionResult: xdr.TransactionHistoryResultEntry{
+				TxResultSet: xdr.TransactionResultSet{Results: results},
+			},
+		},
+	}, nil)
+	backend := ArchiveBackend{Archive: archive, NetworkPassphrase: passphrase}
+	ctx := context.Background()
+
+	ledgers, err := backend.GetLedgers(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, ledgers, 1)
+	require.Len(t, ledgers[0].Transactions, 2)
+	// Transactions are in application order
+	assert.Equal(t, results[0], ledgers[0].Transactions[0].Result)
+	assert.Equal(t, second.Envelope, ledgers[0].Transactions[0].Envelope)
+	assert.Equal(t, uint32(2), ledgers[0].Transactions[1].Index)
+	assert.Equal(t, first.Envelope, ledgers[0].Transactions[1].Envelope)
+
+	_, err = backend.GetLedgers(ctx, 101)
+	assert.Equal(t, ErrNotFound, err)
+	archive.AssertExpectations(t)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/diamnet/go/ingest/ledgerbackend"
+	"github.com/diamnet/go/services/aurora/internal/ratelimit"
 
 	"github.com/sirupsen/logrus"
 	"github.com/diamnet/throttled"
@@ -15,6 +16,8 @@ import (
 type Config struct {
 	DatabaseURL        string
 	RoDatabaseURL      string
+	RoDatabaseURLs     []string
+	ReplicaMaxLag      uint
 	HistoryArchiveURLs []string
 	Port               uint
 	AdminPort          uint
@@ -40,15 +43,33 @@ type Config struct {
 	SSEUpdateFrequency time.Duration
 	ConnectionTimeout  time.Duration
 	RateQuota          *throttled.RateQuota
-	FriendbotURL       *url.URL
-	LogLevel           logrus.Level
-	LogFile            string
+	// EnableAPIKeys enables the rate limiting of requests made with API keys
+	// according to the quotas of their tiers.
+	EnableAPIKeys bool
+	// RateLimitRedisURL is the URL of the Redis compatible server keeping the
+	// quotas of API keys. Quotas are kept in memory when empty.
+	RateLimitRedisURL string
+	// RateLimitRouteCosts are the costs of requests made with API keys by
+	// path prefix.
+	RateLimitRouteCosts ratelimit.Costs
+	FriendbotURL        *url.URL
+	LogLevel            logrus.Level
+	LogFile             string
 
 	// MaxPathLength is the maximum length of the path returned by `/paths` endpoint.
 	MaxPathLength uint
 	// MaxAssetsPerPathRequest is the maximum number of assets considered for `/paths/strict-send` and `/paths/strict-recieve`
 	MaxAssetsPerPathRequest int
 	DisablePoolPathFinding  bool
+	// PathFindingCacheSize is the number of path finding searches cached in
+	// memory, 0 disables the cache.
+	PathFindingCacheSize int
+	// EnableGraphQL enables the /graphql endpoint.
+	EnableGraphQL bool
+	// GraphQLMaxDepth is the maximum field nesting depth of GraphQL queries
+	GraphQLMaxDepth int
+	// GraphQLMaxCost is the maximum cost of GraphQL queries
+	GraphQLMaxCost int
 
 	NetworkPassphrase string
 	SentryDSN         string
@@ -70,6 +91,16 @@ type Config struct {
 	// determining a "retention duration", each ledger roughly corresponds to 10
 	// seconds of real time.
 	HistoryRetentionCount uint
+	// ColdStorageURL is the location of the ledgers exported with `aurora db
+	// export-ledgers`, they are served when they are requested after being
+	// removed from the history tables.
+	ColdStorageURL string
+	// ColdStorageHistoryArchive serves the ledgers removed from the history
+	// tables from the first history archive instead.
+	ColdStorageHistoryArchive bool
+	// ColdStorageCacheSize is the number of ledgers of the cold storage kept
+	// in memory.
+	ColdStorageCacheSize uint
 	// StaleThreshold represents the number of ledgers a history database may be
 	// out-of-date by before aurora begins to respond with an error to history
 	// requests.
@@ -83,6 +114,29 @@ type Config struct {
 	// IngestEnableExtendedLogLedgerStats enables extended ledger stats in
 	// logging.
 	IngestEnableExtendedLogLedgerStats bool
+	// IngestEnableWebhooks enables delivery of ingested operations to webhook
+	// subscriptions registered through the admin port.
+	IngestEnableWebhooks bool
+	// IngestEnableStateVersions keeps the previous versions of the state
+	// tables rows so that state endpoints can be queried as of an earlier
+	// ledger with the `as_of_ledger` parameter.
+	IngestEnableStateVersions bool
+	// IngestFilterAccounts, IngestFilterAssets and IngestFilterOperationTypes
+	// restrict the transactions ingested into the history tables, see
+	// processors.TransactionFilter. They can be replaced at runtime through
+	// the /ingest_filters endpoints of the admin port.
+	IngestFilterAccounts       []string
+	IngestFilterAssets         []string
+	IngestFilterOperationTypes []string
+	// IngestExportEventsFile is the path of the NDJSON file the ingested
+	// events are appended to.
+	IngestExportEventsFile string
+	// IngestExportEventsKafkaBrokers, IngestExportEventsKafkaTopic and
+	// IngestExportEventsKafkaPartition configure the Kafka partition the
+	// ingested events are produced to.
+	IngestExportEventsKafkaBrokers   []string
+	IngestExportEventsKafkaTopic     string
+	IngestExportEventsKafkaPartition uint32
 	// ApplyMigrations will apply pending migrations to the aurora database
 	// before starting the aurora service
 	ApplyMigrations bool
@@ -34,7 +34,9 @@ func (q *Q) GetAccountDataByName(ctx context.Context, id, name string) (Data, er
 // GetAccountDataByAccountID loads account data for a given account ID
 func (q *Q) GetAccountDataByAccountID(ctx context.Context, id string) ([]Data, error) {
 	var data []Data
-	sql := selectAccountData.Where(sq.Eq{"account_id": id})
+	sql := selectAccountData.
+		From(stateTable(ctx, "accounts_data", "accounts_data")).
+		Where(sq.Eq{"account_id": id})
 	err := q.Select(ctx, &data, sql)
 	return data, err
 }
@@ -130,7 +132,9 @@ func (q *Q) RemoveAccountData(ctx context.Context, keys []AccountDataKey) (int64
 // GetAccountDataByAccountsID loads account data for a list of account ID
 func (q *Q) GetAccountDataByAccountsID(ctx context.Context, id []string) ([]Data, error) {
 	var data []Data
-	sql := selectAccountData.Where(sq.Eq{"account_id": id})
+	sql := selectAccountData.
+		From(stateTable(ctx, "accounts_data", "accounts_data")).
+		Where(sq.Eq{"account_id": id})
 	err := q.Select(ctx, &data, sql)
 	return data, err
 }
@@ -11,6 +11,7 @@ import (
 
 func (q *Q) GetAccountSignersByAccountID(ctx context.Context, id string) ([]AccountSigner, error) {
 	sql := selectAccountSigners.
+		From(stateTable(ctx, "accounts_signers", "accounts_signers")).
 		Where(sq.Eq{"accounts_signers.account_id": id}).
 		OrderBy("accounts_signers.signer asc")
 
@@ -24,6 +25,7 @@ func (q *Q) GetAccountSignersByAccountID(ctx context.Context, id string) ([]Acco
 
 func (q *Q) SignersForAccounts(ctx context.Context, accounts []string) ([]AccountSigner, error) {
 	sql := selectAccountSigners.
+		From(stateTable(ctx, "accounts_signers", "accounts_signers")).
 		Where(map[string]interface{}{"accounts_signers.account_id": accounts})
 
 	var results []AccountSigner
@@ -36,7 +38,9 @@ func (q *Q) SignersForAccounts(ctx context.Context, accounts []string) ([]Accoun
 
 // AccountsForSigner returns a list of `AccountSigner` rows for a given signer
 func (q *Q) AccountsForSigner(ctx context.Context, signer string, page db2.PageQuery) ([]AccountSigner, error) {
-	sql := selectAccountSigners.Where("accounts_signers.signer = ?", signer)
+	sql := selectAccountSigners.
+		From(stateTable(ctx, "accounts_signers", "accounts_signers")).
+		Where("accounts_signers.signer = ?", signer)
 	sql, err := page.ApplyToUsingCursor(sql, "accounts_signers.account_id", page.Cursor)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not apply query to page")
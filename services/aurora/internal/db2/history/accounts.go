@@ -47,7 +47,9 @@ func (q *Q) CountAccounts(ctx context.Context) (int, error) {
 
 func (q *Q) GetAccountByID(ctx context.Context, id string) (AccountEntry, error) {
 	var account AccountEntry
-	sql := selectAccounts.Where(sq.Eq{"account_id": id})
+	sql := selectAccounts.
+		From(stateTable(ctx, "accounts", "accounts")).
+		Where(sq.Eq{"account_id": id})
 	err := q.Get(ctx, &account, sql)
 	return account, err
 }
@@ -132,8 +134,8 @@ func (q *Q) AccountsForAsset(ctx context.Context, asset xdr.Asset, page db2.Page
 
 	sql := sq.
 		Select("accounts.*").
-		From("accounts").
-		Join("trust_lines ON accounts.account_id = trust_lines.account_id").
+		From(stateTable(ctx, "accounts", "accounts")).
+		Join(stateTable(ctx, "trust_lines", "trust_lines") + " ON accounts.account_id = trust_lines.account_id").
 		Where(map[string]interface{}{
 			"trust_lines.asset_type":   int32(asset.Type),
 			"trust_lines.asset_issuer": issuer,
@@ -158,8 +160,8 @@ func (q *Q) AccountsForAsset(ctx context.Context, asset xdr.Asset, page db2.Page
 func (q *Q) AccountsForLiquidityPool(ctx context.Context, poolID string, page db2.PageQuery) ([]AccountEntry, error) {
 	sql := sq.
 		Select("accounts.*").
-		From("accounts").
-		Join("trust_lines ON accounts.account_id = trust_lines.account_id").
+		From(stateTable(ctx, "accounts", "accounts")).
+		Join(stateTable(ctx, "trust_lines", "trust_lines") + " ON accounts.account_id = trust_lines.account_id").
 		Where(map[string]interface{}{
 			"trust_lines.liquidity_pool_id": poolID,
 		})
@@ -177,10 +179,10 @@ func (q *Q) AccountsForLiquidityPool(ctx context.Context, poolID string, page db
 	return results, nil
 }
 
-func selectBySponsor(table, sponsor string, page db2.PageQuery) (sq.SelectBuilder, error) {
+func selectBySponsor(ctx context.Context, table, sponsor string, page db2.PageQuery) (sq.SelectBuilder, error) {
 	sql := sq.
 		Select("account_id").
-		From(table).
+		From(stateTable(ctx, table, table)).
 		Where(map[string]interface{}{
 			"sponsor": sponsor,
 		})
@@ -192,10 +194,10 @@ func selectBySponsor(table, sponsor string, page db2.PageQuery) (sq.SelectBuilde
 	return sql, err
 }
 
-func selectUnionBySponsor(tables []string, sponsor string, page db2.PageQuery) (sq.SelectBuilder, error) {
+func selectUnionBySponsor(ctx context.Context, tables []string, sponsor string, page db2.PageQuery) (sq.SelectBuilder, error) {
 	var selectIDs sq.SelectBuilder
 	for i, table := range tables {
-		sql, err := selectBySponsor(table, sponsor, page)
+		sql, err := selectBySponsor(ctx, table, sponsor, page)
 		if err != nil {
 			return sql, errors.Wrap(err, "could not construct account id query")
 		}
@@ -216,7 +218,7 @@ func selectUnionBySponsor(tables []string, sponsor string, page db2.PageQuery) (
 	return sq.
 		Select("accounts.*").
 		FromSelect(selectIDs, "accountSet").
-		Join("accounts ON accounts.account_id = accountSet.account_id").
+		Join(stateTable(ctx, "accounts", "accounts") + " ON accounts.account_id = accountSet.account_id").
 		OrderBy("accounts.account_id " + page.Order).
 		Limit(page.Limit), nil
 }
@@ -225,6 +227,7 @@ func selectUnionBySponsor(tables []string, sponsor string, page db2.PageQuery) (
 // any of its subentries (trust lines, signers, data, or account entry)
 func (q *Q) AccountsForSponsor(ctx context.Context, sponsor string, page db2.PageQuery) ([]AccountEntry, error) {
 	sql, err := selectUnionBySponsor(
+		ctx,
 		[]string{"accounts", "accounts_data", "accounts_signers", "trust_lines"},
 		sponsor,
 		page,
@@ -245,8 +248,8 @@ func (q *Q) AccountsForSponsor(ctx context.Context, sponsor string, page db2.Pag
 func (q *Q) AccountEntriesForSigner(ctx context.Context, signer string, page db2.PageQuery) ([]AccountEntry, error) {
 	sql := sq.
 		Select("accounts.*").
-		From("accounts").
-		Join("accounts_signers ON accounts.account_id = accounts_signers.account_id").
+		From(stateTable(ctx, "accounts", "accounts")).
+		Join(stateTable(ctx, "accounts_signers", "accounts_signers") + " ON accounts.account_id = accounts_signers.account_id").
 		Where(map[string]interface{}{
 			"accounts_signers.signer": signer,
 		})
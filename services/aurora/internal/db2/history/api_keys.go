@@ -0,0 +1,159 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/diamnet/go/support/errors"
+)
+
+// RateLimitTier is a row of data from the `rate_limit_tiers` table. A quota
+// of 0 is unlimited.
+type RateLimitTier struct {
+	Name            string `db:"name"`
+	RequestsPerHour int64  `db:"requests_per_hour"`
+	StreamsPerHour  int64  `db:"streams_per_hour"`
+}
+
+// APIKey is a row of data from the `api_keys` table joined with the quotas
+// of the tier of the key. Only the hash of the key is stored.
+type APIKey struct {
+	ID              int64     `db:"id"`
+	KeyHash         string    `db:"key_hash"`
+	Tier            string    `db:"tier"`
+	Description     string    `db:"description"`
+	CreatedAt       time.Time `db:"created_at"`
+	RequestsPerHour int64     `db:"requests_per_hour"`
+	StreamsPerHour  int64     `db:"streams_per_hour"`
+}
+
+// QAPIKeys defines API key and rate limit tier related queries.
+type QAPIKeys interface {
+	UpsertRateLimitTier(ctx context.Context, tier RateLimitTier) error
+	GetRateLimitTiers(ctx context.Context) ([]RateLimitTier, error)
+	GetRateLimitTierByName(ctx context.Context, name string) (RateLimitTier, error)
+	RemoveRateLimitTier(ctx context.Context, name string) (int64, error)
+	InsertAPIKey(ctx context.Context, key APIKey) (APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]APIKey, error)
+	GetAPIKeyByID(ctx context.Context, id int64) (APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+	RemoveAPIKey(ctx context.Context, id int64) (int64, error)
+}
+
+// UpsertRateLimitTier creates a rate limit tier or updates the quotas of an
+// existing one.
+func (q *Q) UpsertRateLimitTier(ctx context.Context, tier RateLimitTier) error {
+	sql := sq.Insert("rate_limit_tiers").
+		SetMap(map[string]interface{}{
+			"name":              tier.Name,
+			"requests_per_hour": tier.RequestsPerHour,
+			"streams_per_hour":  tier.StreamsPerHour,
+		}).
+		Suffix("ON CONFLICT (name) DO UPDATE SET " +
+			"requests_per_hour = excluded.requests_per_hour, " +
+			"streams_per_hour = excluded.streams_per_hour")
+	_, err := q.Exec(ctx, sql)
+	return err
+}
+
+// GetRateLimitTiers returns all rate limit tiers ordered by name.
+func (q *Q) GetRateLimitTiers(ctx context.Context) ([]RateLimitTier, error) {
+	var tiers []RateLimitTier
+	sql := selectRateLimitTiers.OrderBy("name asc")
+	err := q.Select(ctx, &tiers, sql)
+	return tiers, err
+}
+
+// GetRateLimitTierByName returns a rate limit tier by name.
+func (q *Q) GetRateLimitTierByName(ctx context.Context, name string) (RateLimitTier, error) {
+	var tier RateLimitTier
+	sql := selectRateLimitTiers.Where(sq.Eq{"name": name}).Limit(1)
+	err := q.Get(ctx, &tier, sql)
+	return tier, err
+}
+
+// RemoveRateLimitTier deletes a rate limit tier which is not assigned to any
+// API key. Returns number of rows affected and error.
+func (q *Q) RemoveRateLimitTier(ctx context.Context, name string) (int64, error) {
+	sql := sq.Delete("rate_limit_tiers").
+		Where(sq.Eq{"name": name}).
+		Where("NOT EXISTS (SELECT 1 FROM api_keys WHERE tier = ?)", name)
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// InsertAPIKey creates a new API key and returns it with the ID, creation
+// time and quotas populated.
+func (q *Q) InsertAPIKey(ctx context.Context, key APIKey) (APIKey, error) {
+	sql := sq.Insert("api_keys").
+		SetMap(map[string]interface{}{
+			"key_hash":    key.KeyHash,
+			"tier":        key.Tier,
+			"description": key.Description,
+		}).
+		Suffix("RETURNING id")
+
+	var id int64
+	if err := q.Get(ctx, &id, sql); err != nil {
+		return APIKey{}, errors.Wrap(err, "could not insert API key")
+	}
+
+	return q.GetAPIKeyByID(ctx, id)
+}
+
+// GetAPIKeys returns all API keys ordered by ID.
+func (q *Q) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	sql := selectAPIKeys.OrderBy("k.id asc")
+	err := q.Select(ctx, &keys, sql)
+	return keys, err
+}
+
+// GetAPIKeyByID returns an API key by ID.
+func (q *Q) GetAPIKeyByID(ctx context.Context, id int64) (APIKey, error) {
+	var key APIKey
+	sql := selectAPIKeys.Where(sq.Eq{"k.id": id}).Limit(1)
+	err := q.Get(ctx, &key, sql)
+	return key, err
+}
+
+// GetAPIKeyByHash returns the API key with the given hash.
+func (q *Q) GetAPIKeyByHash(ctx context.Context, hash string) (APIKey, error) {
+	var key APIKey
+	sql := selectAPIKeys.Where(sq.Eq{"k.key_hash": hash}).Limit(1)
+	err := q.Get(ctx, &key, sql)
+	return key, err
+}
+
+// RemoveAPIKey deletes an API key. Returns number of rows affected and error.
+func (q *Q) RemoveAPIKey(ctx context.Context, id int64) (int64, error) {
+	sql := sq.Delete("api_keys").Where(sq.Eq{"id": id})
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+var selectRateLimitTiers = sq.Select(
+	"name",
+	"requests_per_hour",
+	"streams_per_hour",
+).From("rate_limit_tiers")
+
+var selectAPIKeys = sq.Select(
+	"k.id",
+	"k.key_hash",
+	"k.tier",
+	"k.description",
+	"k.created_at",
+	"t.requests_per_hour",
+	"t.streams_per_hour",
+).From("api_keys k").Join("rate_limit_tiers t ON t.name = k.tier")
@@ -0,0 +1,72 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/diamnet/go/services/aurora/internal/test"
+)
+
+func TestAPIKeys(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	tt.Assert.NoError(q.UpsertRateLimitTier(tt.Ctx, RateLimitTier{
+		Name:            "partner",
+		RequestsPerHour: 1000,
+		StreamsPerHour:  100,
+	}))
+	tt.Assert.NoError(q.UpsertRateLimitTier(tt.Ctx, RateLimitTier{Name: "free", RequestsPerHour: 10}))
+
+	key, err := q.InsertAPIKey(tt.Ctx, APIKey{
+		KeyHash:     "7a3f",
+		Tier:        "partner",
+		Description: "anchor",
+	})
+	tt.Assert.NoError(err)
+	tt.Assert.NotZero(key.ID)
+	tt.Assert.Equal(int64(1000), key.RequestsPerHour)
+	tt.Assert.Equal(int64(100), key.StreamsPerHour)
+
+	_, err = q.InsertAPIKey(tt.Ctx, APIKey{KeyHash: "b21c", Tier: "unknown"})
+	tt.Assert.Error(err)
+
+	// Updating a tier updates the quotas of its keys
+	tt.Assert.NoError(q.UpsertRateLimitTier(tt.Ctx, RateLimitTier{
+		Name:            "partner",
+		RequestsPerHour: 5000,
+		StreamsPerHour:  100,
+	}))
+	loaded, err := q.GetAPIKeyByHash(tt.Ctx, "7a3f")
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(key.ID, loaded.ID)
+	tt.Assert.Equal("anchor", loaded.Description)
+	tt.Assert.Equal(int64(5000), loaded.RequestsPerHour)
+
+	tiers, err := q.GetRateLimitTiers(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]RateLimitTier{
+		{Name: "free", RequestsPerHour: 10},
+		{Name: "partner", RequestsPerHour: 5000, StreamsPerHour: 100},
+	}, tiers)
+
+	// Tiers assigned to keys cannot be removed
+	removed, err := q.RemoveRateLimitTier(tt.Ctx, "partner")
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(0), removed)
+
+	removed, err = q.RemoveAPIKey(tt.Ctx, key.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(1), removed)
+	_, err = q.GetAPIKeyByHash(tt.Ctx, "7a3f")
+	tt.Assert.True(q.NoRows(err))
+
+	removed, err = q.RemoveRateLimitTier(tt.Ctx, "partner")
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(1), removed)
+
+	keys, err := q.GetAPIKeys(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(keys)
+}
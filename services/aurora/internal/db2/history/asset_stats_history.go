@@ -0,0 +1,183 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+
+	"github.com/diamnet/go/services/aurora/internal/db2"
+	"github.com/diamnet/go/support/errors"
+	strtime "github.com/diamnet/go/support/time"
+	"github.com/diamnet/go/xdr"
+)
+
+// AssetStatsBucketResolution is the resolution, in milliseconds, of the
+// buckets of the history_asset_stats_86400000 table.
+const AssetStatsBucketResolution = int64(24 * time.Hour / time.Millisecond)
+
+// AllowedAssetStatsResolutions is the set of time windows allowed to be used as
+// the `resolution` of the asset stats aggregations. They are multiples of the
+// daily buckets.
+var AllowedAssetStatsResolutions = map[time.Duration]struct{}{
+	time.Hour * 24:     {}, //day
+	time.Hour * 24 * 7: {}, //week
+}
+
+// AssetStatsLedgerDelta is the activity of an asset in a single ledger.
+type AssetStatsLedgerDelta struct {
+	AssetID int64
+	// PaymentVolume is the sum, in stroops, of the amounts of the asset
+	// received by payments.
+	PaymentVolume string
+	TransferCount int64
+}
+
+// AssetStatsBucket represents the statistics of an asset over a period of
+// time. Holders, Trustlines and Supply are the values at the end of the
+// period, they are null if they are not known.
+type AssetStatsBucket struct {
+	Timestamp     int64       `db:"timestamp"`
+	PaymentVolume string      `db:"payment_volume"`
+	TransferCount int64       `db:"transfer_count"`
+	Holders       null.Int    `db:"holders"`
+	Trustlines    null.Int    `db:"trustlines"`
+	Supply        null.String `db:"supply"`
+}
+
+// QAssetStatsHistory defines the queries used to maintain the history of
+// the asset stats.
+type QAssetStatsHistory interface {
+	CreateAssets(ctx context.Context, assets []xdr.Asset, batchSize int) (map[string]Asset, error)
+	UpsertAssetStatsBuckets(
+		ctx context.Context,
+		ledger xdr.LedgerHeaderHistoryEntry,
+		deltas []AssetStatsLedgerDelta,
+		withSnapshots bool,
+	) error
+}
+
+// assetStatsSnapshotColumns selects the holders, trust lines and supply of an
+// asset from its exp_asset_stats row `s`. Assets without trust lines have no
+// row.
+const assetStatsSnapshotColumns = `COALESCE(s.num_accounts, 0),
+	COALESCE((s.accounts->>'authorized')::integer, 0) +
+		COALESCE((s.accounts->>'authorized_to_maintain_liabilities')::integer, 0) +
+		COALESCE((s.accounts->>'unauthorized')::integer, 0),
+	COALESCE(s.amount::numeric, 0)`
+
+// UpsertAssetStatsBuckets adds the activity of the given ledger to the daily
+// buckets of the assets. If withSnapshots is true the holders, trust lines
+// and supply of the buckets are set from the current exp_asset_stats, which
+// must be the state at the end of the ledger.
+//
+// Ledgers already covered by a bucket are skipped so reingesting a range does
+// not count its payments twice, which requires the ledgers of a bucket to be
+// ingested without gaps.
+func (q *Q) UpsertAssetStatsBuckets(
+	ctx context.Context,
+	ledger xdr.LedgerHeaderHistoryEntry,
+	deltas []AssetStatsLedgerDelta,
+	withSnapshots bool,
+) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	closedAt := int64(ledger.Header.ScpValue.CloseTime) * 1000
+	timestamp := closedAt / AssetStatsBucketResolution * AssetStatsBucketResolution
+	sequence := int32(ledger.Header.LedgerSeq)
+
+	snapshot := "NULL::integer, NULL::integer, NULL::numeric"
+	if withSnapshots {
+		snapshot = assetStatsSnapshotColumns
+	}
+	values := make([]string, 0, len(deltas))
+	args := []interface{}{timestamp, sequence, sequence}
+	for _, delta := range deltas {
+		values = append(values, "(?::bigint, ?::numeric, ?::integer)")
+		args = append(args, delta.AssetID, delta.PaymentVolume, delta.TransferCount)
+	}
+
+	sql := fmt.Sprintf(`INSERT INTO history_asset_stats_86400000 AS h (
+		asset_id, timestamp, first_ledger, last_ledger, payment_volume,
+		transfer_count, holders, trustlines, supply
+	)
+	SELECT d.asset_id, ?::bigint, ?::integer, ?::integer, d.payment_volume, d.transfer_count, %s
+	FROM (VALUES %s) AS d(asset_id, payment_volume, transfer_count)
+	JOIN history_assets a ON a.id = d.asset_id
+	LEFT JOIN exp_asset_stats s ON s.asset_code = a.asset_code AND s.asset_issuer = a.asset_issuer
+	ON CONFLICT (asset_id, timestamp) DO UPDATE SET
+		first_ledger = LEAST(h.first_ledger, excluded.first_ledger),
+		last_ledger = GREATEST(h.last_ledger, excluded.last_ledger),
+		payment_volume = h.payment_volume + excluded.payment_volume,
+		transfer_count = h.transfer_count + excluded.transfer_count,
+		holders = CASE WHEN excluded.last_ledger > h.last_ledger
+			THEN COALESCE(excluded.holders, h.holders) ELSE COALESCE(h.holders, excluded.holders) END,
+		trustlines = CASE WHEN excluded.last_ledger > h.last_ledger
+			THEN COALESCE(excluded.trustlines, h.trustlines) ELSE COALESCE(h.trustlines, excluded.trustlines) END,
+		supply = CASE WHEN excluded.last_ledger > h.last_ledger
+			THEN COALESCE(excluded.supply, h.supply) ELSE COALESCE(h.supply, excluded.supply) END
+	WHERE excluded.first_ledger < h.first_ledger OR excluded.last_ledger > h.last_ledger`,
+		snapshot,
+		strings.Join(values, ", "),
+	)
+	if _, err := q.ExecRaw(ctx, sql, args...); err != nil {
+		return errors.Wrap(err, "could not upsert asset stats buckets")
+	}
+	return nil
+}
+
+// GetAssetStatsBuckets returns a page of the statistics of an asset
+// aggregated by the given resolution. The start time is rounded up and the end
+// time rounded down to the resolution so partial buckets are not returned.
+func (q *Q) GetAssetStatsBuckets(
+	ctx context.Context,
+	assetID int64,
+	resolution int64,
+	startTime, endTime strtime.Millis,
+	page db2.PageQuery,
+) ([]AssetStatsBucket, error) {
+	if _, ok := AllowedAssetStatsResolutions[time.Duration(resolution)*time.Millisecond]; !ok {
+		return nil, errors.New("resolution is not allowed")
+	}
+
+	buckets := sq.Select(
+		"timestamp",
+		"payment_volume",
+		"transfer_count",
+		"holders",
+		"trustlines",
+		"supply",
+	).From("history_asset_stats_86400000").Where(sq.Eq{"asset_id": assetID})
+	if !startTime.IsNil() {
+		buckets = buckets.Where(sq.GtOrEq{"timestamp": startTime.RoundUp(resolution)})
+	}
+	if !endTime.IsNil() {
+		buckets = buckets.Where(sq.Lt{"timestamp": endTime.RoundDown(resolution)})
+	}
+
+	if resolution != AssetStatsBucketResolution {
+		// Snapshots are taken from the last daily bucket which has them.
+		buckets = sq.Select(
+			fmt.Sprintf("(timestamp / %d) * %d as timestamp", resolution, resolution),
+			"sum(payment_volume) as payment_volume",
+			"sum(transfer_count) as transfer_count",
+			"last(holders ORDER BY timestamp) as holders",
+			"last(trustlines ORDER BY timestamp) as trustlines",
+			"last(supply ORDER BY timestamp) as supply",
+		).FromSelect(buckets, "daily").GroupBy("1")
+	}
+
+	sql := sq.Select("*").FromSelect(buckets, "buckets").
+		OrderBy("timestamp " + page.Order).
+		Limit(page.Limit)
+	var records []AssetStatsBucket
+	if err := q.Select(ctx, &records, sql); err != nil {
+		return nil, errors.Wrap(err, "could not load asset stats buckets")
+	}
+	return records, nil
+}
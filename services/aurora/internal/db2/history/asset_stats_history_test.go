@@ -0,0 +1,110 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+
+	"github.com/diamnet/go/services/aurora/internal/db2"
+	"github.com/diamnet/go/services/aurora/internal/test"
+	strtime "github.com/diamnet/go/support/time"
+	"github.com/diamnet/go/xdr"
+)
+
+func TestAssetStatsBuckets(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	issuer := "GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU"
+	usd := xdr.MustNewCreditAsset("USD", issuer)
+	assets, err := q.CreateAssets(tt.Ctx, []xdr.Asset{usd}, 1)
+	tt.Assert.NoError(err)
+	assetID := assets[usd.String()].ID
+
+	_, err = q.InsertAssetStat(tt.Ctx, ExpAssetStat{
+		AssetType:   xdr.AssetTypeAssetTypeCreditAlphanum4,
+		AssetCode:   "USD",
+		AssetIssuer: issuer,
+		Accounts:    ExpAssetStatAccounts{Authorized: 2, Unauthorized: 1},
+		Balances: ExpAssetStatBalances{
+			Authorized:                      "300",
+			AuthorizedToMaintainLiabilities: "0",
+			Unauthorized:                    "10",
+			ClaimableBalances:               "0",
+			LiquidityPools:                  "0",
+		},
+		Amount:      "300",
+		NumAccounts: 2,
+	})
+	tt.Assert.NoError(err)
+
+	day := AssetStatsBucketResolution
+	ledger := func(sequence uint32, closedAt int64) xdr.LedgerHeaderHistoryEntry {
+		return xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{
+			LedgerSeq: xdr.Uint32(sequence),
+			ScpValue:  xdr.DiamnetValue{CloseTime: xdr.TimePoint(closedAt / 1000)},
+		}}
+	}
+	upsert := func(sequence uint32, closedAt int64, volume string, withSnapshots bool) {
+		tt.Assert.NoError(q.UpsertAssetStatsBuckets(
+			tt.Ctx,
+			ledger(sequence, closedAt),
+			[]AssetStatsLedgerDelta{{AssetID: assetID, PaymentVolume: volume, TransferCount: 1}},
+			withSnapshots,
+		))
+	}
+
+	// Ledgers 10 and 11 are reingested, ledger 12 is ingested with the state
+	upsert(11, day+2000, "20", false)
+	upsert(12, day+3000, "30", true)
+	upsert(10, day+1000, "10", false)
+	upsert(11, day+2000, "20", false)
+	upsert(13, 8*day, "40", true)
+
+	page := db2.PageQuery{Order: db2.OrderAscending, Limit: 10}
+	buckets, err := q.GetAssetStatsBuckets(tt.Ctx, assetID, day, strtime.Millis(0), strtime.Millis(0), page)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]AssetStatsBucket{
+		{
+			Timestamp:     day,
+			PaymentVolume: "60",
+			TransferCount: 3,
+			Holders:       null.IntFrom(2),
+			Trustlines:    null.IntFrom(3),
+			Supply:        null.StringFrom("300"),
+		},
+		{
+			Timestamp:     8 * day,
+			PaymentVolume: "40",
+			TransferCount: 1,
+			Holders:       null.IntFrom(2),
+			Trustlines:    null.IntFrom(3),
+			Supply:        null.StringFrom("300"),
+		},
+	}, buckets)
+
+	week := 7 * day
+	buckets, err = q.GetAssetStatsBuckets(tt.Ctx, assetID, week, strtime.Millis(0), strtime.Millis(0), page)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(buckets, 2)
+	tt.Assert.Equal(int64(0), buckets[0].Timestamp)
+	tt.Assert.Equal("60", buckets[0].PaymentVolume)
+	tt.Assert.Equal(week, buckets[1].Timestamp)
+
+	buckets, err = q.GetAssetStatsBuckets(
+		tt.Ctx,
+		assetID,
+		day,
+		strtime.MillisFromInt64(day+1),
+		strtime.Millis(0),
+		db2.PageQuery{Order: db2.OrderDescending, Limit: 10},
+	)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(buckets, 1)
+	tt.Assert.Equal(8*day, buckets[0].Timestamp)
+
+	_, err = q.GetAssetStatsBuckets(tt.Ctx, assetID, 1000, strtime.Millis(0), strtime.Millis(0), page)
+	tt.Assert.EqualError(err, "resolution is not allowed")
+}
@@ -0,0 +1,172 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// BalanceChange is the net change, in stroops, of the balance of an asset
+// held by an account in a single ledger.
+type BalanceChange struct {
+	LedgerSequence int32 `db:"ledger"`
+	Amount         int64 `db:"amount"`
+}
+
+// BalanceChangeAfterLedger returns the net change of the balance of `asset`
+// held by `account` in all the ledgers after `ledger`.
+func (q *Q) BalanceChangeAfterLedger(ctx context.Context, account string, asset xdr.Asset, ledger int32) (int64, error) {
+	deltas, args, err := balanceDeltas(account, asset, ledger, math.MaxInt32)
+	if err != nil {
+		return 0, err
+	}
+
+	var change int64
+	err = q.GetRaw(ctx, &change, "SELECT COALESCE(SUM(d.amount), 0)::bigint FROM ("+deltas+") d", args...)
+	return change, err
+}
+
+// BalanceChangesInRange returns the net change of the balance of `asset` held
+// by `account` in every ledger in the range (`from`, `to`] in which the
+// balance changed, ordered by ledger sequence.
+func (q *Q) BalanceChangesInRange(ctx context.Context, account string, asset xdr.Asset, from, to int32) ([]BalanceChange, error) {
+	deltas, args, err := balanceDeltas(account, asset, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []BalanceChange
+	err = q.SelectRaw(ctx, &changes, `
+		SELECT d.ledger, SUM(d.amount)::bigint AS amount
+		FROM (`+deltas+`) d
+		GROUP BY d.ledger
+		HAVING SUM(d.amount) <> 0
+		ORDER BY d.ledger`,
+		args...,
+	)
+	return changes, err
+}
+
+// LastLedgersByResolution splits the ledgers in the range [`from`, `to`] into
+// buckets of `resolution` milliseconds, based on their close time, and returns
+// the sequence of the last ledger closed in every bucket.
+func (q *Q) LastLedgersByResolution(ctx context.Context, from, to int32, resolution int64) ([]int32, error) {
+	if resolution <= 0 {
+		return nil, errors.New("resolution must be positive")
+	}
+
+	var sequences []int32
+	err := q.SelectRaw(ctx, &sequences, `
+		SELECT MAX(sequence)
+		FROM history_ledgers
+		WHERE sequence BETWEEN ? AND ?
+		GROUP BY floor(extract(epoch from closed_at) * 1000 / ?)
+		ORDER BY 1`,
+		from, to, resolution,
+	)
+	return sequences, err
+}
+
+// balanceDeltas builds a query returning every change of the balance of
+// `asset` held by `account` in the ledgers (`after`, `upTo`]. Changes are read
+// from the credited, debited, account created, trade and liquidity pool
+// effects of the account. Transaction fees are included for the native asset.
+//
+// The source of a path payment gets trade effects for every offer and
+// liquidity pool it crossed, but the payment already debits the source asset
+// from it with an account_debited effect, so those trade effects are skipped.
+// The trade effects of the offer owners are kept.
+func balanceDeltas(account string, asset xdr.Asset, after, upTo int32) (string, []interface{}, error) {
+	var assetType, code, issuer string
+	if err := asset.Extract(&assetType, &code, &issuer); err != nil {
+		return "", nil, errors.Wrap(err, "could not extract asset")
+	}
+	// Effects of operations in ledgers (after, upTo]
+	fromID, toID := toid.AfterLedger(after).ToInt64(), toid.AfterLedger(upTo).ToInt64()
+
+	assetFilter := func(prefix string) (string, []interface{}) {
+		if asset.Type == xdr.AssetTypeAssetTypeNative {
+			return fmt.Sprintf("heff.details->>'%sasset_type' = ?", prefix), []interface{}{assetType}
+		}
+		return fmt.Sprintf(
+			"heff.details->>'%[1]sasset_type' = ? AND heff.details->>'%[1]sasset_code' = ? AND heff.details->>'%[1]sasset_issuer' = ?",
+			prefix,
+		), []interface{}{assetType, code, issuer}
+	}
+	// Liquidity pool effects hold assets in their canonical form
+	canonical := asset.StringCanonical()
+	notPathPaymentSource := `NOT EXISTS (
+				SELECT 1 FROM history_operations hop
+				WHERE hop.id = heff.history_operation_id
+				AND hop.type IN (?, ?)
+				AND hop.source_account = ?
+			)`
+	notPathPaymentSourceArgs := []interface{}{
+		int(xdr.OperationTypePathPaymentStrictReceive),
+		int(xdr.OperationTypePathPaymentStrictSend),
+		account,
+	}
+
+	var parts []string
+	var args []interface{}
+	// addEffects adds the effects of type effectType. join is added to the
+	// FROM clause, it may not have any arguments.
+	addEffects := func(effectType EffectType, amount, join, filter string, filterArgs ...interface{}) {
+		parts = append(parts, `
+			SELECT (heff.history_operation_id >> 32)::integer AS ledger,
+				round((`+amount+`)::numeric * 10000000)::bigint AS amount
+			FROM history_effects heff`+join+`
+			WHERE heff.history_account_id = (SELECT id FROM history_accounts WHERE address = ?)
+			AND heff.history_operation_id > ? AND heff.history_operation_id <= ?
+			AND heff.type = ?
+			AND `+filter)
+		args = append(args, account, fromID, toID, int(effectType))
+		args = append(args, filterArgs...)
+	}
+
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		// account_created effects only carry the native starting balance
+		addEffects(EffectAccountCreated, "heff.details->>'starting_balance'", "", "TRUE")
+	}
+	filter, filterArgs := assetFilter("")
+	addEffects(EffectAccountCredited, "heff.details->>'amount'", "", filter, filterArgs...)
+	addEffects(EffectAccountDebited, "-(heff.details->>'amount')::numeric", "", filter, filterArgs...)
+
+	filter, filterArgs = assetFilter("bought_")
+	addEffects(EffectTrade, "heff.details->>'bought_amount'", "",
+		filter+" AND "+notPathPaymentSource, append(filterArgs, notPathPaymentSourceArgs...)...)
+	filter, filterArgs = assetFilter("sold_")
+	addEffects(EffectTrade, "-(heff.details->>'sold_amount')::numeric", "",
+		filter+" AND "+notPathPaymentSource, append(filterArgs, notPathPaymentSourceArgs...)...)
+
+	// The pool sells the `sold` asset to the account and buys the `bought`
+	// asset from it.
+	addEffects(EffectLiquidityPoolTrade, "heff.details->'sold'->>'amount'", "",
+		"heff.details->'sold'->>'asset' = ? AND "+notPathPaymentSource,
+		append([]interface{}{canonical}, notPathPaymentSourceArgs...)...)
+	addEffects(EffectLiquidityPoolTrade, "-(heff.details->'bought'->>'amount')::numeric", "",
+		"heff.details->'bought'->>'asset' = ? AND "+notPathPaymentSource,
+		append([]interface{}{canonical}, notPathPaymentSourceArgs...)...)
+	addEffects(EffectLiquidityPoolDeposited, "-(reserve->>'amount')::numeric",
+		", jsonb_array_elements(heff.details->'reserves_deposited') reserve",
+		"reserve->>'asset' = ?", canonical)
+	addEffects(EffectLiquidityPoolWithdrew, "reserve->>'amount'",
+		", jsonb_array_elements(heff.details->'reserves_received') reserve",
+		"reserve->>'asset' = ?", canonical)
+
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		parts = append(parts, `
+			SELECT ht.ledger_sequence AS ledger, -ht.fee_charged AS amount
+			FROM history_transactions ht
+			WHERE (ht.fee_account = ? OR (ht.fee_account IS NULL AND ht.account = ?))
+			AND ht.ledger_sequence > ? AND ht.ledger_sequence <= ?`)
+		args = append(args, account, account, after, upTo)
+	}
+
+	return strings.Join(parts, "\n\t\t\tUNION ALL"), args, nil
+}
@@ -0,0 +1,88 @@
+package history
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/diamnet/go/services/aurora/internal/test"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/xdr"
+	"github.com/guregu/null"
+)
+
+func TestBalanceChanges(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	address := "GAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSTVY"
+	issuer := "GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU"
+	accountIDs, err := q.CreateAccounts(tt.Ctx, []string{address}, 1)
+	tt.Assert.NoError(err)
+
+	builder := q.NewEffectBatchInsertBuilder(10)
+	order := int32(1)
+	add := func(ledger int32, effectType EffectType, details map[string]string) {
+		encoded, err := json.Marshal(details)
+		tt.Assert.NoError(err)
+		tt.Assert.NoError(builder.Add(tt.Ctx,
+			accountIDs[address],
+			null.String{},
+			toid.New(ledger, 1, 1).ToInt64(),
+			uint32(order),
+			effectType,
+			encoded,
+		))
+		order++
+	}
+
+	add(2, EffectAccountCreated, map[string]string{"starting_balance": "100.0000000"})
+	add(3, EffectAccountDebited, map[string]string{"amount": "10.5000000", "asset_type": "native"})
+	add(3, EffectAccountCredited, map[string]string{
+		"amount":       "20.0000000",
+		"asset_type":   "credit_alphanum4",
+		"asset_code":   "USD",
+		"asset_issuer": issuer,
+	})
+	add(5, EffectTrade, map[string]string{
+		"bought_amount":       "5.0000000",
+		"bought_asset_type":   "credit_alphanum4",
+		"bought_asset_code":   "USD",
+		"bought_asset_issuer": issuer,
+		"sold_amount":         "2.0000000",
+		"sold_asset_type":     "native",
+	})
+	add(7, EffectAccountCredited, map[string]string{"amount": "1.0000000", "asset_type": "native"})
+	tt.Assert.NoError(builder.Exec(tt.Ctx))
+
+	native := xdr.MustNewNativeAsset()
+	usd := xdr.MustNewCreditAsset("USD", issuer)
+
+	changes, err := q.BalanceChangesInRange(tt.Ctx, address, native, 1, 5)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]BalanceChange{
+		{LedgerSequence: 2, Amount: 1000000000},
+		{LedgerSequence: 3, Amount: -105000000},
+		{LedgerSequence: 5, Amount: -20000000},
+	}, changes)
+
+	changes, err = q.BalanceChangesInRange(tt.Ctx, address, native, 3, 5)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]BalanceChange{{LedgerSequence: 5, Amount: -20000000}}, changes)
+
+	changes, err = q.BalanceChangesInRange(tt.Ctx, address, usd, 1, 10)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]BalanceChange{
+		{LedgerSequence: 3, Amount: 200000000},
+		{LedgerSequence: 5, Amount: 50000000},
+	}, changes)
+
+	change, err := q.BalanceChangeAfterLedger(tt.Ctx, address, native, 3)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(-10000000), change)
+
+	change, err = q.BalanceChangeAfterLedger(tt.Ctx, address, usd, 5)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(0), change)
+}
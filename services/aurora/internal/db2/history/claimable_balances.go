@@ -189,14 +189,19 @@ func (q *Q) RemoveClaimableBalances(ctx context.Context, ids []string) (int64, e
 // FindClaimableBalanceByID returns a claimable balance.
 func (q *Q) FindClaimableBalanceByID(ctx context.Context, balanceID string) (ClaimableBalance, error) {
 	var claimableBalance ClaimableBalance
-	sql := selectClaimableBalances.Limit(1).Where("cb.id = ?", balanceID)
+	sql := selectClaimableBalances.
+		From(stateTable(ctx, "claimable_balances", "cb")).
+		Limit(1).
+		Where("cb.id = ?", balanceID)
 	err := q.Get(ctx, &claimableBalance, sql)
 	return claimableBalance, err
 }
 
 // GetClaimableBalances finds all claimable balances where accountID is one of the claimants
 func (q *Q) GetClaimableBalances(ctx context.Context, query ClaimableBalancesQuery) ([]ClaimableBalance, error) {
-	sql, err := query.ApplyCursor(selectClaimableBalances)
+	sql, err := query.ApplyCursor(
+		selectClaimableBalances.From(stateTable(ctx, "claimable_balances", "cb")),
+	)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not apply query to page")
 	}
@@ -0,0 +1,38 @@
+package history
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// IndexColdTransactions records the ledgers of the transactions with a total
+// order ID between start (inclusive) and end (exclusive), by hash and inner
+// transaction hash, in the `history_cold_transactions` table. It is used
+// before the transactions are reaped so that they can be found in the cold
+// storage.
+func (q *Q) IndexColdTransactions(ctx context.Context, start, end int64) error {
+	_, err := q.ExecRaw(
+		ctx,
+		`INSERT INTO history_cold_transactions (transaction_hash, ledger_sequence)
+		SELECT transaction_hash, ledger_sequence FROM history_transactions
+		WHERE id >= ? AND id < ?
+		UNION ALL
+		SELECT inner_transaction_hash, ledger_sequence FROM history_transactions
+		WHERE id >= ? AND id < ? AND inner_transaction_hash IS NOT NULL
+		ON CONFLICT (transaction_hash) DO NOTHING`,
+		start, end, start, end,
+	)
+	return err
+}
+
+// ColdTransactionLedger returns the ledger of a reaped transaction recorded
+// by IndexColdTransactions.
+func (q *Q) ColdTransactionLedger(ctx context.Context, hash string) (int32, error) {
+	var sequence int32
+	sql := sq.Select("ledger_sequence").
+		From("history_cold_transactions").
+		Where(sq.Eq{"transaction_hash": hash})
+	err := q.Get(ctx, &sequence, sql)
+	return sequence, err
+}
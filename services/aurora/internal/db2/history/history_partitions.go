@@ -0,0 +1,326 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/support/errors"
+)
+
+const (
+	historyPartitionSizeKey = "history_partition_size"
+	// historyPartitionsLockID is the first key of the advisory locks taken
+	// while creating partitions, the second key is the first ledger of the
+	// partition.
+	historyPartitionsLockID = 0x68697374
+)
+
+// HistoryPartitionedTables maps the history tables which are partitioned by
+// ledger range, once PartitionHistoryTables has been run, to their partition
+// key: a total order ID column.
+var HistoryPartitionedTables = map[string]string{
+	"history_effects":      "history_operation_id",
+	"history_operations":   "id",
+	"history_transactions": "id",
+}
+
+// HistoryPartition is a row of data from the `history_partitions` table. The
+// partition contains the rows of the ledgers from StartSequence (inclusive) to
+// EndSequence (exclusive).
+type HistoryPartition struct {
+	PartitionName string `db:"partition_name"`
+	TableName     string `db:"table_name"`
+	StartSequence uint32 `db:"start_ledger"`
+	EndSequence   uint32 `db:"end_ledger"`
+}
+
+// QHistoryPartitions defines the queries used by ingestion to create the
+// partitions of the history tables.
+type QHistoryPartitions interface {
+	CreateHistoryPartitions(ctx context.Context, size, sequence uint32) error
+}
+
+// GetHistoryPartitionSize returns the number of ledgers in every partition of
+// the history tables, or 0 if the history tables are not partitioned.
+func (q *Q) GetHistoryPartitionSize(ctx context.Context) (uint32, error) {
+	value, err := q.getValueFromStore(ctx, historyPartitionSizeKey, false)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	size, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, errors.Wrap(err, "Error converting history partition size value")
+	}
+	return uint32(size), nil
+}
+
+// GetHistoryPartitions returns the partitions of the history tables ordered by
+// table and ledger sequence.
+func (q *Q) GetHistoryPartitions(ctx context.Context) ([]HistoryPartition, error) {
+	var partitions []HistoryPartition
+	sql := selectHistoryPartitions.OrderBy("table_name asc", "start_ledger asc")
+	err := q.Select(ctx, &partitions, sql)
+	return partitions, err
+}
+
+// PartitionHistoryTables converts the tables in HistoryPartitionedTables into
+// tables partitioned by ranges of size ledgers. The existing rows are kept in
+// a single `<table>_legacy` partition containing the ledgers before boundary,
+// which must be a multiple of size. Later partitions are created by
+// CreateHistoryPartitions.
+//
+// It must be called in a transaction while Aurora is stopped: the existing
+// tables are locked and scanned while they are attached to the partitioned
+// tables.
+func (q *Q) PartitionHistoryTables(ctx context.Context, size, boundary uint32) error {
+	if size == 0 {
+		return errors.New("partition size must be positive")
+	}
+	if boundary%size != 0 {
+		return errors.Errorf("boundary %d is not a multiple of the partition size %d", boundary, size)
+	}
+	currentSize, err := q.GetHistoryPartitionSize(ctx)
+	if err != nil {
+		return err
+	}
+	if currentSize != 0 {
+		return errors.Errorf("history tables are already partitioned in partitions of %d ledgers", currentSize)
+	}
+
+	for _, table := range historyPartitionedTableNames() {
+		if err := q.partitionHistoryTable(ctx, table, HistoryPartitionedTables[table], boundary); err != nil {
+			return errors.Wrapf(err, "could not partition %s", table)
+		}
+	}
+	return q.updateValueInStore(ctx, historyPartitionSizeKey, strconv.FormatUint(uint64(size), 10))
+}
+
+func (q *Q) partitionHistoryTable(ctx context.Context, table, column string, boundary uint32) error {
+	var indexes []struct {
+		Name       string `db:"indexname"`
+		Definition string `db:"indexdef"`
+	}
+	err := q.SelectRaw(
+		ctx,
+		&indexes,
+		"SELECT indexname, indexdef FROM pg_indexes WHERE schemaname = current_schema() AND tablename = ?",
+		table,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not load indexes")
+	}
+	var checks []struct {
+		Name       string `db:"conname"`
+		Definition string `db:"definition"`
+		Validated  bool   `db:"convalidated"`
+	}
+	err = q.SelectRaw(
+		ctx,
+		&checks,
+		"SELECT conname, pg_get_constraintdef(oid) AS definition, convalidated FROM pg_constraint "+
+			"WHERE conrelid = ?::regclass AND contype = 'c'",
+		table,
+	)
+	if err != nil {
+		return errors.Wrap(err, "could not load check constraints")
+	}
+
+	legacy := table + "_legacy"
+	statements := []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", pq.QuoteIdentifier(table), pq.QuoteIdentifier(legacy)),
+	}
+	// The indexes of the legacy table are renamed so that the indexes of the
+	// partitioned table keep their names. They are attached to the indexes of
+	// the partitioned table, instead of being built again, because they have
+	// the same definition.
+	for _, index := range indexes {
+		statements = append(statements, fmt.Sprintf(
+			"ALTER INDEX %s RENAME TO %s",
+			pq.QuoteIdentifier(index.Name), pq.QuoteIdentifier(index.Name+"_legacy"),
+		))
+	}
+	statements = append(statements, fmt.Sprintf(
+		"CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS) PARTITION BY RANGE (%s)",
+		pq.QuoteIdentifier(table), pq.QuoteIdentifier(legacy), pq.QuoteIdentifier(column),
+	))
+	// A partition must have the check constraints of the partitioned table
+	// and they must be valid.
+	for _, check := range checks {
+		if !check.Validated {
+			statements = append(statements, fmt.Sprintf(
+				"ALTER TABLE %s VALIDATE CONSTRAINT %s", pq.QuoteIdentifier(legacy), pq.QuoteIdentifier(check.Name),
+			))
+		}
+		statements = append(statements, fmt.Sprintf(
+			"ALTER TABLE %s ADD CONSTRAINT %s %s",
+			pq.QuoteIdentifier(table), pq.QuoteIdentifier(check.Name), strings.TrimSuffix(check.Definition, " NOT VALID"),
+		))
+	}
+	statements = append(statements, fmt.Sprintf(
+		"ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (MINVALUE) TO (%d)",
+		pq.QuoteIdentifier(table), pq.QuoteIdentifier(legacy), ledgerTOID(boundary),
+	))
+	for _, index := range indexes {
+		statements = append(statements, index.Definition)
+	}
+
+	for _, statement := range statements {
+		// Definitions can contain the ? operator which must not be replaced
+		// by a placeholder
+		if _, err := q.ExecRaw(ctx, strings.ReplaceAll(statement, "?", "??")); err != nil {
+			return errors.Wrapf(err, "could not execute %q", statement)
+		}
+	}
+
+	return q.insertHistoryPartition(ctx, HistoryPartition{
+		PartitionName: legacy,
+		TableName:     table,
+		StartSequence: 0,
+		EndSequence:   boundary,
+	})
+}
+
+// CreateHistoryPartitions creates the partitions of size ledgers containing
+// the given ledger in the partitioned history tables which don't have one. It
+// does nothing if the history tables are not partitioned.
+//
+// Creating a partition locks the partitioned table until the transaction is
+// committed.
+func (q *Q) CreateHistoryPartitions(ctx context.Context, size, sequence uint32) error {
+	missing, err := q.missingHistoryPartitions(ctx, sequence)
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+
+	start := sequence / size * size
+	// Reingestion workers can create the same partition concurrently
+	_, err = q.ExecRaw(ctx, "SELECT pg_advisory_xact_lock(?, ?)", historyPartitionsLockID, int32(start))
+	if err != nil {
+		return errors.Wrap(err, "could not lock history partitions")
+	}
+	missing, err = q.missingHistoryPartitions(ctx, sequence)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range missing {
+		partition := HistoryPartition{
+			PartitionName: fmt.Sprintf("%s_p%d", table, start),
+			TableName:     table,
+			StartSequence: start,
+			EndSequence:   start + size,
+		}
+		_, err = q.ExecRaw(ctx, fmt.Sprintf(
+			"CREATE TABLE %s PARTITION OF %s FOR VALUES FROM (%d) TO (%d)",
+			pq.QuoteIdentifier(partition.PartitionName),
+			pq.QuoteIdentifier(table),
+			ledgerTOID(partition.StartSequence),
+			ledgerTOID(partition.EndSequence),
+		))
+		if err != nil {
+			return errors.Wrapf(err, "could not create partition %s", partition.PartitionName)
+		}
+		if err = q.insertHistoryPartition(ctx, partition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropHistoryPartitionsBefore drops the partitions of the history tables which
+// only contain ledgers before the given ledger, and returns them.
+func (q *Q) DropHistoryPartitionsBefore(ctx context.Context, sequence uint32) ([]HistoryPartition, error) {
+	var partitions []HistoryPartition
+	sql := selectHistoryPartitions.
+		Where(sq.LtOrEq{"end_ledger": sequence}).
+		OrderBy("start_ledger asc", "table_name asc")
+	if err := q.Select(ctx, &partitions, sql); err != nil {
+		return nil, errors.Wrap(err, "could not load history partitions")
+	}
+
+	for _, partition := range partitions {
+		if _, err := q.ExecRaw(ctx, "DROP TABLE "+pq.QuoteIdentifier(partition.PartitionName)); err != nil {
+			return nil, errors.Wrapf(err, "could not drop partition %s", partition.PartitionName)
+		}
+		del := sq.Delete("history_partitions").Where(sq.Eq{"partition_name": partition.PartitionName})
+		if _, err := q.Exec(ctx, del); err != nil {
+			return nil, errors.Wrapf(err, "could not delete partition %s", partition.PartitionName)
+		}
+	}
+	return partitions, nil
+}
+
+// missingHistoryPartitions returns the partitioned history tables without a
+// partition containing the given ledger.
+func (q *Q) missingHistoryPartitions(ctx context.Context, sequence uint32) ([]string, error) {
+	var found []string
+	sql := sq.Select("table_name").From("history_partitions").Where(
+		sq.And{sq.LtOrEq{"start_ledger": sequence}, sq.Gt{"end_ledger": sequence}},
+	)
+	if err := q.Select(ctx, &found, sql); err != nil {
+		return nil, errors.Wrap(err, "could not load history partitions")
+	}
+	// The history tables are not partitioned
+	if len(found) == 0 {
+		var count int
+		if err := q.Get(ctx, &count, sq.Select("count(*)").From("history_partitions")); err != nil {
+			return nil, errors.Wrap(err, "could not count history partitions")
+		}
+		if count == 0 {
+			return nil, nil
+		}
+	}
+
+	var missing []string
+	for _, table := range historyPartitionedTableNames() {
+		exists := false
+		for _, name := range found {
+			exists = exists || name == table
+		}
+		if !exists {
+			missing = append(missing, table)
+		}
+	}
+	return missing, nil
+}
+
+func (q *Q) insertHistoryPartition(ctx context.Context, partition HistoryPartition) error {
+	sql := sq.Insert("history_partitions").SetMap(map[string]interface{}{
+		"partition_name": partition.PartitionName,
+		"table_name":     partition.TableName,
+		"start_ledger":   partition.StartSequence,
+		"end_ledger":     partition.EndSequence,
+	})
+	if _, err := q.Exec(ctx, sql); err != nil {
+		return errors.Wrapf(err, "could not insert partition %s", partition.PartitionName)
+	}
+	return nil
+}
+
+func historyPartitionedTableNames() []string {
+	tables := make([]string, 0, len(HistoryPartitionedTables))
+	for table := range HistoryPartitionedTables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// ledgerTOID returns the lowest total order ID of the given ledger.
+func ledgerTOID(sequence uint32) int64 {
+	return toid.New(int32(sequence), 0, 0).ToInt64()
+}
+
+var selectHistoryPartitions = sq.Select(
+	"partition_name",
+	"table_name",
+	"start_ledger",
+	"end_ledger",
+).From("history_partitions")
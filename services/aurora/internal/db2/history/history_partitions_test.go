@@ -0,0 +1,63 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/diamnet/go/services/aurora/internal/test"
+)
+
+func TestHistoryPartitions(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	// The tables are converted back when the transaction is rolled back
+	tt.Assert.NoError(q.Begin())
+	defer q.Rollback()
+
+	// Not partitioned yet
+	tt.Assert.NoError(q.CreateHistoryPartitions(tt.Ctx, 100, 250))
+	size, err := q.GetHistoryPartitionSize(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(uint32(0), size)
+
+	tt.Assert.EqualError(
+		q.PartitionHistoryTables(tt.Ctx, 100, 150),
+		"boundary 150 is not a multiple of the partition size 100",
+	)
+	tt.Assert.NoError(q.PartitionHistoryTables(tt.Ctx, 100, 200))
+	size, err = q.GetHistoryPartitionSize(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(uint32(100), size)
+	tt.Assert.EqualError(
+		q.PartitionHistoryTables(tt.Ctx, 100, 200),
+		"history tables are already partitioned in partitions of 100 ledgers",
+	)
+
+	tt.Assert.NoError(q.CreateHistoryPartitions(tt.Ctx, 100, 150))
+	tt.Assert.NoError(q.CreateHistoryPartitions(tt.Ctx, 100, 250))
+	tt.Assert.NoError(q.CreateHistoryPartitions(tt.Ctx, 100, 299))
+	partitions, err := q.GetHistoryPartitions(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]HistoryPartition{
+		{PartitionName: "history_effects_legacy", TableName: "history_effects", StartSequence: 0, EndSequence: 200},
+		{PartitionName: "history_effects_p200", TableName: "history_effects", StartSequence: 200, EndSequence: 300},
+		{PartitionName: "history_operations_legacy", TableName: "history_operations", StartSequence: 0, EndSequence: 200},
+		{PartitionName: "history_operations_p200", TableName: "history_operations", StartSequence: 200, EndSequence: 300},
+		{PartitionName: "history_transactions_legacy", TableName: "history_transactions", StartSequence: 0, EndSequence: 200},
+		{PartitionName: "history_transactions_p200", TableName: "history_transactions", StartSequence: 200, EndSequence: 300},
+	}, partitions)
+
+	dropped, err := q.DropHistoryPartitionsBefore(tt.Ctx, 250)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]HistoryPartition{partitions[0], partitions[2], partitions[4]}, dropped)
+	partitions, err = q.GetHistoryPartitions(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(partitions, 3)
+	tt.Assert.Equal("history_effects_p200", partitions[0].PartitionName)
+
+	var tables int
+	tt.Assert.NoError(q.GetRaw(tt.Ctx, &tables, "SELECT count(*) FROM pg_tables WHERE tablename LIKE '%_legacy'"))
+	tt.Assert.Equal(0, tables)
+}
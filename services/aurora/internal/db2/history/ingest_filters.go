@@ -0,0 +1,75 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// IngestFilter is a row of data from the `ingest_filters` table, it contains
+// the values allowed by one of the transaction filters of ingestion.
+type IngestFilter struct {
+	Name      string         `db:"name"`
+	Allowed   pq.StringArray `db:"allowed"`
+	UpdatedAt time.Time      `db:"updated_at"`
+}
+
+// QIngestFilters defines ingestion filters related queries.
+type QIngestFilters interface {
+	GetIngestFilters(ctx context.Context) ([]IngestFilter, error)
+}
+
+// GetIngestFilters returns all ingestion filters ordered by name.
+func (q *Q) GetIngestFilters(ctx context.Context) ([]IngestFilter, error) {
+	var filters []IngestFilter
+	sql := selectIngestFilters.OrderBy("name asc")
+	err := q.Select(ctx, &filters, sql)
+	return filters, err
+}
+
+// GetIngestFilterByName returns an ingestion filter by name.
+func (q *Q) GetIngestFilterByName(ctx context.Context, name string) (IngestFilter, error) {
+	var filter IngestFilter
+	sql := selectIngestFilters.Where(sq.Eq{"name": name}).Limit(1)
+	err := q.Get(ctx, &filter, sql)
+	return filter, err
+}
+
+// UpsertIngestFilter creates an ingestion filter or replaces the values
+// allowed by an existing one.
+func (q *Q) UpsertIngestFilter(ctx context.Context, name string, allowed []string) error {
+	if allowed == nil {
+		allowed = []string{}
+	}
+	sql := sq.Insert("ingest_filters").
+		SetMap(map[string]interface{}{
+			"name":       name,
+			"allowed":    pq.Array(allowed),
+			"updated_at": time.Now().UTC(),
+		}).
+		Suffix("ON CONFLICT (name) DO UPDATE SET " +
+			"allowed = excluded.allowed, " +
+			"updated_at = excluded.updated_at")
+	_, err := q.Exec(ctx, sql)
+	return err
+}
+
+// RemoveIngestFilter deletes an ingestion filter. Returns number of rows
+// affected and error.
+func (q *Q) RemoveIngestFilter(ctx context.Context, name string) (int64, error) {
+	sql := sq.Delete("ingest_filters").Where(sq.Eq{"name": name})
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+var selectIngestFilters = sq.Select(
+	"name",
+	"allowed",
+	"updated_at",
+).From("ingest_filters")
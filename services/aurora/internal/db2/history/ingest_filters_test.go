@@ -0,0 +1,46 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/diamnet/go/services/aurora/internal/test"
+)
+
+func TestIngestFilters(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	filters, err := q.GetIngestFilters(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(filters)
+
+	tt.Assert.NoError(q.UpsertIngestFilter(tt.Ctx, "operation_types", []string{"payment"}))
+	tt.Assert.NoError(q.UpsertIngestFilter(tt.Ctx, "assets", []string{"native"}))
+	tt.Assert.NoError(q.UpsertIngestFilter(tt.Ctx, "assets", []string{
+		"USD:GCEZWKCA5VLDNRLN3RPRJMRZOX3Z6G5CHCGSNFHEYVXM3XOJMDS674JZ",
+	}))
+
+	filters, err = q.GetIngestFilters(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(filters, 2)
+	tt.Assert.Equal("assets", filters[0].Name)
+	tt.Assert.Equal(
+		[]string{"USD:GCEZWKCA5VLDNRLN3RPRJMRZOX3Z6G5CHCGSNFHEYVXM3XOJMDS674JZ"},
+		[]string(filters[0].Allowed),
+	)
+	tt.Assert.Equal("operation_types", filters[1].Name)
+
+	// An empty allow-list is kept, it lifts the restriction of the config
+	tt.Assert.NoError(q.UpsertIngestFilter(tt.Ctx, "operation_types", nil))
+	filter, err := q.GetIngestFilterByName(tt.Ctx, "operation_types")
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(filter.Allowed)
+
+	removed, err := q.RemoveIngestFilter(tt.Ctx, "assets")
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(1), removed)
+	_, err = q.GetIngestFilterByName(tt.Ctx, "assets")
+	tt.Assert.True(q.NoRows(err))
+}
@@ -9,8 +9,11 @@ import (
 // the ingestion system using history archive snapshots.
 // Any aurora database tables which cannot be populated using
 // history archive snapshots will not be truncated.
+// The versions of the state tables rows are truncated as well because they
+// can't be reconstructed from the snapshots, while the state tables of the
+// plugins are rebuilt with the rest of the state.
 func (q *Q) TruncateIngestStateTables(ctx context.Context) error {
-	return q.TruncateTables(ctx, []string{
+	tables := []string{
 		"accounts",
 		"accounts_data",
 		"accounts_signers",
@@ -19,5 +22,10 @@ func (q *Q) TruncateIngestStateTables(ctx context.Context) error {
 		"liquidity_pools",
 		"offers",
 		"trust_lines",
-	})
+	}
+	err := q.TruncateTables(ctx, append(tables, pluginStateTables()...))
+	if err != nil {
+		return err
+	}
+	return q.TruncateStateVersions(ctx)
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/guregu/null"
 	"github.com/diamnet/go/services/aurora/internal/db2"
 	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/services/aurora/plugins"
 	"github.com/diamnet/go/support/errors"
 	"github.com/diamnet/go/xdr"
 )
@@ -131,8 +132,9 @@ func (q *Q) InsertLedger(ctx context.Context,
 	return result.RowsAffected()
 }
 
-// GetLedgerGaps obtains ingestion gaps in the history_ledgers table.
-// Returns the gaps and error.
+// GetLedgerGaps obtains ingestion gaps in the history_ledgers table, and the
+// ledgers which were not processed by the transaction processors of the
+// plugins. Returns the gaps and error.
 func (q *Q) GetLedgerGaps(ctx context.Context) ([]LedgerRange, error) {
 	var gaps []LedgerRange
 	query := `
@@ -147,10 +149,36 @@ func (q *Q) GetLedgerGaps(ctx context.Context) ([]LedgerRange, error) {
 	if err := q.SelectRaw(ctx, &gaps, query); err != nil {
 		return nil, err
 	}
-	sort.Slice(gaps, func(i, j int) bool {
-		return gaps[i].StartSequence < gaps[j].StartSequence
+
+	for _, processor := range plugins.Registered() {
+		if processor.NewTransactionProcessor == nil {
+			continue
+		}
+		pluginGaps, err := q.getPluginLedgerGaps(ctx, processor.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not query gaps of plugin %s", processor.Name)
+		}
+		gaps = append(gaps, pluginGaps...)
+	}
+
+	return mergeLedgerRanges(gaps), nil
+}
+
+// mergeLedgerRanges sorts the ranges and merges the overlapping or adjacent
+// ones.
+func mergeLedgerRanges(ranges []LedgerRange) []LedgerRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].StartSequence < ranges[j].StartSequence
 	})
-	return gaps, nil
+	var merged []LedgerRange
+	for _, r := range ranges {
+		if last := len(merged) - 1; last >= 0 && r.StartSequence <= merged[last].EndSequence+1 {
+			merged[last].EndSequence = max(merged[last].EndSequence, r.EndSequence)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
 }
 
 func max(a, b uint32) uint32 {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
 	"github.com/diamnet/go/ingest/ledgerbackend"
 	"github.com/diamnet/go/services/aurora/internal/test"
 	"github.com/diamnet/go/services/aurora/internal/toid"
@@ -337,3 +338,17 @@ func TestGetLedgerGaps(t *testing.T) {
 	expectedGaps = append(expectedGaps, LedgerRange{1001, 1001})
 	tt.Assert.Equal(expectedGaps, gaps)
 }
+
+func TestMergeLedgerRanges(t *testing.T) {
+	assert.Empty(t, mergeLedgerRanges(nil))
+	assert.Equal(t, []LedgerRange{
+		{StartSequence: 2, EndSequence: 9},
+		{StartSequence: 15, EndSequence: 20},
+	}, mergeLedgerRanges([]LedgerRange{
+		{StartSequence: 15, EndSequence: 20},
+		{StartSequence: 5, EndSequence: 9},
+		{StartSequence: 2, EndSequence: 4},
+		{StartSequence: 6, EndSequence: 7},
+		{StartSequence: 16, EndSequence: 16},
+	}))
+}
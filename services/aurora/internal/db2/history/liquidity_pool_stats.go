@@ -0,0 +1,279 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/diamnet/go/services/aurora/internal/db2"
+	"github.com/diamnet/go/support/errors"
+	strtime "github.com/diamnet/go/support/time"
+	"github.com/diamnet/go/xdr"
+)
+
+// LiquidityPoolStatsBucketResolution is the resolution, in milliseconds, of
+// the buckets of the history_liquidity_pool_stats_3600000 table.
+const LiquidityPoolStatsBucketResolution = int64(time.Hour / time.Millisecond)
+
+// AllowedLiquidityPoolStatsResolutions is the set of time windows allowed to
+// be used as the `resolution` of the liquidity pool stats aggregations. They
+// are multiples of the hourly buckets.
+var AllowedLiquidityPoolStatsResolutions = map[time.Duration]struct{}{
+	time.Hour:          {}, //1 hour
+	time.Hour * 24:     {}, //day
+	time.Hour * 24 * 7: {}, //week
+}
+
+// LiquidityPoolStatsLedgerDelta is the activity of a liquidity pool in a
+// single ledger and its state at the end of the ledger. Amounts are in stroops
+// of asset A and B of the pool, fees can have a fractional part.
+type LiquidityPoolStatsLedgerDelta struct {
+	HistoryLiquidityPoolID int64
+	TradeCount             int64
+	VolumeA                string
+	VolumeB                string
+	FeeA                   string
+	FeeB                   string
+	ReserveA               int64
+	ReserveB               int64
+	TotalShares            int64
+	TrustlineCount         int64
+}
+
+// LiquidityPoolStatsBucket represents the statistics of a liquidity pool over
+// a period of time. Reserves, shares and trust lines are the values at the end
+// of the period.
+type LiquidityPoolStatsBucket struct {
+	Timestamp      int64  `db:"timestamp"`
+	TradeCount     int64  `db:"trade_count"`
+	VolumeA        string `db:"volume_a"`
+	VolumeB        string `db:"volume_b"`
+	FeeA           string `db:"fee_a"`
+	FeeB           string `db:"fee_b"`
+	ReserveA       int64  `db:"reserve_a"`
+	ReserveB       int64  `db:"reserve_b"`
+	TotalShares    int64  `db:"total_shares"`
+	TrustlineCount int64  `db:"trustline_count"`
+}
+
+// LiquidityPoolStatsWindow is the activity of a liquidity pool over a window
+// of time with its reserves at the start and the end of the window.
+type LiquidityPoolStatsWindow struct {
+	TradeCount int64
+	VolumeA    string
+	VolumeB    string
+	FeeA       string
+	FeeB       string
+	// Start is nil if the pool has no buckets before the end of the window,
+	// otherwise Start and End are set.
+	Start *LiquidityPoolStatsBucket
+	End   *LiquidityPoolStatsBucket
+}
+
+// QLiquidityPoolStats defines the queries used to maintain the statistics of
+// the liquidity pools.
+type QLiquidityPoolStats interface {
+	CreateHistoryLiquidityPools(ctx context.Context, poolIDs []string, batchSize int) (map[string]int64, error)
+	UpsertLiquidityPoolStatsBuckets(
+		ctx context.Context,
+		ledger xdr.LedgerHeaderHistoryEntry,
+		deltas []LiquidityPoolStatsLedgerDelta,
+	) error
+}
+
+// UpsertLiquidityPoolStatsBuckets adds the activity of the given ledger to
+// the hourly buckets of the liquidity pools and sets their reserves, shares
+// and trust lines if the ledger is the last one of the bucket.
+//
+// Ledgers already covered by a bucket are skipped so reingesting a range does
+// not count its trades twice, which requires the ledgers of a bucket to be
+// ingested without gaps.
+func (q *Q) UpsertLiquidityPoolStatsBuckets(
+	ctx context.Context,
+	ledger xdr.LedgerHeaderHistoryEntry,
+	deltas []LiquidityPoolStatsLedgerDelta,
+) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	closedAt := int64(ledger.Header.ScpValue.CloseTime) * 1000
+	timestamp := closedAt / LiquidityPoolStatsBucketResolution * LiquidityPoolStatsBucketResolution
+	sequence := int32(ledger.Header.LedgerSeq)
+
+	values := make([]string, 0, len(deltas))
+	var args []interface{}
+	for _, delta := range deltas {
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args,
+			delta.HistoryLiquidityPoolID,
+			timestamp,
+			sequence,
+			sequence,
+			delta.TradeCount,
+			delta.VolumeA,
+			delta.VolumeB,
+			delta.FeeA,
+			delta.FeeB,
+			delta.ReserveA,
+			delta.ReserveB,
+			delta.TotalShares,
+			delta.TrustlineCount,
+		)
+	}
+
+	sql := `INSERT INTO history_liquidity_pool_stats_3600000 AS h (
+		history_liquidity_pool_id, timestamp, first_ledger, last_ledger,
+		trade_count, volume_a, volume_b, fee_a, fee_b,
+		reserve_a, reserve_b, total_shares, trustline_count
+	) VALUES ` + strings.Join(values, ", ") + `
+	ON CONFLICT (history_liquidity_pool_id, timestamp) DO UPDATE SET
+		first_ledger = LEAST(h.first_ledger, excluded.first_ledger),
+		last_ledger = GREATEST(h.last_ledger, excluded.last_ledger),
+		trade_count = h.trade_count + excluded.trade_count,
+		volume_a = h.volume_a + excluded.volume_a,
+		volume_b = h.volume_b + excluded.volume_b,
+		fee_a = h.fee_a + excluded.fee_a,
+		fee_b = h.fee_b + excluded.fee_b,
+		reserve_a = CASE WHEN excluded.last_ledger > h.last_ledger THEN excluded.reserve_a ELSE h.reserve_a END,
+		reserve_b = CASE WHEN excluded.last_ledger > h.last_ledger THEN excluded.reserve_b ELSE h.reserve_b END,
+		total_shares = CASE WHEN excluded.last_ledger > h.last_ledger THEN excluded.total_shares ELSE h.total_shares END,
+		trustline_count = CASE WHEN excluded.last_ledger > h.last_ledger THEN excluded.trustline_count ELSE h.trustline_count END
+	WHERE excluded.first_ledger < h.first_ledger OR excluded.last_ledger > h.last_ledger`
+	if _, err := q.ExecRaw(ctx, sql, args...); err != nil {
+		return errors.Wrap(err, "could not upsert liquidity pool stats buckets")
+	}
+	return nil
+}
+
+var selectLiquidityPoolStatsBuckets = sq.Select(
+	"timestamp",
+	"trade_count",
+	"volume_a",
+	"volume_b",
+	"fee_a",
+	"fee_b",
+	"reserve_a",
+	"reserve_b",
+	"total_shares",
+	"trustline_count",
+).From("history_liquidity_pool_stats_3600000")
+
+// GetLiquidityPoolStatsBuckets returns a page of the statistics of a liquidity
+// pool aggregated by the given resolution. The start time is rounded up and
+// the end time rounded down to the resolution so partial buckets are not
+// returned.
+func (q *Q) GetLiquidityPoolStatsBuckets(
+	ctx context.Context,
+	historyLiquidityPoolID int64,
+	resolution int64,
+	startTime, endTime strtime.Millis,
+	page db2.PageQuery,
+) ([]LiquidityPoolStatsBucket, error) {
+	if _, ok := AllowedLiquidityPoolStatsResolutions[time.Duration(resolution)*time.Millisecond]; !ok {
+		return nil, errors.New("resolution is not allowed")
+	}
+
+	buckets := selectLiquidityPoolStatsBuckets.
+		Where(sq.Eq{"history_liquidity_pool_id": historyLiquidityPoolID})
+	if !startTime.IsNil() {
+		buckets = buckets.Where(sq.GtOrEq{"timestamp": startTime.RoundUp(resolution)})
+	}
+	if !endTime.IsNil() {
+		buckets = buckets.Where(sq.Lt{"timestamp": endTime.RoundDown(resolution)})
+	}
+
+	if resolution != LiquidityPoolStatsBucketResolution {
+		buckets = sq.Select(
+			fmt.Sprintf("(timestamp / %d) * %d as timestamp", resolution, resolution),
+			"sum(trade_count) as trade_count",
+			"sum(volume_a) as volume_a",
+			"sum(volume_b) as volume_b",
+			"sum(fee_a) as fee_a",
+			"sum(fee_b) as fee_b",
+			"last(reserve_a ORDER BY timestamp) as reserve_a",
+			"last(reserve_b ORDER BY timestamp) as reserve_b",
+			"last(total_shares ORDER BY timestamp) as total_shares",
+			"last(trustline_count ORDER BY timestamp) as trustline_count",
+		).FromSelect(buckets, "hourly").GroupBy("1")
+	}
+
+	sql := sq.Select("*").FromSelect(buckets, "buckets").
+		OrderBy("timestamp " + page.Order).
+		Limit(page.Limit)
+	var records []LiquidityPoolStatsBucket
+	if err := q.Select(ctx, &records, sql); err != nil {
+		return nil, errors.Wrap(err, "could not load liquidity pool stats buckets")
+	}
+	return records, nil
+}
+
+// GetLiquidityPoolStatsWindow returns the activity of a liquidity pool in the
+// buckets from startTime (inclusive) to endTime (exclusive). The reserves at
+// the start of the window are the ones of the last bucket before the window or,
+// if the pool was created in the window, of its first bucket.
+func (q *Q) GetLiquidityPoolStatsWindow(
+	ctx context.Context,
+	historyLiquidityPoolID int64,
+	startTime, endTime strtime.Millis,
+) (LiquidityPoolStatsWindow, error) {
+	var window LiquidityPoolStatsWindow
+	pool := sq.Eq{"history_liquidity_pool_id": historyLiquidityPoolID}
+
+	sums := sq.Select(
+		"COALESCE(sum(trade_count), 0) as trade_count",
+		"COALESCE(sum(volume_a), 0) as volume_a",
+		"COALESCE(sum(volume_b), 0) as volume_b",
+		"COALESCE(sum(fee_a), 0) as fee_a",
+		"COALESCE(sum(fee_b), 0) as fee_b",
+	).From("history_liquidity_pool_stats_3600000").
+		Where(pool).
+		Where(sq.GtOrEq{"timestamp": startTime}).
+		Where(sq.Lt{"timestamp": endTime})
+	var totals LiquidityPoolStatsBucket
+	if err := q.Get(ctx, &totals, sums); err != nil {
+		return window, errors.Wrap(err, "could not load liquidity pool stats")
+	}
+	window.TradeCount = totals.TradeCount
+	window.VolumeA, window.VolumeB = totals.VolumeA, totals.VolumeB
+	window.FeeA, window.FeeB = totals.FeeA, totals.FeeB
+
+	var end []LiquidityPoolStatsBucket
+	err := q.Select(ctx, &end, selectLiquidityPoolStatsBuckets.
+		Where(pool).
+		Where(sq.Lt{"timestamp": endTime}).
+		OrderBy("timestamp DESC").
+		Limit(1))
+	if err != nil {
+		return window, errors.Wrap(err, "could not load liquidity pool stats")
+	}
+	if len(end) == 0 {
+		return window, nil
+	}
+	window.End = &end[0]
+
+	var start []LiquidityPoolStatsBucket
+	err = q.Select(ctx, &start, selectLiquidityPoolStatsBuckets.
+		Where(pool).
+		Where(sq.Lt{"timestamp": startTime}).
+		OrderBy("timestamp DESC").
+		Limit(1))
+	if err != nil {
+		return window, errors.Wrap(err, "could not load liquidity pool stats")
+	}
+	if len(start) == 0 {
+		err = q.Select(ctx, &start, selectLiquidityPoolStatsBuckets.
+			Where(pool).
+			Where(sq.GtOrEq{"timestamp": startTime}).
+			OrderBy("timestamp ASC").
+			Limit(1))
+		if err != nil {
+			return window, errors.Wrap(err, "could not load liquidity pool stats")
+		}
+	}
+	window.Start = &start[0]
+	return window, nil
+}
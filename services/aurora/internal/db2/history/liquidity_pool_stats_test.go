@@ -0,0 +1,114 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/diamnet/go/services/aurora/internal/db2"
+	"github.com/diamnet/go/services/aurora/internal/test"
+	strtime "github.com/diamnet/go/support/time"
+	"github.com/diamnet/go/xdr"
+)
+
+func TestLiquidityPoolStatsBuckets(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	poolID := "cafebabedeadbeef000000000000000000000000000000000000000000000000"
+	pools, err := q.CreateHistoryLiquidityPools(tt.Ctx, []string{poolID}, 1)
+	tt.Assert.NoError(err)
+	historyID := pools[poolID]
+
+	hour := LiquidityPoolStatsBucketResolution
+	upsert := func(sequence uint32, closedAt int64, volume string, reserveA int64) {
+		tt.Assert.NoError(q.UpsertLiquidityPoolStatsBuckets(
+			tt.Ctx,
+			xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{
+				LedgerSeq: xdr.Uint32(sequence),
+				ScpValue:  xdr.DiamnetValue{CloseTime: xdr.TimePoint(closedAt / 1000)},
+			}},
+			[]LiquidityPoolStatsLedgerDelta{{
+				HistoryLiquidityPoolID: historyID,
+				TradeCount:             1,
+				VolumeA:                volume,
+				VolumeB:                volume,
+				FeeA:                   "0.3000",
+				FeeB:                   "0",
+				ReserveA:               reserveA,
+				ReserveB:               1000,
+				TotalShares:            100,
+				TrustlineCount:         2,
+			}},
+		))
+	}
+
+	// Ledgers 10 and 11 are reingested
+	upsert(11, hour+2000, "20", 110)
+	upsert(12, hour+3000, "30", 120)
+	upsert(10, hour+1000, "10", 100)
+	upsert(11, hour+2000, "20", 110)
+	upsert(13, 30*hour, "40", 130)
+
+	page := db2.PageQuery{Order: db2.OrderAscending, Limit: 10}
+	buckets, err := q.GetLiquidityPoolStatsBuckets(tt.Ctx, historyID, hour, strtime.Millis(0), strtime.Millis(0), page)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]LiquidityPoolStatsBucket{
+		{
+			Timestamp:      hour,
+			TradeCount:     3,
+			VolumeA:        "60",
+			VolumeB:        "60",
+			FeeA:           "0.9000",
+			FeeB:           "0",
+			ReserveA:       120,
+			ReserveB:       1000,
+			TotalShares:    100,
+			TrustlineCount: 2,
+		},
+		{
+			Timestamp:      30 * hour,
+			TradeCount:     1,
+			VolumeA:        "40",
+			VolumeB:        "40",
+			FeeA:           "0.3000",
+			FeeB:           "0",
+			ReserveA:       130,
+			ReserveB:       1000,
+			TotalShares:    100,
+			TrustlineCount: 2,
+		},
+	}, buckets)
+
+	day := 24 * hour
+	buckets, err = q.GetLiquidityPoolStatsBuckets(tt.Ctx, historyID, day, strtime.Millis(0), strtime.Millis(0), page)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(buckets, 2)
+	tt.Assert.Equal(int64(0), buckets[0].Timestamp)
+	tt.Assert.Equal(int64(120), buckets[0].ReserveA)
+	tt.Assert.Equal(day, buckets[1].Timestamp)
+
+	window, err := q.GetLiquidityPoolStatsWindow(
+		tt.Ctx,
+		historyID,
+		strtime.MillisFromInt64(2*hour),
+		strtime.MillisFromInt64(31*hour),
+	)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(1), window.TradeCount)
+	tt.Assert.Equal("40", window.VolumeA)
+	tt.Assert.Equal(int64(120), window.Start.ReserveA)
+	tt.Assert.Equal(int64(130), window.End.ReserveA)
+
+	// The pool was created in the window
+	window, err = q.GetLiquidityPoolStatsWindow(tt.Ctx, historyID, strtime.MillisFromInt64(0), strtime.MillisFromInt64(2*hour))
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(3), window.TradeCount)
+	tt.Assert.Equal(window.Start, window.End)
+
+	window, err = q.GetLiquidityPoolStatsWindow(tt.Ctx, historyID, strtime.MillisFromInt64(0), strtime.MillisFromInt64(hour))
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(0), window.TradeCount)
+	tt.Assert.Nil(window.Start)
+	tt.Assert.Nil(window.End)
+}
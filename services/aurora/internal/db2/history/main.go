@@ -252,12 +252,30 @@ type IngestionQ interface {
 	// duplicate method CreateAccounts
 	NewTransactionParticipantsBatchInsertBuilder(maxBatchSize int) TransactionParticipantsBatchInsertBuilder
 	NewOperationParticipantBatchInsertBuilder(maxBatchSize int) OperationParticipantBatchInsertBuilder
+	QMuxedParticipants
 	QSigners
+	QStateVersions
+	QIngestFilters
+	QPlugins
+	QHistoryPartitions
 	//QTrades
 	NewTradeBatchInsertBuilder(maxBatchSize int) TradeBatchInsertBuilder
 	RebuildTradeAggregationTimes(ctx context.Context, from, to strtime.Millis) error
 	RebuildTradeAggregationBuckets(ctx context.Context, fromLedger, toLedger uint32) error
 	CreateAssets(ctx context.Context, assets []xdr.Asset, batchSize int) (map[string]Asset, error)
+	//QAssetStatsHistory
+	UpsertAssetStatsBuckets(
+		ctx context.Context,
+		ledger xdr.LedgerHeaderHistoryEntry,
+		deltas []AssetStatsLedgerDelta,
+		withSnapshots bool,
+	) error
+	//QLiquidityPoolStats
+	UpsertLiquidityPoolStatsBuckets(
+		ctx context.Context,
+		ledger xdr.LedgerHeaderHistoryEntry,
+		deltas []LiquidityPoolStatsLedgerDelta,
+	) error
 	QTransactions
 	QTrustLines
 
@@ -803,22 +821,29 @@ func (q *Q) CloneIngestionQ() IngestionQ {
 }
 
 // DeleteRangeAll deletes a range of rows from all history tables between
-// `start` and `end` (exclusive).
+// `start` and `end` (exclusive), including the history tables of the plugins.
 func (q *Q) DeleteRangeAll(ctx context.Context, start, end int64) error {
-	for table, column := range map[string]string{
+	tables := map[string]string{
 		"history_effects":                        "history_operation_id",
 		"history_ledgers":                        "id",
 		"history_operation_claimable_balances":   "history_operation_id",
 		"history_operation_participants":         "history_operation_id",
 		"history_operation_liquidity_pools":      "history_operation_id",
+		"history_operation_muxed_participants":   "history_operation_id",
 		"history_operations":                     "id",
 		"history_trades":                         "history_operation_id",
 		"history_trades_60000":                   "open_ledger_toid",
 		"history_transaction_claimable_balances": "history_transaction_id",
 		"history_transaction_participants":       "history_transaction_id",
 		"history_transaction_liquidity_pools":    "history_transaction_id",
+		"history_transaction_muxed_participants": "history_transaction_id",
 		"history_transactions":                   "id",
-	} {
+	}
+	for table, column := range pluginHistoryTables() {
+		tables[table] = column
+	}
+
+	for table, column := range tables {
 		err := q.DeleteRange(ctx, start, end, table, column)
 		if err != nil {
 			return errors.Wrapf(err, "Error clearing %s", table)
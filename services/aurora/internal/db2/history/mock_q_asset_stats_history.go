@@ -0,0 +1,29 @@
+package history
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/diamnet/go/xdr"
+)
+
+// MockQAssetStatsHistory is a mock implementation of the QAssetStatsHistory interface
+type MockQAssetStatsHistory struct {
+	mock.Mock
+}
+
+func (m *MockQAssetStatsHistory) CreateAssets(ctx context.Context, assets []xdr.Asset, batchSize int) (map[string]Asset, error) {
+	a := m.Called(ctx, assets, batchSize)
+	return a.Get(0).(map[string]Asset), a.Error(1)
+}
+
+func (m *MockQAssetStatsHistory) UpsertAssetStatsBuckets(
+	ctx context.Context,
+	ledger xdr.LedgerHeaderHistoryEntry,
+	deltas []AssetStatsLedgerDelta,
+	withSnapshots bool,
+) error {
+	a := m.Called(ctx, ledger, deltas, withSnapshots)
+	return a.Error(0)
+}
@@ -0,0 +1,17 @@
+package history
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQHistoryPartitions is a mock implementation of the QHistoryPartitions interface
+type MockQHistoryPartitions struct {
+	mock.Mock
+}
+
+func (m *MockQHistoryPartitions) CreateHistoryPartitions(ctx context.Context, size, sequence uint32) error {
+	a := m.Called(ctx, size, sequence)
+	return a.Error(0)
+}
@@ -0,0 +1,17 @@
+package history
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQIngestFilters is a mock implementation of the QIngestFilters interface
+type MockQIngestFilters struct {
+	mock.Mock
+}
+
+func (m *MockQIngestFilters) GetIngestFilters(ctx context.Context) ([]IngestFilter, error) {
+	a := m.Called(ctx)
+	return a.Get(0).([]IngestFilter), a.Error(1)
+}
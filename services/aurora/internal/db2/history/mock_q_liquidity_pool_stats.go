@@ -0,0 +1,28 @@
+package history
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/diamnet/go/xdr"
+)
+
+// MockQLiquidityPoolStats is a mock implementation of the QLiquidityPoolStats interface
+type MockQLiquidityPoolStats struct {
+	mock.Mock
+}
+
+func (m *MockQLiquidityPoolStats) CreateHistoryLiquidityPools(ctx context.Context, poolIDs []string, batchSize int) (map[string]int64, error) {
+	a := m.Called(ctx, poolIDs, batchSize)
+	return a.Get(0).(map[string]int64), a.Error(1)
+}
+
+func (m *MockQLiquidityPoolStats) UpsertLiquidityPoolStatsBuckets(
+	ctx context.Context,
+	ledger xdr.LedgerHeaderHistoryEntry,
+	deltas []LiquidityPoolStatsLedgerDelta,
+) error {
+	a := m.Called(ctx, ledger, deltas)
+	return a.Error(0)
+}
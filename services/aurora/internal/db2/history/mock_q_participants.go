@@ -20,6 +20,21 @@ func (m *MockQParticipants) NewTransactionParticipantsBatchInsertBuilder(maxBatc
 	return a.Get(0).(TransactionParticipantsBatchInsertBuilder)
 }
 
+func (m *MockQParticipants) CreateMuxedAccounts(ctx context.Context, addresses []string, batchSize int) (map[string]int64, error) {
+	a := m.Called(ctx, addresses, batchSize)
+	return a.Get(0).(map[string]int64), a.Error(1)
+}
+
+func (m *MockQParticipants) NewTransactionMuxedParticipantsBatchInsertBuilder(maxBatchSize int) TransactionParticipantsBatchInsertBuilder {
+	a := m.Called(maxBatchSize)
+	return a.Get(0).(TransactionParticipantsBatchInsertBuilder)
+}
+
+func (m *MockQParticipants) NewOperationMuxedParticipantsBatchInsertBuilder(maxBatchSize int) OperationParticipantBatchInsertBuilder {
+	a := m.Called(maxBatchSize)
+	return a.Get(0).(OperationParticipantBatchInsertBuilder)
+}
+
 // MockTransactionParticipantsBatchInsertBuilder is a mock implementation of the
 // TransactionParticipantsBatchInsertBuilder interface
 type MockTransactionParticipantsBatchInsertBuilder struct {
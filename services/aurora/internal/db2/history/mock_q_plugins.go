@@ -0,0 +1,24 @@
+package history
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/diamnet/go/support/db"
+)
+
+// MockQPlugins is a mock implementation of the QPlugins interface
+type MockQPlugins struct {
+	mock.Mock
+}
+
+func (m *MockQPlugins) PluginSession() db.SessionInterface {
+	a := m.Called()
+	return a.Get(0).(db.SessionInterface)
+}
+
+func (m *MockQPlugins) InsertPluginLedger(ctx context.Context, name string, sequence uint32) error {
+	a := m.Called(ctx, name, sequence)
+	return a.Error(0)
+}
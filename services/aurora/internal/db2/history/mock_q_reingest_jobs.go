@@ -0,0 +1,44 @@
+package history
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQReingestJobs is a mock implementation of the QReingestJobs interface
+type MockQReingestJobs struct {
+	mock.Mock
+}
+
+func (m *MockQReingestJobs) CreateReingestJob(ctx context.Context, batches []LedgerRange) (ReingestJob, error) {
+	a := m.Called(ctx, batches)
+	return a.Get(0).(ReingestJob), a.Error(1)
+}
+
+func (m *MockQReingestJobs) GetReingestJob(ctx context.Context, id int64) (ReingestJob, error) {
+	a := m.Called(ctx, id)
+	return a.Get(0).(ReingestJob), a.Error(1)
+}
+
+func (m *MockQReingestJobs) GetReingestJobs(ctx context.Context, limit uint64) ([]ReingestJob, error) {
+	a := m.Called(ctx, limit)
+	return a.Get(0).([]ReingestJob), a.Error(1)
+}
+
+func (m *MockQReingestJobs) GetReingestBatches(ctx context.Context, jobID int64) ([]ReingestBatch, error) {
+	a := m.Called(ctx, jobID)
+	return a.Get(0).([]ReingestBatch), a.Error(1)
+}
+
+func (m *MockQReingestJobs) UpdateReingestBatchStatus(
+	ctx context.Context, jobID int64, startSequence uint32, status string, batchErr error,
+) error {
+	a := m.Called(ctx, jobID, startSequence, status, batchErr)
+	return a.Error(0)
+}
+
+func (m *MockQReingestJobs) FinishReingestJob(ctx context.Context, id int64) error {
+	a := m.Called(ctx, id)
+	return a.Error(0)
+}
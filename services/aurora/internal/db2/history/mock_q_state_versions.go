@@ -0,0 +1,57 @@
+package history
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQStateVersions is a mock implementation of the QStateVersions interface
+type MockQStateVersions struct {
+	mock.Mock
+}
+
+func (m *MockQStateVersions) GetStateVersionsLedgerRange(ctx context.Context) (uint32, uint32, error) {
+	a := m.Called(ctx)
+	return a.Get(0).(uint32), a.Get(1).(uint32), a.Error(2)
+}
+
+func (m *MockQStateVersions) UpdateStateVersionsLedgerRange(ctx context.Context, start, last uint32) error {
+	a := m.Called(ctx, start, last)
+	return a.Error(0)
+}
+
+func (m *MockQStateVersions) TruncateStateVersions(ctx context.Context) error {
+	a := m.Called(ctx)
+	return a.Error(0)
+}
+
+func (m *MockQStateVersions) ArchiveAccounts(ctx context.Context, accountIDs []string, sequence uint32) error {
+	a := m.Called(ctx, accountIDs, sequence)
+	return a.Error(0)
+}
+
+func (m *MockQStateVersions) ArchiveAccountData(ctx context.Context, keys []AccountDataKey, sequence uint32) error {
+	a := m.Called(ctx, keys, sequence)
+	return a.Error(0)
+}
+
+func (m *MockQStateVersions) ArchiveAccountSigners(ctx context.Context, accountIDs []string, sequence uint32) error {
+	a := m.Called(ctx, accountIDs, sequence)
+	return a.Error(0)
+}
+
+func (m *MockQStateVersions) ArchiveClaimableBalances(ctx context.Context, ids []string, sequence uint32) error {
+	a := m.Called(ctx, ids, sequence)
+	return a.Error(0)
+}
+
+func (m *MockQStateVersions) ArchiveOffers(ctx context.Context, ids []int64, sequence uint32) error {
+	a := m.Called(ctx, ids, sequence)
+	return a.Error(0)
+}
+
+func (m *MockQStateVersions) ArchiveTrustLines(ctx context.Context, ledgerKeys []string, sequence uint32) error {
+	a := m.Called(ctx, ledgerKeys, sequence)
+	return a.Error(0)
+}
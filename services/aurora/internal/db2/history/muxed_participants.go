@@ -0,0 +1,155 @@
+package history
+
+import (
+	"context"
+	"sort"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/diamnet/go/support/db"
+	"github.com/diamnet/go/support/errors"
+)
+
+// QMuxedParticipants defines the queries used to index the muxed accounts
+// taking part in transactions and operations.
+type QMuxedParticipants interface {
+	CreateMuxedAccounts(ctx context.Context, addresses []string, batchSize int) (map[string]int64, error)
+	NewTransactionMuxedParticipantsBatchInsertBuilder(maxBatchSize int) TransactionParticipantsBatchInsertBuilder
+	NewOperationMuxedParticipantsBatchInsertBuilder(maxBatchSize int) OperationParticipantBatchInsertBuilder
+}
+
+// MuxedAccount is a row of data from the `history_muxed_accounts` table
+type MuxedAccount struct {
+	ID      int64  `db:"id"`
+	Address string `db:"address"`
+}
+
+var selectMuxedAccount = sq.Select("hma.*").From("history_muxed_accounts hma")
+
+// MuxedAccountByAddress loads a row from `history_muxed_accounts`, by M-address
+func (q *Q) MuxedAccountByAddress(ctx context.Context, address string) (dest MuxedAccount, err error) {
+	sql := selectMuxedAccount.Limit(1).Where("hma.address = ?", address)
+	err = q.Get(ctx, &dest, sql)
+	return dest, err
+}
+
+// MuxedAccountsByAddresses loads rows from `history_muxed_accounts`, by M-address
+func (q *Q) MuxedAccountsByAddresses(ctx context.Context, addresses []string) (dest []MuxedAccount, err error) {
+	sql := selectMuxedAccount.Where(map[string]interface{}{
+		"hma.address": addresses, // hma.address IN (...)
+	})
+	err = q.Select(ctx, &dest, sql)
+	return dest, err
+}
+
+// CreateMuxedAccounts creates rows in the history_muxed_accounts table for a
+// given list of M-addresses. It returns a mapping of address to its
+// corresponding id in the history_muxed_accounts table.
+func (q *Q) CreateMuxedAccounts(ctx context.Context, addresses []string, batchSize int) (map[string]int64, error) {
+	builder := &db.BatchInsertBuilder{
+		Table:        q.GetTable("history_muxed_accounts"),
+		MaxBatchSize: batchSize,
+		Suffix:       "ON CONFLICT (address) DO NOTHING",
+	}
+
+	// sort before inserting to prevent deadlocks on acquiring a ShareLock
+	// https://github.com/diamnet/go/issues/2370
+	sort.Strings(addresses)
+	var deduped []string
+	for i, address := range addresses {
+		if i > 0 && address == addresses[i-1] {
+			// skip duplicates
+			continue
+		}
+		deduped = append(deduped, address)
+		err := builder.Row(ctx, map[string]interface{}{
+			"address": address,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "could not insert history_muxed_accounts row")
+		}
+	}
+
+	if err := builder.Exec(ctx); err != nil {
+		return nil, errors.Wrap(err, "could not exec muxed account insert builder")
+	}
+
+	addressToID := map[string]int64{}
+	const selectBatchSize = 10000
+
+	for i := 0; i < len(deduped); i += selectBatchSize {
+		end := i + selectBatchSize
+		if end > len(deduped) {
+			end = len(deduped)
+		}
+
+		accounts, err := q.MuxedAccountsByAddresses(ctx, deduped[i:end])
+		if err != nil {
+			return nil, errors.Wrap(err, "could not select muxed accounts")
+		}
+
+		for _, account := range accounts {
+			addressToID[account.Address] = account.ID
+		}
+	}
+
+	return addressToID, nil
+}
+
+type transactionMuxedParticipantsBatchInsertBuilder struct {
+	builder db.BatchInsertBuilder
+}
+
+// NewTransactionMuxedParticipantsBatchInsertBuilder constructs a new
+// TransactionParticipantsBatchInsertBuilder instance inserting into the
+// history_transaction_muxed_participants table.
+func (q *Q) NewTransactionMuxedParticipantsBatchInsertBuilder(maxBatchSize int) TransactionParticipantsBatchInsertBuilder {
+	return &transactionMuxedParticipantsBatchInsertBuilder{
+		builder: db.BatchInsertBuilder{
+			Table:        q.GetTable("history_transaction_muxed_participants"),
+			MaxBatchSize: maxBatchSize,
+		},
+	}
+}
+
+// Add adds a new transaction muxed participant to the batch
+func (i *transactionMuxedParticipantsBatchInsertBuilder) Add(ctx context.Context, transactionID, muxedAccountID int64) error {
+	return i.builder.Row(ctx, map[string]interface{}{
+		"history_transaction_id":   transactionID,
+		"history_muxed_account_id": muxedAccountID,
+	})
+}
+
+// Exec flushes all pending transaction muxed participants to the db
+func (i *transactionMuxedParticipantsBatchInsertBuilder) Exec(ctx context.Context) error {
+	return i.builder.Exec(ctx)
+}
+
+type operationMuxedParticipantsBatchInsertBuilder struct {
+	builder db.BatchInsertBuilder
+}
+
+// NewOperationMuxedParticipantsBatchInsertBuilder constructs a new
+// OperationParticipantBatchInsertBuilder instance inserting into the
+// history_operation_muxed_participants table.
+func (q *Q) NewOperationMuxedParticipantsBatchInsertBuilder(maxBatchSize int) OperationParticipantBatchInsertBuilder {
+	return &operationMuxedParticipantsBatchInsertBuilder{
+		builder: db.BatchInsertBuilder{
+			Table:        q.GetTable("history_operation_muxed_participants"),
+			MaxBatchSize: maxBatchSize,
+		},
+	}
+}
+
+// Add adds a new operation muxed participant to the batch
+func (i *operationMuxedParticipantsBatchInsertBuilder) Add(ctx context.Context, operationID, muxedAccountID int64) error {
+	return i.builder.Row(ctx, map[string]interface{}{
+		"history_operation_id":     operationID,
+		"history_muxed_account_id": muxedAccountID,
+	})
+}
+
+// Exec flushes all pending operation muxed participants to the db
+func (i *operationMuxedParticipantsBatchInsertBuilder) Exec(ctx context.Context) error {
+	return i.builder.Exec(ctx)
+}
@@ -0,0 +1,46 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/diamnet/go/services/aurora/internal/db2"
+	"github.com/diamnet/go/services/aurora/internal/test"
+)
+
+func TestMuxedParticipants(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	addresses := []string{
+		"MAN4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQAAAAAAAAAAAAGK4O",
+		"MAN4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQAAAAAAAAAAAAGK4O",
+		"MAN4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQAAAAAAAAAAAQFEFA",
+	}
+	accounts, err := q.CreateMuxedAccounts(tt.Ctx, addresses, 1)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(accounts, 2)
+
+	// Creating the accounts again returns the same ids
+	again, err := q.CreateMuxedAccounts(tt.Ctx, addresses[1:], 1)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(accounts, again)
+
+	muxedID := accounts[addresses[0]]
+	operations := q.NewOperationMuxedParticipantsBatchInsertBuilder(2)
+	tt.Assert.NoError(operations.Add(tt.Ctx, 4294971393, muxedID))
+	tt.Assert.NoError(operations.Exec(tt.Ctx))
+	transactions := q.NewTransactionMuxedParticipantsBatchInsertBuilder(2)
+	tt.Assert.NoError(transactions.Add(tt.Ctx, 4294971392, muxedID))
+	tt.Assert.NoError(transactions.Exec(tt.Ctx))
+
+	page := db2.PageQuery{Order: db2.OrderAscending, Limit: 10}
+	var records []Transaction
+	tt.Assert.NoError(q.Transactions().ForMuxedAccount(tt.Ctx, addresses[0]).Page(page).Select(tt.Ctx, &records))
+	tt.Assert.Empty(records)
+
+	err = q.Transactions().ForMuxedAccount(tt.Ctx, "MAN4WOTCFSASG3J6SGLLQZURDDUVNBQANAHEQJ3PBNDZ74X63UZWQAAAAAAAAAAAAPL6O").
+		Page(page).Select(tt.Ctx, &records)
+	tt.Assert.True(q.NoRows(err))
+}
@@ -32,7 +32,9 @@ func (q *Q) CountOffers(ctx context.Context) (int, error) {
 // GetOfferByID loads a row from the `offers` table, selected by offerid.
 func (q *Q) GetOfferByID(ctx context.Context, id int64) (Offer, error) {
 	var offer Offer
-	sql := selectOffers.Where("deleted = ?", false).
+	sql := selectOffers.
+		From(stateTable(ctx, "offers", "offers")).
+		Where("deleted = ?", false).
 		Where("offers.offer_id = ?", id)
 	err := q.Get(ctx, &offer, sql)
 	return offer, err
@@ -49,7 +51,9 @@ func (q *Q) GetOffersByIDs(ctx context.Context, ids []int64) ([]Offer, error) {
 
 // GetOffers loads rows from `offers` by paging query.
 func (q *Q) GetOffers(ctx context.Context, query OffersQuery) ([]Offer, error) {
-	sql := selectOffers.Where("deleted = ?", false)
+	sql := selectOffers.
+		From(stateTable(ctx, "offers", "offers")).
+		Where("deleted = ?", false)
 	sql, err := query.PageQuery.ApplyTo(sql, "offers.offer_id")
 
 	if err != nil {
@@ -153,6 +153,26 @@ func (q *OperationsQ) ForAccount(ctx context.Context, aid string) *OperationsQ {
 	return q
 }
 
+// ForMuxedAccount filters the operations collection to a specific muxed
+// account, specified by its M-address.
+func (q *OperationsQ) ForMuxedAccount(ctx context.Context, address string) *OperationsQ {
+	var account MuxedAccount
+	account, q.Err = q.parent.MuxedAccountByAddress(ctx, address)
+	if q.Err != nil {
+		return q
+	}
+
+	q.sql = q.sql.Join(
+		"history_operation_muxed_participants homp ON "+
+			"homp.history_operation_id = hop.id",
+	).Where("homp.history_muxed_account_id = ?", account.ID)
+
+	// in order to use homp.history_operation_id index
+	q.opIdCol = "homp.history_operation_id"
+
+	return q
+}
+
 // ForClaimableBalance filters the query to only operations pertaining to a
 // claimable balance, specified by the claimable balance's hex-encoded id.
 func (q *OperationsQ) ForClaimableBalance(ctx context.Context, cbID string) *OperationsQ {
@@ -238,16 +258,20 @@ func (q *OperationsQ) ForTransaction(ctx context.Context, hash string) *Operatio
 // are in the "payment" class of operations:  CreateAccountOps, Payments, and
 // PathPayments.
 func (q *OperationsQ) OnlyPayments() *OperationsQ {
-	q.sql = q.sql.Where(sq.Eq{"hop.type": []xdr.OperationType{
-		xdr.OperationTypeCreateAccount,
-		xdr.OperationTypePayment,
-		xdr.OperationTypePathPaymentStrictReceive,
-		xdr.OperationTypePathPaymentStrictSend,
-		xdr.OperationTypeAccountMerge,
-	}})
+	q.sql = q.sql.Where(sq.Eq{"hop.type": PaymentOperationTypes})
 	return q
 }
 
+// PaymentOperationTypes are the types of the operations returned by the
+// payments endpoints.
+var PaymentOperationTypes = []xdr.OperationType{
+	xdr.OperationTypeCreateAccount,
+	xdr.OperationTypePayment,
+	xdr.OperationTypePathPaymentStrictReceive,
+	xdr.OperationTypePathPaymentStrictSend,
+	xdr.OperationTypeAccountMerge,
+}
+
 // IncludeFailed changes the query to include failed transactions.
 func (q *OperationsQ) IncludeFailed() *OperationsQ {
 	q.includeFailed = true
@@ -9,6 +9,7 @@ import (
 // QParticipants defines ingestion participant related queries.
 type QParticipants interface {
 	QCreateAccountsHistory
+	QMuxedParticipants
 	NewTransactionParticipantsBatchInsertBuilder(maxBatchSize int) TransactionParticipantsBatchInsertBuilder
 	NewOperationParticipantBatchInsertBuilder(maxBatchSize int) OperationParticipantBatchInsertBuilder
 }
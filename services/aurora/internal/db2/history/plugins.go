@@ -0,0 +1,78 @@
+package history
+
+import (
+	"context"
+
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/services/aurora/plugins"
+	"github.com/diamnet/go/support/db"
+)
+
+// QPlugins defines the queries used to run the ingestion processors
+// registered with the plugins package.
+type QPlugins interface {
+	PluginSession() db.SessionInterface
+	InsertPluginLedger(ctx context.Context, name string, sequence uint32) error
+}
+
+// PluginSession returns the session passed to the processors registered with
+// the plugins package, which shares the DB transaction of ingestion.
+func (q *Q) PluginSession() db.SessionInterface {
+	return q.SessionInterface
+}
+
+// InsertPluginLedger records that the transaction processor of a plugin
+// processed the ledger with the given sequence.
+func (q *Q) InsertPluginLedger(ctx context.Context, name string, sequence uint32) error {
+	_, err := q.ExecRaw(
+		ctx,
+		`INSERT INTO ingest_plugin_ledgers (name, ledger_id) VALUES (?, ?)
+		ON CONFLICT (name, ledger_id) DO NOTHING`,
+		name, toid.New(int32(sequence), 0, 0).ToInt64(),
+	)
+	return err
+}
+
+// getPluginLedgerGaps returns the ranges of ledgers in the history_ledgers
+// table which were not processed by the transaction processor of a plugin.
+func (q *Q) getPluginLedgerGaps(ctx context.Context, name string) ([]LedgerRange, error) {
+	var gaps []LedgerRange
+	query := `
+	SELECT MIN(sequence) AS start, MAX(sequence) AS end
+	FROM (
+		SELECT hl.sequence,
+		hl.sequence - ROW_NUMBER() OVER (ORDER BY hl.sequence) AS island
+		FROM history_ledgers hl
+		WHERE NOT EXISTS (
+			SELECT 1 FROM ingest_plugin_ledgers p
+			WHERE p.name = ? AND p.ledger_id = hl.id
+		)
+	) missing
+	GROUP BY island`
+	err := q.SelectRaw(ctx, &gaps, query, name)
+	return gaps, err
+}
+
+// pluginHistoryTables returns the history tables written by the registered
+// plugins, with their TOID column.
+func pluginHistoryTables() map[string]string {
+	tables := map[string]string{
+		"ingest_plugin_ledgers": "ledger_id",
+	}
+	for _, processor := range plugins.Registered() {
+		for table, column := range processor.HistoryTables {
+			tables[table] = column
+		}
+	}
+	return tables
+}
+
+// pluginStateTables returns the state tables written by the registered
+// plugins.
+func pluginStateTables() []string {
+	var tables []string
+	for _, processor := range plugins.Registered() {
+		tables = append(tables, processor.StateTables...)
+	}
+	return tables
+}
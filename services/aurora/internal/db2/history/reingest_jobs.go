@@ -0,0 +1,172 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+
+	"github.com/diamnet/go/support/db"
+	"github.com/diamnet/go/support/errors"
+)
+
+// Statuses of the batches of a reingestion job.
+const (
+	ReingestBatchPending = "pending"
+	ReingestBatchRunning = "running"
+	ReingestBatchDone    = "done"
+	ReingestBatchFailed  = "failed"
+)
+
+// ReingestJob is a row of data from the `reingest_jobs` table. FinishedAt is
+// set once all the batches of the job are done.
+type ReingestJob struct {
+	ID         int64     `db:"id"`
+	CreatedAt  time.Time `db:"created_at"`
+	FinishedAt null.Time `db:"finished_at"`
+}
+
+// ReingestBatch is a row of data from the `reingest_job_batches` table, it
+// contains the status of a range of ledgers reingested by a job.
+type ReingestBatch struct {
+	JobID         int64       `db:"job_id"`
+	StartSequence uint32      `db:"start_ledger"`
+	EndSequence   uint32      `db:"end_ledger"`
+	Status        string      `db:"status"`
+	Error         null.String `db:"error"`
+	StartedAt     null.Time   `db:"started_at"`
+	FinishedAt    null.Time   `db:"finished_at"`
+}
+
+// LedgerRange returns the ledgers of the batch.
+func (b ReingestBatch) LedgerRange() LedgerRange {
+	return LedgerRange{StartSequence: b.StartSequence, EndSequence: b.EndSequence}
+}
+
+// QReingestJobs defines reingestion jobs related queries.
+type QReingestJobs interface {
+	CreateReingestJob(ctx context.Context, batches []LedgerRange) (ReingestJob, error)
+	GetReingestJob(ctx context.Context, id int64) (ReingestJob, error)
+	GetReingestJobs(ctx context.Context, limit uint64) ([]ReingestJob, error)
+	GetReingestBatches(ctx context.Context, jobID int64) ([]ReingestBatch, error)
+	UpdateReingestBatchStatus(ctx context.Context, jobID int64, startSequence uint32, status string, batchErr error) error
+	FinishReingestJob(ctx context.Context, id int64) error
+}
+
+// CreateReingestJob creates a reingestion job with a pending batch for every
+// given range. It should be called in a transaction so that the job is not
+// created without its batches.
+func (q *Q) CreateReingestJob(ctx context.Context, batches []LedgerRange) (ReingestJob, error) {
+	var job ReingestJob
+	sql := sq.Insert("reingest_jobs").
+		SetMap(map[string]interface{}{"created_at": time.Now().UTC()}).
+		Suffix("RETURNING id, created_at, finished_at")
+	if err := q.Get(ctx, &job, sql); err != nil {
+		return job, errors.Wrap(err, "could not insert reingest job")
+	}
+
+	builder := &db.BatchInsertBuilder{
+		Table:        q.GetTable("reingest_job_batches"),
+		MaxBatchSize: 10000,
+	}
+	for _, batch := range batches {
+		err := builder.Row(ctx, map[string]interface{}{
+			"job_id":       job.ID,
+			"start_ledger": batch.StartSequence,
+			"end_ledger":   batch.EndSequence,
+			"status":       ReingestBatchPending,
+		})
+		if err != nil {
+			return job, errors.Wrap(err, "could not insert reingest_job_batches row")
+		}
+	}
+	if err := builder.Exec(ctx); err != nil {
+		return job, errors.Wrap(err, "could not exec reingest_job_batches insert builder")
+	}
+	return job, nil
+}
+
+// GetReingestJob returns a reingestion job by ID.
+func (q *Q) GetReingestJob(ctx context.Context, id int64) (ReingestJob, error) {
+	var job ReingestJob
+	sql := selectReingestJobs.Where(sq.Eq{"id": id}).Limit(1)
+	err := q.Get(ctx, &job, sql)
+	return job, err
+}
+
+// GetReingestJobs returns the most recent reingestion jobs, newest first.
+func (q *Q) GetReingestJobs(ctx context.Context, limit uint64) ([]ReingestJob, error) {
+	var jobs []ReingestJob
+	sql := selectReingestJobs.OrderBy("id desc").Limit(limit)
+	err := q.Select(ctx, &jobs, sql)
+	return jobs, err
+}
+
+// GetReingestBatches returns the batches of a reingestion job ordered by
+// ledger sequence.
+func (q *Q) GetReingestBatches(ctx context.Context, jobID int64) ([]ReingestBatch, error) {
+	var batches []ReingestBatch
+	sql := selectReingestBatches.Where(sq.Eq{"job_id": jobID}).OrderBy("start_ledger asc")
+	err := q.Select(ctx, &batches, sql)
+	return batches, err
+}
+
+// UpdateReingestBatchStatus updates the status of the batch of a reingestion
+// job starting at the given ledger. batchErr is recorded for failed batches.
+func (q *Q) UpdateReingestBatchStatus(
+	ctx context.Context, jobID int64, startSequence uint32, status string, batchErr error,
+) error {
+	now := time.Now().UTC()
+	sql := sq.Update("reingest_job_batches").
+		Set("status", status).
+		Where(sq.Eq{"job_id": jobID, "start_ledger": startSequence})
+	switch status {
+	case ReingestBatchRunning:
+		sql = sql.Set("started_at", now).Set("finished_at", nil).Set("error", nil)
+	case ReingestBatchDone:
+		sql = sql.Set("finished_at", now).Set("error", nil)
+	case ReingestBatchFailed:
+		var message null.String
+		if batchErr != nil {
+			message = null.StringFrom(batchErr.Error())
+		}
+		sql = sql.Set("finished_at", now).Set("error", message)
+	}
+
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows != 1 {
+		return errors.Errorf("batch %d of reingest job %d not found", startSequence, jobID)
+	}
+	return nil
+}
+
+// FinishReingestJob marks a reingestion job as finished.
+func (q *Q) FinishReingestJob(ctx context.Context, id int64) error {
+	sql := sq.Update("reingest_jobs").
+		Set("finished_at", time.Now().UTC()).
+		Where(sq.Eq{"id": id})
+	_, err := q.Exec(ctx, sql)
+	return err
+}
+
+var selectReingestJobs = sq.Select(
+	"id",
+	"created_at",
+	"finished_at",
+).From("reingest_jobs")
+
+var selectReingestBatches = sq.Select(
+	"job_id",
+	"start_ledger",
+	"end_ledger",
+	"status",
+	"error",
+	"started_at",
+	"finished_at",
+).From("reingest_job_batches")
@@ -0,0 +1,58 @@
+package history
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/diamnet/go/services/aurora/internal/test"
+	"github.com/diamnet/go/support/errors"
+)
+
+func TestReingestJobs(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	job, err := q.CreateReingestJob(tt.Ctx, []LedgerRange{
+		{StartSequence: 65, EndSequence: 128},
+		{StartSequence: 1, EndSequence: 64},
+	})
+	tt.Assert.NoError(err)
+	tt.Assert.False(job.FinishedAt.Valid)
+
+	batches, err := q.GetReingestBatches(tt.Ctx, job.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(batches, 2)
+	tt.Assert.Equal(LedgerRange{StartSequence: 1, EndSequence: 64}, batches[0].LedgerRange())
+	tt.Assert.Equal(ReingestBatchPending, batches[0].Status)
+
+	tt.Assert.NoError(q.UpdateReingestBatchStatus(tt.Ctx, job.ID, 1, ReingestBatchRunning, nil))
+	tt.Assert.NoError(q.UpdateReingestBatchStatus(tt.Ctx, job.ID, 1, ReingestBatchDone, nil))
+	tt.Assert.NoError(q.UpdateReingestBatchStatus(tt.Ctx, job.ID, 65, ReingestBatchRunning, nil))
+	tt.Assert.NoError(q.UpdateReingestBatchStatus(tt.Ctx, job.ID, 65, ReingestBatchFailed, errors.New("boom")))
+	tt.Assert.EqualError(
+		q.UpdateReingestBatchStatus(tt.Ctx, job.ID, 2, ReingestBatchDone, nil),
+		fmt.Sprintf("batch 2 of reingest job %d not found", job.ID),
+	)
+
+	batches, err = q.GetReingestBatches(tt.Ctx, job.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(ReingestBatchDone, batches[0].Status)
+	tt.Assert.True(batches[0].StartedAt.Valid)
+	tt.Assert.True(batches[0].FinishedAt.Valid)
+	tt.Assert.Equal(ReingestBatchFailed, batches[1].Status)
+	tt.Assert.Equal("boom", batches[1].Error.String)
+
+	tt.Assert.NoError(q.FinishReingestJob(tt.Ctx, job.ID))
+	job, err = q.GetReingestJob(tt.Ctx, job.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.True(job.FinishedAt.Valid)
+
+	_, err = q.CreateReingestJob(tt.Ctx, []LedgerRange{{StartSequence: 129, EndSequence: 192}})
+	tt.Assert.NoError(err)
+	jobs, err := q.GetReingestJobs(tt.Ctx, 10)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(jobs, 2)
+	tt.Assert.Equal(job.ID+1, jobs[0].ID)
+}
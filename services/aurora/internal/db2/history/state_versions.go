@@ -0,0 +1,263 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/diamnet/go/support/errors"
+)
+
+const (
+	stateVersionsStartLedger = "state_versions_start_ledger"
+	stateVersionsLastLedger  = "state_versions_last_ledger"
+)
+
+// stateTableColumns contains the columns of the state tables which are
+// archived in the corresponding *_versions tables.
+var stateTableColumns = map[string][]string{
+	"accounts": {
+		"account_id", "balance", "buying_liabilities", "selling_liabilities",
+		"sequence_number", "num_subentries", "inflation_destination", "flags",
+		"home_domain", "master_weight", "threshold_low", "threshold_medium",
+		"threshold_high", "last_modified_ledger", "sponsor", "num_sponsored",
+		"num_sponsoring",
+	},
+	"accounts_data": {
+		"ledger_key", "account_id", "name", "value", "last_modified_ledger",
+		"sponsor",
+	},
+	"accounts_signers": {
+		"account_id", "signer", "weight", "sponsor",
+	},
+	"claimable_balances": {
+		"id", "claimants", "asset", "amount", "sponsor", "last_modified_ledger",
+		"flags",
+	},
+	"offers": {
+		"seller_id", "offer_id", "selling_asset", "buying_asset", "amount",
+		"pricen", "priced", "price", "flags", "deleted", "last_modified_ledger",
+		"sponsor",
+	},
+	"trust_lines": {
+		"ledger_key", "account_id", "asset_type", "asset_issuer", "asset_code",
+		"liquidity_pool_id", "balance", "trust_line_limit", "buying_liabilities",
+		"selling_liabilities", "flags", "last_modified_ledger", "sponsor",
+	},
+}
+
+// QStateVersions defines the queries used to keep the previous versions of
+// the state tables rows, which allows querying the state as of an earlier
+// ledger.
+type QStateVersions interface {
+	GetStateVersionsLedgerRange(ctx context.Context) (uint32, uint32, error)
+	UpdateStateVersionsLedgerRange(ctx context.Context, start, last uint32) error
+	TruncateStateVersions(ctx context.Context) error
+	ArchiveAccounts(ctx context.Context, accountIDs []string, sequence uint32) error
+	ArchiveAccountData(ctx context.Context, keys []AccountDataKey, sequence uint32) error
+	ArchiveAccountSigners(ctx context.Context, accountIDs []string, sequence uint32) error
+	ArchiveClaimableBalances(ctx context.Context, ids []string, sequence uint32) error
+	ArchiveOffers(ctx context.Context, ids []int64, sequence uint32) error
+	ArchiveTrustLines(ctx context.Context, ledgerKeys []string, sequence uint32) error
+}
+
+// GetStateVersionsLedgerRange returns the first and the last ledger for which
+// the versions of the state tables rows were kept. Both are 0 if versioned
+// state was never ingested since the state tables were last rebuilt.
+func (q *Q) GetStateVersionsLedgerRange(ctx context.Context) (uint32, uint32, error) {
+	start, err := q.getIntValueFromStore(ctx, stateVersionsStartLedger, 32)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Error getting state versions start ledger")
+	}
+	last, err := q.getIntValueFromStore(ctx, stateVersionsLastLedger, 32)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Error getting state versions last ledger")
+	}
+	return uint32(start), uint32(last), nil
+}
+
+// UpdateStateVersionsLedgerRange sets the first and the last ledger for which
+// the versions of the state tables rows were kept.
+func (q *Q) UpdateStateVersionsLedgerRange(ctx context.Context, start, last uint32) error {
+	err := q.updateValueInStore(ctx, stateVersionsStartLedger, strconv.FormatUint(uint64(start), 10))
+	if err != nil {
+		return errors.Wrap(err, "Error updating state versions start ledger")
+	}
+	err = q.updateValueInStore(ctx, stateVersionsLastLedger, strconv.FormatUint(uint64(last), 10))
+	if err != nil {
+		return errors.Wrap(err, "Error updating state versions last ledger")
+	}
+	return nil
+}
+
+// TruncateStateVersions removes all the versions of the state tables rows and
+// resets the range of ledgers they cover.
+func (q *Q) TruncateStateVersions(ctx context.Context) error {
+	if err := q.TruncateTables(ctx, stateVersionsTables()); err != nil {
+		return err
+	}
+	return q.UpdateStateVersionsLedgerRange(ctx, 0, 0)
+}
+
+// DeleteStateVersionsBefore removes the versions of the state tables rows
+// which were replaced before the given ledger, as the state as of earlier
+// ledgers can't be queried anymore.
+func (q *Q) DeleteStateVersionsBefore(ctx context.Context, sequence uint32) error {
+	for _, table := range stateVersionsTables() {
+		_, err := q.ExecRaw(ctx, "DELETE FROM "+table+" WHERE valid_to <= ?", sequence)
+		if err != nil {
+			return errors.Wrapf(err, "Error clearing %s", table)
+		}
+	}
+	return nil
+}
+
+// ArchiveAccounts keeps the current version of the given accounts, which are
+// about to be updated or removed in the given ledger.
+func (q *Q) ArchiveAccounts(ctx context.Context, accountIDs []string, sequence uint32) error {
+	return q.archiveStateRows(ctx, "accounts", "account_id", pq.Array(accountIDs), sequence)
+}
+
+// ArchiveAccountData keeps the current version of the given account data
+// entries, which are about to be updated or removed in the given ledger.
+func (q *Q) ArchiveAccountData(ctx context.Context, keys []AccountDataKey, sequence uint32) error {
+	ledgerKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ledgerKey, err := accountDataKeyToString(key)
+		if err != nil {
+			return err
+		}
+		ledgerKeys = append(ledgerKeys, ledgerKey)
+	}
+	return q.archiveStateRows(ctx, "accounts_data", "ledger_key", pq.Array(ledgerKeys), sequence)
+}
+
+// ArchiveAccountSigners keeps the current signers of the given accounts, which
+// are about to be updated or removed in the given ledger. The signers of an
+// account are only archived once per ledger.
+func (q *Q) ArchiveAccountSigners(ctx context.Context, accountIDs []string, sequence uint32) error {
+	columns := strings.Join(stateTableColumns["accounts_signers"], ", ")
+	sql := fmt.Sprintf(
+		`INSERT INTO accounts_signers_versions (%s, valid_to)
+		SELECT %s, ? FROM accounts_signers
+		WHERE account_id = ANY(?) AND account_id NOT IN (
+			SELECT account_id FROM accounts_signers_versions WHERE valid_to = ?
+		)`,
+		columns, columns,
+	)
+	_, err := q.ExecRaw(ctx, sql, sequence, pq.Array(accountIDs), sequence)
+	return errors.Wrap(err, "could not archive accounts_signers")
+}
+
+// ArchiveClaimableBalances keeps the current version of the given claimable
+// balances, which are about to be updated or removed in the given ledger.
+func (q *Q) ArchiveClaimableBalances(ctx context.Context, ids []string, sequence uint32) error {
+	return q.archiveStateRows(ctx, "claimable_balances", "id", pq.Array(ids), sequence)
+}
+
+// ArchiveOffers keeps the current version of the given offers, which are
+// about to be updated or removed in the given ledger.
+func (q *Q) ArchiveOffers(ctx context.Context, ids []int64, sequence uint32) error {
+	return q.archiveStateRows(ctx, "offers", "offer_id", pq.Array(ids), sequence)
+}
+
+// ArchiveTrustLines keeps the current version of the given trust lines, which
+// are about to be updated or removed in the given ledger.
+func (q *Q) ArchiveTrustLines(ctx context.Context, ledgerKeys []string, sequence uint32) error {
+	return q.archiveStateRows(ctx, "trust_lines", "ledger_key", pq.Array(ledgerKeys), sequence)
+}
+
+// archiveStateRows copies the rows of a state table into its versions table.
+// Rows which were already modified in the given ledger are skipped, their
+// previous version was archived when they were first modified in a batch
+// committed earlier in the same ledger.
+func (q *Q) archiveStateRows(ctx context.Context, table, keyColumn string, keys interface{}, sequence uint32) error {
+	columns := strings.Join(stateTableColumns[table], ", ")
+	sql := fmt.Sprintf(
+		`INSERT INTO %s_versions (%s, valid_to)
+		SELECT %s, ? FROM %s
+		WHERE %s = ANY(?) AND last_modified_ledger < ?`,
+		table, columns, columns, table, keyColumn,
+	)
+	if table == "offers" {
+		sql += " AND deleted = false"
+	}
+	_, err := q.ExecRaw(ctx, sql, sequence, keys, sequence)
+	return errors.Wrapf(err, "could not archive %s", table)
+}
+
+func stateVersionsTables() []string {
+	tables := make([]string, 0, len(stateTableColumns))
+	for table := range stateTableColumns {
+		tables = append(tables, table+"_versions")
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+type asOfLedgerKey struct{}
+
+// WithAsOfLedger returns a context in which the queries of the state tables
+// (accounts, signers, data, trust lines, offers and claimable balances) return
+// the state as of the end of the given ledger instead of the latest state.
+// The ledger must be within the range returned by GetStateVersionsLedgerRange.
+func WithAsOfLedger(ctx context.Context, sequence uint32) context.Context {
+	return context.WithValue(ctx, asOfLedgerKey{}, sequence)
+}
+
+// AsOfLedger returns the ledger set with WithAsOfLedger, if any.
+func AsOfLedger(ctx context.Context) (uint32, bool) {
+	sequence, ok := ctx.Value(asOfLedgerKey{}).(uint32)
+	return sequence, ok
+}
+
+// stateTable returns the expression to select rows of a state table from,
+// which is the table itself unless the context requests the state as of an
+// earlier ledger.
+func stateTable(ctx context.Context, table, alias string) string {
+	sequence, ok := AsOfLedger(ctx)
+	if !ok {
+		if alias == table {
+			return table
+		}
+		return table + " " + alias
+	}
+
+	columns := strings.Join(stateTableColumns[table], ", ")
+	var sql string
+	if table == "accounts_signers" {
+		// The signers of an account at the given ledger are the earliest set
+		// archived after it or the current set if there is none.
+		sql = fmt.Sprintf(
+			`SELECT %[1]s FROM accounts_signers s
+			WHERE NOT EXISTS (
+				SELECT 1 FROM accounts_signers_versions v
+				WHERE v.account_id = s.account_id AND v.valid_to > %[2]d
+			)
+			UNION ALL
+			SELECT %[1]s FROM accounts_signers_versions v
+			WHERE v.valid_to = (
+				SELECT MIN(w.valid_to) FROM accounts_signers_versions w
+				WHERE w.account_id = v.account_id AND w.valid_to > %[2]d
+			)`,
+			columns, sequence,
+		)
+	} else {
+		current := fmt.Sprintf("last_modified_ledger <= %d", sequence)
+		if table == "offers" {
+			current += " AND deleted = false"
+		}
+		sql = fmt.Sprintf(
+			`SELECT %[1]s FROM %[2]s WHERE %[3]s
+			UNION ALL
+			SELECT %[1]s FROM %[2]s_versions
+			WHERE last_modified_ledger <= %[4]d AND valid_to > %[4]d`,
+			columns, table, current, sequence,
+		)
+	}
+	return "(" + sql + ") AS " + alias
+}
@@ -0,0 +1,159 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/diamnet/go/services/aurora/internal/db2"
+	"github.com/diamnet/go/services/aurora/internal/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountsAsOfLedger(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	// account1 is updated in ledger 1240 and account2 removed in ledger 1241
+	assert.NoError(t, q.UpsertAccounts(tt.Ctx, []AccountEntry{account1, account2}))
+	assert.NoError(t, q.ArchiveAccounts(tt.Ctx, []string{account1.AccountID}, 1240))
+	updated := account1
+	updated.Balance = 1
+	updated.LastModifiedLedger = 1240
+	assert.NoError(t, q.UpsertAccounts(tt.Ctx, []AccountEntry{updated}))
+
+	// Rows already modified in the ledger are not archived again
+	assert.NoError(t, q.ArchiveAccounts(tt.Ctx, []string{account1.AccountID}, 1240))
+
+	assert.NoError(t, q.ArchiveAccounts(tt.Ctx, []string{account2.AccountID}, 1241))
+	_, err := q.RemoveAccounts(tt.Ctx, []string{account2.AccountID})
+	assert.NoError(t, err)
+
+	for _, testCase := range []struct {
+		sequence uint32
+		expected map[string]AccountEntry
+	}{
+		{1234, map[string]AccountEntry{account1.AccountID: account1}},
+		{1239, map[string]AccountEntry{account1.AccountID: account1, account2.AccountID: account2}},
+		{1240, map[string]AccountEntry{account1.AccountID: updated, account2.AccountID: account2}},
+		{1241, map[string]AccountEntry{account1.AccountID: updated}},
+	} {
+		ctx := WithAsOfLedger(tt.Ctx, testCase.sequence)
+		for _, id := range []string{account1.AccountID, account2.AccountID} {
+			account, err := q.GetAccountByID(ctx, id)
+			expected, ok := testCase.expected[id]
+			if !ok {
+				assert.True(t, q.NoRows(err))
+				continue
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, expected, account)
+		}
+	}
+
+	account, err := q.GetAccountByID(tt.Ctx, account1.AccountID)
+	assert.NoError(t, err)
+	assert.Equal(t, updated, account)
+
+	assert.NoError(t, q.DeleteStateVersionsBefore(tt.Ctx, 1240))
+	_, err = q.GetAccountByID(WithAsOfLedger(tt.Ctx, 1239), account1.AccountID)
+	assert.True(t, q.NoRows(err))
+	_, err = q.GetAccountByID(WithAsOfLedger(tt.Ctx, 1240), account2.AccountID)
+	assert.NoError(t, err)
+}
+
+func TestOffersAsOfLedger(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	assert.NoError(t, insertOffer(tt, q, eurOffer))
+	assert.NoError(t, q.ArchiveOffers(tt.Ctx, []int64{eurOffer.OfferID}, 1240))
+	deleted := eurOffer
+	deleted.Deleted = true
+	deleted.LastModifiedLedger = 1240
+	assert.NoError(t, insertOffer(tt, q, deleted))
+
+	offer, err := q.GetOfferByID(WithAsOfLedger(tt.Ctx, 1239), eurOffer.OfferID)
+	assert.NoError(t, err)
+	assert.Equal(t, eurOffer, offer)
+
+	pageQuery, err := db2.NewPageQuery("", false, "", 10)
+	assert.NoError(t, err)
+	offers, err := q.GetOffers(WithAsOfLedger(tt.Ctx, 1239), OffersQuery{
+		PageQuery: pageQuery,
+		SellerID:  eurOffer.SellerID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Offer{eurOffer}, offers)
+
+	_, err = q.GetOfferByID(WithAsOfLedger(tt.Ctx, 1240), eurOffer.OfferID)
+	assert.True(t, q.NoRows(err))
+}
+
+func TestAccountSignersAsOfLedger(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	account := account1.AccountID
+	_, err := q.CreateAccountSigner(tt.Ctx, account, account, 1, nil)
+	assert.NoError(t, err)
+
+	// A signer is added in ledger 1240 and the master key removed in 1242
+	assert.NoError(t, q.ArchiveAccountSigners(tt.Ctx, []string{account}, 1240))
+	_, err = q.CreateAccountSigner(tt.Ctx, account, account2.AccountID, 2, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, q.ArchiveAccountSigners(tt.Ctx, []string{account}, 1240))
+	assert.NoError(t, q.ArchiveAccountSigners(tt.Ctx, []string{account}, 1242))
+	_, err = q.RemoveAccountSigner(tt.Ctx, account, account)
+	assert.NoError(t, err)
+
+	for sequence, expected := range map[uint32][]string{
+		1239: {account},
+		1240: {account, account2.AccountID},
+		1241: {account, account2.AccountID},
+		1242: {account2.AccountID},
+	} {
+		signers, err := q.GetAccountSignersByAccountID(WithAsOfLedger(tt.Ctx, sequence), account)
+		assert.NoError(t, err)
+		var actual []string
+		for _, signer := range signers {
+			actual = append(actual, signer.Signer)
+		}
+		assert.ElementsMatch(t, expected, actual, "as of ledger %d", sequence)
+	}
+}
+
+func TestStateVersionsLedgerRange(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	start, last, err := q.GetStateVersionsLedgerRange(tt.Ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), start)
+	assert.Equal(t, uint32(0), last)
+
+	assert.NoError(t, q.UpdateStateVersionsLedgerRange(tt.Ctx, 100, 120))
+	assert.NoError(t, q.UpsertAccounts(tt.Ctx, []AccountEntry{account1}))
+	assert.NoError(t, q.ArchiveAccounts(tt.Ctx, []string{account1.AccountID}, 1240))
+
+	start, last, err = q.GetStateVersionsLedgerRange(tt.Ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(100), start)
+	assert.Equal(t, uint32(120), last)
+
+	assert.NoError(t, q.TruncateIngestStateTables(tt.Ctx))
+	start, last, err = q.GetStateVersionsLedgerRange(tt.Ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), start)
+	assert.Equal(t, uint32(0), last)
+
+	var count int
+	assert.NoError(t, q.GetRaw(tt.Ctx, &count, "SELECT count(*) FROM accounts_versions"))
+	assert.Equal(t, 0, count)
+}
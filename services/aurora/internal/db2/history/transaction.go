@@ -103,6 +103,22 @@ func (q *TransactionsQ) ForAccount(ctx context.Context, aid string) *Transaction
 	return q
 }
 
+// ForMuxedAccount filters the transactions collection to a specific muxed
+// account, specified by its M-address.
+func (q *TransactionsQ) ForMuxedAccount(ctx context.Context, address string) *TransactionsQ {
+	var account MuxedAccount
+	account, q.Err = q.parent.MuxedAccountByAddress(ctx, address)
+	if q.Err != nil {
+		return q
+	}
+
+	q.sql = q.sql.
+		Join("history_transaction_muxed_participants htmp ON htmp.history_transaction_id = ht.id").
+		Where("htmp.history_muxed_account_id = ?", account.ID)
+
+	return q
+}
+
 // ForClaimableBalance filters the transactions collection to a specific claimable balance
 func (q *TransactionsQ) ForClaimableBalance(ctx context.Context, cbID string) *TransactionsQ {
 
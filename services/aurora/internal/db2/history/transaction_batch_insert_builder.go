@@ -46,6 +46,13 @@ func (q *Q) NewTransactionBatchInsertBuilder(maxBatchSize int) TransactionBatchI
 	}
 }
 
+// NewTransactionRow returns the row of the `history_transactions` table of a
+// transaction included in the given ledger.
+func NewTransactionRow(transaction ingest.LedgerTransaction, sequence uint32) (TransactionWithoutLedger, error) {
+	builder := &transactionBatchInsertBuilder{encodingBuffer: xdr.NewEncodingBuffer()}
+	return builder.transactionToRow(transaction, sequence)
+}
+
 // Add adds a new transaction to the batch
 func (i *transactionBatchInsertBuilder) Add(ctx context.Context, transaction ingest.LedgerTransaction, sequence uint32) error {
 	row, err := i.transactionToRow(transaction, sequence)
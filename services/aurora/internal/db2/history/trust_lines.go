@@ -109,7 +109,9 @@ func (q *Q) RemoveTrustLines(ctx context.Context, ledgerKeys []string) (int64, e
 // GetSortedTrustLinesByAccountIDs loads trust lines for a list of accounts ID, ordered by asset and issuer
 func (q *Q) GetSortedTrustLinesByAccountIDs(ctx context.Context, id []string) ([]TrustLine, error) {
 	var data []TrustLine
-	sql := selectTrustLines.Where(sq.Eq{"account_id": id}).
+	sql := selectTrustLines.
+		From(stateTable(ctx, "trust_lines", "trust_lines")).
+		Where(sq.Eq{"account_id": id}).
 		OrderBy("asset_code", "asset_issuer", "liquidity_pool_id")
 	err := q.Select(ctx, &data, sql)
 	return data, err
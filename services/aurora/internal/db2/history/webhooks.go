@@ -0,0 +1,231 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+
+	"github.com/diamnet/go/services/aurora/internal/db2"
+	"github.com/diamnet/go/support/errors"
+)
+
+// webhookSubscriptionsLockID is the first key of the advisory locks taken
+// while the events of a webhook subscription are dispatched, the second one is
+// the subscription ID.
+const webhookSubscriptionsLockID = 0x77656268
+
+// WebhookSubscription is a row of data from the `webhook_subscriptions` table.
+// A subscription matches an operation when every non-empty filter matches.
+type WebhookSubscription struct {
+	ID             int64         `db:"id"`
+	URL            string        `db:"url"`
+	Secret         string        `db:"secret"`
+	AccountID      null.String   `db:"account_id"`
+	AssetType      null.String   `db:"asset_type"`
+	AssetCode      null.String   `db:"asset_code"`
+	AssetIssuer    null.String   `db:"asset_issuer"`
+	OperationTypes pq.Int64Array `db:"operation_types"`
+	// Cursor is the ID of the last operation delivered (or dead-lettered)
+	// for this subscription.
+	Cursor    int64     `db:"cursor"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// WebhookDeadLetter is a row of data from the `webhook_dead_letters` table.
+// It contains a payload which could not be delivered after all retries.
+type WebhookDeadLetter struct {
+	ID             int64           `db:"id"`
+	SubscriptionID int64           `db:"subscription_id"`
+	OperationID    int64           `db:"operation_id"`
+	Payload        json.RawMessage `db:"payload"`
+	Attempts       int             `db:"attempts"`
+	LastError      string          `db:"last_error"`
+	CreatedAt      time.Time       `db:"created_at"`
+}
+
+// QWebhooks defines webhook related queries.
+type QWebhooks interface {
+	InsertWebhookSubscription(ctx context.Context, subscription WebhookSubscription) (WebhookSubscription, error)
+	GetWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error)
+	GetWebhookSubscriptionByID(ctx context.Context, id int64) (WebhookSubscription, error)
+	RemoveWebhookSubscription(ctx context.Context, id int64) (int64, error)
+	UpdateWebhookSubscriptionCursor(ctx context.Context, id int64, cursor int64) error
+	TryLockWebhookSubscription(ctx context.Context, id int64) (func(), bool, error)
+	InsertWebhookDeadLetter(ctx context.Context, deadLetter WebhookDeadLetter) error
+	GetWebhookDeadLetters(ctx context.Context, subscriptionID int64) ([]WebhookDeadLetter, error)
+	GetWebhookOperations(ctx context.Context, accountID string, cursor, maxID int64, limit uint64) ([]Operation, []Transaction, error)
+}
+
+// InsertWebhookSubscription creates a new webhook subscription and returns
+// it with the ID and creation time populated.
+func (q *Q) InsertWebhookSubscription(ctx context.Context, subscription WebhookSubscription) (WebhookSubscription, error) {
+	sql := sq.Insert("webhook_subscriptions").
+		SetMap(map[string]interface{}{
+			"url":             subscription.URL,
+			"secret":          subscription.Secret,
+			"account_id":      subscription.AccountID,
+			"asset_type":      subscription.AssetType,
+			"asset_code":      subscription.AssetCode,
+			"asset_issuer":    subscription.AssetIssuer,
+			"operation_types": subscription.OperationTypes,
+			"cursor":          subscription.Cursor,
+		}).
+		Suffix("RETURNING id, created_at")
+
+	var inserted struct {
+		ID        int64     `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	if err := q.Get(ctx, &inserted, sql); err != nil {
+		return WebhookSubscription{}, errors.Wrap(err, "could not insert webhook subscription")
+	}
+
+	subscription.ID = inserted.ID
+	subscription.CreatedAt = inserted.CreatedAt
+	return subscription, nil
+}
+
+// GetWebhookSubscriptions returns all webhook subscriptions ordered by ID.
+func (q *Q) GetWebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+	sql := selectWebhookSubscriptions.OrderBy("id asc")
+	err := q.Select(ctx, &subscriptions, sql)
+	return subscriptions, err
+}
+
+// GetWebhookSubscriptionByID returns a webhook subscription by ID.
+func (q *Q) GetWebhookSubscriptionByID(ctx context.Context, id int64) (WebhookSubscription, error) {
+	var subscription WebhookSubscription
+	sql := selectWebhookSubscriptions.Where(sq.Eq{"id": id}).Limit(1)
+	err := q.Get(ctx, &subscription, sql)
+	return subscription, err
+}
+
+// RemoveWebhookSubscription deletes a webhook subscription together with its
+// dead letters. Returns number of rows affected and error.
+func (q *Q) RemoveWebhookSubscription(ctx context.Context, id int64) (int64, error) {
+	sql := sq.Delete("webhook_subscriptions").Where(sq.Eq{"id": id})
+	result, err := q.Exec(ctx, sql)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// UpdateWebhookSubscriptionCursor moves the cursor of a webhook subscription.
+// The cursor never moves backwards.
+func (q *Q) UpdateWebhookSubscriptionCursor(ctx context.Context, id int64, cursor int64) error {
+	sql := sq.Update("webhook_subscriptions").
+		Set("cursor", cursor).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Lt{"cursor": cursor})
+	_, err := q.Exec(ctx, sql)
+	return err
+}
+
+// TryLockWebhookSubscription takes the advisory lock of a webhook subscription
+// so a single Aurora node dispatches its events at a time. The lock is held by
+// a transaction on a separate session until the returned function is called.
+// It returns false, without waiting, when the subscription is locked by
+// another node.
+func (q *Q) TryLockWebhookSubscription(ctx context.Context, id int64) (func(), bool, error) {
+	session := &Q{q.Clone()}
+	if err := session.Begin(); err != nil {
+		return nil, false, errors.Wrap(err, "could not begin transaction")
+	}
+	unlock := func() {
+		session.Rollback()
+	}
+
+	var locked bool
+	err := session.GetRaw(ctx, &locked, "SELECT pg_try_advisory_xact_lock(?, ?)", webhookSubscriptionsLockID, int32(id))
+	if err != nil {
+		unlock()
+		return nil, false, errors.Wrap(err, "could not lock webhook subscription")
+	}
+	if !locked {
+		unlock()
+		return nil, false, nil
+	}
+	return unlock, true, nil
+}
+
+// InsertWebhookDeadLetter stores a payload which could not be delivered.
+func (q *Q) InsertWebhookDeadLetter(ctx context.Context, deadLetter WebhookDeadLetter) error {
+	sql := sq.Insert("webhook_dead_letters").
+		SetMap(map[string]interface{}{
+			"subscription_id": deadLetter.SubscriptionID,
+			"operation_id":    deadLetter.OperationID,
+			"payload":         []byte(deadLetter.Payload),
+			"attempts":        deadLetter.Attempts,
+			"last_error":      deadLetter.LastError,
+		})
+	_, err := q.Exec(ctx, sql)
+	return err
+}
+
+// GetWebhookDeadLetters returns all dead letters of a given subscription
+// ordered by ID.
+func (q *Q) GetWebhookDeadLetters(ctx context.Context, subscriptionID int64) ([]WebhookDeadLetter, error) {
+	var deadLetters []WebhookDeadLetter
+	sql := selectWebhookDeadLetters.
+		Where(sq.Eq{"subscription_id": subscriptionID}).
+		OrderBy("id asc")
+	err := q.Select(ctx, &deadLetters, sql)
+	return deadLetters, err
+}
+
+// GetWebhookOperations returns up to `limit` operations of successful
+// transactions with IDs in (cursor, maxID] in ascending order, together with
+// their transactions. When accountID is not empty only operations the account
+// participated in are returned.
+func (q *Q) GetWebhookOperations(ctx context.Context, accountID string, cursor, maxID int64, limit uint64) ([]Operation, []Transaction, error) {
+	query := q.Operations().IncludeTransactions()
+	if accountID != "" {
+		query.ForAccount(ctx, accountID)
+		if q.NoRows(query.Err) {
+			// The account has not participated in any operation yet.
+			return nil, nil, nil
+		}
+	}
+
+	query.Page(db2.PageQuery{
+		Cursor: strconv.FormatInt(cursor, 10),
+		Order:  db2.OrderAscending,
+		Limit:  limit,
+	})
+	if query.Err == nil {
+		query.sql = query.sql.Where(sq.LtOrEq{query.opIdCol: maxID})
+	}
+
+	return query.Fetch(ctx)
+}
+
+var selectWebhookSubscriptions = sq.Select(
+	"id",
+	"url",
+	"secret",
+	"account_id",
+	"asset_type",
+	"asset_code",
+	"asset_issuer",
+	"operation_types",
+	"cursor",
+	"created_at",
+).From("webhook_subscriptions")
+
+var selectWebhookDeadLetters = sq.Select(
+	"id",
+	"subscription_id",
+	"operation_id",
+	"payload",
+	"attempts",
+	"last_error",
+	"created_at",
+).From("webhook_dead_letters")
@@ -0,0 +1,79 @@
+package history
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+
+	"github.com/diamnet/go/services/aurora/internal/test"
+	"github.com/diamnet/go/xdr"
+)
+
+func TestWebhookSubscriptions(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	subscription, err := q.InsertWebhookSubscription(tt.Ctx, WebhookSubscription{
+		URL:            "https://example.com/hook",
+		Secret:         "s3cr3t",
+		AccountID:      null.StringFrom("GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"),
+		OperationTypes: pq.Int64Array{int64(xdr.OperationTypePayment)},
+	})
+	tt.Assert.NoError(err)
+	tt.Assert.NotZero(subscription.ID)
+
+	subscriptions, err := q.GetWebhookSubscriptions(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(subscriptions, 1)
+	tt.Assert.Equal(subscription.URL, subscriptions[0].URL)
+	tt.Assert.Equal(subscription.AccountID, subscriptions[0].AccountID)
+	tt.Assert.Equal(subscription.OperationTypes, subscriptions[0].OperationTypes)
+	tt.Assert.False(subscriptions[0].AssetCode.Valid)
+
+	tt.Assert.NoError(q.UpdateWebhookSubscriptionCursor(tt.Ctx, subscription.ID, 100))
+	// cursor never moves backwards
+	tt.Assert.NoError(q.UpdateWebhookSubscriptionCursor(tt.Ctx, subscription.ID, 50))
+	loaded, err := q.GetWebhookSubscriptionByID(tt.Ctx, subscription.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(100), loaded.Cursor)
+
+	unlock, locked, err := q.TryLockWebhookSubscription(tt.Ctx, subscription.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.True(locked)
+	// another node cannot dispatch the subscription until it is unlocked
+	_, locked, err = q.TryLockWebhookSubscription(tt.Ctx, subscription.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.False(locked)
+	unlock()
+	unlock, locked, err = q.TryLockWebhookSubscription(tt.Ctx, subscription.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.True(locked)
+	unlock()
+
+	payload := json.RawMessage(`{"id":"100"}`)
+	tt.Assert.NoError(q.InsertWebhookDeadLetter(tt.Ctx, WebhookDeadLetter{
+		SubscriptionID: subscription.ID,
+		OperationID:    100,
+		Payload:        payload,
+		Attempts:       5,
+		LastError:      "connection refused",
+	}))
+
+	deadLetters, err := q.GetWebhookDeadLetters(tt.Ctx, subscription.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(deadLetters, 1)
+	tt.Assert.Equal(int64(100), deadLetters[0].OperationID)
+	tt.Assert.JSONEq(string(payload), string(deadLetters[0].Payload))
+
+	removed, err := q.RemoveWebhookSubscription(tt.Ctx, subscription.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(1), removed)
+
+	deadLetters, err = q.GetWebhookDeadLetters(tt.Ctx, subscription.ID)
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(deadLetters)
+}
@@ -47,7 +47,18 @@
 // migrations/4_add_protocol_version.sql (188B)
 // migrations/50_liquidity_pools.sql (3.876kB)
 // migrations/51_remove_ht_unused_indexes.sql (321B)
+// migrations/52_webhooks.sql (1.008kB)
+// migrations/53_api_keys.sql (714B)
+// migrations/54_state_versions.sql (2.725kB)
+// migrations/55_ingest_filters.sql (415B)
+// migrations/56_ingest_plugin_ledgers.sql (472B)
+// migrations/57_reingest_jobs.sql (783B)
+// migrations/58_history_partitions.sql (728B)
+// migrations/59_history_cold_transactions.sql (407B)
 // migrations/5_create_trades_table.sql (1.1kB)
+// migrations/60_history_asset_stats.sql (779B)
+// migrations/61_history_liquidity_pool_stats.sql (902B)
+// migrations/62_history_muxed_participants.sql (1.827kB)
 // migrations/6_create_assets_table.sql (366B)
 // migrations/7_modify_trades_table.sql (2.303kB)
 // migrations/8_add_aggregators.sql (907B)
@@ -1061,6 +1072,166 @@ func migrations51_remove_ht_unused_indexesSql() (*asset, error) {
 	return a, nil
 }
 
+var _migrations52_webhooksSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xb4\x93\x41\x6f\xd3\x40\x10\x85\xef\xfe\x15\x73\x4c\x44\x2b\x01\x82\x5e\x7a\x72\xe3\x2d\x8a\x30\x4e\xe5\x38\x12\x15\x42\xd6\x78\x77\x94\x0c\x38\xbb\xd6\xec\x98\x10\x7e\x3d\x4a\x13\x95\x26\x71\x7a\xe3\x3a\xef\xb3\x67\xdf\x7b\xbb\xd7\xd7\xf0\x66\xcd\x4b\x41\x25\x58\x74\x49\x32\x29\x4d\x5a\x19\xa8\xd2\xbb\xdc\xc0\x86\x9a\x55\x08\x3f\xeb\xd8\x37\xd1\x0a\x77\xca\xc1\x47\x18\x25\x00\x00\xec\xa0\xe1\x65\x24\x61\x6c\xaf\x9e\x26\xbd\xb4\xa0\xf4\x5b\xa1\x98\x55\x50\x2c\xf2\x7c\x3f\x8e\x64\x85\x74\x48\x41\x6b\x43\xef\xb5\x66\x07\x76\x85\x82\x56\x49\xe0\x17\xca\x96\xfd\x72\xf4\xf1\x66\x7c\xa0\x62\x24\xad\x75\xdb\xd1\x00\x75\xf3\xe1\x88\xb2\xc1\x0d\x51\xef\xde\x1f\x51\x1c\x63\x4f\xf2\xda\xce\xd0\x91\xe0\xce\xed\xd3\xde\x08\xec\x95\x96\x24\xdf\xbe\xef\x65\xdb\x4b\x0c\xb2\xf3\xcf\xfe\x9f\x29\xc8\xcc\x7d\xba\xc8\x2b\x78\x7b\xa0\x84\x50\xc9\xd5\xa8\xa0\xbc\xa6\xa8\xb8\xee\x60\xc3\xba\x0a\xfd\x7e\x02\x7f\x82\xa7\xf3\xcf\x7d\xd8\x8c\x0e\xe7\x78\x28\xa7\x5f\xd2\xf2\x11\x3e\x9b\x47\x18\xb1\x1b\x27\xe3\xdb\x0b\x15\x39\x42\x57\xb7\xa4\x4a\x72\xb9\xa1\x97\x3d\xd6\xec\xce\x0c\x94\xe6\xde\x94\xa6\x98\x98\xf9\xa5\xea\xd9\x8d\x61\x56\x40\x66\x72\x53\x19\x98\xa4\xf3\x49\x9a\x99\xd3\xcc\xce\xff\xbc\x27\x3a\xdc\xb6\x01\x1d\xfc\x88\xc1\x37\x27\x1a\xaa\xd2\xba\xd3\xe7\xa8\x4f\xe4\x16\xa3\xd6\x24\x12\x64\xe8\x22\xfd\xe7\xa4\xa7\x45\x66\xbe\x0e\x26\x5d\x37\xdb\xa3\x84\x76\xd9\x0c\x71\xb0\x98\x4f\x8b\x4f\x70\x57\x95\xc6\xc0\xe8\xa4\x86\x2b\x60\xb7\xdb\xf6\xf2\x29\x66\x61\xe3\x93\x24\x2b\x67\x0f\xaf\xf5\x6c\x31\x5a\x74\x74\x3b\x04\x1e\x17\xf7\x4c\xfe\x1d\x00\x78\x63\x8f\xc1\xf0\x03\x00\x00")
+
+func migrations52_webhooksSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations52_webhooksSql,
+		"migrations/52_webhooks.sql",
+	)
+}
+
+func migrations52_webhooksSql() (*asset, error) {
+	bytes, err := migrations52_webhooksSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/52_webhooks.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xc2, 0x85, 0x70, 0xaf, 0x18, 0x5a, 0xb5, 0xc6, 0x75, 0x89, 0xbd, 0xfe, 0xa6, 0x96, 0x85, 0xf7, 0x5c, 0x2a, 0xb, 0x5d, 0x10, 0xcc, 0x1d, 0xa4, 0xcd, 0x58, 0x4f, 0x34, 0x7b, 0x48, 0x91, 0x90}}
+	return a, nil
+}
+
+var _migrations53_api_keysSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8c\x92\x4d\x6f\x82\x40\x10\x86\xef\xfc\x8a\xb9\x09\x69\xbd\x35\xbd\x78\x42\x59\x1b\x52\x8a\x16\x21\xa9\xa7\xcd\x0a\x13\x99\x28\x1f\xdd\x1d\x6b\xe9\xaf\x6f\x90\x48\xad\xda\xd8\xeb\xcc\xc3\x9b\x77\x1e\x76\x38\x84\xbb\x82\xd6\x5a\x31\x42\x52\x5b\xd6\x24\x12\x6e\x2c\x20\x76\xc7\x81\x80\x76\x2a\xb7\x54\x10\x4b\x26\xd4\x06\x6c\x0b\x00\xa0\x54\x05\x42\x9a\x2b\xad\x52\x46\x0d\x1f\x4a\x37\x54\xae\xed\xc7\x07\x07\xc2\x59\x0c\x61\x12\x04\xf7\x07\x50\xe3\xfb\x0e\x0d\x1b\x59\xa3\x96\x79\xb5\xd3\xb0\xa2\x35\x95\x7c\x86\x19\xd6\xa8\x8a\x5b\xd4\x3c\xf2\x5f\xdc\x68\x09\xcf\x62\x09\x76\x5b\xc1\xb1\x9c\xd1\x59\x61\x55\x93\xdc\x60\x73\x2c\x4a\x59\x1b\x65\x50\x93\xda\x76\x21\x1b\x6c\x64\xae\x4c\xfe\xaf\xfa\xed\xcd\x37\x40\x88\xc4\x54\x44\x22\x9c\x88\xc5\x15\x5b\x87\x96\x5d\x56\x86\x26\xd5\x54\x33\x55\x25\x30\x7e\xfe\x1c\x07\x9e\x98\xba\x49\x10\xc3\x60\xd0\x91\xa9\x46\xc5\x98\x49\xc5\xc0\x54\xa0\x61\x55\xd4\xb0\x27\xce\xab\x5d\x37\x81\xaf\xaa\xc4\xcb\xef\xcb\x6a\x6f\x3b\x57\x54\x51\xf6\x4b\x54\x12\xfa\xaf\x89\x00\x3f\xf4\xc4\x5b\xef\x4b\xae\x1a\xd9\xab\x99\x85\xfd\x1c\x92\x85\x1f\x3e\xc1\x38\x8e\x84\x00\xfb\x48\x38\xa3\x63\xd8\x65\xca\xc1\xd9\x9f\x09\xed\xb6\xed\x72\xfa\xea\xbc\x6a\x5f\x5a\x96\x17\xcd\xe6\xe7\x3f\x31\x55\x26\x55\x19\x8e\x4e\x97\x17\x92\x7b\xe8\x7b\x00\x86\x69\x15\x65\xca\x02\x00\x00")
+
+func migrations53_api_keysSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations53_api_keysSql,
+		"migrations/53_api_keys.sql",
+	)
+}
+
+func migrations53_api_keysSql() (*asset, error) {
+	bytes, err := migrations53_api_keysSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/53_api_keys.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xf8, 0x6f, 0xf5, 0x2f, 0x5a, 0x53, 0x6f, 0xf4, 0x4, 0x7c, 0x7e, 0x60, 0x58, 0x35, 0x8b, 0xe1, 0x9a, 0x63, 0x88, 0xf6, 0x53, 0xe3, 0x1d, 0xe1, 0xbe, 0x38, 0x35, 0x9e, 0x90, 0xc5, 0xb, 0xb2}}
+	return a, nil
+}
+
+var _migrations54_state_versionsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9c\x56\xdd\x6e\xdb\x3c\x0c\xbd\xf7\x53\xf0\x32\xfd\xbe\xa6\x2f\xd0\xab\x76\x0d\x86\x62\x41\x3a\x74\x29\xb0\x3b\x43\xb5\x98\x98\x98\x22\x65\x22\x1d\x6f\x6f\x3f\xc8\x7f\x71\x63\xd9\x4d\x72\x17\x88\x87\xe7\x50\x87\xa4\xe2\xf9\x1c\xfe\xdf\xd1\xd6\x2b\x41\x78\xdb\x27\xc9\x7c\x0e\xeb\x1c\xe1\xbf\xf4\x80\x9e\xc9\x59\x06\x51\xef\x06\x19\x7e\x21\xee\x41\x72\x84\xbd\xc7\x03\xb9\x82\xa1\x43\xb8\x4d\x15\xf0\xae\x64\x20\x5b\xfd\x66\x51\x82\x81\xac\xc9\x2e\x73\xb4\x6d\x02\xea\x3a\x0c\x64\xb7\xc8\x42\xce\x02\x31\xa0\x0d\x48\x7d\x07\x0f\x2d\x0e\x4a\xc5\x70\x50\x86\x34\x6c\xbc\xdb\x05\x36\x12\x06\xa3\x58\xd2\x9d\xd3\xb4\x21\xd4\xa9\x41\xbd\x45\x0f\x33\xb2\x99\x29\x98\x0e\x78\x03\xc5\x1e\xc4\xd5\x89\xa9\x38\x98\xe1\x9f\x36\x74\x0b\x65\x4e\x59\x1e\xe4\x24\xaf\xca\x6b\xd2\xc9\x36\x91\xe6\x1e\x95\x74\xb1\xd7\x4a\x50\x83\xf3\xe0\x71\xe7\x0e\xa8\xef\x92\xe4\xcb\xeb\xe2\x61\xbd\x80\xf5\xc3\xe3\x72\x01\x2a\xcb\x5c\x61\x85\x8f\x66\xcd\x12\x00\x80\xe5\xf3\xb7\x63\xf0\xb6\x3a\xea\xca\x21\x2b\x18\x24\x57\x2f\x6b\x58\xbd\x2d\x97\xc9\xcd\x7d\xc7\xfa\xbc\x7a\x5a\xfc\x1c\xb2\xa6\xef\x7f\xd3\xe6\x30\x25\x0d\x2f\xab\x88\xf0\xdb\x8f\xe7\xd5\x57\x78\x5c\xbf\x2e\x16\xb3\x23\xf6\xb6\xd3\xbd\xb9\x3f\x43\xa4\x2b\xf2\x53\x89\x1e\xed\x88\x25\x5a\x89\x9a\xf4\xa5\x42\x5c\x6f\xce\x07\xfe\x09\x87\x3e\xd6\x71\xb5\x4d\x03\xb9\xa8\x57\xe3\x62\x7d\xc3\xe6\xf3\x63\x06\xd3\xd6\xa2\x67\xc8\x15\x83\x75\xf1\xe1\x66\x57\xcd\x65\x99\x3b\x83\xc0\x28\x61\xe1\xda\x3c\xb7\xa9\xe8\x6c\xcb\x08\xc4\xa0\x7c\x96\xd3\x01\x35\x88\xdb\xa2\xe4\xe8\x41\x59\x1d\x02\xf5\x32\x15\x56\xc8\x54\x8c\xa8\xbc\x21\x64\xe9\xae\x1f\xb8\xb6\x1e\x95\xa0\x07\xc9\x55\xbd\xcb\x1e\x7f\x17\xc8\x61\x13\xea\x7a\xee\x46\x3a\xde\x94\x34\xdd\xf4\x06\x74\x7d\xdf\x4f\x55\x26\x5a\x3f\x28\xe8\xea\xee\xc7\x44\xeb\xb3\xf3\x05\xeb\xe0\x45\x12\xd1\x19\x9b\x14\x19\xdd\xcb\xcc\x28\xda\x85\x17\x36\x7d\x57\x46\xd9\x0c\xe3\x7d\x1a\xc2\x2e\xee\xd4\x84\x52\xb8\x53\xdd\xa3\xa9\x72\xfa\xf7\x99\xea\xce\x27\x42\x7d\xf3\xce\x95\x1b\xb5\xcf\x6d\x36\x63\xa3\x5d\x87\x2e\xb6\xe9\x84\x31\x54\x5c\x1d\x35\x06\x9d\x0a\xf6\xab\x6c\x71\xe3\xd6\x44\xc8\x19\x8d\x39\x8b\xbd\x03\x9e\x41\xda\xf7\x78\x8a\x73\xd4\x57\xf1\x05\x4b\x6a\xc8\x8e\xcc\x63\x2f\x7e\xb1\xc3\x31\xee\xe1\x6b\x11\xad\xe0\xd2\x87\x62\x4c\xaa\xef\xcf\xa7\x42\x27\x7f\x11\xdd\x67\xd9\x93\x2b\x6d\x92\x3c\xbd\xbe\x7c\x1f\xfd\xec\xc8\x14\x67\x4a\xe3\x7d\x14\xf5\xf1\x4f\x69\x12\x3a\x78\x5b\x62\xe8\xa9\x65\x8a\xe1\x4f\x07\x23\x86\x89\x9a\xd3\x01\xff\x0d\x00\x62\x7e\xb6\x09\xa5\x0a\x00\x00")
+
+func migrations54_state_versionsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations54_state_versionsSql,
+		"migrations/54_state_versions.sql",
+	)
+}
+
+func migrations54_state_versionsSql() (*asset, error) {
+	bytes, err := migrations54_state_versionsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/54_state_versions.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xfa, 0x2d, 0xda, 0x70, 0x5, 0x3, 0x33, 0x1, 0xb8, 0x1b, 0xdd, 0x75, 0xb2, 0xfb, 0x36, 0xda, 0xb8, 0xd5, 0x89, 0x42, 0x4, 0x8d, 0xa8, 0x74, 0xe8, 0xf3, 0xae, 0x11, 0x30, 0x6e, 0xc9, 0x12}}
+	return a, nil
+}
+
+var _migrations55_ingest_filtersSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x6c\x90\x41\x8b\xdb\x30\x14\x84\xef\xfa\x15\x73\xb4\x69\xdc\x53\xe9\x25\x27\xb7\x71\xa1\xd4\x4d\x82\x71\x0e\xa1\x94\xf0\x2a\xbf\xd8\x0f\x6c\xc9\x48\x2f\xf5\x66\x7f\xfd\x62\x7b\x37\x87\x65\x2f\x02\xcd\x8c\x3e\x34\x93\x65\xf8\x34\x48\x1b\x48\x19\xa7\xd1\x98\x2c\x43\xde\xf7\x7e\xca\x7a\x89\x1a\x11\x38\x6a\x10\xab\xe2\x5a\x68\xc7\xd0\x40\x2e\x92\x55\xf1\x2e\x42\x5c\xcb\x51\xb9\x81\x38\xf5\x8b\xdd\x49\x54\x1f\xee\x50\xfa\xd7\x73\xdc\xcc\x34\xed\xf8\x8e\xc0\x63\x4f\x96\x97\xcc\x55\x7a\xe5\x10\xe1\xaf\xcb\x35\xd2\xc0\x70\xf3\x11\x59\x21\x6e\x11\x57\xb2\x78\x07\xeb\xdd\x55\xda\xcf\xe6\x7b\x55\xe4\x75\x81\x3a\xff\x56\x16\xaf\xf6\xe5\x8d\x94\x18\x00\x2b\xc3\x76\x14\xc8\x2a\x07\xfc\xa7\x70\x17\xd7\x26\x5f\xbf\xa4\xd8\x1f\x6a\xec\x4f\x65\xb9\x59\x82\x34\xf7\xe3\x06\xca\x4f\xfa\xe7\xef\x3b\xf3\x36\x36\xa4\xdc\x5c\x48\xa1\x32\x70\x54\x1a\x46\x4c\xa2\x9d\xbf\xad\x0a\x9e\xbd\xe3\xc7\x23\xec\x8a\x1f\xf9\xa9\xac\xe1\xfc\x94\xa4\x2b\xe2\x58\xfd\xfc\x9d\x57\x67\xfc\x2a\xce\x48\xe6\x5f\xa5\x26\xdd\x2e\xcb\x3e\x96\xde\xf9\xc9\x19\xb3\xab\x0e\xc7\x8f\x1b\x59\x8a\x96\x1a\xde\x9a\x97\x01\x00\xe3\x9f\x99\x06\x9f\x01\x00\x00")
+
+func migrations55_ingest_filtersSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations55_ingest_filtersSql,
+		"migrations/55_ingest_filters.sql",
+	)
+}
+
+func migrations55_ingest_filtersSql() (*asset, error) {
+	bytes, err := migrations55_ingest_filtersSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/55_ingest_filters.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xac, 0x1e, 0x22, 0x4f, 0xc7, 0xdb, 0x97, 0xce, 0x4d, 0x11, 0x81, 0xfc, 0xb8, 0x96, 0xf5, 0x4a, 0x6d, 0x8, 0xda, 0x15, 0x2e, 0x4b, 0x88, 0xe8, 0x5a, 0xa7, 0xcf, 0xbd, 0xb0, 0x34, 0x34, 0x39}}
+	return a, nil
+}
+
+var _migrations56_ingest_plugin_ledgersSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x7c\x91\xc1\x6e\xea\x30\x10\x45\xf7\xfe\x8a\xbb\x0c\x7a\xe4\xad\xaa\x6e\x58\x41\xb1\x2a\xd4\x34\xa0\x34\x48\x65\x15\x19\x7b\x30\x56\x89\x1d\xd9\xa6\x28\x7f\x5f\x25\x81\xa6\xaa\xda\xae\x67\xee\xb9\x47\x33\x69\x8a\x7f\xb5\xd1\x5e\x44\xc2\xb6\x61\x2c\x4d\x91\x91\xd2\xe4\x03\x8c\xd5\x14\x22\x29\xec\x5b\xc4\x23\xa1\xf1\x4e\x52\x08\xce\x07\x78\xd2\x26\x44\xf2\xa4\x70\x31\xf1\x38\x8c\x4f\x67\x6d\x6c\x40\x23\xe4\x9b\xd0\x34\xc5\x39\x90\xea\x78\xd1\x41\x51\x24\x19\xfb\xb5\xd3\x95\x1e\x1d\x3c\x0d\x15\x38\x38\xff\x95\x2e\x94\x22\x05\x71\x88\xe4\xbb\x48\xfd\x9f\x3d\x14\x7c\x5e\x72\x94\xf3\x45\xc6\xaf\x5e\xd5\xd0\x57\xdd\x78\x09\x03\x00\x2b\x6a\x82\x3c\x0a\x2f\x64\x97\x7e\x17\xbe\x35\x56\x27\xf7\x77\x13\xe4\xeb\x12\xf9\x36\xcb\xa6\xfd\xe2\x10\xab\x8c\xc2\xde\x68\x63\xe3\xb7\xf1\xa6\x58\x3d\xcf\x8b\x1d\x9e\xf8\x0e\x49\x07\x9d\x8e\x89\x09\x9b\xcc\xd8\x4d\x69\x95\x2f\xf9\xeb\xcf\x4a\xd5\xbe\xad\xc6\x9a\x75\xfe\x8b\xf8\xf6\x65\x95\x3f\x62\x51\x16\x9c\x23\x19\x4b\x66\xfd\x2f\x3e\x7f\xb3\x74\x17\xcb\xd8\xb2\x58\x6f\xfe\xbc\x82\x14\x41\x0a\x45\x33\xf6\x31\x00\x92\x1f\x82\x1d\xd8\x01\x00\x00")
+
+func migrations56_ingest_plugin_ledgersSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations56_ingest_plugin_ledgersSql,
+		"migrations/56_ingest_plugin_ledgers.sql",
+	)
+}
+
+func migrations56_ingest_plugin_ledgersSql() (*asset, error) {
+	bytes, err := migrations56_ingest_plugin_ledgersSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/56_ingest_plugin_ledgers.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x95, 0x99, 0x61, 0xb5, 0xf7, 0x2, 0xe7, 0xee, 0xdd, 0x51, 0x20, 0x8f, 0x7d, 0x3b, 0x52, 0xc0, 0x83, 0x1c, 0xa8, 0x74, 0xd0, 0x1d, 0x96, 0x7d, 0x3c, 0x8c, 0xb, 0x8e, 0xc3, 0xee, 0xe9, 0xd9}}
+	return a, nil
+}
+
+var _migrations57_reingest_jobsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9c\x92\x41\x6f\xd3\x40\x10\x85\xef\xfb\x2b\xde\x31\x16\x0d\x12\x17\x2e\x3d\x99\x78\x2b\x21\x4c\x52\xb9\xce\xa1\xa7\x74\xec\x9d\xda\x8b\x9a\xdd\x68\x76\x4c\x80\x5f\x8f\xdc\x6d\x21\x54\x6a\x40\x9c\x56\x3b\xfb\xf4\xe6\xcd\x37\xbb\x5c\xe2\xcd\xde\x0f\x42\xca\xd8\x1e\x8c\x59\x2e\xd1\xb0\x0f\x03\x27\xf5\x31\xe0\x4b\xec\x12\x16\x77\x34\x49\x14\x82\xeb\x20\x4f\x8f\x77\x05\x28\x38\xe8\xc8\x48\x4a\x3a\x25\xc4\xfb\xf9\xe6\x05\x1d\x69\x3f\x72\x42\x8a\xb3\x9b\x8e\xa4\xf0\x41\x59\x64\x3a\x28\xbb\x6c\xd9\x53\x40\xc7\x10\x4e\xd3\x9e\xdd\x5b\xb3\x6a\x6c\xd9\x5a\xb4\xe5\x87\xda\xfe\xea\xb1\xcb\xdd\x0d\x00\x78\x87\xce\x0f\x89\xc5\xd3\xc3\xc5\x63\xa5\x17\x26\x65\xb7\x23\x85\xfa\x3d\x27\xa5\xfd\x01\x47\xaf\x63\x9c\x72\x05\x3f\x62\x60\xac\x37\x2d\xd6\xdb\xba\x46\x65\xaf\xca\x6d\xdd\x22\xc4\xe3\xa2\xc8\x16\xf7\x3e\xf8\x34\xfe\xd5\x23\x8b\xaf\x9b\x8f\x9f\xcb\xe6\x16\x9f\xec\x2d\x16\xde\x15\xa6\xb8\x34\xaf\xe7\xde\x3d\x53\xc8\xf1\xe7\x4a\x1e\xc1\x07\xfd\x9d\xa9\xb1\x57\xb6\xb1\xeb\x95\xbd\x79\x39\xb4\x77\x05\x36\x6b\x54\xb6\xb6\xad\xc5\xaa\xbc\x59\x95\x95\xcd\x41\x92\x92\xe8\xee\x81\xdd\xc0\xf2\x48\x76\x3e\x9f\x2d\xb3\x84\x83\x3b\x2f\x78\xda\x59\x3f\x92\x50\xaf\x2c\xf8\x4a\xf2\xdd\x87\x61\xf1\xee\x7d\xf1\xd2\x4b\x24\x0a\x94\xbf\xe9\x49\xfb\x7f\x64\xf6\xff\x80\x33\xb0\x8b\x3f\x86\xcd\xc8\x4f\x7f\x6c\x15\x8f\xc1\x98\xaa\xd9\x5c\x9f\x5b\x41\x4f\xa9\x27\xc7\x97\xaf\x09\x4f\x14\x3f\x07\x00\x2a\xd6\x00\xc8\x0f\x03\x00\x00")
+
+func migrations57_reingest_jobsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations57_reingest_jobsSql,
+		"migrations/57_reingest_jobs.sql",
+	)
+}
+
+func migrations57_reingest_jobsSql() (*asset, error) {
+	bytes, err := migrations57_reingest_jobsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/57_reingest_jobs.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x6b, 0x5b, 0x45, 0xcb, 0x45, 0xfa, 0x56, 0x30, 0xe2, 0xfc, 0x22, 0x77, 0x29, 0x19, 0x57, 0xf5, 0x20, 0xe3, 0x39, 0x30, 0x90, 0x9a, 0xc1, 0xe6, 0x3c, 0xab, 0x6a, 0xcc, 0xdb, 0x4c, 0x8c, 0xe1}}
+	return a, nil
+}
+
+var _migrations58_history_partitionsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8c\x52\xc1\x6e\xda\x40\x10\xbd\xef\x57\xbc\x5b\x40\x8d\x39\x55\xbd\xa0\x1e\x48\xd9\xb6\xa8\xc4\x20\xd7\xa8\xcd\xc9\x19\xec\x89\x59\xc5\xec\xa2\xd9\x85\xd4\xfd\xfa\x6a\x09\x2e\x26\x4d\xa5\x9c\x56\xda\x79\xf3\xde\xbc\x99\x97\x24\x78\xb7\x35\xb5\x50\x60\xac\x76\x4a\x25\x09\x96\x24\xc1\x04\xe3\xac\x87\x7b\x40\xd8\x30\x36\xc6\x07\x27\x2d\x02\xad\x1b\xf6\xd8\x75\x00\xae\xb0\x6e\xd1\x70\x55\xb3\x40\xc8\xd6\x8c\x27\x13\x36\x91\xe4\x9e\xf6\xe2\x84\x50\xad\xcf\xf0\xe4\xc4\x73\x3f\xc2\xe4\xfc\x8b\xd2\xd9\x40\xc6\xfa\xa3\xd4\x33\x99\xc7\x83\xb8\x6d\xe4\xf1\x81\x24\x14\x27\x89\x81\xb1\x65\xb3\xf7\xe6\xc0\x43\x04\x07\xb6\xd5\xdf\x0a\xff\xea\x2a\x23\xf5\x29\xd3\x93\x5c\x23\x9f\xdc\xcc\x75\x37\x7b\xb1\x3b\xbb\x1a\x28\x00\xe7\x01\x0a\x4b\x5b\x46\xb9\x21\xa1\x32\xb0\xe0\x40\xd2\x1a\x5b\x0f\x3e\xbc\x1f\x22\x5d\xe4\x48\x57\xf3\x39\x96\xd9\xec\x76\x92\xdd\xe1\x9b\xbe\xbb\x3e\xb6\x1f\x77\xf1\x96\xd6\x67\xf8\x85\x0f\x63\x03\xc7\xf7\x12\xd2\xb3\xf3\x12\xa0\x86\x63\xd5\xd9\x9a\xa5\x53\xfd\xf3\x15\x5b\xc5\xba\x2d\xba\xed\x2d\xd2\xd7\x7c\xaf\xbe\xcf\xd2\x2f\xb8\xc9\x33\xad\x31\xe8\x0f\x74\xdd\xd3\x8e\x4a\xfd\x50\x4c\xdd\x93\xbd\x8c\x05\x57\x2f\x03\x41\xc2\xb0\x2e\xc4\x4b\x1e\x58\x42\x8c\x05\x95\x8f\xf1\x44\xc2\xf5\xbe\x21\x39\x01\x47\x6a\x9a\x2d\x96\xff\x3f\x4c\x49\xbe\xa4\x8a\xc7\x6a\xaa\xe7\x3a\xd7\xf8\x9c\x2d\x6e\xf1\xc8\x6d\x71\xa0\x66\xcf\x45\xc4\x33\x7e\x7c\xd5\x99\x8e\xbf\xf8\x88\xab\x7f\x48\x0a\x6f\x7e\xf3\xd5\x58\xfd\x19\x00\x36\xaa\x91\x4b\xd8\x02\x00\x00")
+
+func migrations58_history_partitionsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations58_history_partitionsSql,
+		"migrations/58_history_partitions.sql",
+	)
+}
+
+func migrations58_history_partitionsSql() (*asset, error) {
+	bytes, err := migrations58_history_partitionsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/58_history_partitions.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x84, 0x5d, 0x8c, 0x9, 0xb8, 0x79, 0x3, 0xb6, 0x2b, 0xf0, 0xec, 0x0, 0xb2, 0x22, 0x92, 0xa7, 0xce, 0x43, 0xcc, 0x77, 0x2d, 0xc0, 0x3, 0xe, 0x6c, 0x6c, 0x2c, 0x1c, 0x21, 0x60, 0xb6, 0x22}}
+	return a, nil
+}
+
+var _migrations59_history_cold_transactionsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x84\x90\x31\x6f\xc2\x30\x10\x85\x77\xff\x8a\x37\x82\x4a\x3a\x55\x5d\x98\x68\x49\xa5\xaa\x29\xa0\x08\x06\xa6\xe8\xb0\x8f\xc4\x12\xb1\xd3\xb3\xd3\x96\x7f\x5f\x39\x91\x10\x99\xba\xde\xdd\xf7\xbd\xd3\xcb\x32\x3c\xb4\xb6\x16\x8a\x8c\x43\xa7\x54\x96\xa1\x60\x53\xb3\x04\xf8\x33\x62\xc3\x88\x42\x2e\x90\x8e\xd6\xbb\x00\xe1\xd6\x7f\xb3\xc1\xe9\x3a\xec\x84\xa9\x63\x59\xa0\x0f\x6c\x10\x3d\xce\xd6\x99\x71\x68\x92\x69\x82\x5a\x37\x20\xda\x5f\x0c\x42\xf4\x42\x35\x27\x4d\x43\xa1\x79\xc4\x1b\x33\x4e\x7d\xdb\x4d\x11\x12\x06\x5d\x82\x4f\x2e\xeb\x0c\xff\x8e\xc9\xd6\x39\x96\xfb\xcb\x51\xa2\x5e\xcb\x7c\xb5\xcf\xb1\x5f\xbd\x14\x39\x1a\x9b\x42\xae\x55\xca\xab\x26\xd6\x99\x02\x70\x8f\x57\x09\x87\x6e\x48\x48\x47\x96\xd9\xf3\xd3\x1c\x9b\xed\x1e\x9b\x43\x51\x60\x57\xbe\x7f\xae\xca\x23\x3e\xf2\xe3\x62\x00\x2f\x43\x3d\x55\xe0\xaf\x9e\x9d\x66\x58\x17\xb9\x66\xb9\x11\x6a\xbe\x1c\x6a\xbc\xd5\xba\xf6\x3f\x4e\xa9\x75\xb9\xdd\xfd\xfb\x9a\xa6\xa0\xc9\xf0\x52\xfd\x0d\x00\xe0\x26\x38\x34\x97\x01\x00\x00")
+
+func migrations59_history_cold_transactionsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations59_history_cold_transactionsSql,
+		"migrations/59_history_cold_transactions.sql",
+	)
+}
+
+func migrations59_history_cold_transactionsSql() (*asset, error) {
+	bytes, err := migrations59_history_cold_transactionsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/59_history_cold_transactions.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x87, 0x23, 0xf0, 0x3c, 0xee, 0xd5, 0xad, 0xf, 0x94, 0xc2, 0x76, 0xc6, 0xef, 0xc, 0x70, 0x46, 0xa9, 0x8e, 0xa8, 0x32, 0x4b, 0x2e, 0xbe, 0xbd, 0xae, 0x86, 0x17, 0xbd, 0x67, 0x7d, 0x4c, 0xda}}
+	return a, nil
+}
+
 var _migrations5_create_trades_tableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x94\x51\x6f\xaa\x40\x10\x85\xdf\xf9\x15\x13\x9f\x30\x17\x93\x7b\x6f\x5a\x5f\x4c\x9a\x58\x25\xad\xa9\xc1\xd6\x4a\xd2\x37\xb2\xb0\x23\x6c\xa2\x2c\x99\x1d\xda\xf0\xef\x1b\x68\x69\x10\x57\xad\xaf\x9c\x39\x67\x38\xbb\x5f\x76\x34\x82\x3f\x7b\x95\x92\x60\x84\xb0\x70\x66\x6b\x7f\xba\xf1\x61\x33\xbd\x5f\xfa\x90\x29\xc3\x9a\xaa\x88\x49\x48\x34\xe0\x3a\x00\xf0\xf3\x51\x17\x48\x82\x95\xce\x23\x25\x21\x56\xa9\xca\x19\x82\xd5\x06\x82\x70\xb9\xf4\x9a\xc9\x81\x26\x89\x34\x00\x95\x33\xa6\x48\x1d\xb5\x91\xf5\x76\x8b\x64\x35\x37\xb2\xc1\xdd\xee\x84\x5e\xcb\x71\x59\x9d\x75\xeb\x9d\x8c\x84\x31\xc8\x11\x57\x05\x42\x92\x09\x12\x09\x23\xc1\xbb\xa0\x4a\xe5\xa9\x3b\xbe\x19\xf6\x22\x3b\x1e\x65\x4c\x89\x64\x71\xdd\x8e\xcf\xb8\x12\x2d\x6d\x9b\xfe\xfd\xb7\x7b\xf6\xba\xcc\xb9\xff\xff\x30\x7b\xf4\x67\x4f\xe0\x76\x47\xee\xe0\xef\xf0\xbb\x57\xac\xcb\x34\xe3\x6b\x9b\x1d\xb8\xae\xe8\x76\xe0\xfb\x75\xbb\xd6\x75\xb6\xdf\xe1\x50\xdd\xd0\x19\x4e\x9c\x96\xbf\x30\x58\xbc\x84\x3e\x2c\x82\xb9\xff\x06\x19\x93\x8c\x0a\x25\x61\x15\xf4\x91\x0c\x5f\x17\xc1\x03\xc4\x4c\x88\xe0\xda\xc8\xf4\x5a\x0a\x3b\xe1\x9d\xd4\xb8\x8a\x1a\x0c\x2f\x45\xb7\xac\xda\x52\xea\x90\xfa\xb6\x2e\x65\xf4\x90\xf4\xfa\xe4\x78\xc7\x00\x9e\x5a\xf7\x75\x78\x97\x16\x1e\xb1\xe2\x1d\x5f\xa8\x67\x63\xa3\x5e\xdb\x7d\x17\xe6\xfa\x23\x77\xe6\xeb\xd5\xb3\xfd\x5d\x48\x84\x49\x84\xc4\x89\xf3\x19\x00\x00\xff\xff\x79\x87\x24\x6b\x4c\x04\x00\x00")
 
 func migrations5_create_trades_tableSqlBytes() ([]byte, error) {
@@ -1081,6 +1252,66 @@ func migrations5_create_trades_tableSql() (*asset, error) {
 	return a, nil
 }
 
+var _migrations60_history_asset_statsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8c\x52\x4d\x6f\xd3\x40\x10\xbd\xef\xaf\x78\xc7\x56\x38\x15\x07\x84\x90\x7a\x0a\x24\x07\x44\x68\xab\x28\x3d\xf4\x64\x6d\xec\x49\x3c\x62\x3f\xac\x9d\xd9\x04\xff\x7b\xe4\xad\xdd\x36\x08\x04\xbe\x58\x3b\x1f\x6f\xde\xbc\x37\x8b\x05\xde\x79\x3e\x26\xab\x84\xc7\xde\x98\xc5\x02\x2b\xcb\x6e\x80\xa8\x55\x16\xe5\x46\x10\x0f\xd0\x8e\xd0\x24\x6a\x59\x61\x45\x48\xe5\x06\xbb\x8e\xd0\xdb\xc1\x53\x50\x9c\xa2\xcb\x9e\x60\x43\x0b\x4d\x36\xc8\x81\x12\x9a\x98\x83\x8e\x78\x36\x11\x24\x7b\x4f\x2d\xe2\x89\x52\xc1\x72\xd4\x1e\x29\x09\x0e\x29\x7a\x1c\x38\x89\xd6\xcf\x21\x68\x84\xb3\xaf\xcf\x73\xc7\x8e\xd0\x45\xd7\x52\x92\x6a\x84\xd3\x94\x45\x1d\x07\x92\x32\x4f\x72\xdf\xbb\xa1\x0c\x19\x81\xe9\x67\x5f\x17\x86\xf5\xb8\x80\xe0\x64\x5d\x1e\x2b\xf5\x2d\x6a\x21\x3f\xcc\xdc\x42\x76\x0e\x1c\x4a\xfb\x3e\x37\x3f\x48\x05\x31\xb8\x01\xe7\xc4\xaa\x14\xb0\x1f\x90\x88\xc3\x91\x44\x39\x86\x6a\xe4\xd4\x74\x68\x23\x09\x42\x54\x74\xf6\x44\x85\x58\x47\x45\x35\x9a\x05\x9b\x96\xbc\x31\x5f\xb6\xeb\xe5\x6e\x8d\xdd\xf2\xf3\x66\x8d\x8e\x45\x63\x1a\xde\xb2\xac\x3f\x7d\xfc\xf0\x7e\xfc\x70\x65\x00\x3c\x4b\x5c\x73\x8b\x3d\x1f\x39\x28\xee\xee\x77\xb8\x7b\xdc\x6c\xaa\x92\x55\xf6\x24\x6a\x7d\xff\xe7\xf4\x85\x9a\x1c\x94\xc6\xff\x65\x89\xb3\xff\xaa\x98\x8c\xad\x27\x63\x43\xf6\x94\xb8\xf9\x9d\xc8\x64\x75\x5d\xac\xfe\x0b\xd2\x64\xdd\x9c\x9d\x3b\x5f\x3c\xbc\x88\x4f\x66\x4e\xe3\x2a\x53\x82\x0f\xdb\xaf\xdf\x97\xdb\x27\x7c\x5b\x3f\x5d\xcd\xca\x54\xaf\x2a\x5c\x9b\xeb\xdb\x72\xb7\x2f\x77\xbc\x8a\xe7\x60\xcc\x6a\x7b\xff\xf0\x3f\x92\x37\x56\x1a\xdb\xd2\xad\xf9\x35\x00\x53\xa4\x15\x64\x0b\x03\x00\x00")
+
+func migrations60_history_asset_statsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations60_history_asset_statsSql,
+		"migrations/60_history_asset_stats.sql",
+	)
+}
+
+func migrations60_history_asset_statsSql() (*asset, error) {
+	bytes, err := migrations60_history_asset_statsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/60_history_asset_stats.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x8d, 0x9e, 0xbb, 0x37, 0x0, 0x3d, 0x70, 0xb4, 0x93, 0x57, 0x10, 0x6a, 0x54, 0xff, 0xb5, 0xfc, 0x78, 0xac, 0x2d, 0x7f, 0x84, 0x6c, 0xf3, 0x42, 0x77, 0x88, 0xac, 0x18, 0x51, 0x68, 0xe, 0x89}}
+	return a, nil
+}
+
+var _migrations61_history_liquidity_pool_statsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x94\x93\x4f\x6b\xdb\x40\x10\xc5\xef\xfa\x14\xef\x68\x53\x3b\x14\x0a\xbd\xe4\xe4\x34\x86\x96\xba\x49\x30\xce\x21\xa7\x65\x2c\x8d\xac\x81\x95\xd6\xdd\x99\x75\xf0\xb7\x2f\x2b\x2b\xff\xa8\x44\xa9\x2e\x62\xf5\x7e\xf3\x34\xb3\x6f\x77\xb9\xc4\xa7\x56\x0e\x91\x8c\xf1\x78\x2c\x8a\xe5\x12\xdf\x43\x8a\xfe\x0c\x35\x32\x51\x93\x52\x11\x6a\x58\xc3\xf0\xf2\x3b\x49\x25\x76\xc6\x31\x04\xaf\x57\xd8\x35\x0c\x8b\x54\xb1\x2e\x70\x0a\x3e\xb5\xac\xa0\xae\x42\xcd\xac\xd9\x69\x26\x1d\xd4\x62\x08\xc7\xde\x83\x54\xd9\xb0\xea\x91\x9b\x17\xd3\x6c\x35\x07\x45\x86\xa6\xb6\xe5\x0a\xe1\xc4\xf1\xf2\x3b\xae\x0e\x1c\x15\x75\x0c\x6d\x76\xab\x25\xaa\xb9\xcb\x57\x58\x80\xa7\xb7\xe5\x73\x23\x9e\x11\x59\x39\x9e\x72\x37\xda\x50\x1c\x9a\xb1\x98\xd4\xe0\xa5\xcb\xeb\xc8\xd9\x3a\xbb\x9d\xc8\x27\xd6\xf7\x5d\x80\xec\xbd\xe7\x55\xf1\x6d\xbb\x5e\xed\xd6\xd8\xad\x6e\x36\x6b\x34\xa2\x16\xe2\xd9\xbd\x6e\x82\xcb\x35\x2e\xef\x92\xba\x2f\x5f\x3f\xe7\x07\xb3\x02\xc0\x14\x2a\x15\xf6\x72\x90\xce\x70\x77\xbf\xc3\xdd\xe3\x66\xb3\xe8\x71\x93\x96\xd5\xa8\x3d\x8e\xcb\x1f\xc6\x96\xce\x38\xbf\x3f\x22\x9e\xfe\x45\xf4\x29\xb9\x32\xa4\xce\x26\x88\x4b\x7e\x8e\xd0\xa5\x96\xa3\x94\xe3\xf2\x7e\x42\xae\x79\xba\xb4\xe6\xe9\xba\x21\x31\x47\xe3\xb3\xbf\xc8\xfb\x71\xd9\x82\x91\x77\x43\xd6\xe3\x44\x0e\x3f\x67\x3f\xcc\xfe\x17\xd4\x53\x0f\xdb\x1f\xbf\x56\xdb\x27\xfc\x5c\x3f\xcd\x26\xc3\x5b\xbc\x05\x35\x2f\xe6\xd7\xfd\x55\x79\xbd\x3a\xb7\xe1\xb9\x2b\x8a\xdb\xed\xfd\xc3\xff\x1c\x97\x92\xb4\xa4\x8a\xaf\x8b\x3f\x03\x00\x15\xb8\x1e\x2a\x86\x03\x00\x00")
+
+func migrations61_history_liquidity_pool_statsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations61_history_liquidity_pool_statsSql,
+		"migrations/61_history_liquidity_pool_stats.sql",
+	)
+}
+
+func migrations61_history_liquidity_pool_statsSql() (*asset, error) {
+	bytes, err := migrations61_history_liquidity_pool_statsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/61_history_liquidity_pool_stats.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xdf, 0x33, 0x16, 0xeb, 0x59, 0xf7, 0xfc, 0x42, 0xf2, 0xae, 0x95, 0xb0, 0xf, 0x24, 0x79, 0xad, 0xb6, 0x8e, 0x56, 0x98, 0xd9, 0xe3, 0x17, 0x7c, 0x8f, 0xfd, 0xe3, 0x85, 0x63, 0x69, 0x7b, 0x22}}
+	return a, nil
+}
+
+var _migrations62_history_muxed_participantsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xbc\x95\xcf\x6e\x1a\x3d\x14\xc5\xf7\x7e\x8a\xb3\x0b\xa3\x0f\x90\xb2\xf9\xa4\x86\xd5\x04\xdc\x66\x24\x30\x0d\xcc\xb4\xe9\x6a\x64\x6c\x17\xac\x12\x9b\xda\x26\x21\x6f\x5f\x65\xca\xbf\x01\x66\x20\x89\xd4\xa5\xad\x7b\x7d\x7e\xf7\x1c\xcb\x6e\xb5\xf0\xdf\xa3\x9e\x3a\x1e\x14\xb2\x05\x21\xad\x16\x06\xcb\x95\x92\xe0\x42\xd8\xa5\x09\x1e\x8d\x41\xbb\xdd\x06\x97\xd2\x29\xef\x95\x8f\xf0\x3c\xd3\x62\x86\x60\xed\x2f\x2c\xb8\x0b\xd0\x06\xc1\x71\xe3\xb9\x08\xda\x1a\x0f\xeb\x5e\x4f\xb1\x0b\xe5\x78\xb1\xd1\x84\xb7\x08\x33\x85\x99\xf6\xc1\xba\x17\xd8\x9f\xe0\x78\x2c\x54\xfc\x72\xd2\x5a\x2b\x41\x70\x83\x89\xc2\x5c\xfb\xa0\x64\x9b\x74\x47\x34\x4e\x29\xc6\xf4\x3e\xa3\xac\x4b\x37\xdd\x79\xd1\x98\x6f\xf0\x72\x2d\x73\xaf\x7e\x13\x00\x18\xa7\xf1\x28\xc5\xf7\x24\xbd\xc3\x75\xb1\x91\xb0\xee\x88\x0e\x28\x4b\x71\xfb\x63\xbd\xc5\x86\x18\x24\xec\x5b\xdc\xcf\xe8\x76\x1d\x3f\xec\xd6\xdd\xb8\x7b\x47\x71\xdd\x21\x1b\xfd\x34\xbe\xed\x57\x89\xa3\x51\xf4\x68\x89\x89\x9e\x6a\x13\xc0\x86\x29\x58\xd6\xef\xa3\x47\x3f\xc7\x59\x3f\x85\x51\xab\xf0\xc4\xe7\x8d\xab\x5a\xfa\xab\x9b\x1b\xa7\xa6\x62\xce\xbd\x8f\x9a\xc5\x91\x6b\xbb\x21\x66\xdc\x71\x11\x94\xc3\x13\x77\x2f\xda\x4c\x1b\xff\x7f\x8a\xb6\x32\x24\xda\x71\x66\x2c\xb9\xcf\x28\x12\xd6\xa3\x0f\xd0\x46\xaa\x55\x5e\xa1\x69\x4d\xae\x25\x86\xac\x6a\xa8\x6c\x9c\xb0\x2f\x98\x04\xa7\x14\x1a\x5a\x46\x9d\x77\x49\x6c\x26\xb8\x50\x67\x5d\x1e\x55\x19\xbf\xbd\x4e\xeb\x53\x5e\x6f\x9e\x16\x7a\xc1\x77\x31\x1c\x97\x1e\x07\xd3\x2c\x55\x96\x80\x4e\x54\x5f\xe8\x6f\x1d\xdb\x5f\xb7\x4b\x36\xd4\x8e\x52\x32\xa5\x8a\xb3\x79\x72\xd6\x5d\x50\xef\x81\x2c\xb9\xf6\x21\xda\x03\xa6\xd3\x79\xee\xbd\x18\xe7\x13\xdd\x2f\xfe\x47\x99\xd6\xf3\x9d\x48\xf5\xcc\x40\x6f\xcc\xb5\x3c\x71\x7d\xb2\xe7\x51\x0f\xfc\xfb\x20\xf5\x11\x1b\xd9\xff\x44\x7a\xf6\xd9\x10\xd2\x1b\x0d\xbf\xbe\x2d\x71\xc1\xbd\xe0\x52\x75\x4e\xb5\xd6\xde\xc1\xba\xc6\x83\x17\xa7\x5c\x7a\xd9\xcf\xd2\x21\x7f\x06\x00\xba\xbe\x02\xf3\x23\x07\x00\x00")
+
+func migrations62_history_muxed_participantsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations62_history_muxed_participantsSql,
+		"migrations/62_history_muxed_participants.sql",
+	)
+}
+
+func migrations62_history_muxed_participantsSql() (*asset, error) {
+	bytes, err := migrations62_history_muxed_participantsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/62_history_muxed_participants.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x31, 0x69, 0xa0, 0x90, 0x6e, 0xb7, 0xbd, 0x71, 0x9e, 0x1a, 0x6b, 0xe5, 0xbe, 0x4f, 0x68, 0x9c, 0x8e, 0xd4, 0xb2, 0xa6, 0xaf, 0xd2, 0xa1, 0x9, 0x66, 0x6f, 0x58, 0xb0, 0x18, 0x37, 0x17, 0x2a}}
+	return a, nil
+}
+
 var _migrations6_create_assets_tableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x90\x3d\x4f\xc3\x30\x18\x84\x77\xff\x8a\x1b\x1d\x91\x0e\x20\xe8\x92\xc9\x34\x16\x58\x18\xa7\xb8\x31\xa2\x53\xe5\x26\x16\x78\x80\x54\xb6\x11\xca\xbf\x47\xaa\x28\xf9\x50\xe6\x7b\xf4\xbc\xef\xdd\x6a\x85\xab\x4f\xff\x1e\x6c\x72\x30\x27\xb2\xd1\x9c\xd5\x1c\x35\xbb\x97\x1c\x1f\x3e\xa6\x2e\xf4\x07\x1b\xa3\x4b\x11\x94\x00\x80\x6f\xb1\xe3\x5a\x30\x89\xad\x16\xcf\x4c\xef\xf1\xc4\xf7\xc8\xcf\xd9\x19\x3c\xa4\xfe\xe4\xf0\xca\xf4\xe6\x91\x69\xba\xbe\xcd\xa0\xaa\x1a\xca\x48\x39\x86\x9a\xae\x1d\xa0\xeb\x9b\x65\xc8\xc7\xf8\xed\xc2\x3f\x76\xb7\x9e\x63\x46\x89\x17\xc3\xe9\xa0\xcc\x47\x3f\xe4\x13\x4b\x46\xb2\x82\x5c\xfa\x09\x55\xf2\xb7\xbf\xf8\xd8\x5f\xee\x54\x6a\x5e\xd9\xec\x84\x7a\xc0\x31\x05\xe7\x40\x27\xb6\x82\x90\xf1\x74\x65\xf7\xf3\x45\x4a\x5d\x6d\x97\xa7\x6b\x6c\x6c\x6c\xeb\x8a\xdf\x00\x00\x00\xff\xff\xfb\x53\x3e\x81\x6e\x01\x00\x00")
 
 func migrations6_create_assets_tableSqlBytes() ([]byte, error) {
@@ -1319,7 +1550,18 @@ var _bindata = map[string]func() (*asset, error){
 	"migrations/4_add_protocol_version.sql":                              migrations4_add_protocol_versionSql,
 	"migrations/50_liquidity_pools.sql":                                  migrations50_liquidity_poolsSql,
 	"migrations/51_remove_ht_unused_indexes.sql":                         migrations51_remove_ht_unused_indexesSql,
+	"migrations/52_webhooks.sql":                                         migrations52_webhooksSql,
+	"migrations/53_api_keys.sql":                                         migrations53_api_keysSql,
+	"migrations/54_state_versions.sql":                                   migrations54_state_versionsSql,
+	"migrations/55_ingest_filters.sql":                                   migrations55_ingest_filtersSql,
+	"migrations/56_ingest_plugin_ledgers.sql":                            migrations56_ingest_plugin_ledgersSql,
+	"migrations/57_reingest_jobs.sql":                                    migrations57_reingest_jobsSql,
+	"migrations/58_history_partitions.sql":                               migrations58_history_partitionsSql,
+	"migrations/59_history_cold_transactions.sql":                        migrations59_history_cold_transactionsSql,
 	"migrations/5_create_trades_table.sql":                               migrations5_create_trades_tableSql,
+	"migrations/60_history_asset_stats.sql":                              migrations60_history_asset_statsSql,
+	"migrations/61_history_liquidity_pool_stats.sql":                     migrations61_history_liquidity_pool_statsSql,
+	"migrations/62_history_muxed_participants.sql":                       migrations62_history_muxed_participantsSql,
 	"migrations/6_create_assets_table.sql":                               migrations6_create_assets_tableSql,
 	"migrations/7_modify_trades_table.sql":                               migrations7_modify_trades_tableSql,
 	"migrations/8_add_aggregators.sql":                                   migrations8_add_aggregatorsSql,
@@ -1416,7 +1658,18 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"4_add_protocol_version.sql":                              &bintree{migrations4_add_protocol_versionSql, map[string]*bintree{}},
 		"50_liquidity_pools.sql":                                  &bintree{migrations50_liquidity_poolsSql, map[string]*bintree{}},
 		"51_remove_ht_unused_indexes.sql":                         &bintree{migrations51_remove_ht_unused_indexesSql, map[string]*bintree{}},
+		"52_webhooks.sql":                                         &bintree{migrations52_webhooksSql, map[string]*bintree{}},
+		"53_api_keys.sql":                                         &bintree{migrations53_api_keysSql, map[string]*bintree{}},
+		"54_state_versions.sql":                                   &bintree{migrations54_state_versionsSql, map[string]*bintree{}},
+		"55_ingest_filters.sql":                                   &bintree{migrations55_ingest_filtersSql, map[string]*bintree{}},
+		"56_ingest_plugin_ledgers.sql":                            &bintree{migrations56_ingest_plugin_ledgersSql, map[string]*bintree{}},
+		"57_reingest_jobs.sql":                                    &bintree{migrations57_reingest_jobsSql, map[string]*bintree{}},
+		"58_history_partitions.sql":                               &bintree{migrations58_history_partitionsSql, map[string]*bintree{}},
+		"59_history_cold_transactions.sql":                        &bintree{migrations59_history_cold_transactionsSql, map[string]*bintree{}},
 		"5_create_trades_table.sql":                               &bintree{migrations5_create_trades_tableSql, map[string]*bintree{}},
+		"60_history_asset_stats.sql":                              &bintree{migrations60_history_asset_statsSql, map[string]*bintree{}},
+		"61_history_liquidity_pool_stats.sql":                     &bintree{migrations61_history_liquidity_pool_statsSql, map[string]*bintree{}},
+		"62_history_muxed_participants.sql":                       &bintree{migrations62_history_muxed_participantsSql, map[string]*bintree{}},
 		"6_create_assets_table.sql":                               &bintree{migrations6_create_assets_tableSql, map[string]*bintree{}},
 		"7_modify_trades_table.sql":                               &bintree{migrations7_modify_trades_tableSql, map[string]*bintree{}},
 		"8_add_aggregators.sql":                                   &bintree{migrations8_add_aggregatorsSql, map[string]*bintree{}},
@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	stdLog "log"
+	"sort"
 	"text/tabwriter"
 	"time"
 
 	migrate "github.com/rubenv/sql-migrate"
+
+	"github.com/diamnet/go/services/aurora/plugins"
 )
 
 //go:generate go-bindata -nometadata -pkg schema -o bindata.go migrations/
@@ -26,11 +29,36 @@ const (
 	MigrateRedo MigrateDir = "redo"
 )
 
-// Migrations represents all of the schema migration for aurora
-var Migrations migrate.MigrationSource = &migrate.AssetMigrationSource{
-	Asset:    Asset,
-	AssetDir: AssetDir,
-	Dir:      "migrations",
+// Migrations represents all of the schema migration for aurora, including the
+// migrations of the ingestion processors registered with the plugins package.
+var Migrations migrate.MigrationSource = migrationSources{
+	&migrate.AssetMigrationSource{
+		Asset:    Asset,
+		AssetDir: AssetDir,
+		Dir:      "migrations",
+	},
+	plugins.Migrations,
+}
+
+// migrationSources combines several migration sources. The migrations of
+// the plugins have non-numeric IDs so they are sorted after the migrations of
+// Aurora, new Aurora migrations are still applied as they are found missing
+// before the last applied migration.
+type migrationSources []migrate.MigrationSource
+
+func (s migrationSources) FindMigrations() ([]*migrate.Migration, error) {
+	var migrations []*migrate.Migration
+	for _, source := range s {
+		found, err := source.FindMigrations()
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, found...)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Less(migrations[j])
+	})
+	return migrations, nil
 }
 
 // Migrate performs schema migration.  Migrations can occur in one of three
@@ -0,0 +1,33 @@
+-- +migrate Up
+
+CREATE TABLE webhook_subscriptions (
+    id bigserial,
+    url text NOT NULL,
+    secret text NOT NULL,
+    account_id character varying(56),
+    asset_type character varying(64),
+    asset_code character varying(12),
+    asset_issuer character varying(56),
+    operation_types integer[],
+    cursor bigint NOT NULL DEFAULT 0,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE webhook_dead_letters (
+    id bigserial,
+    subscription_id bigint NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    operation_id bigint NOT NULL,
+    payload jsonb NOT NULL,
+    attempts integer NOT NULL,
+    last_error text NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX webhook_dead_letters_by_subscription ON webhook_dead_letters USING BTREE (subscription_id, id);
+
+-- +migrate Down
+
+DROP TABLE webhook_dead_letters cascade;
+DROP TABLE webhook_subscriptions cascade;
@@ -0,0 +1,25 @@
+-- +migrate Up
+
+CREATE TABLE rate_limit_tiers (
+    name character varying(64) NOT NULL,
+    requests_per_hour bigint NOT NULL,
+    streams_per_hour bigint NOT NULL,
+    PRIMARY KEY (name)
+);
+
+CREATE TABLE api_keys (
+    id bigserial,
+    key_hash character varying(64) NOT NULL,
+    tier character varying(64) NOT NULL REFERENCES rate_limit_tiers (name),
+    description text NOT NULL DEFAULT '',
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX api_keys_by_key_hash ON api_keys USING BTREE (key_hash);
+CREATE INDEX api_keys_by_tier ON api_keys USING BTREE (tier);
+
+-- +migrate Down
+
+DROP TABLE api_keys cascade;
+DROP TABLE rate_limit_tiers cascade;
@@ -0,0 +1,68 @@
+-- +migrate Up
+
+-- The *_versions tables keep the previous versions of the rows in the state
+-- tables when versioned state ingestion is enabled. A version was valid from
+-- its last_modified_ledger (inclusive) up to valid_to (exclusive), which is the
+-- ledger in which the row was updated or removed.
+
+CREATE TABLE accounts_versions (
+    LIKE accounts,
+    valid_to integer NOT NULL
+);
+
+CREATE INDEX accounts_versions_by_account_id ON accounts_versions USING BTREE(account_id, valid_to);
+CREATE INDEX accounts_versions_by_valid_to ON accounts_versions USING BTREE(valid_to);
+
+CREATE TABLE accounts_data_versions (
+    LIKE accounts_data,
+    valid_to integer NOT NULL
+);
+
+CREATE INDEX accounts_data_versions_by_account_id ON accounts_data_versions USING BTREE(account_id, valid_to);
+CREATE INDEX accounts_data_versions_by_valid_to ON accounts_data_versions USING BTREE(valid_to);
+
+-- accounts_signers has no last_modified_ledger so the whole set of signers of
+-- an account is archived together and is valid until the earliest valid_to
+-- greater than the requested ledger.
+CREATE TABLE accounts_signers_versions (
+    LIKE accounts_signers,
+    valid_to integer NOT NULL
+);
+
+CREATE INDEX accounts_signers_versions_by_account_id ON accounts_signers_versions USING BTREE(account_id, valid_to);
+CREATE INDEX accounts_signers_versions_by_signer ON accounts_signers_versions USING BTREE(signer);
+CREATE INDEX accounts_signers_versions_by_valid_to ON accounts_signers_versions USING BTREE(valid_to);
+
+CREATE TABLE claimable_balances_versions (
+    LIKE claimable_balances,
+    valid_to integer NOT NULL
+);
+
+CREATE INDEX claimable_balances_versions_by_id ON claimable_balances_versions USING BTREE(id, valid_to);
+CREATE INDEX claimable_balances_versions_by_valid_to ON claimable_balances_versions USING BTREE(valid_to);
+
+CREATE TABLE offers_versions (
+    LIKE offers,
+    valid_to integer NOT NULL
+);
+
+CREATE INDEX offers_versions_by_offer_id ON offers_versions USING BTREE(offer_id, valid_to);
+CREATE INDEX offers_versions_by_seller_id ON offers_versions USING BTREE(seller_id);
+CREATE INDEX offers_versions_by_valid_to ON offers_versions USING BTREE(valid_to);
+
+CREATE TABLE trust_lines_versions (
+    LIKE trust_lines,
+    valid_to integer NOT NULL
+);
+
+CREATE INDEX trust_lines_versions_by_account_id ON trust_lines_versions USING BTREE(account_id, valid_to);
+CREATE INDEX trust_lines_versions_by_valid_to ON trust_lines_versions USING BTREE(valid_to);
+
+-- +migrate Down
+
+DROP TABLE accounts_versions cascade;
+DROP TABLE accounts_data_versions cascade;
+DROP TABLE accounts_signers_versions cascade;
+DROP TABLE claimable_balances_versions cascade;
+DROP TABLE offers_versions cascade;
+DROP TABLE trust_lines_versions cascade;
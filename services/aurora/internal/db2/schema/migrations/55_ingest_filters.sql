@@ -0,0 +1,14 @@
+-- +migrate Up
+
+-- Allow-lists restricting the transactions ingested into the history tables,
+-- they replace the filters of the same name set in the ingestion config.
+CREATE TABLE ingest_filters (
+    name character varying(64) NOT NULL,
+    allowed text[] NOT NULL,
+    updated_at timestamp without time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (name)
+);
+
+-- +migrate Down
+
+DROP TABLE ingest_filters cascade;
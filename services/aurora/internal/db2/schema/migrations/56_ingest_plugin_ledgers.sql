@@ -0,0 +1,15 @@
+-- +migrate Up
+
+-- Ledgers ingested by the processors registered with the plugins package, used
+-- to detect the ledgers to reingest for processors added after them.
+CREATE TABLE ingest_plugin_ledgers (
+    name character varying(64) NOT NULL,
+    ledger_id bigint NOT NULL,
+    PRIMARY KEY (name, ledger_id)
+);
+
+CREATE INDEX ingest_plugin_ledgers_by_ledger_id ON ingest_plugin_ledgers USING BTREE (ledger_id);
+
+-- +migrate Down
+
+DROP TABLE ingest_plugin_ledgers cascade;
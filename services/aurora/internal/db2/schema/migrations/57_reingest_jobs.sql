@@ -0,0 +1,26 @@
+-- +migrate Up
+
+-- Reingestion jobs (`aurora db reingest`) and the status of their batches so
+-- that interrupted jobs can be resumed.
+CREATE TABLE reingest_jobs (
+    id bigserial,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    finished_at timestamp without time zone,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE reingest_job_batches (
+    job_id bigint NOT NULL REFERENCES reingest_jobs (id) ON DELETE CASCADE,
+    start_ledger integer NOT NULL,
+    end_ledger integer NOT NULL,
+    status character varying(16) NOT NULL,
+    error text,
+    started_at timestamp without time zone,
+    finished_at timestamp without time zone,
+    PRIMARY KEY (job_id, start_ledger)
+);
+
+-- +migrate Down
+
+DROP TABLE reingest_job_batches cascade;
+DROP TABLE reingest_jobs cascade;
@@ -0,0 +1,19 @@
+-- +migrate Up
+
+-- Partitions of the history tables partitioned by ledger range with
+-- `aurora db partition-history`. A partition contains the ledgers from
+-- start_ledger (inclusive) to end_ledger (exclusive).
+CREATE TABLE history_partitions (
+    partition_name character varying(64) NOT NULL PRIMARY KEY,
+    table_name character varying(64) NOT NULL,
+    start_ledger integer NOT NULL,
+    end_ledger integer NOT NULL
+);
+
+CREATE INDEX history_partitions_by_ledgers ON history_partitions USING BTREE (start_ledger, end_ledger);
+
+-- +migrate Down
+
+-- Partitioned history tables are not converted back to regular tables.
+DROP TABLE history_partitions cascade;
+DELETE FROM key_value_store WHERE key = 'history_partition_size';
@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- Ledgers of the transactions removed by the reaper, used to find reaped
+-- transactions in the cold storage by hash. Fee bump transactions are also
+-- indexed by inner transaction hash.
+CREATE TABLE history_cold_transactions (
+    transaction_hash character(64) NOT NULL PRIMARY KEY,
+    ledger_sequence integer NOT NULL
+);
+
+-- +migrate Down
+
+DROP TABLE history_cold_transactions cascade;
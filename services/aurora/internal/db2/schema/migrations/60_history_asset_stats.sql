@@ -0,0 +1,24 @@
+-- +migrate Up
+
+-- Daily statistics of the credit assets. The payment volume and transfer count
+-- are summed over the ledgers from first_ledger to last_ledger while holders,
+-- trustlines and supply are the exp_asset_stats values at last_ledger. They
+-- are null in the buckets only written by reingestion, which does not have
+-- the state of the ledgers.
+CREATE TABLE history_asset_stats_86400000 (
+    asset_id bigint NOT NULL,
+    timestamp bigint NOT NULL,
+    first_ledger integer NOT NULL,
+    last_ledger integer NOT NULL,
+    payment_volume numeric NOT NULL,
+    transfer_count integer NOT NULL,
+    holders integer,
+    trustlines integer,
+    supply numeric,
+
+    PRIMARY KEY(asset_id, timestamp)
+);
+
+-- +migrate Down
+
+DROP TABLE history_asset_stats_86400000 cascade;
@@ -0,0 +1,27 @@
+-- +migrate Up
+
+-- Hourly statistics of the liquidity pools. The trades, volumes and fees
+-- (in stroops of asset A and B of the pool) are summed over the ledgers from
+-- first_ledger to last_ledger while reserves, shares and trust lines are the
+-- values of the pool at last_ledger.
+CREATE TABLE history_liquidity_pool_stats_3600000 (
+    history_liquidity_pool_id bigint NOT NULL,
+    timestamp bigint NOT NULL,
+    first_ledger integer NOT NULL,
+    last_ledger integer NOT NULL,
+    trade_count integer NOT NULL,
+    volume_a numeric NOT NULL,
+    volume_b numeric NOT NULL,
+    fee_a numeric NOT NULL,
+    fee_b numeric NOT NULL,
+    reserve_a bigint NOT NULL,
+    reserve_b bigint NOT NULL,
+    total_shares bigint NOT NULL,
+    trustline_count bigint NOT NULL,
+
+    PRIMARY KEY(history_liquidity_pool_id, timestamp)
+);
+
+-- +migrate Down
+
+DROP TABLE history_liquidity_pool_stats_3600000 cascade;
@@ -0,0 +1,41 @@
+-- +migrate Up
+
+-- Muxed accounts (M... addresses) which took part in transactions or
+-- operations, so the history of a muxed sub-account can be listed.
+CREATE SEQUENCE history_muxed_accounts_id_seq
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+CREATE TABLE history_muxed_accounts (
+    id bigint NOT NULL DEFAULT nextval('history_muxed_accounts_id_seq'::regclass),
+    address character varying(69) NOT NULL
+);
+
+CREATE UNIQUE INDEX index_history_muxed_accounts_on_id ON history_muxed_accounts USING btree (id);
+CREATE UNIQUE INDEX index_history_muxed_accounts_on_address ON history_muxed_accounts USING btree (address);
+
+CREATE TABLE history_operation_muxed_participants (
+    history_operation_id bigint NOT NULL,
+    history_muxed_account_id bigint NOT NULL
+);
+
+CREATE UNIQUE INDEX index_history_operation_muxed_participants_on_ids ON history_operation_muxed_participants USING btree (history_muxed_account_id, history_operation_id);
+CREATE INDEX index_history_operation_muxed_participants_on_operation_id ON history_operation_muxed_participants USING btree (history_operation_id);
+
+CREATE TABLE history_transaction_muxed_participants (
+    history_transaction_id bigint NOT NULL,
+    history_muxed_account_id bigint NOT NULL
+);
+
+CREATE UNIQUE INDEX index_history_transaction_muxed_participants_on_ids ON history_transaction_muxed_participants USING btree (history_muxed_account_id, history_transaction_id);
+CREATE INDEX index_history_transaction_muxed_participants_on_transaction_id ON history_transaction_muxed_participants USING btree (history_transaction_id);
+
+-- +migrate Down
+
+DROP TABLE history_transaction_muxed_participants cascade;
+DROP TABLE history_operation_muxed_participants cascade;
+DROP TABLE history_muxed_accounts cascade;
+DROP SEQUENCE history_muxed_accounts_id_seq;
@@ -15,6 +15,9 @@ import (
 	"github.com/diamnet/go/ingest/ledgerbackend"
 	"github.com/diamnet/go/network"
 	"github.com/diamnet/go/services/aurora/internal/db2/schema"
+	"github.com/diamnet/go/services/aurora/internal/gql"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
+	"github.com/diamnet/go/services/aurora/internal/ratelimit"
 	apkg "github.com/diamnet/go/support/app"
 	support "github.com/diamnet/go/support/config"
 	"github.com/diamnet/go/support/db"
@@ -117,6 +120,31 @@ func Flags() (*Config, support.ConfigOptions) {
 			Required:  false,
 			Usage:     "aurora postgres read-replica to connect with, when set it will return stale history error when replica is behind primary",
 		},
+		&support.ConfigOption{
+			Name:        "ro-database-urls",
+			ConfigKey:   &config.RoDatabaseURLs,
+			OptType:     types.String,
+			Required:    false,
+			FlagDefault: "",
+			CustomSetValue: func(co *support.ConfigOption) error {
+				var urls []string
+				for _, url := range strings.Split(viper.GetString(co.Name), ",") {
+					if url = strings.TrimSpace(url); url != "" {
+						urls = append(urls, url)
+					}
+				}
+				*(co.ConfigKey.(*[]string)) = urls
+				return nil
+			},
+			Usage: "comma-separated list of aurora postgres read-replicas to connect with, history queries are routed to the healthy replicas which are not lagging behind (including --ro-database-url if set)",
+		},
+		&support.ConfigOption{
+			Name:        "replica-max-lag",
+			ConfigKey:   &config.ReplicaMaxLag,
+			OptType:     types.Uint,
+			FlagDefault: uint(3),
+			Usage:       "number of ledgers a read-replica can lag behind the primary database before queries are routed away from it (0 to disable)",
+		},
 		&support.ConfigOption{
 			Name:        DiamnetCoreBinaryPathName,
 			OptType:     types.String,
@@ -313,6 +341,35 @@ func Flags() (*Config, support.ConfigOptions) {
 			},
 			Usage: "max count of requests allowed in a one hour period, by remote ip address",
 		},
+		&support.ConfigOption{
+			Name:        "enable-api-keys",
+			ConfigKey:   &config.EnableAPIKeys,
+			OptType:     types.Bool,
+			FlagDefault: false,
+			Usage:       "rate limits requests made with an API key (`X-API-Key` header or `api_key` query parameter) according to the tier of the key instead of the remote ip address, API keys and tiers are managed with the /api_keys and /rate_limit_tiers endpoints of the admin port",
+		},
+		&support.ConfigOption{
+			Name:      "rate-limit-redis-url",
+			ConfigKey: &config.RateLimitRedisURL,
+			OptType:   types.String,
+			Required:  false,
+			Usage:     "URL of a Redis compatible server (`redis://[:password@]host:port[/db]`) in which the quotas of API keys are counted so they are shared by several aurora instances, quotas are counted in memory when not set",
+		},
+		&support.ConfigOption{
+			Name:        "rate-limit-route-costs",
+			ConfigKey:   &config.RateLimitRouteCosts,
+			OptType:     types.String,
+			FlagDefault: "/paths=10,/trade_aggregations=5",
+			CustomSetValue: func(co *support.ConfigOption) error {
+				costs, err := ratelimit.ParseCosts(viper.GetString(co.Name))
+				if err != nil {
+					return err
+				}
+				*(co.ConfigKey.(*ratelimit.Costs)) = costs
+				return nil
+			},
+			Usage: "comma separated list of `prefix=cost` pairs defining how many units of the hourly quota of an API key are used by requests to paths starting with prefix, other requests cost 1",
+		},
 		&support.ConfigOption{
 			Name:           "friendbot-url",
 			ConfigKey:      &config.FriendbotURL,
@@ -371,6 +428,36 @@ func Flags() (*Config, support.ConfigOptions) {
 			Required:    false,
 			Usage:       "excludes liquidity pools from consideration in the `/paths` endpoint",
 		},
+		&support.ConfigOption{
+			Name:        "path-finding-cache-size",
+			ConfigKey:   &config.PathFindingCacheSize,
+			OptType:     types.Int,
+			FlagDefault: int(0),
+			Required:    false,
+			Usage:       "the number of `/paths` searches whose routes are cached in memory and reused until the order book is updated along them, 0 disables the cache",
+		},
+		&support.ConfigOption{
+			Name:        "enable-graphql",
+			ConfigKey:   &config.EnableGraphQL,
+			OptType:     types.Bool,
+			FlagDefault: false,
+			Required:    false,
+			Usage:       "enables the `/graphql` endpoint serving accounts, offers, trades, operations, transactions, ledgers and claimable balances",
+		},
+		&support.ConfigOption{
+			Name:        "graphql-max-depth",
+			ConfigKey:   &config.GraphQLMaxDepth,
+			OptType:     types.Int,
+			FlagDefault: gql.DefaultMaxDepth,
+			Usage:       "the maximum field nesting depth of queries to the `/graphql` endpoint",
+		},
+		&support.ConfigOption{
+			Name:        "graphql-max-cost",
+			ConfigKey:   &config.GraphQLMaxCost,
+			OptType:     types.Int,
+			FlagDefault: gql.DefaultMaxCost,
+			Usage:       "the maximum cost of queries to the `/graphql` endpoint, every list field costs its limit and every other database query costs 1",
+		},
 		&support.ConfigOption{
 			Name:      "network-passphrase",
 			ConfigKey: &config.NetworkPassphrase,
@@ -431,6 +518,27 @@ func Flags() (*Config, support.ConfigOptions) {
 			FlagDefault: uint(0),
 			Usage:       "the minimum number of ledgers to maintain within aurora's history tables.  0 signifies an unlimited number of ledgers will be retained",
 		},
+		&support.ConfigOption{
+			Name:        "cold-storage-url",
+			ConfigKey:   &config.ColdStorageURL,
+			OptType:     types.String,
+			FlagDefault: "",
+			Usage:       "location (file://, s3:// or http(s):// URL) of the ledgers exported with `aurora db export-ledgers`, used to serve the ledgers removed by --history-retention-count (cannot be used with --cold-storage-history-archive)",
+		},
+		&support.ConfigOption{
+			Name:        "cold-storage-history-archive",
+			ConfigKey:   &config.ColdStorageHistoryArchive,
+			OptType:     types.Bool,
+			FlagDefault: false,
+			Usage:       "serve the ledgers removed by --history-retention-count from the history archive, without effects and operation changes",
+		},
+		&support.ConfigOption{
+			Name:        "cold-storage-cache-size",
+			ConfigKey:   &config.ColdStorageCacheSize,
+			OptType:     types.Uint,
+			FlagDefault: uint(1024),
+			Usage:       "number of ledgers of the cold storage kept in memory",
+		},
 		&support.ConfigOption{
 			Name:        "history-stale-threshold",
 			ConfigKey:   &config.StaleThreshold,
@@ -459,6 +567,82 @@ func Flags() (*Config, support.ConfigOptions) {
 			FlagDefault: false,
 			Usage:       "enables extended ledger stats in the log (ledger entry changes and operations stats)",
 		},
+		&support.ConfigOption{
+			Name:        "ingest-enable-webhooks",
+			ConfigKey:   &config.IngestEnableWebhooks,
+			OptType:     types.Bool,
+			FlagDefault: false,
+			Usage:       "enables delivery of ingested operations to webhook subscriptions, subscriptions are managed with the /webhooks endpoints of the admin port",
+		},
+		&support.ConfigOption{
+			Name:        "ingest-versioned-state",
+			ConfigKey:   &config.IngestEnableStateVersions,
+			OptType:     types.Bool,
+			FlagDefault: false,
+			Usage:       "keeps the previous versions of accounts, offers, trust lines and claimable balances within the history retention window, required by the as_of_ledger parameter of the state endpoints",
+		},
+		&support.ConfigOption{
+			Name:           "ingest-filter-accounts",
+			ConfigKey:      &config.IngestFilterAccounts,
+			OptType:        types.String,
+			FlagDefault:    "",
+			CustomSetValue: setTransactionFilter(processors.AccountsFilter),
+			Usage:          "comma-separated list of accounts, only the transactions involving these accounts (or the assets of --ingest-filter-assets) are ingested into the history tables",
+		},
+		&support.ConfigOption{
+			Name:           "ingest-filter-assets",
+			ConfigKey:      &config.IngestFilterAssets,
+			OptType:        types.String,
+			FlagDefault:    "",
+			CustomSetValue: setTransactionFilter(processors.AssetsFilter),
+			Usage:          "comma-separated list of assets (native or CODE:ISSUER), only the transactions involving these assets (or the accounts of --ingest-filter-accounts) are ingested into the history tables",
+		},
+		&support.ConfigOption{
+			Name:           "ingest-filter-operation-types",
+			ConfigKey:      &config.IngestFilterOperationTypes,
+			OptType:        types.String,
+			FlagDefault:    "",
+			CustomSetValue: setTransactionFilter(processors.OperationTypesFilter),
+			Usage:          "comma-separated list of operation types (ex. payment), only the transactions containing an operation of these types are ingested into the history tables",
+		},
+		&support.ConfigOption{
+			Name:        "ingest-export-events-file",
+			ConfigKey:   &config.IngestExportEventsFile,
+			OptType:     types.String,
+			FlagDefault: "",
+			Usage:       "path of a file the transactions, operations, effects and state changes of every ingested ledger are appended to as newline delimited JSON events (cannot be used with --ingest-export-events-kafka-brokers)",
+		},
+		&support.ConfigOption{
+			Name:        "ingest-export-events-kafka-brokers",
+			ConfigKey:   &config.IngestExportEventsKafkaBrokers,
+			OptType:     types.String,
+			FlagDefault: "",
+			CustomSetValue: func(co *support.ConfigOption) error {
+				var brokers []string
+				for _, broker := range strings.Split(viper.GetString(co.Name), ",") {
+					if broker = strings.TrimSpace(broker); broker != "" {
+						brokers = append(brokers, broker)
+					}
+				}
+				*(co.ConfigKey.(*[]string)) = brokers
+				return nil
+			},
+			Usage: "comma-separated list of Kafka brokers (host:port), when set the transactions, operations, effects and state changes of every ingested ledger are produced as JSON events to --ingest-export-events-kafka-topic",
+		},
+		&support.ConfigOption{
+			Name:        "ingest-export-events-kafka-topic",
+			ConfigKey:   &config.IngestExportEventsKafkaTopic,
+			OptType:     types.String,
+			FlagDefault: "",
+			Usage:       "Kafka topic the ingested events are produced to",
+		},
+		&support.ConfigOption{
+			Name:        "ingest-export-events-kafka-partition",
+			ConfigKey:   &config.IngestExportEventsKafkaPartition,
+			OptType:     types.Uint32,
+			FlagDefault: uint32(0),
+			Usage:       "partition of --ingest-export-events-kafka-topic the ingested events are produced to, a single partition is used to keep the events ordered",
+		},
 		&support.ConfigOption{
 			Name:        "apply-migrations",
 			ConfigKey:   &config.ApplyMigrations,
@@ -521,6 +705,30 @@ type ApplyOptions struct {
 	RequireCaptiveCoreConfig bool
 }
 
+// setTransactionFilter returns the function setting the allow-list of one of
+// the ingestion transaction filters from a comma-separated list.
+func setTransactionFilter(name string) func(*support.ConfigOption) error {
+	return func(co *support.ConfigOption) error {
+		var allowed []string
+		for _, value := range strings.Split(viper.GetString(co.Name), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				allowed = append(allowed, value)
+			}
+		}
+
+		var rules processors.TransactionFilterRules
+		if err := rules.Set(name, allowed); err != nil {
+			return err
+		}
+		if _, err := processors.NewTransactionFilter(rules); err != nil {
+			return fmt.Errorf("Invalid config: --%s %v", co.Name, err)
+		}
+
+		*(co.ConfigKey.(*[]string)) = allowed
+		return nil
+	}
+}
+
 // ApplyFlags applies the command line flags on the given Config instance
 func ApplyFlags(config *Config, flags support.ConfigOptions, options ApplyOptions) error {
 	// Verify required options and load the config struct
@@ -677,6 +885,20 @@ func ApplyFlags(config *Config, flags support.ConfigOptions, options ApplyOption
 		config.AuroraDBMaxIdleConnections = config.MaxDBConnections
 	}
 
+	if config.IngestExportEventsFile != "" && len(config.IngestExportEventsKafkaBrokers) > 0 {
+		return fmt.Errorf("Invalid config: Only one option of --ingest-export-events-file and --ingest-export-events-kafka-brokers is allowed.")
+	}
+	if len(config.IngestExportEventsKafkaBrokers) > 0 && config.IngestExportEventsKafkaTopic == "" {
+		return fmt.Errorf("Invalid config: --ingest-export-events-kafka-topic must be set when --ingest-export-events-kafka-brokers is set")
+	}
+
+	if config.ColdStorageURL != "" && config.ColdStorageHistoryArchive {
+		return fmt.Errorf("Invalid config: Only one option of --cold-storage-url and --cold-storage-history-archive is allowed.")
+	}
+	if config.ColdStorageHistoryArchive && (len(config.HistoryArchiveURLs) == 0 || config.HistoryArchiveURLs[0] == "") {
+		return fmt.Errorf("Invalid config: --history-archive-urls must be set when --cold-storage-history-archive is set")
+	}
+
 	if config.BehindCloudflare && config.BehindAWSLoadBalancer {
 		return fmt.Errorf("Invalid config: Only one option of --behind-cloudflare and --behind-aws-load-balancer is allowed. If Aurora is behind both, use --behind-cloudflare only.")
 	}
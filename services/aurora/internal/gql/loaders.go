@@ -0,0 +1,130 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/support/errors"
+)
+
+// Nested ledger and transaction fields are resolved through loaders to avoid
+// one query per list item. List resolvers prime the loaders with the keys of
+// all returned items and the first load then fetches every pending key with
+// a single query.
+
+// ledgerLoader batches ledger lookups by sequence.
+type ledgerLoader struct {
+	lock    sync.Mutex
+	pending map[int32]bool
+	ledgers map[int32]history.Ledger
+}
+
+func (l *ledgerLoader) prime(seqs ...int32) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.pending == nil {
+		l.pending = map[int32]bool{}
+	}
+	for _, seq := range seqs {
+		if _, ok := l.ledgers[seq]; !ok {
+			l.pending[seq] = true
+		}
+	}
+}
+
+func (l *ledgerLoader) load(ctx context.Context, state *requestState, seq int32) (history.Ledger, error) {
+	l.prime(seq)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if ledger, ok := l.ledgers[seq]; ok {
+		return ledger, nil
+	}
+
+	if err := state.charge(1); err != nil {
+		return history.Ledger{}, err
+	}
+	seqs := make([]int32, 0, len(l.pending))
+	for pending := range l.pending {
+		seqs = append(seqs, pending)
+	}
+	var rows []history.Ledger
+	if err := state.q.LedgersBySequence(ctx, &rows, seqs...); err != nil {
+		return history.Ledger{}, queryFailed(ctx, err)
+	}
+
+	if l.ledgers == nil {
+		l.ledgers = map[int32]history.Ledger{}
+	}
+	for _, row := range rows {
+		l.ledgers[row.Sequence] = row
+	}
+	l.pending = nil
+
+	ledger, ok := l.ledgers[seq]
+	if !ok {
+		return history.Ledger{}, errors.Errorf("ledger %d not found", seq)
+	}
+	return ledger, nil
+}
+
+// transactionLoader batches transaction lookups by id.
+type transactionLoader struct {
+	lock         sync.Mutex
+	pending      map[int64]bool
+	transactions map[int64]history.Transaction
+}
+
+func (l *transactionLoader) prime(ids ...int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.pending == nil {
+		l.pending = map[int64]bool{}
+	}
+	for _, id := range ids {
+		if _, ok := l.transactions[id]; !ok {
+			l.pending[id] = true
+		}
+	}
+}
+
+func (l *transactionLoader) load(ctx context.Context, state *requestState, id int64) (history.Transaction, error) {
+	l.prime(id)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if transaction, ok := l.transactions[id]; ok {
+		return transaction, nil
+	}
+
+	if err := state.charge(1); err != nil {
+		return history.Transaction{}, err
+	}
+	ids := make([]int64, 0, len(l.pending))
+	for pending := range l.pending {
+		ids = append(ids, pending)
+	}
+	rows, err := state.q.TransactionsByIDs(ctx, ids...)
+	if err != nil {
+		return history.Transaction{}, queryFailed(ctx, err)
+	}
+
+	if l.transactions == nil {
+		l.transactions = map[int64]history.Transaction{}
+	}
+	for id, row := range rows {
+		l.transactions[id] = row
+	}
+	l.pending = nil
+
+	transaction, ok := l.transactions[id]
+	if !ok {
+		return history.Transaction{}, errors.Errorf("transaction %d not found", id)
+	}
+	return transaction, nil
+}
@@ -0,0 +1,154 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/graph-gophers/graphql-go"
+
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/log"
+	"github.com/diamnet/go/support/render/problem"
+)
+
+const (
+	// DefaultMaxDepth is the default maximum field nesting depth of a query.
+	DefaultMaxDepth = 6
+	// DefaultMaxCost is the default maximum cost of a query, see
+	// requestState.charge.
+	DefaultMaxCost = 1000
+	// maxRequestSize limits the size of the request body
+	maxRequestSize = 64 * 1024
+)
+
+// errQueryFailed is returned to clients in place of database errors to avoid
+// exposing the underlying implementation
+var errQueryFailed = errors.New("could not retrieve the requested data")
+
+// Config configures the GraphQL handler.
+type Config struct {
+	// MaxDepth is the maximum field nesting depth of a query.
+	MaxDepth int
+	// MaxCost is the maximum cost of a query. Every root field and every
+	// batched load costs 1, every list field costs its limit.
+	MaxCost int
+}
+
+// Handler executes GraphQL queries against the history database. It expects
+// the database session to be present in the request context, see
+// StateMiddleware.
+type Handler struct {
+	schema  *graphql.Schema
+	maxCost int
+}
+
+// NewHandler parses the schema and returns a new Handler.
+func NewHandler(config Config) *Handler {
+	if config.MaxDepth <= 0 {
+		config.MaxDepth = DefaultMaxDepth
+	}
+	if config.MaxCost <= 0 {
+		config.MaxCost = DefaultMaxCost
+	}
+
+	return &Handler{
+		schema: graphql.MustParseSchema(
+			Schema,
+			&resolver{},
+			graphql.MaxDepth(config.MaxDepth),
+			// All resolvers of a request share a single database
+			// transaction which cannot run queries concurrently.
+			graphql.MaxParallelism(1),
+		),
+		maxCost: config.MaxCost,
+	}
+}
+
+// resolver resolves the root Query GraphQL type.
+type resolver struct{}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(ctx, w, err)
+		return
+	}
+
+	var params request
+	if err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&params); err != nil {
+		problem.Render(ctx, w, problem.MakeInvalidFieldProblem("body", err))
+		return
+	}
+	if params.Query == "" {
+		problem.Render(ctx, w, problem.MakeInvalidFieldProblem("query", errors.New("query is required")))
+		return
+	}
+
+	state := &requestState{q: q, maxCost: h.maxCost}
+	response := h.schema.Exec(
+		context.WithValue(ctx, requestStateKey, state),
+		params.Query,
+		params.OperationName,
+		params.Variables,
+	)
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		problem.Render(ctx, w, errors.Wrap(err, "could not marshal response"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(responseJSON)
+}
+
+type contextKey string
+
+const requestStateKey = contextKey("gql_request_state")
+
+// requestState holds the database session, the remaining cost budget and the
+// loaders of a single query.
+type requestState struct {
+	q            *history.Q
+	lock         sync.Mutex
+	cost         int
+	maxCost      int
+	ledgers      ledgerLoader
+	transactions transactionLoader
+}
+
+func stateFromContext(ctx context.Context) *requestState {
+	return ctx.Value(requestStateKey).(*requestState)
+}
+
+// charge adds n to the cost of the query and fails once the cost exceeds the
+// configured maximum. Resolvers charge before querying the database, so a
+// query over budget stops doing work as soon as the budget is spent.
+func (s *requestState) charge(n int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cost += n
+	if s.cost > s.maxCost {
+		return fmt.Errorf("query exceeds the maximum cost of %d", s.maxCost)
+	}
+	return nil
+}
+
+// queryFailed logs err and returns an error that is safe to show to clients.
+func queryFailed(ctx context.Context, err error) error {
+	log.Ctx(ctx).WithError(err).Error("GraphQL query failed")
+	return errQueryFailed
+}
@@ -0,0 +1,148 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	auroraContext "github.com/diamnet/go/services/aurora/internal/context"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/test"
+)
+
+type testResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func TestQueryDepthLimit(t *testing.T) {
+	tt := assert.New(t)
+	handler := NewHandler(Config{MaxDepth: 2})
+
+	// Depth is validated before any resolver runs
+	response := handler.schema.Exec(
+		context.WithValue(context.Background(), requestStateKey, &requestState{maxCost: DefaultMaxCost}),
+		`{ account(id: "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H") { payments { transaction { hash } } } }`,
+		"",
+		nil,
+	)
+	if tt.NotEmpty(response.Errors) {
+		tt.Contains(response.Errors[0].Message, "exceeds max depth 2")
+	}
+}
+
+func TestQueryCostLimit(t *testing.T) {
+	tt := assert.New(t)
+	state := &requestState{maxCost: 10}
+
+	tt.NoError(state.charge(4))
+	tt.NoError(state.charge(6))
+	tt.EqualError(state.charge(1), "query exceeds the maximum cost of 10")
+
+	_, err := pageArgs{Limit: 20, Order: "ASC"}.pageQuery(&requestState{maxCost: 10}, false)
+	tt.EqualError(err, "query exceeds the maximum cost of 10")
+
+	_, err = pageArgs{Limit: -1, Order: "ASC"}.pageQuery(&requestState{maxCost: 10}, false)
+	tt.Error(err)
+}
+
+func TestAccountQuery(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	tt.Scenario("base")
+
+	q := &history.Q{tt.AuroraSession()}
+	address := "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []history.AccountEntry{{
+		AccountID:          address,
+		Balance:            1000000000,
+		SequenceNumber:     8589934595,
+		LastModifiedLedger: 2,
+	}}))
+
+	execute := func(query string, maxCost int) testResponse {
+		body, err := json.Marshal(request{Query: query})
+		tt.Assert.NoError(err)
+		r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+		r = r.WithContext(context.WithValue(r.Context(), &auroraContext.SessionContextKey, q.SessionInterface))
+
+		w := httptest.NewRecorder()
+		NewHandler(Config{MaxCost: maxCost}).ServeHTTP(w, r)
+		tt.Assert.Equal(http.StatusOK, w.Code)
+
+		var response testResponse
+		tt.Assert.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	query := `{
+		account(id: "` + address + `") {
+			id
+			sequence
+			balances { balance asset { type } }
+			operations(limit: 3) {
+				type
+				transaction { hash }
+				ledger { sequence }
+			}
+		}
+	}`
+	response := execute(query, DefaultMaxCost)
+	tt.Assert.Empty(response.Errors)
+
+	var data struct {
+		Account struct {
+			ID       string
+			Sequence string
+			Balances []struct {
+				Balance string
+				Asset   struct{ Type string }
+			}
+			Operations []struct {
+				Type        string
+				Transaction struct{ Hash string }
+				Ledger      struct{ Sequence int32 }
+			}
+		}
+	}
+	tt.Assert.NoError(json.Unmarshal(response.Data, &data))
+	tt.Assert.Equal(address, data.Account.ID)
+	tt.Assert.Equal("8589934595", data.Account.Sequence)
+	if tt.Assert.Len(data.Account.Balances, 1) {
+		tt.Assert.Equal("100.0000000", data.Account.Balances[0].Balance)
+		tt.Assert.Equal("native", data.Account.Balances[0].Asset.Type)
+	}
+	if tt.Assert.Len(data.Account.Operations, 3) {
+		for _, operation := range data.Account.Operations {
+			tt.Assert.Equal("create_account", operation.Type)
+			tt.Assert.Len(operation.Transaction.Hash, 64)
+			tt.Assert.Equal(int32(2), operation.Ledger.Sequence)
+		}
+	}
+
+	// account + balances + operations(limit: 3) + one batch of transactions
+	// + one batch of ledgers
+	response = execute(query, 7)
+	tt.Assert.Empty(response.Errors)
+	response = execute(query, 6)
+	if tt.Assert.NotEmpty(response.Errors) {
+		tt.Assert.Equal("query exceeds the maximum cost of 6", response.Errors[0].Message)
+	}
+
+	response = execute(`{ account(id: "GA5WBPYA5Y4WAEHXWR2UKO2UO4BUGHUQ74EUPKON2QHV4WRHOIRNKKH2") { id } }`, DefaultMaxCost)
+	tt.Assert.Empty(response.Errors)
+	tt.Assert.JSONEq(`{"account": null}`, string(response.Data))
+
+	response = execute(`{ account(id: "GABC") { id } }`, DefaultMaxCost)
+	if tt.Assert.Len(response.Errors, 1) {
+		tt.Assert.Equal("invalid account id", response.Errors[0].Message)
+	}
+}
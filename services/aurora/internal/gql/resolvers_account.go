@@ -0,0 +1,345 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/diamnet/go/amount"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// Account resolves the account() GraphQL query.
+func (r *resolver) Account(ctx context.Context, args struct{ ID string }) (*accountResolver, error) {
+	if _, err := xdr.AddressToAccountId(args.ID); err != nil {
+		return nil, errors.New("invalid account id")
+	}
+
+	state := stateFromContext(ctx)
+	if err := state.charge(1); err != nil {
+		return nil, err
+	}
+	account, err := state.q.GetAccountByID(ctx, args.ID)
+	if state.q.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, queryFailed(ctx, err)
+	}
+	return &accountResolver{account: account}, nil
+}
+
+// accountResolver resolves the Account GraphQL type.
+type accountResolver struct {
+	account history.AccountEntry
+}
+
+func (a *accountResolver) ID() string           { return a.account.AccountID }
+func (a *accountResolver) Sequence() string     { return strconv.FormatInt(a.account.SequenceNumber, 10) }
+func (a *accountResolver) SubentryCount() int32 { return int32(a.account.NumSubEntries) }
+func (a *accountResolver) HomeDomain() string   { return a.account.HomeDomain }
+func (a *accountResolver) Sponsor() *string     { return nullString(a.account.Sponsor) }
+func (a *accountResolver) LastModifiedLedger() int32 {
+	return int32(a.account.LastModifiedLedger)
+}
+
+// Balances resolves the native balance and the trust lines of the account.
+func (a *accountResolver) Balances(ctx context.Context) ([]*balanceResolver, error) {
+	state := stateFromContext(ctx)
+	if err := state.charge(1); err != nil {
+		return nil, err
+	}
+	trustLines, err := state.q.GetSortedTrustLinesByAccountID(ctx, a.account.AccountID)
+	if err != nil {
+		return nil, queryFailed(ctx, err)
+	}
+
+	balances := make([]*balanceResolver, 0, len(trustLines)+1)
+	for _, trustLine := range trustLines {
+		balances = append(balances, newTrustLineBalanceResolver(trustLine))
+	}
+	balances = append(balances, &balanceResolver{
+		asset:              &assetResolver{typ: xdr.AssetTypeToString[xdr.AssetTypeAssetTypeNative]},
+		balance:            a.account.Balance,
+		buyingLiabilities:  a.account.BuyingLiabilities,
+		sellingLiabilities: a.account.SellingLiabilities,
+		lastModifiedLedger: a.account.LastModifiedLedger,
+	})
+	return balances, nil
+}
+
+// Offers resolves the offers created by the account.
+func (a *accountResolver) Offers(ctx context.Context, args pageArgs) ([]*offerResolver, error) {
+	state := stateFromContext(ctx)
+	page, err := args.pageQuery(state, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = page.CursorInt64(); err != nil {
+		return nil, err
+	}
+
+	offers, err := state.q.GetOffers(ctx, history.OffersQuery{
+		PageQuery: page,
+		SellerID:  a.account.AccountID,
+	})
+	if err != nil {
+		return nil, queryFailed(ctx, err)
+	}
+
+	result := make([]*offerResolver, len(offers))
+	for i := range offers {
+		result[i] = &offerResolver{offer: offers[i]}
+	}
+	return result, nil
+}
+
+// Trades resolves the trades the account participated in.
+func (a *accountResolver) Trades(ctx context.Context, args pageArgs) ([]*tradeResolver, error) {
+	state := stateFromContext(ctx)
+	page, err := args.pageQuery(state, true)
+	if err != nil {
+		return nil, err
+	}
+
+	trades, err := state.q.GetTrades(ctx, page, a.account.AccountID, history.AllTrades)
+	if err != nil {
+		return nil, queryFailed(ctx, err)
+	}
+
+	result := make([]*tradeResolver, len(trades))
+	for i := range trades {
+		result[i] = &tradeResolver{trade: trades[i]}
+	}
+	return result, nil
+}
+
+// Payments resolves the payment operations of the account.
+func (a *accountResolver) Payments(ctx context.Context, args historyPageArgs) ([]*operationResolver, error) {
+	return a.operations(ctx, args, true)
+}
+
+// Operations resolves the operations of the account.
+func (a *accountResolver) Operations(ctx context.Context, args historyPageArgs) ([]*operationResolver, error) {
+	return a.operations(ctx, args, false)
+}
+
+func (a *accountResolver) operations(ctx context.Context, args historyPageArgs, onlyPayments bool) ([]*operationResolver, error) {
+	state := stateFromContext(ctx)
+	page, err := args.pageArgs().pageQuery(state, true)
+	if err != nil {
+		return nil, err
+	}
+
+	query := state.q.Operations().ForAccount(ctx, a.account.AccountID)
+	if onlyPayments {
+		query = query.OnlyPayments()
+	}
+	if args.IncludeFailed {
+		query = query.IncludeFailed()
+	}
+	operations, _, err := query.Page(page).Fetch(ctx)
+	if err != nil {
+		return nil, queryFailed(ctx, err)
+	}
+
+	return newOperationResolvers(state, operations), nil
+}
+
+// Transactions resolves the transactions of the account.
+func (a *accountResolver) Transactions(ctx context.Context, args historyPageArgs) ([]*transactionResolver, error) {
+	state := stateFromContext(ctx)
+	page, err := args.pageArgs().pageQuery(state, true)
+	if err != nil {
+		return nil, err
+	}
+
+	query := state.q.Transactions().ForAccount(ctx, a.account.AccountID)
+	if args.IncludeFailed {
+		query = query.IncludeFailed()
+	}
+	var transactions []history.Transaction
+	if err = query.Page(page).Select(ctx, &transactions); err != nil {
+		return nil, queryFailed(ctx, err)
+	}
+
+	return newTransactionResolvers(state, transactions), nil
+}
+
+// ClaimableBalances resolves the claimable balances the account can claim.
+func (a *accountResolver) ClaimableBalances(ctx context.Context, args pageArgs) ([]*claimableBalanceResolver, error) {
+	state := stateFromContext(ctx)
+	page, err := args.pageQuery(state, false)
+	if err != nil {
+		return nil, err
+	}
+
+	claimant := xdr.MustAddress(a.account.AccountID)
+	query := history.ClaimableBalancesQuery{
+		PageQuery: page,
+		Claimant:  &claimant,
+	}
+	if _, _, err = query.Cursor(); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	balances, err := state.q.GetClaimableBalances(ctx, query)
+	if err != nil {
+		return nil, queryFailed(ctx, err)
+	}
+
+	result := make([]*claimableBalanceResolver, len(balances))
+	for i := range balances {
+		result[i] = &claimableBalanceResolver{balance: balances[i]}
+	}
+	return result, nil
+}
+
+// balanceResolver resolves the Balance GraphQL type.
+type balanceResolver struct {
+	asset              *assetResolver
+	liquidityPoolID    string
+	balance            int64
+	limit              *int64
+	buyingLiabilities  int64
+	sellingLiabilities int64
+	lastModifiedLedger uint32
+	sponsor            *string
+}
+
+func newTrustLineBalanceResolver(trustLine history.TrustLine) *balanceResolver {
+	limit := trustLine.Limit
+	return &balanceResolver{
+		asset: &assetResolver{
+			typ:    xdr.AssetTypeToString[trustLine.AssetType],
+			code:   trustLine.AssetCode,
+			issuer: trustLine.AssetIssuer,
+		},
+		liquidityPoolID:    trustLine.LiquidityPoolID,
+		balance:            trustLine.Balance,
+		limit:              &limit,
+		buyingLiabilities:  trustLine.BuyingLiabilities,
+		sellingLiabilities: trustLine.SellingLiabilities,
+		lastModifiedLedger: trustLine.LastModifiedLedger,
+		sponsor:            nullString(trustLine.Sponsor),
+	}
+}
+
+func (b *balanceResolver) Asset() *assetResolver    { return b.asset }
+func (b *balanceResolver) LiquidityPoolID() *string { return optionalString(b.liquidityPoolID) }
+func (b *balanceResolver) Balance() string          { return amount.StringFromInt64(b.balance) }
+func (b *balanceResolver) BuyingLiabilities() string {
+	return amount.StringFromInt64(b.buyingLiabilities)
+}
+func (b *balanceResolver) SellingLiabilities() string {
+	return amount.StringFromInt64(b.sellingLiabilities)
+}
+func (b *balanceResolver) LastModifiedLedger() int32 { return int32(b.lastModifiedLedger) }
+func (b *balanceResolver) Sponsor() *string          { return b.sponsor }
+
+func (b *balanceResolver) Limit() *string {
+	if b.limit == nil {
+		return nil
+	}
+	limit := amount.StringFromInt64(*b.limit)
+	return &limit
+}
+
+// offerResolver resolves the Offer GraphQL type.
+type offerResolver struct {
+	offer history.Offer
+}
+
+func (o *offerResolver) ID() string              { return strconv.FormatInt(o.offer.OfferID, 10) }
+func (o *offerResolver) PagingToken() string     { return o.ID() }
+func (o *offerResolver) Seller() string          { return o.offer.SellerID }
+func (o *offerResolver) Selling() *assetResolver { return newAssetResolver(o.offer.SellingAsset) }
+func (o *offerResolver) Buying() *assetResolver  { return newAssetResolver(o.offer.BuyingAsset) }
+func (o *offerResolver) Amount() string          { return amount.StringFromInt64(o.offer.Amount) }
+func (o *offerResolver) Sponsor() *string        { return nullString(o.offer.Sponsor) }
+func (o *offerResolver) LastModifiedLedger() int32 {
+	return int32(o.offer.LastModifiedLedger)
+}
+
+func (o *offerResolver) Price() string {
+	return big.NewRat(int64(o.offer.Pricen), int64(o.offer.Priced)).FloatString(7)
+}
+
+func (o *offerResolver) PriceR() *priceResolver {
+	return &priceResolver{n: int64(o.offer.Pricen), d: int64(o.offer.Priced)}
+}
+
+// tradeResolver resolves the Trade GraphQL type.
+type tradeResolver struct {
+	trade history.Trade
+}
+
+func (t *tradeResolver) ID() string          { return t.trade.PagingToken() }
+func (t *tradeResolver) PagingToken() string { return t.trade.PagingToken() }
+func (t *tradeResolver) LedgerCloseTime() graphql.Time {
+	return graphql.Time{Time: t.trade.LedgerCloseTime}
+}
+func (t *tradeResolver) BaseOfferID() *string         { return nullIntString(t.trade.BaseOfferID) }
+func (t *tradeResolver) BaseAccount() *string         { return nullString(t.trade.BaseAccount) }
+func (t *tradeResolver) BaseLiquidityPoolID() *string { return nullString(t.trade.BaseLiquidityPoolID) }
+func (t *tradeResolver) BaseAmount() string           { return amount.StringFromInt64(t.trade.BaseAmount) }
+func (t *tradeResolver) CounterOfferID() *string      { return nullIntString(t.trade.CounterOfferID) }
+func (t *tradeResolver) CounterAccount() *string      { return nullString(t.trade.CounterAccount) }
+func (t *tradeResolver) CounterLiquidityPoolID() *string {
+	return nullString(t.trade.CounterLiquidityPoolID)
+}
+func (t *tradeResolver) CounterAmount() string { return amount.StringFromInt64(t.trade.CounterAmount) }
+func (t *tradeResolver) BaseIsSeller() bool    { return t.trade.BaseIsSeller }
+
+func (t *tradeResolver) BaseAsset() *assetResolver {
+	return &assetResolver{
+		typ:    t.trade.BaseAssetType,
+		code:   t.trade.BaseAssetCode,
+		issuer: t.trade.BaseAssetIssuer,
+	}
+}
+
+func (t *tradeResolver) CounterAsset() *assetResolver {
+	return &assetResolver{
+		typ:    t.trade.CounterAssetType,
+		code:   t.trade.CounterAssetCode,
+		issuer: t.trade.CounterAssetIssuer,
+	}
+}
+
+func (t *tradeResolver) Price() *priceResolver {
+	if !t.trade.HasPrice() {
+		return nil
+	}
+	return &priceResolver{n: t.trade.PriceN.Int64, d: t.trade.PriceD.Int64}
+}
+
+// claimableBalanceResolver resolves the ClaimableBalance GraphQL type.
+type claimableBalanceResolver struct {
+	balance history.ClaimableBalance
+}
+
+func (c *claimableBalanceResolver) ID() string            { return c.balance.BalanceID }
+func (c *claimableBalanceResolver) Asset() *assetResolver { return newAssetResolver(c.balance.Asset) }
+func (c *claimableBalanceResolver) Amount() string        { return amount.String(c.balance.Amount) }
+func (c *claimableBalanceResolver) Sponsor() *string      { return nullString(c.balance.Sponsor) }
+
+func (c *claimableBalanceResolver) PagingToken() string {
+	return fmt.Sprintf("%d-%s", c.balance.LastModifiedLedger, c.balance.BalanceID)
+}
+
+func (c *claimableBalanceResolver) LastModifiedLedger() int32 {
+	return int32(c.balance.LastModifiedLedger)
+}
+
+func (c *claimableBalanceResolver) Claimants() []string {
+	claimants := make([]string, len(c.balance.Claimants))
+	for i, claimant := range c.balance.Claimants {
+		claimants[i] = claimant.Destination
+	}
+	return claimants
+}
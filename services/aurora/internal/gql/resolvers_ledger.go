@@ -0,0 +1,49 @@
+package gql
+
+import (
+	"context"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/diamnet/go/amount"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+)
+
+// Ledger resolves the ledger() GraphQL query.
+func (r *resolver) Ledger(ctx context.Context, args struct{ Sequence int32 }) (*ledgerResolver, error) {
+	state := stateFromContext(ctx)
+	if err := state.charge(1); err != nil {
+		return nil, err
+	}
+	var ledger history.Ledger
+	err := state.q.LedgerBySequence(ctx, &ledger, args.Sequence)
+	if state.q.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, queryFailed(ctx, err)
+	}
+	return &ledgerResolver{ledger: ledger}, nil
+}
+
+// ledgerResolver resolves the Ledger GraphQL type.
+type ledgerResolver struct {
+	ledger history.Ledger
+}
+
+func (l *ledgerResolver) Sequence() int32        { return l.ledger.Sequence }
+func (l *ledgerResolver) PagingToken() string    { return l.ledger.PagingToken() }
+func (l *ledgerResolver) Hash() string           { return l.ledger.LedgerHash }
+func (l *ledgerResolver) PrevHash() *string      { return nullString(l.ledger.PreviousLedgerHash) }
+func (l *ledgerResolver) ClosedAt() graphql.Time { return graphql.Time{Time: l.ledger.ClosedAt} }
+func (l *ledgerResolver) SuccessfulTransactionCount() *int32 {
+	return l.ledger.SuccessfulTransactionCount
+}
+func (l *ledgerResolver) FailedTransactionCount() *int32 { return l.ledger.FailedTransactionCount }
+func (l *ledgerResolver) OperationCount() int32          { return l.ledger.OperationCount }
+func (l *ledgerResolver) TxSetOperationCount() *int32    { return l.ledger.TxSetOperationCount }
+func (l *ledgerResolver) TotalCoins() string             { return amount.StringFromInt64(l.ledger.TotalCoins) }
+func (l *ledgerResolver) FeePool() string                { return amount.StringFromInt64(l.ledger.FeePool) }
+func (l *ledgerResolver) BaseFee() int32                 { return l.ledger.BaseFee }
+func (l *ledgerResolver) BaseReserve() int32             { return l.ledger.BaseReserve }
+func (l *ledgerResolver) MaxTxSetSize() int32            { return l.ledger.MaxTxSetSize }
+func (l *ledgerResolver) ProtocolVersion() int32         { return l.ledger.ProtocolVersion }
@@ -0,0 +1,120 @@
+package gql
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/diamnet/go/protocols/aurora/operations"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/support/errors"
+)
+
+// Transaction resolves the transaction() GraphQL query.
+func (r *resolver) Transaction(ctx context.Context, args struct{ Hash string }) (*transactionResolver, error) {
+	if len(args.Hash) != 64 {
+		return nil, errors.New("invalid transaction hash")
+	}
+
+	state := stateFromContext(ctx)
+	if err := state.charge(1); err != nil {
+		return nil, err
+	}
+	var transaction history.Transaction
+	err := state.q.TransactionByHash(ctx, &transaction, args.Hash)
+	if state.q.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, queryFailed(ctx, err)
+	}
+	return &transactionResolver{state: state, transaction: transaction}, nil
+}
+
+// transactionResolver resolves the Transaction GraphQL type.
+type transactionResolver struct {
+	state       *requestState
+	transaction history.Transaction
+}
+
+func newTransactionResolvers(state *requestState, transactions []history.Transaction) []*transactionResolver {
+	result := make([]*transactionResolver, len(transactions))
+	for i := range transactions {
+		result[i] = &transactionResolver{state: state, transaction: transactions[i]}
+		state.ledgers.prime(transactions[i].LedgerSequence)
+	}
+	return result
+}
+
+func (t *transactionResolver) ID() string                    { return t.transaction.TransactionHash }
+func (t *transactionResolver) PagingToken() string           { return t.transaction.PagingToken() }
+func (t *transactionResolver) Hash() string                  { return t.transaction.TransactionHash }
+func (t *transactionResolver) LedgerSequence() int32         { return t.transaction.LedgerSequence }
+func (t *transactionResolver) SourceAccount() string         { return t.transaction.Account }
+func (t *transactionResolver) SourceAccountSequence() string { return t.transaction.AccountSequence }
+func (t *transactionResolver) FeeAccount() *string           { return nullString(t.transaction.FeeAccount) }
+func (t *transactionResolver) FeeCharged() string {
+	return strconv.FormatInt(t.transaction.FeeCharged, 10)
+}
+func (t *transactionResolver) MaxFee() string        { return strconv.FormatInt(t.transaction.MaxFee, 10) }
+func (t *transactionResolver) OperationCount() int32 { return t.transaction.OperationCount }
+func (t *transactionResolver) Successful() bool      { return t.transaction.Successful }
+func (t *transactionResolver) MemoType() string      { return t.transaction.MemoType }
+func (t *transactionResolver) Memo() *string         { return nullString(t.transaction.Memo) }
+
+func (t *transactionResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: t.transaction.LedgerCloseTime}
+}
+
+// Ledger resolves the ledger containing the transaction.
+func (t *transactionResolver) Ledger(ctx context.Context) (*ledgerResolver, error) {
+	ledger, err := t.state.ledgers.load(ctx, t.state, t.transaction.LedgerSequence)
+	if err != nil {
+		return nil, err
+	}
+	return &ledgerResolver{ledger: ledger}, nil
+}
+
+// operationResolver resolves the Operation GraphQL type.
+type operationResolver struct {
+	state     *requestState
+	operation history.Operation
+}
+
+func newOperationResolvers(state *requestState, operations []history.Operation) []*operationResolver {
+	result := make([]*operationResolver, len(operations))
+	for i := range operations {
+		result[i] = &operationResolver{state: state, operation: operations[i]}
+		state.transactions.prime(operations[i].TransactionID)
+		state.ledgers.prime(toid.Parse(operations[i].ID).LedgerSequence)
+	}
+	return result
+}
+
+func (o *operationResolver) ID() string                  { return strconv.FormatInt(o.operation.ID, 10) }
+func (o *operationResolver) PagingToken() string         { return o.operation.PagingToken() }
+func (o *operationResolver) Type() string                { return operations.TypeNames[o.operation.Type] }
+func (o *operationResolver) TypeI() int32                { return int32(o.operation.Type) }
+func (o *operationResolver) SourceAccount() string       { return o.operation.SourceAccount }
+func (o *operationResolver) TransactionHash() string     { return o.operation.TransactionHash }
+func (o *operationResolver) TransactionSuccessful() bool { return o.operation.TransactionSuccessful }
+func (o *operationResolver) Details() *string            { return nullString(o.operation.DetailsString) }
+
+// Transaction resolves the transaction containing the operation.
+func (o *operationResolver) Transaction(ctx context.Context) (*transactionResolver, error) {
+	transaction, err := o.state.transactions.load(ctx, o.state, o.operation.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	return &transactionResolver{state: o.state, transaction: transaction}, nil
+}
+
+// Ledger resolves the ledger containing the operation.
+func (o *operationResolver) Ledger(ctx context.Context) (*ledgerResolver, error) {
+	ledger, err := o.state.ledgers.load(ctx, o.state, toid.Parse(o.operation.ID).LedgerSequence)
+	if err != nil {
+		return nil, err
+	}
+	return &ledgerResolver{ledger: ledger}, nil
+}
@@ -0,0 +1,153 @@
+package gql
+
+// Schema is the GraphQL schema served by the /graphql endpoint.
+//
+// Lists only appear on the account type and all list fields are paginated.
+// Fields pointing back to ledgers and transactions are batched per request,
+// see loaders.go.
+const Schema = `
+schema {
+	query: Query
+}
+
+scalar Time
+
+enum Order {
+	ASC
+	DESC
+}
+
+type Query {
+	account(id: String!): Account
+	ledger(sequence: Int!): Ledger
+	transaction(hash: String!): Transaction
+}
+
+type Asset {
+	type: String!
+	code: String
+	issuer: String
+}
+
+type Price {
+	n: String!
+	d: String!
+}
+
+type Balance {
+	asset: Asset!
+	liquidityPoolId: String
+	balance: String!
+	limit: String
+	buyingLiabilities: String!
+	sellingLiabilities: String!
+	lastModifiedLedger: Int!
+	sponsor: String
+}
+
+type Account {
+	id: String!
+	sequence: String!
+	subentryCount: Int!
+	homeDomain: String!
+	lastModifiedLedger: Int!
+	sponsor: String
+	balances: [Balance!]!
+	offers(limit: Int = 10, cursor: String, order: Order = ASC): [Offer!]!
+	trades(limit: Int = 10, cursor: String, order: Order = ASC): [Trade!]!
+	payments(limit: Int = 10, cursor: String, order: Order = ASC, includeFailed: Boolean = false): [Operation!]!
+	operations(limit: Int = 10, cursor: String, order: Order = ASC, includeFailed: Boolean = false): [Operation!]!
+	transactions(limit: Int = 10, cursor: String, order: Order = ASC, includeFailed: Boolean = false): [Transaction!]!
+	claimableBalances(limit: Int = 10, cursor: String, order: Order = ASC): [ClaimableBalance!]!
+}
+
+type Offer {
+	id: String!
+	pagingToken: String!
+	seller: String!
+	selling: Asset!
+	buying: Asset!
+	amount: String!
+	price: String!
+	priceR: Price!
+	lastModifiedLedger: Int!
+	sponsor: String
+}
+
+type Trade {
+	id: String!
+	pagingToken: String!
+	ledgerCloseTime: Time!
+	baseOfferId: String
+	baseAccount: String
+	baseLiquidityPoolId: String
+	baseAsset: Asset!
+	baseAmount: String!
+	counterOfferId: String
+	counterAccount: String
+	counterLiquidityPoolId: String
+	counterAsset: Asset!
+	counterAmount: String!
+	baseIsSeller: Boolean!
+	price: Price
+}
+
+type Operation {
+	id: String!
+	pagingToken: String!
+	type: String!
+	typeI: Int!
+	sourceAccount: String!
+	transactionHash: String!
+	transactionSuccessful: Boolean!
+	details: String
+	transaction: Transaction!
+	ledger: Ledger!
+}
+
+type Transaction {
+	id: String!
+	pagingToken: String!
+	hash: String!
+	ledgerSequence: Int!
+	createdAt: Time!
+	sourceAccount: String!
+	sourceAccountSequence: String!
+	feeAccount: String
+	feeCharged: String!
+	maxFee: String!
+	operationCount: Int!
+	successful: Boolean!
+	memoType: String!
+	memo: String
+	ledger: Ledger!
+}
+
+type Ledger {
+	sequence: Int!
+	pagingToken: String!
+	hash: String!
+	prevHash: String
+	closedAt: Time!
+	successfulTransactionCount: Int
+	failedTransactionCount: Int
+	operationCount: Int!
+	txSetOperationCount: Int
+	totalCoins: String!
+	feePool: String!
+	baseFee: Int!
+	baseReserve: Int!
+	maxTxSetSize: Int!
+	protocolVersion: Int!
+}
+
+type ClaimableBalance {
+	id: String!
+	pagingToken: String!
+	asset: Asset!
+	amount: String!
+	sponsor: String
+	lastModifiedLedger: Int!
+	claimants: [String!]!
+}
+`
@@ -0,0 +1,100 @@
+package gql
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/guregu/null"
+
+	"github.com/diamnet/go/services/aurora/internal/db2"
+	"github.com/diamnet/go/xdr"
+)
+
+// assetResolver resolves the Asset GraphQL type.
+type assetResolver struct {
+	typ    string
+	code   string
+	issuer string
+}
+
+func newAssetResolver(asset xdr.Asset) *assetResolver {
+	var result assetResolver
+	asset.Extract(&result.typ, &result.code, &result.issuer)
+	return &result
+}
+
+func (a *assetResolver) Type() string    { return a.typ }
+func (a *assetResolver) Code() *string   { return optionalString(a.code) }
+func (a *assetResolver) Issuer() *string { return optionalString(a.issuer) }
+
+// priceResolver resolves the Price GraphQL type.
+type priceResolver struct {
+	n int64
+	d int64
+}
+
+func (p *priceResolver) N() string { return strconv.FormatInt(p.n, 10) }
+func (p *priceResolver) D() string { return strconv.FormatInt(p.d, 10) }
+
+// pageArgs are the pagination arguments of list fields.
+type pageArgs struct {
+	Limit  int32
+	Cursor *string
+	Order  string
+}
+
+// historyPageArgs are the pagination arguments of list fields which can
+// include failed transactions.
+type historyPageArgs struct {
+	Limit         int32
+	Cursor        *string
+	Order         string
+	IncludeFailed bool
+}
+
+func (args historyPageArgs) pageArgs() pageArgs {
+	return pageArgs{Limit: args.Limit, Cursor: args.Cursor, Order: args.Order}
+}
+
+// pageQuery converts the arguments to a db2.PageQuery and charges its limit
+// to the query cost.
+func (args pageArgs) pageQuery(state *requestState, validateCursor bool) (db2.PageQuery, error) {
+	var cursor string
+	if args.Cursor != nil {
+		cursor = *args.Cursor
+	}
+	if args.Limit < 0 {
+		return db2.PageQuery{}, db2.ErrInvalidLimit
+	}
+
+	page, err := db2.NewPageQuery(cursor, validateCursor, strings.ToLower(args.Order), uint64(args.Limit))
+	if err != nil {
+		return db2.PageQuery{}, err
+	}
+	if err = state.charge(int(page.Limit)); err != nil {
+		return db2.PageQuery{}, err
+	}
+	return page, nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func nullString(s null.String) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullIntString(i null.Int) *string {
+	if !i.Valid {
+		return nil
+	}
+	s := strconv.FormatInt(i.Int64, 10)
+	return &s
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/diamnet/go/services/aurora/internal/actions"
@@ -54,6 +55,10 @@ const (
 	clientVersionHeader = "X-Client-Version"
 	appNameHeader       = "X-App-Name"
 	appVersionHeader    = "X-App-Version"
+	// minLedgerHeader is the ledger a client has already seen (usually the
+	// Latest-Ledger header of a previous response), requests are routed to
+	// read replicas which have ingested it.
+	minLedgerHeader = "X-Min-Ledger"
 )
 
 func newWrapResponseWriter(w http.ResponseWriter, r *http.Request) middleware.WrapResponseWriter {
@@ -92,6 +97,13 @@ func loggerMiddleware(serverMetrics *ServerMetrics) func(next http.Handler) http
 func timeoutMiddleware(timeout time.Duration) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			// WebSocket connections are long lived, the streaming requests of
+			// their subscriptions are subject to the timeout instead.
+			if websocket.IsWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			mw := newWrapResponseWriter(w, r)
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
 			defer func() {
@@ -255,7 +267,7 @@ func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, sessi
 				}
 			}
 
-			requestSession := session.Clone()
+			requestSession := replicaSession(ctx, session).Clone()
 			h.ServeHTTP(w, r.WithContext(
 				context.WithValue(
 					ctx,
@@ -313,7 +325,7 @@ func (m *StateMiddleware) WrapFunc(h http.HandlerFunc) http.HandlerFunc {
 		if chiRoute != nil {
 			ctx = context.WithValue(ctx, &db.RouteContextKey, sanitizeMetricRoute(chiRoute.RoutePattern()))
 		}
-		session := m.AuroraSession.Clone()
+		session := replicaSession(ctx, m.AuroraSession).Clone()
 		q := &history.Q{session}
 		sseRequest := render.Negotiate(r) == render.MimeEventStream
 
@@ -433,3 +445,58 @@ func (m *ReplicaSyncCheckMiddleware) WrapFunc(h http.HandlerFunc) http.HandlerFu
 func (m *ReplicaSyncCheckMiddleware) Wrap(h http.Handler) http.Handler {
 	return m.WrapFunc(h.ServeHTTP)
 }
+
+var replicaSessionContextKey = auroraContext.CtxKey("replica_session")
+
+// replicaSession returns the session of the read replica selected for the
+// request by ReplicaRoutingMiddleware, or session if there is none.
+func replicaSession(ctx context.Context, session db.SessionInterface) db.SessionInterface {
+	if replica, ok := ctx.Value(&replicaSessionContextKey).(db.SessionInterface); ok {
+		return replica
+	}
+	return session
+}
+
+// ReplicaRoutingMiddleware selects the read replica serving the history
+// queries of a request. Replicas which have not ingested the ledger sent by
+// the client in the X-Min-Ledger header are avoided.
+type ReplicaRoutingMiddleware struct {
+	Pool          *db.ReplicaPool
+	ServerMetrics *ServerMetrics
+}
+
+// WrapFunc executes the middleware on a given HTTP handler function
+func (m *ReplicaRoutingMiddleware) WrapFunc(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var minLedger uint32
+		if value := r.Header.Get(minLedgerHeader); value != "" {
+			ledger, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				problem.Render(r.Context(), w, problem.MakeInvalidFieldProblem(
+					minLedgerHeader,
+					supportErrors.New("header must be a ledger sequence"),
+				))
+				return
+			}
+			minLedger = uint32(ledger)
+		}
+
+		session, err := m.Pool.Session(minLedger)
+		if err == db.ErrNoReplicaAvailable {
+			problem.Render(r.Context(), w, hProblem.StaleHistory)
+			m.ServerMetrics.ReplicaLagErrorsCounter.Inc()
+			return
+		} else if err != nil {
+			problem.Render(r.Context(), w, err)
+			return
+		}
+
+		h.ServeHTTP(w, r.WithContext(
+			context.WithValue(r.Context(), &replicaSessionContextKey, session),
+		))
+	}
+}
+
+func (m *ReplicaRoutingMiddleware) Wrap(h http.Handler) http.Handler {
+	return m.WrapFunc(h.ServeHTTP)
+}
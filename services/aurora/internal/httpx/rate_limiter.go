@@ -1,17 +1,26 @@
 package httpx
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/diamnet/throttled"
 
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/services/aurora/internal/ledger"
+	"github.com/diamnet/go/services/aurora/internal/ratelimit"
 	hProblem "github.com/diamnet/go/services/aurora/internal/render/problem"
+	"github.com/diamnet/go/support/db"
 	"github.com/diamnet/go/support/render/problem"
 )
 
+// APIKeyHeader is the header containing the API key of a request. Streaming
+// clients which cannot set headers can use the `api_key` query parameter.
+const APIKeyHeader = "X-API-Key"
+
 const lruCacheSize = 50000
 
 type historyLedgerSourceFactory struct {
@@ -54,3 +63,110 @@ func newRateLimiter(rateQuota *throttled.RateQuota) (*throttled.HTTPRateLimiter,
 	}
 	return result, nil
 }
+
+// NewAPIKeyLimiter returns a ratelimit.Limiter loading API keys from the
+// database of session.
+func NewAPIKeyLimiter(store ratelimit.Store, session db.SessionInterface) *ratelimit.Limiter {
+	return ratelimit.NewLimiter(store, func(ctx context.Context, hash string) (ratelimit.Key, bool, error) {
+		q := &history.Q{session}
+		key, err := q.GetAPIKeyByHash(ctx, hash)
+		if q.NoRows(err) {
+			return ratelimit.Key{}, false, nil
+		} else if err != nil {
+			return ratelimit.Key{}, false, err
+		}
+		return ratelimit.Key{
+			ID: key.ID,
+			Tier: ratelimit.Tier{
+				Name:            key.Tier,
+				RequestsPerHour: key.RequestsPerHour,
+				StreamsPerHour:  key.StreamsPerHour,
+			},
+		}, true, nil
+	})
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+func isStreamRequest(r *http.Request) bool {
+	return r.Header.Get("Accept") == "text/event-stream"
+}
+
+// apiKeyRateLimiter rate limits requests made with API keys according to the
+// tiers of the keys. Other requests are passed to the fallback middleware.
+type apiKeyRateLimiter struct {
+	limiter  *ratelimit.Limiter
+	costs    ratelimit.Costs
+	fallback func(http.Handler) http.Handler
+}
+
+func (l apiKeyRateLimiter) Wrap(next http.Handler) http.Handler {
+	fallback := next
+	if l.fallback != nil {
+		fallback = l.fallback(next)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey := apiKeyFromRequest(r)
+		if apiKey == "" {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		key, ok, err := l.limiter.Key(ctx, apiKey)
+		if err != nil {
+			problem.Render(ctx, w, err)
+			return
+		}
+		if !ok {
+			problem.Render(ctx, w, hProblem.InvalidAPIKey)
+			return
+		}
+		r = r.WithContext(ratelimit.WithKey(ctx, key))
+
+		// The updates of streams are counted by the stream handler
+		if !isStreamRequest(r) {
+			result, err := l.limiter.Take(ctx, key, ratelimit.BucketRequests, l.costs.For(r.URL.Path))
+			if err != nil {
+				problem.Render(ctx, w, err)
+				return
+			}
+			setRateLimitHeaders(w, result)
+			if result.Limited {
+				problem.Render(ctx, w, hProblem.RateLimitExceeded)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RateLimitStream implements sse.StreamRateLimiter.
+func (l apiKeyRateLimiter) RateLimitStream(r *http.Request) (bool, bool, error) {
+	key, ok := ratelimit.KeyFromContext(r.Context())
+	if !ok {
+		return false, false, nil
+	}
+	result, err := l.limiter.Take(r.Context(), key, ratelimit.BucketStreams, 1)
+	return result.Limited, true, err
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, result ratelimit.Result) {
+	if result.Limit <= 0 {
+		return
+	}
+	resetSeconds := strconv.FormatInt(int64((result.Reset+time.Second-1)/time.Second), 10)
+	w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+	w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+	w.Header().Set("X-RateLimit-Reset", resetSeconds)
+	if result.Limited {
+		w.Header().Set("Retry-After", resetSeconds)
+	}
+}
@@ -0,0 +1,101 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/diamnet/go/services/aurora/internal/ratelimit"
+)
+
+func TestAPIKeyRateLimiter(t *testing.T) {
+	tt := assert.New(t)
+	partner := ratelimit.Key{ID: 1, Tier: ratelimit.Tier{Name: "partner", RequestsPerHour: 12, StreamsPerHour: 1}}
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), func(ctx context.Context, hash string) (ratelimit.Key, bool, error) {
+		if hash == ratelimit.HashKey("partner-key") {
+			return partner, true, nil
+		}
+		return ratelimit.Key{}, false, nil
+	})
+
+	fallbackCalls := 0
+	keyRateLimiter := apiKeyRateLimiter{
+		limiter: limiter,
+		costs:   ratelimit.Costs{"/paths": 10},
+		fallback: func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fallbackCalls++
+				next.ServeHTTP(w, r)
+			})
+		},
+	}
+	var streamLimited, streamOK bool
+	handler := keyRateLimiter.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isStreamRequest(r) {
+			var err error
+			streamLimited, streamOK, err = keyRateLimiter.RateLimitStream(r)
+			tt.NoError(err)
+		}
+	}))
+	request := func(path, apiKey string, headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if apiKey != "" {
+			r.Header.Set(APIKeyHeader, apiKey)
+		}
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// Requests without API keys are limited by the fallback
+	w := request("/ledgers", "", nil)
+	tt.Equal(http.StatusOK, w.Code)
+	tt.Equal(1, fallbackCalls)
+	tt.Empty(w.Header().Get("X-RateLimit-Limit"))
+
+	w = request("/ledgers", "unknown-key", nil)
+	tt.Equal(http.StatusUnauthorized, w.Code)
+
+	w = request("/ledgers/1", "partner-key", nil)
+	tt.Equal(http.StatusOK, w.Code)
+	tt.Equal("12", w.Header().Get("X-RateLimit-Limit"))
+	tt.Equal("11", w.Header().Get("X-RateLimit-Remaining"))
+	tt.NotEmpty(w.Header().Get("X-RateLimit-Reset"))
+
+	w = request("/paths/strict-send", "partner-key", nil)
+	tt.Equal(http.StatusOK, w.Code)
+	tt.Equal("1", w.Header().Get("X-RateLimit-Remaining"))
+
+	// The cost of path finding exceeds the remaining quota
+	w = request("/paths/strict-send", "partner-key", nil)
+	tt.Equal(http.StatusTooManyRequests, w.Code)
+	tt.Equal("1", w.Header().Get("X-RateLimit-Remaining"))
+	tt.NotEmpty(w.Header().Get("Retry-After"))
+
+	// The API key can be sent as a query parameter
+	w = request("/ledgers?api_key=partner-key", "", nil)
+	tt.Equal(http.StatusOK, w.Code)
+	tt.Equal("0", w.Header().Get("X-RateLimit-Remaining"))
+
+	// Streams use their own quota, counted by the stream handler
+	stream := map[string]string{"Accept": "text/event-stream"}
+	w = request("/ledgers", "partner-key", stream)
+	tt.Equal(http.StatusOK, w.Code)
+	tt.True(streamOK)
+	tt.False(streamLimited)
+
+	w = request("/ledgers", "partner-key", stream)
+	tt.Equal(http.StatusOK, w.Code)
+	tt.True(streamLimited)
+
+	w = request("/ledgers", "", stream)
+	tt.Equal(http.StatusOK, w.Code)
+	tt.False(streamOK)
+	tt.Equal(2, fallbackCalls)
+}
@@ -16,10 +16,15 @@ import (
 	"github.com/diamnet/throttled"
 
 	"github.com/diamnet/go/services/aurora/internal/actions"
+	"github.com/diamnet/go/services/aurora/internal/coldstorage"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/gql"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
 	"github.com/diamnet/go/services/aurora/internal/ledger"
 	"github.com/diamnet/go/services/aurora/internal/paths"
+	"github.com/diamnet/go/services/aurora/internal/ratelimit"
 	"github.com/diamnet/go/services/aurora/internal/render/sse"
+	"github.com/diamnet/go/services/aurora/internal/txsim"
 	"github.com/diamnet/go/services/aurora/internal/txsub"
 	"github.com/diamnet/go/support/db"
 	supporthttp "github.com/diamnet/go/support/http"
@@ -29,8 +34,13 @@ import (
 type RouterConfig struct {
 	DBSession        db.SessionInterface
 	PrimaryDBSession db.SessionInterface
+	ReplicaPool      *db.ReplicaPool
 	TxSubmitter      *txsub.System
 	RateQuota        *throttled.RateQuota
+	// APIKeyLimiter rate limits requests made with API keys, requests without
+	// an API key are limited by RateQuota
+	APIKeyLimiter       *ratelimit.Limiter
+	RateLimitRouteCosts ratelimit.Costs
 
 	BehindCloudflare        bool
 	BehindAWSLoadBalancer   bool
@@ -41,11 +51,20 @@ type RouterConfig struct {
 	MaxPathLength           uint
 	MaxAssetsPerPathRequest int
 	PathFinder              paths.Finder
+	OrderBook               txsim.OrderBook
 	PrometheusRegistry      *prometheus.Registry
 	CoreGetter              actions.CoreStateGetter
 	AuroraVersion          string
 	FriendbotURL            *url.URL
+	EnableGraphQL           bool
+	GraphQLMaxDepth         int
+	GraphQLMaxCost          int
 	HealthCheck             http.Handler
+	// ColdStorage serves the ledgers removed by the reaper, if set.
+	ColdStorage *coldstorage.Store
+	// IngestFilters are the transaction filters of ingestion set in the
+	// config.
+	IngestFilters processors.TransactionFilterRules
 }
 
 type Router struct {
@@ -66,13 +85,24 @@ func NewRouter(config *RouterConfig, serverMetrics *ServerMetrics, ledgerState *
 			return nil, fmt.Errorf("unable to create RateLimiter: %v", err)
 		}
 	}
-	result.addMiddleware(config, rateLimiter, serverMetrics)
-	result.addRoutes(config, rateLimiter, ledgerState)
+	var keyRateLimiter *apiKeyRateLimiter
+	if config.APIKeyLimiter != nil {
+		keyRateLimiter = &apiKeyRateLimiter{
+			limiter: config.APIKeyLimiter,
+			costs:   config.RateLimitRouteCosts,
+		}
+		if rateLimiter != nil {
+			keyRateLimiter.fallback = rateLimiter.RateLimit
+		}
+	}
+	result.addMiddleware(config, rateLimiter, keyRateLimiter, serverMetrics)
+	result.addRoutes(config, rateLimiter, keyRateLimiter, ledgerState)
 	return &result, nil
 }
 
 func (r *Router) addMiddleware(config *RouterConfig,
 	rateLimitter *throttled.HTTPRateLimiter,
+	keyRateLimiter *apiKeyRateLimiter,
 	serverMetrics *ServerMetrics) {
 
 	r.Use(chimiddleware.StripSlashes)
@@ -96,11 +126,19 @@ func (r *Router) addMiddleware(config *RouterConfig,
 	})
 	r.Use(c.Handler)
 
-	if rateLimitter != nil {
+	if keyRateLimiter != nil {
+		r.Use(keyRateLimiter.Wrap)
+	} else if rateLimitter != nil {
 		r.Use(rateLimitter.RateLimit)
 	}
 
-	if config.PrimaryDBSession != nil {
+	if config.ReplicaPool != nil {
+		replicaRoutingMiddleware := ReplicaRoutingMiddleware{
+			Pool:          config.ReplicaPool,
+			ServerMetrics: serverMetrics,
+		}
+		r.Use(replicaRoutingMiddleware.Wrap)
+	} else if config.PrimaryDBSession != nil {
 		replicaSyncMiddleware := ReplicaSyncCheckMiddleware{
 			PrimaryHistoryQ: &history.Q{config.PrimaryDBSession},
 			ReplicaHistoryQ: &history.Q{config.DBSession},
@@ -115,7 +153,7 @@ func (r *Router) addMiddleware(config *RouterConfig,
 	r.Internal.Use(loggerMiddleware(serverMetrics))
 }
 
-func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRateLimiter, ledgerState *ledger.State) {
+func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRateLimiter, keyRateLimiter *apiKeyRateLimiter, ledgerState *ledger.State) {
 	stateMiddleware := StateMiddleware{
 		AuroraSession: config.DBSession,
 	}
@@ -134,6 +172,9 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		RateLimiter:         rateLimiter,
 		LedgerSourceFactory: historyLedgerSourceFactory{ledgerState: ledgerState, updateFrequency: config.SSEUpdateFrequency},
 	}
+	if keyRateLimiter != nil {
+		streamHandler.StreamRateLimiter = keyRateLimiter
+	}
 
 	historyMiddleware := NewHistoryMiddleware(ledgerState, int32(config.StaleThreshold), config.DBSession)
 	// State endpoints behind stateMiddleware
@@ -155,6 +196,10 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 					accountData,
 				))
 				r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/offers", streamableStatePageHandler(ledgerState, actions.GetAccountOffersHandler{LedgerState: ledgerState}, streamHandler))
+				r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/balances/history", ObjectActionHandler{actions.GetAccountBalanceHistoryHandler{
+					LedgerState:   ledgerState,
+					IngestFilters: config.IngestFilters,
+				}})
 			})
 		})
 
@@ -174,6 +219,8 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 				r.With(historyMiddleware).Method(http.MethodGet, "/transactions", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/effects", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{LedgerState: ledgerState}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
+				r.With(historyMiddleware).Method(http.MethodGet, "/history", ObjectActionHandler{actions.GetLiquidityPoolHistoryHandler{LedgerState: ledgerState}})
+				r.With(historyMiddleware).Method(http.MethodGet, "/apr", ObjectActionHandler{actions.GetLiquidityPoolAPRHandler{LedgerState: ledgerState}})
 			})
 		})
 
@@ -200,6 +247,11 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths", findPaths)
 		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/strict-receive", findPaths)
 		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/strict-send", findFixedPaths)
+		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/strict-send/split", ObjectActionHandler{actions.FindSplitPathsHandler{
+			MaxPathLength:       config.MaxPathLength,
+			SetLastLedgerHeader: true,
+			PathFinder:          config.PathFinder,
+		}})
 		r.With(stateMiddleware.Wrap).Method(
 			http.MethodGet,
 			"/order_book",
@@ -208,6 +260,13 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 				action:        actions.GetOrderbookHandler{},
 			},
 		)
+
+		if config.EnableGraphQL {
+			r.With(stateMiddleware.Wrap).Method(http.MethodPost, "/graphql", gql.NewHandler(gql.Config{
+				MaxDepth: config.GraphQLMaxDepth,
+				MaxCost:  config.GraphQLMaxCost,
+			}))
+		}
 	})
 
 	// account actions - /accounts/{account_id} has been created above so we
@@ -230,17 +289,19 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	r.Route("/ledgers", func(r chi.Router) {
 		r.With(historyMiddleware).Method(http.MethodGet, "/", streamableHistoryPageHandler(ledgerState, actions.GetLedgersHandler{LedgerState: ledgerState}, streamHandler))
 		r.Route("/{ledger_id}", func(r chi.Router) {
-			r.With(historyMiddleware).Method(http.MethodGet, "/", ObjectActionHandler{actions.GetLedgerByIDHandler{LedgerState: ledgerState}})
-			r.With(historyMiddleware).Method(http.MethodGet, "/transactions", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
+			r.With(historyMiddleware).Method(http.MethodGet, "/", ObjectActionHandler{actions.GetLedgerByIDHandler{LedgerState: ledgerState, ColdStorage: config.ColdStorage}})
+			r.With(historyMiddleware).Method(http.MethodGet, "/transactions", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState, ColdStorage: config.ColdStorage}, streamHandler))
 			r.Group(func(r chi.Router) {
 				r.With(historyMiddleware).Method(http.MethodGet, "/effects", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{LedgerState: ledgerState}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/operations", streamableHistoryPageHandler(ledgerState, actions.GetOperationsHandler{
 					LedgerState:  ledgerState,
 					OnlyPayments: false,
+					ColdStorage:  config.ColdStorage,
 				}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/payments", streamableHistoryPageHandler(ledgerState, actions.GetOperationsHandler{
 					LedgerState:  ledgerState,
 					OnlyPayments: true,
+					ColdStorage:  config.ColdStorage,
 				}, streamHandler))
 			})
 		})
@@ -258,8 +319,13 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	// transaction history actions
 	r.Route("/transactions", func(r chi.Router) {
 		r.With(historyMiddleware).Method(http.MethodGet, "/", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
+		r.With(stateMiddleware.Wrap).Method(http.MethodPost, "/simulate", ObjectActionHandler{actions.SimulateTransactionHandler{
+			NetworkPassphrase: config.NetworkPassphrase,
+			OrderBook:         config.OrderBook,
+		}})
 		r.Route("/{tx_id}", func(r chi.Router) {
-			r.With(historyMiddleware).Method(http.MethodGet, "/", ObjectActionHandler{actions.GetTransactionByHashHandler{}})
+			r.With(historyMiddleware).Method(http.MethodGet, "/", ObjectActionHandler{actions.GetTransactionByHashHandler{ColdStorage: config.ColdStorage}})
+			r.With(historyMiddleware).Method(http.MethodGet, "/status", ObjectActionHandler{actions.GetTransactionStatusHandler{Submitter: config.TxSubmitter}})
 			r.With(historyMiddleware).Method(http.MethodGet, "/effects", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{LedgerState: ledgerState}, streamHandler))
 			r.With(historyMiddleware).Method(http.MethodGet, "/operations", streamableHistoryPageHandler(ledgerState, actions.GetOperationsHandler{
 				LedgerState:  ledgerState,
@@ -295,6 +361,7 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		// trading related endpoints
 		r.With(historyMiddleware).Method(http.MethodGet, "/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
 		r.With(historyMiddleware).Method(http.MethodGet, "/trade_aggregations", ObjectActionHandler{actions.GetTradeAggregationsHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}})
+		r.With(historyMiddleware).Method(http.MethodGet, "/assets/{asset}/stats", ObjectActionHandler{actions.GetAssetStatsHistoryHandler{LedgerState: ledgerState}})
 		// /offers/{offer_id} has been created above so we need to use absolute
 		// routes here.
 		r.With(historyMiddleware).Method(http.MethodGet, "/offers/{offer_id}/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
@@ -306,6 +373,11 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		NetworkPassphrase: config.NetworkPassphrase,
 		CoreStateGetter:   config.CoreGetter,
 	}})
+	r.Method(http.MethodPost, "/transactions_async", ObjectActionHandler{actions.AsyncSubmitTransactionHandler{
+		Submitter:         config.TxSubmitter,
+		NetworkPassphrase: config.NetworkPassphrase,
+		CoreStateGetter:   config.CoreGetter,
+	}})
 
 	// Network state related endpoints
 	r.Method(http.MethodGet, "/fee_stats", ObjectActionHandler{actions.FeeStatsHandler{}})
@@ -320,6 +392,9 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		r.Get("/friendbot", redirectFriendbot)
 	}
 
+	// WebSocket connections multiplexing the streams of the endpoints above
+	r.Method(http.MethodGet, "/ws", newWebSocketHandler(r.Mux))
+
 	r.NotFound(func(w http.ResponseWriter, request *http.Request) {
 		problem.Render(request.Context(), w, problem.NotFound)
 	})
@@ -328,4 +403,38 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	r.Internal.Get("/metrics", promhttp.HandlerFor(config.PrometheusRegistry, promhttp.HandlerOpts{}).ServeHTTP)
 	r.Internal.Get("/debug/pprof/heap", pprof.Index)
 	r.Internal.Get("/debug/pprof/profile", pprof.Profile)
+
+	// Webhook subscriptions, API keys and ingestion filters are written to,
+	// so always use the primary database when running against a read replica.
+	adminSession := config.DBSession
+	if config.PrimaryDBSession != nil {
+		adminSession = config.PrimaryDBSession
+	}
+	r.Internal.Route("/webhooks", func(r chi.Router) {
+		r.Use(NewHistoryMiddleware(ledgerState, 0, adminSession))
+		r.Method(http.MethodGet, "/", ObjectActionHandler{actions.GetWebhookSubscriptionsHandler{}})
+		r.Method(http.MethodPost, "/", ObjectActionHandler{actions.CreateWebhookSubscriptionHandler{}})
+		r.Method(http.MethodGet, "/{id}", ObjectActionHandler{actions.GetWebhookSubscriptionByIDHandler{}})
+		r.Method(http.MethodDelete, "/{id}", ObjectActionHandler{actions.DeleteWebhookSubscriptionHandler{}})
+		r.Method(http.MethodGet, "/{id}/dead_letters", ObjectActionHandler{actions.GetWebhookDeadLettersHandler{}})
+	})
+	r.Internal.Route("/rate_limit_tiers", func(r chi.Router) {
+		r.Use(NewHistoryMiddleware(ledgerState, 0, adminSession))
+		r.Method(http.MethodGet, "/", ObjectActionHandler{actions.GetRateLimitTiersHandler{}})
+		r.Method(http.MethodPut, "/{name}", ObjectActionHandler{actions.PutRateLimitTierHandler{Limiter: config.APIKeyLimiter}})
+		r.Method(http.MethodDelete, "/{name}", ObjectActionHandler{actions.DeleteRateLimitTierHandler{}})
+	})
+	r.Internal.Route("/api_keys", func(r chi.Router) {
+		r.Use(NewHistoryMiddleware(ledgerState, 0, adminSession))
+		r.Method(http.MethodGet, "/", ObjectActionHandler{actions.GetAPIKeysHandler{}})
+		r.Method(http.MethodPost, "/", ObjectActionHandler{actions.CreateAPIKeyHandler{}})
+		r.Method(http.MethodGet, "/{id}", ObjectActionHandler{actions.GetAPIKeyByIDHandler{}})
+		r.Method(http.MethodDelete, "/{id}", ObjectActionHandler{actions.DeleteAPIKeyHandler{Limiter: config.APIKeyLimiter}})
+	})
+	r.Internal.Route("/ingest_filters", func(r chi.Router) {
+		r.Use(NewHistoryMiddleware(ledgerState, 0, adminSession))
+		r.Method(http.MethodGet, "/", ObjectActionHandler{actions.GetIngestFiltersHandler{}})
+		r.Method(http.MethodPut, "/{name}", ObjectActionHandler{actions.PutIngestFilterHandler{}})
+		r.Method(http.MethodDelete, "/{name}", ObjectActionHandler{actions.DeleteIngestFilterHandler{}})
+	})
 }
@@ -0,0 +1,456 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+
+	protocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/render"
+	hProblem "github.com/diamnet/go/services/aurora/internal/render/problem"
+	"github.com/diamnet/go/services/aurora/internal/render/sse"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/log"
+	"github.com/diamnet/go/support/render/problem"
+)
+
+const (
+	// maxWebSocketSubscriptions is the maximum number of streams a single
+	// WebSocket connection can subscribe to at the same time.
+	maxWebSocketSubscriptions = 50
+	// maxWebSocketMessageSize is the maximum size of a message sent by
+	// clients.
+	maxWebSocketMessageSize = 4096
+	// maxWebSocketStreamFailures is the number of consecutive server errors
+	// after which a stream is closed instead of being resumed.
+	maxWebSocketStreamFailures = 3
+
+	webSocketWriteTimeout = 10 * time.Second
+	webSocketPongTimeout  = 60 * time.Second
+	webSocketPingPeriod   = 30 * time.Second
+	webSocketRetryDelay   = time.Second
+)
+
+// webSocketHandler serves the `/ws` endpoint which multiplexes the streams of
+// several streamable endpoints over a single WebSocket connection.
+//
+// Every subscription is served by sending a streaming request (with
+// `Accept: text/event-stream`) for the subscribed path to router, so the
+// streams use the same middlewares (including rate limiting) and actions as
+// SSE requests. The events generated by sse.StreamHandler are sent to the
+// WebSocket connection instead of the response (see sse.WithEventWriter).
+// Like SSE clients do, subscriptions are resumed from the last event when the
+// streaming request ends.
+type webSocketHandler struct {
+	router   http.Handler
+	upgrader websocket.Upgrader
+}
+
+func newWebSocketHandler(router http.Handler) webSocketHandler {
+	return webSocketHandler{
+		router: router,
+		upgrader: websocket.Upgrader{
+			// All origins are allowed, the same as the CORS configuration of
+			// the other endpoints.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func (handler webSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		p := problem.BadRequest
+		p.Detail = "The endpoint only accepts WebSocket connections."
+		problem.Render(r.Context(), w, p)
+		return
+	}
+
+	// Upgrade replies with an error if the handshake fails
+	conn, err := handler.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	session := &webSocketSession{
+		conn:          conn,
+		request:       r,
+		router:        handler.router,
+		subscriptions: map[string]context.CancelFunc{},
+	}
+	session.serve(ctx)
+	cancel()
+	session.wg.Wait()
+	conn.Close()
+}
+
+// webSocketSession holds the state of a single WebSocket connection.
+type webSocketSession struct {
+	conn    *websocket.Conn
+	request *http.Request
+	router  http.Handler
+
+	// writeLock serializes writes to conn, which doesn't support concurrent
+	// writers.
+	writeLock sync.Mutex
+
+	lock          sync.Mutex
+	subscriptions map[string]context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+// serve reads the client messages until the connection is closed or ctx is
+// cancelled.
+func (s *webSocketSession) serve(ctx context.Context) {
+	s.conn.SetReadLimit(maxWebSocketMessageSize)
+	s.conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.ping(ctx)
+	}()
+
+	go func() {
+		// Unblock ReadMessage when the request is cancelled
+		<-ctx.Done()
+		s.conn.SetReadDeadline(time.Now())
+	}()
+
+	for {
+		_, message, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && ctx.Err() == nil {
+				log.Ctx(ctx).WithError(err).Debug("Error reading WebSocket message")
+			}
+			return
+		}
+
+		var request protocol.WebSocketRequest
+		if err := json.Unmarshal(message, &request); err != nil {
+			s.sendError(ctx, "", problem.BadRequest)
+			continue
+		}
+
+		switch request.Action {
+		case protocol.WebSocketActionSubscribe:
+			s.subscribe(ctx, request)
+		case protocol.WebSocketActionUnsubscribe:
+			s.unsubscribe(ctx, request.Stream)
+		default:
+			s.sendError(ctx, request.Stream, problem.MakeInvalidFieldProblem(
+				"action",
+				errors.New("Action must be subscribe or unsubscribe"),
+			))
+		}
+	}
+}
+
+func (s *webSocketSession) ping(ctx context.Context) {
+	ticker := time.NewTicker(webSocketPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.writeLock.Lock()
+			err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteTimeout))
+			s.writeLock.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *webSocketSession) subscribe(ctx context.Context, request protocol.WebSocketRequest) {
+	if request.Stream == "" {
+		s.sendError(ctx, "", problem.MakeInvalidFieldProblem(
+			"stream",
+			errors.New("Stream identifier is required"),
+		))
+		return
+	}
+
+	target, err := url.Parse(request.Path)
+	if err != nil || target.IsAbs() || target.Host != "" || !strings.HasPrefix(target.Path, "/") {
+		s.sendError(ctx, request.Stream, problem.MakeInvalidFieldProblem(
+			"path",
+			errors.New("Path must be the absolute path of a streamable endpoint"),
+		))
+		return
+	}
+
+	s.lock.Lock()
+	if _, exists := s.subscriptions[request.Stream]; exists {
+		s.lock.Unlock()
+		s.sendError(ctx, request.Stream, problem.MakeInvalidFieldProblem(
+			"stream",
+			errors.New("Stream is already subscribed"),
+		))
+		return
+	}
+	if len(s.subscriptions) >= maxWebSocketSubscriptions {
+		s.lock.Unlock()
+		s.sendError(ctx, request.Stream, problem.MakeInvalidFieldProblem(
+			"stream",
+			errors.Errorf("Connection is limited to %d streams", maxWebSocketSubscriptions),
+		))
+		return
+	}
+	streamCtx, cancel := context.WithCancel(ctx)
+	s.subscriptions[request.Stream] = cancel
+	s.lock.Unlock()
+
+	s.send(protocol.WebSocketMessage{Type: protocol.WebSocketMessageSubscribed, Stream: request.Stream})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.stream(streamCtx, request.Stream, target)
+
+		s.lock.Lock()
+		// Cancelled subscriptions have already been removed (and may have
+		// been replaced by a new subscription with the same identifier).
+		if streamCtx.Err() == nil {
+			delete(s.subscriptions, request.Stream)
+		}
+		s.lock.Unlock()
+		cancel()
+	}()
+}
+
+func (s *webSocketSession) unsubscribe(ctx context.Context, stream string) {
+	s.lock.Lock()
+	cancel, exists := s.subscriptions[stream]
+	delete(s.subscriptions, stream)
+	s.lock.Unlock()
+
+	if !exists {
+		s.sendError(ctx, stream, problem.NotFound)
+		return
+	}
+
+	cancel()
+	s.send(protocol.WebSocketMessage{Type: protocol.WebSocketMessageUnsubscribed, Stream: stream})
+}
+
+// stream sends streaming requests for target until ctx is cancelled or the
+// stream fails.
+func (s *webSocketSession) stream(ctx context.Context, stream string, target *url.URL) {
+	writer := &webSocketEventWriter{session: s, stream: stream}
+	failures := 0
+	for {
+		w := &bufferedResponseWriter{header: http.Header{}}
+		writer.reset()
+		s.router.ServeHTTP(w, s.streamRequest(ctx, target, writer))
+		if ctx.Err() != nil {
+			return
+		}
+
+		var p *problem.P
+		switch {
+		case writer.err != nil:
+			p = renderProblem(ctx, writer.err)
+		case !writer.started:
+			// The request failed before streaming, or the endpoint isn't
+			// streamable.
+			p = w.problem()
+		}
+
+		if p == nil {
+			failures = 0
+			continue
+		}
+
+		if writer.sent > 0 {
+			failures = 0
+		}
+		failures++
+		if p.Status < http.StatusInternalServerError || failures >= maxWebSocketStreamFailures {
+			s.sendProblem(stream, *p)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(webSocketRetryDelay):
+		}
+	}
+}
+
+// streamRequest builds the streaming request for target based on the
+// WebSocket upgrade request, resuming the stream from the last event sent.
+func (s *webSocketSession) streamRequest(ctx context.Context, target *url.URL, writer *webSocketEventWriter) *http.Request {
+	// Reset the chi route context so the router routes the request again
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, nil)
+	ctx = sse.WithEventWriter(ctx, writer)
+
+	r := s.request.Clone(ctx)
+	r.Method = http.MethodGet
+	r.URL = &url.URL{Path: target.Path, RawQuery: target.RawQuery}
+	r.RequestURI = r.URL.RequestURI()
+	r.Body = http.NoBody
+	r.ContentLength = 0
+
+	for _, header := range []string{
+		"Connection",
+		"Upgrade",
+		"Accept-Encoding",
+		"Sec-Websocket-Key",
+		"Sec-Websocket-Version",
+		"Sec-Websocket-Extensions",
+		"Sec-Websocket-Protocol",
+	} {
+		r.Header.Del(header)
+	}
+	r.Header.Set("Accept", render.MimeEventStream)
+	if writer.lastID != "" {
+		r.Header.Set("Last-Event-ID", writer.lastID)
+	}
+
+	return r
+}
+
+func (s *webSocketSession) send(message protocol.WebSocketMessage) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+	return s.conn.WriteJSON(message)
+}
+
+func (s *webSocketSession) sendError(ctx context.Context, stream string, err error) {
+	s.sendProblem(stream, *renderProblem(ctx, err))
+}
+
+func (s *webSocketSession) sendProblem(stream string, p problem.P) {
+	s.send(protocol.WebSocketMessage{Type: protocol.WebSocketMessageError, Stream: stream, Error: &p})
+}
+
+// webSocketEventWriter is the sse.EventWriter sending the events of a stream
+// to the WebSocket connection.
+type webSocketEventWriter struct {
+	session *webSocketSession
+	stream  string
+
+	// lastID is the ID of the last event sent, it's kept when the stream is
+	// resumed.
+	lastID string
+	// lastData is the last event sent for streams of objects (which don't have
+	// IDs) so the current state of the object isn't sent again when the
+	// stream is resumed.
+	lastData []byte
+
+	started bool
+	sent    int
+	err     error
+}
+
+func (w *webSocketEventWriter) reset() {
+	w.started = false
+	w.sent = 0
+	w.err = nil
+}
+
+func (w *webSocketEventWriter) Init() {
+	w.started = true
+}
+
+func (w *webSocketEventWriter) Send(e sse.Event) {
+	w.started = true
+
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		w.err = errors.Wrap(err, "could not marshal event")
+		return
+	}
+
+	if e.ID == "" {
+		if bytes.Equal(data, w.lastData) {
+			return
+		}
+		w.lastData = data
+	} else {
+		w.lastID = e.ID
+	}
+
+	if err := w.session.send(protocol.WebSocketMessage{
+		Type:   protocol.WebSocketMessageEvent,
+		Stream: w.stream,
+		ID:     e.ID,
+		Data:   data,
+	}); err == nil {
+		w.sent++
+	}
+}
+
+func (w *webSocketEventWriter) Done() {
+	w.started = true
+}
+
+func (w *webSocketEventWriter) Err(err error) {
+	w.err = err
+}
+
+// bufferedResponseWriter records the response of a streaming request which
+// failed before streaming any event.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// problem returns the problem rendered in the response.
+func (w *bufferedResponseWriter) problem() *problem.P {
+	if w.status < http.StatusBadRequest {
+		// The endpoint responded without streaming
+		p := hProblem.NotAcceptable
+		return &p
+	}
+
+	var p problem.P
+	if err := json.Unmarshal(w.body.Bytes(), &p); err != nil || p.Status == 0 {
+		p = problem.P{Title: http.StatusText(w.status), Status: w.status}
+	}
+	return &p
+}
+
+// renderProblem returns the problem which would be rendered for err.
+func renderProblem(ctx context.Context, err error) *problem.P {
+	w := &bufferedResponseWriter{header: http.Header{}}
+	problem.Render(ctx, w, err)
+	return w.problem()
+}
@@ -0,0 +1,122 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	protocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/ledger"
+	"github.com/diamnet/go/services/aurora/internal/render/sse"
+)
+
+func readWebSocketMessage(t *testing.T, conn *websocket.Conn) protocol.WebSocketMessage {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var message protocol.WebSocketMessage
+	require.NoError(t, conn.ReadJSON(&message))
+	return message
+}
+
+func TestWebSocketStreams(t *testing.T) {
+	ledgerSource := ledger.NewTestingSource(3)
+	action := &testPageAction{
+		objects: map[uint32][]string{
+			3: {"a", "b", "c"},
+		},
+		ledgerSource: ledgerSource,
+	}
+	streamHandler := sse.StreamHandler{LedgerSourceFactory: &testingFactory{ledgerSource}}
+
+	router := chi.NewMux()
+	router.Method(http.MethodGet, "/pages", streamableHistoryPageHandler(&ledger.State{}, action, streamHandler))
+	router.Method(http.MethodGet, "/ws", newWebSocketHandler(router))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Errors of the streaming request are sent to the stream
+	require.NoError(t, conn.WriteJSON(protocol.WebSocketRequest{
+		Action: protocol.WebSocketActionSubscribe,
+		Stream: "missing",
+		Path:   "/missing",
+	}))
+	message := readWebSocketMessage(t, conn)
+	assert.Equal(t, protocol.WebSocketMessageSubscribed, message.Type)
+	assert.Equal(t, "missing", message.Stream)
+	message = readWebSocketMessage(t, conn)
+	assert.Equal(t, protocol.WebSocketMessageError, message.Type)
+	assert.Equal(t, "missing", message.Stream)
+	require.NotNil(t, message.Error)
+	assert.Equal(t, http.StatusNotFound, message.Error.Status)
+
+	// Paths must not point to other hosts
+	require.NoError(t, conn.WriteJSON(protocol.WebSocketRequest{
+		Action: protocol.WebSocketActionSubscribe,
+		Stream: "invalid",
+		Path:   "https://example.com/pages",
+	}))
+	message = readWebSocketMessage(t, conn)
+	assert.Equal(t, protocol.WebSocketMessageError, message.Type)
+	assert.Equal(t, "path", message.Error.Extras["invalid_field"])
+
+	// The stream is resumed from the last event when the streaming request
+	// reaches its limit
+	require.NoError(t, conn.WriteJSON(protocol.WebSocketRequest{
+		Action: protocol.WebSocketActionSubscribe,
+		Stream: "pages",
+		Path:   "/pages?cursor=0&limit=2",
+	}))
+	message = readWebSocketMessage(t, conn)
+	assert.Equal(t, protocol.WebSocketMessageSubscribed, message.Type)
+
+	var values, ids []string
+	for i := 0; i < 3; i++ {
+		message = readWebSocketMessage(t, conn)
+		require.Equal(t, protocol.WebSocketMessageEvent, message.Type)
+		assert.Equal(t, "pages", message.Stream)
+
+		var page testPage
+		require.NoError(t, json.Unmarshal(message.Data, &page))
+		values = append(values, page.Value)
+		ids = append(ids, message.ID)
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, values)
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+
+	require.NoError(t, conn.WriteJSON(protocol.WebSocketRequest{
+		Action: protocol.WebSocketActionUnsubscribe,
+		Stream: "pages",
+	}))
+	message = readWebSocketMessage(t, conn)
+	assert.Equal(t, protocol.WebSocketMessageUnsubscribed, message.Type)
+	assert.Equal(t, "pages", message.Stream)
+
+	require.NoError(t, conn.WriteJSON(protocol.WebSocketRequest{
+		Action: protocol.WebSocketActionUnsubscribe,
+		Stream: "pages",
+	}))
+	message = readWebSocketMessage(t, conn)
+	assert.Equal(t, protocol.WebSocketMessageError, message.Type)
+	assert.Equal(t, http.StatusNotFound, message.Error.Status)
+}
+
+func TestWebSocketRequiresUpgrade(t *testing.T) {
+	router := chi.NewMux()
+	router.Method(http.MethodGet, "/ws", newWebSocketHandler(router))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/diamnet/go/support/errors"
+)
+
+// FileSink appends the events to a file as newline delimited JSON (one event
+// per line).
+type FileSink struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+// NewFileSink opens (or creates) the file at the given path for appending
+// events.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open %s", path)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Publish writes the events to the file and syncs it.
+func (s *FileSink) Publish(ctx context.Context, events []Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	writer := bufio.NewWriter(s.file)
+	encoder := json.NewEncoder(writer)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return errors.Wrapf(err, "could not write event %s", event.ID)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return errors.Wrap(err, "could not write events")
+	}
+	return errors.Wrap(s.file.Sync(), "could not sync events file")
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.file.Close()
+}
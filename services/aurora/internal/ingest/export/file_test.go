@@ -0,0 +1,52 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	ctx := context.Background()
+
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Publish(ctx, []Event{
+		{ID: EventID(TransactionEvent, 1, 0), Type: TransactionEvent, LedgerSequence: 10, TOID: 1},
+		{ID: EventID(LedgerEvent, 2, 0), Type: LedgerEvent, LedgerSequence: 10, TOID: 2},
+	}))
+	require.NoError(t, sink.Close())
+
+	// Events are appended to the existing file
+	sink, err = NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, sink.Publish(ctx, []Event{
+		{ID: EventID(LedgerEvent, 3, 0), Type: LedgerEvent, LedgerSequence: 11, TOID: 3},
+	}))
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		ids = append(ids, event.ID)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{
+		"transaction-0000000000000000001-0",
+		"ledger-0000000000000000002-0",
+		"ledger-0000000000000000003-0",
+	}, ids)
+}
@@ -0,0 +1,446 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/diamnet/go/support/errors"
+)
+
+const (
+	kafkaProduceAPIKey  = 0
+	kafkaMetadataAPIKey = 3
+
+	defaultKafkaClientID      = "aurora"
+	defaultKafkaTimeout       = 30 * time.Second
+	defaultKafkaMaxBatchBytes = 900 * 1024
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// KafkaConfig configures the KafkaSink.
+type KafkaConfig struct {
+	// Brokers are the addresses (host:port) of the brokers used to find the
+	// leader of the partition.
+	Brokers []string
+	Topic   string
+	// Partition is the partition of the topic the events are produced to. A
+	// single partition is used so that consumers receive the events in order.
+	Partition int32
+	// ClientID identifies Aurora in the logs of the brokers. Defaults to
+	// "aurora".
+	ClientID string
+	// Timeout of the requests sent to the brokers. Defaults to 30s.
+	Timeout time.Duration
+	// MaxBatchBytes is the approximate maximum size of the record batches
+	// sent to the brokers, it must be lower than the max.message.bytes of the
+	// topic. The events of a ledger are split into several batches when
+	// needed. Defaults to 900KiB.
+	MaxBatchBytes int
+}
+
+// KafkaSink produces the events to a Kafka topic (or any broker implementing
+// the Kafka protocol) with the event ID as the record key. Records are only
+// considered published once all the in-sync replicas have them (acks=all).
+type KafkaSink struct {
+	config KafkaConfig
+
+	lock          sync.Mutex
+	conn          net.Conn
+	correlationID int32
+}
+
+// NewKafkaSink returns a new KafkaSink. Brokers are contacted on the first
+// call to Publish.
+func NewKafkaSink(config KafkaConfig) (*KafkaSink, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("at least one Kafka broker is required")
+	}
+	if config.Topic == "" {
+		return nil, errors.New("Kafka topic is required")
+	}
+	if config.ClientID == "" {
+		config.ClientID = defaultKafkaClientID
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultKafkaTimeout
+	}
+	if config.MaxBatchBytes <= 0 {
+		config.MaxBatchBytes = defaultKafkaMaxBatchBytes
+	}
+	return &KafkaSink{config: config}, nil
+}
+
+type kafkaRecord struct {
+	key, value []byte
+}
+
+// Publish produces the events. The connection to the partition leader is
+// closed on errors and opened again, refreshing the metadata, on the next
+// call.
+func (s *KafkaSink) Publish(ctx context.Context, events []Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var batches [][]kafkaRecord
+	var batch []kafkaRecord
+	batchBytes := 0
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return errors.Wrapf(err, "could not encode event %s", event.ID)
+		}
+		record := kafkaRecord{key: []byte(event.ID), value: value}
+		size := len(record.key) + len(record.value)
+		if len(batch) > 0 && batchBytes+size > s.config.MaxBatchBytes {
+			batches = append(batches, batch)
+			batch, batchBytes = nil, 0
+		}
+		batch = append(batch, record)
+		batchBytes += size
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	for _, batch := range batches {
+		if err := s.produce(ctx, batch); err != nil {
+			s.closeConn()
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the broker.
+func (s *KafkaSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closeConn()
+}
+
+func (s *KafkaSink) closeConn() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *KafkaSink) produce(ctx context.Context, records []kafkaRecord) error {
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	var body kafkaEncoder
+	body.int16(-1) // transactional_id
+	body.int16(-1) // acks=all
+	body.int32(int32(s.config.Timeout / time.Millisecond))
+	body.int32(1)
+	body.string(s.config.Topic)
+	body.int32(1)
+	body.int32(s.config.Partition)
+	body.bytes(encodeRecordBatch(records, time.Now().UnixNano()/int64(time.Millisecond)))
+
+	response, err := s.request(ctx, s.conn, kafkaProduceAPIKey, 3, body.Bytes())
+	if err != nil {
+		return errors.Wrap(err, "could not produce events")
+	}
+
+	d := kafkaDecoder{buf: response}
+	for i := d.int32(); i > 0 && d.err == nil; i-- {
+		d.string()
+		for j := d.int32(); j > 0 && d.err == nil; j-- {
+			d.int32()
+			code := d.int16()
+			d.int64()
+			d.int64()
+			if d.err == nil && code != 0 {
+				return errors.Errorf("could not produce events: Kafka error code %d", code)
+			}
+		}
+	}
+	return errors.Wrap(d.err, "could not decode produce response")
+}
+
+// connect finds the leader of the partition using the first broker which
+// responds and opens a connection to it.
+func (s *KafkaSink) connect(ctx context.Context) error {
+	var err error
+	for _, broker := range s.config.Brokers {
+		var leader string
+		leader, err = s.findLeader(ctx, broker)
+		if err != nil {
+			continue
+		}
+		s.conn, err = s.dial(ctx, leader)
+		if err == nil {
+			return nil
+		}
+	}
+	return errors.Wrapf(err, "could not connect to the leader of %s/%d", s.config.Topic, s.config.Partition)
+}
+
+func (s *KafkaSink) dial(ctx context.Context, address string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: s.config.Timeout}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+func (s *KafkaSink) findLeader(ctx context.Context, broker string) (string, error) {
+	conn, err := s.dial(ctx, broker)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	var body kafkaEncoder
+	body.int32(1)
+	body.string(s.config.Topic)
+	response, err := s.request(ctx, conn, kafkaMetadataAPIKey, 1, body.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	d := kafkaDecoder{buf: response}
+	brokers := map[int32]string{}
+	for i := d.int32(); i > 0 && d.err == nil; i-- {
+		nodeID := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		brokers[nodeID] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.int32() // controller_id
+	for i := d.int32(); i > 0 && d.err == nil; i-- {
+		topicCode := d.int16()
+		topic := d.string()
+		d.int8() // is_internal
+		for j := d.int32(); j > 0 && d.err == nil; j-- {
+			partitionCode := d.int16()
+			partition := d.int32()
+			leader := d.int32()
+			d.int32Array() // replicas
+			d.int32Array() // in-sync replicas
+			if d.err != nil || topic != s.config.Topic || partition != s.config.Partition {
+				continue
+			}
+			if topicCode != 0 {
+				return "", errors.Errorf("Kafka error code %d", topicCode)
+			}
+			if partitionCode != 0 {
+				return "", errors.Errorf("Kafka error code %d", partitionCode)
+			}
+			if address, ok := brokers[leader]; ok {
+				return address, nil
+			}
+		}
+		if d.err == nil && topicCode != 0 {
+			return "", errors.Errorf("Kafka error code %d", topicCode)
+		}
+	}
+	if d.err != nil {
+		return "", errors.Wrap(d.err, "could not decode metadata response")
+	}
+	return "", errors.Errorf("no leader found for %s/%d", s.config.Topic, s.config.Partition)
+}
+
+// request sends a request and returns the body of its response.
+func (s *KafkaSink) request(ctx context.Context, conn net.Conn, apiKey, apiVersion int16, body []byte) ([]byte, error) {
+	deadline := time.Now().Add(s.config.Timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	s.correlationID++
+	var request kafkaEncoder
+	request.int32(int32(2 + 2 + 4 + 2 + len(s.config.ClientID) + len(body)))
+	request.int16(apiKey)
+	request.int16(apiVersion)
+	request.int32(s.correlationID)
+	request.string(s.config.ClientID)
+	request.raw(body)
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 {
+		return nil, errors.Errorf("invalid response size %d", size)
+	}
+	if correlationID := int32(binary.BigEndian.Uint32(header[4:])); correlationID != s.correlationID {
+		return nil, errors.Errorf("unexpected correlation ID %d", correlationID)
+	}
+	response := make([]byte, size-4)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// encodeRecordBatch encodes the records into a record batch (magic 2)
+// starting at offset 0.
+func encodeRecordBatch(records []kafkaRecord, timestamp int64) []byte {
+	var encodedRecords kafkaEncoder
+	for i, record := range records {
+		var r kafkaEncoder
+		r.int8(0)   // attributes
+		r.varint(0) // timestamp delta
+		r.varint(int64(i))
+		r.varint(int64(len(record.key)))
+		r.raw(record.key)
+		r.varint(int64(len(record.value)))
+		r.raw(record.value)
+		r.varint(0) // headers
+		encodedRecords.varint(int64(r.Len()))
+		encodedRecords.raw(r.Bytes())
+	}
+
+	// The CRC covers everything from the attributes to the end of the batch
+	var checked kafkaEncoder
+	checked.int16(0) // attributes
+	checked.int32(int32(len(records) - 1))
+	checked.int64(timestamp)
+	checked.int64(timestamp)
+	checked.int64(-1) // producer ID
+	checked.int16(-1) // producer epoch
+	checked.int32(-1) // base sequence
+	checked.int32(int32(len(records)))
+	checked.raw(encodedRecords.Bytes())
+
+	var batch kafkaEncoder
+	batch.int64(0)
+	batch.int32(int32(4 + 1 + 4 + checked.Len()))
+	batch.int32(-1) // partition leader epoch
+	batch.int8(2)
+	batch.int32(int32(crc32.Checksum(checked.Bytes(), castagnoliTable)))
+	batch.raw(checked.Bytes())
+	return batch.Bytes()
+}
+
+type kafkaEncoder struct {
+	bytes.Buffer
+}
+
+func (e *kafkaEncoder) int8(v int8) {
+	e.WriteByte(byte(v))
+}
+
+func (e *kafkaEncoder) int16(v int16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(v))
+	e.Write(b[:])
+}
+
+func (e *kafkaEncoder) int32(v int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	e.Write(b[:])
+}
+
+func (e *kafkaEncoder) int64(v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	e.Write(b[:])
+}
+
+func (e *kafkaEncoder) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	e.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func (e *kafkaEncoder) string(v string) {
+	e.int16(int16(len(v)))
+	e.WriteString(v)
+}
+
+func (e *kafkaEncoder) bytes(v []byte) {
+	e.int32(int32(len(v)))
+	e.Write(v)
+}
+
+func (e *kafkaEncoder) raw(v []byte) {
+	e.Write(v)
+}
+
+// kafkaDecoder reads big endian values, after the first error all the reads
+// return zero values.
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// string reads a string, null strings are returned as "".
+func (d *kafkaDecoder) string() string {
+	n := d.int16()
+	if n == -1 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+func (d *kafkaDecoder) int32Array() []int32 {
+	var values []int32
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		values = append(values, d.int32())
+	}
+	return values
+}
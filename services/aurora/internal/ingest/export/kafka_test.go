@@ -0,0 +1,203 @@
+package export
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKafkaBroker answers Metadata requests, announcing itself as the leader
+// of every partition, and Produce requests, recording the produced batches.
+type fakeKafkaBroker struct {
+	listener    net.Listener
+	produceCode int16
+	batches     chan []byte
+}
+
+func newFakeKafkaBroker(t *testing.T) *fakeKafkaBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	broker := &fakeKafkaBroker{listener: listener, batches: make(chan []byte, 10)}
+	t.Cleanup(func() { listener.Close() })
+	go broker.serve()
+	return broker
+}
+
+func (b *fakeKafkaBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *fakeKafkaBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		request := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+
+		d := kafkaDecoder{buf: request}
+		apiKey := d.int16()
+		d.int16()
+		correlationID := d.int32()
+		d.string()
+
+		var response kafkaEncoder
+		response.int32(correlationID)
+		switch apiKey {
+		case kafkaMetadataAPIKey:
+			d.int32()
+			topic := d.string()
+			host, port, _ := net.SplitHostPort(b.listener.Addr().String())
+			portNumber, _ := strconv.Atoi(port)
+			response.int32(1)
+			response.int32(7)
+			response.string(host)
+			response.int32(int32(portNumber))
+			response.int16(-1)
+			response.int32(7)
+			response.int32(1)
+			response.int16(0)
+			response.string(topic)
+			response.int8(0)
+			response.int32(3)
+			for partition := int32(0); partition < 3; partition++ {
+				response.int16(0)
+				response.int32(partition)
+				response.int32(7)
+				response.int32(0)
+				response.int32(0)
+			}
+		case kafkaProduceAPIKey:
+			d.int16()
+			d.int16()
+			d.int32()
+			d.int32()
+			topic := d.string()
+			d.int32()
+			partition := d.int32()
+			batch := d.next(int(d.int32()))
+			b.batches <- batch
+			response.int32(1)
+			response.string(topic)
+			response.int32(1)
+			response.int32(partition)
+			response.int16(b.produceCode)
+			response.int64(0)
+			response.int64(-1)
+			response.int32(0)
+		}
+
+		var responseSize kafkaEncoder
+		responseSize.int32(int32(response.Len()))
+		conn.Write(append(responseSize.Bytes(), response.Bytes()...))
+	}
+}
+
+// decodeRecordBatch checks the CRC of the batch and returns its records.
+func decodeRecordBatch(t *testing.T, batch []byte) []kafkaRecord {
+	d := kafkaDecoder{buf: batch}
+	assert.Equal(t, int64(0), d.int64())
+	assert.Equal(t, int(d.int32()), len(batch)-12)
+	d.int32()
+	assert.Equal(t, int8(2), d.int8())
+	crc := uint32(d.int32())
+	assert.Equal(t, crc32.Checksum(d.buf, castagnoliTable), crc)
+	d.next(2 + 4 + 8 + 8 + 8 + 2 + 4)
+	count := d.int32()
+	require.NoError(t, d.err)
+
+	var records []kafkaRecord
+	for i := int32(0); i < count; i++ {
+		length, n := binary.Varint(d.buf)
+		record := d.buf[n : n+int(length)]
+		d.buf = d.buf[n+int(length):]
+
+		record = record[1:] // attributes
+		for j := 0; j < 2; j++ {
+			_, n = binary.Varint(record)
+			record = record[n:]
+		}
+		keyLength, n := binary.Varint(record)
+		key := record[n : n+int(keyLength)]
+		record = record[n+int(keyLength):]
+		valueLength, n := binary.Varint(record)
+		value := record[n : n+int(valueLength)]
+		records = append(records, kafkaRecord{key: key, value: value})
+	}
+	assert.Empty(t, d.buf)
+	return records
+}
+
+func TestKafkaSinkPublish(t *testing.T) {
+	broker := newFakeKafkaBroker(t)
+	sink, err := NewKafkaSink(KafkaConfig{
+		Brokers:       []string{"127.0.0.1:1", broker.listener.Addr().String()},
+		Topic:         "ledgers",
+		Partition:     2,
+		MaxBatchBytes: 200,
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	events := []Event{
+		{ID: EventID(TransactionEvent, 1, 0), Type: TransactionEvent, LedgerSequence: 10, TOID: 1, Data: map[string]string{"hash": "a"}},
+		{ID: EventID(OperationEvent, 2, 0), Type: OperationEvent, LedgerSequence: 10, TOID: 2},
+		{ID: EventID(LedgerEvent, 0, 0), Type: LedgerEvent, LedgerSequence: 10},
+	}
+	require.NoError(t, sink.Publish(context.Background(), events))
+
+	// The events do not fit in a single batch of 200 bytes
+	var received []Event
+	for len(received) < len(events) {
+		for _, record := range decodeRecordBatch(t, <-broker.batches) {
+			var event Event
+			require.NoError(t, json.Unmarshal(record.value, &event))
+			assert.Equal(t, event.ID, string(record.key))
+			received = append(received, event)
+		}
+	}
+	assert.Len(t, received, 3)
+	for i, event := range received {
+		assert.Equal(t, events[i].ID, event.ID)
+	}
+}
+
+func TestKafkaSinkPublishError(t *testing.T) {
+	broker := newFakeKafkaBroker(t)
+	broker.produceCode = 6 // NOT_LEADER_OR_FOLLOWER
+	sink, err := NewKafkaSink(KafkaConfig{
+		Brokers: []string{broker.listener.Addr().String()},
+		Topic:   "ledgers",
+	})
+	require.NoError(t, err)
+	defer sink.Close()
+
+	err = sink.Publish(context.Background(), []Event{{ID: "ledger-1", Type: LedgerEvent}})
+	assert.EqualError(t, err, "could not produce events: Kafka error code 6")
+	assert.Nil(t, sink.conn)
+}
+
+func TestNewKafkaSink(t *testing.T) {
+	_, err := NewKafkaSink(KafkaConfig{Topic: "ledgers"})
+	assert.EqualError(t, err, "at least one Kafka broker is required")
+	_, err = NewKafkaSink(KafkaConfig{Brokers: []string{"localhost:9092"}})
+	assert.EqualError(t, err, "Kafka topic is required")
+}
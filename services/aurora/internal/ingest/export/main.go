@@ -0,0 +1,125 @@
+// Package export publishes the data of the ledgers ingested by Aurora as an
+// ordered stream of events, for consumers which cannot keep up by polling the
+// REST API.
+//
+// The events of a ledger are published before the ledger is committed to the
+// history database. If ingestion fails after the events were published the
+// ledger is ingested again and its events are published again, so events are
+// delivered at least once. Every event has an ID which is unique and stable
+// across retries: consumers should use it to discard duplicates.
+package export
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Types of the exported events.
+const (
+	// TransactionEvent is published for every ingested transaction.
+	TransactionEvent = "transaction"
+	// OperationEvent is published for every operation of an ingested
+	// transaction, after the transaction event.
+	OperationEvent = "operation"
+	// EffectEvent is published for every effect of an operation, after the
+	// operation event.
+	EffectEvent = "effect"
+	// StateChangeEvent is published for every ledger entry created, updated or
+	// removed by the ledger.
+	StateChangeEvent = "state_change"
+	// LedgerEvent is the last event of every ledger. Once it is received all
+	// the events of the ledger have been received.
+	LedgerEvent = "ledger"
+)
+
+// Event is a single exported event.
+type Event struct {
+	// ID uniquely identifies the event, see EventID.
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// LedgerSequence is the sequence of the ledger which produced the event.
+	LedgerSequence uint32 `json:"ledger_sequence"`
+	// TOID is the total order ID of the transaction (transaction events) or of
+	// the operation (operation and effect events). It is the ID of the ledger
+	// for state change and ledger events.
+	TOID int64       `json:"toid"`
+	Data interface{} `json:"data"`
+}
+
+// EventID returns the ID of the event with the given type, TOID and index.
+// The index distinguishes events sharing a TOID, ex. the effects of an
+// operation, and is 0 otherwise.
+func EventID(eventType string, toid int64, index uint32) string {
+	return fmt.Sprintf("%s-%019d-%d", eventType, toid, index)
+}
+
+// Sink receives the exported events.
+type Sink interface {
+	// Publish durably stores the events of a ledger, in the given order. When
+	// it returns an error the ledger is ingested again and all its events are
+	// published again, even if some of them were stored.
+	Publish(ctx context.Context, events []Event) error
+	Close() error
+}
+
+// Transaction is the data of transaction events.
+type Transaction struct {
+	Hash               string `json:"hash"`
+	Successful         bool   `json:"successful"`
+	SourceAccount      string `json:"source_account"`
+	SourceAccountMuxed string `json:"source_account_muxed,omitempty"`
+	FeeAccount         string `json:"fee_account,omitempty"`
+	FeeCharged         int64  `json:"fee_charged"`
+	MaxFee             int64  `json:"max_fee"`
+	OperationCount     int    `json:"operation_count"`
+	EnvelopeXDR        string `json:"envelope_xdr"`
+	ResultXDR          string `json:"result_xdr"`
+	ApplicationOrder   uint32 `json:"application_order"`
+}
+
+// Operation is the data of operation events. Details contains the same
+// fields as the operation resources of the REST API.
+type Operation struct {
+	Type                  string                 `json:"type"`
+	TypeI                 int32                  `json:"type_i"`
+	SourceAccount         string                 `json:"source_account"`
+	SourceAccountMuxed    string                 `json:"source_account_muxed,omitempty"`
+	TransactionHash       string                 `json:"transaction_hash"`
+	TransactionSuccessful bool                   `json:"transaction_successful"`
+	Details               map[string]interface{} `json:"details"`
+}
+
+// Effect is the data of effect events. Details contains the same fields as
+// the effect resources of the REST API.
+type Effect struct {
+	Type         string                 `json:"type"`
+	TypeI        int32                  `json:"type_i"`
+	Account      string                 `json:"account"`
+	AccountMuxed string                 `json:"account_muxed,omitempty"`
+	Details      map[string]interface{} `json:"details"`
+}
+
+// StateChange is the data of state change events. The ledger entries are
+// base64 encoded XDR, PreXDR is empty for created entries and PostXDR for
+// removed entries.
+type StateChange struct {
+	EntryType string `json:"entry_type"`
+	Change    string `json:"change"`
+	KeyXDR    string `json:"key_xdr"`
+	PreXDR    string `json:"pre_xdr,omitempty"`
+	PostXDR   string `json:"post_xdr,omitempty"`
+}
+
+// Ledger is the data of ledger events.
+type Ledger struct {
+	Hash                       string    `json:"hash"`
+	PreviousHash               string    `json:"prev_hash"`
+	ClosedAt                   time.Time `json:"closed_at"`
+	ProtocolVersion            uint32    `json:"protocol_version"`
+	SuccessfulTransactionCount int       `json:"successful_transaction_count"`
+	FailedTransactionCount     int       `json:"failed_transaction_count"`
+	OperationCount             int       `json:"operation_count"`
+	// EventCount is the number of events of the ledger, including this one.
+	EventCount int `json:"event_count"`
+}
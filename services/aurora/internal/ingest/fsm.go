@@ -483,6 +483,10 @@ func (r resumeState) run(s *system) (transition, error) {
 		log.WithError(err).Warn("error updating diamnet-core cursor")
 	}
 
+	if s.webhooks != nil {
+		s.webhooks.NotifyLedger(ingestLedger)
+	}
+
 	duration = time.Since(startTime).Seconds()
 	s.Metrics().LedgerIngestionDuration.Observe(float64(duration))
 
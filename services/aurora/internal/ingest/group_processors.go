@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
 	"github.com/diamnet/go/support/errors"
 )
 
@@ -51,6 +52,11 @@ func (g groupChangeProcessors) Commit(ctx context.Context) error {
 
 type groupTransactionProcessors struct {
 	processors []auroraTransactionProcessor
+	// filter, if set, selects the transactions passed to the processors
+	// writing history rows, the rejected transactions are only passed to
+	// the processors which need every transaction of the ledger (see
+	// processesAllTransactions).
+	filter func(ingest.LedgerTransaction) (bool, error)
 	processorsRunDurations
 }
 
@@ -62,7 +68,20 @@ func newGroupTransactionProcessors(processors []auroraTransactionProcessor) *gro
 }
 
 func (g groupTransactionProcessors) ProcessTransaction(ctx context.Context, tx ingest.LedgerTransaction) error {
+	accepted := true
+	if g.filter != nil {
+		startTime := time.Now()
+		var err error
+		if accepted, err = g.filter(tx); err != nil {
+			return errors.Wrap(err, "error filtering transaction")
+		}
+		g.AddRunDuration("filter", startTime)
+	}
+
 	for _, p := range g.processors {
+		if !accepted && !processesAllTransactions(p) {
+			continue
+		}
 		startTime := time.Now()
 		if err := p.ProcessTransaction(ctx, tx); err != nil {
 			return errors.Wrapf(err, "error in %T.ProcessTransaction", p)
@@ -82,3 +101,20 @@ func (g groupTransactionProcessors) Commit(ctx context.Context) error {
 	}
 	return nil
 }
+
+// processesAllTransactions returns true for the processors which are not
+// affected by the transaction filters: the ledger stats and the ledgers
+// table count all the transactions and operations of a ledger, and the asset
+// and liquidity pool stats history aggregate the payments and trades of all
+// the transactions.
+func processesAllTransactions(p auroraTransactionProcessor) bool {
+	switch p.(type) {
+	case *statsLedgerTransactionProcessor,
+		*processors.LedgersProcessor,
+		*processors.AssetStatsHistoryProcessor,
+		*processors.LiquidityPoolStatsProcessor:
+		return true
+	default:
+		return false
+	}
+}
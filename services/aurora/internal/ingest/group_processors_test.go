@@ -7,10 +7,13 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
+	"github.com/diamnet/go/xdr"
 )
 
 var _ auroraChangeProcessor = (*mockAuroraChangeProcessor)(nil)
@@ -190,3 +193,43 @@ func (s *GroupTransactionProcessorsTestSuiteLedger) TestCommitSucceeds() {
 	err := s.processors.Commit(s.ctx)
 	s.Assert().NoError(err)
 }
+
+func (s *GroupTransactionProcessorsTestSuiteLedger) TestProcessTransactionFiltered() {
+	transaction := ingest.LedgerTransaction{Index: 1}
+	s.processors.filter = func(tx ingest.LedgerTransaction) (bool, error) {
+		return tx.Index != 1, nil
+	}
+
+	err := s.processors.ProcessTransaction(s.ctx, transaction)
+	s.Assert().NoError(err)
+
+	transaction = ingest.LedgerTransaction{Index: 2}
+	s.processorA.
+		On("ProcessTransaction", s.ctx, transaction).
+		Return(nil).Once()
+	s.processorB.
+		On("ProcessTransaction", s.ctx, transaction).
+		Return(nil).Once()
+
+	err = s.processors.ProcessTransaction(s.ctx, transaction)
+	s.Assert().NoError(err)
+}
+
+func (s *GroupTransactionProcessorsTestSuiteLedger) TestProcessTransactionFilterFails() {
+	s.processors.filter = func(tx ingest.LedgerTransaction) (bool, error) {
+		return false, errors.New("transient error")
+	}
+
+	err := s.processors.ProcessTransaction(s.ctx, ingest.LedgerTransaction{})
+	s.Assert().EqualError(err, "error filtering transaction: transient error")
+}
+
+func TestProcessesAllTransactions(t *testing.T) {
+	ledger := xdr.LedgerHeaderHistoryEntry{}
+	assert.True(t, processesAllTransactions(&statsLedgerTransactionProcessor{}))
+	assert.True(t, processesAllTransactions(processors.NewLedgerProcessor(nil, ledger, 0)))
+	assert.True(t, processesAllTransactions(processors.NewAssetStatsHistoryProcessor(nil, ledger, false)))
+	assert.True(t, processesAllTransactions(processors.NewLiquidityPoolStatsProcessor(nil, ledger)))
+	assert.False(t, processesAllTransactions(&processors.OperationProcessor{}))
+	assert.False(t, processesAllTransactions(&mockAuroraTransactionProcessor{}))
+}
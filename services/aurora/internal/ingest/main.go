@@ -17,6 +17,10 @@ import (
 	"github.com/diamnet/go/ingest"
 	"github.com/diamnet/go/ingest/ledgerbackend"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ingest/export"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
+	"github.com/diamnet/go/services/aurora/internal/webhooks"
+	"github.com/diamnet/go/services/aurora/plugins"
 	"github.com/diamnet/go/support/db"
 	"github.com/diamnet/go/support/errors"
 	logpkg "github.com/diamnet/go/support/log"
@@ -83,6 +87,35 @@ type Config struct {
 	DisableStateVerification     bool
 	EnableExtendedLogLedgerStats bool
 
+	// EnableWebhooks enables delivery of ingested operations to webhook
+	// subscriptions after each ledger is committed.
+	EnableWebhooks bool
+
+	// EnableStateVersions keeps the previous versions of the state tables rows
+	// so that the state can be queried as of an earlier ledger.
+	EnableStateVersions bool
+
+	// TransactionFilters restricts the transactions ingested into the history
+	// tables. The filters stored in the ingest_filters table with the admin
+	// endpoint replace the filters of the same name set here.
+	TransactionFilters processors.TransactionFilterRules
+
+	// EventSink, if set, receives the transactions, operations, effects and
+	// state changes of every ledger ingested live (not reingested). See the
+	// export package.
+	EventSink export.Sink
+
+	// HistoryPartitionSize is the number of ledgers in every partition of the
+	// history tables partitioned with `aurora db partition-history`, 0 if they
+	// are not partitioned. The partitions of the ingested ledgers are created
+	// when needed.
+	HistoryPartitionSize uint32
+
+	// LedgerFilesURL, if set, is the location of the ledgers exported with
+	// ledgerbackend.FileLedgerExporter. Ledgers are read from these files
+	// instead of diamnet-core.
+	LedgerFilesURL string
+
 	MaxReingestRetries          int
 	ReingestRetryBackoffSeconds int
 
@@ -192,6 +225,8 @@ type system struct {
 	disableStateVerification bool
 
 	checkpointManager historyarchive.CheckpointManager
+
+	webhooks *webhooks.Dispatcher
 }
 
 func NewSystem(config Config) (System, error) {
@@ -210,12 +245,72 @@ func NewSystem(config Config) (System, error) {
 		return nil, errors.Wrap(err, "error creating history archive")
 	}
 
+	ledgerBackend, err := newLedgerBackend(ctx, config)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	historyQ := &history.Q{config.HistorySession.Clone()}
+
+	historyAdapter := newHistoryArchiveAdapter(archive)
+
+	system := &system{
+		cancel:                      cancel,
+		config:                      config,
+		ctx:                         ctx,
+		disableStateVerification:    config.DisableStateVerification,
+		historyAdapter:              historyAdapter,
+		historyQ:                    historyQ,
+		ledgerBackend:               ledgerBackend,
+		maxReingestRetries:          config.MaxReingestRetries,
+		reingestRetryBackoffSeconds: config.ReingestRetryBackoffSeconds,
+		diamnetCoreClient: &diamnetcore.Client{
+			URL: config.DiamnetCoreURL,
+		},
+		runner: &ProcessorRunner{
+			ctx:            ctx,
+			config:         config,
+			historyQ:       historyQ,
+			historyAdapter: historyAdapter,
+		},
+		checkpointManager: historyarchive.NewCheckpointManager(config.CheckpointFrequency),
+	}
+
+	for _, plugin := range plugins.Registered() {
+		if plugin.DisableStateVerification && !system.disableStateVerification {
+			log.WithField("plugin", plugin.Name).
+				Warn("State verification is disabled by a plugin changing the state tables")
+			system.disableStateVerification = true
+		}
+	}
+
+	if config.EnableWebhooks {
+		system.webhooks = webhooks.NewDispatcher(
+			&history.Q{config.HistorySession.Clone()},
+			webhooks.Config{},
+		)
+	}
+
+	system.initMetrics()
+	return system, nil
+}
+
+// newLedgerBackend returns the backend ingestion reads ledgers from: the files
+// at LedgerFilesURL, Captive Core or the diamnet-core DB.
+func newLedgerBackend(ctx context.Context, config Config) (ledgerbackend.LedgerBackend, error) {
 	var ledgerBackend ledgerbackend.LedgerBackend
-	if config.EnableCaptiveCore {
+	var err error
+	if config.LedgerFilesURL != "" {
+		storage, err := historyarchive.ConnectBackend(config.LedgerFilesURL, historyarchive.ConnectOptions{Context: ctx})
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating ledger files backend")
+		}
+		ledgerBackend = ledgerbackend.NewFileLedgerBackend(storage)
+	} else if config.EnableCaptiveCore {
 		if len(config.RemoteCaptiveCoreURL) > 0 {
 			ledgerBackend, err = ledgerbackend.NewRemoteCaptive(config.RemoteCaptiveCoreURL)
 			if err != nil {
-				cancel()
 				return nil, errors.Wrap(err, "error creating captive core backend")
 			}
 		} else {
@@ -234,7 +329,6 @@ func NewSystem(config Config) (System, error) {
 				},
 			)
 			if err != nil {
-				cancel()
 				return nil, errors.Wrap(err, "error creating captive core backend")
 			}
 		}
@@ -242,39 +336,23 @@ func NewSystem(config Config) (System, error) {
 		coreSession := config.CoreSession.Clone()
 		ledgerBackend, err = ledgerbackend.NewDatabaseBackendFromSession(coreSession, config.NetworkPassphrase)
 		if err != nil {
-			cancel()
 			return nil, errors.Wrap(err, "error creating ledger backend")
 		}
 	}
+	return ledgerBackend, nil
+}
 
-	historyQ := &history.Q{config.HistorySession.Clone()}
-
-	historyAdapter := newHistoryArchiveAdapter(archive)
-
-	system := &system{
-		cancel:                      cancel,
-		config:                      config,
-		ctx:                         ctx,
-		disableStateVerification:    config.DisableStateVerification,
-		historyAdapter:              historyAdapter,
-		historyQ:                    historyQ,
-		ledgerBackend:               ledgerBackend,
-		maxReingestRetries:          config.MaxReingestRetries,
-		reingestRetryBackoffSeconds: config.ReingestRetryBackoffSeconds,
-		diamnetCoreClient: &diamnetcore.Client{
-			URL: config.DiamnetCoreURL,
-		},
-		runner: &ProcessorRunner{
-			ctx:            ctx,
-			config:         config,
-			historyQ:       historyQ,
-			historyAdapter: historyAdapter,
-		},
-		checkpointManager: historyarchive.NewCheckpointManager(config.CheckpointFrequency),
+// ExportLedgers writes the ledgers from `from` to `to` (inclusive), read from
+// Captive Core or the diamnet-core DB, with the given exporter.
+func ExportLedgers(config Config, exporter *ledgerbackend.FileLedgerExporter, from, to uint32) error {
+	ctx := context.Background()
+	config.LedgerFilesURL = ""
+	ledgerBackend, err := newLedgerBackend(ctx, config)
+	if err != nil {
+		return err
 	}
-
-	system.initMetrics()
-	return system, nil
+	defer ledgerBackend.Close()
+	return exporter.Export(ctx, ledgerBackend, from, to)
 }
 
 func (s *system) initMetrics() {
@@ -441,6 +519,9 @@ func (s *system) RegisterMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(s.metrics.CaptiveCoreSupportedProtocolVersion)
 	registry.MustRegister(s.metrics.LedgerFetchDurationSummary)
 	registry.MustRegister(s.metrics.StateVerifyLedgerEntriesCount)
+	if s.webhooks != nil {
+		s.webhooks.RegisterMetrics(registry)
+	}
 }
 
 // Run starts ingestion system. Ingestion system supports distributed ingestion
@@ -473,6 +554,13 @@ func (s *system) RegisterMetrics(registry *prometheus.Registry) {
 //   * If instances is a NOT leader, it runs ledger pipeline without updating a
 //     a database so order book graph is updated but database is not overwritten.
 func (s *system) Run() {
+	if s.webhooks != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.webhooks.Run(s.ctx)
+		}()
+	}
 	s.runStateMachine(startState{})
 }
 
@@ -696,6 +784,11 @@ func (s *system) Shutdown() {
 	if err := s.ledgerBackend.Close(); err != nil {
 		log.WithError(err).Info("could not close ledger backend")
 	}
+	if s.config.EventSink != nil {
+		if err := s.config.EventSink.Close(); err != nil {
+			log.WithError(err).Info("could not close event sink")
+		}
+	}
 }
 
 func markStateInvalid(ctx context.Context, historyQ history.IngestionQ, err error) {
@@ -241,6 +241,11 @@ type mockDBQ struct {
 	history.MockQOffers
 	history.MockQOperations
 	history.MockQSigners
+	history.MockQStateVersions
+	history.MockQIngestFilters
+	history.MockQPlugins
+	history.MockQHistoryPartitions
+	history.MockQAssetStatsHistory
 	history.MockQTransactions
 	history.MockQTrustLines
 }
@@ -355,6 +360,21 @@ func (m *mockDBQ) NewOperationParticipantBatchInsertBuilder(maxBatchSize int) hi
 	return args.Get(0).(history.TransactionParticipantsBatchInsertBuilder)
 }
 
+func (m *mockDBQ) CreateMuxedAccounts(ctx context.Context, addresses []string, batchSize int) (map[string]int64, error) {
+	args := m.Called(ctx, addresses, batchSize)
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *mockDBQ) NewTransactionMuxedParticipantsBatchInsertBuilder(maxBatchSize int) history.TransactionParticipantsBatchInsertBuilder {
+	args := m.Called(maxBatchSize)
+	return args.Get(0).(history.TransactionParticipantsBatchInsertBuilder)
+}
+
+func (m *mockDBQ) NewOperationMuxedParticipantsBatchInsertBuilder(maxBatchSize int) history.OperationParticipantBatchInsertBuilder {
+	args := m.Called(maxBatchSize)
+	return args.Get(0).(history.OperationParticipantBatchInsertBuilder)
+}
+
 func (m *mockDBQ) NewTradeBatchInsertBuilder(maxBatchSize int) history.TradeBatchInsertBuilder {
 	args := m.Called(maxBatchSize)
 	return args.Get(0).(history.TradeBatchInsertBuilder)
@@ -375,6 +395,15 @@ func (m *mockDBQ) CreateAssets(ctx context.Context, assets []xdr.Asset, batchSiz
 	return args.Get(0).(map[string]history.Asset), args.Error(1)
 }
 
+func (m *mockDBQ) UpsertLiquidityPoolStatsBuckets(
+	ctx context.Context,
+	ledger xdr.LedgerHeaderHistoryEntry,
+	deltas []history.LiquidityPoolStatsLedgerDelta,
+) error {
+	args := m.Called(ctx, ledger, deltas)
+	return args.Error(0)
+}
+
 type mockLedgerBackend struct {
 	mock.Mock
 }
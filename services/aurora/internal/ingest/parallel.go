@@ -1,8 +1,10 @@
 package ingest
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/support/errors"
@@ -23,10 +25,24 @@ func (e rangeError) Error() string {
 	return fmt.Sprintf("error when processing [%d, %d] range: %s", e.ledgerRange.StartSequence, e.ledgerRange.EndSequence, e.err)
 }
 
+func (e rangeError) Cause() error {
+	return e.err
+}
+
+// reingestJobsQ defines the queries used to track reingestion jobs.
+type reingestJobsQ interface {
+	history.QReingestJobs
+	history.QHistoryPartitions
+	Begin() error
+	Commit() error
+	Rollback() error
+}
+
 type ParallelSystems struct {
 	config        Config
 	workerCount   uint
 	systemFactory func(Config) (System, error)
+	historyQ      reingestJobsQ
 }
 
 func NewParallelSystems(config Config, workerCount uint) (*ParallelSystems, error) {
@@ -40,48 +56,136 @@ func newParallelSystems(config Config, workerCount uint, systemFactory func(Conf
 		return nil, errors.New("workerCount must be > 0")
 	}
 
-	return &ParallelSystems{
+	ps := &ParallelSystems{
 		config:        config,
 		workerCount:   workerCount,
 		systemFactory: systemFactory,
-	}, nil
+	}
+	if config.HistorySession != nil {
+		ps.historyQ = &history.Q{config.HistorySession.Clone()}
+	}
+	return ps, nil
+}
+
+// reingestTracker records the status of the batches of a reingestion job, a
+// nil tracker records nothing.
+type reingestTracker struct {
+	q     history.QReingestJobs
+	jobID int64
+}
+
+func (t *reingestTracker) update(ledgerRange history.LedgerRange, status string, batchErr error) error {
+	if t == nil {
+		return nil
+	}
+	err := t.q.UpdateReingestBatchStatus(context.Background(), t.jobID, ledgerRange.StartSequence, status, batchErr)
+	return errors.Wrapf(err, "could not update status of batch [%d, %d] of reingest job %d",
+		ledgerRange.StartSequence, ledgerRange.EndSequence, t.jobID)
+}
+
+// reingestProgress reports the throughput and the estimated time left of a
+// reingestion.
+type reingestProgress struct {
+	lock    sync.Mutex
+	started time.Time
+	total   uint32
+	done    uint32
+}
+
+func newReingestProgress(batches []history.LedgerRange) *reingestProgress {
+	return &reingestProgress{started: time.Now(), total: totalRangeSize(batches)}
 }
 
-func (ps *ParallelSystems) runReingestWorker(s System, stop <-chan struct{}, reingestJobQueue <-chan history.LedgerRange) rangeError {
+// batchDone records a reingested batch and returns the fields logged with it.
+func (p *reingestProgress) batchDone(ledgerRange history.LedgerRange) logpkg.F {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.done += ledgerRange.EndSequence - ledgerRange.StartSequence + 1
+
+	fields := logpkg.F{
+		"from":        ledgerRange.StartSequence,
+		"to":          ledgerRange.EndSequence,
+		"ledgersDone": p.done,
+		"ledgersLeft": p.total - p.done,
+	}
+	elapsed := time.Since(p.started)
+	if rate := float64(p.done) / elapsed.Seconds(); rate > 0 {
+		fields["ledgersPerSecond"] = fmt.Sprintf("%.2f", rate)
+		eta := time.Duration(float64(p.total-p.done) / rate * float64(time.Second))
+		fields["eta"] = eta.Round(time.Second).String()
+	}
+	return fields
+}
+
+func (ps *ParallelSystems) runReingestWorker(
+	s System,
+	stop <-chan struct{},
+	reingestJobQueue <-chan history.LedgerRange,
+	tracker *reingestTracker,
+	progress *reingestProgress,
+) rangeError {
 
 	for {
 		select {
 		case <-stop:
 			return rangeError{}
 		case reingestRange := <-reingestJobQueue:
+			if err := tracker.update(reingestRange, history.ReingestBatchRunning, nil); err != nil {
+				return rangeError{
+					err:         err,
+					ledgerRange: reingestRange,
+				}
+			}
 			err := s.ReingestRange([]history.LedgerRange{reingestRange}, false)
 			if err != nil {
+				if updateErr := tracker.update(reingestRange, history.ReingestBatchFailed, err); updateErr != nil {
+					log.WithError(updateErr).Error("error recording failed batch")
+				}
 				return rangeError{
 					err:         err,
 					ledgerRange: reingestRange,
 				}
 			}
-			log.WithFields(logpkg.F{"from": reingestRange.StartSequence, "to": reingestRange.EndSequence}).Info("successfully reingested range")
+			if err = tracker.update(reingestRange, history.ReingestBatchDone, nil); err != nil {
+				return rangeError{
+					err:         err,
+					ledgerRange: reingestRange,
+				}
+			}
+			log.WithFields(progress.batchDone(reingestRange)).Info("successfully reingested range")
 		}
 	}
 }
 
-func enqueueReingestTasks(ledgerRanges []history.LedgerRange, batchSize uint32, stop <-chan struct{}, reingestJobQueue chan<- history.LedgerRange) {
+// splitLedgerRanges splits the ledger ranges into batches of at most
+// batchSize ledgers.
+func splitLedgerRanges(ledgerRanges []history.LedgerRange, batchSize uint32) []history.LedgerRange {
+	var batches []history.LedgerRange
 	for _, cur := range ledgerRanges {
-		for subRangeFrom := cur.StartSequence; subRangeFrom < cur.EndSequence; {
-			// job queuing
+		for subRangeFrom := cur.StartSequence; subRangeFrom <= cur.EndSequence; {
 			subRangeTo := subRangeFrom + (batchSize - 1) // we subtract one because both from and to are part of the batch
 			if subRangeTo > cur.EndSequence {
 				subRangeTo = cur.EndSequence
 			}
-			select {
-			case <-stop:
-				return
-			case reingestJobQueue <- history.LedgerRange{StartSequence: subRangeFrom, EndSequence: subRangeTo}:
+			batches = append(batches, history.LedgerRange{StartSequence: subRangeFrom, EndSequence: subRangeTo})
+			if subRangeTo == cur.EndSequence {
+				break
 			}
 			subRangeFrom = subRangeTo + 1
 		}
 	}
+	return batches
+}
+
+func enqueueReingestTasks(batches []history.LedgerRange, stop <-chan struct{}, reingestJobQueue chan<- history.LedgerRange) {
+	for _, batch := range batches {
+		// job queuing
+		select {
+		case <-stop:
+			return
+		case reingestJobQueue <- batch:
+		}
+	}
 }
 
 func calculateParallelLedgerBatchSize(rangeSize uint32, batchSizeSuggestion uint32, workerCount uint) uint32 {
@@ -107,11 +211,162 @@ func totalRangeSize(ledgerRanges []history.LedgerRange) uint32 {
 	return sum
 }
 
+// ReingestRange reingests the ledger ranges in batches processed by the
+// workers. The batches are not recorded, see CreateReingestJob for resumable
+// reingestions.
 func (ps *ParallelSystems) ReingestRange(ledgerRanges []history.LedgerRange, batchSizeSuggestion uint32) error {
+	if err := validateRanges(ledgerRanges); err != nil {
+		return err
+	}
+	batchSize := calculateParallelLedgerBatchSize(totalRangeSize(ledgerRanges), batchSizeSuggestion, ps.workerCount)
+	if err := ps.createHistoryPartitions(ledgerRanges); err != nil {
+		return err
+	}
+	systems, err := ps.newSystems()
+	if err != nil {
+		return err
+	}
+
+	lowestRangeErr := ps.reingestBatches(systems, splitLedgerRanges(ledgerRanges, batchSize), nil)
+	if lowestRangeErr != nil {
+		lastLedger := ledgerRanges[len(ledgerRanges)-1].EndSequence
+		return errors.Wrapf(lowestRangeErr, "job failed, recommended restart range: [%d, %d]", lowestRangeErr.ledgerRange.StartSequence, lastLedger)
+	}
+	return nil
+}
+
+// CreateReingestJob records a reingestion job for the ledger ranges, split in
+// batches like in ReingestRange. The job is run with ResumeReingestJob.
+func (ps *ParallelSystems) CreateReingestJob(ledgerRanges []history.LedgerRange, batchSizeSuggestion uint32) (history.ReingestJob, error) {
+	if ps.historyQ == nil {
+		return history.ReingestJob{}, errors.New("reingest jobs require a history session")
+	}
+	if err := validateRanges(ledgerRanges); err != nil {
+		return history.ReingestJob{}, err
+	}
+	batchSize := calculateParallelLedgerBatchSize(totalRangeSize(ledgerRanges), batchSizeSuggestion, ps.workerCount)
+
+	if err := ps.historyQ.Begin(); err != nil {
+		return history.ReingestJob{}, errors.Wrap(err, "Error starting a transaction")
+	}
+	defer ps.historyQ.Rollback()
+
+	job, err := ps.historyQ.CreateReingestJob(context.Background(), splitLedgerRanges(ledgerRanges, batchSize))
+	if err != nil {
+		return job, err
+	}
+	if err = ps.historyQ.Commit(); err != nil {
+		return job, errors.Wrap(err, commitErrMsg)
+	}
+	return job, nil
+}
+
+// ResumeReingestJob reingests the batches of the job which are not done,
+// including the batches which failed or were left running by an interrupted
+// run, and recording the status of every batch. The job is marked as finished
+// once all its batches are done.
+func (ps *ParallelSystems) ResumeReingestJob(jobID int64) error {
+	if ps.historyQ == nil {
+		return errors.New("reingest jobs require a history session")
+	}
+	ctx := context.Background()
+	if _, err := ps.historyQ.GetReingestJob(ctx, jobID); err != nil {
+		return errors.Wrapf(err, "could not load reingest job %d", jobID)
+	}
+	jobBatches, err := ps.historyQ.GetReingestBatches(ctx, jobID)
+	if err != nil {
+		return errors.Wrapf(err, "could not load batches of reingest job %d", jobID)
+	}
+
+	var batches []history.LedgerRange
+	for _, batch := range jobBatches {
+		if batch.Status != history.ReingestBatchDone {
+			batches = append(batches, batch.LedgerRange())
+		}
+	}
+	log.WithFields(logpkg.F{
+		"job":         jobID,
+		"batches":     len(jobBatches),
+		"batchesLeft": len(batches),
+	}).Info("resuming reingest job")
+
+	if err = ps.createHistoryPartitions(batches); err != nil {
+		return err
+	}
+	systems, err := ps.newSystems()
+	if err != nil {
+		return err
+	}
+	if lowestRangeErr := ps.reingestBatches(systems, batches, &reingestTracker{q: ps.historyQ, jobID: jobID}); lowestRangeErr != nil {
+		return errors.Wrapf(lowestRangeErr, "reingest job %d failed", jobID)
+	}
+	return errors.Wrapf(ps.historyQ.FinishReingestJob(ctx, jobID), "could not finish reingest job %d", jobID)
+}
+
+// createHistoryPartitions creates the partitions of the history tables
+// containing the ledger ranges before the workers start. A worker would
+// otherwise create them in the transaction of its batch, where the
+// partitioned tables stay locked until the batch is committed, and the
+// workers could deadlock each other.
+func (ps *ParallelSystems) createHistoryPartitions(ledgerRanges []history.LedgerRange) error {
+	size := ps.config.HistoryPartitionSize
+	if size == 0 || ps.historyQ == nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	created := map[uint32]bool{}
+	for _, ledgerRange := range ledgerRanges {
+		for start := ledgerRange.StartSequence / size * size; start <= ledgerRange.EndSequence; start += size {
+			if created[start] {
+				continue
+			}
+			created[start] = true
+			sequence := start
+			if sequence < ledgerRange.StartSequence {
+				sequence = ledgerRange.StartSequence
+			}
+			// Every partition is created in its own transaction so the
+			// tables are only locked briefly
+			if err := ps.historyQ.Begin(); err != nil {
+				return errors.Wrap(err, "Error starting a transaction")
+			}
+			if err := ps.historyQ.CreateHistoryPartitions(ctx, size, sequence); err != nil {
+				ps.historyQ.Rollback()
+				return errors.Wrapf(err, "could not create history partitions of ledger %d", sequence)
+			}
+			if err := ps.historyQ.Commit(); err != nil {
+				return errors.Wrap(err, commitErrMsg)
+			}
+		}
+	}
+	return nil
+}
+
+// newSystems returns the systems used by the workers.
+func (ps *ParallelSystems) newSystems() ([]System, error) {
+	systems := make([]System, 0, ps.workerCount)
+	for i := uint(0); i < ps.workerCount; i++ {
+		s, err := ps.systemFactory(ps.config)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating new system")
+		}
+		systems = append(systems, s)
+	}
+	return systems, nil
+}
+
+// reingestBatches reingests the batches with a worker per system and returns
+// the error of the failed batch with the lowest starting ledger, if any.
+func (ps *ParallelSystems) reingestBatches(
+	systems []System,
+	batches []history.LedgerRange,
+	tracker *reingestTracker,
+) *rangeError {
 	var (
-		batchSize        = calculateParallelLedgerBatchSize(totalRangeSize(ledgerRanges), batchSizeSuggestion, ps.workerCount)
 		reingestJobQueue = make(chan history.LedgerRange)
 		wg               sync.WaitGroup
+		progress         = newReingestProgress(batches)
 
 		// stopOnce is used to close the stop channel once: closing a closed channel panics and it can happen in case
 		// of errors in multiple ranges.
@@ -128,19 +383,13 @@ func (ps *ParallelSystems) ReingestRange(ledgerRanges []history.LedgerRange, bat
 		// the user needs to start again to prevent the gaps.
 		lowestRangeErr *rangeError
 	)
-	if err := validateRanges(ledgerRanges); err != nil {
-		return err
-	}
 
-	for i := uint(0); i < ps.workerCount; i++ {
+	for _, s := range systems {
 		wg.Add(1)
-		s, err := ps.systemFactory(ps.config)
-		if err != nil {
-			return errors.Wrap(err, "error creating new system")
-		}
+		s := s
 		go func() {
 			defer wg.Done()
-			rangeErr := ps.runReingestWorker(s, stop, reingestJobQueue)
+			rangeErr := ps.runReingestWorker(s, stop, reingestJobQueue, tracker, progress)
 			if rangeErr.err != nil {
 				log.WithError(rangeErr).Error("error in reingest worker")
 				lowestRangeErrMutex.Lock()
@@ -156,7 +405,7 @@ func (ps *ParallelSystems) ReingestRange(ledgerRanges []history.LedgerRange, bat
 		}()
 	}
 
-	enqueueReingestTasks(ledgerRanges, batchSize, stop, reingestJobQueue)
+	enqueueReingestTasks(batches, stop, reingestJobQueue)
 
 	stopOnce.Do(func() {
 		close(stop)
@@ -164,9 +413,5 @@ func (ps *ParallelSystems) ReingestRange(ledgerRanges []history.LedgerRange, bat
 	wg.Wait()
 	close(reingestJobQueue)
 
-	if lowestRangeErr != nil {
-		lastLedger := ledgerRanges[len(ledgerRanges)-1].EndSequence
-		return errors.Wrapf(lowestRangeErr, "job failed, recommended restart range: [%d, %d]", lowestRangeErr.ledgerRange.StartSequence, lastLedger)
-	}
-	return nil
+	return lowestRangeErr
 }
@@ -1,6 +1,7 @@
 package ingest
 
 import (
+	"context"
 	"math/rand"
 	"sort"
 	"sync"
@@ -117,3 +118,160 @@ func TestParallelReingestRangeErrorInEarlierJob(t *testing.T) {
 	assert.Equal(t, "job failed, recommended restart range: [1025, 2050]: error when processing [1025, 1280] range: failed because of foo", err.Error())
 
 }
+
+func TestSplitLedgerRanges(t *testing.T) {
+	assert.Equal(t, []history.LedgerRange{
+		{StartSequence: 1, EndSequence: 64},
+		{StartSequence: 65, EndSequence: 65},
+		{StartSequence: 100, EndSequence: 100},
+		{StartSequence: 200, EndSequence: 263},
+		{StartSequence: 264, EndSequence: 300},
+	}, splitLedgerRanges([]history.LedgerRange{{1, 65}, {100, 100}, {200, 300}}, 64))
+}
+
+func TestSplitLedgerRangesLastLedger(t *testing.T) {
+	// the last ledger of a range is part of the last batch, even when it's
+	// the first ledger of the batch
+	for _, testCase := range []struct {
+		ledgerRange history.LedgerRange
+		expected    []history.LedgerRange
+	}{
+		{
+			ledgerRange: history.LedgerRange{StartSequence: 100, EndSequence: 100},
+			expected:    []history.LedgerRange{{StartSequence: 100, EndSequence: 100}},
+		},
+		{
+			ledgerRange: history.LedgerRange{StartSequence: 1, EndSequence: 2},
+			expected:    []history.LedgerRange{{StartSequence: 1, EndSequence: 2}},
+		},
+		{
+			ledgerRange: history.LedgerRange{StartSequence: 1, EndSequence: 3},
+			expected:    []history.LedgerRange{{StartSequence: 1, EndSequence: 2}, {StartSequence: 3, EndSequence: 3}},
+		},
+		{
+			ledgerRange: history.LedgerRange{StartSequence: 1, EndSequence: 4},
+			expected:    []history.LedgerRange{{StartSequence: 1, EndSequence: 2}, {StartSequence: 3, EndSequence: 4}},
+		},
+	} {
+		assert.Equal(t, testCase.expected, splitLedgerRanges([]history.LedgerRange{testCase.ledgerRange}, 2))
+	}
+}
+
+type mockReingestJobsQ struct {
+	history.MockQReingestJobs
+}
+
+func (m *mockReingestJobsQ) Begin() error {
+	return m.Called().Error(0)
+}
+
+func (m *mockReingestJobsQ) Commit() error {
+	return m.Called().Error(0)
+}
+
+func (m *mockReingestJobsQ) Rollback() error {
+	return m.Called().Error(0)
+}
+
+func (m *mockReingestJobsQ) CreateHistoryPartitions(ctx context.Context, size, sequence uint32) error {
+	return m.Called(ctx, size, sequence).Error(0)
+}
+
+func TestParallelReingestRangeCreatesHistoryPartitions(t *testing.T) {
+	q := &mockReingestJobsQ{}
+	result := &mockSystem{}
+	defer mock.AssertExpectationsForObjects(t, q, result)
+	system, err := newParallelSystems(Config{HistoryPartitionSize: 100}, 2, func(c Config) (System, error) {
+		return result, nil
+	})
+	assert.NoError(t, err)
+	system.historyQ = q
+
+	// The partitions are created before the workers start
+	workersStarted := false
+	for _, sequence := range []uint32{150, 200, 300} {
+		q.On("Begin").Return(nil).Once()
+		q.On("CreateHistoryPartitions", mock.Anything, uint32(100), sequence).Run(func(mock.Arguments) {
+			assert.False(t, workersStarted)
+		}).Return(nil).Once()
+		q.On("Commit").Return(nil).Once()
+	}
+	result.On("ReingestRange", mock.AnythingOfType("[]history.LedgerRange"), false).Run(func(mock.Arguments) {
+		workersStarted = true
+	}).Return(nil)
+
+	assert.NoError(t, system.ReingestRange([]history.LedgerRange{{150, 320}}, 64))
+
+	q.On("Begin").Return(nil).Once()
+	q.On("CreateHistoryPartitions", mock.Anything, uint32(100), uint32(400)).Return(errors.New("lock timeout")).Once()
+	q.On("Rollback").Return(nil).Once()
+	err = system.ReingestRange([]history.LedgerRange{{400, 500}}, 64)
+	assert.EqualError(t, err, "could not create history partitions of ledger 400: lock timeout")
+}
+
+func TestParallelCreateReingestJob(t *testing.T) {
+	q := &mockReingestJobsQ{}
+	defer mock.AssertExpectationsForObjects(t, q)
+	system, err := newParallelSystems(Config{}, 2, func(c Config) (System, error) {
+		return &mockSystem{}, nil
+	})
+	assert.NoError(t, err)
+	system.historyQ = q
+
+	q.On("Begin").Return(nil).Once()
+	q.On("CreateReingestJob", mock.Anything, []history.LedgerRange{{1, 64}, {65, 128}, {129, 130}}).
+		Return(history.ReingestJob{ID: 3}, nil).Once()
+	q.On("Commit").Return(nil).Once()
+	q.On("Rollback").Return(nil).Once()
+
+	job, err := system.CreateReingestJob([]history.LedgerRange{{1, 130}}, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), job.ID)
+}
+
+func TestParallelResumeReingestJob(t *testing.T) {
+	q := &mockReingestJobsQ{}
+	defer mock.AssertExpectationsForObjects(t, q)
+	result := &mockSystem{}
+	defer mock.AssertExpectationsForObjects(t, result)
+	system, err := newParallelSystems(Config{}, 2, func(c Config) (System, error) {
+		return result, nil
+	})
+	assert.NoError(t, err)
+	system.historyQ = q
+
+	q.On("GetReingestJob", mock.Anything, int64(3)).
+		Return(history.ReingestJob{ID: 3}, nil).Once()
+	q.On("GetReingestBatches", mock.Anything, int64(3)).
+		Return([]history.ReingestBatch{
+			{JobID: 3, StartSequence: 1, EndSequence: 64, Status: history.ReingestBatchDone},
+			{JobID: 3, StartSequence: 65, EndSequence: 128, Status: history.ReingestBatchRunning},
+			{JobID: 3, StartSequence: 129, EndSequence: 192, Status: history.ReingestBatchFailed},
+		}, nil).Once()
+
+	result.On("ReingestRange", []history.LedgerRange{{65, 128}}, false).Return(nil).Once()
+	q.On("UpdateReingestBatchStatus", mock.Anything, int64(3), uint32(65), history.ReingestBatchRunning, nil).
+		Return(nil).Once()
+	q.On("UpdateReingestBatchStatus", mock.Anything, int64(3), uint32(65), history.ReingestBatchDone, nil).
+		Return(nil).Once()
+
+	batchErr := errors.New("failed because of foo")
+	result.On("ReingestRange", []history.LedgerRange{{129, 192}}, false).Return(batchErr).Once()
+	q.On("UpdateReingestBatchStatus", mock.Anything, int64(3), uint32(129), history.ReingestBatchRunning, nil).
+		Return(nil).Once()
+	q.On("UpdateReingestBatchStatus", mock.Anything, int64(3), uint32(129), history.ReingestBatchFailed, batchErr).
+		Return(nil).Once()
+
+	err = system.ResumeReingestJob(3)
+	assert.EqualError(t, err, "reingest job 3 failed: error when processing [129, 192] range: failed because of foo")
+
+	// All the batches are done now
+	q.On("GetReingestJob", mock.Anything, int64(3)).
+		Return(history.ReingestJob{ID: 3}, nil).Once()
+	q.On("GetReingestBatches", mock.Anything, int64(3)).
+		Return([]history.ReingestBatch{
+			{JobID: 3, StartSequence: 1, EndSequence: 64, Status: history.ReingestBatchDone},
+		}, nil).Once()
+	q.On("FinishReingestJob", mock.Anything, int64(3)).Return(nil).Once()
+	assert.NoError(t, system.ResumeReingestJob(3))
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/diamnet/go/ingest"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
+	"github.com/diamnet/go/services/aurora/plugins"
 	"github.com/diamnet/go/support/errors"
 	"github.com/diamnet/go/xdr"
 )
@@ -99,35 +100,98 @@ func buildChangeProcessor(
 	changeStats *ingest.StatsChangeProcessor,
 	source ingestionSource,
 	ledgerSequence uint32,
+	versions processors.StateVersions,
 ) *groupChangeProcessors {
 	statsChangeProcessor := &statsChangeProcessor{
 		StatsChangeProcessor: changeStats,
 	}
 
 	useLedgerCache := source == ledgerSource
-	return newGroupChangeProcessors([]auroraChangeProcessor{
+	changeProcessors := []auroraChangeProcessor{
 		statsChangeProcessor,
-		processors.NewAccountDataProcessor(historyQ),
-		processors.NewAccountsProcessor(historyQ),
-		processors.NewOffersProcessor(historyQ, ledgerSequence),
+		processors.NewAccountDataProcessor(historyQ, versions),
+		processors.NewAccountsProcessor(historyQ, versions),
+		processors.NewOffersProcessor(historyQ, ledgerSequence, versions),
 		processors.NewAssetStatsProcessor(historyQ, useLedgerCache),
-		processors.NewSignersProcessor(historyQ, useLedgerCache),
-		processors.NewTrustLinesProcessor(historyQ),
-		processors.NewClaimableBalancesChangeProcessor(historyQ),
+		processors.NewSignersProcessor(historyQ, useLedgerCache, versions),
+		processors.NewTrustLinesProcessor(historyQ, versions),
+		processors.NewClaimableBalancesChangeProcessor(historyQ, versions),
 		processors.NewLiquidityPoolsChangeProcessor(historyQ, ledgerSequence),
-	})
+	}
+	for _, plugin := range plugins.Registered() {
+		if plugin.NewChangeProcessor != nil {
+			changeProcessors = append(
+				changeProcessors,
+				plugin.NewChangeProcessor(historyQ.PluginSession(), ledgerSequence),
+			)
+		}
+	}
+	return newGroupChangeProcessors(changeProcessors)
+}
+
+// stateVersions returns the configuration of the change processors to keep the
+// versions of the state tables rows replaced in the ledger with the given
+// sequence. The versions kept so far are discarded if the previous ledger was
+// not ingested with versioned state, as they would not cover the ledgers in
+// between.
+func (s *ProcessorRunner) stateVersions(sequence uint32) (processors.StateVersions, error) {
+	if !s.config.EnableStateVersions {
+		return processors.StateVersions{}, nil
+	}
+
+	start, last, err := s.historyQ.GetStateVersionsLedgerRange(s.ctx)
+	if err != nil {
+		return processors.StateVersions{}, errors.Wrap(err, "Error getting state versions ledger range")
+	}
+
+	if start == 0 || last+1 != sequence {
+		if err = s.historyQ.TruncateStateVersions(s.ctx); err != nil {
+			return processors.StateVersions{}, errors.Wrap(err, "Error truncating state versions")
+		}
+		start = sequence
+	}
+
+	if err = s.historyQ.UpdateStateVersionsLedgerRange(s.ctx, start, sequence); err != nil {
+		return processors.StateVersions{}, errors.Wrap(err, "Error updating state versions ledger range")
+	}
+
+	return processors.StateVersions{Q: s.historyQ, Sequence: sequence}, nil
+}
+
+// transactionFilter returns the filter of the transactions ingested into the
+// history tables, which combines the filters of the config with the ones
+// stored in the DB. It is nil if no filter restricts anything.
+func (s *ProcessorRunner) transactionFilter() (*processors.TransactionFilter, error) {
+	rules := s.config.TransactionFilters
+	stored, err := s.historyQ.GetIngestFilters(s.ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting ingest filters")
+	}
+	for _, filter := range stored {
+		if err = rules.Set(filter.Name, filter.Allowed); err != nil {
+			return nil, err
+		}
+	}
+
+	filter, err := processors.NewTransactionFilter(rules)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid ingest filters")
+	}
+	return filter, nil
 }
 
 func (s *ProcessorRunner) buildTransactionProcessor(
 	ledgerTransactionStats *processors.StatsLedgerTransactionProcessor,
 	ledger xdr.LedgerHeaderHistoryEntry,
+	filter *processors.TransactionFilter,
+	withState bool,
 ) *groupTransactionProcessors {
 	statsLedgerTransactionProcessor := &statsLedgerTransactionProcessor{
 		StatsLedgerTransactionProcessor: ledgerTransactionStats,
 	}
 
 	sequence := uint32(ledger.Header.LedgerSeq)
-	return newGroupTransactionProcessors([]auroraTransactionProcessor{
+	transactionProcessors := []auroraTransactionProcessor{
 		statsLedgerTransactionProcessor,
 		processors.NewEffectProcessor(s.historyQ, sequence),
 		processors.NewLedgerProcessor(s.historyQ, ledger, CurrentVersion),
@@ -137,7 +201,25 @@ func (s *ProcessorRunner) buildTransactionProcessor(
 		processors.NewTransactionProcessor(s.historyQ, sequence),
 		processors.NewClaimableBalancesTransactionProcessor(s.historyQ, sequence),
 		processors.NewLiquidityPoolsTransactionProcessor(s.historyQ, sequence),
-	})
+		processors.NewAssetStatsHistoryProcessor(s.historyQ, ledger, withState),
+		processors.NewLiquidityPoolStatsProcessor(s.historyQ, ledger),
+	}
+	for _, plugin := range plugins.Registered() {
+		if plugin.NewTransactionProcessor != nil {
+			transactionProcessors = append(
+				transactionProcessors,
+				plugin.NewTransactionProcessor(s.historyQ.PluginSession(), ledger),
+			)
+		}
+	}
+
+	group := newGroupTransactionProcessors(transactionProcessors)
+	if filter != nil {
+		group.filter = func(tx ingest.LedgerTransaction) (bool, error) {
+			return filter.Match(sequence, tx)
+		}
+	}
+	return group
 }
 
 // checkIfProtocolVersionSupported checks if this Aurora version supports the
@@ -189,7 +271,13 @@ func (s *ProcessorRunner) RunHistoryArchiveIngestion(
 	bucketListHash xdr.Hash,
 ) (ingest.StatsChangeProcessorResults, error) {
 	changeStats := ingest.StatsChangeProcessor{}
-	changeProcessor := buildChangeProcessor(s.historyQ, &changeStats, historyArchiveSource, checkpointLedger)
+	changeProcessor := buildChangeProcessor(
+		s.historyQ,
+		&changeStats,
+		historyArchiveSource,
+		checkpointLedger,
+		processors.StateVersions{},
+	)
 
 	if checkpointLedger == 1 {
 		if err := changeProcessor.ProcessChange(s.ctx, ingest.GenesisChange(s.config.NetworkPassphrase)); err != nil {
@@ -265,6 +353,21 @@ func (s *ProcessorRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerClos
 	transactionStats processors.StatsLedgerTransactionProcessorResults,
 	transactionDurations processorsRunDurations,
 	err error,
+) {
+	return s.runTransactionProcessorsOnLedger(ledger, nil, false)
+}
+
+// runTransactionProcessorsOnLedger runs the transaction processors, and the
+// exporter if not nil, on the ledger. withState is true if the change
+// processors were run on the ledger before.
+func (s *ProcessorRunner) runTransactionProcessorsOnLedger(
+	ledger xdr.LedgerCloseMeta,
+	exporter *processors.EventExportProcessor,
+	withState bool,
+) (
+	transactionStats processors.StatsLedgerTransactionProcessorResults,
+	transactionDurations processorsRunDurations,
+	err error,
 ) {
 	var (
 		ledgerTransactionStats processors.StatsLedgerTransactionProcessor
@@ -282,7 +385,26 @@ func (s *ProcessorRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerClos
 		return
 	}
 
-	groupTransactionProcessors := s.buildTransactionProcessor(&ledgerTransactionStats, transactionReader.GetHeader())
+	filter, err := s.transactionFilter()
+	if err != nil {
+		return
+	}
+
+	// ParallelSystems creates the partitions of the reingested ranges before
+	// its workers start, so this only creates the partitions of the ledgers
+	// ingested live or reingested by a single system.
+	if s.config.HistoryPartitionSize > 0 {
+		err = s.historyQ.CreateHistoryPartitions(s.ctx, s.config.HistoryPartitionSize, ledger.LedgerSequence())
+		if err != nil {
+			err = errors.Wrap(err, "Error creating history partitions")
+			return
+		}
+	}
+
+	groupTransactionProcessors := s.buildTransactionProcessor(&ledgerTransactionStats, transactionReader.GetHeader(), filter, withState)
+	if exporter != nil {
+		groupTransactionProcessors.processors = append(groupTransactionProcessors.processors, exporter)
+	}
 	err = processors.StreamLedgerTransactions(s.ctx, groupTransactionProcessors, transactionReader)
 	if err != nil {
 		err = errors.Wrap(err, "Error streaming changes from ledger")
@@ -295,6 +417,17 @@ func (s *ProcessorRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerClos
 		return
 	}
 
+	for _, plugin := range plugins.Registered() {
+		if plugin.NewTransactionProcessor == nil {
+			continue
+		}
+		err = s.historyQ.InsertPluginLedger(s.ctx, plugin.Name, transactionReader.GetSequence())
+		if err != nil {
+			err = errors.Wrapf(err, "Error recording ledger processed by plugin %s", plugin.Name)
+			return
+		}
+	}
+
 	transactionStats = ledgerTransactionStats.GetResults()
 	transactionDurations = groupTransactionProcessors.processorsRunDurations
 	return
@@ -314,7 +447,23 @@ func (s *ProcessorRunner) RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 		return
 	}
 
-	groupChangeProcessors := buildChangeProcessor(s.historyQ, &changeStatsProcessor, ledgerSource, ledger.LedgerSequence())
+	versions, err := s.stateVersions(ledger.LedgerSequence())
+	if err != nil {
+		return
+	}
+
+	groupChangeProcessors := buildChangeProcessor(
+		s.historyQ,
+		&changeStatsProcessor,
+		ledgerSource,
+		ledger.LedgerSequence(),
+		versions,
+	)
+	var exporter *processors.EventExportProcessor
+	if s.config.EventSink != nil {
+		exporter = processors.NewEventExportProcessor(ledger.MustV0().LedgerHeader)
+		groupChangeProcessors.processors = append(groupChangeProcessors.processors, exporter)
+	}
 	err = s.runChangeProcessorOnLedger(groupChangeProcessors, ledger)
 	if err != nil {
 		return
@@ -324,10 +473,19 @@ func (s *ProcessorRunner) RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 	changeDurations = groupChangeProcessors.processorsRunDurations
 
 	transactionStats, transactionDurations, err =
-		s.RunTransactionProcessorsOnLedger(ledger)
+		s.runTransactionProcessorsOnLedger(ledger, exporter, true)
 	if err != nil {
 		return
 	}
 
+	if exporter != nil {
+		// The events are published before the DB transaction is committed so
+		// a ledger whose events could not be published is ingested again.
+		if err = s.config.EventSink.Publish(s.ctx, exporter.Events()); err != nil {
+			err = errors.Wrap(err, "Error publishing ledger events")
+			return
+		}
+	}
+
 	return
 }
@@ -13,7 +13,11 @@ import (
 	"github.com/diamnet/go/ingest"
 	"github.com/diamnet/go/network"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ingest/export"
 	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
+	"github.com/diamnet/go/services/aurora/plugins"
+	"github.com/diamnet/go/support/db"
+	"github.com/diamnet/go/support/errors"
 	"github.com/diamnet/go/xdr"
 )
 
@@ -173,7 +177,7 @@ func TestProcessorRunnerBuildChangeProcessor(t *testing.T) {
 	}
 
 	stats := &ingest.StatsChangeProcessor{}
-	processor := buildChangeProcessor(runner.historyQ, stats, ledgerSource, 123)
+	processor := buildChangeProcessor(runner.historyQ, stats, ledgerSource, 123, processors.StateVersions{})
 	assert.IsType(t, &groupChangeProcessors{}, processor)
 
 	assert.IsType(t, &statsChangeProcessor{}, processor.processors[0])
@@ -193,7 +197,7 @@ func TestProcessorRunnerBuildChangeProcessor(t *testing.T) {
 		historyQ: q,
 	}
 
-	processor = buildChangeProcessor(runner.historyQ, stats, historyArchiveSource, 456)
+	processor = buildChangeProcessor(runner.historyQ, stats, historyArchiveSource, 456, processors.StateVersions{})
 	assert.IsType(t, &groupChangeProcessors{}, processor)
 
 	assert.IsType(t, &statsChangeProcessor{}, processor.processors[0])
@@ -229,7 +233,7 @@ func TestProcessorRunnerBuildTransactionProcessor(t *testing.T) {
 
 	stats := &processors.StatsLedgerTransactionProcessor{}
 	ledger := xdr.LedgerHeaderHistoryEntry{}
-	processor := runner.buildTransactionProcessor(stats, ledger)
+	processor := runner.buildTransactionProcessor(stats, ledger, nil, true)
 	assert.IsType(t, &groupTransactionProcessors{}, processor)
 
 	assert.IsType(t, &statsLedgerTransactionProcessor{}, processor.processors[0])
@@ -282,6 +286,8 @@ func TestProcessorRunnerRunAllProcessorsOnLedger(t *testing.T) {
 
 	q.MockQLedgers.On("InsertLedger", ctx, ledger.V0.LedgerHeader, 0, 0, 0, 0, CurrentVersion).
 		Return(int64(1), nil).Once()
+	q.MockQIngestFilters.On("GetIngestFilters", ctx).
+		Return([]history.IngestFilter{}, nil).Once()
 
 	runner := ProcessorRunner{
 		ctx:      ctx,
@@ -293,6 +299,194 @@ func TestProcessorRunnerRunAllProcessorsOnLedger(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+type mockEventSink struct {
+	mock.Mock
+}
+
+func (m *mockEventSink) Publish(ctx context.Context, events []export.Event) error {
+	a := m.Called(ctx, events)
+	return a.Error(0)
+}
+
+func (m *mockEventSink) Close() error {
+	a := m.Called()
+	return a.Error(0)
+}
+
+func TestProcessorRunnerRunAllProcessorsOnLedgerWithEventSink(t *testing.T) {
+	ctx := context.Background()
+	maxBatchSize := 100000
+
+	sink := &mockEventSink{}
+	defer mock.AssertExpectationsForObjects(t, sink)
+	config := Config{
+		NetworkPassphrase: network.PublicNetworkPassphrase,
+		EventSink:         sink,
+	}
+
+	q := &mockDBQ{}
+	defer mock.AssertExpectationsForObjects(t, q)
+
+	ledger := xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					BucketListHash: xdr.Hash([32]byte{0, 1, 2}),
+				},
+			},
+		},
+	}
+
+	// Batches
+	mockAccountSignersBatchInsertBuilder := &history.MockAccountSignersBatchInsertBuilder{}
+	defer mock.AssertExpectationsForObjects(t, mockAccountSignersBatchInsertBuilder)
+	q.MockQSigners.On("NewAccountSignersBatchInsertBuilder", maxBatchSize).
+		Return(mockAccountSignersBatchInsertBuilder).Once()
+
+	mockOperationsBatchInsertBuilder := &history.MockOperationsBatchInsertBuilder{}
+	defer mock.AssertExpectationsForObjects(t, mockOperationsBatchInsertBuilder)
+	mockOperationsBatchInsertBuilder.On("Exec", ctx).Return(nil).Once()
+	q.MockQOperations.On("NewOperationBatchInsertBuilder", maxBatchSize).
+		Return(mockOperationsBatchInsertBuilder).Twice()
+
+	mockTransactionsBatchInsertBuilder := &history.MockTransactionsBatchInsertBuilder{}
+	defer mock.AssertExpectationsForObjects(t, mockTransactionsBatchInsertBuilder)
+	mockTransactionsBatchInsertBuilder.On("Exec", ctx).Return(nil).Once()
+	q.MockQTransactions.On("NewTransactionBatchInsertBuilder", maxBatchSize).
+		Return(mockTransactionsBatchInsertBuilder).Twice()
+
+	q.MockQLedgers.On("InsertLedger", ctx, ledger.V0.LedgerHeader, 0, 0, 0, 0, CurrentVersion).
+		Return(int64(1), nil).Once()
+	q.MockQIngestFilters.On("GetIngestFilters", ctx).
+		Return([]history.IngestFilter{}, nil).Once()
+
+	runner := ProcessorRunner{
+		ctx:      ctx,
+		config:   config,
+		historyQ: q,
+	}
+
+	sink.On("Publish", ctx, mock.AnythingOfType("[]export.Event")).
+		Run(func(args mock.Arguments) {
+			events := args.Get(1).([]export.Event)
+			if assert.Len(t, events, 1) {
+				assert.Equal(t, export.LedgerEvent, events[0].Type)
+			}
+		}).
+		Return(nil).Once()
+
+	_, _, _, _, err := runner.RunAllProcessorsOnLedger(ledger)
+	assert.NoError(t, err)
+}
+
+type testPluginProcessor struct {
+	session   db.SessionInterface
+	committed bool
+}
+
+func (p *testPluginProcessor) ProcessTransaction(ctx context.Context, transaction ingest.LedgerTransaction) error {
+	return nil
+}
+
+func (p *testPluginProcessor) Commit(ctx context.Context) error {
+	p.committed = true
+	return nil
+}
+
+func TestProcessorRunnerRunTransactionProcessorsOnLedgerWithPlugin(t *testing.T) {
+	ctx := context.Background()
+	maxBatchSize := 100000
+
+	session := &db.MockSession{}
+	pluginProcessor := &testPluginProcessor{}
+	plugins.Register(plugins.Processor{
+		Name: "test_runner",
+		NewTransactionProcessor: func(s db.SessionInterface, ledger xdr.LedgerHeaderHistoryEntry) plugins.TransactionProcessor {
+			pluginProcessor.session = s
+			return pluginProcessor
+		},
+	})
+	defer plugins.Unregister("test_runner")
+
+	q := &mockDBQ{}
+	defer mock.AssertExpectationsForObjects(t, q)
+
+	ledger := xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq: 7,
+				},
+			},
+		},
+	}
+
+	mockOperationsBatchInsertBuilder := &history.MockOperationsBatchInsertBuilder{}
+	mockOperationsBatchInsertBuilder.On("Exec", ctx).Return(nil).Once()
+	q.MockQOperations.On("NewOperationBatchInsertBuilder", maxBatchSize).
+		Return(mockOperationsBatchInsertBuilder).Twice()
+
+	mockTransactionsBatchInsertBuilder := &history.MockTransactionsBatchInsertBuilder{}
+	mockTransactionsBatchInsertBuilder.On("Exec", ctx).Return(nil).Once()
+	q.MockQTransactions.On("NewTransactionBatchInsertBuilder", maxBatchSize).
+		Return(mockTransactionsBatchInsertBuilder).Twice()
+
+	q.MockQLedgers.On("InsertLedger", ctx, ledger.V0.LedgerHeader, 0, 0, 0, 0, CurrentVersion).
+		Return(int64(1), nil).Once()
+	q.MockQIngestFilters.On("GetIngestFilters", ctx).
+		Return([]history.IngestFilter{}, nil).Once()
+	q.MockQPlugins.On("PluginSession").Return(session).Once()
+	q.MockQPlugins.On("InsertPluginLedger", ctx, "test_runner", uint32(7)).
+		Return(nil).Once()
+
+	runner := ProcessorRunner{
+		ctx: ctx,
+		config: Config{
+			NetworkPassphrase: network.PublicNetworkPassphrase,
+		},
+		historyQ: q,
+	}
+
+	_, _, err := runner.RunTransactionProcessorsOnLedger(ledger)
+	assert.NoError(t, err)
+	assert.Equal(t, session, pluginProcessor.session)
+	assert.True(t, pluginProcessor.committed)
+}
+
+func TestProcessorRunnerRunTransactionProcessorsOnLedgerHistoryPartitionsError(t *testing.T) {
+	ctx := context.Background()
+
+	q := &mockDBQ{}
+	defer mock.AssertExpectationsForObjects(t, q)
+
+	ledger := xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq: 1234,
+				},
+			},
+		},
+	}
+
+	q.MockQIngestFilters.On("GetIngestFilters", ctx).
+		Return([]history.IngestFilter{}, nil).Once()
+	q.MockQHistoryPartitions.On("CreateHistoryPartitions", ctx, uint32(1000), uint32(1234)).
+		Return(errors.New("transient error")).Once()
+
+	runner := ProcessorRunner{
+		ctx: ctx,
+		config: Config{
+			NetworkPassphrase:    network.PublicNetworkPassphrase,
+			HistoryPartitionSize: 1000,
+		},
+		historyQ: q,
+	}
+
+	_, _, err := runner.RunTransactionProcessorsOnLedger(ledger)
+	assert.EqualError(t, err, "Error creating history partitions: transient error")
+}
+
 func TestProcessorRunnerRunAllProcessorsOnLedgerProtocolVersionNotSupported(t *testing.T) {
 	ctx := context.Background()
 	maxBatchSize := 100000
@@ -340,3 +534,39 @@ func TestProcessorRunnerRunAllProcessorsOnLedgerProtocolVersionNotSupported(t *t
 	_, _, _, _, err := runner.RunAllProcessorsOnLedger(ledger)
 	assert.EqualError(t, err, "Error while checking for supported protocol version: This Aurora version does not support protocol version 200. The latest supported protocol version is 18. Please upgrade to the latest Aurora version.")
 }
+
+func TestProcessorRunnerStateVersions(t *testing.T) {
+	ctx := context.Background()
+	q := &mockDBQ{}
+	defer mock.AssertExpectationsForObjects(t, q)
+
+	runner := ProcessorRunner{
+		ctx:      ctx,
+		historyQ: q,
+	}
+
+	versions, err := runner.stateVersions(100)
+	assert.NoError(t, err)
+	assert.Equal(t, processors.StateVersions{}, versions)
+
+	runner.config.EnableStateVersions = true
+
+	// Consecutive ledgers extend the range
+	q.MockQStateVersions.On("GetStateVersionsLedgerRange", ctx).
+		Return(uint32(90), uint32(99), nil).Once()
+	q.MockQStateVersions.On("UpdateStateVersionsLedgerRange", ctx, uint32(90), uint32(100)).
+		Return(nil).Once()
+	versions, err = runner.stateVersions(100)
+	assert.NoError(t, err)
+	assert.Equal(t, processors.StateVersions{Q: q, Sequence: 100}, versions)
+
+	// A gap discards the versions kept so far
+	q.MockQStateVersions.On("GetStateVersionsLedgerRange", ctx).
+		Return(uint32(90), uint32(100), nil).Once()
+	q.MockQStateVersions.On("TruncateStateVersions", ctx).Return(nil).Once()
+	q.MockQStateVersions.On("UpdateStateVersionsLedgerRange", ctx, uint32(110), uint32(110)).
+		Return(nil).Once()
+	versions, err = runner.stateVersions(110)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(110), versions.Sequence)
+}
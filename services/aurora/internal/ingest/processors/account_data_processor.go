@@ -10,13 +10,14 @@ import (
 )
 
 type AccountDataProcessor struct {
-	dataQ history.QData
+	dataQ    history.QData
+	versions StateVersions
 
 	cache *ingest.ChangeCompactor
 }
 
-func NewAccountDataProcessor(dataQ history.QData) *AccountDataProcessor {
-	p := &AccountDataProcessor{dataQ: dataQ}
+func NewAccountDataProcessor(dataQ history.QData, versions StateVersions) *AccountDataProcessor {
+	p := &AccountDataProcessor{dataQ: dataQ, versions: versions}
 	p.reset()
 	return p
 }
@@ -72,6 +73,16 @@ func (p *AccountDataProcessor) Commit(ctx context.Context) error {
 		}
 	}
 
+	if p.versions.enabled() && len(datasToUpsert)+len(datasToDelete) > 0 {
+		keys := append([]history.AccountDataKey{}, datasToDelete...)
+		for _, data := range datasToUpsert {
+			keys = append(keys, history.AccountDataKey{AccountID: data.AccountID, DataName: data.Name})
+		}
+		if err := p.versions.Q.ArchiveAccountData(ctx, keys, p.versions.Sequence); err != nil {
+			return errors.Wrap(err, "error archiving account data")
+		}
+	}
+
 	if len(datasToUpsert) > 0 {
 		if err := p.dataQ.UpsertAccountData(ctx, datasToUpsert); err != nil {
 			return errors.Wrap(err, "error executing upsert")
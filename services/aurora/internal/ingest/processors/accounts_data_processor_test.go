@@ -27,7 +27,7 @@ func (s *AccountsDataProcessorTestSuiteState) SetupTest() {
 	s.ctx = context.Background()
 	s.mockQ = &history.MockQData{}
 
-	s.processor = NewAccountDataProcessor(s.mockQ)
+	s.processor = NewAccountDataProcessor(s.mockQ, StateVersions{})
 }
 
 func (s *AccountsDataProcessorTestSuiteState) TearDownTest() {
@@ -85,7 +85,7 @@ func (s *AccountsDataProcessorTestSuiteLedger) SetupTest() {
 	s.ctx = context.Background()
 	s.mockQ = &history.MockQData{}
 
-	s.processor = NewAccountDataProcessor(s.mockQ)
+	s.processor = NewAccountDataProcessor(s.mockQ, StateVersions{})
 }
 
 func (s *AccountsDataProcessorTestSuiteLedger) TearDownTest() {
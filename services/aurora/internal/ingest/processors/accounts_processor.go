@@ -11,12 +11,13 @@ import (
 
 type AccountsProcessor struct {
 	accountsQ history.QAccounts
+	versions  StateVersions
 
 	cache *ingest.ChangeCompactor
 }
 
-func NewAccountsProcessor(accountsQ history.QAccounts) *AccountsProcessor {
-	p := &AccountsProcessor{accountsQ: accountsQ}
+func NewAccountsProcessor(accountsQ history.QAccounts, versions StateVersions) *AccountsProcessor {
+	p := &AccountsProcessor{accountsQ: accountsQ, versions: versions}
 	p.reset()
 	return p
 }
@@ -76,6 +77,16 @@ func (p *AccountsProcessor) Commit(ctx context.Context) error {
 		}
 	}
 
+	if p.versions.enabled() && len(batchUpsertAccounts)+len(removeBatch) > 0 {
+		accountIDs := append([]string{}, removeBatch...)
+		for _, row := range batchUpsertAccounts {
+			accountIDs = append(accountIDs, row.AccountID)
+		}
+		if err := p.versions.Q.ArchiveAccounts(ctx, accountIDs, p.versions.Sequence); err != nil {
+			return errors.Wrap(err, "error in ArchiveAccounts")
+		}
+	}
+
 	// Upsert accounts
 	if len(batchUpsertAccounts) > 0 {
 		err := p.accountsQ.UpsertAccounts(ctx, batchUpsertAccounts)
@@ -9,6 +9,8 @@ import (
 	"github.com/diamnet/go/ingest"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -27,7 +29,7 @@ func (s *AccountsProcessorTestSuiteState) SetupTest() {
 	s.ctx = context.Background()
 	s.mockQ = &history.MockQAccounts{}
 
-	s.processor = NewAccountsProcessor(s.mockQ)
+	s.processor = NewAccountsProcessor(s.mockQ, StateVersions{})
 }
 
 func (s *AccountsProcessorTestSuiteState) TearDownTest() {
@@ -87,7 +89,7 @@ func (s *AccountsProcessorTestSuiteLedger) SetupTest() {
 	s.ctx = context.Background()
 	s.mockQ = &history.MockQAccounts{}
 
-	s.processor = NewAccountsProcessor(s.mockQ)
+	s.processor = NewAccountsProcessor(s.mockQ, StateVersions{})
 }
 
 func (s *AccountsProcessorTestSuiteLedger) TearDownTest() {
@@ -306,3 +308,63 @@ func (s *AccountsProcessorTestSuiteLedger) TestFeeProcessedBeforeEverythingElse(
 		},
 	).Return(nil).Once()
 }
+
+func TestAccountsProcessorArchivesVersions(t *testing.T) {
+	ctx := context.Background()
+	mockQ := &history.MockQAccounts{}
+	versionsQ := &history.MockQStateVersions{}
+	processor := NewAccountsProcessor(mockQ, StateVersions{Q: versionsQ, Sequence: 124})
+
+	updated := xdr.AccountEntry{
+		AccountId:  xdr.MustAddress("GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"),
+		Thresholds: [4]byte{1, 1, 1, 1},
+	}
+	removed := xdr.AccountEntry{
+		AccountId:  xdr.MustAddress("GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"),
+		Thresholds: [4]byte{1, 1, 1, 1},
+	}
+	changes := []ingest.Change{
+		{
+			Type: xdr.LedgerEntryTypeAccount,
+			Pre: &xdr.LedgerEntry{
+				LastModifiedLedgerSeq: 100,
+				Data:                  xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeAccount, Account: &updated},
+			},
+			Post: &xdr.LedgerEntry{
+				LastModifiedLedgerSeq: 124,
+				Data: xdr.LedgerEntryData{
+					Type: xdr.LedgerEntryTypeAccount,
+					Account: &xdr.AccountEntry{
+						AccountId:  updated.AccountId,
+						Balance:    200,
+						Thresholds: [4]byte{1, 1, 1, 1},
+					},
+				},
+			},
+		},
+		{
+			Type: xdr.LedgerEntryTypeAccount,
+			Pre: &xdr.LedgerEntry{
+				LastModifiedLedgerSeq: 100,
+				Data:                  xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeAccount, Account: &removed},
+			},
+		},
+	}
+	for _, change := range changes {
+		assert.NoError(t, processor.ProcessChange(ctx, change))
+	}
+
+	versionsQ.On(
+		"ArchiveAccounts",
+		ctx,
+		[]string{removed.AccountId.Address(), updated.AccountId.Address()},
+		uint32(124),
+	).Return(nil).Once()
+	mockQ.On("UpsertAccounts", ctx, mock.Anything).Return(nil).Once()
+	mockQ.On("RemoveAccounts", ctx, []string{removed.AccountId.Address()}).
+		Return(int64(1), nil).Once()
+
+	assert.NoError(t, processor.Commit(ctx))
+	mockQ.AssertExpectations(t)
+	versionsQ.AssertExpectations(t)
+}
@@ -0,0 +1,161 @@
+package processors
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+type assetActivity struct {
+	asset     xdr.Asset
+	volume    big.Int
+	transfers int64
+}
+
+// AssetStatsHistoryProcessor maintains the daily statistics of the credit
+// assets. It sums the amounts received by payments and counts the payments of
+// every asset and, if withSnapshots is true, records the holders, trust lines
+// and supply of the assets whose trust lines changed in the ledger.
+type AssetStatsHistoryProcessor struct {
+	assetStatsHistoryQ history.QAssetStatsHistory
+	ledger             xdr.LedgerHeaderHistoryEntry
+	withSnapshots      bool
+	assets             map[string]*assetActivity
+}
+
+// NewAssetStatsHistoryProcessor constructs a new AssetStatsHistoryProcessor
+// instance. withSnapshots must only be true when the state of the ledger is
+// ingested before the processor is committed, as the holders, trust lines and
+// supply are read from the asset stats of the state.
+func NewAssetStatsHistoryProcessor(
+	assetStatsHistoryQ history.QAssetStatsHistory,
+	ledger xdr.LedgerHeaderHistoryEntry,
+	withSnapshots bool,
+) *AssetStatsHistoryProcessor {
+	return &AssetStatsHistoryProcessor{
+		assetStatsHistoryQ: assetStatsHistoryQ,
+		ledger:             ledger,
+		withSnapshots:      withSnapshots,
+		assets:             map[string]*assetActivity{},
+	}
+}
+
+// ProcessTransaction records the payments and trust line changes of the
+// given transaction.
+func (p *AssetStatsHistoryProcessor) ProcessTransaction(ctx context.Context, transaction ingest.LedgerTransaction) error {
+	if !transaction.Result.Successful() {
+		return nil
+	}
+
+	results, _ := transaction.Result.OperationResults()
+	for i, op := range transaction.Envelope.Operations() {
+		switch op.Body.Type {
+		case xdr.OperationTypePayment:
+			payment := op.Body.MustPaymentOp()
+			p.addPayment(payment.Asset, payment.Amount)
+		case xdr.OperationTypePathPaymentStrictReceive:
+			payment := op.Body.MustPathPaymentStrictReceiveOp()
+			p.addPayment(payment.DestAsset, payment.DestAmount)
+		case xdr.OperationTypePathPaymentStrictSend:
+			if i >= len(results) {
+				return errors.Errorf("missing result of operation %d", i)
+			}
+			payment := op.Body.MustPathPaymentStrictSendOp()
+			result := results[i].MustTr().MustPathPaymentStrictSendResult().MustSuccess()
+			p.addPayment(payment.DestAsset, result.Last.Amount)
+		}
+	}
+
+	if !p.withSnapshots {
+		return nil
+	}
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return errors.Wrap(err, "could not get transaction changes")
+	}
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeTrustline {
+			continue
+		}
+		entry := change.Post
+		if entry == nil {
+			entry = change.Pre
+		}
+		trustLineAsset := entry.Data.MustTrustLine().Asset
+		if trustLineAsset.Type == xdr.AssetTypeAssetTypePoolShare {
+			continue
+		}
+		p.activity(trustLineAsset.ToAsset())
+	}
+	return nil
+}
+
+func (p *AssetStatsHistoryProcessor) addPayment(asset xdr.Asset, amount xdr.Int64) {
+	activity := p.activity(asset)
+	if activity == nil {
+		return
+	}
+	activity.volume.Add(&activity.volume, big.NewInt(int64(amount)))
+	activity.transfers++
+}
+
+// activity returns the activity of the given asset in the ledger, it is nil
+// for the native asset which has no asset stats.
+func (p *AssetStatsHistoryProcessor) activity(asset xdr.Asset) *assetActivity {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return nil
+	}
+	key := asset.String()
+	activity, ok := p.assets[key]
+	if !ok {
+		activity = &assetActivity{asset: asset}
+		p.assets[key] = activity
+	}
+	return activity
+}
+
+// Commit adds the activity of the ledger to the daily buckets of the assets.
+func (p *AssetStatsHistoryProcessor) Commit(ctx context.Context) error {
+	if len(p.assets) == 0 {
+		return nil
+	}
+
+	assets := make([]xdr.Asset, 0, len(p.assets))
+	for _, activity := range p.assets {
+		assets = append(assets, activity.asset)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i].String() < assets[j].String()
+	})
+	assetMap, err := p.assetStatsHistoryQ.CreateAssets(ctx, assets, maxBatchSize)
+	if err != nil {
+		return errors.Wrap(err, "Error creating asset ids")
+	}
+
+	deltas := make([]history.AssetStatsLedgerDelta, 0, len(p.assets))
+	for key, activity := range p.assets {
+		asset, ok := assetMap[key]
+		if !ok {
+			return errors.Errorf("Could not find history asset id for %s", key)
+		}
+		deltas = append(deltas, history.AssetStatsLedgerDelta{
+			AssetID:       asset.ID,
+			PaymentVolume: activity.volume.String(),
+			TransferCount: activity.transfers,
+		})
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		return deltas[i].AssetID < deltas[j].AssetID
+	})
+
+	err = p.assetStatsHistoryQ.UpsertAssetStatsBuckets(ctx, p.ledger, deltas, p.withSnapshots)
+	if err != nil {
+		return errors.Wrap(err, "Error upserting asset stats buckets")
+	}
+	return nil
+}
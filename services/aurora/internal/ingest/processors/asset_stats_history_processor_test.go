@@ -0,0 +1,122 @@
+package processors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/xdr"
+)
+
+const assetStatsHistoryIssuer = "GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY"
+
+func assetStatsHistoryTransaction(successful bool, usd, eur, btc xdr.Asset) ingest.LedgerTransaction {
+	destination := xdr.MustMuxedAddress("GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H")
+	transaction := createTransaction(successful, 4)
+	transaction.Envelope.V1.Tx.Operations = []xdr.Operation{
+		{Body: xdr.OperationBody{
+			Type:      xdr.OperationTypePayment,
+			PaymentOp: &xdr.PaymentOp{Destination: destination, Asset: usd, Amount: 100},
+		}},
+		{Body: xdr.OperationBody{
+			Type:      xdr.OperationTypePayment,
+			PaymentOp: &xdr.PaymentOp{Destination: destination, Asset: xdr.MustNewNativeAsset(), Amount: 1000},
+		}},
+		{Body: xdr.OperationBody{
+			Type: xdr.OperationTypePathPaymentStrictReceive,
+			PathPaymentStrictReceiveOp: &xdr.PathPaymentStrictReceiveOp{
+				SendAsset:   eur,
+				SendMax:     80,
+				Destination: destination,
+				DestAsset:   usd,
+				DestAmount:  50,
+			},
+		}},
+		{Body: xdr.OperationBody{
+			Type: xdr.OperationTypePathPaymentStrictSend,
+			PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{
+				SendAsset:   usd,
+				SendAmount:  20,
+				Destination: destination,
+				DestAsset:   eur,
+				DestMin:     10,
+			},
+		}},
+	}
+	transaction.Result.Result.Result.Results = &[]xdr.OperationResult{
+		{}, {}, {},
+		{Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypePathPaymentStrictSend,
+			PathPaymentStrictSendResult: &xdr.PathPaymentStrictSendResult{
+				Code: xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+				Success: &xdr.PathPaymentStrictSendResultSuccess{
+					Last: xdr.SimplePaymentResult{Destination: destination.ToAccountId(), Asset: eur, Amount: 30},
+				},
+			},
+		}},
+	}
+	transaction.UnsafeMeta.V2.Operations[0].Changes = xdr.LedgerEntryChanges{{
+		Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated,
+		Created: &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type: xdr.LedgerEntryTypeTrustline,
+				TrustLine: &xdr.TrustLineEntry{
+					AccountId: destination.ToAccountId(),
+					Asset:     btc.ToTrustLineAsset(),
+				},
+			},
+		},
+	}}
+	return transaction
+}
+
+func TestAssetStatsHistoryProcessor(t *testing.T) {
+	ctx := context.Background()
+	usd := xdr.MustNewCreditAsset("USD", assetStatsHistoryIssuer)
+	eur := xdr.MustNewCreditAsset("EUR", assetStatsHistoryIssuer)
+	btc := xdr.MustNewCreditAsset("BTC", assetStatsHistoryIssuer)
+	ledger := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 20}}
+
+	for _, withSnapshots := range []bool{true, false} {
+		q := &history.MockQAssetStatsHistory{}
+		processor := NewAssetStatsHistoryProcessor(q, ledger, withSnapshots)
+
+		assert.NoError(t, processor.ProcessTransaction(ctx, assetStatsHistoryTransaction(true, usd, eur, btc)))
+		assert.NoError(t, processor.ProcessTransaction(ctx, assetStatsHistoryTransaction(false, usd, eur, btc)))
+
+		assets := []xdr.Asset{eur, usd}
+		deltas := []history.AssetStatsLedgerDelta{
+			{AssetID: 2, PaymentVolume: "30", TransferCount: 1},
+			{AssetID: 3, PaymentVolume: "150", TransferCount: 2},
+		}
+		if withSnapshots {
+			// The trust lines of BTC changed
+			assets = []xdr.Asset{btc, eur, usd}
+			deltas = append([]history.AssetStatsLedgerDelta{
+				{AssetID: 1, PaymentVolume: "0", TransferCount: 0},
+			}, deltas...)
+		}
+		q.On("CreateAssets", ctx, assets, maxBatchSize).Return(map[string]history.Asset{
+			btc.String(): {ID: 1},
+			eur.String(): {ID: 2},
+			usd.String(): {ID: 3},
+		}, nil).Once()
+		q.On("UpsertAssetStatsBuckets", ctx, ledger, deltas, withSnapshots).Return(nil).Once()
+
+		assert.NoError(t, processor.Commit(ctx))
+		q.AssertExpectations(t)
+	}
+}
+
+func TestAssetStatsHistoryProcessorNoActivity(t *testing.T) {
+	q := &history.MockQAssetStatsHistory{}
+	processor := NewAssetStatsHistoryProcessor(q, xdr.LedgerHeaderHistoryEntry{}, true)
+
+	assert.NoError(t, processor.ProcessTransaction(context.Background(), createTransaction(true, 2)))
+	assert.NoError(t, processor.Commit(context.Background()))
+	q.AssertNotCalled(t, "CreateAssets", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -12,13 +12,15 @@ import (
 type ClaimableBalancesChangeProcessor struct {
 	encodingBuffer     *xdr.EncodingBuffer
 	qClaimableBalances history.QClaimableBalances
+	versions           StateVersions
 	cache              *ingest.ChangeCompactor
 }
 
-func NewClaimableBalancesChangeProcessor(Q history.QClaimableBalances) *ClaimableBalancesChangeProcessor {
+func NewClaimableBalancesChangeProcessor(Q history.QClaimableBalances, versions StateVersions) *ClaimableBalancesChangeProcessor {
 	p := &ClaimableBalancesChangeProcessor{
 		encodingBuffer:     xdr.NewEncodingBuffer(),
 		qClaimableBalances: Q,
+		versions:           versions,
 	}
 	p.reset()
 	return p
@@ -82,6 +84,16 @@ func (p *ClaimableBalancesChangeProcessor) Commit(ctx context.Context) error {
 		}
 	}
 
+	if p.versions.enabled() && len(cbsToUpsert)+len(cbIDsToDelete) > 0 {
+		ids := append([]string{}, cbIDsToDelete...)
+		for _, row := range cbsToUpsert {
+			ids = append(ids, row.BalanceID)
+		}
+		if err := p.versions.Q.ArchiveClaimableBalances(ctx, ids, p.versions.Sequence); err != nil {
+			return errors.Wrap(err, "error archiving claimable balances")
+		}
+	}
+
 	if len(cbsToUpsert) > 0 {
 		if err := p.qClaimableBalances.UpsertClaimableBalances(ctx, cbsToUpsert); err != nil {
 			return errors.Wrap(err, "error executing upsert")
@@ -29,7 +29,7 @@ func (s *ClaimableBalancesChangeProcessorTestSuiteState) SetupTest() {
 	s.ctx = context.Background()
 	s.mockQ = &history.MockQClaimableBalances{}
 
-	s.processor = NewClaimableBalancesChangeProcessor(s.mockQ)
+	s.processor = NewClaimableBalancesChangeProcessor(s.mockQ, StateVersions{})
 }
 
 func (s *ClaimableBalancesChangeProcessorTestSuiteState) TearDownTest() {
@@ -95,7 +95,7 @@ func (s *ClaimableBalancesChangeProcessorTestSuiteLedger) SetupTest() {
 	s.ctx = context.Background()
 	s.mockQ = &history.MockQClaimableBalances{}
 
-	s.processor = NewClaimableBalancesChangeProcessor(s.mockQ)
+	s.processor = NewClaimableBalancesChangeProcessor(s.mockQ, StateVersions{})
 }
 
 func (s *ClaimableBalancesChangeProcessorTestSuiteLedger) TearDownTest() {
@@ -0,0 +1,225 @@
+package processors
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/protocols/aurora/effects"
+	"github.com/diamnet/go/protocols/aurora/operations"
+	"github.com/diamnet/go/services/aurora/internal/ingest/export"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+var ledgerEntryTypeNames = map[xdr.LedgerEntryType]string{
+	xdr.LedgerEntryTypeAccount:          "account",
+	xdr.LedgerEntryTypeTrustline:        "trustline",
+	xdr.LedgerEntryTypeOffer:            "offer",
+	xdr.LedgerEntryTypeData:             "data",
+	xdr.LedgerEntryTypeClaimableBalance: "claimable_balance",
+	xdr.LedgerEntryTypeLiquidityPool:    "liquidity_pool",
+}
+
+// EventExportProcessor builds the events exported for a ledger. It processes
+// both the changes and the transactions of the ledger, Events returns the
+// events once both have been processed.
+type EventExportProcessor struct {
+	ledger   xdr.LedgerHeaderHistoryEntry
+	sequence uint32
+
+	transactionEvents []export.Event
+	changeEvents      []export.Event
+
+	successTxCount int
+	failedTxCount  int
+	opCount        int
+}
+
+func NewEventExportProcessor(ledger xdr.LedgerHeaderHistoryEntry) *EventExportProcessor {
+	return &EventExportProcessor{
+		ledger:   ledger,
+		sequence: uint32(ledger.Header.LedgerSeq),
+	}
+}
+
+func (p *EventExportProcessor) ProcessChange(ctx context.Context, change ingest.Change) error {
+	var entry *xdr.LedgerEntry
+	stateChange := export.StateChange{EntryType: ledgerEntryTypeNames[change.Type]}
+	switch {
+	case change.Pre == nil:
+		stateChange.Change = "created"
+		entry = change.Post
+	case change.Post == nil:
+		stateChange.Change = "removed"
+		entry = change.Pre
+	default:
+		stateChange.Change = "updated"
+		entry = change.Post
+	}
+
+	var err error
+	if stateChange.KeyXDR, err = xdr.MarshalBase64(entry.LedgerKey()); err != nil {
+		return errors.Wrap(err, "Error encoding ledger key")
+	}
+	if change.Pre != nil {
+		if stateChange.PreXDR, err = xdr.MarshalBase64(change.Pre); err != nil {
+			return errors.Wrap(err, "Error encoding ledger entry")
+		}
+	}
+	if change.Post != nil {
+		if stateChange.PostXDR, err = xdr.MarshalBase64(change.Post); err != nil {
+			return errors.Wrap(err, "Error encoding ledger entry")
+		}
+	}
+
+	p.changeEvents = append(p.changeEvents, p.ledgerEvent(
+		export.StateChangeEvent,
+		uint32(len(p.changeEvents)),
+		stateChange,
+	))
+	return nil
+}
+
+func (p *EventExportProcessor) ProcessTransaction(ctx context.Context, transaction ingest.LedgerTransaction) error {
+	hash := hex.EncodeToString(transaction.Result.TransactionHash[:])
+	successful := transaction.Result.Successful()
+	ops := transaction.Envelope.Operations()
+	if successful {
+		p.successTxCount++
+		p.opCount += len(ops)
+	} else {
+		p.failedTxCount++
+	}
+
+	data := export.Transaction{
+		Hash:             hash,
+		Successful:       successful,
+		FeeCharged:       int64(transaction.Result.Result.FeeCharged),
+		MaxFee:           int64(transaction.Envelope.Fee()),
+		OperationCount:   len(ops),
+		ApplicationOrder: transaction.Index,
+	}
+	data.SourceAccount, data.SourceAccountMuxed = muxedAddresses(transaction.Envelope.SourceAccount())
+	if transaction.Envelope.IsFeeBump() {
+		data.FeeAccount, _ = muxedAddresses(transaction.Envelope.FeeBumpAccount())
+		data.MaxFee = transaction.Envelope.FeeBumpFee()
+	}
+	var err error
+	if data.EnvelopeXDR, err = xdr.MarshalBase64(transaction.Envelope); err != nil {
+		return errors.Wrapf(err, "Error encoding envelope of transaction %s", hash)
+	}
+	if data.ResultXDR, err = xdr.MarshalBase64(transaction.Result.Result); err != nil {
+		return errors.Wrapf(err, "Error encoding result of transaction %s", hash)
+	}
+
+	transactionID := toid.New(int32(p.sequence), int32(transaction.Index), 0).ToInt64()
+	p.transactionEvents = append(p.transactionEvents, export.Event{
+		ID:             export.EventID(export.TransactionEvent, transactionID, 0),
+		Type:           export.TransactionEvent,
+		LedgerSequence: p.sequence,
+		TOID:           transactionID,
+		Data:           data,
+	})
+
+	for i, op := range ops {
+		operation := transactionOperationWrapper{
+			index:          uint32(i),
+			transaction:    transaction,
+			operation:      op,
+			ledgerSequence: p.sequence,
+		}
+		details, err := operation.Details()
+		if err != nil {
+			return errors.Wrapf(err, "Error obtaining details for operation %v", operation.ID())
+		}
+		data := export.Operation{
+			Type:                  operations.TypeNames[operation.OperationType()],
+			TypeI:                 int32(operation.OperationType()),
+			TransactionHash:       hash,
+			TransactionSuccessful: successful,
+			Details:               details,
+		}
+		data.SourceAccount, data.SourceAccountMuxed = muxedAddresses(*operation.SourceAccount())
+		p.transactionEvents = append(p.transactionEvents, export.Event{
+			ID:             export.EventID(export.OperationEvent, operation.ID(), 0),
+			Type:           export.OperationEvent,
+			LedgerSequence: p.sequence,
+			TOID:           operation.ID(),
+			Data:           data,
+		})
+
+		// Failed transactions don't have operation effects
+		if !successful {
+			continue
+		}
+		operationEffects, err := operation.effects()
+		if err != nil {
+			return errors.Wrapf(err, "reading operation %v effects", operation.ID())
+		}
+		for _, effect := range operationEffects {
+			effectType := effects.EffectType(effect.effectType)
+			p.transactionEvents = append(p.transactionEvents, export.Event{
+				ID:             export.EventID(export.EffectEvent, effect.operationID, effect.order),
+				Type:           export.EffectEvent,
+				LedgerSequence: p.sequence,
+				TOID:           effect.operationID,
+				Data: export.Effect{
+					Type:         effects.EffectTypeNames[effectType],
+					TypeI:        int32(effectType),
+					Account:      effect.address,
+					AccountMuxed: effect.addressMuxed.String,
+					Details:      effect.details,
+				},
+			})
+		}
+	}
+
+	return nil
+}
+
+func (p *EventExportProcessor) Commit(ctx context.Context) error {
+	return nil
+}
+
+// Events returns the events of the ledger: every transaction followed by its
+// operations, each followed by its effects, then the state changes and
+// finally the ledger event.
+func (p *EventExportProcessor) Events() []export.Event {
+	events := make([]export.Event, 0, len(p.transactionEvents)+len(p.changeEvents)+1)
+	events = append(events, p.transactionEvents...)
+	events = append(events, p.changeEvents...)
+	return append(events, p.ledgerEvent(export.LedgerEvent, 0, export.Ledger{
+		Hash:                       hex.EncodeToString(p.ledger.Hash[:]),
+		PreviousHash:               hex.EncodeToString(p.ledger.Header.PreviousLedgerHash[:]),
+		ClosedAt:                   time.Unix(int64(p.ledger.Header.ScpValue.CloseTime), 0).UTC(),
+		ProtocolVersion:            uint32(p.ledger.Header.LedgerVersion),
+		SuccessfulTransactionCount: p.successTxCount,
+		FailedTransactionCount:     p.failedTxCount,
+		OperationCount:             p.opCount,
+		EventCount:                 len(events) + 1,
+	}))
+}
+
+func (p *EventExportProcessor) ledgerEvent(eventType string, index uint32, data interface{}) export.Event {
+	ledgerID := toid.New(int32(p.sequence), 0, 0).ToInt64()
+	return export.Event{
+		ID:             export.EventID(eventType, ledgerID, index),
+		Type:           eventType,
+		LedgerSequence: p.sequence,
+		TOID:           ledgerID,
+		Data:           data,
+	}
+}
+
+// muxedAddresses returns the G... address of the account and, for muxed
+// accounts, the M... address.
+func muxedAddresses(account xdr.MuxedAccount) (string, string) {
+	accountID := account.ToAccountId()
+	if account.Type == xdr.CryptoKeyTypeKeyTypeMuxedEd25519 {
+		return accountID.Address(), account.Address()
+	}
+	return accountID.Address(), ""
+}
@@ -0,0 +1,86 @@
+package processors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/services/aurora/internal/ingest/export"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/xdr"
+)
+
+func TestEventExportProcessor(t *testing.T) {
+	ctx := context.Background()
+	processor := NewEventExportProcessor(xdr.LedgerHeaderHistoryEntry{
+		Hash: xdr.Hash{1},
+		Header: xdr.LedgerHeader{
+			LedgerSeq:     20,
+			LedgerVersion: 17,
+			ScpValue:      xdr.DiamnetValue{CloseTime: 1600000000},
+		},
+	})
+
+	account := xdr.MustAddress("GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY")
+	require.NoError(t, processor.ProcessChange(ctx, ingest.Change{
+		Type: xdr.LedgerEntryTypeAccount,
+		Post: &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type:    xdr.LedgerEntryTypeAccount,
+				Account: &xdr.AccountEntry{AccountId: account, Balance: 100},
+			},
+		},
+	}))
+
+	successful := createTransaction(true, 1)
+	successful.Index = 1
+	failed := createTransaction(false, 2)
+	failed.Index = 2
+	require.NoError(t, processor.ProcessTransaction(ctx, successful))
+	require.NoError(t, processor.ProcessTransaction(ctx, failed))
+	require.NoError(t, processor.Commit(ctx))
+
+	events := processor.Events()
+	var ids []string
+	for _, event := range events {
+		assert.Equal(t, uint32(20), event.LedgerSequence)
+		ids = append(ids, event.ID)
+	}
+	ledgerID := toid.New(20, 0, 0).ToInt64()
+	assert.Equal(t, []string{
+		export.EventID(export.TransactionEvent, toid.New(20, 1, 0).ToInt64(), 0),
+		export.EventID(export.OperationEvent, toid.New(20, 1, 1).ToInt64(), 0),
+		export.EventID(export.TransactionEvent, toid.New(20, 2, 0).ToInt64(), 0),
+		export.EventID(export.OperationEvent, toid.New(20, 2, 1).ToInt64(), 0),
+		export.EventID(export.OperationEvent, toid.New(20, 2, 2).ToInt64(), 0),
+		export.EventID(export.StateChangeEvent, ledgerID, 0),
+		export.EventID(export.LedgerEvent, ledgerID, 0),
+	}, ids)
+
+	transaction := events[0].Data.(export.Transaction)
+	assert.True(t, transaction.Successful)
+	assert.Equal(t, account.Address(), transaction.SourceAccount)
+	assert.Equal(t, 1, transaction.OperationCount)
+	assert.NotEmpty(t, transaction.EnvelopeXDR)
+
+	operation := events[1].Data.(export.Operation)
+	assert.Equal(t, "bump_sequence", operation.Type)
+	assert.Equal(t, account.Address(), operation.SourceAccount)
+	assert.True(t, operation.TransactionSuccessful)
+
+	stateChange := events[5].Data.(export.StateChange)
+	assert.Equal(t, "account", stateChange.EntryType)
+	assert.Equal(t, "created", stateChange.Change)
+	assert.Empty(t, stateChange.PreXDR)
+	assert.NotEmpty(t, stateChange.PostXDR)
+
+	ledger := events[6].Data.(export.Ledger)
+	assert.Equal(t, 1, ledger.SuccessfulTransactionCount)
+	assert.Equal(t, 1, ledger.FailedTransactionCount)
+	assert.Equal(t, 1, ledger.OperationCount)
+	assert.Equal(t, 7, ledger.EventCount)
+	assert.Equal(t, int64(1600000000), ledger.ClosedAt.Unix())
+}
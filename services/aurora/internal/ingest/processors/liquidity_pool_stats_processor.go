@@ -0,0 +1,158 @@
+package processors
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+type liquidityPoolActivity struct {
+	pool   xdr.LiquidityPoolEntryConstantProduct
+	trades int64
+	volume [2]big.Int
+	// fees are the amounts received by the pool multiplied by the fee in
+	// basis points
+	fees [2]big.Int
+}
+
+// LiquidityPoolStatsProcessor maintains the hourly statistics of the
+// liquidity pools: the trades against every pool with their volume and fees
+// and the reserves, shares and trust lines of the pool at the end of the hour.
+// Everything is read from the ledger so it does not depend on the state.
+type LiquidityPoolStatsProcessor struct {
+	liquidityPoolStatsQ history.QLiquidityPoolStats
+	ledger              xdr.LedgerHeaderHistoryEntry
+	pools               map[string]*liquidityPoolActivity
+}
+
+// NewLiquidityPoolStatsProcessor constructs a new LiquidityPoolStatsProcessor
+// instance.
+func NewLiquidityPoolStatsProcessor(
+	liquidityPoolStatsQ history.QLiquidityPoolStats,
+	ledger xdr.LedgerHeaderHistoryEntry,
+) *LiquidityPoolStatsProcessor {
+	return &LiquidityPoolStatsProcessor{
+		liquidityPoolStatsQ: liquidityPoolStatsQ,
+		ledger:              ledger,
+		pools:               map[string]*liquidityPoolActivity{},
+	}
+}
+
+// ProcessTransaction records the changes of the liquidity pools and the
+// trades against them in the given transaction.
+func (p *LiquidityPoolStatsProcessor) ProcessTransaction(ctx context.Context, transaction ingest.LedgerTransaction) error {
+	if !transaction.Result.Successful() {
+		return nil
+	}
+
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return errors.Wrap(err, "could not get transaction changes")
+	}
+	changed := false
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeLiquidityPool {
+			continue
+		}
+		changed = true
+		var entry xdr.LiquidityPoolEntry
+		var pool xdr.LiquidityPoolEntryConstantProduct
+		if change.Post != nil {
+			entry = change.Post.Data.MustLiquidityPool()
+			pool = entry.Body.MustConstantProduct()
+		} else {
+			// The pool was removed, only its parameters are kept
+			entry = change.Pre.Data.MustLiquidityPool()
+			pool = xdr.LiquidityPoolEntryConstantProduct{
+				Params: entry.Body.MustConstantProduct().Params,
+			}
+		}
+		p.activity(PoolIDToString(entry.LiquidityPoolId)).pool = pool
+	}
+	// Trades against a pool always change it
+	if !changed {
+		return nil
+	}
+
+	trades, err := (&TradeProcessor{}).extractTrades(p.ledger, transaction)
+	if err != nil {
+		return err
+	}
+	for _, trade := range trades {
+		if trade.liquidityPoolID == "" {
+			continue
+		}
+		activity := p.activity(trade.liquidityPoolID)
+		// The pool sold the base asset and bought the counter asset
+		bought, sold := 0, 1
+		if !trade.boughtAsset.Equals(activity.pool.Params.AssetA) {
+			bought, sold = 1, 0
+		}
+		counterAmount := big.NewInt(trade.row.CounterAmount)
+		activity.trades++
+		activity.volume[bought].Add(&activity.volume[bought], counterAmount)
+		activity.volume[sold].Add(&activity.volume[sold], big.NewInt(trade.row.BaseAmount))
+		fee := new(big.Int).Mul(counterAmount, big.NewInt(trade.row.LiquidityPoolFee.Int64))
+		activity.fees[bought].Add(&activity.fees[bought], fee)
+	}
+	return nil
+}
+
+func (p *LiquidityPoolStatsProcessor) activity(poolID string) *liquidityPoolActivity {
+	activity, ok := p.pools[poolID]
+	if !ok {
+		activity = &liquidityPoolActivity{}
+		p.pools[poolID] = activity
+	}
+	return activity
+}
+
+// Commit adds the activity of the ledger to the hourly buckets of the pools.
+func (p *LiquidityPoolStatsProcessor) Commit(ctx context.Context) error {
+	if len(p.pools) == 0 {
+		return nil
+	}
+
+	poolIDs := make([]string, 0, len(p.pools))
+	for poolID := range p.pools {
+		poolIDs = append(poolIDs, poolID)
+	}
+	sort.Strings(poolIDs)
+	historyIDs, err := p.liquidityPoolStatsQ.CreateHistoryLiquidityPools(ctx, poolIDs, maxBatchSize)
+	if err != nil {
+		return errors.Wrap(err, "Error creating pool ids")
+	}
+
+	deltas := make([]history.LiquidityPoolStatsLedgerDelta, 0, len(p.pools))
+	basisPoints := big.NewInt(10000)
+	for _, poolID := range poolIDs {
+		historyID, ok := historyIDs[poolID]
+		if !ok {
+			return errors.Errorf("Could not find history liquidity pool id for %s", poolID)
+		}
+		activity := p.pools[poolID]
+		deltas = append(deltas, history.LiquidityPoolStatsLedgerDelta{
+			HistoryLiquidityPoolID: historyID,
+			TradeCount:             activity.trades,
+			VolumeA:                activity.volume[0].String(),
+			VolumeB:                activity.volume[1].String(),
+			FeeA:                   new(big.Rat).SetFrac(&activity.fees[0], basisPoints).FloatString(4),
+			FeeB:                   new(big.Rat).SetFrac(&activity.fees[1], basisPoints).FloatString(4),
+			ReserveA:               int64(activity.pool.ReserveA),
+			ReserveB:               int64(activity.pool.ReserveB),
+			TotalShares:            int64(activity.pool.TotalPoolShares),
+			TrustlineCount:         int64(activity.pool.PoolSharesTrustLineCount),
+		})
+	}
+
+	err = p.liquidityPoolStatsQ.UpsertLiquidityPoolStatsBuckets(ctx, p.ledger, deltas)
+	if err != nil {
+		return errors.Wrap(err, "Error upserting liquidity pool stats buckets")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package processors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/xdr"
+)
+
+func liquidityPoolStatsEntry(
+	poolID xdr.PoolId,
+	usd, eur xdr.Asset,
+	reserveA, reserveB xdr.Int64,
+) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeLiquidityPool,
+			LiquidityPool: &xdr.LiquidityPoolEntry{
+				LiquidityPoolId: poolID,
+				Body: xdr.LiquidityPoolEntryBody{
+					Type: xdr.LiquidityPoolTypeLiquidityPoolConstantProduct,
+					ConstantProduct: &xdr.LiquidityPoolEntryConstantProduct{
+						Params: xdr.LiquidityPoolConstantProductParameters{
+							AssetA: eur,
+							AssetB: usd,
+							Fee:    xdr.LiquidityPoolFeeV18,
+						},
+						ReserveA:                 reserveA,
+						ReserveB:                 reserveB,
+						TotalPoolShares:          500,
+						PoolSharesTrustLineCount: 3,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestLiquidityPoolStatsProcessor(t *testing.T) {
+	ctx := context.Background()
+	usd := xdr.MustNewCreditAsset("USD", assetStatsHistoryIssuer)
+	eur := xdr.MustNewCreditAsset("EUR", assetStatsHistoryIssuer)
+	poolID := xdr.PoolId{1, 2, 3}
+	ledger := xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 20}}
+
+	// Two path payments sending USD to the pool for EUR
+	transaction := createTransaction(true, 2)
+	for i := range transaction.Envelope.V1.Tx.Operations {
+		transaction.Envelope.V1.Tx.Operations[i].Body = xdr.OperationBody{
+			Type: xdr.OperationTypePathPaymentStrictSend,
+			PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{
+				SendAsset:  usd,
+				SendAmount: 1000,
+				DestAsset:  eur,
+			},
+		}
+	}
+	results := make([]xdr.OperationResult, 2)
+	for i := range results {
+		results[i] = xdr.OperationResult{Tr: &xdr.OperationResultTr{
+			Type: xdr.OperationTypePathPaymentStrictSend,
+			PathPaymentStrictSendResult: &xdr.PathPaymentStrictSendResult{
+				Code: xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+				Success: &xdr.PathPaymentStrictSendResultSuccess{
+					Offers: []xdr.ClaimAtom{{
+						Type: xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool,
+						LiquidityPool: &xdr.ClaimLiquidityAtom{
+							LiquidityPoolId: poolID,
+							AssetSold:       eur,
+							AmountSold:      495,
+							AssetBought:     usd,
+							AmountBought:    1000,
+						},
+					}},
+				},
+			},
+		}}
+	}
+	transaction.Result.Result.Result.Results = &results
+	transaction.UnsafeMeta.V2.Operations[0].Changes = xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: liquidityPoolStatsEntry(poolID, usd, eur, 100000, 200000)},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: liquidityPoolStatsEntry(poolID, usd, eur, 99505, 201000)},
+	}
+	transaction.UnsafeMeta.V2.Operations[1].Changes = xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: liquidityPoolStatsEntry(poolID, usd, eur, 99505, 201000)},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: liquidityPoolStatsEntry(poolID, usd, eur, 99010, 202000)},
+	}
+
+	q := &history.MockQLiquidityPoolStats{}
+	processor := NewLiquidityPoolStatsProcessor(q, ledger)
+	assert.NoError(t, processor.ProcessTransaction(ctx, transaction))
+	assert.NoError(t, processor.ProcessTransaction(ctx, createTransaction(false, 1)))
+
+	q.On("CreateHistoryLiquidityPools", ctx, []string{PoolIDToString(poolID)}, maxBatchSize).
+		Return(map[string]int64{PoolIDToString(poolID): 7}, nil).Once()
+	q.On("UpsertLiquidityPoolStatsBuckets", ctx, ledger, []history.LiquidityPoolStatsLedgerDelta{{
+		HistoryLiquidityPoolID: 7,
+		TradeCount:             2,
+		VolumeA:                "990",
+		VolumeB:                "2000",
+		FeeA:                   "0.0000",
+		FeeB:                   "6.0000",
+		ReserveA:               99010,
+		ReserveB:               202000,
+		TotalShares:            500,
+		TrustlineCount:         3,
+	}}).Return(nil).Once()
+
+	assert.NoError(t, processor.Commit(ctx))
+	q.AssertExpectations(t)
+}
+
+func TestLiquidityPoolStatsProcessorNoPools(t *testing.T) {
+	q := &history.MockQLiquidityPoolStats{}
+	processor := NewLiquidityPoolStatsProcessor(q, xdr.LedgerHeaderHistoryEntry{})
+
+	assert.NoError(t, processor.ProcessTransaction(context.Background(), createTransaction(true, 2)))
+	assert.NoError(t, processor.Commit(context.Background()))
+	q.AssertNotCalled(t, "CreateHistoryLiquidityPools", mock.Anything, mock.Anything, mock.Anything)
+}
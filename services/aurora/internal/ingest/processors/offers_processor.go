@@ -16,12 +16,13 @@ const compactionWindow = uint32(100)
 type OffersProcessor struct {
 	offersQ  history.QOffers
 	sequence uint32
+	versions StateVersions
 
 	cache *ingest.ChangeCompactor
 }
 
-func NewOffersProcessor(offersQ history.QOffers, sequence uint32) *OffersProcessor {
-	p := &OffersProcessor{offersQ: offersQ, sequence: sequence}
+func NewOffersProcessor(offersQ history.QOffers, sequence uint32, versions StateVersions) *OffersProcessor {
+	p := &OffersProcessor{offersQ: offersQ, sequence: sequence, versions: versions}
 	p.reset()
 	return p
 }
@@ -86,6 +87,16 @@ func (p *OffersProcessor) flushCache(ctx context.Context) error {
 		}
 	}
 
+	if p.versions.enabled() && len(batchUpsertOffers) > 0 {
+		ids := make([]int64, 0, len(batchUpsertOffers))
+		for _, row := range batchUpsertOffers {
+			ids = append(ids, row.OfferID)
+		}
+		if err := p.versions.Q.ArchiveOffers(ctx, ids, p.versions.Sequence); err != nil {
+			return errors.Wrap(err, "errors in ArchiveOffers")
+		}
+	}
+
 	if len(batchUpsertOffers) > 0 {
 		err := p.offersQ.UpsertOffers(ctx, batchUpsertOffers)
 		if err != nil {
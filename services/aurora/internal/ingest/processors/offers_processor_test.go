@@ -32,7 +32,7 @@ func (s *OffersProcessorTestSuiteState) SetupTest() {
 	s.mockQ = &history.MockQOffers{}
 
 	s.sequence = 456
-	s.processor = NewOffersProcessor(s.mockQ, s.sequence)
+	s.processor = NewOffersProcessor(s.mockQ, s.sequence, StateVersions{})
 }
 
 func (s *OffersProcessorTestSuiteState) TearDownTest() {
@@ -93,7 +93,7 @@ func (s *OffersProcessorTestSuiteLedger) SetupTest() {
 	s.mockQ = &history.MockQOffers{}
 
 	s.sequence = 456
-	s.processor = NewOffersProcessor(s.mockQ, s.sequence)
+	s.processor = NewOffersProcessor(s.mockQ, s.sequence, StateVersions{})
 }
 
 func (s *OffersProcessorTestSuiteLedger) TearDownTest() {
@@ -3,6 +3,7 @@ package processors
 import (
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 
@@ -77,6 +78,61 @@ func (p *OperationProcessor) Commit(ctx context.Context) error {
 	return p.batch.Exec(ctx)
 }
 
+// OperationRows returns the rows of the history_operations table of the
+// operations of a transaction, as returned by the history queries. The details
+// of liquidity pool deposits and withdrawals are only known from the meta of
+// the transaction, they are left empty if it doesn't contain the changes of
+// the operation.
+func OperationRows(transaction ingest.LedgerTransaction, sequence uint32) ([]history.Operation, error) {
+	hash := hex.EncodeToString(transaction.Result.TransactionHash[:])
+	txResult, err := xdr.MarshalBase64(transaction.Result.Result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error encoding result of transaction %s", hash)
+	}
+
+	var rows []history.Operation
+	for i, op := range transaction.Envelope.Operations() {
+		operation := transactionOperationWrapper{
+			index:          uint32(i),
+			transaction:    transaction,
+			operation:      op,
+			ledgerSequence: sequence,
+		}
+		var detailsString null.String
+		details, err := operation.Details()
+		switch {
+		case errors.Cause(err) == errLiquidityPoolChangeNotFound:
+		case err != nil:
+			return nil, errors.Wrapf(err, "Error obtaining details for operation %v", operation.ID())
+		default:
+			detailsJSON, err := json.Marshal(details)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error marshaling details for operation %v", operation.ID())
+			}
+			detailsString = null.StringFrom(string(detailsJSON))
+		}
+
+		source := operation.SourceAccount()
+		acID := source.ToAccountId()
+		row := history.Operation{
+			TransactionID:         operation.TransactionID(),
+			TransactionHash:       hash,
+			TxResult:              txResult,
+			ApplicationOrder:      int32(operation.Order()),
+			Type:                  operation.OperationType(),
+			DetailsString:         detailsString,
+			SourceAccount:         acID.Address(),
+			TransactionSuccessful: transaction.Result.Successful(),
+		}
+		row.ID = operation.ID()
+		if source.Type == xdr.CryptoKeyTypeKeyTypeMuxedEd25519 {
+			row.SourceAccountMuxed = null.StringFrom(source.Address())
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
 // transactionOperationWrapper represents the data for a single operation within a transaction
 type transactionOperationWrapper struct {
 	index          uint32
@@ -824,6 +880,47 @@ func (operation *transactionOperationWrapper) Participants() ([]xdr.AccountId, e
 	return dedupeParticipants(participants), nil
 }
 
+// MuxedParticipants returns the M-addresses of the muxed accounts taking part
+// in the operation: its source account and the accounts receiving or losing
+// funds, when they are muxed.
+func (operation *transactionOperationWrapper) MuxedParticipants() []string {
+	accounts := []xdr.MuxedAccount{*operation.SourceAccount()}
+	op := operation.operation
+
+	switch operation.OperationType() {
+	case xdr.OperationTypePayment:
+		accounts = append(accounts, op.Body.MustPaymentOp().Destination)
+	case xdr.OperationTypePathPaymentStrictReceive:
+		accounts = append(accounts, op.Body.MustPathPaymentStrictReceiveOp().Destination)
+	case xdr.OperationTypePathPaymentStrictSend:
+		accounts = append(accounts, op.Body.MustPathPaymentStrictSendOp().Destination)
+	case xdr.OperationTypeAccountMerge:
+		accounts = append(accounts, op.Body.MustDestination())
+	case xdr.OperationTypeClawback:
+		accounts = append(accounts, op.Body.MustClawbackOp().From)
+	}
+
+	return distinctMuxedAddresses(accounts)
+}
+
+// distinctMuxedAddresses returns the distinct M-addresses of the muxed accounts in
+// `accounts`, ignoring the G-addresses.
+func distinctMuxedAddresses(accounts []xdr.MuxedAccount) []string {
+	var out []string
+	set := map[string]struct{}{}
+	for _, account := range accounts {
+		if account.Type != xdr.CryptoKeyTypeKeyTypeMuxedEd25519 {
+			continue
+		}
+		address := account.Address()
+		if _, ok := set[address]; !ok {
+			set[address] = struct{}{}
+			out = append(out, address)
+		}
+	}
+	return out
+}
+
 // dedupeParticipants remove any duplicate ids from `in`
 func dedupeParticipants(in []xdr.AccountId) (out []xdr.AccountId) {
 	set := map[string]xdr.AccountId{}
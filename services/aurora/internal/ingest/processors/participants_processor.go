@@ -17,13 +17,17 @@ type ParticipantsProcessor struct {
 	participantsQ  history.QParticipants
 	sequence       uint32
 	participantSet map[string]participant
+	// muxedParticipantSet is keyed by M-address, the accountID of its
+	// participants is the id in history_muxed_accounts
+	muxedParticipantSet map[string]participant
 }
 
 func NewParticipantsProcessor(participantsQ history.QParticipants, sequence uint32) *ParticipantsProcessor {
 	return &ParticipantsProcessor{
-		participantsQ:  participantsQ,
-		sequence:       sequence,
-		participantSet: map[string]participant{},
+		participantsQ:       participantsQ,
+		sequence:            sequence,
+		participantSet:      map[string]participant{},
+		muxedParticipantSet: map[string]participant{},
 	}
 }
 
@@ -72,6 +76,31 @@ func (p *ParticipantsProcessor) loadAccountIDs(ctx context.Context, participantS
 	return nil
 }
 
+func (p *ParticipantsProcessor) loadMuxedAccountIDs(ctx context.Context, participantSet map[string]participant) error {
+	addresses := make([]string, 0, len(participantSet))
+	for address := range participantSet {
+		addresses = append(addresses, address)
+	}
+
+	addressToID, err := p.participantsQ.CreateMuxedAccounts(ctx, addresses, maxBatchSize)
+	if err != nil {
+		return errors.Wrap(err, "Could not create muxed account ids")
+	}
+
+	for _, address := range addresses {
+		id, ok := addressToID[address]
+		if !ok {
+			return errors.Errorf("no id found for muxed account address %s", address)
+		}
+
+		participantForAddress := participantSet[address]
+		participantForAddress.accountID = id
+		participantSet[address] = participantForAddress
+	}
+
+	return nil
+}
+
 func participantsForChanges(
 	changes xdr.LedgerEntryChanges,
 ) ([]xdr.AccountId, error) {
@@ -227,9 +256,46 @@ func (p *ParticipantsProcessor) addOperationsParticipants(
 	return nil
 }
 
-func (p *ParticipantsProcessor) insertDBTransactionParticipants(ctx context.Context, participantSet map[string]participant) error {
-	batch := p.participantsQ.NewTransactionParticipantsBatchInsertBuilder(maxBatchSize)
+// addMuxedParticipants adds the muxed accounts taking part in the transaction
+// and its operations: the muxed sources of the transaction, the fee bump and
+// the operations and the muxed accounts receiving or losing funds.
+func (p *ParticipantsProcessor) addMuxedParticipants(
+	participantSet map[string]participant,
+	sequence uint32,
+	transaction ingest.LedgerTransaction,
+) {
+	transactionID := toid.New(int32(sequence), int32(transaction.Index), 0).ToInt64()
+	sources := []xdr.MuxedAccount{transaction.Envelope.SourceAccount()}
+	if transaction.Envelope.IsFeeBump() {
+		sources = append(sources, transaction.Envelope.FeeBumpAccount())
+	}
+	for _, address := range distinctMuxedAddresses(sources) {
+		entry := participantSet[address]
+		entry.addTransactionID(transactionID)
+		participantSet[address] = entry
+	}
 
+	for opi, op := range transaction.Envelope.Operations() {
+		operation := transactionOperationWrapper{
+			index:          uint32(opi),
+			transaction:    transaction,
+			operation:      op,
+			ledgerSequence: sequence,
+		}
+		for _, address := range operation.MuxedParticipants() {
+			entry := participantSet[address]
+			entry.addTransactionID(transactionID)
+			entry.addOperationID(operation.ID())
+			participantSet[address] = entry
+		}
+	}
+}
+
+func (p *ParticipantsProcessor) insertDBTransactionParticipants(
+	ctx context.Context,
+	batch history.TransactionParticipantsBatchInsertBuilder,
+	participantSet map[string]participant,
+) error {
 	for _, entry := range participantSet {
 		for transactionID := range entry.transactionSet {
 			if err := batch.Add(ctx, transactionID, entry.accountID); err != nil {
@@ -244,9 +310,11 @@ func (p *ParticipantsProcessor) insertDBTransactionParticipants(ctx context.Cont
 	return nil
 }
 
-func (p *ParticipantsProcessor) insertDBOperationsParticipants(ctx context.Context, participantSet map[string]participant) error {
-	batch := p.participantsQ.NewOperationParticipantBatchInsertBuilder(maxBatchSize)
-
+func (p *ParticipantsProcessor) insertDBOperationsParticipants(
+	ctx context.Context,
+	batch history.OperationParticipantBatchInsertBuilder,
+	participantSet map[string]participant,
+) error {
 	for _, entry := range participantSet {
 		for operationID := range entry.operationSet {
 			if err := batch.Add(ctx, operationID, entry.accountID); err != nil {
@@ -272,6 +340,7 @@ func (p *ParticipantsProcessor) ProcessTransaction(ctx context.Context, transact
 		return err
 	}
 
+	p.addMuxedParticipants(p.muxedParticipantSet, p.sequence, transaction)
 	return nil
 }
 
@@ -281,11 +350,29 @@ func (p *ParticipantsProcessor) Commit(ctx context.Context) (err error) {
 			return err
 		}
 
-		if err = p.insertDBTransactionParticipants(ctx, p.participantSet); err != nil {
+		transactionBatch := p.participantsQ.NewTransactionParticipantsBatchInsertBuilder(maxBatchSize)
+		if err = p.insertDBTransactionParticipants(ctx, transactionBatch, p.participantSet); err != nil {
+			return err
+		}
+
+		operationBatch := p.participantsQ.NewOperationParticipantBatchInsertBuilder(maxBatchSize)
+		if err = p.insertDBOperationsParticipants(ctx, operationBatch, p.participantSet); err != nil {
+			return err
+		}
+	}
+
+	if len(p.muxedParticipantSet) > 0 {
+		if err = p.loadMuxedAccountIDs(ctx, p.muxedParticipantSet); err != nil {
+			return err
+		}
+
+		transactionBatch := p.participantsQ.NewTransactionMuxedParticipantsBatchInsertBuilder(maxBatchSize)
+		if err = p.insertDBTransactionParticipants(ctx, transactionBatch, p.muxedParticipantSet); err != nil {
 			return err
 		}
 
-		if err = p.insertDBOperationsParticipants(ctx, p.participantSet); err != nil {
+		operationBatch := p.participantsQ.NewOperationMuxedParticipantsBatchInsertBuilder(maxBatchSize)
+		if err = p.insertDBOperationsParticipants(ctx, operationBatch, p.muxedParticipantSet); err != nil {
 			return err
 		}
 	}
@@ -361,3 +361,65 @@ func (s *ParticipantsProcessorTestSuiteLedger) TestOpeartionBatchAddExecFails()
 	err := s.processor.Commit(s.ctx)
 	s.Assert().EqualError(err, "could not flush operation participants to db: transient error")
 }
+
+func (s *ParticipantsProcessorTestSuiteLedger) TestMuxedParticipants() {
+	source, err := xdr.MuxedAccountFromAccountId(s.addresses[0], 1)
+	s.Assert().NoError(err)
+	destination, err := xdr.MuxedAccountFromAccountId(s.addresses[1], 2)
+	s.Assert().NoError(err)
+
+	tx := createTransaction(true, 2)
+	tx.Index = 1
+	tx.Envelope.V1.Tx.SourceAccount = source
+	tx.Envelope.Operations()[0].Body = xdr.OperationBody{
+		Type:      xdr.OperationTypePayment,
+		PaymentOp: &xdr.PaymentOp{Destination: destination},
+	}
+	// The source of the second operation is not muxed
+	otherSource := xdr.MustMuxedAddress(s.addresses[2])
+	tx.Envelope.Operations()[1].SourceAccount = &otherSource
+	txID := toid.New(20, 1, 0).ToInt64()
+
+	addressToID := map[string]int64{
+		s.addresses[0]: s.addressToID[s.addresses[0]],
+		s.addresses[1]: s.addressToID[s.addresses[1]],
+		s.addresses[2]: s.addressToID[s.addresses[2]],
+	}
+	s.mockQ.On("CreateAccounts", s.ctx, mock.AnythingOfType("[]string"), maxBatchSize).
+		Return(addressToID, nil).Once()
+	s.mockQ.On("NewTransactionParticipantsBatchInsertBuilder", maxBatchSize).
+		Return(s.mockBatchInsertBuilder).Once()
+	s.mockQ.On("NewOperationParticipantBatchInsertBuilder", maxBatchSize).
+		Return(s.mockOperationsBatchInsertBuilder).Once()
+	s.mockBatchInsertBuilder.On("Add", s.ctx, txID, mock.AnythingOfType("int64")).Return(nil).Times(3)
+	s.mockOperationsBatchInsertBuilder.On("Add", s.ctx, txID+1, mock.AnythingOfType("int64")).Return(nil).Twice()
+	s.mockOperationsBatchInsertBuilder.On("Add", s.ctx, txID+2, addressToID[s.addresses[2]]).Return(nil).Once()
+	s.mockBatchInsertBuilder.On("Exec", s.ctx).Return(nil).Once()
+	s.mockOperationsBatchInsertBuilder.On("Exec", s.ctx).Return(nil).Once()
+
+	muxedBatchInsertBuilder := &history.MockTransactionParticipantsBatchInsertBuilder{}
+	muxedOperationsBatchInsertBuilder := &history.MockOperationParticipantBatchInsertBuilder{}
+	s.mockQ.On("CreateMuxedAccounts", s.ctx, mock.AnythingOfType("[]string"), maxBatchSize).
+		Run(func(args mock.Arguments) {
+			s.Assert().ElementsMatch(
+				[]string{source.Address(), destination.Address()},
+				args.Get(1).([]string),
+			)
+		}).Return(map[string]int64{source.Address(): 5, destination.Address(): 6}, nil).Once()
+	s.mockQ.On("NewTransactionMuxedParticipantsBatchInsertBuilder", maxBatchSize).
+		Return(muxedBatchInsertBuilder).Once()
+	s.mockQ.On("NewOperationMuxedParticipantsBatchInsertBuilder", maxBatchSize).
+		Return(muxedOperationsBatchInsertBuilder).Once()
+	muxedBatchInsertBuilder.On("Add", s.ctx, txID, int64(5)).Return(nil).Once()
+	muxedBatchInsertBuilder.On("Add", s.ctx, txID, int64(6)).Return(nil).Once()
+	muxedBatchInsertBuilder.On("Exec", s.ctx).Return(nil).Once()
+	// The muxed source of the transaction is the source of the first operation
+	muxedOperationsBatchInsertBuilder.On("Add", s.ctx, txID+1, int64(5)).Return(nil).Once()
+	muxedOperationsBatchInsertBuilder.On("Add", s.ctx, txID+1, int64(6)).Return(nil).Once()
+	muxedOperationsBatchInsertBuilder.On("Exec", s.ctx).Return(nil).Once()
+
+	s.Assert().NoError(s.processor.ProcessTransaction(s.ctx, tx))
+	s.Assert().NoError(s.processor.Commit(s.ctx))
+	muxedBatchInsertBuilder.AssertExpectations(s.T())
+	muxedOperationsBatchInsertBuilder.AssertExpectations(s.T())
+}
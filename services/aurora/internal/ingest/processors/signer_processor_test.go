@@ -36,7 +36,7 @@ func (s *AccountsSignerProcessorTestSuiteState) SetupTest() {
 		On("NewAccountSignersBatchInsertBuilder", maxBatchSize).
 		Return(s.mockBatchInsertBuilder).Once()
 
-	s.processor = NewSignersProcessor(s.mockQ, false)
+	s.processor = NewSignersProcessor(s.mockQ, false, StateVersions{})
 }
 
 func (s *AccountsSignerProcessorTestSuiteState) TearDownTest() {
@@ -168,7 +168,7 @@ func (s *AccountsSignerProcessorTestSuiteLedger) SetupTest() {
 		On("NewAccountSignersBatchInsertBuilder", maxBatchSize).
 		Return(&history.MockAccountSignersBatchInsertBuilder{}).Once()
 
-	s.processor = NewSignersProcessor(s.mockQ, true)
+	s.processor = NewSignersProcessor(s.mockQ, true, StateVersions{})
 }
 
 func (s *AccountsSignerProcessorTestSuiteLedger) TearDownTest() {
@@ -13,6 +13,7 @@ import (
 
 type SignersProcessor struct {
 	signersQ history.QSigners
+	versions StateVersions
 
 	cache *ingest.ChangeCompactor
 	batch history.AccountSignersBatchInsertBuilder
@@ -23,9 +24,13 @@ type SignersProcessor struct {
 }
 
 func NewSignersProcessor(
-	signersQ history.QSigners, useLedgerEntryCache bool,
+	signersQ history.QSigners, useLedgerEntryCache bool, versions StateVersions,
 ) *SignersProcessor {
-	p := &SignersProcessor{signersQ: signersQ, useLedgerEntryCache: useLedgerEntryCache}
+	p := &SignersProcessor{
+		signersQ:            signersQ,
+		useLedgerEntryCache: useLedgerEntryCache,
+		versions:            versions,
+	}
 	p.reset()
 	return p
 }
@@ -91,6 +96,12 @@ func (p *SignersProcessor) Commit(ctx context.Context) error {
 	}
 
 	changes := p.cache.GetChanges()
+	if p.versions.enabled() {
+		if err := p.archiveSigners(ctx, changes); err != nil {
+			return err
+		}
+	}
+
 	for _, change := range changes {
 		if !change.AccountSignersChanged() {
 			continue
@@ -155,3 +166,21 @@ func (p *SignersProcessor) Commit(ctx context.Context) error {
 
 	return nil
 }
+
+func (p *SignersProcessor) archiveSigners(ctx context.Context, changes []ingest.Change) error {
+	var accountIDs []string
+	for _, change := range changes {
+		if change.Pre != nil && change.AccountSignersChanged() {
+			accountIDs = append(accountIDs, change.Pre.Data.MustAccount().AccountId.Address())
+		}
+	}
+
+	if len(accountIDs) == 0 {
+		return nil
+	}
+
+	if err := p.versions.Q.ArchiveAccountSigners(ctx, accountIDs, p.versions.Sequence); err != nil {
+		return errors.Wrap(err, "Error archiving signers")
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package processors
+
+import (
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+)
+
+// StateVersions configures the change processors of the state tables to
+// archive the rows they are about to update or remove in the ledger with the
+// given sequence, which makes the state as of earlier ledgers queryable. The
+// zero value disables archiving.
+type StateVersions struct {
+	Q        history.QStateVersions
+	Sequence uint32
+}
+
+func (v StateVersions) enabled() bool {
+	return v.Q != nil
+}
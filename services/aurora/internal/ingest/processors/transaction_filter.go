@@ -0,0 +1,274 @@
+package processors
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/protocols/aurora/operations"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// Names of the transaction filters.
+const (
+	AccountsFilter       = "accounts"
+	AssetsFilter         = "assets"
+	OperationTypesFilter = "operation_types"
+)
+
+// TransactionFilterRules contains the allow-lists of the transaction filters:
+// account addresses (G...), assets ("native" or "CODE:ISSUER") and operation
+// types (as named in the operation resources, ex. "payment"). An empty
+// allow-list does not restrict anything.
+type TransactionFilterRules struct {
+	Accounts       []string
+	Assets         []string
+	OperationTypes []string
+}
+
+// Empty returns true if none of the filters restricts anything.
+func (r TransactionFilterRules) Empty() bool {
+	return len(r.Accounts) == 0 && len(r.Assets) == 0 && len(r.OperationTypes) == 0
+}
+
+// Set replaces the allow-list of the filter with the given name.
+func (r *TransactionFilterRules) Set(name string, allowed []string) error {
+	switch name {
+	case AccountsFilter:
+		r.Accounts = allowed
+	case AssetsFilter:
+		r.Assets = allowed
+	case OperationTypesFilter:
+		r.OperationTypes = allowed
+	default:
+		return errors.Errorf("unknown transaction filter %s", name)
+	}
+	return nil
+}
+
+// TransactionFilter selects the transactions whose history (transactions,
+// operations, effects, trades and participants) is ingested. A transaction
+// is accepted when it involves one of the allowed accounts or one of the
+// allowed assets, and contains an operation of one of the allowed types.
+// Filters with an empty allow-list are skipped.
+//
+// The filter does not apply to the state tables (accounts, offers, trust
+// lines...) which always reflect the whole ledger.
+type TransactionFilter struct {
+	accounts       map[string]bool
+	assets         map[string]bool
+	operationTypes map[xdr.OperationType]bool
+}
+
+// NewTransactionFilter validates the rules and returns the corresponding
+// filter, which is nil if none of the rules restricts anything.
+func NewTransactionFilter(rules TransactionFilterRules) (*TransactionFilter, error) {
+	if rules.Empty() {
+		return nil, nil
+	}
+
+	filter := &TransactionFilter{
+		accounts:       map[string]bool{},
+		assets:         map[string]bool{},
+		operationTypes: map[xdr.OperationType]bool{},
+	}
+
+	for _, address := range rules.Accounts {
+		accountID, err := xdr.AddressToAccountId(address)
+		if err != nil {
+			return nil, errors.Errorf("%s is not a valid account address", address)
+		}
+		filter.accounts[accountID.Address()] = true
+	}
+
+	for _, value := range rules.Assets {
+		if strings.Contains(value, ",") {
+			return nil, errors.Errorf("%s is not a valid asset", value)
+		}
+		assets, err := xdr.BuildAssets(value)
+		if err != nil || len(assets) != 1 {
+			return nil, errors.Errorf("%s is not a valid asset", value)
+		}
+		filter.assets[assets[0].StringCanonical()] = true
+	}
+
+	for _, name := range rules.OperationTypes {
+		operationType, ok := operationTypesByName[name]
+		if !ok {
+			return nil, errors.Errorf("%s is not a valid operation type", name)
+		}
+		filter.operationTypes[operationType] = true
+	}
+
+	return filter, nil
+}
+
+// Rules returns the allow-lists of the filter, sorted.
+func (f *TransactionFilter) Rules() TransactionFilterRules {
+	var rules TransactionFilterRules
+	if f == nil {
+		return rules
+	}
+	for address := range f.accounts {
+		rules.Accounts = append(rules.Accounts, address)
+	}
+	for asset := range f.assets {
+		rules.Assets = append(rules.Assets, asset)
+	}
+	for operationType := range f.operationTypes {
+		rules.OperationTypes = append(rules.OperationTypes, operations.TypeNames[operationType])
+	}
+	sort.Strings(rules.Accounts)
+	sort.Strings(rules.Assets)
+	sort.Strings(rules.OperationTypes)
+	return rules
+}
+
+// Match returns true if the transaction, included in the ledger with the
+// given sequence, is accepted by the filter.
+func (f *TransactionFilter) Match(sequence uint32, transaction ingest.LedgerTransaction) (bool, error) {
+	if len(f.operationTypes) > 0 {
+		found := false
+		for _, op := range transaction.Envelope.Operations() {
+			if f.operationTypes[op.Body.Type] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	if len(f.accounts) == 0 && len(f.assets) == 0 {
+		return true, nil
+	}
+
+	if len(f.accounts) > 0 {
+		participants, err := participantsForTransaction(sequence, transaction)
+		if err != nil {
+			return false, errors.Wrap(err, "could not determine transaction participants")
+		}
+		for _, participant := range participants {
+			if f.accounts[participant.Address()] {
+				return true, nil
+			}
+		}
+	}
+
+	if len(f.assets) > 0 {
+		assets, err := transactionAssets(transaction)
+		if err != nil {
+			return false, errors.Wrap(err, "could not determine transaction assets")
+		}
+		for _, asset := range assets {
+			if f.assets[asset.StringCanonical()] {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+var operationTypesByName = func() map[string]xdr.OperationType {
+	m := make(map[string]xdr.OperationType, len(operations.TypeNames))
+	for operationType, name := range operations.TypeNames {
+		m[name] = operationType
+	}
+	return m
+}()
+
+// transactionAssets returns the assets used by the operations of a
+// transaction and the assets of the ledger entries it changed, which covers
+// the operations whose assets are not part of the operation body (ex.
+// claiming a claimable balance or depositing into a liquidity pool).
+func transactionAssets(transaction ingest.LedgerTransaction) ([]xdr.Asset, error) {
+	var assets []xdr.Asset
+	for _, op := range transaction.Envelope.Operations() {
+		source := transaction.Envelope.SourceAccount()
+		if op.SourceAccount != nil {
+			source = *op.SourceAccount
+		}
+		assets = append(assets, operationAssets(source.ToAccountId(), op)...)
+	}
+
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		if change.Pre != nil {
+			assets = append(assets, ledgerEntryAssets(change.Pre.Data)...)
+		}
+		if change.Post != nil {
+			assets = append(assets, ledgerEntryAssets(change.Post.Data)...)
+		}
+	}
+
+	return assets, nil
+}
+
+func operationAssets(source xdr.AccountId, op xdr.Operation) []xdr.Asset {
+	switch op.Body.Type {
+	case xdr.OperationTypeCreateAccount, xdr.OperationTypeAccountMerge, xdr.OperationTypeInflation:
+		return []xdr.Asset{xdr.MustNewNativeAsset()}
+	case xdr.OperationTypePayment:
+		return []xdr.Asset{op.Body.MustPaymentOp().Asset}
+	case xdr.OperationTypePathPaymentStrictReceive:
+		body := op.Body.MustPathPaymentStrictReceiveOp()
+		return append([]xdr.Asset{body.SendAsset, body.DestAsset}, body.Path...)
+	case xdr.OperationTypePathPaymentStrictSend:
+		body := op.Body.MustPathPaymentStrictSendOp()
+		return append([]xdr.Asset{body.SendAsset, body.DestAsset}, body.Path...)
+	case xdr.OperationTypeManageSellOffer:
+		body := op.Body.MustManageSellOfferOp()
+		return []xdr.Asset{body.Selling, body.Buying}
+	case xdr.OperationTypeManageBuyOffer:
+		body := op.Body.MustManageBuyOfferOp()
+		return []xdr.Asset{body.Selling, body.Buying}
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		body := op.Body.MustCreatePassiveSellOfferOp()
+		return []xdr.Asset{body.Selling, body.Buying}
+	case xdr.OperationTypeChangeTrust:
+		line := op.Body.MustChangeTrustOp().Line
+		if line.Type == xdr.AssetTypeAssetTypePoolShare {
+			params := line.MustLiquidityPool().MustConstantProduct()
+			return []xdr.Asset{params.AssetA, params.AssetB}
+		}
+		return []xdr.Asset{line.ToAsset()}
+	case xdr.OperationTypeAllowTrust:
+		// The issuer of the asset is the source of the operation
+		return []xdr.Asset{op.Body.MustAllowTrustOp().Asset.ToAsset(source)}
+	case xdr.OperationTypeCreateClaimableBalance:
+		return []xdr.Asset{op.Body.MustCreateClaimableBalanceOp().Asset}
+	case xdr.OperationTypeClawback:
+		return []xdr.Asset{op.Body.MustClawbackOp().Asset}
+	case xdr.OperationTypeSetTrustLineFlags:
+		return []xdr.Asset{op.Body.MustSetTrustLineFlagsOp().Asset}
+	default:
+		return nil
+	}
+}
+
+func ledgerEntryAssets(data xdr.LedgerEntryData) []xdr.Asset {
+	switch data.Type {
+	case xdr.LedgerEntryTypeTrustline:
+		asset := data.MustTrustLine().Asset
+		if asset.Type == xdr.AssetTypeAssetTypePoolShare {
+			return nil
+		}
+		return []xdr.Asset{asset.ToAsset()}
+	case xdr.LedgerEntryTypeOffer:
+		offer := data.MustOffer()
+		return []xdr.Asset{offer.Selling, offer.Buying}
+	case xdr.LedgerEntryTypeClaimableBalance:
+		return []xdr.Asset{data.MustClaimableBalance().Asset}
+	case xdr.LedgerEntryTypeLiquidityPool:
+		params := data.MustLiquidityPool().Body.MustConstantProduct().Params
+		return []xdr.Asset{params.AssetA, params.AssetB}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,93 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/diamnet/go/xdr"
+)
+
+func TestNewTransactionFilter(t *testing.T) {
+	filter, err := NewTransactionFilter(TransactionFilterRules{})
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+
+	for _, rules := range []TransactionFilterRules{
+		{Accounts: []string{"GABC"}},
+		{Assets: []string{"USD"}},
+		{Assets: []string{"native,native"}},
+		{OperationTypes: []string{"bump"}},
+	} {
+		_, err = NewTransactionFilter(rules)
+		assert.Error(t, err)
+	}
+
+	issuer := "GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY"
+	filter, err = NewTransactionFilter(TransactionFilterRules{
+		Accounts:       []string{issuer},
+		Assets:         []string{"USD:" + issuer, "NATIVE"},
+		OperationTypes: []string{"payment", "bump_sequence"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, TransactionFilterRules{
+		Accounts:       []string{issuer},
+		Assets:         []string{"USD:" + issuer, "native"},
+		OperationTypes: []string{"bump_sequence", "payment"},
+	}, filter.Rules())
+
+	var rules TransactionFilterRules
+	assert.NoError(t, rules.Set(AssetsFilter, []string{"native"}))
+	assert.Equal(t, []string{"native"}, rules.Assets)
+	assert.EqualError(t, rules.Set("ledgers", nil), "unknown transaction filter ledgers")
+}
+
+func TestTransactionFilterMatch(t *testing.T) {
+	source := "GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY"
+	destination := "GAXI33UCLQTCKM2NMRBS7XYBR535LLEVAHL5YBN4FTCB4HZHT7ZA5CVK"
+	other := "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+
+	bumpSequence := createTransaction(true, 1)
+	payment := createTransaction(true, 1)
+	payment.Envelope.Operations()[0].Body = xdr.OperationBody{
+		Type: xdr.OperationTypePayment,
+		PaymentOp: &xdr.PaymentOp{
+			Destination: xdr.MustMuxedAddress(destination),
+			Asset:       xdr.MustNewCreditAsset("USD", other),
+			Amount:      100,
+		},
+	}
+
+	for _, testCase := range []struct {
+		name     string
+		rules    TransactionFilterRules
+		expected []bool
+	}{
+		{"source account", TransactionFilterRules{Accounts: []string{source}}, []bool{true, true}},
+		{"payment destination", TransactionFilterRules{Accounts: []string{destination}}, []bool{false, true}},
+		{"payment asset", TransactionFilterRules{Assets: []string{"USD:" + other}}, []bool{false, true}},
+		{"other asset", TransactionFilterRules{Assets: []string{"native"}}, []bool{false, false}},
+		{"account or asset", TransactionFilterRules{
+			Accounts: []string{other},
+			Assets:   []string{"USD:" + other},
+		}, []bool{false, true}},
+		{"operation type", TransactionFilterRules{OperationTypes: []string{"bump_sequence"}}, []bool{true, false}},
+		{"account and operation type", TransactionFilterRules{
+			Accounts:       []string{source},
+			OperationTypes: []string{"payment"},
+		}, []bool{false, true}},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			filter, err := NewTransactionFilter(testCase.rules)
+			assert.NoError(t, err)
+
+			matched, err := filter.Match(20, bumpSequence)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected[0], matched)
+
+			matched, err = filter.Match(20, payment)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected[1], matched)
+		})
+	}
+}
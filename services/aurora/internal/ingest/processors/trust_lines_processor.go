@@ -11,12 +11,13 @@ import (
 
 type TrustLinesProcessor struct {
 	trustLinesQ history.QTrustLines
+	versions    StateVersions
 
 	cache *ingest.ChangeCompactor
 }
 
-func NewTrustLinesProcessor(trustLinesQ history.QTrustLines) *TrustLinesProcessor {
-	p := &TrustLinesProcessor{trustLinesQ: trustLinesQ}
+func NewTrustLinesProcessor(trustLinesQ history.QTrustLines, versions StateVersions) *TrustLinesProcessor {
+	p := &TrustLinesProcessor{trustLinesQ: trustLinesQ, versions: versions}
 	p.reset()
 	return p
 }
@@ -124,6 +125,16 @@ func (p *TrustLinesProcessor) Commit(ctx context.Context) error {
 		}
 	}
 
+	if p.versions.enabled() && len(batchUpsertTrustLines)+len(batchRemoveTrustLineKeys) > 0 {
+		ledgerKeys := append([]string{}, batchRemoveTrustLineKeys...)
+		for _, tl := range batchUpsertTrustLines {
+			ledgerKeys = append(ledgerKeys, tl.LedgerKey)
+		}
+		if err := p.versions.Q.ArchiveTrustLines(ctx, ledgerKeys, p.versions.Sequence); err != nil {
+			return errors.Wrap(err, "errors in ArchiveTrustLines")
+		}
+	}
+
 	if len(batchUpsertTrustLines) > 0 {
 		err := p.trustLinesQ.UpsertTrustLines(ctx, batchUpsertTrustLines)
 		if err != nil {
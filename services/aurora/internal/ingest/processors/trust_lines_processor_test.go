@@ -32,7 +32,7 @@ type TrustLinesProcessorTestSuiteState struct {
 func (s *TrustLinesProcessorTestSuiteState) SetupTest() {
 	s.ctx = context.Background()
 	s.mockQ = &history.MockQTrustLines{}
-	s.processor = NewTrustLinesProcessor(s.mockQ)
+	s.processor = NewTrustLinesProcessor(s.mockQ, StateVersions{})
 }
 
 func (s *TrustLinesProcessorTestSuiteState) TearDownTest() {
@@ -179,7 +179,7 @@ type TrustLinesProcessorTestSuiteLedger struct {
 func (s *TrustLinesProcessorTestSuiteLedger) SetupTest() {
 	s.ctx = context.Background()
 	s.mockQ = &history.MockQTrustLines{}
-	s.processor = NewTrustLinesProcessor(s.mockQ)
+	s.processor = NewTrustLinesProcessor(s.mockQ, StateVersions{})
 }
 
 func (s *TrustLinesProcessorTestSuiteLedger) TearDownTest() {
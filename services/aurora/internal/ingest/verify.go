@@ -34,6 +34,9 @@ const stateVerifierExpectedIngestionVersion = 15
 // verifyState is called as a go routine from pipeline post hook every 64
 // ledgers. It checks if the state is correct. If another go routine is already
 // running it exits.
+// The transaction filters (Config.TransactionFilters) only restrict the history
+// tables, the state tables always contain every ledger entry so they can be
+// checked against the history archives whatever the filters are.
 func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 	s.stateVerificationMutex.Lock()
 	if s.stateVerificationRunning {
@@ -11,6 +11,7 @@ import (
 	"github.com/diamnet/go/ingest"
 	"github.com/diamnet/go/randxdr"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
 	"github.com/diamnet/go/services/aurora/internal/test"
 	"github.com/diamnet/go/support/db"
 	"github.com/diamnet/go/xdr"
@@ -161,7 +162,7 @@ func TestStateVerifier(t *testing.T) {
 	q := &history.Q{&db.Session{DB: tt.AuroraDB}}
 
 	checkpointLedger := uint32(63)
-	changeProcessor := buildChangeProcessor(q, &ingest.StatsChangeProcessor{}, ledgerSource, checkpointLedger)
+	changeProcessor := buildChangeProcessor(q, &ingest.StatsChangeProcessor{}, ledgerSource, checkpointLedger, processors.StateVersions{})
 	mockChangeReader := &ingest.MockChangeReader{}
 
 	gen := randxdr.NewGenerator()
@@ -8,8 +8,12 @@ import (
 	"github.com/getsentry/raven-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/diamnet/go/exp/orderbook"
+	"github.com/diamnet/go/historyarchive"
+	"github.com/diamnet/go/services/aurora/internal/coldstorage"
 	"github.com/diamnet/go/services/aurora/internal/db2/history"
 	"github.com/diamnet/go/services/aurora/internal/ingest"
+	"github.com/diamnet/go/services/aurora/internal/ingest/export"
+	"github.com/diamnet/go/services/aurora/internal/ingest/processors"
 	"github.com/diamnet/go/services/aurora/internal/simplepath"
 	"github.com/diamnet/go/services/aurora/internal/txsub"
 	"github.com/diamnet/go/services/aurora/internal/txsub/sequence"
@@ -42,7 +46,9 @@ func mustInitAuroraDB(app *App) {
 		}
 	}
 
-	if app.config.RoDatabaseURL == "" {
+	if len(app.config.RoDatabaseURLs) > 0 {
+		mustInitReplicaPool(app, maxIdle, maxOpen)
+	} else if app.config.RoDatabaseURL == "" {
 		app.historyQ = &history.Q{mustNewDBSession(
 			db.HistorySubservice,
 			app.config.DatabaseURL,
@@ -70,18 +76,68 @@ func mustInitAuroraDB(app *App) {
 	}
 }
 
+// mustInitReplicaPool routes history queries across the read replicas. The
+// primary database is used for the other queries and when none of the
+// replicas is available.
+func mustInitReplicaPool(app *App, maxIdle, maxOpen int) {
+	urls := app.config.RoDatabaseURLs
+	if app.config.RoDatabaseURL != "" {
+		urls = append([]string{app.config.RoDatabaseURL}, urls...)
+	}
+
+	primary := mustNewDBSession(
+		db.HistoryPrimarySubservice,
+		app.config.DatabaseURL,
+		maxIdle,
+		maxOpen,
+		app.prometheusRegistry,
+	)
+	app.historyQ = &history.Q{primary}
+
+	config := db.ReplicaPoolConfig{
+		LatestLedger: func(ctx context.Context, session db.SessionInterface) (uint32, error) {
+			return (&history.Q{session}).GetLatestHistoryLedger(ctx)
+		},
+		Fallback: primary,
+		MaxLag:   uint32(app.config.ReplicaMaxLag),
+	}
+	for i, url := range urls {
+		subservice := db.ReplicaSubservice(db.HistorySubservice, i)
+		config.Replicas = append(config.Replicas, db.Replica{
+			Name:    string(subservice),
+			Session: mustNewDBSession(subservice, url, maxIdle, maxOpen, app.prometheusRegistry),
+		})
+	}
+
+	pool, err := db.NewReplicaPool(config)
+	if err != nil {
+		log.Fatalf("cannot create replica pool: %v", err)
+	}
+	db.RegisterReplicaPoolMetrics(pool, "aurora", db.HistorySubservice, app.prometheusRegistry)
+	pool.Start()
+	app.replicaPool = pool
+}
+
 func initIngester(app *App) {
-	var err error
 	var coreSession db.SessionInterface
 	if !app.config.EnableCaptiveCoreIngestion {
 		coreSession = mustNewDBSession(
 			db.CoreSubservice, app.config.DiamnetCoreDatabaseURL, ingest.MaxDBConnections, ingest.MaxDBConnections, app.prometheusRegistry)
 	}
+	eventSink, err := newEventSink(app.config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	historySession := mustNewDBSession(
+		db.IngestSubservice, app.config.DatabaseURL, ingest.MaxDBConnections, ingest.MaxDBConnections, app.prometheusRegistry,
+	)
+	historyPartitionSize, err := (&history.Q{historySession}).GetHistoryPartitionSize(context.Background())
+	if err != nil {
+		log.Fatalf("cannot get history partition size: %v", err)
+	}
 	app.ingester, err = ingest.NewSystem(ingest.Config{
-		CoreSession: coreSession,
-		HistorySession: mustNewDBSession(
-			db.IngestSubservice, app.config.DatabaseURL, ingest.MaxDBConnections, ingest.MaxDBConnections, app.prometheusRegistry,
-		),
+		CoreSession:       coreSession,
+		HistorySession:    historySession,
 		NetworkPassphrase: app.config.NetworkPassphrase,
 		// TODO:
 		// Use the first archive for now. We don't have a mechanism to
@@ -97,6 +153,15 @@ func initIngester(app *App) {
 		EnableCaptiveCore:            app.config.EnableCaptiveCoreIngestion,
 		DisableStateVerification:     app.config.IngestDisableStateVerification,
 		EnableExtendedLogLedgerStats: app.config.IngestEnableExtendedLogLedgerStats,
+		EnableWebhooks:               app.config.IngestEnableWebhooks,
+		EnableStateVersions:          app.config.IngestEnableStateVersions,
+		TransactionFilters: processors.TransactionFilterRules{
+			Accounts:       app.config.IngestFilterAccounts,
+			Assets:         app.config.IngestFilterAssets,
+			OperationTypes: app.config.IngestFilterOperationTypes,
+		},
+		EventSink:            eventSink,
+		HistoryPartitionSize: historyPartitionSize,
 	})
 
 	if err != nil {
@@ -104,6 +169,65 @@ func initIngester(app *App) {
 	}
 }
 
+// newEventSink returns the sink of the ingested events configured with the
+// --ingest-export-events-* flags, or nil when events are not exported.
+func newEventSink(config Config) (export.Sink, error) {
+	switch {
+	case config.IngestExportEventsFile != "":
+		sink, err := export.NewFileSink(config.IngestExportEventsFile)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	case len(config.IngestExportEventsKafkaBrokers) > 0:
+		sink, err := export.NewKafkaSink(export.KafkaConfig{
+			Brokers:   config.IngestExportEventsKafkaBrokers,
+			Topic:     config.IngestExportEventsKafkaTopic,
+			Partition: int32(config.IngestExportEventsKafkaPartition),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	default:
+		return nil, nil
+	}
+}
+
+// initColdStorage creates the store serving the ledgers removed by the reaper
+// when a cold storage is configured.
+func initColdStorage(app *App) {
+	var backend coldstorage.Backend
+	switch {
+	case app.config.ColdStorageURL != "":
+		fileBackend, err := coldstorage.NewFileBackend(app.config.ColdStorageURL, historyarchive.ConnectOptions{
+			Context:             app.ctx,
+			NetworkPassphrase:   app.config.NetworkPassphrase,
+			CheckpointFrequency: app.config.CheckpointFrequency,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		backend = fileBackend
+	case app.config.ColdStorageHistoryArchive:
+		archive, err := historyarchive.Connect(app.config.HistoryArchiveURLs[0], historyarchive.ConnectOptions{
+			Context:             app.ctx,
+			NetworkPassphrase:   app.config.NetworkPassphrase,
+			CheckpointFrequency: app.config.CheckpointFrequency,
+		})
+		if err != nil {
+			log.Fatalf("cannot connect to history archive: %v", err)
+		}
+		backend = coldstorage.ArchiveBackend{
+			Archive:           archive,
+			NetworkPassphrase: app.config.NetworkPassphrase,
+		}
+	default:
+		return
+	}
+	app.coldStorage = coldstorage.NewStore(backend, int(app.config.ColdStorageCacheSize))
+}
+
 func initPathFinder(app *App) {
 	orderBookGraph := orderbook.NewOrderBookGraph()
 	app.orderBookStream = ingest.NewOrderBookStream(
@@ -111,7 +235,13 @@ func initPathFinder(app *App) {
 		orderBookGraph,
 	)
 
+	if app.config.PathFindingCacheSize > 0 {
+		app.pathCache = orderbook.NewPathCache(app.config.PathFindingCacheSize)
+		orderBookGraph.SetPathCache(app.pathCache)
+	}
+
 	app.paths = simplepath.NewInMemoryFinder(orderBookGraph, !app.config.DisablePoolPathFinding)
+	app.orderBook = orderBookGraph
 }
 
 // initSentry initialized the default sentry client with the configured DSN
@@ -170,6 +300,46 @@ func initDbMetrics(app *App) {
 	app.prometheusRegistry.MustRegister(app.orderBookStream.LatestLedgerGauge)
 }
 
+// initPathFinderMetrics registers the metrics of the path finding cache, if it
+// is enabled.
+func initPathFinderMetrics(app *App) {
+	if app.pathCache == nil {
+		return
+	}
+
+	cache := app.pathCache
+	app.prometheusRegistry.MustRegister(
+		prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace: "aurora", Subsystem: "path_finding", Name: "cache_hits_total",
+				Help: "number of path finding requests served from cached routes",
+			},
+			func() float64 { return float64(cache.Stats().Hits) },
+		),
+		prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace: "aurora", Subsystem: "path_finding", Name: "cache_misses_total",
+				Help: "number of path finding requests which searched the order book graph",
+			},
+			func() float64 { return float64(cache.Stats().Misses) },
+		),
+		prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace: "aurora", Subsystem: "path_finding", Name: "cache_invalidations_total",
+				Help: "number of cached routes invalidated by order book updates",
+			},
+			func() float64 { return float64(cache.Stats().Invalidations) },
+		),
+		prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace: "aurora", Subsystem: "path_finding", Name: "cache_entries",
+				Help: "number of searches in the path finding cache",
+			},
+			func() float64 { return float64(cache.Stats().Entries) },
+		),
+	)
+}
+
 // initGoMetrics registers the Go collector provided by prometheus package which
 // includes Go-related metrics.
 func initGoMetrics(app *App) {
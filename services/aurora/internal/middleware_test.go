@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/diamnet/throttled"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -365,3 +366,89 @@ func TestCheckHistoryStaleMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestReplicaRoutingMiddleware(t *testing.T) {
+	primary := &db.MockSession{}
+	primary.On("Clone").Return(primary)
+	replicas := []*db.MockSession{{}, {}}
+	ledgers := map[db.SessionInterface]uint32{}
+	config := db.ReplicaPoolConfig{
+		LatestLedger: func(ctx context.Context, session db.SessionInterface) (uint32, error) {
+			return ledgers[session], nil
+		},
+	}
+	for i, replica := range replicas {
+		replica.On("Clone").Return(replica)
+		ledgers[replica] = uint32(10 + i)
+		config.Replicas = append(config.Replicas, db.Replica{
+			Name:    strconv.Itoa(i),
+			Session: replica,
+		})
+	}
+	pool, err := db.NewReplicaPool(config)
+	assert.NoError(t, err)
+	pool.Check(context.Background())
+
+	var selected db.SessionInterface
+	endpoint := func(w http.ResponseWriter, r *http.Request) {
+		selected = r.Context().Value(&auroraContext.SessionContextKey).(db.SessionInterface)
+		w.WriteHeader(http.StatusOK)
+	}
+	replicaRoutingMiddleware := &httpx.ReplicaRoutingMiddleware{
+		Pool: pool,
+		ServerMetrics: &httpx.ServerMetrics{
+			ReplicaLagErrorsCounter: prometheus.NewCounter(prometheus.CounterOpts{Name: "replica_lag_errors"}),
+		},
+	}
+	historyMiddleware := httpx.NewHistoryMiddleware(&ledger.State{}, 0, primary)
+	handler := chi.NewRouter()
+	handler.With(replicaRoutingMiddleware.Wrap, historyMiddleware).MethodFunc("GET", "/", endpoint)
+
+	for _, testCase := range []struct {
+		name           string
+		minLedger      string
+		expectedStatus int
+		expected       db.SessionInterface
+	}{
+		{
+			name:           "routes to the replica with the requested ledger",
+			minLedger:      "11",
+			expectedStatus: http.StatusOK,
+			expected:       replicas[1],
+		},
+		{
+			name:           "responds with stale history if no replica has the requested ledger",
+			minLedger:      "12",
+			expectedStatus: hProblem.StaleHistory.Status,
+		},
+		{
+			name:           "responds with bad request if the header is invalid",
+			minLedger:      "latest",
+			expectedStatus: http.StatusBadRequest,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			selected = nil
+			request, err := http.NewRequest("GET", "http://localhost/", nil)
+			assert.NoError(t, err)
+			request.Header.Set("X-Min-Ledger", testCase.minLedger)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request)
+			assert.Equal(t, testCase.expectedStatus, w.Code)
+			assert.True(t, selected == testCase.expected)
+		})
+	}
+
+	// Requests without the header are routed to the replicas in turn
+	seen := map[db.SessionInterface]bool{}
+	for i := 0; i < 4; i++ {
+		request, err := http.NewRequest("GET", "http://localhost/", nil)
+		assert.NoError(t, err)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request)
+		assert.Equal(t, http.StatusOK, w.Code)
+		seen[selected] = true
+	}
+	assert.Len(t, seen, 2)
+	assert.False(t, seen[primary])
+}
@@ -44,4 +44,18 @@ type Finder interface {
 		destinationAssets []xdr.Asset,
 		maxLength uint,
 	) ([]Path, uint32, error)
+	// FindSplitPaths splits a payment of `amountToSpend` of `sourceAsset` across
+	// up to `maxPaths` payment paths delivering `destinationAsset`, maximizing
+	// the total amount delivered. The payment paths must be executed in the
+	// returned order because each of them accounts for the offers and liquidity
+	// pools consumed by the previous ones.
+	// The payment paths are accurate and consistent with the returned ledger sequence number
+	FindSplitPaths(
+		ctx context.Context,
+		sourceAsset xdr.Asset,
+		amountToSpend xdr.Int64,
+		destinationAsset xdr.Asset,
+		maxPaths int,
+		maxLength uint,
+	) ([]Path, uint32, error)
 }
@@ -31,3 +31,16 @@ func (m *MockFinder) FindFixedPaths(
 
 	return args.Get(0).([]Path), args.Get(1).(uint32), args.Error(2)
 }
+
+func (m *MockFinder) FindSplitPaths(
+	ctx context.Context,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAsset xdr.Asset,
+	maxPaths int,
+	maxLength uint,
+) ([]Path, uint32, error) {
+	args := m.Called(ctx, sourceAsset, amountToSpend, destinationAsset, maxPaths, maxLength)
+
+	return args.Get(0).([]Path), args.Get(1).(uint32), args.Error(2)
+}
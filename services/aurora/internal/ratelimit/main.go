@@ -0,0 +1,250 @@
+// Package ratelimit implements the rate limiting of requests made with API
+// keys. Every API key belongs to a tier defining its hourly quotas, which are
+// counted in fixed windows kept in a Store so they can be shared by several
+// Aurora nodes.
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamnet/go/support/errors"
+)
+
+// Bucket identifies the quota a request is counted against.
+type Bucket string
+
+const (
+	// BucketRequests counts non-streaming requests, weighted by their cost.
+	BucketRequests Bucket = "requests"
+	// BucketStreams counts the updates of streaming requests.
+	BucketStreams Bucket = "streams"
+)
+
+// Tier defines the quotas of the API keys assigned to it. A quota of 0 is
+// unlimited.
+type Tier struct {
+	Name            string
+	RequestsPerHour int64
+	StreamsPerHour  int64
+}
+
+func (t Tier) limit(bucket Bucket) int64 {
+	if bucket == BucketStreams {
+		return t.StreamsPerHour
+	}
+	return t.RequestsPerHour
+}
+
+// Key is an API key known to Aurora.
+type Key struct {
+	ID   int64
+	Tier Tier
+}
+
+// KeyLoader loads the API key with the given hash. It returns false if the
+// key is unknown.
+type KeyLoader func(ctx context.Context, hash string) (Key, bool, error)
+
+// Result describes the state of a quota after a request was counted.
+type Result struct {
+	Limited bool
+	// Limit is 0 when the quota is unlimited
+	Limit     int64
+	Remaining int64
+	// Reset is the time left until the quota is replenished
+	Reset time.Duration
+}
+
+type cachedKey struct {
+	key      Key
+	loadedAt time.Time
+}
+
+// Limiter counts the requests of API keys against the quotas of their tiers.
+type Limiter struct {
+	store   Store
+	loadKey KeyLoader
+	// window is the duration of the fixed windows in which quotas are counted
+	window time.Duration
+	// keyCacheTTL is the time after which API keys are loaded again so
+	// changes made on other nodes are picked up
+	keyCacheTTL time.Duration
+	now         func() time.Time
+
+	mutex sync.Mutex
+	// keys caches the known API keys by hash. Unknown keys are not cached,
+	// otherwise requests made with random keys would grow the cache without
+	// bound.
+	keys map[string]cachedKey
+}
+
+// NewLimiter returns a Limiter counting quotas in store and loading API keys
+// with loadKey.
+func NewLimiter(store Store, loadKey KeyLoader) *Limiter {
+	return &Limiter{
+		store:       store,
+		loadKey:     loadKey,
+		window:      time.Hour,
+		keyCacheTTL: 30 * time.Second,
+		now:         time.Now,
+		keys:        map[string]cachedKey{},
+	}
+}
+
+// NewKey generates a random API key.
+func NewKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", errors.Wrap(err, "could not generate API key")
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// HashKey returns the hash under which an API key is stored. API keys are
+// never stored in plain text.
+func HashKey(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// Key returns the API key matching apiKey. It returns false if the key is
+// unknown.
+func (l *Limiter) Key(ctx context.Context, apiKey string) (Key, bool, error) {
+	hash := HashKey(apiKey)
+	now := l.now()
+
+	l.mutex.Lock()
+	cached, ok := l.keys[hash]
+	l.mutex.Unlock()
+	if ok && now.Sub(cached.loadedAt) < l.keyCacheTTL {
+		return cached.key, true, nil
+	}
+
+	key, found, err := l.loadKey(ctx, hash)
+	if err != nil {
+		return Key{}, false, errors.Wrap(err, "could not load API key")
+	}
+
+	l.mutex.Lock()
+	if found {
+		l.keys[hash] = cachedKey{key: key, loadedAt: now}
+	} else {
+		delete(l.keys, hash)
+	}
+	l.mutex.Unlock()
+	return key, found, nil
+}
+
+// Forget removes an API key from the cache. It should be called when a key
+// or its tier is modified on this node.
+func (l *Limiter) Forget(hash string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if hash == "" {
+		l.keys = map[string]cachedKey{}
+	} else {
+		delete(l.keys, hash)
+	}
+}
+
+// Take counts cost units against the quota of bucket of the API key. Limited
+// requests are not counted.
+func (l *Limiter) Take(ctx context.Context, key Key, bucket Bucket, cost int64) (Result, error) {
+	now := l.now()
+	windowStart := now.Truncate(l.window)
+	result := Result{
+		Limit: key.Tier.limit(bucket),
+		Reset: windowStart.Add(l.window).Sub(now),
+	}
+	if result.Limit <= 0 {
+		return Result{}, nil
+	}
+
+	storeKey := fmt.Sprintf("aurora:ratelimit:%d:%s:%d", key.ID, bucket, windowStart.Unix())
+	count, err := l.store.IncrBy(ctx, storeKey, cost)
+	if err != nil {
+		return result, errors.Wrap(err, "could not increment quota")
+	}
+	// The counter is removed once the window is over. The expiration is set
+	// on every request rather than only on the first one of the window so
+	// that a counter is never left without one when setting it failed.
+	if err = l.store.PExpire(ctx, storeKey, result.Reset); err != nil {
+		return result, errors.Wrap(err, "could not set quota expiration")
+	}
+
+	if count > result.Limit {
+		result.Limited = true
+		count, err = l.store.IncrBy(ctx, storeKey, -cost)
+		if err != nil {
+			return result, errors.Wrap(err, "could not decrement quota")
+		}
+	}
+	if count < result.Limit {
+		result.Remaining = result.Limit - count
+	}
+	return result, nil
+}
+
+// Costs maps path prefixes to the cost of the requests made to them.
+type Costs map[string]int64
+
+// ParseCosts parses a comma separated list of `prefix=cost` pairs, for
+// example `/paths=10,/trade_aggregations=5`.
+func ParseCosts(value string) (Costs, error) {
+	costs := Costs{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, "=")
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "/") {
+			return nil, errors.Errorf("invalid route cost: %s", pair)
+		}
+		cost, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || cost < 0 {
+			return nil, errors.Errorf("invalid route cost: %s", pair)
+		}
+		costs[strings.TrimSuffix(parts[0], "/")] = cost
+	}
+	return costs, nil
+}
+
+// For returns the cost of a request to path, which is the cost of the longest
+// matching prefix or 1 if no prefix matches.
+func (c Costs) For(path string) int64 {
+	cost, matched := int64(1), -1
+	for prefix, prefixCost := range c {
+		if len(prefix) <= matched {
+			continue
+		}
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			cost, matched = prefixCost, len(prefix)
+		}
+	}
+	return cost
+}
+
+type contextKey string
+
+var keyContextKey = contextKey("api_key")
+
+// WithKey returns a copy of ctx containing the API key of a request.
+func WithKey(ctx context.Context, key Key) context.Context {
+	return context.WithValue(ctx, &keyContextKey, key)
+}
+
+// KeyFromContext returns the API key of a request. It returns false if the
+// request was not made with an API key.
+func KeyFromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(&keyContextKey).(Key)
+	return key, ok
+}
@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter(keys map[string]Key) (*Limiter, *MemoryStore, *time.Time) {
+	now := time.Date(2021, 12, 1, 10, 15, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	store := NewMemoryStore()
+	store.now = clock
+	limiter := NewLimiter(store, func(ctx context.Context, hash string) (Key, bool, error) {
+		for apiKey, key := range keys {
+			if HashKey(apiKey) == hash {
+				return key, true, nil
+			}
+		}
+		return Key{}, false, nil
+	})
+	limiter.now = clock
+	return limiter, store, &now
+}
+
+func TestTake(t *testing.T) {
+	tt := assert.New(t)
+	ctx := context.Background()
+	key := Key{ID: 1, Tier: Tier{Name: "partner", RequestsPerHour: 10, StreamsPerHour: 2}}
+	limiter, _, now := newTestLimiter(nil)
+
+	result, err := limiter.Take(ctx, key, BucketRequests, 4)
+	tt.NoError(err)
+	tt.Equal(Result{Limit: 10, Remaining: 6, Reset: 45 * time.Minute}, result)
+
+	result, err = limiter.Take(ctx, key, BucketRequests, 6)
+	tt.NoError(err)
+	tt.False(result.Limited)
+	tt.Equal(int64(0), result.Remaining)
+
+	// Limited requests are not counted
+	result, err = limiter.Take(ctx, key, BucketRequests, 1)
+	tt.NoError(err)
+	tt.True(result.Limited)
+	tt.Equal(int64(0), result.Remaining)
+
+	// Streams are counted separately
+	result, err = limiter.Take(ctx, key, BucketStreams, 1)
+	tt.NoError(err)
+	tt.False(result.Limited)
+	tt.Equal(int64(1), result.Remaining)
+
+	// Other keys have their own quotas
+	result, err = limiter.Take(ctx, Key{ID: 2, Tier: key.Tier}, BucketRequests, 1)
+	tt.NoError(err)
+	tt.Equal(int64(9), result.Remaining)
+
+	// Quotas are replenished in the next window
+	*now = now.Add(45 * time.Minute)
+	result, err = limiter.Take(ctx, key, BucketRequests, 1)
+	tt.NoError(err)
+	tt.Equal(Result{Limit: 10, Remaining: 9, Reset: time.Hour}, result)
+}
+
+func TestTakeSetsExpiration(t *testing.T) {
+	tt := assert.New(t)
+	ctx := context.Background()
+	key := Key{ID: 1, Tier: Tier{Name: "partner", RequestsPerHour: 10}}
+	limiter, store, now := newTestLimiter(nil)
+
+	// A counter left without expiration, for example because setting it
+	// failed on the first request of the window
+	storeKey := "aurora:ratelimit:1:requests:" + strconv.FormatInt(now.Truncate(time.Hour).Unix(), 10)
+	_, err := store.IncrBy(ctx, storeKey, 1)
+	tt.NoError(err)
+
+	_, err = limiter.Take(ctx, key, BucketRequests, 1)
+	tt.NoError(err)
+	tt.Equal(now.Truncate(time.Hour).Add(time.Hour), store.counters[storeKey].expiresAt)
+
+	// The expiration is not extended past the end of the window
+	*now = now.Add(10 * time.Minute)
+	_, err = limiter.Take(ctx, key, BucketRequests, 1)
+	tt.NoError(err)
+	tt.Equal(now.Truncate(time.Hour).Add(time.Hour), store.counters[storeKey].expiresAt)
+}
+
+func TestTakeUnlimited(t *testing.T) {
+	tt := assert.New(t)
+	limiter, store, _ := newTestLimiter(nil)
+
+	key := Key{ID: 1, Tier: Tier{Name: "internal"}}
+	for i := 0; i < 100; i++ {
+		result, err := limiter.Take(context.Background(), key, BucketRequests, 10)
+		tt.NoError(err)
+		tt.False(result.Limited)
+	}
+	tt.Empty(store.counters)
+}
+
+func TestMemoryStoreExpiration(t *testing.T) {
+	tt := assert.New(t)
+	ctx := context.Background()
+	_, store, now := newTestLimiter(nil)
+
+	value, err := store.IncrBy(ctx, "a", 3)
+	tt.NoError(err)
+	tt.Equal(int64(3), value)
+	tt.NoError(store.PExpire(ctx, "a", time.Minute))
+	_, err = store.IncrBy(ctx, "b", 1)
+	tt.NoError(err)
+
+	*now = now.Add(time.Minute)
+	value, err = store.IncrBy(ctx, "a", 1)
+	tt.NoError(err)
+	tt.Equal(int64(1), value)
+	// Counters without expiration are kept
+	tt.Contains(store.counters, "b")
+}
+
+func TestKeyCache(t *testing.T) {
+	tt := assert.New(t)
+	ctx := context.Background()
+	keys := map[string]Key{"secret": {ID: 1, Tier: Tier{Name: "free", RequestsPerHour: 100}}}
+	limiter, _, now := newTestLimiter(keys)
+
+	key, ok, err := limiter.Key(ctx, "secret")
+	tt.NoError(err)
+	tt.True(ok)
+	tt.Equal(keys["secret"], key)
+
+	// Unknown keys are not cached
+	_, ok, err = limiter.Key(ctx, "unknown")
+	tt.NoError(err)
+	tt.False(ok)
+	tt.NotContains(limiter.keys, HashKey("unknown"))
+	keys["unknown"] = Key{ID: 2, Tier: Tier{Name: "free", RequestsPerHour: 100}}
+	_, ok, err = limiter.Key(ctx, "unknown")
+	tt.NoError(err)
+	tt.True(ok)
+
+	// Cached keys are used until they expire or are forgotten
+	keys["secret"] = Key{ID: 1, Tier: Tier{Name: "paid", RequestsPerHour: 1000}}
+	key, _, err = limiter.Key(ctx, "secret")
+	tt.NoError(err)
+	tt.Equal("free", key.Tier.Name)
+
+	*now = now.Add(limiter.keyCacheTTL)
+	key, _, err = limiter.Key(ctx, "secret")
+	tt.NoError(err)
+	tt.Equal("paid", key.Tier.Name)
+
+	delete(keys, "secret")
+	limiter.Forget(HashKey("secret"))
+	_, ok, err = limiter.Key(ctx, "secret")
+	tt.NoError(err)
+	tt.False(ok)
+	tt.NotContains(limiter.keys, HashKey("secret"))
+}
+
+func TestCosts(t *testing.T) {
+	tt := assert.New(t)
+
+	costs, err := ParseCosts("/paths=10, /paths/strict-send=20,/trade_aggregations/=5")
+	tt.NoError(err)
+	tt.Equal(Costs{"/paths": 10, "/paths/strict-send": 20, "/trade_aggregations": 5}, costs)
+
+	tt.Equal(int64(10), costs.For("/paths"))
+	tt.Equal(int64(10), costs.For("/paths/strict-receive"))
+	tt.Equal(int64(20), costs.For("/paths/strict-send"))
+	tt.Equal(int64(5), costs.For("/trade_aggregations"))
+	tt.Equal(int64(1), costs.For("/pathsfinder"))
+	tt.Equal(int64(1), costs.For("/ledgers/1"))
+
+	costs, err = ParseCosts("")
+	tt.NoError(err)
+	tt.Empty(costs)
+
+	for _, invalid := range []string{"paths=10", "/paths", "/paths=-1", "/paths=ten"} {
+		_, err = ParseCosts(invalid)
+		tt.Error(err, invalid)
+	}
+}
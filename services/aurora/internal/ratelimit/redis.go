@@ -0,0 +1,245 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamnet/go/support/errors"
+)
+
+const (
+	redisDialTimeout = 5 * time.Second
+	redisMaxIdle     = 16
+)
+
+// RedisStore is a Store keeping counters in a Redis compatible server, which
+// allows several Aurora nodes to share quotas.
+type RedisStore struct {
+	address  string
+	password string
+	database int
+	idle     chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisStore returns a RedisStore connecting to the server at rawURL, for
+// example `redis://:password@localhost:6379/0`. Connections are opened
+// lazily.
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse redis URL")
+	}
+	if u.Scheme != "redis" || u.Host == "" {
+		return nil, errors.Errorf("invalid redis URL: %s", rawURL)
+	}
+
+	store := &RedisStore{
+		address: u.Host,
+		idle:    make(chan *redisConn, redisMaxIdle),
+	}
+	if u.Port() == "" {
+		store.address = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		store.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if store.database, err = strconv.Atoi(db); err != nil {
+			return nil, errors.Errorf("invalid redis database: %s", db)
+		}
+	}
+	return store, nil
+}
+
+// IncrBy implements Store.
+func (s *RedisStore) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	return s.do(ctx, "INCRBY", key, strconv.FormatInt(value, 10))
+}
+
+// PExpire implements Store.
+func (s *RedisStore) PExpire(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := s.do(ctx, "PEXPIRE", key, strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+// Close closes the idle connections to the server.
+func (s *RedisStore) Close() {
+	for {
+		select {
+		case conn := <-s.idle:
+			conn.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// do sends a command expecting an integer reply.
+func (s *RedisStore) do(ctx context.Context, args ...string) (int64, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	reply, err := conn.command(ctx, args...)
+	if err != nil {
+		// The state of the connection is unknown
+		conn.conn.Close()
+		return 0, err
+	}
+	s.release(conn)
+
+	if replyErr, ok := reply.(redisError); ok {
+		return 0, replyErr
+	}
+	value, ok := reply.(int64)
+	if !ok {
+		return 0, errors.Errorf("unexpected reply to %s: %v", args[0], reply)
+	}
+	return value, nil
+}
+
+func (s *RedisStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-s.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: redisDialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to redis")
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if s.password != "" {
+		if err = conn.expectOK(ctx, "AUTH", s.password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if s.database != 0 {
+		if err = conn.expectOK(ctx, "SELECT", strconv.Itoa(s.database)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (s *RedisStore) release(conn *redisConn) {
+	select {
+	case s.idle <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// redisError is an error reply sent by the server.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func (c *redisConn) expectOK(ctx context.Context, args ...string) error {
+	reply, err := c.command(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if replyErr, ok := reply.(redisError); ok {
+		return replyErr
+	}
+	if reply != "OK" {
+		return errors.Errorf("unexpected reply to %s: %v", args[0], reply)
+	}
+	return nil
+}
+
+func (c *redisConn) command(ctx context.Context, args ...string) (interface{}, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	} else {
+		c.conn.SetDeadline(time.Time{})
+	}
+
+	var request strings.Builder
+	fmt.Fprintf(&request, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&request, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, request.String()); err != nil {
+		return nil, errors.Wrap(err, "could not send redis command")
+	}
+
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read redis reply")
+	}
+	return reply, nil
+}
+
+// readReply reads a single RESP reply. Simple strings and bulk strings are
+// returned as strings, integers as int64 and arrays as []interface{}.
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return redisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		data := make([]byte, length+2)
+		if _, err = io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:length]), nil
+	case '*':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		values := make([]interface{}, length)
+		for i := range values {
+			if values[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, errors.Errorf("unknown reply type: %q", line[0])
+	}
+}
@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis is a minimal Redis server supporting the commands used by
+// RedisStore.
+type fakeRedis struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	values   map[string]int64
+	ttls     map[string]time.Duration
+	commands [][]string
+}
+
+func startFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeRedis{
+		listener: listener,
+		values:   map[string]int64{},
+		ttls:     map[string]time.Duration{},
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, count)
+		for i := range args {
+			if _, err = reader.ReadString('\n'); err != nil {
+				return
+			}
+			arg, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args[i] = strings.TrimSuffix(arg, "\r\n")
+		}
+		fmt.Fprint(conn, s.reply(args))
+	}
+}
+
+func (s *fakeRedis) reply(args []string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.commands = append(s.commands, args)
+
+	switch args[0] {
+	case "AUTH":
+		if args[1] != "secret" {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "INCRBY":
+		value, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return "-ERR value is not an integer\r\n"
+		}
+		s.values[args[1]] += value
+		return fmt.Sprintf(":%d\r\n", s.values[args[1]])
+	case "PEXPIRE":
+		ms, _ := strconv.ParseInt(args[2], 10, 64)
+		if _, ok := s.values[args[1]]; !ok {
+			return ":0\r\n"
+		}
+		s.ttls[args[1]] = time.Duration(ms) * time.Millisecond
+		return ":1\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	tt := assert.New(t)
+	ctx := context.Background()
+	server := startFakeRedis(t)
+	defer server.listener.Close()
+
+	store, err := NewRedisStore("redis://:secret@" + server.listener.Addr().String() + "/2")
+	tt.NoError(err)
+	defer store.Close()
+
+	value, err := store.IncrBy(ctx, "counter", 5)
+	tt.NoError(err)
+	tt.Equal(int64(5), value)
+	value, err = store.IncrBy(ctx, "counter", -2)
+	tt.NoError(err)
+	tt.Equal(int64(3), value)
+	tt.NoError(store.PExpire(ctx, "counter", time.Hour))
+
+	server.mutex.Lock()
+	tt.Equal(time.Hour, server.ttls["counter"])
+	// The connection is reused after authenticating once
+	tt.Equal([][]string{
+		{"AUTH", "secret"},
+		{"SELECT", "2"},
+		{"INCRBY", "counter", "5"},
+		{"INCRBY", "counter", "-2"},
+		{"PEXPIRE", "counter", "3600000"},
+	}, server.commands)
+	server.mutex.Unlock()
+
+	_, err = store.do(ctx, "UNKNOWN")
+	tt.EqualError(err, "redis: ERR unknown command")
+
+	store, err = NewRedisStore("redis://:wrong@" + server.listener.Addr().String())
+	tt.NoError(err)
+	_, err = store.IncrBy(ctx, "counter", 1)
+	tt.EqualError(err, "redis: WRONGPASS invalid password")
+}
+
+func TestNewRedisStore(t *testing.T) {
+	tt := assert.New(t)
+
+	store, err := NewRedisStore("redis://localhost")
+	tt.NoError(err)
+	tt.Equal("localhost:6379", store.address)
+	tt.Equal("", store.password)
+	tt.Equal(0, store.database)
+
+	for _, invalid := range []string{"http://localhost", "redis://", "redis://localhost/db"} {
+		_, err = NewRedisStore(invalid)
+		tt.Error(err, invalid)
+	}
+}
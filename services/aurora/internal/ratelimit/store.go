@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps the counters of quotas. Its methods behave like the Redis
+// commands of the same name so quotas can be shared by several Aurora nodes
+// through a Redis compatible server.
+type Store interface {
+	// IncrBy increments the counter at key by value and returns the new
+	// value. Missing counters start at 0.
+	IncrBy(ctx context.Context, key string, value int64) (int64, error)
+	// PExpire sets the time after which the counter at key is removed.
+	PExpire(ctx context.Context, key string, ttl time.Duration) error
+}
+
+type memoryCounter struct {
+	value     int64
+	expiresAt time.Time
+}
+
+func (c *memoryCounter) expired(now time.Time) bool {
+	return !c.expiresAt.IsZero() && !now.Before(c.expiresAt)
+}
+
+// MemoryStore is a Store keeping counters in memory. Quotas are not shared
+// with other nodes.
+type MemoryStore struct {
+	mutex    sync.Mutex
+	counters map[string]*memoryCounter
+	// lastSweep is the last time expired counters were removed
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: map[string]*memoryCounter{},
+		now:      time.Now,
+	}
+}
+
+// IncrBy implements Store.
+func (s *MemoryStore) IncrBy(ctx context.Context, key string, value int64) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= time.Minute {
+		s.removeExpired(now)
+	}
+	counter, ok := s.counters[key]
+	if !ok || counter.expired(now) {
+		counter = &memoryCounter{}
+		s.counters[key] = counter
+	}
+	counter.value += value
+	return counter.value, nil
+}
+
+// PExpire implements Store.
+func (s *MemoryStore) PExpire(ctx context.Context, key string, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if counter, ok := s.counters[key]; ok {
+		counter.expiresAt = s.now().Add(ttl)
+	}
+	return nil
+}
+
+func (s *MemoryStore) removeExpired(now time.Time) {
+	for key, counter := range s.counters {
+		if counter.expired(now) {
+			delete(s.counters, key)
+		}
+	}
+	s.lastSweep = now
+}
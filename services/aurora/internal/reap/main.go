@@ -16,6 +16,9 @@ import (
 type System struct {
 	HistoryQ       *history.Q
 	RetentionCount uint
+	// IndexColdTransactions records the ledgers of the reaped transactions so
+	// that they can be found in the cold storage by hash.
+	IndexColdTransactions bool
 	ledgerState    *ledger.State
 	ctx            context.Context
 	cancel         context.CancelFunc
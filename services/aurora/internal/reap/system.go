@@ -26,11 +26,30 @@ func (r *System) DeleteUnretainedHistory(ctx context.Context) error {
 		return nil
 	}
 
-	err := r.clearBefore(ctx, latest.HistoryElder, targetElder)
+	if r.IndexColdTransactions {
+		err := r.indexColdTransactions(ctx, latest.HistoryElder, targetElder)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := r.dropPartitionsBefore(ctx, targetElder)
 	if err != nil {
 		return err
 	}
 
+	err = r.clearBefore(ctx, latest.HistoryElder, targetElder)
+	if err != nil {
+		return err
+	}
+
+	// The state can't be queried as of reaped ledgers so the versions of the
+	// state tables rows replaced before the new elder are not needed anymore.
+	err = r.HistoryQ.DeleteStateVersionsBefore(ctx, uint32(targetElder))
+	if err != nil {
+		return errors.Wrap(err, "Error in DeleteStateVersionsBefore")
+	}
+
 	log.
 		WithField("new_elder", targetElder).
 		Info("reaper succeeded")
@@ -70,6 +89,35 @@ func (r *System) runOnce(ctx context.Context) {
 	}
 }
 
+// dropPartitionsBefore drops the partitions of the partitioned history tables
+// which only contain ledgers before endSeq. The rows of the partitions which
+// are kept are then deleted by clearBefore.
+func (r *System) dropPartitionsBefore(ctx context.Context, endSeq int32) error {
+	err := r.HistoryQ.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Error in begin")
+	}
+	defer r.HistoryQ.Rollback()
+
+	dropped, err := r.HistoryQ.DropHistoryPartitionsBefore(ctx, uint32(endSeq))
+	if err != nil {
+		return errors.Wrap(err, "Error in DropHistoryPartitionsBefore")
+	}
+
+	err = r.HistoryQ.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Error in commit")
+	}
+
+	for _, partition := range dropped {
+		log.
+			WithField("partition", partition.PartitionName).
+			WithField("end_ledger", partition.EndSequence).
+			Info("reaper: dropped partition")
+	}
+	return nil
+}
+
 // Work backwards in 100k ledger blocks to prevent using all the CPU.
 //
 // This runs every hour, so we need to make sure it doesn't
@@ -82,6 +130,32 @@ func (r *System) runOnce(ctx context.Context) {
 var batchSize = int32(100_000)
 var sleep = 1 * time.Second
 
+// indexColdTransactions records the ledgers of the transactions between
+// startSeq and endSeq (exclusive) before they are deleted.
+func (r *System) indexColdTransactions(ctx context.Context, startSeq, endSeq int32) error {
+	for batchStartSeq := startSeq; batchStartSeq < endSeq; batchStartSeq += batchSize {
+		batchEndSeq := batchStartSeq + batchSize - 1
+		if batchEndSeq >= endSeq {
+			batchEndSeq = endSeq - 1
+		}
+		log.WithField("start_ledger", batchStartSeq).WithField("end_ledger", batchEndSeq).Info("reaper: indexing cold transactions")
+
+		batchStart, batchEnd, err := toid.LedgerRangeInclusive(batchStartSeq, batchEndSeq)
+		if err != nil {
+			return err
+		}
+
+		err = r.HistoryQ.IndexColdTransactions(ctx, batchStart, batchEnd)
+		if err != nil {
+			return errors.Wrap(err, "Error in IndexColdTransactions")
+		}
+
+		time.Sleep(sleep)
+	}
+
+	return nil
+}
+
 func (r *System) clearBefore(ctx context.Context, startSeq, endSeq int32) error {
 	for batchEndSeq := endSeq - 1; batchEndSeq >= startSeq; batchEndSeq -= batchSize {
 		batchStartSeq := batchEndSeq - batchSize
@@ -29,6 +29,16 @@ var (
 			"headers.",
 	}
 
+	// InvalidAPIKey is a well-known problem type.  Use it as a shortcut
+	// in your actions.
+	InvalidAPIKey = problem.P{
+		Type:   "invalid_api_key",
+		Title:  "Invalid API Key",
+		Status: http.StatusUnauthorized,
+		Detail: "The API key sent with the request is unknown or was revoked. " +
+			"Requests without an API key are rate limited by IP address.",
+	}
+
 	// NotImplemented is a well-known problem type.  Use it as a shortcut
 	// in your actions.
 	NotImplemented = problem.P{
@@ -104,6 +114,17 @@ var (
 			"also possible that Diamnet-Core is out of sync. Please try again later.",
 	}
 
+	// FilteredHistory is a well-known problem type.  Use it as a shortcut
+	// in your actions.
+	FilteredHistory = problem.P{
+		Type:   "filtered_history",
+		Title:  "History Is Filtered",
+		Status: http.StatusNotImplemented,
+		Detail: "This aurora instance only ingests the history of the transactions " +
+			"allowed by its ingest filters, so the data requested cannot be " +
+			"derived from its history.",
+	}
+
 	// StillIngesting is a well-known problem type.  Use it as a shortcut
 	// in your actions.
 	StillIngesting = problem.P{
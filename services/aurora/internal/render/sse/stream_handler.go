@@ -1,6 +1,7 @@
 package sse
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/diamnet/go/services/aurora/internal/ledger"
@@ -12,12 +13,43 @@ type LedgerSourceFactory interface {
 	Get() ledger.Source
 }
 
+// StreamRateLimiter rate limits the updates of streams separately from the
+// RateLimiter of StreamHandler.
+type StreamRateLimiter interface {
+	// RateLimitStream counts an update of the stream of r. It returns false
+	// as second value if r is not limited by the StreamRateLimiter.
+	RateLimitStream(r *http.Request) (limited bool, ok bool, err error)
+}
+
 // StreamHandler represents a stream handling action
 type StreamHandler struct {
-	RateLimiter         *throttled.HTTPRateLimiter
+	RateLimiter *throttled.HTTPRateLimiter
+	// StreamRateLimiter, when set, takes precedence over RateLimiter for
+	// the requests it limits
+	StreamRateLimiter   StreamRateLimiter
 	LedgerSourceFactory LedgerSourceFactory
 }
 
+// EventWriter receives the events generated by StreamHandler. Stream
+// implements it by writing the events to the response as Server Sent Events.
+type EventWriter interface {
+	Init()
+	Send(e Event)
+	Done()
+	Err(err error)
+}
+
+type contextKey string
+
+var eventWriterContextKey = contextKey("event_writer")
+
+// WithEventWriter returns a copy of ctx which makes StreamHandler send the
+// events of requests using it to ew instead of rendering them to the
+// response. It allows other transports to reuse the streaming actions.
+func WithEventWriter(ctx context.Context, ew EventWriter) context.Context {
+	return context.WithValue(ctx, &eventWriterContextKey, ew)
+}
+
 // GenerateEventsFunc generates a slice of sse.Event which are sent via
 // streaming.
 type GenerateEventsFunc func() ([]Event, error)
@@ -31,8 +63,12 @@ func (handler StreamHandler) ServeStream(
 	generateEvents GenerateEventsFunc,
 ) {
 	ctx := r.Context()
-	stream := NewStream(ctx, w)
-	stream.SetLimit(limit)
+	stream, ok := ctx.Value(&eventWriterContextKey).(EventWriter)
+	if !ok {
+		sseStream := NewStream(ctx, w)
+		sseStream.SetLimit(limit)
+		stream = sseStream
+	}
 
 	ledgerSource := handler.LedgerSourceFactory.Get()
 	defer ledgerSource.Close()
@@ -41,17 +77,14 @@ func (handler StreamHandler) ServeStream(
 	for {
 		// Rate limit the request if it's a call to stream since it queries the DB every second. See
 		// https://github.com/diamnet/go/issues/715 for more details.
-		rateLimiter := handler.RateLimiter
-		if rateLimiter != nil {
-			limited, _, err := rateLimiter.RateLimiter.RateLimit(rateLimiter.VaryBy.Key(r), 1)
-			if err != nil {
-				stream.Err(errors.Wrap(err, "RateLimiter error"))
-				return
-			}
-			if limited {
-				stream.Err(ErrRateLimited)
-				return
-			}
+		limited, err := handler.rateLimit(r)
+		if err != nil {
+			stream.Err(errors.Wrap(err, "RateLimiter error"))
+			return
+		}
+		if limited {
+			stream.Err(ErrRateLimited)
+			return
 		}
 
 		events, err := generateEvents()
@@ -86,3 +119,19 @@ func (handler StreamHandler) ServeStream(
 		}
 	}
 }
+
+func (handler StreamHandler) rateLimit(r *http.Request) (bool, error) {
+	if handler.StreamRateLimiter != nil {
+		limited, ok, err := handler.StreamRateLimiter.RateLimitStream(r)
+		if ok || err != nil {
+			return limited, err
+		}
+	}
+
+	rateLimiter := handler.RateLimiter
+	if rateLimiter == nil {
+		return false, nil
+	}
+	limited, _, err := rateLimiter.RateLimiter.RateLimit(rateLimiter.VaryBy.Key(r), 1)
+	return limited, err
+}
@@ -42,3 +42,41 @@ func TestSendByeByeOnContextDone(t *testing.T) {
 		t.Fatalf("expected '%v' but got '%v'", expected, got)
 	}
 }
+
+type recordingEventWriter struct {
+	events []Event
+	done   bool
+	err    error
+}
+
+func (w *recordingEventWriter) Init()         {}
+func (w *recordingEventWriter) Send(e Event)  { w.events = append(w.events, e) }
+func (w *recordingEventWriter) Done()         { w.done = true }
+func (w *recordingEventWriter) Err(err error) { w.err = err }
+
+func TestServeStreamWithEventWriter(t *testing.T) {
+	ledgerSource := ledger.NewTestingSource(1)
+	handler := StreamHandler{LedgerSourceFactory: &testingFactory{ledgerSource}}
+
+	r, err := http.NewRequest("GET", "http://localhost", nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	ew := &recordingEventWriter{}
+	r = r.WithContext(WithEventWriter(context.Background(), ew))
+
+	w := httptest.NewRecorder()
+	handler.ServeStream(w, r, 2, func() ([]Event, error) {
+		return []Event{{ID: "1", Data: "a"}, {ID: "2", Data: "b"}}, nil
+	})
+
+	if got := w.Body.String(); got != "" {
+		t.Fatalf("expected an empty response but got '%v'", got)
+	}
+	if len(ew.events) != 2 || ew.events[1].ID != "2" {
+		t.Fatalf("unexpected events %v", ew.events)
+	}
+	if !ew.done {
+		t.Fatal("expected the stream to be done")
+	}
+}
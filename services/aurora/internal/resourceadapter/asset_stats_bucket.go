@@ -0,0 +1,41 @@
+package resourceadapter
+
+import (
+	"context"
+
+	"github.com/diamnet/go/amount"
+	protocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+)
+
+// PopulateAssetStatsBucket fills out the details of the statistics of an asset
+// over a period of time using a row from the asset stats buckets.
+func PopulateAssetStatsBucket(
+	ctx context.Context,
+	dest *protocol.AssetStatsBucket,
+	row history.AssetStatsBucket,
+) error {
+	var err error
+	dest.Timestamp = row.Timestamp
+	dest.TransferCount = row.TransferCount
+	dest.PaymentVolume, err = amount.IntStringToAmount(row.PaymentVolume)
+	if err != nil {
+		return err
+	}
+	if row.Holders.Valid {
+		holders := int32(row.Holders.Int64)
+		dest.Holders = &holders
+	}
+	if row.Trustlines.Valid {
+		trustlines := int32(row.Trustlines.Int64)
+		dest.Trustlines = &trustlines
+	}
+	if row.Supply.Valid {
+		supply, err := amount.IntStringToAmount(row.Supply.String)
+		if err != nil {
+			return err
+		}
+		dest.Supply = &supply
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package resourceadapter
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/diamnet/go/amount"
+	protocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/support/errors"
+)
+
+// PopulateLiquidityPoolStatsBucket fills out the details of the activity of a
+// liquidity pool over a period of time using a row from the liquidity pool
+// stats buckets.
+func PopulateLiquidityPoolStatsBucket(
+	ctx context.Context,
+	dest *protocol.LiquidityPoolStatsBucket,
+	pool history.LiquidityPool,
+	row history.LiquidityPoolStatsBucket,
+) error {
+	dest.Timestamp = row.Timestamp
+	dest.TradeCount = row.TradeCount
+	dest.TotalTrustlines = uint64(row.TrustlineCount)
+	dest.TotalShares = amount.StringFromInt64(row.TotalShares)
+
+	reserves := [2]int64{row.ReserveA, row.ReserveB}
+	for i, reserve := range reserves {
+		asset := pool.AssetReserves[i].Asset.StringCanonical()
+		dest.Reserves = append(dest.Reserves, protocol.LiquidityPoolReserve{
+			Asset:  asset,
+			Amount: amount.StringFromInt64(reserve),
+		})
+		sharePrice := "0.0000000"
+		if row.TotalShares > 0 {
+			sharePrice = big.NewRat(reserve, row.TotalShares).FloatString(7)
+		}
+		dest.SharePrice = append(dest.SharePrice, protocol.LiquidityPoolReserve{
+			Asset:  asset,
+			Amount: sharePrice,
+		})
+	}
+
+	var err error
+	if dest.Volume, err = liquidityPoolAmounts(pool, row.VolumeA, row.VolumeB); err != nil {
+		return err
+	}
+	dest.FeeRevenue, err = liquidityPoolAmounts(pool, row.FeeA, row.FeeB)
+	return err
+}
+
+// PopulateLiquidityPoolAPRWindow fills out the activity of a liquidity pool
+// over a window of time and computes its fee APR and impermanent loss from the
+// reserves at the start and the end of the window.
+func PopulateLiquidityPoolAPRWindow(
+	ctx context.Context,
+	dest *protocol.LiquidityPoolAPRWindow,
+	pool history.LiquidityPool,
+	days uint32,
+	startTime, endTime time.Time,
+	window history.LiquidityPoolStatsWindow,
+) error {
+	dest.Days = days
+	dest.StartTime = startTime
+	dest.EndTime = endTime
+	dest.TradeCount = window.TradeCount
+
+	var err error
+	if dest.Volume, err = liquidityPoolAmounts(pool, window.VolumeA, window.VolumeB); err != nil {
+		return err
+	}
+	if dest.FeeRevenue, err = liquidityPoolAmounts(pool, window.FeeA, window.FeeB); err != nil {
+		return err
+	}
+
+	if window.Start == nil || window.Start.ReserveA == 0 || window.Start.ReserveB == 0 {
+		return nil
+	}
+	feeA, err := strconv.ParseFloat(window.FeeA, 64)
+	if err != nil {
+		return errors.Wrap(err, "invalid fee revenue")
+	}
+	feeB, err := strconv.ParseFloat(window.FeeB, 64)
+	if err != nil {
+		return errors.Wrap(err, "invalid fee revenue")
+	}
+	apr := strconv.FormatFloat(LiquidityPoolFeeAPR(
+		feeA, feeB,
+		float64(window.Start.ReserveA), float64(window.Start.ReserveB),
+		days,
+	), 'f', 7, 64)
+	dest.FeeAPR = &apr
+
+	if window.End.ReserveA == 0 || window.End.ReserveB == 0 {
+		return nil
+	}
+	impermanentLoss := strconv.FormatFloat(LiquidityPoolImpermanentLoss(
+		float64(window.Start.ReserveB)/float64(window.Start.ReserveA),
+		float64(window.End.ReserveB)/float64(window.End.ReserveA),
+	), 'f', 7, 64)
+	dest.ImpermanentLoss = &impermanentLoss
+	return nil
+}
+
+// LiquidityPoolFeeAPR returns the annualized return of the fees of a constant
+// product pool over the given number of days. Both assets of such a pool have
+// the same value so the return is the average of the fees relative to the
+// reserves of each asset.
+func LiquidityPoolFeeAPR(feeA, feeB, reserveA, reserveB float64, days uint32) float64 {
+	return (feeA/reserveA + feeB/reserveB) / 2 * 365 / float64(days)
+}
+
+// LiquidityPoolImpermanentLoss returns the loss, as a negative fraction, of a
+// deposit in a constant product pool when the price of the pool moves from
+// startPrice to endPrice compared to holding the deposited assets.
+func LiquidityPoolImpermanentLoss(startPrice, endPrice float64) float64 {
+	ratio := endPrice / startPrice
+	return 2*math.Sqrt(ratio)/(1+ratio) - 1
+}
+
+// liquidityPoolAmounts converts amounts in stroops of the assets of a pool,
+// which can have a fractional part, to reserves.
+func liquidityPoolAmounts(pool history.LiquidityPool, amounts ...string) ([]protocol.LiquidityPoolReserve, error) {
+	stroops := big.NewRat(amount.One, 1)
+	reserves := make([]protocol.LiquidityPoolReserve, 0, len(amounts))
+	for i, value := range amounts {
+		parsed, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return nil, errors.Errorf("invalid amount: %s", value)
+		}
+		reserves = append(reserves, protocol.LiquidityPoolReserve{
+			Asset:  pool.AssetReserves[i].Asset.StringCanonical(),
+			Amount: parsed.Quo(parsed, stroops).FloatString(7),
+		})
+	}
+	return reserves, nil
+}
@@ -0,0 +1,104 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	protocol "github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/xdr"
+)
+
+func liquidityPoolStatsPool() history.LiquidityPool {
+	issuer := "GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY"
+	return history.LiquidityPool{
+		PoolID: "cafebabedeadbeef000000000000000000000000000000000000000000000000",
+		Fee:    30,
+		AssetReserves: history.LiquidityPoolAssetReserves{
+			{Asset: xdr.MustNewNativeAsset()},
+			{Asset: xdr.MustNewCreditAsset("USD", issuer)},
+		},
+	}
+}
+
+func TestPopulateLiquidityPoolStatsBucket(t *testing.T) {
+	var dest protocol.LiquidityPoolStatsBucket
+	assert.NoError(t, PopulateLiquidityPoolStatsBucket(context.Background(), &dest, liquidityPoolStatsPool(), history.LiquidityPoolStatsBucket{
+		Timestamp:      3600000,
+		TradeCount:     2,
+		VolumeA:        "20000000",
+		VolumeB:        "5",
+		FeeA:           "60000.0000",
+		FeeB:           "0.0150",
+		ReserveA:       40000000,
+		ReserveB:       10000000,
+		TotalShares:    20000000,
+		TrustlineCount: 3,
+	}))
+
+	usd := "USD:GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY"
+	assert.Equal(t, protocol.LiquidityPoolStatsBucket{
+		Timestamp:       3600000,
+		TradeCount:      2,
+		TotalTrustlines: 3,
+		TotalShares:     "2.0000000",
+		Reserves:        []protocol.LiquidityPoolReserve{{"native", "4.0000000"}, {usd, "1.0000000"}},
+		SharePrice:      []protocol.LiquidityPoolReserve{{"native", "2.0000000"}, {usd, "0.5000000"}},
+		Volume:          []protocol.LiquidityPoolReserve{{"native", "2.0000000"}, {usd, "0.0000005"}},
+		FeeRevenue:      []protocol.LiquidityPoolReserve{{"native", "0.0060000"}, {usd, "0.0000000"}},
+	}, dest)
+}
+
+func TestPopulateLiquidityPoolAPRWindow(t *testing.T) {
+	endTime := time.Date(2021, 11, 8, 0, 0, 0, 0, time.UTC)
+	startTime := endTime.Add(-time.Hour * 24 * 10)
+
+	var dest protocol.LiquidityPoolAPRWindow
+	assert.NoError(t, PopulateLiquidityPoolAPRWindow(
+		context.Background(),
+		&dest,
+		liquidityPoolStatsPool(),
+		10,
+		startTime,
+		endTime,
+		history.LiquidityPoolStatsWindow{
+			TradeCount: 4,
+			VolumeA:    "1000",
+			VolumeB:    "2000",
+			FeeA:       "10",
+			FeeB:       "30",
+			Start:      &history.LiquidityPoolStatsBucket{ReserveA: 10000, ReserveB: 10000},
+			End:        &history.LiquidityPoolStatsBucket{ReserveA: 5000, ReserveB: 20000},
+		},
+	))
+	assert.Equal(t, uint32(10), dest.Days)
+	assert.Equal(t, startTime, dest.StartTime)
+	assert.Equal(t, int64(4), dest.TradeCount)
+	// (0.001 + 0.003) / 2 for 10 days
+	assert.Equal(t, "0.0730000", *dest.FeeAPR)
+	// The price moved by 4x
+	assert.Equal(t, "-0.2000000", *dest.ImpermanentLoss)
+
+	// A pool without reserves at the start of the window has no returns
+	dest = protocol.LiquidityPoolAPRWindow{}
+	assert.NoError(t, PopulateLiquidityPoolAPRWindow(
+		context.Background(),
+		&dest,
+		liquidityPoolStatsPool(),
+		1,
+		startTime,
+		endTime,
+		history.LiquidityPoolStatsWindow{VolumeA: "0", VolumeB: "0", FeeA: "0", FeeB: "0"},
+	))
+	assert.Nil(t, dest.FeeAPR)
+	assert.Nil(t, dest.ImpermanentLoss)
+}
+
+func TestLiquidityPoolImpermanentLoss(t *testing.T) {
+	assert.Equal(t, 0.0, LiquidityPoolImpermanentLoss(2, 2))
+	assert.InDelta(t, -0.0572, LiquidityPoolImpermanentLoss(1, 2), 0.0001)
+	assert.InDelta(t, -0.0572, LiquidityPoolImpermanentLoss(2, 1), 0.0001)
+}
@@ -117,3 +117,47 @@ func (finder InMemoryFinder) FindFixedPaths(
 	}
 	return results, lastLedger, err
 }
+
+// FindSplitPaths splits a payment of `amountToSpend` of `sourceAsset` across up
+// to `maxPaths` payment paths delivering `destinationAsset`. The payment paths
+// must be executed in the returned order.
+func (finder InMemoryFinder) FindSplitPaths(
+	ctx context.Context,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAsset xdr.Asset,
+	maxPaths int,
+	maxLength uint,
+) ([]paths.Path, uint32, error) {
+	if finder.graph.IsEmpty() {
+		return nil, 0, ErrEmptyInMemoryOrderBook
+	}
+
+	if maxLength == 0 {
+		maxLength = MaxInMemoryPathLength
+	}
+	if maxLength > MaxInMemoryPathLength {
+		return nil, 0, errors.New("invalid value of maxLength")
+	}
+
+	orderbookPaths, lastLedger, err := finder.graph.FindSplitPaths(
+		ctx,
+		int(maxLength),
+		sourceAsset,
+		amountToSpend,
+		destinationAsset,
+		maxPaths,
+		finder.includePools,
+	)
+	results := make([]paths.Path, len(orderbookPaths))
+	for i, path := range orderbookPaths {
+		results[i] = paths.Path{
+			Path:              path.InteriorNodes,
+			Source:            path.SourceAsset,
+			SourceAmount:      path.SourceAmount,
+			Destination:       path.DestinationAsset,
+			DestinationAmount: path.DestinationAmount,
+		}
+	}
+	return results, lastLedger, err
+}
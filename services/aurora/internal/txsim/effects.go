@@ -0,0 +1,56 @@
+package txsim
+
+import (
+	"github.com/diamnet/go/protocols/aurora/effects"
+	"github.com/diamnet/go/xdr"
+)
+
+func newEffect(effectType effects.EffectType, account string, details map[string]interface{}) Effect {
+	return Effect{
+		Type:    effects.EffectTypeNames[effectType],
+		Account: account,
+		Details: details,
+	}
+}
+
+// addAssetDetails adds the details of the asset using the same keys as the
+// effects ingested by aurora.
+func addAssetDetails(details map[string]interface{}, asset xdr.Asset, prefix string) {
+	var assetType, code, issuer string
+	if err := asset.Extract(&assetType, &code, &issuer); err != nil {
+		return
+	}
+	details[prefix+"asset_type"] = assetType
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return
+	}
+	details[prefix+"asset_code"] = code
+	details[prefix+"asset_issuer"] = issuer
+}
+
+func setTrustLineFlagDetails(details map[string]interface{}, flags xdr.TrustLineFlags, value bool) {
+	if flags.IsAuthorized() {
+		details["authorized_flag"] = value
+	}
+	if flags.IsAuthorizedToMaintainLiabilitiesFlag() {
+		details["authorized_to_maintain_liabilites"] = value
+	}
+	if flags.IsClawbackEnabledFlag() {
+		details["clawback_enabled_flag"] = value
+	}
+}
+
+func setAuthFlagDetails(details map[string]interface{}, flags xdr.AccountFlags, value bool) {
+	if flags.IsAuthRequired() {
+		details["auth_required_flag"] = value
+	}
+	if flags.IsAuthRevocable() {
+		details["auth_revocable_flag"] = value
+	}
+	if flags.IsAuthImmutable() {
+		details["auth_immutable_flag"] = value
+	}
+	if flags.IsAuthClawbackEnabled() {
+		details["auth_clawback_enabled_flag"] = value
+	}
+}
@@ -0,0 +1,164 @@
+package txsim
+
+import (
+	"math"
+
+	"github.com/diamnet/go/amount"
+	"github.com/diamnet/go/price"
+	"github.com/diamnet/go/protocols/aurora/effects"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// exchange describes the offers crossed by a taker selling one asset in
+// exchange for another.
+type exchange struct {
+	taker   string
+	selling xdr.Asset
+	buying  xdr.Asset
+	// maxSend limits the amount of selling sent and maxReceive the amount of
+	// buying received
+	maxSend    int64
+	maxReceive int64
+	// sendAll is set when the taker sends exactly maxSend
+	sendAll bool
+	// maxPrice is the highest price, in units of selling per unit of buying,
+	// the taker accepts. Offers at exactly maxPrice are not crossed by
+	// passive takers.
+	maxPrice *xdr.Price
+	passive  bool
+}
+
+type trade struct {
+	offer xdr.OfferEntry
+	// sold is the amount of the selling asset of the offer bought by the
+	// taker and bought the amount of the buying asset of the offer paid by
+	// the taker
+	sold   int64
+	bought int64
+}
+
+type crossResult struct {
+	sent        int64
+	received    int64
+	trades      []trade
+	crossSelf   bool
+	noLiquidity bool
+}
+
+// cross crosses the offers of the order book and updates the remaining
+// amounts of the crossed offers. Balances are not modified.
+func (s *ledgerState) cross(ex exchange) (crossResult, error) {
+	var result crossResult
+	if s.orderBook == nil {
+		result.noLiquidity = true
+		return result, nil
+	}
+
+	for _, offer := range s.orderBook.OffersForPair(ex.buying, ex.selling) {
+		if result.sent >= ex.maxSend || result.received >= ex.maxReceive {
+			break
+		}
+
+		remaining := int64(offer.Amount)
+		if crossed, ok := s.crossed[int64(offer.OfferId)]; ok {
+			remaining = crossed
+		}
+		if remaining <= 0 {
+			continue
+		}
+
+		n, d := int64(offer.Price.N), int64(offer.Price.D)
+		if ex.maxPrice != nil {
+			offerPrice, limit := n*int64(ex.maxPrice.D), int64(ex.maxPrice.N)*d
+			if offerPrice > limit || (ex.passive && offerPrice == limit) {
+				break
+			}
+		}
+		if offer.SellerId.Address() == ex.taker {
+			result.crossSelf = true
+			break
+		}
+
+		wanted := remaining
+		if left := ex.maxReceive - result.received; left < wanted {
+			wanted = left
+		}
+		if ex.maxSend != math.MaxInt64 {
+			affordable, err := price.MulFractionRoundDown(ex.maxSend-result.sent, d, n)
+			if err != nil && err != price.ErrOverflow {
+				return result, errors.Wrap(err, "could not compute amount bought")
+			}
+			if err == nil && affordable < wanted {
+				wanted = affordable
+			}
+		}
+		if wanted <= 0 {
+			break
+		}
+
+		paid, bought, err := price.ConvertToBuyingUnits(remaining, wanted, n, d)
+		if err != nil {
+			return result, errors.Wrap(err, "could not cross offer")
+		}
+		if bought < remaining && ex.sendAll {
+			// The offer is not consumed entirely so it takes the rest of the
+			// amount sent
+			paid = ex.maxSend - result.sent
+		}
+
+		result.sent += paid
+		result.received += bought
+		s.crossed[int64(offer.OfferId)] = remaining - bought
+		result.trades = append(result.trades, trade{offer: offer, sold: bought, bought: paid})
+	}
+
+	if ex.sendAll {
+		result.noLiquidity = result.sent < ex.maxSend
+	} else if ex.maxReceive != math.MaxInt64 {
+		result.noLiquidity = result.received < ex.maxReceive
+	}
+	return result, nil
+}
+
+// settle transfers the traded amounts to the sellers of the crossed offers
+// and returns the trade effects. The balances of sellers are only updated if
+// they are already part of the simulated state.
+func (s *ledgerState) settle(taker string, trades []trade) ([]Effect, error) {
+	var tradeEffects []Effect
+	for _, t := range trades {
+		seller := t.offer.SellerId.Address()
+		if acc, ok := s.accounts[seller]; ok && acc != nil {
+			if err := s.addBalance(acc, t.offer.Selling, -t.sold); err != nil {
+				return nil, err
+			}
+			if err := s.addBalance(acc, t.offer.Buying, t.bought); err != nil {
+				return nil, err
+			}
+		}
+
+		takerDetails := map[string]interface{}{
+			"offer_id":      int64(t.offer.OfferId),
+			"seller":        seller,
+			"bought_amount": amount.StringFromInt64(t.sold),
+			"sold_amount":   amount.StringFromInt64(t.bought),
+		}
+		addAssetDetails(takerDetails, t.offer.Selling, "bought_")
+		addAssetDetails(takerDetails, t.offer.Buying, "sold_")
+
+		sellerDetails := map[string]interface{}{
+			"offer_id":      int64(t.offer.OfferId),
+			"seller":        taker,
+			"bought_amount": amount.StringFromInt64(t.bought),
+			"sold_amount":   amount.StringFromInt64(t.sold),
+		}
+		addAssetDetails(sellerDetails, t.offer.Buying, "bought_")
+		addAssetDetails(sellerDetails, t.offer.Selling, "sold_")
+
+		tradeEffects = append(tradeEffects,
+			newEffect(effects.EffectTrade, taker, takerDetails),
+			newEffect(effects.EffectTrade, seller, sellerDetails),
+		)
+	}
+	return tradeEffects, nil
+}
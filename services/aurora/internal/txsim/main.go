@@ -0,0 +1,327 @@
+// Package txsim predicts the outcome of transactions by applying them to the
+// state ingested by aurora without submitting them to Diamnet Core.
+//
+// The simulation follows the validation and apply rules of Diamnet Core for
+// sequence numbers, signatures, balances, trust line authorization, reserves
+// and offer crossing, but it is an approximation: it runs against the last
+// ingested ledger, offers are only crossed against the in-memory order book
+// (liquidity pools are not considered) and operations without a simulation
+// (for example claimable balance, sponsorship and liquidity pool operations)
+// are reported as not simulated.
+package txsim
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/diamnet/go/network"
+	"github.com/diamnet/go/protocols/aurora/operations"
+	"github.com/diamnet/go/services/aurora/internal/codes"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// Q defines the queries used to load the state transactions are simulated
+// against.
+type Q interface {
+	GetLastLedgerIngestNonBlocking(ctx context.Context) (uint32, error)
+	LedgerBySequence(ctx context.Context, dest interface{}, seq int32) error
+	GetAccountByID(ctx context.Context, id string) (history.AccountEntry, error)
+	GetAccountSignersByAccountID(ctx context.Context, id string) ([]history.AccountSigner, error)
+	GetSortedTrustLinesByAccountID(ctx context.Context, id string) ([]history.TrustLine, error)
+	GetAccountDataByName(ctx context.Context, id, name string) (history.Data, error)
+	GetOfferByID(ctx context.Context, id int64) (history.Offer, error)
+	NoRows(err error) bool
+}
+
+// OrderBook provides the offers crossed by simulated operations.
+type OrderBook interface {
+	// OffersForPair returns the offers selling `selling` in exchange for
+	// `buying` sorted from cheapest to most expensive.
+	OffersForPair(selling, buying xdr.Asset) []xdr.OfferEntry
+}
+
+// Simulator simulates transactions.
+type Simulator struct {
+	NetworkPassphrase string
+	OrderBook         OrderBook
+}
+
+// Result is the predicted outcome of a transaction.
+type Result struct {
+	Hash string
+	// Ledger is the last ingested ledger the transaction was simulated against
+	Ledger               int32
+	FeeCharged           int64
+	TransactionCode      string
+	InnerTransactionCode string
+	Operations           []OperationResult
+}
+
+// Successful returns true if the transaction is predicted to succeed.
+func (r Result) Successful() bool {
+	return r.TransactionCode == "tx_success" || r.TransactionCode == "tx_fee_bump_inner_success"
+}
+
+// OperationResult is the predicted outcome of an operation. Effects are only
+// predicted when the whole transaction is predicted to succeed.
+type OperationResult struct {
+	Type      string
+	Simulated bool
+	Code      string
+	Effects   []Effect
+}
+
+// Effect is a predicted effect. Details contain the same fields as the
+// corresponding effect resource.
+type Effect struct {
+	Type    string
+	Account string
+	Details map[string]interface{}
+}
+
+// ErrNoIngestedLedger is returned when there is no state to simulate
+// transactions against.
+var ErrNoIngestedLedger = errors.New("no ledger has been ingested yet")
+
+// Simulate predicts the outcome of the given transaction envelope.
+func (s *Simulator) Simulate(ctx context.Context, q Q, envelope xdr.TransactionEnvelope) (Result, error) {
+	lastIngested, err := q.GetLastLedgerIngestNonBlocking(ctx)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "could not load last ingested ledger")
+	}
+	if lastIngested == 0 {
+		return Result{}, ErrNoIngestedLedger
+	}
+	var ledger history.Ledger
+	if err = q.LedgerBySequence(ctx, &ledger, int32(lastIngested)); err != nil {
+		return Result{}, errors.Wrap(err, "could not load last ingested ledger")
+	}
+
+	hash, err := network.HashTransactionInEnvelope(envelope, s.NetworkPassphrase)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "could not hash transaction")
+	}
+
+	state := newLedgerState(ctx, q, s.OrderBook, ledger)
+	result := Result{
+		Hash:   hex.EncodeToString(hash[:]),
+		Ledger: ledger.Sequence,
+	}
+
+	tx := transaction{
+		hash:       hash,
+		source:     envelope.SourceAccount().ToAccountId().Address(),
+		fee:        int64(envelope.Fee()),
+		seqNum:     envelope.SeqNum(),
+		timeBounds: envelope.TimeBounds(),
+		operations: envelope.Operations(),
+		signatures: envelope.Signatures(),
+	}
+
+	var code xdr.TransactionResultCode
+	if envelope.IsFeeBump() {
+		tx.hash, err = network.HashTransaction(envelope.FeeBump.Tx.InnerTx.V1.Tx, s.NetworkPassphrase)
+		if err != nil {
+			return Result{}, errors.Wrap(err, "could not hash inner transaction")
+		}
+		feeBump := transaction{
+			hash:       hash,
+			source:     envelope.FeeBumpAccount().ToAccountId().Address(),
+			fee:        envelope.FeeBumpFee(),
+			signatures: envelope.FeeBumpSignatures(),
+		}
+		code, err = state.chargeFeeBump(feeBump, len(tx.operations), &result)
+		if err != nil || code != xdr.TransactionResultCodeTxFeeBumpInnerSuccess {
+			return s.finish(result, code, err)
+		}
+
+		var innerCode xdr.TransactionResultCode
+		innerCode, err = state.apply(tx, false, &result)
+		if err != nil {
+			return Result{}, err
+		}
+		if result.InnerTransactionCode, err = codes.String(innerCode); err != nil {
+			return Result{}, err
+		}
+		if innerCode != xdr.TransactionResultCodeTxSuccess {
+			code = xdr.TransactionResultCodeTxFeeBumpInnerFailed
+		}
+	} else {
+		code, err = state.apply(tx, true, &result)
+	}
+
+	return s.finish(result, code, err)
+}
+
+func (s *Simulator) finish(result Result, code xdr.TransactionResultCode, err error) (Result, error) {
+	if err != nil {
+		return Result{}, err
+	}
+	if result.TransactionCode, err = codes.String(code); err != nil {
+		return Result{}, err
+	}
+	if !result.Successful() {
+		// Failed transactions only consume the fee and the sequence number
+		for i := range result.Operations {
+			result.Operations[i].Effects = nil
+		}
+	}
+	return result, nil
+}
+
+// transaction contains the fields of a transaction or a fee bump transaction
+// relevant to the simulation
+type transaction struct {
+	hash       [32]byte
+	source     string
+	fee        int64
+	seqNum     int64
+	timeBounds *xdr.TimeBounds
+	operations []xdr.Operation
+	signatures []xdr.DecoratedSignature
+}
+
+// chargeFeeBump validates the outer transaction of a fee bump transaction and
+// charges its fee.
+func (s *ledgerState) chargeFeeBump(tx transaction, operations int, result *Result) (xdr.TransactionResultCode, error) {
+	minFee := int64(s.ledger.BaseFee) * int64(operations+1)
+	if tx.fee < minFee {
+		return xdr.TransactionResultCodeTxInsufficientFee, nil
+	}
+
+	feeSource, err := s.account(tx.source)
+	if err != nil {
+		return 0, err
+	}
+	if feeSource == nil {
+		return xdr.TransactionResultCodeTxNoAccount, nil
+	}
+
+	checker := newSignatureChecker(tx.hash, tx.signatures)
+	ok, err := s.checkSignatures(checker, feeSource, thresholdLow)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return xdr.TransactionResultCodeTxBadAuth, nil
+	}
+	if !checker.allUsed() {
+		return xdr.TransactionResultCodeTxBadAuthExtra, nil
+	}
+
+	if s.availableNative(feeSource) < minFee {
+		return xdr.TransactionResultCodeTxInsufficientBalance, nil
+	}
+	feeSource.entry.Balance -= minFee
+	result.FeeCharged = minFee
+	return xdr.TransactionResultCodeTxFeeBumpInnerSuccess, nil
+}
+
+// apply validates the transaction and applies its operations. The fee is
+// only charged if chargeFee is set, the fee of inner transactions is paid by
+// the fee bump transaction.
+func (s *ledgerState) apply(tx transaction, chargeFee bool, result *Result) (xdr.TransactionResultCode, error) {
+	if len(tx.operations) == 0 {
+		return xdr.TransactionResultCodeTxMissingOperation, nil
+	}
+	if tx.timeBounds != nil {
+		closeTime := s.ledger.ClosedAt.Unix()
+		if tx.timeBounds.MinTime != 0 && int64(tx.timeBounds.MinTime) > closeTime {
+			return xdr.TransactionResultCodeTxTooEarly, nil
+		}
+		if tx.timeBounds.MaxTime != 0 && int64(tx.timeBounds.MaxTime) < closeTime {
+			return xdr.TransactionResultCodeTxTooLate, nil
+		}
+	}
+	minFee := int64(s.ledger.BaseFee) * int64(len(tx.operations))
+	if chargeFee && tx.fee < minFee {
+		return xdr.TransactionResultCodeTxInsufficientFee, nil
+	}
+
+	source, err := s.account(tx.source)
+	if err != nil {
+		return 0, err
+	}
+	if source == nil {
+		return xdr.TransactionResultCodeTxNoAccount, nil
+	}
+	if tx.seqNum != source.entry.SequenceNumber+1 {
+		return xdr.TransactionResultCodeTxBadSeq, nil
+	}
+
+	checker := newSignatureChecker(tx.hash, tx.signatures)
+	ok, err := s.checkSignatures(checker, source, thresholdLow)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return xdr.TransactionResultCodeTxBadAuth, nil
+	}
+
+	if chargeFee {
+		if s.availableNative(source) < minFee {
+			return xdr.TransactionResultCodeTxInsufficientBalance, nil
+		}
+		source.entry.Balance -= minFee
+		result.FeeCharged = minFee
+	}
+	source.entry.SequenceNumber = tx.seqNum
+
+	// Signatures of all operations are verified before any operation is
+	// applied
+	result.Operations = make([]OperationResult, len(tx.operations))
+	valid := true
+	for i, op := range tx.operations {
+		result.Operations[i].Type = operations.TypeNames[op.Body.Type]
+
+		code, err := s.checkOperationSignatures(checker, tx.source, op)
+		if err != nil {
+			return 0, err
+		}
+		if code != xdr.OperationResultCodeOpInner {
+			valid = false
+			result.Operations[i].Simulated = true
+			if result.Operations[i].Code, err = codes.String(code); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if !valid {
+		return xdr.TransactionResultCodeTxFailed, nil
+	}
+	if !checker.allUsed() {
+		return xdr.TransactionResultCodeTxBadAuthExtra, nil
+	}
+
+	successful := true
+	for i, op := range tx.operations {
+		opSource := tx.source
+		if op.SourceAccount != nil {
+			opSource = op.SourceAccount.ToAccountId().Address()
+		}
+
+		code, effects, err := s.applyOperation(opSource, op)
+		if err != nil {
+			return 0, err
+		}
+		if code == nil {
+			continue
+		}
+
+		result.Operations[i].Simulated = true
+		result.Operations[i].Effects = effects
+		if result.Operations[i].Code, err = codes.String(code); err != nil {
+			return 0, err
+		}
+		if result.Operations[i].Code != codes.OpSuccess {
+			successful = false
+		}
+	}
+
+	if !successful {
+		return xdr.TransactionResultCodeTxFailed, nil
+	}
+	return xdr.TransactionResultCodeTxSuccess, nil
+}
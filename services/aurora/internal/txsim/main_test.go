@@ -0,0 +1,323 @@
+package txsim
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/diamnet/go/keypair"
+	"github.com/diamnet/go/network"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/txnbuild"
+	"github.com/diamnet/go/xdr"
+)
+
+type testQ struct {
+	ledger     history.Ledger
+	accounts   map[string]history.AccountEntry
+	trustLines map[string][]history.TrustLine
+}
+
+func (q *testQ) GetLastLedgerIngestNonBlocking(ctx context.Context) (uint32, error) {
+	return uint32(q.ledger.Sequence), nil
+}
+
+func (q *testQ) LedgerBySequence(ctx context.Context, dest interface{}, seq int32) error {
+	*dest.(*history.Ledger) = q.ledger
+	return nil
+}
+
+func (q *testQ) GetAccountByID(ctx context.Context, id string) (history.AccountEntry, error) {
+	entry, ok := q.accounts[id]
+	if !ok {
+		return history.AccountEntry{}, sql.ErrNoRows
+	}
+	return entry, nil
+}
+
+func (q *testQ) GetAccountSignersByAccountID(ctx context.Context, id string) ([]history.AccountSigner, error) {
+	entry := q.accounts[id]
+	return []history.AccountSigner{{Account: id, Signer: id, Weight: int32(entry.MasterWeight)}}, nil
+}
+
+func (q *testQ) GetSortedTrustLinesByAccountID(ctx context.Context, id string) ([]history.TrustLine, error) {
+	return q.trustLines[id], nil
+}
+
+func (q *testQ) GetAccountDataByName(ctx context.Context, id, name string) (history.Data, error) {
+	return history.Data{}, sql.ErrNoRows
+}
+
+func (q *testQ) GetOfferByID(ctx context.Context, id int64) (history.Offer, error) {
+	return history.Offer{}, sql.ErrNoRows
+}
+
+func (q *testQ) NoRows(err error) bool {
+	return err == sql.ErrNoRows
+}
+
+type testOrderBook []xdr.OfferEntry
+
+func (b testOrderBook) OffersForPair(selling, buying xdr.Asset) []xdr.OfferEntry {
+	var offers []xdr.OfferEntry
+	for _, offer := range b {
+		if offer.Selling.Equals(selling) && offer.Buying.Equals(buying) {
+			offers = append(offers, offer)
+		}
+	}
+	return offers
+}
+
+var (
+	passphrase = network.TestNetworkPassphrase
+	source     = keypair.MustRandom()
+	receiver   = keypair.MustRandom()
+	issuer     = keypair.MustRandom()
+	usd        = xdr.MustNewCreditAsset("USD", issuer.Address())
+)
+
+func newTestQ() *testQ {
+	q := &testQ{
+		ledger: history.Ledger{
+			Sequence:    10,
+			BaseFee:     100,
+			BaseReserve: 5000000,
+			ClosedAt:    time.Now(),
+		},
+		accounts:   map[string]history.AccountEntry{},
+		trustLines: map[string][]history.TrustLine{},
+	}
+	for _, kp := range []*keypair.Full{source, receiver, issuer} {
+		q.accounts[kp.Address()] = history.AccountEntry{
+			AccountID:      kp.Address(),
+			Balance:        1000 * 10000000,
+			SequenceNumber: 100,
+			MasterWeight:   1,
+		}
+	}
+	return q
+}
+
+func buildEnvelope(t *testing.T, sequence int64, signer *keypair.Full, ops ...txnbuild.Operation) xdr.TransactionEnvelope {
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &txnbuild.SimpleAccount{AccountID: source.Address(), Sequence: sequence},
+		IncrementSequenceNum: true,
+		Operations:           ops,
+		BaseFee:              txnbuild.MinBaseFee,
+		Timebounds:           txnbuild.NewInfiniteTimeout(),
+	})
+	assert.NoError(t, err)
+	tx, err = tx.Sign(passphrase, signer)
+	assert.NoError(t, err)
+	return tx.ToXDR()
+}
+
+func TestSimulatePayment(t *testing.T) {
+	tt := assert.New(t)
+	simulator := &Simulator{NetworkPassphrase: passphrase}
+	q := newTestQ()
+
+	envelope := buildEnvelope(t, 100, source, &txnbuild.Payment{
+		Destination: receiver.Address(),
+		Amount:      "10",
+		Asset:       txnbuild.NativeAsset{},
+	})
+	result, err := simulator.Simulate(context.Background(), q, envelope)
+	tt.NoError(err)
+	tt.True(result.Successful())
+	tt.Equal(int32(10), result.Ledger)
+	tt.Equal(int64(100), result.FeeCharged)
+	tt.Len(result.Hash, 64)
+	if tt.Len(result.Operations, 1) {
+		op := result.Operations[0]
+		tt.Equal("payment", op.Type)
+		tt.True(op.Simulated)
+		tt.Equal("op_success", op.Code)
+		if tt.Len(op.Effects, 2) {
+			tt.Equal("account_credited", op.Effects[0].Type)
+			tt.Equal(receiver.Address(), op.Effects[0].Account)
+			tt.Equal("10.0000000", op.Effects[0].Details["amount"])
+			tt.Equal("account_debited", op.Effects[1].Type)
+			tt.Equal(source.Address(), op.Effects[1].Account)
+		}
+	}
+
+	// the state in the database is not modified
+	tt.Equal(int64(100), q.accounts[source.Address()].SequenceNumber)
+}
+
+func TestSimulateTransactionFailures(t *testing.T) {
+	tt := assert.New(t)
+	simulator := &Simulator{NetworkPassphrase: passphrase}
+	payment := &txnbuild.Payment{
+		Destination: receiver.Address(),
+		Amount:      "10",
+		Asset:       txnbuild.NativeAsset{},
+	}
+
+	result, err := simulator.Simulate(context.Background(), newTestQ(), buildEnvelope(t, 101, source, payment))
+	tt.NoError(err)
+	tt.Equal("tx_bad_seq", result.TransactionCode)
+	tt.Empty(result.Operations)
+
+	result, err = simulator.Simulate(context.Background(), newTestQ(), buildEnvelope(t, 100, receiver, payment))
+	tt.NoError(err)
+	tt.Equal("tx_bad_auth", result.TransactionCode)
+
+	result, err = simulator.Simulate(context.Background(), newTestQ(), buildEnvelope(t, 100, source, &txnbuild.Payment{
+		Destination: receiver.Address(),
+		Amount:      "1000",
+		Asset:       txnbuild.NativeAsset{},
+	}))
+	tt.NoError(err)
+	tt.Equal("tx_failed", result.TransactionCode)
+	tt.Equal(int64(100), result.FeeCharged)
+	if tt.Len(result.Operations, 1) {
+		tt.Equal("op_underfunded", result.Operations[0].Code)
+		tt.Empty(result.Operations[0].Effects)
+	}
+}
+
+func TestSimulateTrustLines(t *testing.T) {
+	tt := assert.New(t)
+	simulator := &Simulator{NetworkPassphrase: passphrase}
+	q := newTestQ()
+	q.trustLines[source.Address()] = []history.TrustLine{{
+		AccountID:   source.Address(),
+		AssetType:   xdr.AssetTypeAssetTypeCreditAlphanum4,
+		AssetCode:   "USD",
+		AssetIssuer: issuer.Address(),
+		Balance:     500 * 10000000,
+		Limit:       1000 * 10000000,
+		Flags:       uint32(xdr.TrustLineFlagsAuthorizedFlag),
+	}}
+
+	usdAsset := txnbuild.CreditAsset{Code: "USD", Issuer: issuer.Address()}
+	envelope := buildEnvelope(t, 100, source, &txnbuild.Payment{
+		Destination: receiver.Address(),
+		Amount:      "10",
+		Asset:       usdAsset,
+	})
+	result, err := simulator.Simulate(context.Background(), q, envelope)
+	tt.NoError(err)
+	tt.Equal("tx_failed", result.TransactionCode)
+	if tt.Len(result.Operations, 1) {
+		tt.Equal("op_no_trust", result.Operations[0].Code)
+	}
+
+	// operations are applied on top of the changes of previous operations
+	receiverTrust := &txnbuild.ChangeTrust{
+		Line:          usdAsset.MustToChangeTrustAsset(),
+		Limit:         "100",
+		SourceAccount: receiver.Address(),
+	}
+	envelope = buildEnvelope(t, 100, source, receiverTrust, &txnbuild.Payment{
+		Destination: receiver.Address(),
+		Amount:      "10",
+		Asset:       usdAsset,
+	})
+	result, err = simulator.Simulate(context.Background(), q, envelope)
+	tt.NoError(err)
+	tt.Equal("tx_failed", result.TransactionCode)
+	if tt.Len(result.Operations, 2) {
+		tt.Equal("op_bad_auth", result.Operations[0].Code)
+		tt.False(result.Operations[1].Simulated)
+	}
+
+	tx, err := txnbuild.TransactionFromXDR(mustMarshal(t, envelope))
+	tt.NoError(err)
+	signed, ok := tx.Transaction()
+	tt.True(ok)
+	signed, err = signed.Sign(passphrase, receiver)
+	tt.NoError(err)
+	result, err = simulator.Simulate(context.Background(), q, signed.ToXDR())
+	tt.NoError(err)
+	tt.True(result.Successful())
+	if tt.Len(result.Operations, 2) {
+		tt.Equal("trustline_created", result.Operations[0].Effects[0].Type)
+		tt.Equal("op_success", result.Operations[1].Code)
+	}
+}
+
+func TestSimulatePathPayment(t *testing.T) {
+	tt := assert.New(t)
+	q := newTestQ()
+	q.trustLines[receiver.Address()] = []history.TrustLine{{
+		AccountID:   receiver.Address(),
+		AssetType:   xdr.AssetTypeAssetTypeCreditAlphanum4,
+		AssetCode:   "USD",
+		AssetIssuer: issuer.Address(),
+		Limit:       1000 * 10000000,
+		Flags:       uint32(xdr.TrustLineFlagsAuthorizedFlag),
+	}}
+	orderBook := testOrderBook{{
+		SellerId: xdr.MustAddress(issuer.Address()),
+		OfferId:  1,
+		Selling:  usd,
+		Buying:   xdr.MustNewNativeAsset(),
+		Amount:   50 * 10000000,
+		Price:    xdr.Price{N: 2, D: 1},
+	}}
+	simulator := &Simulator{NetworkPassphrase: passphrase, OrderBook: orderBook}
+
+	pathPayment := &txnbuild.PathPaymentStrictSend{
+		SendAsset:   txnbuild.NativeAsset{},
+		SendAmount:  "20",
+		Destination: receiver.Address(),
+		DestAsset:   txnbuild.CreditAsset{Code: "USD", Issuer: issuer.Address()},
+		DestMin:     "10",
+	}
+	result, err := simulator.Simulate(context.Background(), q, buildEnvelope(t, 100, source, pathPayment))
+	tt.NoError(err)
+	tt.True(result.Successful())
+	if tt.Len(result.Operations, 1) {
+		effects := result.Operations[0].Effects
+		if tt.Len(effects, 4) {
+			tt.Equal("account_credited", effects[0].Type)
+			tt.Equal("10.0000000", effects[0].Details["amount"])
+			tt.Equal("USD", effects[0].Details["asset_code"])
+			tt.Equal("trade", effects[2].Type)
+			tt.Equal(int64(1), effects[2].Details["offer_id"])
+			tt.Equal("20.0000000", effects[2].Details["sold_amount"])
+		}
+	}
+
+	pathPayment.DestMin = "11"
+	result, err = simulator.Simulate(context.Background(), q, buildEnvelope(t, 100, source, pathPayment))
+	tt.NoError(err)
+	if tt.Len(result.Operations, 1) {
+		tt.Equal("op_under_dest_min", result.Operations[0].Code)
+	}
+
+	pathPayment.DestMin = "10"
+	pathPayment.SendAmount = "200"
+	result, err = simulator.Simulate(context.Background(), q, buildEnvelope(t, 100, source, pathPayment))
+	tt.NoError(err)
+	if tt.Len(result.Operations, 1) {
+		tt.Equal("op_too_few_offers", result.Operations[0].Code)
+	}
+}
+
+func TestSimulateUnsupportedOperation(t *testing.T) {
+	tt := assert.New(t)
+	simulator := &Simulator{NetworkPassphrase: passphrase}
+
+	envelope := buildEnvelope(t, 100, source, &txnbuild.Inflation{})
+	result, err := simulator.Simulate(context.Background(), newTestQ(), envelope)
+	tt.NoError(err)
+	tt.Equal("tx_success", result.TransactionCode)
+	if tt.Len(result.Operations, 1) {
+		tt.Equal("inflation", result.Operations[0].Type)
+		tt.False(result.Operations[0].Simulated)
+		tt.Empty(result.Operations[0].Code)
+	}
+}
+
+func mustMarshal(t *testing.T, envelope xdr.TransactionEnvelope) string {
+	encoded, err := xdr.MarshalBase64(envelope)
+	assert.NoError(t, err)
+	return encoded
+}
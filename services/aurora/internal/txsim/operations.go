@@ -0,0 +1,1059 @@
+package txsim
+
+import (
+	"encoding/base64"
+	"math"
+
+	"github.com/diamnet/go/amount"
+	"github.com/diamnet/go/price"
+	"github.com/diamnet/go/protocols/aurora/effects"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/xdr"
+)
+
+// maxSigners is the maximum number of signers an account can have besides
+// the master key
+const maxSigners = 20
+
+// applyOperation applies the operation to the simulated state and returns
+// its result code and effects. A nil code means the operation type is not
+// simulated.
+func (s *ledgerState) applyOperation(sourceID string, op xdr.Operation) (interface{}, []Effect, error) {
+	source, err := s.account(sourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if source == nil {
+		// The source account was merged by a previous operation
+		return xdr.OperationResultCodeOpNoAccount, nil, nil
+	}
+
+	switch op.Body.Type {
+	case xdr.OperationTypeCreateAccount:
+		return s.createAccount(source, op.Body.MustCreateAccountOp())
+	case xdr.OperationTypePayment:
+		return s.payment(source, op.Body.MustPaymentOp())
+	case xdr.OperationTypePathPaymentStrictReceive:
+		return s.pathPaymentStrictReceive(source, op.Body.MustPathPaymentStrictReceiveOp())
+	case xdr.OperationTypePathPaymentStrictSend:
+		return s.pathPaymentStrictSend(source, op.Body.MustPathPaymentStrictSendOp())
+	case xdr.OperationTypeManageSellOffer:
+		return s.manageSellOffer(source, op.Body.MustManageSellOfferOp(), false)
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		passive := op.Body.MustCreatePassiveSellOfferOp()
+		return s.manageSellOffer(source, xdr.ManageSellOfferOp{
+			Selling: passive.Selling,
+			Buying:  passive.Buying,
+			Amount:  passive.Amount,
+			Price:   passive.Price,
+		}, true)
+	case xdr.OperationTypeManageBuyOffer:
+		return s.manageBuyOffer(source, op.Body.MustManageBuyOfferOp())
+	case xdr.OperationTypeChangeTrust:
+		return s.changeTrust(source, op.Body.MustChangeTrustOp())
+	case xdr.OperationTypeAllowTrust:
+		return s.allowTrust(source, op.Body.MustAllowTrustOp())
+	case xdr.OperationTypeSetTrustLineFlags:
+		return s.setTrustLineFlags(source, op.Body.MustSetTrustLineFlagsOp())
+	case xdr.OperationTypeAccountMerge:
+		return s.accountMerge(source, op.Body.MustDestination())
+	case xdr.OperationTypeManageData:
+		return s.manageData(source, op.Body.MustManageDataOp())
+	case xdr.OperationTypeBumpSequence:
+		return s.bumpSequence(source, op.Body.MustBumpSequenceOp())
+	case xdr.OperationTypeSetOptions:
+		return s.setOptions(source, op.Body.MustSetOptionsOp())
+	default:
+		return nil, nil, nil
+	}
+}
+
+func (s *ledgerState) createAccount(source *account, op xdr.CreateAccountOp) (interface{}, []Effect, error) {
+	destination := op.Destination.Address()
+	startingBalance := int64(op.StartingBalance)
+	if startingBalance < 0 || destination == source.entry.AccountID {
+		return xdr.CreateAccountResultCodeCreateAccountMalformed, nil, nil
+	}
+
+	existing, err := s.account(destination)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing != nil {
+		return xdr.CreateAccountResultCodeCreateAccountAlreadyExist, nil, nil
+	}
+	if startingBalance < 2*int64(s.ledger.BaseReserve) {
+		return xdr.CreateAccountResultCodeCreateAccountLowReserve, nil, nil
+	}
+	if s.availableNative(source) < startingBalance {
+		return xdr.CreateAccountResultCodeCreateAccountUnderfunded, nil, nil
+	}
+
+	source.entry.Balance -= startingBalance
+	s.accounts[destination] = &account{
+		entry: history.AccountEntry{
+			AccountID:      destination,
+			Balance:        startingBalance,
+			SequenceNumber: int64(s.ledger.Sequence) << 32,
+			MasterWeight:   1,
+		},
+		signers:    []history.AccountSigner{{Account: destination, Signer: destination, Weight: 1}},
+		trustLines: map[string]*history.TrustLine{},
+	}
+
+	return xdr.CreateAccountResultCodeCreateAccountSuccess, []Effect{
+		newEffect(effects.EffectAccountCreated, destination, map[string]interface{}{
+			"starting_balance": amount.StringFromInt64(startingBalance),
+		}),
+		newEffect(effects.EffectAccountDebited, source.entry.AccountID, map[string]interface{}{
+			"asset_type": "native",
+			"amount":     amount.StringFromInt64(startingBalance),
+		}),
+		newEffect(effects.EffectSignerCreated, destination, map[string]interface{}{
+			"public_key": destination,
+			"weight":     1,
+		}),
+	}, nil
+}
+
+// transferFailure is a failure shared by payments and path payments which
+// is mapped to the result code of the operation.
+type transferFailure int
+
+const (
+	transferOK transferFailure = iota
+	transferUnderfunded
+	transferSrcNoTrust
+	transferSrcNotAuthorized
+	transferNoDestination
+	transferNoTrust
+	transferNotAuthorized
+	transferLineFull
+	transferTooFewOffers
+	transferCrossSelf
+	// transferOverLimit is returned when a strict receive path payment
+	// exceeds its send max or a strict send path payment receives less than
+	// its destination min
+	transferOverLimit
+)
+
+// transfer describes a payment through the given path. The amount received
+// is fixed for strict receive payments and the amount sent for strict send
+// payments.
+type transfer struct {
+	destination string
+	sendAsset   xdr.Asset
+	destAsset   xdr.Asset
+	path        []xdr.Asset
+	amount      int64
+	limit       int64
+	strictSend  bool
+}
+
+func (s *ledgerState) transfer(source *account, t transfer) (transferFailure, []Effect, error) {
+	destination, err := s.account(t.destination)
+	if err != nil {
+		return transferOK, nil, err
+	}
+	if destination == nil {
+		return transferNoDestination, nil, nil
+	}
+
+	assets := append(append([]xdr.Asset{t.sendAsset}, t.path...), t.destAsset)
+	var (
+		sent, received int64
+		trades         []trade
+	)
+	if t.strictSend {
+		if failure, err := s.checkSend(source, t.sendAsset, t.amount); failure != transferOK || err != nil {
+			return failure, nil, err
+		}
+
+		sent, received = t.amount, t.amount
+		for i := 0; i+1 < len(assets); i++ {
+			if assets[i].Equals(assets[i+1]) {
+				continue
+			}
+			crossed, err := s.cross(exchange{
+				taker:      source.entry.AccountID,
+				selling:    assets[i],
+				buying:     assets[i+1],
+				maxSend:    received,
+				maxReceive: math.MaxInt64,
+				sendAll:    true,
+			})
+			if err != nil {
+				return transferOK, nil, err
+			}
+			if crossed.crossSelf {
+				return transferCrossSelf, nil, nil
+			}
+			if crossed.noLiquidity {
+				return transferTooFewOffers, nil, nil
+			}
+			received = crossed.received
+			trades = append(trades, crossed.trades...)
+		}
+		if received < t.limit {
+			return transferOverLimit, nil, nil
+		}
+		if failure, err := s.checkReceive(destination, t.destAsset, received); failure != transferOK || err != nil {
+			return failure, nil, err
+		}
+	} else {
+		if failure, err := s.checkReceive(destination, t.destAsset, t.amount); failure != transferOK || err != nil {
+			return failure, nil, err
+		}
+
+		sent, received = t.amount, t.amount
+		for i := len(assets) - 1; i > 0; i-- {
+			if assets[i-1].Equals(assets[i]) {
+				continue
+			}
+			crossed, err := s.cross(exchange{
+				taker:      source.entry.AccountID,
+				selling:    assets[i-1],
+				buying:     assets[i],
+				maxSend:    math.MaxInt64,
+				maxReceive: sent,
+			})
+			if err != nil {
+				return transferOK, nil, err
+			}
+			if crossed.crossSelf {
+				return transferCrossSelf, nil, nil
+			}
+			if crossed.noLiquidity {
+				return transferTooFewOffers, nil, nil
+			}
+			sent = crossed.sent
+			trades = append(trades, crossed.trades...)
+		}
+		if sent > t.limit {
+			return transferOverLimit, nil, nil
+		}
+		if failure, err := s.checkSend(source, t.sendAsset, sent); failure != transferOK || err != nil {
+			return failure, nil, err
+		}
+	}
+
+	if err = s.addBalance(source, t.sendAsset, -sent); err != nil {
+		return transferOK, nil, err
+	}
+	if err = s.addBalance(destination, t.destAsset, received); err != nil {
+		return transferOK, nil, err
+	}
+	tradeEffects, err := s.settle(source.entry.AccountID, trades)
+	if err != nil {
+		return transferOK, nil, err
+	}
+
+	credited := map[string]interface{}{"amount": amount.StringFromInt64(received)}
+	addAssetDetails(credited, t.destAsset, "")
+	debited := map[string]interface{}{"amount": amount.StringFromInt64(sent)}
+	addAssetDetails(debited, t.sendAsset, "")
+
+	result := []Effect{
+		newEffect(effects.EffectAccountCredited, destination.entry.AccountID, credited),
+		newEffect(effects.EffectAccountDebited, source.entry.AccountID, debited),
+	}
+	return transferOK, append(result, tradeEffects...), nil
+}
+
+func (s *ledgerState) checkSend(source *account, asset xdr.Asset, amount int64) (transferFailure, error) {
+	available, failure, err := s.available(source, asset)
+	switch {
+	case err != nil:
+		return transferOK, err
+	case failure == lineMissing:
+		return transferSrcNoTrust, nil
+	case failure == lineNotAuthorized:
+		return transferSrcNotAuthorized, nil
+	case available < amount:
+		return transferUnderfunded, nil
+	}
+	return transferOK, nil
+}
+
+func (s *ledgerState) checkReceive(destination *account, asset xdr.Asset, amount int64) (transferFailure, error) {
+	capacity, failure, err := s.capacity(destination, asset)
+	switch {
+	case err != nil:
+		return transferOK, err
+	case failure == lineMissing:
+		return transferNoTrust, nil
+	case failure == lineNotAuthorized:
+		return transferNotAuthorized, nil
+	case capacity < amount:
+		return transferLineFull, nil
+	}
+	return transferOK, nil
+}
+
+var paymentCodes = map[transferFailure]xdr.PaymentResultCode{
+	transferOK:               xdr.PaymentResultCodePaymentSuccess,
+	transferUnderfunded:      xdr.PaymentResultCodePaymentUnderfunded,
+	transferSrcNoTrust:       xdr.PaymentResultCodePaymentSrcNoTrust,
+	transferSrcNotAuthorized: xdr.PaymentResultCodePaymentSrcNotAuthorized,
+	transferNoDestination:    xdr.PaymentResultCodePaymentNoDestination,
+	transferNoTrust:          xdr.PaymentResultCodePaymentNoTrust,
+	transferNotAuthorized:    xdr.PaymentResultCodePaymentNotAuthorized,
+	transferLineFull:         xdr.PaymentResultCodePaymentLineFull,
+}
+
+func (s *ledgerState) payment(source *account, op xdr.PaymentOp) (interface{}, []Effect, error) {
+	if op.Amount <= 0 {
+		return xdr.PaymentResultCodePaymentMalformed, nil, nil
+	}
+
+	failure, opEffects, err := s.transfer(source, transfer{
+		destination: op.Destination.ToAccountId().Address(),
+		sendAsset:   op.Asset,
+		destAsset:   op.Asset,
+		amount:      int64(op.Amount),
+		limit:       int64(op.Amount),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return paymentCodes[failure], opEffects, nil
+}
+
+var pathPaymentStrictReceiveCodes = map[transferFailure]xdr.PathPaymentStrictReceiveResultCode{
+	transferOK:               xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSuccess,
+	transferUnderfunded:      xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveUnderfunded,
+	transferSrcNoTrust:       xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSrcNoTrust,
+	transferSrcNotAuthorized: xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSrcNotAuthorized,
+	transferNoDestination:    xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveNoDestination,
+	transferNoTrust:          xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveNoTrust,
+	transferNotAuthorized:    xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveNotAuthorized,
+	transferLineFull:         xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveLineFull,
+	transferTooFewOffers:     xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveTooFewOffers,
+	transferCrossSelf:        xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveOfferCrossSelf,
+	transferOverLimit:        xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveOverSendmax,
+}
+
+func (s *ledgerState) pathPaymentStrictReceive(source *account, op xdr.PathPaymentStrictReceiveOp) (interface{}, []Effect, error) {
+	if op.DestAmount <= 0 || op.SendMax <= 0 {
+		return xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveMalformed, nil, nil
+	}
+
+	failure, opEffects, err := s.transfer(source, transfer{
+		destination: op.Destination.ToAccountId().Address(),
+		sendAsset:   op.SendAsset,
+		destAsset:   op.DestAsset,
+		path:        op.Path,
+		amount:      int64(op.DestAmount),
+		limit:       int64(op.SendMax),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pathPaymentStrictReceiveCodes[failure], opEffects, nil
+}
+
+var pathPaymentStrictSendCodes = map[transferFailure]xdr.PathPaymentStrictSendResultCode{
+	transferOK:               xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+	transferUnderfunded:      xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendUnderfunded,
+	transferSrcNoTrust:       xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSrcNoTrust,
+	transferSrcNotAuthorized: xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSrcNotAuthorized,
+	transferNoDestination:    xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendNoDestination,
+	transferNoTrust:          xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendNoTrust,
+	transferNotAuthorized:    xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendNotAuthorized,
+	transferLineFull:         xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendLineFull,
+	transferTooFewOffers:     xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendTooFewOffers,
+	transferCrossSelf:        xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendOfferCrossSelf,
+	transferOverLimit:        xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendUnderDestmin,
+}
+
+func (s *ledgerState) pathPaymentStrictSend(source *account, op xdr.PathPaymentStrictSendOp) (interface{}, []Effect, error) {
+	if op.SendAmount <= 0 || op.DestMin <= 0 {
+		return xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendMalformed, nil, nil
+	}
+
+	failure, opEffects, err := s.transfer(source, transfer{
+		destination: op.Destination.ToAccountId().Address(),
+		sendAsset:   op.SendAsset,
+		destAsset:   op.DestAsset,
+		path:        op.Path,
+		amount:      int64(op.SendAmount),
+		limit:       int64(op.DestMin),
+		strictSend:  true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pathPaymentStrictSendCodes[failure], opEffects, nil
+}
+
+// offerFailure is a failure shared by manage sell and manage buy offer
+// operations which is mapped to the result code of the operation.
+type offerFailure int
+
+const (
+	offerOK offerFailure = iota
+	offerMalformed
+	offerSellNoTrust
+	offerBuyNoTrust
+	offerSellNotAuthorized
+	offerBuyNotAuthorized
+	offerLineFull
+	offerUnderfunded
+	offerCrossSelf
+	offerNotFound
+	offerLowReserve
+)
+
+var manageSellOfferCodes = map[offerFailure]xdr.ManageSellOfferResultCode{
+	offerOK:                xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+	offerMalformed:         xdr.ManageSellOfferResultCodeManageSellOfferMalformed,
+	offerSellNoTrust:       xdr.ManageSellOfferResultCodeManageSellOfferSellNoTrust,
+	offerBuyNoTrust:        xdr.ManageSellOfferResultCodeManageSellOfferBuyNoTrust,
+	offerSellNotAuthorized: xdr.ManageSellOfferResultCodeManageSellOfferSellNotAuthorized,
+	offerBuyNotAuthorized:  xdr.ManageSellOfferResultCodeManageSellOfferBuyNotAuthorized,
+	offerLineFull:          xdr.ManageSellOfferResultCodeManageSellOfferLineFull,
+	offerUnderfunded:       xdr.ManageSellOfferResultCodeManageSellOfferUnderfunded,
+	offerCrossSelf:         xdr.ManageSellOfferResultCodeManageSellOfferCrossSelf,
+	offerNotFound:          xdr.ManageSellOfferResultCodeManageSellOfferNotFound,
+	offerLowReserve:        xdr.ManageSellOfferResultCodeManageSellOfferLowReserve,
+}
+
+var manageBuyOfferCodes = map[offerFailure]xdr.ManageBuyOfferResultCode{
+	offerOK:                xdr.ManageBuyOfferResultCodeManageBuyOfferSuccess,
+	offerMalformed:         xdr.ManageBuyOfferResultCodeManageBuyOfferMalformed,
+	offerSellNoTrust:       xdr.ManageBuyOfferResultCodeManageBuyOfferSellNoTrust,
+	offerBuyNoTrust:        xdr.ManageBuyOfferResultCodeManageBuyOfferBuyNoTrust,
+	offerSellNotAuthorized: xdr.ManageBuyOfferResultCodeManageBuyOfferSellNotAuthorized,
+	offerBuyNotAuthorized:  xdr.ManageBuyOfferResultCodeManageBuyOfferBuyNotAuthorized,
+	offerLineFull:          xdr.ManageBuyOfferResultCodeManageBuyOfferLineFull,
+	offerUnderfunded:       xdr.ManageBuyOfferResultCodeManageBuyOfferUnderfunded,
+	offerCrossSelf:         xdr.ManageBuyOfferResultCodeManageBuyOfferCrossSelf,
+	offerNotFound:          xdr.ManageBuyOfferResultCodeManageBuyOfferNotFound,
+	offerLowReserve:        xdr.ManageBuyOfferResultCodeManageBuyOfferLowReserve,
+}
+
+// offerChange describes a manage offer operation. amount is the amount of
+// selling for sell offers and the amount of buying for buy offers, price is
+// always expressed in units of selling per unit of buying.
+type offerChange struct {
+	selling xdr.Asset
+	buying  xdr.Asset
+	amount  int64
+	price   xdr.Price
+	offerID int64
+	buy     bool
+	passive bool
+}
+
+func (s *ledgerState) manageSellOffer(source *account, op xdr.ManageSellOfferOp, passive bool) (interface{}, []Effect, error) {
+	failure, opEffects, err := s.manageOffer(source, offerChange{
+		selling: op.Selling,
+		buying:  op.Buying,
+		amount:  int64(op.Amount),
+		price:   xdr.Price{N: op.Price.D, D: op.Price.N},
+		offerID: int64(op.OfferId),
+		passive: passive,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return manageSellOfferCodes[failure], opEffects, nil
+}
+
+func (s *ledgerState) manageBuyOffer(source *account, op xdr.ManageBuyOfferOp) (interface{}, []Effect, error) {
+	failure, opEffects, err := s.manageOffer(source, offerChange{
+		selling: op.Selling,
+		buying:  op.Buying,
+		amount:  int64(op.BuyAmount),
+		price:   op.Price,
+		offerID: int64(op.OfferId),
+		buy:     true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return manageBuyOfferCodes[failure], opEffects, nil
+}
+
+func (s *ledgerState) manageOffer(source *account, change offerChange) (offerFailure, []Effect, error) {
+	if change.selling.Equals(change.buying) ||
+		change.amount < 0 ||
+		change.price.N <= 0 || change.price.D <= 0 ||
+		(change.offerID == 0 && change.amount == 0) {
+		return offerMalformed, nil, nil
+	}
+
+	var existing *history.Offer
+	if change.offerID != 0 {
+		var err error
+		existing, err = s.offer(change.offerID)
+		if err != nil {
+			return offerOK, nil, err
+		}
+		if existing == nil || existing.SellerID != source.entry.AccountID {
+			return offerNotFound, nil, nil
+		}
+		if change.amount == 0 {
+			s.offers[change.offerID] = nil
+			source.entry.NumSubEntries--
+			return offerOK, nil, nil
+		}
+	}
+
+	available, failure, err := s.available(source, change.selling)
+	switch {
+	case err != nil:
+		return offerOK, nil, err
+	case failure == lineMissing:
+		return offerSellNoTrust, nil, nil
+	case failure == lineNotAuthorized:
+		return offerSellNotAuthorized, nil, nil
+	}
+	capacity, failure, err := s.capacity(source, change.buying)
+	switch {
+	case err != nil:
+		return offerOK, nil, err
+	case failure == lineMissing:
+		return offerBuyNoTrust, nil, nil
+	case failure == lineNotAuthorized:
+		return offerBuyNotAuthorized, nil, nil
+	}
+	if available <= 0 {
+		return offerUnderfunded, nil, nil
+	}
+	if capacity <= 0 {
+		return offerLineFull, nil, nil
+	}
+	if existing == nil && source.entry.Balance-source.entry.SellingLiabilities < s.minBalance(source, 1) {
+		return offerLowReserve, nil, nil
+	}
+
+	ex := exchange{
+		taker:      source.entry.AccountID,
+		selling:    change.selling,
+		buying:     change.buying,
+		maxSend:    available,
+		maxReceive: capacity,
+		maxPrice:   &change.price,
+		passive:    change.passive,
+	}
+	if change.buy && change.amount < ex.maxReceive {
+		ex.maxReceive = change.amount
+	} else if !change.buy && change.amount < ex.maxSend {
+		ex.maxSend = change.amount
+	}
+
+	crossed, err := s.cross(ex)
+	if err != nil {
+		return offerOK, nil, err
+	}
+	if crossed.crossSelf {
+		return offerCrossSelf, nil, nil
+	}
+
+	if err = s.addBalance(source, change.selling, -crossed.sent); err != nil {
+		return offerOK, nil, err
+	}
+	if err = s.addBalance(source, change.buying, crossed.received); err != nil {
+		return offerOK, nil, err
+	}
+	tradeEffects, err := s.settle(source.entry.AccountID, crossed.trades)
+	if err != nil {
+		return offerOK, nil, err
+	}
+
+	// remaining is the amount of selling left in the offer
+	remaining := change.amount - crossed.sent
+	if change.buy {
+		remaining, err = price.MulFractionRoundDown(change.amount-crossed.received, int64(change.price.N), int64(change.price.D))
+		if err != nil {
+			return offerOK, nil, err
+		}
+	}
+	switch {
+	case existing == nil && remaining > 0:
+		source.entry.NumSubEntries++
+	case existing != nil && remaining <= 0:
+		s.offers[change.offerID] = nil
+		source.entry.NumSubEntries--
+	case existing != nil:
+		existing.Amount = remaining
+	}
+
+	return offerOK, tradeEffects, nil
+}
+
+func (s *ledgerState) changeTrust(source *account, op xdr.ChangeTrustOp) (interface{}, []Effect, error) {
+	if op.Line.Type == xdr.AssetTypeAssetTypePoolShare {
+		return nil, nil, nil
+	}
+
+	asset := op.Line.ToAsset()
+	limit := int64(op.Limit)
+	if asset.Type == xdr.AssetTypeAssetTypeNative || limit < 0 {
+		return xdr.ChangeTrustResultCodeChangeTrustMalformed, nil, nil
+	}
+	if isIssuer(source.entry.AccountID, asset) {
+		return xdr.ChangeTrustResultCodeChangeTrustSelfNotAllowed, nil, nil
+	}
+
+	trustLine, err := s.trustLine(source, asset)
+	if err != nil {
+		return nil, nil, err
+	}
+	details := map[string]interface{}{"limit": amount.StringFromInt64(limit)}
+	addAssetDetails(details, asset, "")
+
+	switch {
+	case trustLine == nil && limit == 0:
+		return xdr.ChangeTrustResultCodeChangeTrustInvalidLimit, nil, nil
+	case trustLine == nil:
+		issuer, err := s.account(asset.GetIssuer())
+		if err != nil {
+			return nil, nil, err
+		}
+		if issuer == nil {
+			return xdr.ChangeTrustResultCodeChangeTrustNoIssuer, nil, nil
+		}
+		if source.entry.Balance-source.entry.SellingLiabilities < s.minBalance(source, 1) {
+			return xdr.ChangeTrustResultCodeChangeTrustLowReserve, nil, nil
+		}
+
+		var flags uint32
+		if !xdr.AccountFlags(issuer.entry.Flags).IsAuthRequired() {
+			flags = uint32(xdr.TrustLineFlagsAuthorizedFlag)
+		}
+		var assetType, code, issuerID string
+		if err = asset.Extract(&assetType, &code, &issuerID); err != nil {
+			return nil, nil, err
+		}
+		source.trustLines[asset.String()] = &history.TrustLine{
+			AccountID:   source.entry.AccountID,
+			AssetType:   asset.Type,
+			AssetIssuer: issuerID,
+			AssetCode:   code,
+			Limit:       limit,
+			Flags:       flags,
+		}
+		source.entry.NumSubEntries++
+		return xdr.ChangeTrustResultCodeChangeTrustSuccess, []Effect{
+			newEffect(effects.EffectTrustlineCreated, source.entry.AccountID, details),
+		}, nil
+	case limit < trustLine.Balance+trustLine.BuyingLiabilities:
+		return xdr.ChangeTrustResultCodeChangeTrustInvalidLimit, nil, nil
+	case limit == 0:
+		delete(source.trustLines, asset.String())
+		source.entry.NumSubEntries--
+		return xdr.ChangeTrustResultCodeChangeTrustSuccess, []Effect{
+			newEffect(effects.EffectTrustlineRemoved, source.entry.AccountID, details),
+		}, nil
+	default:
+		trustLine.Limit = limit
+		return xdr.ChangeTrustResultCodeChangeTrustSuccess, []Effect{
+			newEffect(effects.EffectTrustlineUpdated, source.entry.AccountID, details),
+		}, nil
+	}
+}
+
+// trustLineFailure is a failure shared by allow trust and set trust line
+// flags operations which is mapped to the result code of the operation.
+type trustLineFailure int
+
+const (
+	trustLineOK trustLineFailure = iota
+	trustLineMalformed
+	trustLineNoTrustLine
+	trustLineCantRevoke
+)
+
+var allowTrustCodes = map[trustLineFailure]xdr.AllowTrustResultCode{
+	trustLineOK:          xdr.AllowTrustResultCodeAllowTrustSuccess,
+	trustLineMalformed:   xdr.AllowTrustResultCodeAllowTrustMalformed,
+	trustLineNoTrustLine: xdr.AllowTrustResultCodeAllowTrustNoTrustLine,
+	trustLineCantRevoke:  xdr.AllowTrustResultCodeAllowTrustCantRevoke,
+}
+
+var setTrustLineFlagsCodes = map[trustLineFailure]xdr.SetTrustLineFlagsResultCode{
+	trustLineOK:          xdr.SetTrustLineFlagsResultCodeSetTrustLineFlagsSuccess,
+	trustLineMalformed:   xdr.SetTrustLineFlagsResultCodeSetTrustLineFlagsMalformed,
+	trustLineNoTrustLine: xdr.SetTrustLineFlagsResultCodeSetTrustLineFlagsNoTrustLine,
+	trustLineCantRevoke:  xdr.SetTrustLineFlagsResultCodeSetTrustLineFlagsCantRevoke,
+}
+
+func (s *ledgerState) allowTrust(source *account, op xdr.AllowTrustOp) (interface{}, []Effect, error) {
+	issuer, err := xdr.AddressToAccountId(source.entry.AccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	authorize := xdr.TrustLineFlags(op.Authorize)
+	failure, flagEffects, err := s.updateTrustLineFlags(
+		source,
+		op.Trustor.Address(),
+		op.Asset.ToAsset(issuer),
+		authorize,
+		(xdr.TrustLineFlagsAuthorizedFlag|xdr.TrustLineFlagsAuthorizedToMaintainLiabilitiesFlag)&^authorize,
+	)
+	if err != nil || failure != trustLineOK {
+		return allowTrustCodes[failure], nil, err
+	}
+
+	details := map[string]interface{}{"trustor": op.Trustor.Address()}
+	addAssetDetails(details, op.Asset.ToAsset(issuer), "")
+	effectType := effects.EffectTrustlineDeauthorized
+	switch {
+	case authorize.IsAuthorized():
+		effectType = effects.EffectTrustlineAuthorized
+	case authorize.IsAuthorizedToMaintainLiabilitiesFlag():
+		effectType = effects.EffectTrustlineAuthorizedToMaintainLiabilities
+	}
+	opEffects := []Effect{newEffect(effectType, source.entry.AccountID, details)}
+	return allowTrustCodes[failure], append(opEffects, flagEffects...), nil
+}
+
+func (s *ledgerState) setTrustLineFlags(source *account, op xdr.SetTrustLineFlagsOp) (interface{}, []Effect, error) {
+	failure, flagEffects, err := s.updateTrustLineFlags(
+		source,
+		op.Trustor.Address(),
+		op.Asset,
+		xdr.TrustLineFlags(op.SetFlags),
+		xdr.TrustLineFlags(op.ClearFlags),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return setTrustLineFlagsCodes[failure], flagEffects, nil
+}
+
+func (s *ledgerState) updateTrustLineFlags(
+	source *account,
+	trustorID string,
+	asset xdr.Asset,
+	setFlags, clearFlags xdr.TrustLineFlags,
+) (trustLineFailure, []Effect, error) {
+	if asset.Type == xdr.AssetTypeAssetTypeNative ||
+		trustorID == source.entry.AccountID ||
+		!isIssuer(source.entry.AccountID, asset) ||
+		setFlags&clearFlags != 0 {
+		return trustLineMalformed, nil, nil
+	}
+
+	trustor, err := s.account(trustorID)
+	if err != nil {
+		return trustLineOK, nil, err
+	}
+	if trustor == nil {
+		return trustLineNoTrustLine, nil, nil
+	}
+	trustLine, err := s.trustLine(trustor, asset)
+	if err != nil {
+		return trustLineOK, nil, err
+	}
+	if trustLine == nil {
+		return trustLineNoTrustLine, nil, nil
+	}
+
+	flags := (xdr.TrustLineFlags(trustLine.Flags) &^ clearFlags) | setFlags
+	revoked := xdr.TrustLineFlags(trustLine.Flags).IsAuthorized() && !flags.IsAuthorized()
+	if revoked && !xdr.AccountFlags(source.entry.Flags).IsAuthRevocable() {
+		return trustLineCantRevoke, nil, nil
+	}
+	trustLine.Flags = uint32(flags)
+
+	details := map[string]interface{}{"trustor": trustorID}
+	addAssetDetails(details, asset, "")
+	setTrustLineFlagDetails(details, setFlags, true)
+	setTrustLineFlagDetails(details, clearFlags, false)
+	return trustLineOK, []Effect{
+		newEffect(effects.EffectTrustlineFlagsUpdated, source.entry.AccountID, details),
+	}, nil
+}
+
+func (s *ledgerState) accountMerge(source *account, destination xdr.MuxedAccount) (interface{}, []Effect, error) {
+	destinationID := destination.ToAccountId().Address()
+	if destinationID == source.entry.AccountID {
+		return xdr.AccountMergeResultCodeAccountMergeMalformed, nil, nil
+	}
+
+	dest, err := s.account(destinationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dest == nil {
+		return xdr.AccountMergeResultCodeAccountMergeNoAccount, nil, nil
+	}
+	if xdr.AccountFlags(source.entry.Flags).IsAuthImmutable() {
+		return xdr.AccountMergeResultCodeAccountMergeImmutableSet, nil, nil
+	}
+
+	signers, err := s.signers(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	var additionalSigners uint32
+	for _, signer := range signers {
+		if signer.Signer != source.entry.AccountID {
+			additionalSigners++
+		}
+	}
+	if source.entry.NumSubEntries > additionalSigners {
+		return xdr.AccountMergeResultCodeAccountMergeHasSubEntries, nil, nil
+	}
+	if source.entry.NumSponsoring > 0 {
+		return xdr.AccountMergeResultCodeAccountMergeIsSponsor, nil, nil
+	}
+	if source.entry.SequenceNumber >= int64(s.ledger.Sequence)<<32 {
+		return xdr.AccountMergeResultCodeAccountMergeSeqnumTooFar, nil, nil
+	}
+	if source.entry.Balance > math.MaxInt64-dest.entry.Balance-dest.entry.BuyingLiabilities {
+		return xdr.AccountMergeResultCodeAccountMergeDestFull, nil, nil
+	}
+
+	balance := source.entry.Balance
+	dest.entry.Balance += balance
+	s.accounts[source.entry.AccountID] = nil
+
+	details := map[string]interface{}{
+		"amount":     amount.StringFromInt64(balance),
+		"asset_type": "native",
+	}
+	return xdr.AccountMergeResultCodeAccountMergeSuccess, []Effect{
+		newEffect(effects.EffectAccountDebited, source.entry.AccountID, details),
+		newEffect(effects.EffectAccountCredited, destinationID, details),
+		newEffect(effects.EffectAccountRemoved, source.entry.AccountID, map[string]interface{}{}),
+	}, nil
+}
+
+func (s *ledgerState) manageData(source *account, op xdr.ManageDataOp) (interface{}, []Effect, error) {
+	name := string(op.DataName)
+	if name == "" {
+		return xdr.ManageDataResultCodeManageDataInvalidName, nil, nil
+	}
+
+	existing, err := s.dataEntry(source.entry.AccountID, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := dataKey{account: source.entry.AccountID, name: name}
+	details := map[string]interface{}{"name": name}
+	if op.DataValue == nil {
+		if existing == nil {
+			return xdr.ManageDataResultCodeManageDataNameNotFound, nil, nil
+		}
+		s.data[key] = nil
+		source.entry.NumSubEntries--
+		return xdr.ManageDataResultCodeManageDataSuccess, []Effect{
+			newEffect(effects.EffectDataRemoved, source.entry.AccountID, details),
+		}, nil
+	}
+
+	details["value"] = base64.StdEncoding.EncodeToString(*op.DataValue)
+	if existing != nil {
+		existing.Value = history.AccountDataValue(*op.DataValue)
+		return xdr.ManageDataResultCodeManageDataSuccess, []Effect{
+			newEffect(effects.EffectDataUpdated, source.entry.AccountID, details),
+		}, nil
+	}
+
+	if source.entry.Balance-source.entry.SellingLiabilities < s.minBalance(source, 1) {
+		return xdr.ManageDataResultCodeManageDataLowReserve, nil, nil
+	}
+	s.data[key] = &history.Data{
+		AccountID: source.entry.AccountID,
+		Name:      name,
+		Value:     history.AccountDataValue(*op.DataValue),
+	}
+	source.entry.NumSubEntries++
+	return xdr.ManageDataResultCodeManageDataSuccess, []Effect{
+		newEffect(effects.EffectDataCreated, source.entry.AccountID, details),
+	}, nil
+}
+
+func (s *ledgerState) bumpSequence(source *account, op xdr.BumpSequenceOp) (interface{}, []Effect, error) {
+	bumpTo := int64(op.BumpTo)
+	if bumpTo < 0 {
+		return xdr.BumpSequenceResultCodeBumpSequenceBadSeq, nil, nil
+	}
+	if bumpTo <= source.entry.SequenceNumber {
+		return xdr.BumpSequenceResultCodeBumpSequenceSuccess, nil, nil
+	}
+
+	source.entry.SequenceNumber = bumpTo
+	return xdr.BumpSequenceResultCodeBumpSequenceSuccess, []Effect{
+		newEffect(effects.EffectSequenceBumped, source.entry.AccountID, map[string]interface{}{
+			"new_seq": bumpTo,
+		}),
+	}, nil
+}
+
+func (s *ledgerState) setOptions(source *account, op xdr.SetOptionsOp) (interface{}, []Effect, error) {
+	for _, threshold := range []*xdr.Uint32{op.MasterWeight, op.LowThreshold, op.MedThreshold, op.HighThreshold} {
+		if threshold != nil && *threshold > math.MaxUint8 {
+			return xdr.SetOptionsResultCodeSetOptionsThresholdOutOfRange, nil, nil
+		}
+	}
+
+	var setFlags, clearFlags xdr.AccountFlags
+	if op.SetFlags != nil {
+		setFlags = xdr.AccountFlags(*op.SetFlags)
+	}
+	if op.ClearFlags != nil {
+		clearFlags = xdr.AccountFlags(*op.ClearFlags)
+	}
+	if (setFlags|clearFlags)&^xdr.MaskAccountFlagsV17 != 0 {
+		return xdr.SetOptionsResultCodeSetOptionsUnknownFlag, nil, nil
+	}
+	if setFlags&clearFlags != 0 {
+		return xdr.SetOptionsResultCodeSetOptionsBadFlags, nil, nil
+	}
+	if (setFlags|clearFlags) != 0 && xdr.AccountFlags(source.entry.Flags).IsAuthImmutable() {
+		return xdr.SetOptionsResultCodeSetOptionsCantChange, nil, nil
+	}
+
+	if op.InflationDest != nil {
+		destination, err := s.account(op.InflationDest.Address())
+		if err != nil {
+			return nil, nil, err
+		}
+		if destination == nil {
+			return xdr.SetOptionsResultCodeSetOptionsInvalidInflation, nil, nil
+		}
+	}
+
+	signers, err := s.signers(source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var opEffects []Effect
+	if op.Signer != nil {
+		key := op.Signer.Key.Address()
+		weight := int32(op.Signer.Weight)
+		if key == source.entry.AccountID || weight > math.MaxUint8 {
+			return xdr.SetOptionsResultCodeSetOptionsBadSigner, nil, nil
+		}
+
+		index, additionalSigners := -1, 0
+		for i, signer := range signers {
+			if signer.Signer == key {
+				index = i
+			}
+			if signer.Signer != source.entry.AccountID {
+				additionalSigners++
+			}
+		}
+		switch {
+		case index == -1 && weight == 0:
+		case index == -1:
+			if additionalSigners >= maxSigners {
+				return xdr.SetOptionsResultCodeSetOptionsTooManySigners, nil, nil
+			}
+			if source.entry.Balance-source.entry.SellingLiabilities < s.minBalance(source, 1) {
+				return xdr.SetOptionsResultCodeSetOptionsLowReserve, nil, nil
+			}
+			source.signers = append(signers, history.AccountSigner{
+				Account: source.entry.AccountID,
+				Signer:  key,
+				Weight:  weight,
+			})
+			source.entry.NumSubEntries++
+			opEffects = append(opEffects, newEffect(effects.EffectSignerCreated, source.entry.AccountID, map[string]interface{}{
+				"public_key": key,
+				"weight":     weight,
+			}))
+		case weight == 0:
+			source.signers = append(signers[:index:index], signers[index+1:]...)
+			source.entry.NumSubEntries--
+			opEffects = append(opEffects, newEffect(effects.EffectSignerRemoved, source.entry.AccountID, map[string]interface{}{
+				"public_key": key,
+			}))
+		default:
+			signers[index].Weight = weight
+			opEffects = append(opEffects, newEffect(effects.EffectSignerUpdated, source.entry.AccountID, map[string]interface{}{
+				"public_key": key,
+				"weight":     weight,
+			}))
+		}
+	}
+
+	if op.MasterWeight != nil {
+		opEffects = append(opEffects, s.setMasterWeight(source, int32(*op.MasterWeight))...)
+	}
+
+	thresholdDetails := map[string]interface{}{}
+	if op.LowThreshold != nil {
+		source.entry.ThresholdLow = byte(*op.LowThreshold)
+		thresholdDetails["low_threshold"] = *op.LowThreshold
+	}
+	if op.MedThreshold != nil {
+		source.entry.ThresholdMedium = byte(*op.MedThreshold)
+		thresholdDetails["med_threshold"] = *op.MedThreshold
+	}
+	if op.HighThreshold != nil {
+		source.entry.ThresholdHigh = byte(*op.HighThreshold)
+		thresholdDetails["high_threshold"] = *op.HighThreshold
+	}
+	if len(thresholdDetails) > 0 {
+		opEffects = append(opEffects, newEffect(effects.EffectAccountThresholdsUpdated, source.entry.AccountID, thresholdDetails))
+	}
+
+	if op.HomeDomain != nil {
+		source.entry.HomeDomain = string(*op.HomeDomain)
+		opEffects = append(opEffects, newEffect(effects.EffectAccountHomeDomainUpdated, source.entry.AccountID, map[string]interface{}{
+			"home_domain": string(*op.HomeDomain),
+		}))
+	}
+
+	if setFlags|clearFlags != 0 {
+		source.entry.Flags = uint32((xdr.AccountFlags(source.entry.Flags) &^ clearFlags) | setFlags)
+		flagDetails := map[string]interface{}{}
+		setAuthFlagDetails(flagDetails, setFlags, true)
+		setAuthFlagDetails(flagDetails, clearFlags, false)
+		opEffects = append(opEffects, newEffect(effects.EffectAccountFlagsUpdated, source.entry.AccountID, flagDetails))
+	}
+
+	if op.InflationDest != nil {
+		source.entry.InflationDestination = op.InflationDest.Address()
+		opEffects = append(opEffects, newEffect(effects.EffectAccountInflationDestinationUpdated, source.entry.AccountID, map[string]interface{}{
+			"inflation_destination": op.InflationDest.Address(),
+		}))
+	}
+
+	return xdr.SetOptionsResultCodeSetOptionsSuccess, opEffects, nil
+}
+
+// setMasterWeight updates the master key weight which is stored both in the
+// account entry and, if it is positive, in the list of signers.
+func (s *ledgerState) setMasterWeight(source *account, weight int32) []Effect {
+	previous := int32(source.entry.MasterWeight)
+	source.entry.MasterWeight = byte(weight)
+
+	remaining := source.signers[:0]
+	for _, signer := range source.signers {
+		if signer.Signer != source.entry.AccountID {
+			remaining = append(remaining, signer)
+		}
+	}
+	if weight > 0 {
+		remaining = append(remaining, history.AccountSigner{
+			Account: source.entry.AccountID,
+			Signer:  source.entry.AccountID,
+			Weight:  weight,
+		})
+	}
+	source.signers = remaining
+
+	details := map[string]interface{}{"public_key": source.entry.AccountID}
+	switch {
+	case previous == weight:
+		return nil
+	case weight == 0:
+		return []Effect{newEffect(effects.EffectSignerRemoved, source.entry.AccountID, details)}
+	case previous == 0:
+		details["weight"] = weight
+		return []Effect{newEffect(effects.EffectSignerCreated, source.entry.AccountID, details)}
+	default:
+		details["weight"] = weight
+		return []Effect{newEffect(effects.EffectSignerUpdated, source.entry.AccountID, details)}
+	}
+}
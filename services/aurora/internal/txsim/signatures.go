@@ -0,0 +1,197 @@
+package txsim
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/diamnet/go/keypair"
+	"github.com/diamnet/go/strkey"
+	"github.com/diamnet/go/xdr"
+)
+
+type threshold int
+
+const (
+	thresholdLow threshold = iota
+	thresholdMedium
+	thresholdHigh
+)
+
+// signatureChecker keeps track of the signatures of a transaction used to
+// authorize its source accounts.
+type signatureChecker struct {
+	hash       [32]byte
+	signatures []xdr.DecoratedSignature
+	used       []bool
+}
+
+func newSignatureChecker(hash [32]byte, signatures []xdr.DecoratedSignature) *signatureChecker {
+	return &signatureChecker{
+		hash:       hash,
+		signatures: signatures,
+		used:       make([]bool, len(signatures)),
+	}
+}
+
+// weight returns the weight the signatures contribute for the given signers.
+// Every signer is counted at most once.
+func (c *signatureChecker) weight(signers []signer) int32 {
+	var total int32
+	for _, signer := range signers {
+		for i, signature := range c.signatures {
+			if signer.verify(c.hash, signature) {
+				c.used[i] = true
+				total += signer.weight
+				break
+			}
+		}
+	}
+	return total
+}
+
+// allUsed returns true if every signature authorized at least one signer.
+func (c *signatureChecker) allUsed() bool {
+	for _, used := range c.used {
+		if !used {
+			return false
+		}
+	}
+	return true
+}
+
+type signer struct {
+	key    string
+	weight int32
+}
+
+func (s signer) verify(hash [32]byte, signature xdr.DecoratedSignature) bool {
+	version, err := strkey.Version(s.key)
+	if err != nil {
+		return false
+	}
+
+	switch version {
+	case strkey.VersionByteAccountID:
+		kp, err := keypair.ParseAddress(s.key)
+		if err != nil {
+			return false
+		}
+		hint := kp.Hint()
+		if !bytes.Equal(hint[:], signature.Hint[:]) {
+			return false
+		}
+		return kp.Verify(hash[:], signature.Signature) == nil
+	case strkey.VersionByteHashTx:
+		// Pre-authorized transactions do not need a signature, they are
+		// matched against the transaction hash in checkSignatures
+		return false
+	case strkey.VersionByteHashX:
+		raw, err := strkey.Decode(strkey.VersionByteHashX, s.key)
+		if err != nil {
+			return false
+		}
+		preimageHash := sha256.Sum256(signature.Signature)
+		return bytes.Equal(raw, preimageHash[:])
+	default:
+		return false
+	}
+}
+
+// preAuthorized returns true if the signer is a pre-authorized transaction
+// signer for the given hash.
+func (s signer) preAuthorized(hash [32]byte) bool {
+	raw, err := strkey.Decode(strkey.VersionByteHashTx, s.key)
+	return err == nil && bytes.Equal(raw, hash[:])
+}
+
+// checkSignatures returns true if the signatures meet the given threshold of
+// the account.
+func (s *ledgerState) checkSignatures(checker *signatureChecker, acc *account, level threshold) (bool, error) {
+	accountSigners, err := s.signers(acc)
+	if err != nil {
+		return false, err
+	}
+
+	var needed int32
+	switch level {
+	case thresholdLow:
+		needed = int32(acc.entry.ThresholdLow)
+	case thresholdMedium:
+		needed = int32(acc.entry.ThresholdMedium)
+	case thresholdHigh:
+		needed = int32(acc.entry.ThresholdHigh)
+	}
+	if needed == 0 {
+		needed = 1
+	}
+
+	var total int32
+	signers := make([]signer, 0, len(accountSigners))
+	for _, accountSigner := range accountSigners {
+		candidate := signer{key: accountSigner.Signer, weight: accountSigner.Weight}
+		if candidate.preAuthorized(checker.hash) {
+			total += candidate.weight
+			continue
+		}
+		signers = append(signers, candidate)
+	}
+	total += checker.weight(signers)
+
+	return total >= needed, nil
+}
+
+// checkOperationSignatures returns OperationResultCodeOpInner if the source
+// account of the operation exists and the signatures meet the threshold of
+// the operation.
+func (s *ledgerState) checkOperationSignatures(
+	checker *signatureChecker,
+	txSource string,
+	op xdr.Operation,
+) (xdr.OperationResultCode, error) {
+	sourceID := txSource
+	if op.SourceAccount != nil {
+		sourceID = op.SourceAccount.ToAccountId().Address()
+	}
+
+	source, err := s.account(sourceID)
+	if err != nil {
+		return 0, err
+	}
+	if source == nil {
+		return xdr.OperationResultCodeOpNoAccount, nil
+	}
+
+	ok, err := s.checkSignatures(checker, source, operationThreshold(op))
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return xdr.OperationResultCodeOpBadAuth, nil
+	}
+	return xdr.OperationResultCodeOpInner, nil
+}
+
+func operationThreshold(op xdr.Operation) threshold {
+	switch op.Body.Type {
+	case xdr.OperationTypeAllowTrust,
+		xdr.OperationTypeBumpSequence,
+		xdr.OperationTypeSetTrustLineFlags,
+		xdr.OperationTypeClaimClaimableBalance,
+		xdr.OperationTypeInflation:
+		return thresholdLow
+	case xdr.OperationTypeAccountMerge:
+		return thresholdHigh
+	case xdr.OperationTypeSetOptions:
+		options := op.Body.MustSetOptionsOp()
+		if options.MasterWeight != nil ||
+			options.LowThreshold != nil ||
+			options.MedThreshold != nil ||
+			options.HighThreshold != nil ||
+			options.Signer != nil {
+			return thresholdHigh
+		}
+		return thresholdMedium
+	default:
+		return thresholdMedium
+	}
+}
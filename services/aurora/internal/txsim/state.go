@@ -0,0 +1,250 @@
+package txsim
+
+import (
+	"context"
+	"math"
+
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// account is an account loaded into the simulated state. Signers and trust
+// lines are loaded on first use.
+type account struct {
+	entry      history.AccountEntry
+	signers    []history.AccountSigner
+	trustLines map[string]*history.TrustLine
+}
+
+type dataKey struct {
+	account string
+	name    string
+}
+
+// ledgerState is a copy-on-read view of the ingested state which is modified
+// by simulated operations. Entries are loaded from the database the first
+// time they are used, a nil entry means the entry does not exist.
+type ledgerState struct {
+	ctx       context.Context
+	q         Q
+	orderBook OrderBook
+	ledger    history.Ledger
+
+	accounts map[string]*account
+	data     map[dataKey]*history.Data
+	offers   map[int64]*history.Offer
+	// crossed maps offers of the order book crossed by simulated operations
+	// to their remaining amount
+	crossed map[int64]int64
+}
+
+func newLedgerState(ctx context.Context, q Q, orderBook OrderBook, ledger history.Ledger) *ledgerState {
+	return &ledgerState{
+		ctx:       ctx,
+		q:         q,
+		orderBook: orderBook,
+		ledger:    ledger,
+		accounts:  map[string]*account{},
+		data:      map[dataKey]*history.Data{},
+		offers:    map[int64]*history.Offer{},
+		crossed:   map[int64]int64{},
+	}
+}
+
+func (s *ledgerState) account(id string) (*account, error) {
+	if loaded, ok := s.accounts[id]; ok {
+		return loaded, nil
+	}
+
+	entry, err := s.q.GetAccountByID(s.ctx, id)
+	if s.q.NoRows(err) {
+		s.accounts[id] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "could not load account")
+	}
+
+	loaded := &account{entry: entry}
+	s.accounts[id] = loaded
+	return loaded, nil
+}
+
+func (s *ledgerState) signers(acc *account) ([]history.AccountSigner, error) {
+	if acc.signers != nil {
+		return acc.signers, nil
+	}
+
+	signers, err := s.q.GetAccountSignersByAccountID(s.ctx, acc.entry.AccountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load account signers")
+	}
+	acc.signers = append([]history.AccountSigner{}, signers...)
+	return acc.signers, nil
+}
+
+func (s *ledgerState) loadTrustLines(acc *account) error {
+	if acc.trustLines != nil {
+		return nil
+	}
+
+	trustLines, err := s.q.GetSortedTrustLinesByAccountID(s.ctx, acc.entry.AccountID)
+	if err != nil {
+		return errors.Wrap(err, "could not load trust lines")
+	}
+
+	acc.trustLines = map[string]*history.TrustLine{}
+	for i := range trustLines {
+		if trustLines[i].LiquidityPoolID != "" {
+			continue
+		}
+		asset, err := xdr.BuildAsset(
+			xdr.AssetTypeToString[trustLines[i].AssetType],
+			trustLines[i].AssetIssuer,
+			trustLines[i].AssetCode,
+		)
+		if err != nil {
+			return errors.Wrap(err, "invalid trust line asset")
+		}
+		acc.trustLines[asset.String()] = &trustLines[i]
+	}
+	return nil
+}
+
+// trustLine returns the trust line of the account for the given credit asset
+// or nil if there is none.
+func (s *ledgerState) trustLine(acc *account, asset xdr.Asset) (*history.TrustLine, error) {
+	if err := s.loadTrustLines(acc); err != nil {
+		return nil, err
+	}
+	return acc.trustLines[asset.String()], nil
+}
+
+func (s *ledgerState) dataEntry(accountID, name string) (*history.Data, error) {
+	key := dataKey{account: accountID, name: name}
+	if loaded, ok := s.data[key]; ok {
+		return loaded, nil
+	}
+
+	data, err := s.q.GetAccountDataByName(s.ctx, accountID, name)
+	if s.q.NoRows(err) {
+		s.data[key] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "could not load account data")
+	}
+
+	s.data[key] = &data
+	return &data, nil
+}
+
+func (s *ledgerState) offer(id int64) (*history.Offer, error) {
+	if loaded, ok := s.offers[id]; ok {
+		return loaded, nil
+	}
+
+	offer, err := s.q.GetOfferByID(s.ctx, id)
+	if s.q.NoRows(err) {
+		s.offers[id] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "could not load offer")
+	}
+
+	s.offers[id] = &offer
+	return &offer, nil
+}
+
+// minBalance returns the minimum native balance of the account including the
+// reserve of numSubEntries additional sub entries.
+func (s *ledgerState) minBalance(acc *account, numSubEntries int64) int64 {
+	entries := 2 + int64(acc.entry.NumSubEntries) + numSubEntries +
+		int64(acc.entry.NumSponsoring) - int64(acc.entry.NumSponsored)
+	return entries * int64(s.ledger.BaseReserve)
+}
+
+// availableNative returns the native balance the account can spend.
+func (s *ledgerState) availableNative(acc *account) int64 {
+	return acc.entry.Balance - s.minBalance(acc, 0) - acc.entry.SellingLiabilities
+}
+
+func isIssuer(accountID string, asset xdr.Asset) bool {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return false
+	}
+	return asset.GetIssuer() == accountID
+}
+
+func isAuthorized(trustLine *history.TrustLine) bool {
+	return xdr.TrustLineFlags(trustLine.Flags).IsAuthorized()
+}
+
+// available returns the amount of the asset the account can send. The second
+// return value is an operation independent failure (no trust line or not
+// authorized) which callers map to their result codes.
+func (s *ledgerState) available(acc *account, asset xdr.Asset) (int64, lineFailure, error) {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return s.availableNative(acc), lineOK, nil
+	}
+	if isIssuer(acc.entry.AccountID, asset) {
+		return math.MaxInt64, lineOK, nil
+	}
+
+	trustLine, err := s.trustLine(acc, asset)
+	if err != nil || trustLine == nil {
+		return 0, lineMissing, err
+	}
+	if !isAuthorized(trustLine) {
+		return 0, lineNotAuthorized, nil
+	}
+	return trustLine.Balance - trustLine.SellingLiabilities, lineOK, nil
+}
+
+// capacity returns the amount of the asset the account can receive.
+func (s *ledgerState) capacity(acc *account, asset xdr.Asset) (int64, lineFailure, error) {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		return math.MaxInt64 - acc.entry.Balance - acc.entry.BuyingLiabilities, lineOK, nil
+	}
+	if isIssuer(acc.entry.AccountID, asset) {
+		return math.MaxInt64, lineOK, nil
+	}
+
+	trustLine, err := s.trustLine(acc, asset)
+	if err != nil || trustLine == nil {
+		return 0, lineMissing, err
+	}
+	if !isAuthorized(trustLine) {
+		return 0, lineNotAuthorized, nil
+	}
+	return trustLine.Limit - trustLine.Balance - trustLine.BuyingLiabilities, lineOK, nil
+}
+
+// addBalance adds delta to the balance of the account. The trust line must
+// have been checked with available or capacity before.
+func (s *ledgerState) addBalance(acc *account, asset xdr.Asset, delta int64) error {
+	if asset.Type == xdr.AssetTypeAssetTypeNative {
+		acc.entry.Balance += delta
+		return nil
+	}
+	if isIssuer(acc.entry.AccountID, asset) {
+		return nil
+	}
+
+	trustLine, err := s.trustLine(acc, asset)
+	if err != nil {
+		return err
+	}
+	if trustLine == nil {
+		return errors.New("missing trust line")
+	}
+	trustLine.Balance += delta
+	return nil
+}
+
+type lineFailure int
+
+const (
+	lineOK lineFailure = iota
+	lineMissing
+	lineNotAuthorized
+)
@@ -0,0 +1,148 @@
+package txsub
+
+import (
+	"context"
+	"time"
+
+	proto "github.com/diamnet/go/protocols/diamnetcore"
+	"github.com/diamnet/go/support/log"
+	"github.com/diamnet/go/xdr"
+)
+
+// asyncResult is the final result of a transaction submitted with SubmitAsync
+// which is kept around so clients polling for its status can find it.
+type asyncResult struct {
+	Result
+	FinishedAt time.Time
+	// MaxTime is the max time bound of the transaction, zero if the
+	// transaction has none.
+	MaxTime time.Time
+}
+
+// expired returns true once a transaction which timed out of the open
+// submission list can't be included in a ledger anymore: margin after its max
+// time bound, leaving time for the ledger closed before the max time bound to
+// be ingested. Transactions without a max time bound never expire.
+func (r asyncResult) expired(now time.Time, margin time.Duration) bool {
+	return r.Err == ErrTimeout && !r.MaxTime.IsZero() && now.After(r.MaxTime.Add(margin))
+}
+
+// retainedUntil returns the time until which the result is kept: retention
+// after the transaction finished or, for transactions which timed out,
+// retention after they expired, so that they are reported as pending until
+// then.
+func (r asyncResult) retainedUntil(retention, margin time.Duration) time.Time {
+	until := r.FinishedAt
+	if r.Err == ErrTimeout && !r.MaxTime.IsZero() {
+		if expiry := r.MaxTime.Add(margin); expiry.After(until) {
+			until = expiry
+		}
+	}
+	return until.Add(retention)
+}
+
+// SubmitAsync submits the provided base64 encoded transaction envelope to
+// diamnet-core and returns as soon as diamnet-core has responded, without
+// waiting for the transaction to be included in a ledger. Transactions accepted
+// by diamnet-core are added to the open submission list so their status can be
+// followed with SubmissionStatus.
+//
+// Transactions which are already in the history database are not submitted
+// again and are reported with the DUPLICATE status.
+func (sys *System) SubmitAsync(
+	ctx context.Context,
+	rawTx string,
+	envelope xdr.TransactionEnvelope,
+	hash string,
+) SubmissionResult {
+	sys.Init()
+
+	sys.Log.Ctx(ctx).WithFields(log.F{
+		"hash":    hash,
+		"tx_type": envelope.Type.String(),
+		"tx":      rawTx,
+	}).Info("Processing asynchronous transaction")
+
+	if _, err := txResultByHash(ctx, sys.DB(ctx), hash); err != ErrNoResults {
+		// failed transactions are in the ledger too
+		if _, failed := err.(*FailedTransactionError); err == nil || failed {
+			return SubmissionResult{Status: proto.TXStatusDuplicate}
+		}
+		return SubmissionResult{Err: err}
+	}
+
+	sr := sys.submitOnce(ctx, rawTx)
+	sys.updateTransactionTypeMetrics(envelope)
+
+	if sr.Status != proto.TXStatusPending && sr.Status != proto.TXStatusDuplicate {
+		return sr
+	}
+
+	listener := make(chan Result, 1)
+	if err := sys.Pending.Add(ctx, hash, listener); err != nil {
+		sys.Log.Ctx(ctx).WithError(err).WithField("hash", hash).
+			Error("could not add asynchronous submission to the open submission list")
+		return sr
+	}
+	var maxTime time.Time
+	if timeBounds := envelope.TimeBounds(); timeBounds != nil && timeBounds.MaxTime != 0 {
+		maxTime = time.Unix(int64(timeBounds.MaxTime), 0)
+	}
+	go sys.waitForAsyncResult(hash, maxTime, listener)
+
+	return sr
+}
+
+// waitForAsyncResult records the result of an asynchronous submission once the
+// open submission list has either found the transaction in a ledger or timed
+// it out.
+func (sys *System) waitForAsyncResult(hash string, maxTime time.Time, listener <-chan Result) {
+	result, ok := <-listener
+	if !ok {
+		return
+	}
+
+	sys.asyncMutex.Lock()
+	defer sys.asyncMutex.Unlock()
+	sys.asyncResults[hash] = asyncResult{Result: result, FinishedAt: time.Now(), MaxTime: maxTime}
+}
+
+// SubmissionStatus returns the status of a transaction submitted to this
+// instance. pending is true while the transaction can still be included in a
+// ledger: while it is in the open submission list and, once it timed out of
+// the list, until SubmissionTimeout after its max time bound. Otherwise,
+// result holds the outcome of the asynchronous submission of the transaction,
+// ErrTimeout if it expired, for AsyncResultRetention. found is false if the
+// transaction is unknown to this instance, including transactions without a
+// max time bound which timed out more than AsyncResultRetention ago.
+func (sys *System) SubmissionStatus(ctx context.Context, hash string) (result Result, pending bool, found bool) {
+	sys.Init()
+
+	for _, pendingHash := range sys.Pending.Pending(ctx) {
+		if pendingHash == hash {
+			return Result{}, true, true
+		}
+	}
+
+	sys.asyncMutex.Lock()
+	defer sys.asyncMutex.Unlock()
+	r, found := sys.asyncResults[hash]
+	if found && r.Err == ErrTimeout && !r.expired(time.Now(), sys.SubmissionTimeout) {
+		return Result{}, true, true
+	}
+	return r.Result, false, found
+}
+
+// cleanAsyncResults removes the results of asynchronous submissions which
+// finished, or expired, more than AsyncResultRetention ago.
+func (sys *System) cleanAsyncResults() {
+	sys.asyncMutex.Lock()
+	defer sys.asyncMutex.Unlock()
+
+	now := time.Now()
+	for hash, r := range sys.asyncResults {
+		if now.After(r.retainedUntil(sys.AsyncResultRetention, sys.SubmissionTimeout)) {
+			delete(sys.asyncResults, hash)
+		}
+	}
+}
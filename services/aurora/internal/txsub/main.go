@@ -68,6 +68,11 @@ type SubmissionResult struct {
 	// Duration records the time it took to submit a transaction
 	// to diamnet-core
 	Duration time.Duration
+
+	// Status is the status diamnet-core responded with (PENDING, DUPLICATE,
+	// ERROR or TRY_AGAIN_LATER). It is empty if the submission did not reach
+	// diamnet-core.
+	Status string
 }
 
 func (s SubmissionResult) IsBadSeq() (bool, error) {
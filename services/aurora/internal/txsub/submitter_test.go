@@ -20,6 +20,7 @@ func TestDefaultSubmitter(t *testing.T) {
 	s := NewDefaultSubmitter(http.DefaultClient, server.URL)
 	sr := s.Submit(ctx, "hello")
 	assert.Nil(t, sr.Err)
+	assert.Equal(t, "PENDING", sr.Status)
 	assert.True(t, sr.Duration > 0)
 	assert.Equal(t, "hello", server.LastRequest.URL.Query().Get("blob"))
 
@@ -33,6 +34,7 @@ func TestDefaultSubmitter(t *testing.T) {
 	s = NewDefaultSubmitter(http.DefaultClient, server.URL)
 	sr = s.Submit(ctx, "hello")
 	assert.Nil(t, sr.Err)
+	assert.Equal(t, "DUPLICATE", sr.Status)
 
 	// Errors when the diamnet-core url is empty
 
@@ -35,6 +35,9 @@ type System struct {
 
 	accountSeqPollInterval time.Duration
 
+	asyncMutex   sync.Mutex
+	asyncResults map[string]asyncResult
+
 	DB                func(context.Context) AuroraDB
 	Pending           OpenSubmissionList
 	Submitter         Submitter
@@ -42,6 +45,10 @@ type System struct {
 	SubmissionTimeout time.Duration
 	Log               *log.Entry
 
+	// AsyncResultRetention is how long the results of transactions submitted
+	// with SubmitAsync are kept after they are found in a ledger or expire.
+	AsyncResultRetention time.Duration
+
 	Metrics struct {
 		// SubmissionDuration exposes timing metrics about the rate and latency of
 		// submissions to diamnet-core
@@ -385,6 +392,8 @@ func (sys *System) Tick(ctx context.Context) {
 		return
 	}
 
+	sys.cleanAsyncResults()
+
 	sys.Metrics.OpenSubmissionsGauge.Set(float64(stillOpen))
 	sys.Metrics.BufferedSubmissionsGauge.Set(float64(sys.SubmissionQueue.Size()))
 }
@@ -421,6 +430,7 @@ func (sys *System) Init() {
 		})
 
 		sys.accountSeqPollInterval = time.Second
+		sys.asyncResults = map[string]asyncResult{}
 
 		if sys.SubmissionTimeout == 0 {
 			// HTTP clients in SDKs usually timeout in 60 seconds. We want SubmissionTimeout
@@ -431,6 +441,12 @@ func (sys *System) Init() {
 			// by sending a Timeout response.
 			sys.SubmissionTimeout = 30 * time.Second
 		}
+
+		if sys.AsyncResultRetention == 0 {
+			// long enough for clients of the asynchronous submission endpoint
+			// which poll infrequently to see that their transaction expired
+			sys.AsyncResultRetention = time.Hour
+		}
 	})
 }
 
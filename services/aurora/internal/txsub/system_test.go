@@ -434,6 +434,161 @@ func (suite *SystemTestSuite) TestTick_RemovesStaleSubmissions() {
 	}
 }
 
+func (suite *SystemTestSuite) TestSubmitAsync_Duplicate() {
+	suite.db.On("TransactionByHash", suite.ctx, mock.Anything, suite.successTx.Transaction.TransactionHash).
+		Run(func(args mock.Arguments) {
+			ptr := args.Get(1).(*history.Transaction)
+			*ptr = suite.successTx.Transaction
+		}).
+		Return(nil).Once()
+
+	sr := suite.system.SubmitAsync(
+		suite.ctx,
+		suite.successTx.Transaction.TxEnvelope,
+		suite.successXDR,
+		suite.successTx.Transaction.TransactionHash,
+	)
+
+	assert.NoError(suite.T(), sr.Err)
+	assert.Equal(suite.T(), "DUPLICATE", sr.Status)
+	assert.False(suite.T(), suite.submitter.WasSubmittedTo)
+}
+
+func (suite *SystemTestSuite) TestSubmitAsync_TryAgainLater() {
+	suite.db.On("TransactionByHash", suite.ctx, mock.Anything, suite.successTx.Transaction.TransactionHash).
+		Return(sql.ErrNoRows).Once()
+	suite.db.On("NoRows", sql.ErrNoRows).Return(true).Once()
+
+	suite.submitter.R.Status = "TRY_AGAIN_LATER"
+	sr := suite.system.SubmitAsync(
+		suite.ctx,
+		suite.successTx.Transaction.TxEnvelope,
+		suite.successXDR,
+		suite.successTx.Transaction.TransactionHash,
+	)
+
+	assert.NoError(suite.T(), sr.Err)
+	assert.Equal(suite.T(), "TRY_AGAIN_LATER", sr.Status)
+	assert.True(suite.T(), suite.submitter.WasSubmittedTo)
+	assert.Empty(suite.T(), suite.system.Pending.Pending(suite.ctx))
+
+	_, _, found := suite.system.SubmissionStatus(suite.ctx, suite.successTx.Transaction.TransactionHash)
+	assert.False(suite.T(), found)
+}
+
+// submitAsyncTimingOut submits the transaction asynchronously with the max
+// time bound and times it out of the open submission list.
+func (suite *SystemTestSuite) submitAsyncTimingOut(maxTime time.Time) string {
+	hash := suite.successTx.Transaction.TransactionHash
+	suite.db.On("TransactionByHash", suite.ctx, mock.Anything, hash).
+		Return(sql.ErrNoRows).Once()
+	suite.db.On("NoRows", sql.ErrNoRows).Return(true).Once()
+
+	envelope := suite.successXDR
+	tx := envelope.V1.Tx
+	if !maxTime.IsZero() {
+		tx.TimeBounds = &xdr.TimeBounds{MaxTime: xdr.TimePoint(maxTime.Unix())}
+	}
+	envelope.V1 = &xdr.TransactionV1Envelope{Tx: tx, Signatures: envelope.V1.Signatures}
+
+	suite.submitter.R.Status = "PENDING"
+	sr := suite.system.SubmitAsync(suite.ctx, suite.successTx.Transaction.TxEnvelope, envelope, hash)
+
+	assert.NoError(suite.T(), sr.Err)
+	assert.Equal(suite.T(), "PENDING", sr.Status)
+
+	_, pending, found := suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.True(suite.T(), found)
+	assert.True(suite.T(), pending)
+
+	_, err := suite.system.Pending.Clean(suite.ctx, 0)
+	assert.NoError(suite.T(), err)
+
+	assert.Eventually(suite.T(), func() bool {
+		suite.system.asyncMutex.Lock()
+		defer suite.system.asyncMutex.Unlock()
+		_, ok := suite.system.asyncResults[hash]
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	return hash
+}
+
+func (suite *SystemTestSuite) TestSubmitAsync_Expires() {
+	suite.system.SubmissionTimeout = time.Minute
+	hash := suite.submitAsyncTimingOut(time.Now().Add(-2 * time.Minute))
+	assert.Equal(suite.T(), float64(1), getMetricValue(suite.system.Metrics.SuccessfulSubmissionsCounter).GetCounter().GetValue())
+
+	r, pending, found := suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.True(suite.T(), found)
+	assert.False(suite.T(), pending)
+	assert.Equal(suite.T(), ErrTimeout, r.Err)
+
+	suite.system.AsyncResultRetention = time.Nanosecond
+	suite.system.cleanAsyncResults()
+	_, _, found = suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.False(suite.T(), found)
+}
+
+func (suite *SystemTestSuite) TestSubmitAsync_PendingUntilMaxTime() {
+	// the transaction timed out of the open submission list but can still be
+	// included in a ledger until its max time bound
+	suite.system.SubmissionTimeout = time.Minute
+	hash := suite.submitAsyncTimingOut(time.Now().Add(time.Hour))
+
+	_, pending, found := suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.True(suite.T(), found)
+	assert.True(suite.T(), pending)
+
+	// the result is kept until the transaction expired, even when its max
+	// time bound is further away than the retention
+	suite.system.AsyncResultRetention = time.Minute
+	suite.system.cleanAsyncResults()
+	_, pending, found = suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.True(suite.T(), found)
+	assert.True(suite.T(), pending)
+
+	suite.system.asyncMutex.Lock()
+	stored := suite.system.asyncResults[hash]
+	stored.MaxTime = time.Now().Add(-30 * time.Second)
+	suite.system.asyncResults[hash] = stored
+	suite.system.asyncMutex.Unlock()
+
+	// it is not expired until SubmissionTimeout after the max time bound
+	_, pending, _ = suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.True(suite.T(), pending)
+
+	suite.system.SubmissionTimeout = time.Second
+	r, pending, found := suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.True(suite.T(), found)
+	assert.False(suite.T(), pending)
+	assert.Equal(suite.T(), ErrTimeout, r.Err)
+
+	// the expired result is kept for the retention after it expired
+	suite.system.cleanAsyncResults()
+	_, _, found = suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.True(suite.T(), found)
+
+	suite.system.AsyncResultRetention = time.Nanosecond
+	suite.system.cleanAsyncResults()
+	_, _, found = suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.False(suite.T(), found)
+}
+
+func (suite *SystemTestSuite) TestSubmitAsync_NoMaxTime() {
+	// a transaction without max time bound is reported as pending until its
+	// result is cleaned
+	hash := suite.submitAsyncTimingOut(time.Time{})
+
+	_, pending, found := suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.True(suite.T(), found)
+	assert.True(suite.T(), pending)
+
+	suite.system.AsyncResultRetention = time.Nanosecond
+	suite.system.cleanAsyncResults()
+	_, _, found = suite.system.SubmissionStatus(suite.ctx, hash)
+	assert.False(suite.T(), found)
+}
+
 func TestSystemTestSuite(t *testing.T) {
 	suite.Run(t, new(SystemTestSuite))
 }
@@ -0,0 +1,196 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/resourceadapter"
+	"github.com/diamnet/go/support/errors"
+)
+
+const (
+	// SignatureHeader is the name of the header containing the signature of
+	// the event, see Sign.
+	SignatureHeader = "X-Aurora-Webhook-Signature"
+	// TimestampHeader is the name of the header containing the unix time
+	// the event was signed at.
+	TimestampHeader = "X-Aurora-Webhook-Timestamp"
+	// EventIDHeader is the name of the header containing the ID of the event.
+	// Events can be delivered more than once, receivers should use it for
+	// deduplication.
+	EventIDHeader = "X-Aurora-Webhook-Id"
+)
+
+type event struct {
+	id          string
+	operationID int64
+	body        []byte
+}
+
+// NewSecret generates a new random secret used to sign events of a
+// subscription.
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.Wrap(err, "could not generate secret")
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of `timestamp.body` keyed with the
+// subscription secret. Receivers should recompute it and compare it with the
+// value of the SignatureHeader.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) buildEvents(
+	ctx context.Context,
+	subscription history.WebhookSubscription,
+	operations []history.Operation,
+	transactions []history.Transaction,
+) ([]event, error) {
+	ledgerCache := map[int32]history.Ledger{}
+	var sequences []int32
+	for _, op := range operations {
+		if !Matches(subscription, op) {
+			continue
+		}
+		if _, ok := ledgerCache[op.LedgerSequence()]; !ok {
+			ledgerCache[op.LedgerSequence()] = history.Ledger{}
+			sequences = append(sequences, op.LedgerSequence())
+		}
+	}
+
+	if len(sequences) == 0 {
+		return nil, nil
+	}
+
+	var ledgers []history.Ledger
+	if err := d.q.LedgersBySequence(ctx, &ledgers, sequences...); err != nil {
+		return nil, errors.Wrap(err, "could not load ledgers")
+	}
+	for _, l := range ledgers {
+		ledgerCache[l.Sequence] = l
+	}
+
+	var events []event
+	for i, op := range operations {
+		if !Matches(subscription, op) {
+			continue
+		}
+
+		var transaction *history.Transaction
+		if i < len(transactions) {
+			transaction = &transactions[i]
+		}
+		resource, err := resourceadapter.NewOperation(
+			ctx,
+			op,
+			op.TransactionHash,
+			transaction,
+			ledgerCache[op.LedgerSequence()],
+		)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not build operation %d", op.ID)
+		}
+		operation, err := json.Marshal(resource)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not marshal operation %d", op.ID)
+		}
+
+		id := fmt.Sprintf("%d-%d", subscription.ID, op.ID)
+		body, err := json.Marshal(aurora.WebhookEvent{
+			ID:             id,
+			SubscriptionID: strconv.FormatInt(subscription.ID, 10),
+			LedgerSequence: op.LedgerSequence(),
+			Operation:      operation,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not marshal event %s", id)
+		}
+
+		events = append(events, event{id: id, operationID: op.ID, body: body})
+	}
+
+	return events, nil
+}
+
+// deliver sends the event to the subscription URL in at most maxAttempts
+// attempts, retrying with exponential backoff. It returns the number of
+// attempts made.
+func (d *Dispatcher) deliver(ctx context.Context, subscription history.WebhookSubscription, e event, maxAttempts int) (int, error) {
+	backoff := d.config.InitialBackoff
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = d.post(ctx, subscription, e); err == nil {
+			return attempt, nil
+		}
+		if attempt == maxAttempts {
+			return attempt, err
+		}
+
+		log.WithField("subscription", subscription.ID).
+			WithField("event", e.id).
+			WithField("attempt", attempt).
+			WithError(err).
+			Info("Webhook delivery failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > d.config.MaxBackoff {
+			backoff = d.config.MaxBackoff
+		}
+	}
+
+	return maxAttempts, err
+}
+
+func (d *Dispatcher) post(ctx context.Context, subscription history.WebhookSubscription, e event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(e.body))
+	if err != nil {
+		return errors.Wrap(err, "could not create request")
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, e.id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, timestamp, e.body))
+
+	resp, err := d.config.HTTP.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error sending request")
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused.
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("unexpected response status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
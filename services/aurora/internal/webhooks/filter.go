@@ -0,0 +1,57 @@
+package webhooks
+
+import (
+	"encoding/json"
+
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+)
+
+// assetDetailPrefixes are the prefixes of the asset fields found in operation
+// details, see addAssetDetails in the operations processor.
+var assetDetailPrefixes = []string{"", "source_", "buying_", "selling_"}
+
+// Matches returns true if the operation matches the operation type and asset
+// filters of the subscription. The account filter is applied when loading
+// operations from the history database.
+func Matches(subscription history.WebhookSubscription, op history.Operation) bool {
+	if len(subscription.OperationTypes) > 0 {
+		found := false
+		for _, typ := range subscription.OperationTypes {
+			if typ == int64(op.Type) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if !subscription.AssetType.Valid {
+		return true
+	}
+
+	if !op.DetailsString.Valid {
+		return false
+	}
+
+	var details map[string]interface{}
+	if err := json.Unmarshal([]byte(op.DetailsString.String), &details); err != nil {
+		return false
+	}
+
+	for _, prefix := range assetDetailPrefixes {
+		if details[prefix+"asset_type"] != subscription.AssetType.String {
+			continue
+		}
+		if subscription.AssetType.String == "native" {
+			return true
+		}
+		if details[prefix+"asset_code"] == subscription.AssetCode.String &&
+			details[prefix+"asset_issuer"] == subscription.AssetIssuer.String {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,341 @@
+// Package webhooks delivers operations ingested by Aurora to webhook
+// subscriptions registered through the admin port.
+//
+// After each ledger is committed to the history database the ingestion system
+// calls Dispatcher.NotifyLedger. The dispatcher then walks every subscription
+// from its cursor (stored in the history database) up to the new ledger and
+// POSTs a signed JSON event for every matching operation. Subscriptions are
+// dispatched concurrently by a bounded pool of workers, and the events of a
+// subscription are delivered in order. Every subscription is dispatched under
+// an advisory lock so, when several ingesting nodes share a database, a
+// single node delivers its events at a time. Failed deliveries are retried with
+// exponential backoff. When all attempts of an event fail the endpoint is
+// considered down: the dispatch of the subscription stops without moving its
+// cursor, and the next ledger retries from that event. An event is only moved
+// to the dead-letter table once it has been failing for longer than
+// Config.DeadLetterAfter, so a single event the endpoint keeps rejecting does
+// not hold up the subscription forever. The cursor is only moved after an
+// event has been delivered or dead-lettered so events are delivered at least
+// once, even across restarts. Receivers should deduplicate events using the
+// event ID.
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/support/errors"
+	logpkg "github.com/diamnet/go/support/log"
+)
+
+const (
+	// operationsPageSize is the number of operations loaded from the history
+	// database at once for a single subscription.
+	operationsPageSize = 200
+
+	defaultWorkers         = 8
+	defaultMaxAttempts     = 5
+	defaultInitialBackoff  = time.Second
+	defaultMaxBackoff      = time.Minute
+	defaultDeadLetterAfter = time.Hour
+	defaultRequestTimeout  = 10 * time.Second
+)
+
+var log = logpkg.DefaultLogger.WithField("service", "webhooks")
+
+// historyQ defines the history database queries used by the Dispatcher.
+type historyQ interface {
+	history.QWebhooks
+	LedgersBySequence(ctx context.Context, dest interface{}, seqs ...int32) error
+	NoRows(err error) bool
+}
+
+// Config configures the Dispatcher.
+type Config struct {
+	// Workers is the number of subscriptions dispatched concurrently.
+	// Defaults to 8.
+	Workers int
+	// MaxAttempts is the number of delivery attempts of a single event in a
+	// dispatch before the endpoint is considered down. Defaults to 5.
+	MaxAttempts int
+	// InitialBackoff is the time to wait before the first retry. The backoff
+	// doubles after every failed attempt. Defaults to 1s.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the time between two attempts. Defaults to 1m.
+	MaxBackoff time.Duration
+	// DeadLetterAfter is the time an event must keep failing, across
+	// dispatches, before it is moved to the dead-letter table and the
+	// following events of its subscription are delivered. Defaults to 1h.
+	DeadLetterAfter time.Duration
+	// HTTP is the client used to deliver events. Defaults to a client with
+	// a 10s timeout.
+	HTTP *http.Client
+}
+
+// Dispatcher delivers webhook events for ingested ledgers.
+type Dispatcher struct {
+	q       historyQ
+	config  Config
+	metrics *prometheus.CounterVec
+
+	lock       sync.Mutex
+	lastLedger uint32
+	notify     chan struct{}
+	// failing contains the first event of each subscription which could
+	// not be delivered in the last dispatch, and since when it fails.
+	failing map[int64]failure
+	now     func() time.Time
+}
+
+// failure is an event which could not be delivered.
+type failure struct {
+	operationID int64
+	since       time.Time
+}
+
+// NewDispatcher returns a new Dispatcher using the given history database
+// queries.
+func NewDispatcher(q historyQ, config Config) *Dispatcher {
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.DeadLetterAfter <= 0 {
+		config.DeadLetterAfter = defaultDeadLetterAfter
+	}
+	if config.HTTP == nil {
+		config.HTTP = &http.Client{Timeout: defaultRequestTimeout}
+	}
+
+	return &Dispatcher{
+		q:       q,
+		config:  config,
+		notify:  make(chan struct{}, 1),
+		failing: map[int64]failure{},
+		now:     time.Now,
+		metrics: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "aurora", Subsystem: "ingest", Name: "webhook_deliveries_total",
+				Help: "number of webhook events delivered, moved to the dead-letter table or failed because the endpoint is down",
+			},
+			[]string{"result"},
+		),
+	}
+}
+
+// RegisterMetrics registers the prometheus metrics of the dispatcher.
+func (d *Dispatcher) RegisterMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(d.metrics)
+}
+
+// NotifyLedger informs the dispatcher that the ledger with the given sequence
+// has been committed to the history database. It never blocks.
+func (d *Dispatcher) NotifyLedger(sequence uint32) {
+	d.lock.Lock()
+	if sequence > d.lastLedger {
+		d.lastLedger = sequence
+	}
+	d.lock.Unlock()
+
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers events until the context is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.notify:
+		}
+
+		d.lock.Lock()
+		ledger := d.lastLedger
+		d.lock.Unlock()
+
+		if err := d.Dispatch(ctx, ledger); err != nil && ctx.Err() == nil {
+			log.WithField("ledger", ledger).WithError(err).Error("Error dispatching webhook events")
+		}
+	}
+}
+
+// Dispatch delivers events for all operations up to (and including) the
+// given ledger to all subscriptions. Subscriptions are dispatched
+// concurrently, an error dispatching one of them does not stop the others.
+// Subscriptions which are being dispatched by another node are skipped.
+func (d *Dispatcher) Dispatch(ctx context.Context, ledger uint32) error {
+	subscriptions, err := d.q.GetWebhookSubscriptions(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not load webhook subscriptions")
+	}
+
+	maxID := toid.AfterLedger(int32(ledger)).ToInt64()
+	errs := make([]error, len(subscriptions))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < d.config.Workers && w < len(subscriptions); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				errs[i] = d.dispatchLocked(ctx, subscriptions[i].ID, maxID)
+			}
+		}()
+	}
+	for i := range subscriptions {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return errors.Wrapf(err, "could not dispatch events of webhook subscription %d", subscriptions[i].ID)
+		}
+	}
+	return nil
+}
+
+// dispatchLocked dispatches the subscription with the given ID unless it is
+// locked by another node.
+func (d *Dispatcher) dispatchLocked(ctx context.Context, id int64, maxID int64) error {
+	unlock, locked, err := d.q.TryLockWebhookSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+	defer unlock()
+
+	// Load the subscription again once it is locked, its cursor may have
+	// been moved by another node in the meantime.
+	subscription, err := d.q.GetWebhookSubscriptionByID(ctx, id)
+	if d.q.NoRows(err) {
+		// The subscription has been removed
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "could not load webhook subscription")
+	}
+
+	return d.dispatchSubscription(ctx, subscription, maxID)
+}
+
+func (d *Dispatcher) dispatchSubscription(ctx context.Context, subscription history.WebhookSubscription, maxID int64) error {
+	cursor := subscription.Cursor
+	for cursor < maxID {
+		operations, transactions, err := d.q.GetWebhookOperations(
+			ctx,
+			subscription.AccountID.String,
+			cursor,
+			maxID,
+			operationsPageSize,
+		)
+		if err != nil {
+			return errors.Wrap(err, "could not load operations")
+		}
+
+		events, err := d.buildEvents(ctx, subscription, operations, transactions)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			delivered, err := d.deliverOrDeadLetter(ctx, subscription, e)
+			if err != nil {
+				return err
+			}
+			if !delivered {
+				// The endpoint is down, the next dispatch retries from this
+				// event.
+				return nil
+			}
+			if err := d.q.UpdateWebhookSubscriptionCursor(ctx, subscription.ID, e.operationID); err != nil {
+				return errors.Wrap(err, "could not update cursor")
+			}
+		}
+
+		if len(operations) < operationsPageSize {
+			cursor = maxID
+		} else {
+			cursor = operations[len(operations)-1].ID
+		}
+
+		if err := d.q.UpdateWebhookSubscriptionCursor(ctx, subscription.ID, cursor); err != nil {
+			return errors.Wrap(err, "could not update cursor")
+		}
+	}
+
+	return nil
+}
+
+// deliverOrDeadLetter delivers the event, or moves it to the dead-letter
+// table when it has been failing for longer than Config.DeadLetterAfter. It
+// returns false when the event was neither delivered nor dead-lettered, in
+// which case the cursor of the subscription must not move past it.
+func (d *Dispatcher) deliverOrDeadLetter(ctx context.Context, subscription history.WebhookSubscription, e event) (bool, error) {
+	attempts, err := d.deliver(ctx, subscription, e, d.config.MaxAttempts)
+	if err == nil {
+		d.lock.Lock()
+		delete(d.failing, subscription.ID)
+		d.lock.Unlock()
+		d.metrics.With(prometheus.Labels{"result": "delivered"}).Inc()
+		return true, nil
+	}
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	now := d.now()
+	d.lock.Lock()
+	failed, ok := d.failing[subscription.ID]
+	if !ok || failed.operationID != e.operationID {
+		failed = failure{operationID: e.operationID, since: now}
+		d.failing[subscription.ID] = failed
+	}
+	deadLetter := now.Sub(failed.since) >= d.config.DeadLetterAfter
+	if deadLetter {
+		delete(d.failing, subscription.ID)
+	}
+	d.lock.Unlock()
+
+	fields := logpkg.F{
+		"subscription": subscription.ID,
+		"operation":    e.operationID,
+		"attempts":     attempts,
+		"failing_for":  now.Sub(failed.since).String(),
+	}
+	if !deadLetter {
+		log.WithFields(fields).WithError(err).Warn("Webhook endpoint is down, retrying on the next ledger")
+		d.metrics.With(prometheus.Labels{"result": "failed"}).Inc()
+		return false, nil
+	}
+
+	log.WithFields(fields).WithError(err).Warn("Moving webhook event to the dead-letter table")
+
+	d.metrics.With(prometheus.Labels{"result": "dead_letter"}).Inc()
+	return true, d.q.InsertWebhookDeadLetter(ctx, history.WebhookDeadLetter{
+		SubscriptionID: subscription.ID,
+		OperationID:    e.operationID,
+		Payload:        e.body,
+		Attempts:       attempts,
+		LastError:      err.Error(),
+	})
+}
@@ -0,0 +1,385 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/protocols/aurora"
+	"github.com/diamnet/go/services/aurora/internal/db2/history"
+	"github.com/diamnet/go/services/aurora/internal/toid"
+	"github.com/diamnet/go/xdr"
+)
+
+type fakeHistoryQ struct {
+	history.QWebhooks
+	lock          sync.Mutex
+	subscriptions []history.WebhookSubscription
+	operations    []history.Operation
+	deadLetters   []history.WebhookDeadLetter
+	locked        map[int64]bool
+}
+
+func (q *fakeHistoryQ) GetWebhookSubscriptionByID(ctx context.Context, id int64) (history.WebhookSubscription, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for _, subscription := range q.subscriptions {
+		if subscription.ID == id {
+			return subscription, nil
+		}
+	}
+	return history.WebhookSubscription{}, sql.ErrNoRows
+}
+
+func (q *fakeHistoryQ) TryLockWebhookSubscription(ctx context.Context, id int64) (func(), bool, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.locked[id] {
+		return nil, false, nil
+	}
+	if q.locked == nil {
+		q.locked = map[int64]bool{}
+	}
+	q.locked[id] = true
+	return func() {
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		delete(q.locked, id)
+	}, true, nil
+}
+
+func (q *fakeHistoryQ) NoRows(err error) bool {
+	return err == sql.ErrNoRows
+}
+
+func (q *fakeHistoryQ) GetWebhookSubscriptions(ctx context.Context) ([]history.WebhookSubscription, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return append([]history.WebhookSubscription(nil), q.subscriptions...), nil
+}
+
+func (q *fakeHistoryQ) UpdateWebhookSubscriptionCursor(ctx context.Context, id int64, cursor int64) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for i := range q.subscriptions {
+		if q.subscriptions[i].ID == id && q.subscriptions[i].Cursor < cursor {
+			q.subscriptions[i].Cursor = cursor
+		}
+	}
+	return nil
+}
+
+func (q *fakeHistoryQ) InsertWebhookDeadLetter(ctx context.Context, deadLetter history.WebhookDeadLetter) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.deadLetters = append(q.deadLetters, deadLetter)
+	return nil
+}
+
+func (q *fakeHistoryQ) GetWebhookOperations(ctx context.Context, accountID string, cursor, maxID int64, limit uint64) ([]history.Operation, []history.Transaction, error) {
+	var ops []history.Operation
+	for _, op := range q.operations {
+		if op.ID > cursor && op.ID <= maxID && uint64(len(ops)) < limit {
+			ops = append(ops, op)
+		}
+	}
+	return ops, nil, nil
+}
+
+func (q *fakeHistoryQ) LedgersBySequence(ctx context.Context, dest interface{}, seqs ...int32) error {
+	ledgers := dest.(*[]history.Ledger)
+	for _, seq := range seqs {
+		*ledgers = append(*ledgers, history.Ledger{Sequence: seq, ClosedAt: time.Unix(0, 0).UTC()})
+	}
+	return nil
+}
+
+func paymentOperation(ledger int32, order int32, assetType string) history.Operation {
+	details := `{"asset_type":"native","amount":"10.0000000"}`
+	if assetType != "native" {
+		details = `{"asset_type":"credit_alphanum4","asset_code":"USD","asset_issuer":"GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB","amount":"10.0000000"}`
+	}
+	return history.Operation{
+		TotalOrderID:          history.TotalOrderID{ID: toid.New(ledger, 1, order).ToInt64()},
+		TransactionHash:       "2374e99349b9ef7dba9a5db3339b78fda8f34777b1af33ba468ad5c0df946d4d",
+		Type:                  xdr.OperationTypePayment,
+		DetailsString:         null.StringFrom(details),
+		SourceAccount:         "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB",
+		TransactionSuccessful: true,
+	}
+}
+
+func TestSign(t *testing.T) {
+	signature := Sign("secret", 1600000000, []byte(`{"id":"1-1"}`))
+	assert.Len(t, signature, 64)
+	assert.Equal(t, signature, Sign("secret", 1600000000, []byte(`{"id":"1-1"}`)))
+	assert.NotEqual(t, signature, Sign("other", 1600000000, []byte(`{"id":"1-1"}`)))
+	assert.NotEqual(t, signature, Sign("secret", 1600000001, []byte(`{"id":"1-1"}`)))
+}
+
+func TestMatches(t *testing.T) {
+	op := paymentOperation(10, 1, "credit_alphanum4")
+
+	assert.True(t, Matches(history.WebhookSubscription{}, op))
+	assert.True(t, Matches(history.WebhookSubscription{
+		OperationTypes: pq.Int64Array{int64(xdr.OperationTypePayment)},
+	}, op))
+	assert.False(t, Matches(history.WebhookSubscription{
+		OperationTypes: pq.Int64Array{int64(xdr.OperationTypeCreateAccount)},
+	}, op))
+	assert.True(t, Matches(history.WebhookSubscription{
+		AssetType:   null.StringFrom("credit_alphanum4"),
+		AssetCode:   null.StringFrom("USD"),
+		AssetIssuer: null.StringFrom("GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"),
+	}, op))
+	assert.False(t, Matches(history.WebhookSubscription{
+		AssetType:   null.StringFrom("credit_alphanum4"),
+		AssetCode:   null.StringFrom("EUR"),
+		AssetIssuer: null.StringFrom("GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"),
+	}, op))
+	assert.False(t, Matches(history.WebhookSubscription{
+		AssetType: null.StringFrom("native"),
+	}, op))
+	assert.True(t, Matches(history.WebhookSubscription{
+		AssetType: null.StringFrom("native"),
+	}, paymentOperation(10, 2, "native")))
+}
+
+func TestDispatchDeliversSignedEvents(t *testing.T) {
+	var lock sync.Mutex
+	var received []aurora.WebhookEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, Sign("secret", timestamp, body), r.Header.Get(SignatureHeader))
+
+		var e aurora.WebhookEvent
+		require.NoError(t, json.Unmarshal(body, &e))
+		assert.Equal(t, e.ID, r.Header.Get(EventIDHeader))
+
+		lock.Lock()
+		received = append(received, e)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	q := &fakeHistoryQ{
+		subscriptions: []history.WebhookSubscription{{
+			ID:        1,
+			URL:       server.URL,
+			Secret:    "secret",
+			AssetType: null.StringFrom("native"),
+		}},
+		operations: []history.Operation{
+			paymentOperation(10, 1, "native"),
+			paymentOperation(10, 2, "credit_alphanum4"),
+			paymentOperation(11, 1, "native"),
+			paymentOperation(12, 1, "native"),
+		},
+	}
+
+	dispatcher := NewDispatcher(q, Config{})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 11))
+
+	require.Len(t, received, 2)
+	assert.Equal(t, int32(10), received[0].LedgerSequence)
+	assert.Equal(t, int32(11), received[1].LedgerSequence)
+	assert.Equal(t, toid.AfterLedger(11).ToInt64(), q.subscriptions[0].Cursor)
+	assert.Empty(t, q.deadLetters)
+
+	// Events are not delivered twice
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 12))
+	require.Len(t, received, 3)
+	assert.Equal(t, int32(12), received[2].LedgerSequence)
+}
+
+func TestDispatchDeadLetter(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	q := &fakeHistoryQ{
+		subscriptions: []history.WebhookSubscription{{
+			ID:     1,
+			URL:    server.URL,
+			Secret: "secret",
+		}},
+		operations: []history.Operation{
+			paymentOperation(10, 1, "native"),
+			paymentOperation(10, 2, "native"),
+		},
+	}
+
+	dispatcher := NewDispatcher(q, Config{
+		MaxAttempts:     3,
+		InitialBackoff:  time.Millisecond,
+		DeadLetterAfter: time.Hour,
+	})
+	now := time.Unix(1600000000, 0)
+	dispatcher.now = func() time.Time { return now }
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 10))
+
+	// The dispatch stops at the first event and does not move the cursor
+	assert.Equal(t, 3, attempts)
+	assert.Empty(t, q.deadLetters)
+	assert.Equal(t, int64(0), q.subscriptions[0].Cursor)
+
+	// The event is dead-lettered once it has been failing for long enough,
+	// the following events are then delivered (and fail) in turn
+	now = now.Add(time.Hour)
+	q.operations = append(q.operations, paymentOperation(11, 1, "native"))
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 11))
+	assert.Equal(t, 9, attempts)
+	require.Len(t, q.deadLetters, 1)
+	assert.Equal(t, int64(1), q.deadLetters[0].SubscriptionID)
+	assert.Equal(t, q.operations[0].ID, q.deadLetters[0].OperationID)
+	assert.Equal(t, 3, q.deadLetters[0].Attempts)
+	assert.Equal(t, q.operations[0].ID, q.subscriptions[0].Cursor)
+}
+
+func TestDispatchOutageRecovers(t *testing.T) {
+	var lock sync.Mutex
+	down := true
+	var received []aurora.WebhookEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var e aurora.WebhookEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		received = append(received, e)
+	}))
+	defer server.Close()
+
+	q := &fakeHistoryQ{
+		subscriptions: []history.WebhookSubscription{{
+			ID:     1,
+			URL:    server.URL,
+			Secret: "secret",
+		}},
+		operations: []history.Operation{
+			paymentOperation(10, 1, "native"),
+			paymentOperation(10, 2, "native"),
+			paymentOperation(11, 1, "native"),
+		},
+	}
+
+	dispatcher := NewDispatcher(q, Config{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+	})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 10))
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 11))
+	assert.Empty(t, received)
+	assert.Empty(t, q.deadLetters)
+	assert.Equal(t, int64(0), q.subscriptions[0].Cursor)
+
+	// Once the endpoint is back no event of the outage is lost
+	lock.Lock()
+	down = false
+	lock.Unlock()
+	q.operations = append(q.operations, paymentOperation(12, 1, "native"))
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 12))
+	require.Len(t, received, 4)
+	for i, e := range received {
+		assert.Equal(t, fmt.Sprintf("1-%d", q.operations[i].ID), e.ID)
+	}
+	assert.Empty(t, q.deadLetters)
+	assert.Equal(t, toid.AfterLedger(12).ToInt64(), q.subscriptions[0].Cursor)
+}
+
+func TestDispatchSubscriptionsConcurrently(t *testing.T) {
+	// The slow subscription only responds once the fast one received its
+	// event, which never happens if subscriptions are dispatched in turn
+	fastDelivered := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-fastDelivered:
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(fastDelivered)
+	}))
+	defer fast.Close()
+
+	q := &fakeHistoryQ{
+		subscriptions: []history.WebhookSubscription{
+			{ID: 1, URL: slow.URL, Secret: "secret"},
+			{ID: 2, URL: fast.URL, Secret: "secret"},
+		},
+		operations: []history.Operation{
+			paymentOperation(10, 1, "native"),
+		},
+	}
+
+	dispatcher := NewDispatcher(q, Config{MaxAttempts: 1})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 10))
+	assert.Empty(t, q.deadLetters)
+	for _, subscription := range q.subscriptions {
+		assert.Equal(t, toid.AfterLedger(10).ToInt64(), subscription.Cursor)
+	}
+}
+
+func TestDispatchSkipsLockedSubscriptions(t *testing.T) {
+	delivered := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered++
+	}))
+	defer server.Close()
+
+	q := &fakeHistoryQ{
+		subscriptions: []history.WebhookSubscription{{
+			ID:     1,
+			URL:    server.URL,
+			Secret: "secret",
+		}},
+		operations: []history.Operation{
+			paymentOperation(10, 1, "native"),
+		},
+	}
+
+	// The subscription is being dispatched by another node
+	unlock, locked, err := q.TryLockWebhookSubscription(context.Background(), 1)
+	require.NoError(t, err)
+	require.True(t, locked)
+
+	dispatcher := NewDispatcher(q, Config{})
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 10))
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, int64(0), q.subscriptions[0].Cursor)
+
+	// The other node moved the cursor before releasing the lock, the event
+	// is not delivered twice
+	require.NoError(t, q.UpdateWebhookSubscriptionCursor(context.Background(), 1, q.operations[0].ID))
+	unlock()
+	q.operations = append(q.operations, paymentOperation(11, 1, "native"))
+	require.NoError(t, dispatcher.Dispatch(context.Background(), 11))
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, toid.AfterLedger(11).ToInt64(), q.subscriptions[0].Cursor)
+	assert.Empty(t, q.locked)
+}
@@ -0,0 +1,175 @@
+// Package plugins allows registering ingestion processors in addition to the
+// processors built into Aurora, for example to write custom tables derived
+// from the ledgers. Processors are registered before running Aurora:
+//
+//	func main() {
+//		plugins.Register(plugins.Processor{
+//			Name:                    "payroll",
+//			Migrations:              &migrate.FileMigrationSource{Dir: "migrations"},
+//			NewTransactionProcessor: newPayrollProcessor,
+//			HistoryTables:           map[string]string{"payroll_payments": "history_operation_id"},
+//		})
+//		if err := cmd.Execute(); err != nil {
+//			...
+//		}
+//	}
+//
+// Registered processors run in the same DB transaction as the built-in
+// processors, so a ledger is either ingested by all of them or by none.
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	migrate "github.com/rubenv/sql-migrate"
+
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/support/db"
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/xdr"
+)
+
+// ChangeProcessor processes the ledger entry changes of a ledger. Commit is
+// called once all the changes have been processed.
+type ChangeProcessor interface {
+	ProcessChange(ctx context.Context, change ingest.Change) error
+	Commit(ctx context.Context) error
+}
+
+// TransactionProcessor processes the transactions of a ledger. Commit is
+// called once all the transactions have been processed.
+type TransactionProcessor interface {
+	ProcessTransaction(ctx context.Context, transaction ingest.LedgerTransaction) error
+	Commit(ctx context.Context) error
+}
+
+// Processor describes an ingestion processor registered with Register.
+type Processor struct {
+	// Name identifies the processor in logs and in the DB, it must be unique
+	// and contain 1 to 32 lowercase letters, digits or '_'.
+	Name string
+
+	// Migrations creates the tables written by the processor. They are
+	// applied with the migrations of Aurora (`aurora db migrate` or
+	// --apply-migrations) and their IDs are prefixed with
+	// `plugin_<Name>_`. IDs starting with a number are sorted numerically.
+	Migrations migrate.MigrationSource
+
+	// NewChangeProcessor, if set, returns the processor of the ledger entry
+	// changes of the ledger with the given sequence. It is also used when the
+	// state is rebuilt from a history archive, in which case the changes
+	// contain every ledger entry of the checkpoint ledger.
+	NewChangeProcessor func(session db.SessionInterface, sequence uint32) ChangeProcessor
+
+	// NewTransactionProcessor, if set, returns the processor of the
+	// transactions of the given ledger. It is used for ledgers ingested live
+	// and for reingested ledger ranges, and is subject to the ingestion
+	// transaction filters.
+	NewTransactionProcessor func(session db.SessionInterface, ledger xdr.LedgerHeaderHistoryEntry) TransactionProcessor
+
+	// HistoryTables maps the tables written by the transaction processor to
+	// their column holding a total order ID like the IDs of the history tables
+	// of Aurora (ledger sequence << 32 | transaction index << 12 | operation
+	// index, ex. history_operation_id). Their rows are removed with the rest
+	// of the history when a ledger range is reingested or reaped.
+	HistoryTables map[string]string
+
+	// StateTables are the tables written by the change processor. They are
+	// truncated with the state tables of Aurora when the state is rebuilt.
+	StateTables []string
+
+	// DisableStateVerification must be set if the processor changes the
+	// tables checked by the state verification of Aurora (accounts, offers,
+	// trust lines...), which is then disabled.
+	DisableStateVerification bool
+}
+
+var (
+	processorNameRegexp = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+	mutex      sync.RWMutex
+	registered = map[string]Processor{}
+)
+
+// Register adds an ingestion processor. It must be called before Aurora is
+// started and panics if the processor is invalid or if a processor with the
+// same name was already registered.
+func Register(processor Processor) {
+	if !processorNameRegexp.MatchString(processor.Name) {
+		panic(fmt.Sprintf("plugins: invalid processor name %q", processor.Name))
+	}
+	if processor.NewChangeProcessor == nil && processor.NewTransactionProcessor == nil {
+		panic(fmt.Sprintf("plugins: processor %s has neither a change nor a transaction processor", processor.Name))
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if _, ok := registered[processor.Name]; ok {
+		panic(fmt.Sprintf("plugins: processor %s registered twice", processor.Name))
+	}
+	registered[processor.Name] = processor
+}
+
+// Registered returns the registered processors sorted by name.
+func Registered() []Processor {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	processors := make([]Processor, 0, len(registered))
+	for _, processor := range registered {
+		processors = append(processors, processor)
+	}
+	sort.Slice(processors, func(i, j int) bool {
+		return processors[i].Name < processors[j].Name
+	})
+	return processors
+}
+
+// Unregister removes a registered processor, it is meant to be used in tests.
+func Unregister(name string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	delete(registered, name)
+}
+
+// Migrations is the source of the migrations of all the registered
+// processors, with their IDs prefixed with the name of the processor.
+var Migrations migrate.MigrationSource = migrationSource{}
+
+type migrationSource struct{}
+
+func (migrationSource) FindMigrations() ([]*migrate.Migration, error) {
+	var migrations []*migrate.Migration
+	for _, processor := range Registered() {
+		if processor.Migrations == nil {
+			continue
+		}
+		found, err := processor.Migrations.FindMigrations()
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not find migrations of processor %s", processor.Name)
+		}
+		for _, migration := range found {
+			prefixed := *migration
+			prefixed.Id = migrationID(processor.Name, migration)
+			migrations = append(migrations, &prefixed)
+		}
+	}
+	return migrations, nil
+}
+
+// migrationID prefixes the ID of a migration with the name of its processor.
+// The number the ID starts with, if any, is padded so that the migrations of
+// a processor are sorted by number.
+func migrationID(name string, migration *migrate.Migration) string {
+	if matches := migration.NumberPrefixMatches(); len(matches) > 0 {
+		return fmt.Sprintf(
+			"plugin_%s_%010d%s",
+			name, migration.VersionInt(), strings.TrimPrefix(migration.Id, matches[1]),
+		)
+	}
+	return "plugin_" + name + "_" + migration.Id
+}
@@ -0,0 +1,88 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamnet/go/ingest"
+	"github.com/diamnet/go/support/db"
+	"github.com/diamnet/go/xdr"
+)
+
+type testProcessor struct{}
+
+func (testProcessor) ProcessTransaction(ctx context.Context, transaction ingest.LedgerTransaction) error {
+	return nil
+}
+
+func (testProcessor) Commit(ctx context.Context) error {
+	return nil
+}
+
+func newTestProcessor(session db.SessionInterface, ledger xdr.LedgerHeaderHistoryEntry) TransactionProcessor {
+	return testProcessor{}
+}
+
+func TestRegister(t *testing.T) {
+	defer Unregister("b")
+	defer Unregister("a")
+
+	Register(Processor{Name: "b", NewTransactionProcessor: newTestProcessor})
+	Register(Processor{Name: "a", NewTransactionProcessor: newTestProcessor})
+
+	processors := Registered()
+	require.Len(t, processors, 2)
+	assert.Equal(t, "a", processors[0].Name)
+	assert.Equal(t, "b", processors[1].Name)
+
+	assert.PanicsWithValue(t, "plugins: processor a registered twice", func() {
+		Register(Processor{Name: "a", NewTransactionProcessor: newTestProcessor})
+	})
+	assert.PanicsWithValue(t, `plugins: invalid processor name "Payroll"`, func() {
+		Register(Processor{Name: "Payroll", NewTransactionProcessor: newTestProcessor})
+	})
+	assert.PanicsWithValue(t, `plugins: invalid processor name ""`, func() {
+		Register(Processor{NewTransactionProcessor: newTestProcessor})
+	})
+	assert.PanicsWithValue(t, "plugins: processor c has neither a change nor a transaction processor", func() {
+		Register(Processor{Name: "c"})
+	})
+
+	Unregister("b")
+	assert.Len(t, Registered(), 1)
+}
+
+func TestMigrations(t *testing.T) {
+	defer Unregister("payroll")
+	defer Unregister("audit")
+
+	Register(Processor{
+		Name:                    "payroll",
+		NewTransactionProcessor: newTestProcessor,
+		Migrations: &migrate.MemoryMigrationSource{
+			Migrations: []*migrate.Migration{
+				{Id: "10_add_index.sql"},
+				{Id: "2_create_tables.sql"},
+				{Id: "seed"},
+			},
+		},
+	})
+	Register(Processor{Name: "audit", NewTransactionProcessor: newTestProcessor})
+
+	migrations, err := Migrations.FindMigrations()
+	require.NoError(t, err)
+
+	var ids []string
+	for _, migration := range migrations {
+		ids = append(ids, migration.Id)
+	}
+	assert.Equal(t, []string{
+		"plugin_payroll_0000000002_create_tables.sql",
+		"plugin_payroll_0000000010_add_index.sql",
+		"plugin_payroll_seed",
+	}, ids)
+}
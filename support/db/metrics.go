@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/Masterminds/squirrel"
@@ -23,6 +24,11 @@ var HistoryPrimarySubservice = Subservice("history_primary")
 var HistorySubservice = Subservice("history")
 var IngestSubservice = Subservice("ingest")
 
+// ReplicaSubservice returns the subservice of the i-th read replica of sub.
+func ReplicaSubservice(sub Subservice, i int) Subservice {
+	return Subservice(fmt.Sprintf("%s_replica_%d", sub, i))
+}
+
 type QueryType string
 
 var DeleteQueryType = QueryType("delete")
@@ -479,3 +485,95 @@ func (s *SessionWithMetrics) DeleteRange(
 	err = s.SessionInterface.DeleteRange(ctx, start, end, table, idCol)
 	return err
 }
+
+// RegisterReplicaPoolMetrics registers the health, latest ledger and lag of
+// every replica of pool, along with the number of queries routed to them.
+func RegisterReplicaPoolMetrics(pool *ReplicaPool, namespace string, sub Subservice, registry *prometheus.Registry) {
+	for _, replica := range pool.replicas {
+		replica := replica
+		labels := prometheus.Labels{"subservice": string(sub), "replica": replica.Name}
+
+		registry.MustRegister(prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   "db",
+				Name:        "replica_healthy",
+				Help:        "1 if the last health check of the replica succeeded, 0 otherwise",
+				ConstLabels: labels,
+			},
+			func() float64 {
+				if healthy, _, _ := pool.status(replica); healthy {
+					return 1
+				}
+				return 0
+			},
+		))
+
+		registry.MustRegister(prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   "db",
+				Name:        "replica_latest_ledger",
+				Help:        "latest ledger available in the replica at the last successful health check",
+				ConstLabels: labels,
+			},
+			func() float64 {
+				_, ledger, _ := pool.status(replica)
+				return float64(ledger)
+			},
+		))
+
+		registry.MustRegister(prometheus.NewGaugeFunc(
+			prometheus.GaugeOpts{
+				Namespace:   namespace,
+				Subsystem:   "db",
+				Name:        "replica_lag_ledgers",
+				Help:        "number of ledgers the replica lags behind the fallback database, or behind the most up to date replica when there is no fallback or its latest ledger cannot be loaded",
+				ConstLabels: labels,
+			},
+			func() float64 {
+				_, _, lag := pool.status(replica)
+				return float64(lag)
+			},
+		))
+
+		registry.MustRegister(prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Subsystem:   "db",
+				Name:        "replica_selected_total",
+				Help:        "number of times the replica was selected to serve queries",
+				ConstLabels: labels,
+			},
+			func() float64 {
+				return float64(atomic.LoadUint64(&replica.selected))
+			},
+		))
+
+		registry.MustRegister(prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace:   namespace,
+				Subsystem:   "db",
+				Name:        "replica_check_errors_total",
+				Help:        "number of failed health checks of the replica",
+				ConstLabels: labels,
+			},
+			func() float64 {
+				return float64(atomic.LoadUint64(&replica.checkErrors))
+			},
+		))
+	}
+
+	registry.MustRegister(prometheus.NewCounterFunc(
+		prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   "db",
+			Name:        "replica_fallback_total",
+			Help:        "number of times none of the replicas could serve queries and the fallback session was used",
+			ConstLabels: prometheus.Labels{"subservice": string(sub)},
+		},
+		func() float64 {
+			return float64(atomic.LoadUint64(&pool.fallbacks))
+		},
+	))
+}
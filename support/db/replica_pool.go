@@ -0,0 +1,266 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diamnet/go/support/errors"
+	"github.com/diamnet/go/support/log"
+)
+
+const (
+	defaultReplicaCheckInterval = time.Second
+	defaultReplicaCheckTimeout  = time.Second
+)
+
+// ErrNoReplicaAvailable is returned by ReplicaPool.Session when none of the
+// replicas is healthy and up to date with the requested ledger, and the pool
+// does not have a fallback session.
+var ErrNoReplicaAvailable = errors.New("no replica available")
+
+// LatestLedgerFunc returns the latest ledger available in the database of
+// the given session.
+type LatestLedgerFunc func(ctx context.Context, session SessionInterface) (uint32, error)
+
+// Replica is a read-only database of a ReplicaPool.
+type Replica struct {
+	Name    string
+	Session SessionInterface
+}
+
+type replicaState struct {
+	Replica
+	// checkSession is used by the health checks, which run concurrently with
+	// the queries of the sessions returned by ReplicaPool.Session.
+	checkSession SessionInterface
+	healthy      bool
+	ledger       uint32
+	// selected and checkErrors are updated atomically.
+	selected    uint64
+	checkErrors uint64
+}
+
+// ReplicaPoolConfig configures a ReplicaPool.
+type ReplicaPoolConfig struct {
+	Replicas []Replica
+	// LatestLedger is used by the health checks to determine the replication
+	// lag of every replica.
+	LatestLedger LatestLedgerFunc
+	// Fallback is the session returned when none of the replicas can serve a
+	// query, usually the primary database. When nil, ReplicaPool.Session
+	// returns ErrNoReplicaAvailable instead.
+	Fallback SessionInterface
+	// MaxLag is the number of ledgers a replica can lag behind the Fallback
+	// database before queries are routed away from it. Without Fallback, or
+	// when its latest ledger cannot be loaded, the lag is measured against
+	// the most up to date replica. Zero disables the check.
+	MaxLag uint32
+	// CheckInterval is the interval between health checks, defaults to 1
+	// second.
+	CheckInterval time.Duration
+	// CheckTimeout is the timeout of the health check of a replica, defaults
+	// to 1 second.
+	CheckTimeout time.Duration
+}
+
+// ReplicaPool routes read-only queries across several replicas of the same
+// database. The replicas are checked periodically and queries are routed in
+// turn to the healthy replicas whose replication lag is within bounds, so that
+// a replica failing or falling behind is avoided until it recovers.
+type ReplicaPool struct {
+	config   ReplicaPoolConfig
+	lock     sync.RWMutex
+	replicas []*replicaState
+	// fallbackCheckSession is used to load the latest ledger of the Fallback
+	// database, it's nil if the pool does not have a fallback.
+	fallbackCheckSession SessionInterface
+	fallbackCheckFailed  bool
+	// latestLedger is the ledger the lag of the replicas is measured against.
+	latestLedger uint32
+	// next and fallbacks are updated atomically.
+	next      uint64
+	fallbacks uint64
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReplicaPool constructs a ReplicaPool. Replicas are considered unhealthy
+// until they are checked by Start or Check.
+func NewReplicaPool(config ReplicaPoolConfig) (*ReplicaPool, error) {
+	if len(config.Replicas) == 0 {
+		return nil, errors.New("replica pool requires at least one replica")
+	}
+	if config.LatestLedger == nil {
+		return nil, errors.New("replica pool requires a LatestLedger function")
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaultReplicaCheckInterval
+	}
+	if config.CheckTimeout <= 0 {
+		config.CheckTimeout = defaultReplicaCheckTimeout
+	}
+
+	pool := &ReplicaPool{
+		config:    config,
+		closeChan: make(chan struct{}),
+	}
+	if config.Fallback != nil {
+		pool.fallbackCheckSession = config.Fallback.Clone()
+	}
+	names := map[string]bool{}
+	for _, replica := range config.Replicas {
+		if names[replica.Name] {
+			return nil, errors.Errorf("duplicate replica name %s", replica.Name)
+		}
+		names[replica.Name] = true
+		pool.replicas = append(pool.replicas, &replicaState{
+			Replica:      replica,
+			checkSession: replica.Session.Clone(),
+		})
+	}
+	return pool, nil
+}
+
+// Start checks the replicas and keeps checking them in a separate go routine
+// until the pool is closed.
+func (p *ReplicaPool) Start() {
+	p.Check(context.Background())
+	ticker := time.NewTicker(p.config.CheckInterval)
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				p.Check(context.Background())
+			case <-p.closeChan:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the health checks of the pool. The sessions of the replicas are
+// not closed.
+func (p *ReplicaPool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closeChan)
+	})
+}
+
+// Check updates the health and the latest ledger of every replica, and the
+// latest ledger of the fallback database.
+func (p *ReplicaPool) Check(ctx context.Context) {
+	ledgers := make([]uint32, len(p.replicas))
+	errs := make([]error, len(p.replicas))
+	var fallbackLedger uint32
+	var fallbackErr error
+	var wg sync.WaitGroup
+	if p.fallbackCheckSession != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, p.config.CheckTimeout)
+			defer cancel()
+			fallbackLedger, fallbackErr = p.config.LatestLedger(checkCtx, p.fallbackCheckSession)
+		}()
+	}
+	for i, replica := range p.replicas {
+		wg.Add(1)
+		go func(i int, replica *replicaState) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, p.config.CheckTimeout)
+			defer cancel()
+			ledgers[i], errs[i] = p.config.LatestLedger(checkCtx, replica.checkSession)
+		}(i, replica)
+	}
+	wg.Wait()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var latestReplicaLedger uint32
+	for i, replica := range p.replicas {
+		if errs[i] != nil {
+			if replica.healthy {
+				log.WithField("replica", replica.Name).WithError(errs[i]).
+					Warn("Replica health check failed, routing queries away from it")
+			}
+			atomic.AddUint64(&replica.checkErrors, 1)
+			replica.healthy = false
+			continue
+		}
+		if !replica.healthy {
+			log.WithField("replica", replica.Name).Info("Replica is healthy")
+		}
+		replica.healthy = true
+		replica.ledger = ledgers[i]
+		if replica.ledger > latestReplicaLedger {
+			latestReplicaLedger = replica.ledger
+		}
+	}
+
+	switch {
+	case p.fallbackCheckSession == nil:
+		p.latestLedger = latestReplicaLedger
+	case fallbackErr != nil:
+		// Replicas lagging behind the fallback database are still avoided
+		// if some replicas are up to date
+		if !p.fallbackCheckFailed {
+			log.WithField("error", fallbackErr).
+				Warn("Could not load the latest ledger of the fallback database, measuring replica lag against the replicas")
+		}
+		p.fallbackCheckFailed = true
+		p.latestLedger = latestReplicaLedger
+	default:
+		p.fallbackCheckFailed = false
+		p.latestLedger = fallbackLedger
+	}
+}
+
+// Session returns the session of a replica which is healthy, has the given
+// ledger and does not lag behind the fallback database by more than MaxLag
+// ledgers. The eligible replicas are returned in turn. Callers must Clone the
+// returned session before using it.
+//
+// If no replica is eligible the fallback session is returned, or
+// ErrNoReplicaAvailable if the pool does not have one.
+func (p *ReplicaPool) Session(minLedger uint32) (SessionInterface, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	eligible := make([]*replicaState, 0, len(p.replicas))
+	for _, replica := range p.replicas {
+		if !replica.healthy || replica.ledger < minLedger {
+			continue
+		}
+		if p.config.MaxLag > 0 && replica.ledger+p.config.MaxLag < p.latestLedger {
+			continue
+		}
+		eligible = append(eligible, replica)
+	}
+
+	if len(eligible) == 0 {
+		if p.config.Fallback == nil {
+			return nil, ErrNoReplicaAvailable
+		}
+		atomic.AddUint64(&p.fallbacks, 1)
+		return p.config.Fallback, nil
+	}
+
+	replica := eligible[atomic.AddUint64(&p.next, 1)%uint64(len(eligible))]
+	atomic.AddUint64(&replica.selected, 1)
+	return replica.Session, nil
+}
+
+// status returns the health, the latest ledger and the lag of a replica.
+func (p *ReplicaPool) status(replica *replicaState) (bool, uint32, uint32) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if replica.ledger >= p.latestLedger {
+		return replica.healthy, replica.ledger, 0
+	}
+	return replica.healthy, replica.ledger, p.latestLedger - replica.ledger
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/diamnet/go/support/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type testReplicas struct {
+	lock     sync.Mutex
+	sessions []*MockSession
+	ledgers  map[SessionInterface]uint32
+	failing  map[SessionInterface]bool
+}
+
+func newTestReplicas(n int) *testReplicas {
+	replicas := &testReplicas{
+		ledgers: map[SessionInterface]uint32{},
+		failing: map[SessionInterface]bool{},
+	}
+	for i := 0; i < n; i++ {
+		session := &MockSession{}
+		session.On("Clone").Return(session)
+		replicas.sessions = append(replicas.sessions, session)
+	}
+	return replicas
+}
+
+func (r *testReplicas) set(i int, ledger uint32, failing bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.ledgers[r.sessions[i]] = ledger
+	r.failing[r.sessions[i]] = failing
+}
+
+func (r *testReplicas) latestLedger(ctx context.Context, session SessionInterface) (uint32, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.failing[session] {
+		return 0, errors.New("connection refused")
+	}
+	return r.ledgers[session], nil
+}
+
+func (r *testReplicas) config() ReplicaPoolConfig {
+	config := ReplicaPoolConfig{LatestLedger: r.latestLedger}
+	for i, session := range r.sessions {
+		config.Replicas = append(config.Replicas, Replica{
+			Name:    string(ReplicaSubservice(HistorySubservice, i)),
+			Session: session,
+		})
+	}
+	return config
+}
+
+func assertSelected(t *testing.T, pool *ReplicaPool, minLedger uint32, expected ...SessionInterface) {
+	selected := map[SessionInterface]bool{}
+	for i := 0; i < 2*len(expected); i++ {
+		session, err := pool.Session(minLedger)
+		if assert.NoError(t, err) {
+			selected[session] = true
+		}
+	}
+	assert.Len(t, selected, len(expected))
+	for _, session := range expected {
+		assert.True(t, selected[session])
+	}
+}
+
+func TestReplicaPool(t *testing.T) {
+	replicas := newTestReplicas(3)
+	config := replicas.config()
+	config.MaxLag = 2
+	pool, err := NewReplicaPool(config)
+	assert.NoError(t, err)
+
+	// Replicas are unhealthy until they are checked
+	_, err = pool.Session(0)
+	assert.Equal(t, ErrNoReplicaAvailable, err)
+
+	replicas.set(0, 10, false)
+	replicas.set(1, 9, false)
+	replicas.set(2, 5, false)
+	pool.Check(context.Background())
+
+	// The lagging replica is avoided and queries are routed in turn to the
+	// others
+	assertSelected(t, pool, 0, replicas.sessions[0], replicas.sessions[1])
+
+	// Replicas behind the requested ledger are avoided
+	assertSelected(t, pool, 10, replicas.sessions[0])
+	_, err = pool.Session(11)
+	assert.Equal(t, ErrNoReplicaAvailable, err)
+
+	// Failing replicas are avoided until they recover
+	replicas.set(0, 10, true)
+	pool.Check(context.Background())
+	assertSelected(t, pool, 0, replicas.sessions[1])
+
+	replicas.set(0, 11, false)
+	replicas.set(2, 10, false)
+	pool.Check(context.Background())
+	assertSelected(t, pool, 0, replicas.sessions[0], replicas.sessions[1], replicas.sessions[2])
+
+	healthy, ledger, lag := pool.status(pool.replicas[1])
+	assert.True(t, healthy)
+	assert.Equal(t, uint32(9), ledger)
+	assert.Equal(t, uint32(2), lag)
+}
+
+func TestReplicaPoolFallback(t *testing.T) {
+	replicas := newTestReplicas(2)
+	config := replicas.config()
+	fallback := &MockSession{}
+	fallback.On("Clone").Return(fallback)
+	config.Fallback = fallback
+	pool, err := NewReplicaPool(config)
+	assert.NoError(t, err)
+
+	replicas.set(0, 10, true)
+	replicas.set(1, 8, false)
+	replicas.ledgers[fallback] = 8
+	pool.Check(context.Background())
+
+	session, err := pool.Session(8)
+	assert.NoError(t, err)
+	assert.Equal(t, replicas.sessions[1], session)
+
+	session, err = pool.Session(9)
+	assert.NoError(t, err)
+	assert.Equal(t, fallback, session)
+	assert.Equal(t, uint64(1), pool.fallbacks)
+}
+
+func TestReplicaPoolLagBehindFallback(t *testing.T) {
+	replicas := newTestReplicas(2)
+	config := replicas.config()
+	config.MaxLag = 2
+	fallback := &MockSession{}
+	fallback.On("Clone").Return(fallback)
+	config.Fallback = fallback
+	pool, err := NewReplicaPool(config)
+	assert.NoError(t, err)
+
+	// The lag is measured against the fallback database, so replicas which
+	// are all behind it are avoided
+	replicas.set(0, 10, false)
+	replicas.set(1, 9, false)
+	replicas.ledgers[fallback] = 20
+	pool.Check(context.Background())
+	session, err := pool.Session(0)
+	assert.NoError(t, err)
+	assert.Equal(t, fallback, session)
+	_, _, lag := pool.status(pool.replicas[0])
+	assert.Equal(t, uint32(10), lag)
+
+	replicas.ledgers[fallback] = 11
+	pool.Check(context.Background())
+	assertSelected(t, pool, 0, replicas.sessions[0], replicas.sessions[1])
+
+	// The most up to date replica is used when the fallback database can't
+	// be checked
+	replicas.failing[fallback] = true
+	replicas.set(1, 7, false)
+	pool.Check(context.Background())
+	assertSelected(t, pool, 0, replicas.sessions[0])
+}
+
+func TestReplicaPoolConfig(t *testing.T) {
+	_, err := NewReplicaPool(ReplicaPoolConfig{})
+	assert.EqualError(t, err, "replica pool requires at least one replica")
+
+	config := newTestReplicas(1).config()
+	config.LatestLedger = nil
+	_, err = NewReplicaPool(config)
+	assert.EqualError(t, err, "replica pool requires a LatestLedger function")
+
+	config = newTestReplicas(2).config()
+	config.Replicas[1].Name = config.Replicas[0].Name
+	_, err = NewReplicaPool(config)
+	assert.EqualError(t, err, "duplicate replica name history_replica_0")
+}
+
+func TestReplicaPoolMetrics(t *testing.T) {
+	replicas := newTestReplicas(2)
+	pool, err := NewReplicaPool(replicas.config())
+	assert.NoError(t, err)
+	replicas.set(0, 10, false)
+	replicas.set(1, 7, true)
+	pool.Check(context.Background())
+	_, err = pool.Session(0)
+	assert.NoError(t, err)
+
+	registry := prometheus.NewRegistry()
+	RegisterReplicaPoolMetrics(pool, "aurora", HistorySubservice, registry)
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+
+	values := map[string]map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			replica := ""
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "replica" {
+					replica = label.GetValue()
+				}
+			}
+			if values[family.GetName()] == nil {
+				values[family.GetName()] = map[string]float64{}
+			}
+			if metric.GetGauge() != nil {
+				values[family.GetName()][replica] = metric.GetGauge().GetValue()
+			} else {
+				values[family.GetName()][replica] = metric.GetCounter().GetValue()
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{"history_replica_0": 1, "history_replica_1": 0}, values["aurora_db_replica_healthy"])
+	assert.Equal(t, map[string]float64{"history_replica_0": 10, "history_replica_1": 0}, values["aurora_db_replica_latest_ledger"])
+	assert.Equal(t, map[string]float64{"history_replica_0": 1, "history_replica_1": 0}, values["aurora_db_replica_selected_total"])
+	assert.Equal(t, map[string]float64{"history_replica_0": 0, "history_replica_1": 1}, values["aurora_db_replica_check_errors_total"])
+	assert.Equal(t, map[string]float64{"": 0}, values["aurora_db_replica_fallback_total"])
+}
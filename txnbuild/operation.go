@@ -34,6 +34,22 @@ func SetOpSourceMuxedAccount(op *xdr.Operation, sourceAccount string) {
 	op.SourceAccount = &opSourceAccountID
 }
 
+// WithOperationSourceAccount returns a copy of op with sourceAccount as its
+// source account. sourceAccount can be an M-strkey if withMuxedAccounts is set.
+func WithOperationSourceAccount(op Operation, sourceAccount string, withMuxedAccounts bool) (Operation, error) {
+	xdrOp, err := op.BuildXDR(withMuxedAccounts)
+	if err != nil {
+		return nil, err
+	}
+	xdrOp.SourceAccount = nil
+	if withMuxedAccounts {
+		SetOpSourceMuxedAccount(&xdrOp, sourceAccount)
+	} else {
+		SetOpSourceAccount(&xdrOp, sourceAccount)
+	}
+	return operationFromXDR(xdrOp, withMuxedAccounts)
+}
+
 // operationFromXDR returns a txnbuild Operation from its corresponding XDR operation
 func operationFromXDR(xdrOp xdr.Operation, withMuxedAccounts bool) (Operation, error) {
 	var newOp Operation
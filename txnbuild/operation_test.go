@@ -492,3 +492,22 @@ func testOperationsMarshallingRoundtrip(t *testing.T, operations []Operation, wi
 		assert.Equal(t, operations[i], tx.Operations()[i])
 	}
 }
+
+func TestWithOperationSourceAccount(t *testing.T) {
+	payment := &Payment{
+		Destination: "GAIH3ULLFQ4DGSECF2AR555KZ4KNDGEKN4AFI4SU2M7B43MGK3QJZNSR",
+		Amount:      "10",
+		Asset:       NativeAsset{},
+	}
+
+	op, err := WithOperationSourceAccount(payment, "GCPHE7DYMAUAY4UWA6OIYDFGLKRXGLLEMT6MVETC36L7LW4Z3A37EJW5", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "GCPHE7DYMAUAY4UWA6OIYDFGLKRXGLLEMT6MVETC36L7LW4Z3A37EJW5", op.GetSourceAccount())
+	assert.Equal(t, "GAIH3ULLFQ4DGSECF2AR555KZ4KNDGEKN4AFI4SU2M7B43MGK3QJZNSR", op.(*Payment).Destination)
+	assert.Equal(t, "10.0000000", op.(*Payment).Amount)
+	assert.Equal(t, "", payment.SourceAccount, "the operation should be copied")
+
+	op, err = WithOperationSourceAccount(op, "", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "", op.GetSourceAccount())
+}